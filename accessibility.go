@@ -0,0 +1,115 @@
+package mermaid
+
+import (
+	"strconv"
+	"strings"
+)
+
+// describeGraphStructure summarizes a diagram for screen readers: its
+// nodes, groups and connections, or the items of chart-like diagrams.
+func describeGraphStructure(graph *Graph) string {
+	kind := string(graph.Kind)
+	var parts []string
+	switch {
+	case graph.Kind == DiagramSequence && len(graph.SequenceParticipants) > 0:
+		names := make([]string, 0, len(graph.SequenceParticipants))
+		for _, id := range graph.SequenceParticipants {
+			names = append(names, accessibleName(graph.SequenceParticipantLabels[id], id))
+		}
+		parts = append(parts, kind+" diagram with "+countNoun(len(names), "participant")+": "+strings.Join(names, ", ")+".")
+		var messages []string
+		for _, msg := range graph.SequenceMessages {
+			if msg.IsNote {
+				continue
+			}
+			text := accessibleName(graph.SequenceParticipantLabels[msg.From], msg.From) + " to " +
+				accessibleName(graph.SequenceParticipantLabels[msg.To], msg.To)
+			if label := accessibleName(msg.Label, ""); label != "" {
+				text += ": " + label
+			}
+			messages = append(messages, text)
+		}
+		if len(messages) > 0 {
+			parts = append(parts, "Messages: "+strings.Join(messages, "; ")+".")
+		}
+	case graph.Kind == DiagramPie && len(graph.PieSlices) > 0:
+		slices := make([]string, 0, len(graph.PieSlices))
+		for _, slice := range graph.PieSlices {
+			slices = append(slices, accessibleName(slice.Label, "")+" "+strconv.FormatFloat(slice.Value, 'f', -1, 64))
+		}
+		parts = append(parts, kind+" chart with "+countNoun(len(slices), "slice")+": "+strings.Join(slices, ", ")+".")
+	case graph.Kind == DiagramMindmap && len(graph.MindmapNodes) > 0:
+		labels := map[string]string{}
+		var branches []string
+		for _, node := range graph.MindmapNodes {
+			labels[node.ID] = accessibleName(node.Label, node.ID)
+			if node.Parent != "" {
+				branches = append(branches, labels[node.Parent]+" to "+labels[node.ID])
+			}
+		}
+		parts = append(parts, kind+" with "+countNoun(len(graph.MindmapNodes), "node")+" rooted at "+labels[graph.MindmapNodes[0].ID]+".")
+		if len(branches) > 0 {
+			parts = append(parts, "Branches: "+strings.Join(branches, "; ")+".")
+		}
+	case len(graph.NodeOrder) > 0:
+		names := make([]string, 0, len(graph.NodeOrder))
+		for _, id := range graph.NodeOrder {
+			names = append(names, accessibleName(graph.Nodes[id].Label, id))
+		}
+		parts = append(parts, kind+" diagram with "+countNoun(len(names), "node")+" and "+
+			countNoun(len(graph.Edges), "connection")+". Nodes: "+strings.Join(names, ", ")+".")
+		if len(graph.FlowSubgraphs) > 0 {
+			groups := make([]string, 0, len(graph.FlowSubgraphs))
+			for _, sg := range graph.FlowSubgraphs {
+				members := make([]string, 0, len(sg.NodeIDs))
+				for _, id := range sg.NodeIDs {
+					members = append(members, graphNodeName(graph, id))
+				}
+				groups = append(groups, accessibleName(sg.Label, sg.ID)+" ("+strings.Join(members, ", ")+")")
+			}
+			parts = append(parts, "Groups: "+strings.Join(groups, "; ")+".")
+		}
+		if len(graph.Edges) > 0 {
+			edges := make([]string, 0, len(graph.Edges))
+			for _, e := range graph.Edges {
+				text := graphNodeName(graph, e.From) + " to " + graphNodeName(graph, e.To)
+				if label := accessibleName(e.Label, ""); label != "" {
+					text += " labelled " + label
+				}
+				edges = append(edges, text)
+			}
+			parts = append(parts, "Connections: "+strings.Join(edges, "; ")+".")
+		}
+	default:
+		parts = append(parts, kind+" diagram.")
+	}
+	return strings.Join(parts, " ")
+}
+
+// graphNodeName names a node or subgraph by its label.
+func graphNodeName(graph *Graph, id string) string {
+	if node, ok := graph.Nodes[id]; ok {
+		return accessibleName(node.Label, id)
+	}
+	if idx := graph.flowSubgraphIndex(id); idx >= 0 {
+		return accessibleName(graph.FlowSubgraphs[idx].Label, id)
+	}
+	return id
+}
+
+// accessibleName flattens a label to plain single-line text, falling back
+// to fallback when the label is empty.
+func accessibleName(label, fallback string) string {
+	text := strings.Join(strings.Fields(labelLinesText(parseLabelText(label))), " ")
+	if text == "" {
+		return fallback
+	}
+	return text
+}
+
+func countNoun(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+	return strconv.Itoa(n) + " " + noun + "s"
+}
@@ -0,0 +1,1329 @@
+package mermaid
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// RenderASCII renders a flowchart, sequence, state or class diagram as
+// monospace text drawn with Unicode box-drawing characters, or with plain
+// ASCII when options.Text.ASCII is set. Flowcharts, state and class
+// diagrams keep the rank order of the dagre layout on a character grid;
+// sequence diagrams keep the participant columns of the SVG renderer.
+func RenderASCII(input string, options RenderOptions) (string, error) {
+	if strings.TrimSpace(input) == "" {
+		return "", errors.New("input diagram is empty")
+	}
+	parsed, err := ParseMermaid(input)
+	if err != nil {
+		return "", err
+	}
+	graph := &parsed.Graph
+	canvas := newASCIICanvas()
+	switch graph.Kind {
+	case DiagramSequence:
+		drawSequenceText(canvas, graph, options.Theme)
+	case DiagramFlowchart, DiagramState, DiagramClass:
+		layout := ComputeLayout(graph, options.Theme, options.Layout)
+		drawGraphText(canvas, graph, layout)
+	default:
+		return "", fmt.Errorf("text output supports flowchart, sequence, state and class diagrams, not %s", graph.Kind)
+	}
+	return canvas.String(graph.Title, options.Text.ASCII), nil
+}
+
+// WriteASCIIFromSource renders a Mermaid diagram as text to a file, or to
+// stdout when outputPath is empty. See RenderASCII.
+func WriteASCIIFromSource(mermaidCode string, outputPath string, options RenderOptions) error {
+	text, err := RenderASCII(mermaidCode, options)
+	if err != nil {
+		return err
+	}
+	return writeOutputBytes([]byte(text), outputPath)
+}
+
+type asciiDir uint8
+
+const (
+	asciiUp asciiDir = 1 << iota
+	asciiDown
+	asciiLeft
+	asciiRight
+)
+
+func (d asciiDir) opposite() asciiDir {
+	switch d {
+	case asciiUp:
+		return asciiDown
+	case asciiDown:
+		return asciiUp
+	case asciiLeft:
+		return asciiRight
+	default:
+		return asciiLeft
+	}
+}
+
+type asciiPos struct {
+	x, y int
+}
+
+func (p asciiPos) step(d asciiDir) asciiPos {
+	switch d {
+	case asciiUp:
+		p.y--
+	case asciiDown:
+		p.y++
+	case asciiLeft:
+		p.x--
+	case asciiRight:
+		p.x++
+	}
+	return p
+}
+
+// asciiDirection is the direction from a to b, which must share a row or
+// a column.
+func asciiDirection(a, b asciiPos) asciiDir {
+	switch {
+	case b.y < a.y:
+		return asciiUp
+	case b.y > a.y:
+		return asciiDown
+	case b.x < a.x:
+		return asciiLeft
+	default:
+		return asciiRight
+	}
+}
+
+// asciiCell is one character of the text canvas. Text wins over lines;
+// lines are merged from the directions they leave the cell in.
+type asciiCell struct {
+	text  string
+	ascii string // replaces text in ASCII output when set
+	wide  bool   // right half of a double-width character
+	lines asciiDir
+	style EdgeStyle
+	round bool
+	// box marks node boxes, and fill the cells that lines must not cross.
+	box  bool
+	fill bool
+}
+
+// asciiCanvas is an unbounded character grid; coordinates may be negative
+// and String crops it to the cells in use.
+type asciiCanvas struct {
+	cells map[asciiPos]*asciiCell
+}
+
+func newASCIICanvas() *asciiCanvas {
+	return &asciiCanvas{cells: map[asciiPos]*asciiCell{}}
+}
+
+func (c *asciiCanvas) cell(p asciiPos) *asciiCell {
+	cell := c.cells[p]
+	if cell == nil {
+		cell = &asciiCell{}
+		c.cells[p] = cell
+	}
+	return cell
+}
+
+func (c *asciiCanvas) peek(p asciiPos) asciiCell {
+	if cell := c.cells[p]; cell != nil {
+		return *cell
+	}
+	return asciiCell{}
+}
+
+// link joins p to its neighbour in direction d unless either is filled.
+func (c *asciiCanvas) link(p asciiPos, d asciiDir, style EdgeStyle) {
+	next := p.step(d)
+	if c.peek(p).fill || c.peek(next).fill {
+		return
+	}
+	for _, end := range []struct {
+		p asciiPos
+		d asciiDir
+	}{{p, d}, {next, d.opposite()}} {
+		cell := c.cell(end.p)
+		cell.lines |= end.d
+		if style != "" && style != EdgeSolid || cell.style == "" {
+			cell.style = style
+		}
+	}
+}
+
+// path draws a polyline through axis-aligned points.
+func (c *asciiCanvas) path(points []asciiPos, style EdgeStyle) {
+	for i := 1; i < len(points); i++ {
+		d := asciiDirection(points[i-1], points[i])
+		for p := points[i-1]; p != points[i]; p = p.step(d) {
+			c.link(p, d, style)
+		}
+	}
+}
+
+// rect draws a box outline and marks it, and optionally its inside, as a
+// node box.
+func (c *asciiCanvas) rect(r asciiRect, round bool, style EdgeStyle, node bool) {
+	right, bottom := r.right(), r.bottom()
+	c.path([]asciiPos{{r.x, r.y}, {right, r.y}, {right, bottom}, {r.x, bottom}, {r.x, r.y}}, style)
+	for _, corner := range []asciiPos{{r.x, r.y}, {right, r.y}, {right, bottom}, {r.x, bottom}} {
+		c.cell(corner).round = round
+	}
+	if !node {
+		return
+	}
+	for y := r.y; y <= bottom; y++ {
+		for x := r.x; x <= right; x++ {
+			cell := c.cell(asciiPos{x, y})
+			cell.box = true
+			cell.fill = x > r.x && x < right && y > r.y && y < bottom
+		}
+	}
+}
+
+// rule draws a compartment separator across the inside of a box.
+func (c *asciiCanvas) rule(r asciiRect, y int) {
+	for x := r.x; x < r.right(); x++ {
+		for _, p := range []asciiPos{{x, y}, {x + 1, y}} {
+			c.cell(p).fill = false
+		}
+		c.link(asciiPos{x, y}, asciiRight, EdgeSolid)
+	}
+	for x := r.x + 1; x < r.right(); x++ {
+		c.cell(asciiPos{x, y}).fill = true
+	}
+}
+
+// write puts text at p and returns its width in cells.
+func (c *asciiCanvas) write(p asciiPos, text string) int {
+	x := p.x
+	var last *asciiCell
+	for _, r := range text {
+		w := asciiRuneWidth(r)
+		if w == 0 {
+			if last != nil {
+				last.text += string(r)
+			}
+			continue
+		}
+		last = c.cell(asciiPos{x, p.y})
+		last.text, last.ascii, last.wide = string(r), "", false
+		if w == 2 {
+			next := c.cell(asciiPos{x + 1, p.y})
+			next.text, next.ascii, next.wide = "", "", true
+		}
+		x += w
+	}
+	return x - p.x
+}
+
+// glyph puts a symbol at p with an ASCII alternative, centring the
+// shorter of the two over the cells of the longer.
+func (c *asciiCanvas) glyph(p asciiPos, unicodeText, asciiText string) {
+	u, a := []rune(unicodeText), []rune(asciiText)
+	w := max(len(u), len(a))
+	for i := 0; i < w; i++ {
+		cell := c.cell(asciiPos{p.x + i, p.y})
+		cell.text, cell.ascii, cell.wide = " ", " ", false
+		if j := i - (w-len(u))/2; j >= 0 && j < len(u) {
+			cell.text = string(u[j])
+		}
+		if j := i - (w-len(a))/2; j >= 0 && j < len(a) {
+			cell.ascii = string(a[j])
+		}
+	}
+}
+
+// free reports whether w cells from p hold neither text nor node boxes.
+func (c *asciiCanvas) free(p asciiPos, w int) bool {
+	for x := p.x; x < p.x+w; x++ {
+		cell := c.peek(asciiPos{x, p.y})
+		if cell.box || cell.text != "" || cell.wide {
+			return false
+		}
+	}
+	return true
+}
+
+// String crops the canvas and renders it row by row, with the title
+// centred above.
+func (c *asciiCanvas) String(title string, ascii bool) string {
+	if len(c.cells) == 0 {
+		return ""
+	}
+	minX, minY := math.MaxInt, math.MaxInt
+	maxX, maxY := math.MinInt, math.MinInt
+	for p := range c.cells {
+		minX, maxX = min(minX, p.x), max(maxX, p.x)
+		minY, maxY = min(minY, p.y), max(maxY, p.y)
+	}
+	var lines []string
+	if title = strings.TrimSpace(title); title != "" {
+		pad := max(0, (maxX-minX+1-asciiTextWidth(title))/2)
+		lines = append(lines, strings.Repeat(" ", pad)+title, "")
+	}
+	var row strings.Builder
+	for y := minY; y <= maxY; y++ {
+		row.Reset()
+		for x := minX; x <= maxX; x++ {
+			cell := c.peek(asciiPos{x, y})
+			switch {
+			case cell.wide:
+			case ascii && cell.ascii != "":
+				row.WriteString(cell.ascii)
+			case cell.text != "":
+				row.WriteString(cell.text)
+			case cell.lines != 0:
+				row.WriteRune(cell.glyph(ascii))
+			default:
+				row.WriteByte(' ')
+			}
+		}
+		lines = append(lines, strings.TrimRight(row.String(), " "))
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+func (c asciiCell) glyph(ascii bool) rune {
+	horizontal := c.lines&(asciiLeft|asciiRight) != 0
+	vertical := c.lines&(asciiUp|asciiDown) != 0
+	switch {
+	case horizontal && !vertical:
+		switch {
+		case c.style == EdgeDotted && ascii:
+			return '.'
+		case c.style == EdgeDotted:
+			return '┄'
+		case c.style == EdgeThick && ascii:
+			return '='
+		case c.style == EdgeThick:
+			return '━'
+		case ascii:
+			return '-'
+		}
+		return '─'
+	case vertical && !horizontal:
+		switch {
+		case c.style == EdgeDotted && ascii:
+			return ':'
+		case c.style == EdgeDotted:
+			return '┆'
+		case c.style == EdgeThick && !ascii:
+			return '┃'
+		case ascii:
+			return '|'
+		}
+		return '│'
+	case ascii:
+		return '+'
+	}
+	switch c.lines {
+	case asciiRight | asciiDown:
+		if c.round {
+			return '╭'
+		}
+		return '┌'
+	case asciiLeft | asciiDown:
+		if c.round {
+			return '╮'
+		}
+		return '┐'
+	case asciiRight | asciiUp:
+		if c.round {
+			return '╰'
+		}
+		return '└'
+	case asciiLeft | asciiUp:
+		if c.round {
+			return '╯'
+		}
+		return '┘'
+	case asciiUp | asciiDown | asciiRight:
+		return '├'
+	case asciiUp | asciiDown | asciiLeft:
+		return '┤'
+	case asciiLeft | asciiRight | asciiDown:
+		return '┬'
+	case asciiLeft | asciiRight | asciiUp:
+		return '┴'
+	}
+	return '┼'
+}
+
+// asciiRuneWidth is the number of terminal cells r takes: two for East
+// Asian wide characters and emoji, zero for combining marks.
+func asciiRuneWidth(r rune) int {
+	switch {
+	case unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf):
+		return 0
+	case unicode.In(r, unicode.Han, unicode.Hangul, unicode.Hiragana, unicode.Katakana),
+		r >= 0xFF01 && r <= 0xFF60, r >= 0xFFE0 && r <= 0xFFE6,
+		r >= 0x3000 && r <= 0x303F, r >= 0x1F300 && r <= 0x1FAFF:
+		return 2
+	}
+	return 1
+}
+
+func asciiTextWidth(text string) int {
+	w := 0
+	for _, r := range text {
+		w += asciiRuneWidth(r)
+	}
+	return w
+}
+
+func asciiLines(text string) []string {
+	lines := splitLinesPreserve(text)
+	for i := range lines {
+		lines[i] = strings.TrimSpace(lines[i])
+	}
+	return lines
+}
+
+func asciiMaxWidth(lines []string) int {
+	w := 0
+	for _, line := range lines {
+		w = max(w, asciiTextWidth(line))
+	}
+	return w
+}
+
+// asciiMarker returns the glyph drawn at an edge end for an arrow or a
+// class relation marker, pointing in direction d.
+func asciiMarker(marker string, arrow bool, d asciiDir, ascii bool) string {
+	pick := func(up, down, left, right string) string {
+		switch d {
+		case asciiUp:
+			return up
+		case asciiDown:
+			return down
+		case asciiLeft:
+			return left
+		}
+		return right
+	}
+	switch {
+	case strings.Contains(marker, "extension"):
+		if ascii {
+			return pick("^", "v", "<", ">")
+		}
+		return pick("△", "▽", "◁", "▷")
+	case strings.Contains(marker, "composition"):
+		if ascii {
+			return "*"
+		}
+		return "◆"
+	case strings.Contains(marker, "aggregation"):
+		if ascii {
+			return "o"
+		}
+		return "◇"
+	case strings.Contains(marker, "lollipop"):
+		if ascii {
+			return "o"
+		}
+		return "○"
+	case strings.Contains(marker, "circle"):
+		if ascii {
+			return "o"
+		}
+		return "●"
+	case strings.Contains(marker, "cross"):
+		if ascii {
+			return "x"
+		}
+		return "✕"
+	case marker != "" || arrow:
+		if ascii {
+			return pick("^", "v", "<", ">")
+		}
+		return pick("▲", "▼", "◄", "►")
+	}
+	return ""
+}
+
+type asciiRect struct {
+	x, y, w, h int
+}
+
+func (r asciiRect) right() int  { return r.x + r.w - 1 }
+func (r asciiRect) bottom() int { return r.y + r.h - 1 }
+
+func (r asciiRect) union(o asciiRect) asciiRect {
+	if r.w == 0 {
+		return o
+	}
+	x, y := min(r.x, o.x), min(r.y, o.y)
+	return asciiRect{x, y, max(r.right(), o.right()) - x + 1, max(r.bottom(), o.bottom()) - y + 1}
+}
+
+func (r asciiRect) grow(n int) asciiRect {
+	return asciiRect{r.x - n, r.y - n, r.w + 2*n, r.h + 2*n}
+}
+
+// asciiAxes maps rank (p) and cross (q) coordinates to x and y, so edges
+// are routed the same way for every layout direction. p grows in the
+// direction edges flow.
+type asciiAxes struct {
+	vertical bool
+	reverse  bool
+}
+
+func (a asciiAxes) pt(p, q int) asciiPos {
+	if a.reverse {
+		p = -p
+	}
+	if a.vertical {
+		return asciiPos{q, p}
+	}
+	return asciiPos{p, q}
+}
+
+func (a asciiAxes) span(r asciiRect) (p0, p1, q0, q1 int) {
+	if a.vertical {
+		p0, p1, q0, q1 = r.y, r.bottom(), r.x, r.right()
+	} else {
+		p0, p1, q0, q1 = r.x, r.right(), r.y, r.bottom()
+	}
+	if a.reverse {
+		p0, p1 = -p1, -p0
+	}
+	return p0, p1, q0, q1
+}
+
+func (a asciiAxes) size(w, h int) (p, q int) {
+	if a.vertical {
+		return h, w
+	}
+	return w, h
+}
+
+// asciiBox is a node placed on the character grid.
+type asciiBox struct {
+	id    string
+	rect  asciiRect
+	lines []string
+	// rules are the rows of class compartment separators.
+	rules []int
+	left  bool
+	round bool
+	glyph string
+	bar   bool
+
+	rank, lane int
+	// groups holds the subgraphs the node is in, directly or not.
+	groups map[string]bool
+	// cp and cq are the layout centre along and across the ranks, and half
+	// the layout half-size across them.
+	cp, cq, half float64
+}
+
+type asciiGraph struct {
+	canvas *asciiCanvas
+	axes   asciiAxes
+	boxes  []*asciiBox
+	rects  map[string]asciiRect
+	frames map[asciiRect]bool
+}
+
+// drawGraphText draws a flowchart, state or class diagram. Node centres of
+// the dagre layout are clustered into ranks and lanes, which become rows
+// and columns sized to the widest text in them.
+func drawGraphText(c *asciiCanvas, graph *Graph, layout Layout) {
+	g := &asciiGraph{
+		canvas: c,
+		axes: asciiAxes{
+			vertical: graph.Direction != DirectionLeftRight && graph.Direction != DirectionRightLeft,
+			reverse:  graph.Direction == DirectionBottomTop || graph.Direction == DirectionRightLeft,
+		},
+		rects:  map[string]asciiRect{},
+		frames: map[asciiRect]bool{},
+	}
+	subgraphs := map[string]bool{}
+	for _, sg := range graph.FlowSubgraphs {
+		subgraphs[sg.ID] = true
+	}
+	for _, node := range layout.Nodes {
+		if node.Shape == ShapeHidden || subgraphs[node.ID] {
+			continue
+		}
+		box := newASCIIBox(graph, node)
+		if g.axes.vertical {
+			box.cp, box.cq, box.half = node.Y+node.H/2, node.X+node.W/2, node.W/2
+		} else {
+			box.cp, box.cq, box.half = node.X+node.W/2, node.Y+node.H/2, node.H/2
+		}
+		g.boxes = append(g.boxes, box)
+	}
+	if len(g.boxes) == 0 {
+		return
+	}
+
+	groups := map[string]map[string]bool{}
+	for _, sg := range graph.FlowSubgraphs {
+		for _, id := range sg.NodeIDs {
+			if groups[id] == nil {
+				groups[id] = map[string]bool{}
+			}
+			groups[id][sg.ID] = true
+			for i, n := graph.flowSubgraphIndex(sg.Parent), 0; i >= 0 && n < len(graph.FlowSubgraphs); n++ {
+				groups[id][graph.FlowSubgraphs[i].ID] = true
+				i = graph.flowSubgraphIndex(graph.FlowSubgraphs[i].Parent)
+			}
+		}
+	}
+	for _, box := range g.boxes {
+		box.groups = groups[box.id]
+	}
+	labelWidth := 0
+	for _, edge := range graph.Edges {
+		labelWidth = max(labelWidth, asciiTextWidth(strings.Join(asciiLines(edge.Label), " ")))
+	}
+	rankGap, laneGap := 3, 4
+	if g.axes.vertical && labelWidth > 0 {
+		rankGap++
+	}
+	if !g.axes.vertical {
+		rankGap, laneGap = max(6, labelWidth+6), 1
+	}
+	g.place(rankGap, laneGap)
+
+	for _, box := range g.boxes {
+		g.rects[box.id] = box.rect
+		g.drawBox(box)
+	}
+	g.drawFrames(graph)
+
+	type label struct {
+		points []asciiPos
+		text   string
+	}
+	var labels []label
+	for _, edge := range graph.Edges {
+		if edge.Style == EdgeInvisible {
+			continue
+		}
+		s, okS := g.rects[edge.From]
+		t, okT := g.rects[edge.To]
+		if !okS || !okT {
+			continue
+		}
+		points := g.route(edge.From == edge.To, s, t)
+		if len(points) < 2 {
+			continue
+		}
+		g.drawEdge(points, edge)
+		if text := strings.Join(asciiLines(edge.Label), " "); text != "" {
+			labels = append(labels, label{points, text})
+		}
+	}
+	for _, l := range labels {
+		g.drawLabel(l.points, l.text)
+	}
+}
+
+func newASCIIBox(graph *Graph, node NodeLayout) *asciiBox {
+	box := &asciiBox{id: node.ID}
+	var lines []string
+	if len(node.LabelLines) > 0 {
+		for _, line := range node.LabelLines {
+			var b strings.Builder
+			for _, run := range line {
+				b.WriteString(run.Text)
+			}
+			lines = append(lines, strings.TrimSpace(b.String()))
+		}
+	} else {
+		lines = asciiLines(node.Label)
+	}
+	empty := strings.TrimSpace(strings.Join(lines, "")) == ""
+	switch {
+	case node.Shape == ShapeFork:
+		box.bar = true
+		if node.W >= node.H {
+			box.rect = asciiRect{w: 7, h: 1}
+		} else {
+			box.rect = asciiRect{w: 1, h: 3}
+		}
+		return box
+	case empty && (node.Shape == ShapeCircle || node.Shape == ShapeSmallCircle || node.Shape == ShapeFilledCircle):
+		box.glyph = "●|(*)"
+	case empty && (node.Shape == ShapeDoubleCircle || node.Shape == ShapeFramedCircle):
+		box.glyph = "◉|(@)"
+	case empty && node.Shape == ShapeDiamond:
+		box.glyph = "◇|<>"
+	}
+	if box.glyph != "" {
+		unicodeGlyph, asciiGlyph, _ := strings.Cut(box.glyph, "|")
+		box.rect = asciiRect{w: max(asciiTextWidth(unicodeGlyph), asciiTextWidth(asciiGlyph)), h: 1}
+		return box
+	}
+
+	if graph.Kind == DiagramClass {
+		members, methods := graph.ClassMembers[node.ID], graph.ClassMethods[node.ID]
+		if len(members)+len(methods) > 0 {
+			box.left = true
+			box.rules = []int{len(lines) + 1, len(lines) + len(members) + 2}
+			lines = append(lines, "")
+			lines = append(lines, members...)
+			lines = append(lines, "")
+			lines = append(lines, methods...)
+		}
+	}
+	box.lines = lines
+	box.round = graph.Kind == DiagramState || node.Shape == ShapeRoundRect || node.Shape == ShapeStadium ||
+		node.Shape == ShapeCircle || node.Shape == ShapeDoubleCircle
+	box.rect = asciiRect{w: max(5, asciiMaxWidth(lines)+4), h: len(lines) + 2}
+	return box
+}
+
+// place clusters the boxes into ranks along the layout direction and lanes
+// across it, then assigns grid positions. Gaps widen by two cells for
+// every subgraph frame that starts or ends in them.
+func (g *asciiGraph) place(rankGap, laneGap int) {
+	byRank := append([]*asciiBox(nil), g.boxes...)
+	sort.SliceStable(byRank, func(i, j int) bool { return byRank[i].cp < byRank[j].cp })
+	ranks := 0
+	start := math.Inf(-1)
+	for _, box := range byRank {
+		if box.cp-start > 10 {
+			start = box.cp
+			ranks++
+		}
+		box.rank = ranks - 1
+	}
+
+	byLane := append([]*asciiBox(nil), g.boxes...)
+	sort.SliceStable(byLane, func(i, j int) bool { return byLane[i].cq < byLane[j].cq })
+	type lane struct {
+		center float64
+		half   float64
+		ranks  map[int]bool
+	}
+	var lanes []*lane
+	for _, box := range byLane {
+		last := len(lanes) - 1
+		if last < 0 || box.cq-lanes[last].center > min(lanes[last].half, box.half) || lanes[last].ranks[box.rank] {
+			lanes = append(lanes, &lane{center: box.cq, half: box.half, ranks: map[int]bool{}})
+			last++
+		}
+		lanes[last].ranks[box.rank] = true
+		box.lane = last
+	}
+
+	rankSize := make([]int, ranks)
+	laneSize := make([]int, len(lanes))
+	for _, box := range g.boxes {
+		p, q := g.axes.size(box.rect.w, box.rect.h)
+		rankSize[box.rank] = max(rankSize[box.rank], p)
+		laneSize[box.lane] = max(laneSize[box.lane], q)
+	}
+	rankGroups := make([]map[string]bool, ranks)
+	laneGroups := make([]map[string]bool, len(lanes))
+	addGroups := func(sets []map[string]bool, i int, groups map[string]bool) {
+		if sets[i] == nil {
+			sets[i] = map[string]bool{}
+		}
+		for id := range groups {
+			sets[i][id] = true
+		}
+	}
+	for _, box := range g.boxes {
+		addGroups(rankGroups, box.rank, box.groups)
+		addGroups(laneGroups, box.lane, box.groups)
+	}
+	offsets := func(sizes []int, sets []map[string]bool, gap int) []int {
+		out := make([]int, len(sizes))
+		for i := 1; i < len(sizes); i++ {
+			frames := 0
+			for id := range sets[i-1] {
+				if !sets[i][id] {
+					frames++
+				}
+			}
+			for id := range sets[i] {
+				if !sets[i-1][id] {
+					frames++
+				}
+			}
+			out[i] = out[i-1] + sizes[i-1] + gap + 2*frames
+		}
+		return out
+	}
+	rankStart, laneStart := offsets(rankSize, rankGroups, rankGap), offsets(laneSize, laneGroups, laneGap)
+	for _, box := range g.boxes {
+		p, q := g.axes.size(box.rect.w, box.rect.h)
+		// Ranks are already in layout order, so they are placed unreversed.
+		pos := asciiAxes{vertical: g.axes.vertical}.pt(rankStart[box.rank]+(rankSize[box.rank]-p)/2, laneStart[box.lane]+(laneSize[box.lane]-q)/2)
+		box.rect.x, box.rect.y = pos.x, pos.y
+	}
+}
+
+func (g *asciiGraph) drawBox(box *asciiBox) {
+	r := box.rect
+	c := g.canvas
+	switch {
+	case box.bar:
+		if r.w > 1 {
+			c.path([]asciiPos{{r.x, r.y}, {r.right(), r.y}}, EdgeThick)
+		} else {
+			c.path([]asciiPos{{r.x, r.y}, {r.x, r.bottom()}}, EdgeThick)
+		}
+		return
+	case box.glyph != "":
+		unicodeGlyph, asciiGlyph, _ := strings.Cut(box.glyph, "|")
+		c.glyph(asciiPos{r.x, r.y}, unicodeGlyph, asciiGlyph)
+		for x := r.x; x <= r.right(); x++ {
+			c.cell(asciiPos{x, r.y}).box = true
+		}
+		return
+	}
+	c.rect(r, box.round, EdgeSolid, true)
+	for _, y := range box.rules {
+		c.rule(r, r.y+y)
+	}
+	for i, line := range box.lines {
+		x := r.x + 2
+		if !box.left || i == 0 {
+			x = r.x + (r.w-asciiTextWidth(line))/2
+		}
+		c.write(asciiPos{x, r.y + 1 + i}, line)
+	}
+}
+
+// drawFrames outlines subgraphs and composite states around their members,
+// innermost first, with the label on the top border.
+func (g *asciiGraph) drawFrames(graph *Graph) {
+	frames := map[string]asciiRect{}
+	var frame func(sg FlowSubgraph, seen map[string]bool) asciiRect
+	frame = func(sg FlowSubgraph, seen map[string]bool) asciiRect {
+		if r, ok := frames[sg.ID]; ok || seen[sg.ID] {
+			return r
+		}
+		seen[sg.ID] = true
+		var r asciiRect
+		for _, id := range sg.NodeIDs {
+			if box, ok := g.rects[id]; ok {
+				r = r.union(box)
+			}
+		}
+		for _, child := range graph.FlowSubgraphs {
+			if child.Parent == sg.ID {
+				if inner := frame(child, seen); inner.w > 0 {
+					r = r.union(inner)
+				}
+			}
+		}
+		if r.w > 0 {
+			r = r.grow(2)
+			r.w = max(r.w, asciiTextWidth(sg.Label)+6)
+		}
+		frames[sg.ID] = r
+		return r
+	}
+	for _, sg := range graph.FlowSubgraphs {
+		r := frame(sg, map[string]bool{})
+		if r.w == 0 {
+			continue
+		}
+		g.rects[sg.ID] = r
+		g.frames[r] = true
+		g.canvas.rect(r, false, EdgeSolid, false)
+		if label := strings.Join(asciiLines(sg.Label), " "); label != "" {
+			g.canvas.write(asciiPos{r.x + 2, r.y}, " "+label+" ")
+		}
+	}
+}
+
+// route returns the points of an orthogonal edge from the border of s to
+// the cell just outside t. Edges run along the rank axis and turn in the
+// gap before the target; edges against the layout direction loop around
+// the far side of both nodes.
+func (g *asciiGraph) route(self bool, s, t asciiRect) []asciiPos {
+	a := g.axes
+	sp0, sp1, sq0, sq1 := a.span(s)
+	tp0, tp1, tq0, tq1 := a.span(t)
+	spc, sqc := (sp0+sp1)/2, (sq0+sq1)/2
+	tpc, tqc := (tp0+tp1)/2, (tq0+tq1)/2
+	// Frames are entered and left at the point nearest the other end, so
+	// their edges stay clear of the edges of the nodes inside.
+	if g.frames[t] {
+		tqc = min(max(sqc, tq0+1), tq1-1)
+	}
+	if g.frames[s] {
+		sqc = min(max(tqc, sq0+1), sq1-1)
+	}
+	switch {
+	case self:
+		q := sq1 + 2
+		return []asciiPos{a.pt(spc, sq1), a.pt(spc, q), a.pt(sp1+2, q), a.pt(sp1+2, sq1-1), a.pt(sp1+1, sq1-1)}
+	case tp0 > sp1:
+		exit, entry := g.bandEnd(s), g.bandStart(t)
+		for frame := range g.frames {
+			if p0, _, _, _ := a.span(frame); p0 > sp1 && p0 < entry {
+				entry = p0
+			}
+		}
+		jog := entry - 2
+		if jog <= sp1 {
+			jog = sp1 + 1
+		}
+		if sqc == tqc && !g.blocked(sqc, sp1+1, tp0-1, s, t) {
+			return []asciiPos{a.pt(sp1, sqc), a.pt(tp0-1, tqc)}
+		}
+		if !g.blocked(sqc, sp1+1, jog, s, t) && !g.blocked(tqc, jog, tp0-1, s, t) {
+			return asciiCompact([]asciiPos{a.pt(sp1, sqc), a.pt(jog, sqc), a.pt(jog, tqc), a.pt(tp0-1, tqc)})
+		}
+		out := exit + 1
+		q := g.clearLane(max(sqc, tqc)+2, out, jog, s, t)
+		return asciiCompact([]asciiPos{a.pt(sp1, sqc), a.pt(out, sqc), a.pt(out, q), a.pt(jog, q), a.pt(jog, tqc), a.pt(tp0-1, tqc)})
+	case tp1 < sp0:
+		q := g.clearLane(max(sq1, tq1)+2, tp0, sp1, s, t)
+		return asciiCompact([]asciiPos{a.pt(spc, sq1), a.pt(spc, q), a.pt(tpc, q), a.pt(tpc, tq1+1)})
+	case tqc >= sqc:
+		mid := (sq1 + tq0) / 2
+		return asciiCompact([]asciiPos{a.pt(spc, sq1), a.pt(spc, mid), a.pt(tpc, mid), a.pt(tpc, tq0-1)})
+	default:
+		mid := (sq0 + tq1) / 2
+		return asciiCompact([]asciiPos{a.pt(spc, sq0), a.pt(spc, mid), a.pt(tpc, mid), a.pt(tpc, tq1+1)})
+	}
+}
+
+// bandEnd and bandStart are the rank extents of the boxes beside r, so
+// turns happen clear of the whole rank rather than just r.
+func (g *asciiGraph) bandEnd(r asciiRect) int {
+	p0, p1, _, _ := g.axes.span(r)
+	end := p1
+	for _, box := range g.boxes {
+		b0, b1, _, _ := g.axes.span(box.rect)
+		if b0 <= p1 && b1 >= p0 {
+			end = max(end, b1)
+		}
+	}
+	return end
+}
+
+func (g *asciiGraph) bandStart(r asciiRect) int {
+	p0, p1, _, _ := g.axes.span(r)
+	start := p0
+	for _, box := range g.boxes {
+		b0, b1, _, _ := g.axes.span(box.rect)
+		if b0 <= p1 && b1 >= p0 {
+			start = min(start, b0)
+		}
+	}
+	return start
+}
+
+// blocked reports whether a line at cross position q from p0 to p1 would
+// pass through a box other than the edge's own ends.
+func (g *asciiGraph) blocked(q, p0, p1 int, s, t asciiRect) bool {
+	if p0 > p1 {
+		p0, p1 = p1, p0
+	}
+	for _, box := range g.boxes {
+		if box.rect == s || box.rect == t {
+			continue
+		}
+		b0, b1, c0, c1 := g.axes.span(box.rect)
+		if b0 <= p1 && b1 >= p0 && c0 <= q && c1 >= q {
+			return true
+		}
+	}
+	return false
+}
+
+// clearLane moves q past any box in the way between p0 and p1.
+func (g *asciiGraph) clearLane(q, p0, p1 int, s, t asciiRect) int {
+	for range g.boxes {
+		if !g.blocked(q, p0, p1, s, t) {
+			return q
+		}
+		for _, box := range g.boxes {
+			b0, b1, c0, c1 := g.axes.span(box.rect)
+			if b0 <= max(p0, p1) && b1 >= min(p0, p1) && c0 <= q && c1 >= q {
+				q = c1 + 2
+			}
+		}
+	}
+	return q
+}
+
+// asciiCompact drops repeated points and points inside straight runs.
+func asciiCompact(points []asciiPos) []asciiPos {
+	out := make([]asciiPos, 0, len(points))
+	for _, p := range points {
+		if n := len(out); n > 0 && out[n-1] == p {
+			continue
+		}
+		if n := len(out); n > 1 && (out[n-2].x == out[n-1].x && out[n-1].x == p.x || out[n-2].y == out[n-1].y && out[n-1].y == p.y) {
+			out[n-1] = p
+			continue
+		}
+		out = append(out, p)
+	}
+	return out
+}
+
+func (g *asciiGraph) drawEdge(points []asciiPos, edge Edge) {
+	c := g.canvas
+	style := edge.Style
+	if style == "" {
+		style = EdgeSolid
+	}
+	c.path(points, style)
+	n := len(points)
+	end := asciiDirection(points[n-2], points[n-1])
+	if asciiMarker(edge.MarkerEnd, edge.ArrowEnd, end, false) != "" {
+		g.mark(points[n-1], edge.MarkerEnd, edge.ArrowEnd, end)
+	} else {
+		c.link(points[n-1], end, style)
+	}
+	start := asciiDirection(points[0], points[1])
+	if asciiMarker(edge.MarkerStart, edge.ArrowStart, start, false) != "" {
+		g.mark(points[0].step(start), edge.MarkerStart, edge.ArrowStart, start.opposite())
+	}
+}
+
+func (g *asciiGraph) mark(p asciiPos, marker string, arrow bool, d asciiDir) {
+	g.canvas.glyph(p, asciiMarker(marker, arrow, d, false), asciiMarker(marker, arrow, d, true))
+}
+
+// drawLabel writes an edge label over its longest horizontal run when it
+// fits, otherwise beside the middle of its longest vertical run.
+func (g *asciiGraph) drawLabel(points []asciiPos, text string) {
+	w := asciiTextWidth(text)
+	best, bestLen := -1, 0
+	for i := 1; i < len(points); i++ {
+		if points[i].y == points[i-1].y {
+			if l := abs(points[i].x - points[i-1].x); l-1 >= w+2 && l > bestLen {
+				best, bestLen = i, l
+			}
+		}
+	}
+	if best >= 0 {
+		a, b := points[best-1], points[best]
+		x := (a.x+b.x+1)/2 - (w+2)/2
+		g.canvas.write(asciiPos{x, a.y}, " "+text+" ")
+		return
+	}
+	best, bestLen = 1, -1
+	for i := 1; i < len(points); i++ {
+		if points[i].x == points[i-1].x {
+			if l := abs(points[i].y - points[i-1].y); l > bestLen {
+				best, bestLen = i, l
+			}
+		}
+	}
+	a, b := points[best-1], points[best]
+	y := (a.y + b.y) / 2
+	at := asciiPos{a.x + 2, y}
+	if !g.canvas.free(asciiPos{a.x + 1, y}, w+2) && g.canvas.free(asciiPos{a.x - w - 2, y}, w+2) {
+		at = asciiPos{a.x - w - 1, y}
+	}
+	g.canvas.write(at, text)
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+// asciiFrame is an alt or par block of a sequence diagram.
+type asciiFrame struct {
+	kind     string
+	label    string
+	top      int
+	bottom   int
+	sections []asciiFrameSection
+	minX     int
+	maxX     int
+	used     bool
+}
+
+type asciiFrameSection struct {
+	y     int
+	label string
+}
+
+// drawSequenceText draws participants at the columns of the sequence plan,
+// then one or two rows per message, note and block boundary.
+func drawSequenceText(c *asciiCanvas, graph *Graph, theme Theme) {
+	participants := append([]string(nil), graph.SequenceParticipants...)
+	if len(participants) == 0 {
+		seen := map[string]bool{}
+		for _, msg := range graph.SequenceMessages {
+			for _, id := range []string{msg.From, msg.To} {
+				if id != "" && !seen[id] {
+					seen[id] = true
+					participants = append(participants, id)
+				}
+			}
+		}
+	}
+	if len(participants) == 0 {
+		return
+	}
+	events := graph.SequenceEvents
+	if len(events) == 0 {
+		events = defaultSequenceEvents(graph.SequenceMessages)
+	}
+	plan := buildSequencePlan(participants, graph.SequenceParticipantLabels, graph.SequenceMessages, events, theme)
+
+	index := map[string]int{}
+	labels := make([][]string, len(participants))
+	boxH := 0
+	for i, id := range participants {
+		index[id] = i
+		label := strings.TrimSpace(graph.SequenceParticipantLabels[id])
+		if label == "" {
+			label = id
+		}
+		labels[i] = asciiLines(label)
+		boxH = max(boxH, len(labels[i])+2)
+	}
+	boxW := func(i int) int { return asciiMaxWidth(labels[i]) + 4 }
+
+	gaps := make([]int, len(participants))
+	for i := 1; i < len(participants); i++ {
+		planned := int(math.Round((plan.ParticipantCenter[participants[i]] - plan.ParticipantCenter[participants[i-1]]) / 10))
+		gaps[i] = max(planned, (boxW(i-1)+1)/2+boxW(i)/2+2)
+	}
+	need := func(i, j, width int) {
+		if i > j {
+			i, j = j, i
+		}
+		if i == j || i < 0 {
+			return
+		}
+		sum := 0
+		for k := i + 1; k <= j; k++ {
+			sum += gaps[k]
+		}
+		if sum < width {
+			gaps[j] += width - sum
+		}
+	}
+	for _, msg := range graph.SequenceMessages {
+		from, okFrom := index[msg.From]
+		to, okTo := index[msg.To]
+		if !okFrom || !okTo {
+			continue
+		}
+		w := asciiMaxWidth(asciiLines(msg.Label))
+		switch {
+		case msg.IsNote && msg.NotePlacement == SequenceNoteRightOf && to+1 < len(participants):
+			need(to, to+1, w+7)
+		case msg.IsNote && msg.NotePlacement == SequenceNoteLeftOf:
+			need(from-1, from, w+7)
+		case msg.IsNote:
+			need(from, to, w+2)
+		case from == to && from+1 < len(participants):
+			need(from, from+1, w+6)
+		default:
+			need(from, to, w+4)
+		}
+	}
+	cols := make([]int, len(participants))
+	for i := 1; i < len(participants); i++ {
+		cols[i] = cols[i-1] + gaps[i]
+	}
+
+	drawActors := func(y int) {
+		for i := range participants {
+			w := boxW(i)
+			r := asciiRect{cols[i] - w/2, y, w, boxH}
+			c.rect(r, false, EdgeSolid, true)
+			for j, line := range labels[i] {
+				c.write(asciiPos{r.x + (w-asciiTextWidth(line))/2, y + 1 + j}, line)
+			}
+		}
+	}
+	drawActors(0)
+
+	var open, frames []*asciiFrame
+	extend := func(minX, maxX int) {
+		for _, f := range open {
+			f.minX, f.maxX = min(f.minX, minX), max(f.maxX, maxX)
+			f.used = true
+		}
+	}
+	active := map[int][][2]int{}
+	activeStart := map[int][]int{}
+	y := boxH
+	for _, event := range events {
+		switch event.Kind {
+		case SequenceEventAltStart, SequenceEventParStart:
+			kind := "alt"
+			if event.Kind == SequenceEventParStart {
+				kind = "par"
+			}
+			y++
+			open = append(open, &asciiFrame{kind: kind, label: strings.TrimSpace(event.Label), top: y, minX: math.MaxInt, maxX: math.MinInt})
+		case SequenceEventAltElse, SequenceEventParAnd:
+			if len(open) > 0 {
+				y++
+				f := open[len(open)-1]
+				f.sections = append(f.sections, asciiFrameSection{y: y, label: strings.TrimSpace(event.Label)})
+			}
+		case SequenceEventAltEnd, SequenceEventParEnd:
+			if len(open) == 0 {
+				continue
+			}
+			y++
+			f := open[len(open)-1]
+			open = open[:len(open)-1]
+			f.bottom = y
+			if !f.used {
+				f.minX, f.maxX = cols[0], cols[len(cols)-1]
+			}
+			f.minX -= 3
+			f.maxX = max(f.maxX+3, f.minX+asciiTextWidth(f.kind+" ["+f.label+"]")+6)
+			frames = append(frames, f)
+			extend(f.minX, f.maxX)
+		case SequenceEventActivateStart:
+			if i, ok := index[event.Actor]; ok {
+				activeStart[i] = append(activeStart[i], y)
+			}
+		case SequenceEventActivateEnd:
+			if i, ok := index[event.Actor]; ok && len(activeStart[i]) > 0 {
+				n := len(activeStart[i]) - 1
+				active[i] = append(active[i], [2]int{activeStart[i][n], y})
+				activeStart[i] = activeStart[i][:n]
+			}
+		case SequenceEventMessage:
+			if event.MessageIndex < 0 || event.MessageIndex >= len(graph.SequenceMessages) {
+				continue
+			}
+			msg := graph.SequenceMessages[event.MessageIndex]
+			from, okFrom := index[msg.From]
+			to, okTo := index[msg.To]
+			if !okFrom || !okTo {
+				continue
+			}
+			y++
+			text := strings.Join(asciiLines(msg.Label), " ")
+			w := asciiTextWidth(text)
+			switch {
+			case msg.IsNote:
+				lines := asciiLines(msg.Label)
+				nw := asciiMaxWidth(lines) + 4
+				var r asciiRect
+				switch msg.NotePlacement {
+				case SequenceNoteRightOf:
+					r = asciiRect{cols[to] + 2, y, nw, len(lines) + 2}
+				case SequenceNoteLeftOf:
+					r = asciiRect{cols[from] - 1 - nw, y, nw, len(lines) + 2}
+				default:
+					left, right := min(cols[from], cols[to]), max(cols[from], cols[to])
+					if from == to {
+						r = asciiRect{left - nw/2, y, nw, len(lines) + 2}
+					} else {
+						r = asciiRect{left - 2, y, max(nw, right-left+5), len(lines) + 2}
+					}
+				}
+				c.rect(r, false, EdgeSolid, true)
+				for yy := r.y; yy <= r.bottom(); yy++ {
+					for xx := r.x; xx <= r.right(); xx++ {
+						c.cell(asciiPos{xx, yy}).fill = true
+					}
+				}
+				for j, line := range lines {
+					c.write(asciiPos{r.x + 2, r.y + 1 + j}, line)
+				}
+				extend(r.x, r.right())
+				y = r.bottom()
+			case from == to:
+				x := cols[from]
+				c.path([]asciiPos{{x, y}, {x + 2, y}, {x + 2, y + 1}, {x + 1, y + 1}}, sequenceTextStyle(msg.Arrow))
+				if text != "" {
+					c.write(asciiPos{x + 4, y}, text)
+				}
+				sequenceTextHead(c, asciiPos{x + 1, y + 1}, asciiLeft, msg.Arrow)
+				extend(x, x+4+w)
+				y++
+			default:
+				x0, x1 := cols[from], cols[to]
+				if text != "" {
+					c.write(asciiPos{(x0+x1+1)/2 - w/2, y}, text)
+					y++
+				}
+				d := asciiRight
+				if x1 < x0 {
+					d = asciiLeft
+				}
+				head := asciiPos{x1, y}.step(d.opposite())
+				c.path([]asciiPos{{x0, y}, head}, sequenceTextStyle(msg.Arrow))
+				sequenceTextHead(c, head, d, msg.Arrow)
+				if strings.HasPrefix(sequenceArrowBase(msg.Arrow), "<<") {
+					sequenceTextHead(c, asciiPos{x0, y}.step(d), d.opposite(), "->>")
+				}
+				extend(min(x0, x1), max(x0, x1))
+			}
+		}
+	}
+	y += 2
+	for i, starts := range activeStart {
+		for _, start := range starts {
+			active[i] = append(active[i], [2]int{start, y})
+		}
+	}
+
+	for _, f := range frames {
+		r := asciiRect{f.minX, f.top, f.maxX - f.minX + 1, f.bottom - f.top + 1}
+		c.rect(r, false, EdgeSolid, false)
+		title := f.kind
+		if f.label != "" {
+			title += " [" + f.label + "]"
+		}
+		c.write(asciiPos{r.x + 2, r.y}, " "+title+" ")
+		for _, section := range f.sections {
+			c.path([]asciiPos{{r.x, section.y}, {r.right(), section.y}}, EdgeDotted)
+			if section.label != "" {
+				c.write(asciiPos{r.x + 2, section.y}, " ["+section.label+"] ")
+			}
+		}
+	}
+	for i, x := range cols {
+		c.path([]asciiPos{{x, boxH - 1}, {x, y}}, EdgeSolid)
+		for _, span := range active[i] {
+			for yy := span[0] + 1; yy <= span[1]; yy++ {
+				if cell := c.cells[asciiPos{x, yy}]; cell != nil && cell.lines == asciiUp|asciiDown {
+					cell.style = EdgeThick
+				}
+			}
+		}
+	}
+	drawActors(y)
+}
+
+func sequenceTextStyle(arrow string) EdgeStyle {
+	if strings.Contains(sequenceArrowBase(arrow), "--") {
+		return EdgeDotted
+	}
+	return EdgeSolid
+}
+
+// sequenceTextHead marks the arrow head of a message at p, pointing in d.
+// Open arrows have no head, and -x arrows end in a cross.
+func sequenceTextHead(c *asciiCanvas, p asciiPos, d asciiDir, arrow string) {
+	base := sequenceArrowBase(arrow)
+	switch {
+	case strings.HasSuffix(base, "x"):
+		c.glyph(p, asciiMarker("cross", false, d, false), asciiMarker("cross", false, d, true))
+	case sequenceOpenArrow(arrow):
+		c.link(p, d, sequenceTextStyle(arrow))
+	default:
+		c.glyph(p, asciiMarker("", true, d, false), asciiMarker("", true, d, true))
+	}
+}
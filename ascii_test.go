@@ -0,0 +1,98 @@
+package mermaid
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderASCIIFlowchart(t *testing.T) {
+	out, err := RenderASCII("flowchart TD\n  A[Start] --> B{Ok?}\n  B -->|yes| C[Done]\n  B -.-> D[Retry]", DefaultRenderOptions())
+	if err != nil {
+		t.Fatalf("RenderASCII() error = %v", err)
+	}
+	for _, want := range []string{"│ Start │", "│ Ok? │", "│ Done │", "│ Retry │", "yes", "▼", "┬", "┆"} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("output is missing %q:\n%s", want, out)
+		}
+	}
+	lines := strings.Split(out, "\n")
+	row := func(text string) int {
+		for i, line := range lines {
+			if strings.Contains(line, text) {
+				return i
+			}
+		}
+		return -1
+	}
+	if !(row("Start") < row("Ok?") && row("Ok?") < row("Done") && row("Done") == row("Retry")) {
+		t.Fatalf("expected ranks top to bottom with Done and Retry side by side:\n%s", out)
+	}
+}
+
+func TestRenderASCIIFallback(t *testing.T) {
+	out, err := RenderASCII("flowchart LR\n  A --> B", DefaultRenderOptions().WithASCII(true))
+	if err != nil {
+		t.Fatalf("RenderASCII() error = %v", err)
+	}
+	want := "+---+      +---+\n| A +----->| B |\n+---+      +---+\n"
+	if out != want {
+		t.Fatalf("RenderASCII() =\n%s\nwant\n%s", out, want)
+	}
+}
+
+func TestRenderASCIISequence(t *testing.T) {
+	input := "sequenceDiagram\n  participant A as Alice\n  participant B as Bob\n  A->>B: Hello\n  B-->>A: Hi\n  Note right of B: think\n  alt ok\n    A->>A: self\n  end"
+	out, err := RenderASCII(input, DefaultRenderOptions())
+	if err != nil {
+		t.Fatalf("RenderASCII() error = %v", err)
+	}
+	for _, want := range []string{"│ Alice │", "│ Bob │", "├", "►│", "│◄┄", "│ think │", "alt [ok]", "◄┘"} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("output is missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Count(out, "│ Alice │") != 2 {
+		t.Fatalf("expected participants at the top and bottom:\n%s", out)
+	}
+	hello, hi := strings.Index(out, "Hello"), strings.Index(out, " Hi ")
+	if hello < 0 || hi < hello {
+		t.Fatalf("expected messages in order:\n%s", out)
+	}
+}
+
+func TestRenderASCIIClassAndState(t *testing.T) {
+	class, err := RenderASCII("classDiagram\n  Animal <|-- Duck\n  Animal : +int age\n  Animal : +eat()", DefaultRenderOptions())
+	if err != nil {
+		t.Fatalf("RenderASCII(class) error = %v", err)
+	}
+	for _, want := range []string{"│ +int age │", "│ +eat()   │", "├──────────┤", "△"} {
+		if !strings.Contains(class, want) {
+			t.Fatalf("class output is missing %q:\n%s", want, class)
+		}
+	}
+
+	state, err := RenderASCII("stateDiagram-v2\n  [*] --> Idle\n  Idle --> [*]", DefaultRenderOptions().WithASCII(true))
+	if err != nil {
+		t.Fatalf("RenderASCII(state) error = %v", err)
+	}
+	for _, want := range []string{"(*)", "(@)", "| Idle |"} {
+		if !strings.Contains(state, want) {
+			t.Fatalf("state output is missing %q:\n%s", want, state)
+		}
+	}
+}
+
+func TestRenderASCIIRejectsOtherDiagrams(t *testing.T) {
+	if _, err := RenderASCII("pie\n  \"A\" : 1", DefaultRenderOptions()); err == nil || !strings.Contains(err.Error(), "pie") {
+		t.Fatalf("expected an error naming the diagram kind, got %v", err)
+	}
+}
+
+func TestASCIICanvasWideText(t *testing.T) {
+	c := newASCIICanvas()
+	c.rect(asciiRect{0, 0, 8, 3}, false, EdgeSolid, true)
+	c.write(asciiPos{2, 1}, "你好")
+	if got := c.String("", false); got != "┌──────┐\n│ 你好 │\n└──────┘\n" {
+		t.Fatalf("String() =\n%s", got)
+	}
+}
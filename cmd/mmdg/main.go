@@ -35,21 +35,47 @@ func run() error {
 		timing               bool
 		fastText             bool
 		allowApproximate     bool
+		svgLabels            bool
+		embedFont            bool
+		fontDirs             string
+		assetDirs            string
+		describe             bool
+		scale                float64
+		backgroundColor      string
+		dpi                  float64
+		pdfFit               bool
+		pdfPages             bool
+		pdfRaster            bool
+		asciiOnly            bool
 	)
 
 	fs := flag.NewFlagSet("mmdg", flag.ContinueOnError)
 	fs.SetOutput(io.Discard)
 	fs.StringVar(&inputPath, "i", "", "input file (.mmd/.md) or '-' for stdin")
 	fs.StringVar(&outputPath, "o", "", "output file path")
-	fs.StringVar(&outputFormat, "e", "svg", "output format: svg|png")
-	fs.Float64Var(&width, "w", 1200, "render width (reserved)")
-	fs.Float64Var(&height, "H", 800, "render height (reserved)")
+	fs.StringVar(&outputFormat, "e", "svg", "output format: svg|png|pdf|txt|html|layout-json|drawio|excalidraw")
+	fs.Float64Var(&width, "w", 1200, "viewport width used for layout")
+	fs.Float64Var(&height, "H", 800, "viewport height used for layout")
+	fs.Float64Var(&scale, "s", 1, "PNG scale factor")
+	fs.Float64Var(&scale, "scale", 1, "PNG scale factor")
+	fs.StringVar(&backgroundColor, "b", "white", "PNG background color, or transparent")
+	fs.StringVar(&backgroundColor, "backgroundColor", "white", "PNG background color, or transparent")
+	fs.Float64Var(&dpi, "dpi", 0, "PNG resolution in dots per inch (default 96 x scale)")
+	fs.BoolVar(&pdfFit, "pdfFit", false, "size PDF pages to the diagram instead of US Letter")
+	fs.BoolVar(&pdfPages, "pdfPages", false, "write all diagrams of a markdown input as the pages of one PDF")
+	fs.BoolVar(&pdfRaster, "pdfRaster", false, "place diagram kinds without vector PDF output (all but flowchart, sequence, class, state, er, requirement, pie, timeline and xychart) as an image without a warning")
+	fs.BoolVar(&asciiOnly, "ascii", false, "draw txt output with plain ASCII instead of Unicode box drawing")
 	fs.StringVar(&preferredAspectRatio, "preferredAspectRatio", "", "preferred ratio: 16:9, 4/3, or decimal")
 	fs.Float64Var(&nodeSpacing, "nodeSpacing", 0, "node spacing")
 	fs.Float64Var(&rankSpacing, "rankSpacing", 0, "rank spacing")
 	fs.BoolVar(&timing, "timing", false, "print timing as JSON to stderr")
 	fs.BoolVar(&fastText, "fastText", false, "use fast text width approximation")
 	fs.BoolVar(&allowApproximate, "allowApproximate", false, "allow rendering for experimental low-fidelity diagram families")
+	fs.BoolVar(&svgLabels, "svgLabels", false, "render labels as SVG text instead of HTML foreignObject")
+	fs.BoolVar(&embedFont, "embedFont", false, "embed a subset of the measuring font in SVG output")
+	fs.StringVar(&fontDirs, "fontDirs", "", "font directories to search instead of the system ones, separated by '"+string(filepath.ListSeparator)+"'; empty uses only the bundled font")
+	fs.StringVar(&assetDirs, "assetDirs", "", "directories flowchart img nodes may embed local files from, separated by '"+string(filepath.ListSeparator)+"'; empty embeds none")
+	fs.BoolVar(&describe, "describe", false, "describe the diagram structure in <desc> when accDescr is missing")
 	if err := fs.Parse(os.Args[1:]); err != nil {
 		if errors.Is(err, flag.ErrHelp) {
 			printUsage(fs)
@@ -65,6 +91,8 @@ func run() error {
 			explicitWidth = true
 		case "H":
 			explicitHeight = true
+		case "fontDirs":
+			mermaid.SetFontDirs(filepath.SplitList(fontDirs)...)
 		}
 	})
 
@@ -92,9 +120,23 @@ func run() error {
 	}
 	options.Layout.FastTextMetrics = fastText
 	options.Layout.AllowApproximate = allowApproximate
+	options = options.WithSVGLabels(svgLabels).WithEmbeddedFont(embedFont)
+	options = options.WithStructureDescription(describe)
+	if assetDirs != "" {
+		options = options.WithAssetDirs(filepath.SplitList(assetDirs)...)
+	}
+	if scale <= 0 {
+		return fmt.Errorf("scale must be positive, got %v", scale)
+	}
+	options = options.WithScale(scale).WithBackgroundColor(backgroundColor).WithDPI(dpi)
+	options = options.WithPDFFit(pdfFit).WithPDFAllowRaster(pdfRaster)
+	options = options.WithASCII(asciiOnly)
+	if inputPath != "" && inputPath != "-" {
+		options = options.WithHTMLTitle(strings.TrimSuffix(filepath.Base(inputPath), filepath.Ext(inputPath)))
+	}
 
 	switch lower(outputFormat) {
-	case "svg", "png":
+	case "svg", "png", "pdf", "txt", "html", "layout-json", "drawio", "excalidraw":
 	default:
 		return fmt.Errorf("unsupported output format %q", outputFormat)
 	}
@@ -110,11 +152,22 @@ func run() error {
 	if len(diagrams) == 1 {
 		return renderOne(diagrams[0], outputPath, outputFormat, options, timing)
 	}
+	if pdfPages && lower(outputFormat) == "pdf" {
+		return writePDF(options, func(options mermaid.RenderOptions) error {
+			return mermaid.WritePDFPagesFromSource(diagrams, outputPath, options)
+		})
+	}
+	if lower(outputFormat) == "html" {
+		return mermaid.WriteHTMLDocumentFromSource(diagrams, outputPath, options)
+	}
 
 	if outputPath == "" {
 		return errors.New("output path is required when rendering multiple diagrams")
 	}
 	ext := strings.ToLower(outputFormat)
+	if ext == "layout-json" {
+		ext = "json"
+	}
 	outputs, err := mermaid.ResolveMultiOutputs(outputPath, ext, len(diagrams))
 	if err != nil {
 		return err
@@ -130,7 +183,7 @@ func run() error {
 func printUsage(fs *flag.FlagSet) {
 	fmt.Fprintln(os.Stdout, "Usage: mmdg [flags]")
 	fmt.Fprintln(os.Stdout)
-	fmt.Fprintln(os.Stdout, "Render Mermaid diagrams to SVG or PNG without browser/chromium.")
+	fmt.Fprintln(os.Stdout, "Render Mermaid diagrams to SVG, PNG, PDF, HTML, text, layout JSON, draw.io or Excalidraw without browser/chromium.")
 	fmt.Fprintln(os.Stdout)
 	fmt.Fprintln(os.Stdout, "Flags:")
 	fs.SetOutput(os.Stdout)
@@ -144,7 +197,25 @@ func renderOne(diagram, outputPath, outputFormat string, options mermaid.RenderO
 		if err != nil {
 			return err
 		}
-		if err := writeOutput(result.SVG, outputPath, outputFormat); err != nil {
+		switch lower(outputFormat) {
+		case "pdf":
+			err = writePDF(options, func(options mermaid.RenderOptions) error {
+				return mermaid.WritePDFFromSource(diagram, outputPath, options)
+			})
+		case "txt":
+			err = mermaid.WriteASCIIFromSource(diagram, outputPath, options)
+		case "html":
+			err = mermaid.WriteHTMLFromSource(diagram, outputPath, options)
+		case "layout-json":
+			err = mermaid.WriteLayoutJSONFromSource(diagram, outputPath, options)
+		case "drawio":
+			err = mermaid.WriteDrawIOFromSource(diagram, outputPath, options)
+		case "excalidraw":
+			err = mermaid.WriteExcalidrawFromSource(diagram, outputPath, options)
+		default:
+			err = writeOutput(result.SVG, outputPath, outputFormat)
+		}
+		if err != nil {
 			return err
 		}
 		payload, _ := json.Marshal(map[string]any{
@@ -157,6 +228,24 @@ func renderOne(diagram, outputPath, outputFormat string, options mermaid.RenderO
 		return nil
 	}
 
+	switch lower(outputFormat) {
+	case "png":
+		return mermaid.WritePNGFromSourceWithOptions(diagram, outputPath, options)
+	case "pdf":
+		return writePDF(options, func(options mermaid.RenderOptions) error {
+			return mermaid.WritePDFFromSource(diagram, outputPath, options)
+		})
+	case "txt":
+		return mermaid.WriteASCIIFromSource(diagram, outputPath, options)
+	case "html":
+		return mermaid.WriteHTMLFromSource(diagram, outputPath, options)
+	case "layout-json":
+		return mermaid.WriteLayoutJSONFromSource(diagram, outputPath, options)
+	case "drawio":
+		return mermaid.WriteDrawIOFromSource(diagram, outputPath, options)
+	case "excalidraw":
+		return mermaid.WriteExcalidrawFromSource(diagram, outputPath, options)
+	}
 	svg, err := mermaid.RenderWithOptions(diagram, options)
 	if err != nil {
 		return err
@@ -164,6 +253,19 @@ func renderOne(diagram, outputPath, outputFormat string, options mermaid.RenderO
 	return writeOutput(svg, outputPath, outputFormat)
 }
 
+// writePDF calls write, and when a diagram has no vector PDF output warns
+// and writes it again with that diagram placed as an image, so -e pdf takes
+// every diagram kind as it does in mmdc.
+func writePDF(options mermaid.RenderOptions, write func(mermaid.RenderOptions) error) error {
+	err := write(options)
+	var noVector *mermaid.NoVectorPDFError
+	if !errors.As(err, &noVector) {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "warning: %s diagrams have no vector PDF output; placed as an image without selectable text (--pdfRaster hides this warning)\n", noVector.Kind)
+	return write(options.WithPDFAllowRaster(true))
+}
+
 func writeOutput(svg, outputPath, outputFormat string) error {
 	switch lower(outputFormat) {
 	case "svg":
@@ -2,12 +2,15 @@ package main
 
 import (
 	"bytes"
+	"image"
 	"image/png"
 	"io"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+
+	mermaid "github.com/bvolpato/mermaid-go-renderer"
 )
 
 func TestRunRendersSVGFile(t *testing.T) {
@@ -65,6 +68,43 @@ func TestRunRendersPNGFile(t *testing.T) {
 	}
 }
 
+func TestRunRendersScaledTransparentPNG(t *testing.T) {
+	tmp := t.TempDir()
+	inputPath := filepath.Join(tmp, "diagram.mmd")
+	input := "flowchart LR\nA --> B\n"
+	if err := os.WriteFile(inputPath, []byte(input), 0o644); err != nil {
+		t.Fatalf("write input: %v", err)
+	}
+
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	decode := func(args ...string) image.Image {
+		outputPath := filepath.Join(tmp, "out.png")
+		os.Args = append([]string{"mmdg", "-i", inputPath, "-o", outputPath, "-e", "png"}, args...)
+		if err := run(); err != nil {
+			t.Fatalf("run(%v) error = %v", args, err)
+		}
+		out, err := os.ReadFile(outputPath)
+		if err != nil {
+			t.Fatalf("read output: %v", err)
+		}
+		img, err := png.Decode(bytes.NewReader(out))
+		if err != nil {
+			t.Fatalf("invalid PNG output: %v", err)
+		}
+		return img
+	}
+
+	base := decode()
+	scaled := decode("-s", "2", "--backgroundColor", "transparent")
+	if got, want := scaled.Bounds().Dx(), base.Bounds().Dx()*2; got < want-1 || got > want+1 {
+		t.Fatalf("scaled width = %d, want about %d", got, want)
+	}
+	if _, _, _, a := scaled.At(0, 0).RGBA(); a != 0 {
+		t.Fatalf("expected a transparent background, got alpha %d", a)
+	}
+}
+
 func TestRunUsesViewportWidthForGanttSVGWhenRequested(t *testing.T) {
 	tmp := t.TempDir()
 	inputPath := filepath.Join(tmp, "diagram.mmd")
@@ -130,6 +170,171 @@ func TestRunMarkdownMultiOutput(t *testing.T) {
 	}
 }
 
+func TestRunMarkdownPDFPages(t *testing.T) {
+	tmp := t.TempDir()
+	inputPath := filepath.Join(tmp, "docs.md")
+	outputPath := filepath.Join(tmp, "docs.pdf")
+	input := "text\n```mermaid\nflowchart LR\nA-->B\n```\n" +
+		"```mermaid\npie\n\"A\" : 1\n```\n"
+	if err := os.WriteFile(inputPath, []byte(input), 0o644); err != nil {
+		t.Fatalf("write markdown: %v", err)
+	}
+
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	os.Args = []string{"mmdg", "-i", inputPath, "-o", outputPath, "-e", "pdf", "--pdfPages", "--pdfFit"}
+
+	if err := run(); err != nil {
+		t.Fatalf("run() error = %v", err)
+	}
+
+	out, err := os.ReadFile(outputPath)
+	if err != nil {
+		t.Fatalf("read output: %v", err)
+	}
+	if !bytes.HasPrefix(out, []byte("%PDF-")) {
+		t.Fatalf("expected PDF output")
+	}
+	if !bytes.Contains(out, []byte("/Count 2")) {
+		t.Fatalf("expected one page per diagram")
+	}
+	if bytes.Contains(out, []byte("/MediaBox [0 0 612 792]")) {
+		t.Fatalf("expected --pdfFit to size pages to the diagrams")
+	}
+}
+
+func TestRunPlacesRasterOnlyKindsInPDF(t *testing.T) {
+	tmp := t.TempDir()
+	inputPath := filepath.Join(tmp, "plan.mmd")
+	outputPath := filepath.Join(tmp, "plan.pdf")
+	input := "gantt\n  title Plan\n  section Build\n  Task :a1, 2024-01-01, 3d\n"
+	if err := os.WriteFile(inputPath, []byte(input), 0o644); err != nil {
+		t.Fatalf("write input: %v", err)
+	}
+
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	os.Args = []string{"mmdg", "-i", inputPath, "-o", outputPath, "-e", "pdf"}
+
+	if err := run(); err != nil {
+		t.Fatalf("run() error = %v", err)
+	}
+
+	out, err := os.ReadFile(outputPath)
+	if err != nil {
+		t.Fatalf("read output: %v", err)
+	}
+	if !bytes.HasPrefix(out, []byte("%PDF-")) || !bytes.Contains(out, []byte("/Subtype /Image")) {
+		t.Fatalf("expected the gantt chart to be placed in the PDF as an image")
+	}
+}
+
+func TestRunRendersASCIIText(t *testing.T) {
+	tmp := t.TempDir()
+	inputPath := filepath.Join(tmp, "diagram.mmd")
+	outputPath := filepath.Join(tmp, "diagram.txt")
+	if err := os.WriteFile(inputPath, []byte("flowchart LR\nA[Start]-->B[End]\n"), 0o644); err != nil {
+		t.Fatalf("write input: %v", err)
+	}
+
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	os.Args = []string{"mmdg", "-i", inputPath, "-o", outputPath, "-e", "txt", "--ascii"}
+
+	if err := run(); err != nil {
+		t.Fatalf("run() error = %v", err)
+	}
+
+	out, err := os.ReadFile(outputPath)
+	if err != nil {
+		t.Fatalf("read output: %v", err)
+	}
+	if !strings.Contains(string(out), "| Start +") || !strings.Contains(string(out), ">| End |") {
+		t.Fatalf("unexpected text output:\n%s", out)
+	}
+}
+
+func TestRunWritesLayoutJSON(t *testing.T) {
+	tmp := t.TempDir()
+	inputPath := filepath.Join(tmp, "diagram.mmd")
+	outputPath := filepath.Join(tmp, "diagram.json")
+	if err := os.WriteFile(inputPath, []byte("flowchart LR\nA[Start]-->B[End]\n"), 0o644); err != nil {
+		t.Fatalf("write input: %v", err)
+	}
+
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	os.Args = []string{"mmdg", "-i", inputPath, "-o", outputPath, "-e", "layout-json"}
+
+	if err := run(); err != nil {
+		t.Fatalf("run() error = %v", err)
+	}
+
+	out, err := os.ReadFile(outputPath)
+	if err != nil {
+		t.Fatalf("read output: %v", err)
+	}
+	layout, err := mermaid.UnmarshalLayout(out)
+	if err != nil {
+		t.Fatalf("UnmarshalLayout() error = %v", err)
+	}
+	if layout.Kind != mermaid.DiagramFlowchart || len(layout.Nodes) != 2 || len(layout.Edges) != 1 {
+		t.Fatalf("unexpected layout: kind %q, %d nodes, %d edges", layout.Kind, len(layout.Nodes), len(layout.Edges))
+	}
+}
+
+func TestRunWritesDrawIOAndExcalidraw(t *testing.T) {
+	tmp := t.TempDir()
+	inputPath := filepath.Join(tmp, "diagram.mmd")
+	if err := os.WriteFile(inputPath, []byte("flowchart LR\nA[Start]-->B[End]\n"), 0o644); err != nil {
+		t.Fatalf("write input: %v", err)
+	}
+
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	for format, want := range map[string]string{"drawio": "<mxGraphModel", "excalidraw": `"type": "excalidraw"`} {
+		outputPath := filepath.Join(tmp, "diagram."+format)
+		os.Args = []string{"mmdg", "-i", inputPath, "-o", outputPath, "-e", format}
+		if err := run(); err != nil {
+			t.Fatalf("run() %s error = %v", format, err)
+		}
+		out, err := os.ReadFile(outputPath)
+		if err != nil {
+			t.Fatalf("read output: %v", err)
+		}
+		if !strings.Contains(string(out), want) || !strings.Contains(string(out), "Start") {
+			t.Fatalf("unexpected %s output: %s", format, out)
+		}
+	}
+}
+
+func TestRunMarkdownHTMLPage(t *testing.T) {
+	tmp := t.TempDir()
+	inputPath := filepath.Join(tmp, "design.md")
+	outputPath := filepath.Join(tmp, "design.html")
+	input := "```mermaid\nflowchart LR\nA-->B\n```\n```mermaid\npie\n\"A\" : 1\n```\n"
+	if err := os.WriteFile(inputPath, []byte(input), 0o644); err != nil {
+		t.Fatalf("write markdown: %v", err)
+	}
+
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	os.Args = []string{"mmdg", "-i", inputPath, "-o", outputPath, "-e", "html"}
+
+	if err := run(); err != nil {
+		t.Fatalf("run() error = %v", err)
+	}
+
+	out, err := os.ReadFile(outputPath)
+	if err != nil {
+		t.Fatalf("read output: %v", err)
+	}
+	page := string(out)
+	if !strings.Contains(page, "<title>design</title>") || !strings.Contains(page, `id="diagram-2"`) {
+		t.Fatalf("expected one page titled after the input with both diagrams")
+	}
+}
+
 func TestParseAspectRatioValue(t *testing.T) {
 	cases := []struct {
 		input string
@@ -175,7 +380,7 @@ func TestRunHelp(t *testing.T) {
 	if !strings.Contains(output, "Usage: mmdg [flags]") {
 		t.Fatalf("expected usage header in help output, got: %q", output)
 	}
-	if !strings.Contains(output, "Render Mermaid diagrams to SVG or PNG without browser/chromium.") {
+	if !strings.Contains(output, "Render Mermaid diagrams to SVG, PNG, PDF, HTML, text, layout JSON, draw.io or Excalidraw without browser/chromium.") {
 		t.Fatalf("expected help description, got: %q", output)
 	}
 }
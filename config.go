@@ -1,5 +1,10 @@
 package mermaid
 
+import (
+	"net/url"
+	"strings"
+)
+
 type Theme struct {
 	Background               string
 	PrimaryColor             string
@@ -377,6 +382,252 @@ func DefaultGitGraphConfig() GitGraphConfig {
 	}
 }
 
+type SankeyNodeAlignment string
+
+const (
+	SankeyAlignJustify SankeyNodeAlignment = "justify"
+	SankeyAlignLeft    SankeyNodeAlignment = "left"
+	SankeyAlignRight   SankeyNodeAlignment = "right"
+	SankeyAlignCenter  SankeyNodeAlignment = "center"
+)
+
+const (
+	SankeyLinkColorSource   = "source"
+	SankeyLinkColorTarget   = "target"
+	SankeyLinkColorGradient = "gradient"
+)
+
+type SankeyConfig struct {
+	Width         float64
+	Height        float64
+	NodeWidth     float64
+	NodeAlignment SankeyNodeAlignment
+	// LinkColor is "source", "target", "gradient" or any literal SVG color.
+	LinkColor  string
+	ShowValues bool
+	Prefix     string
+	Suffix     string
+}
+
+func DefaultSankeyConfig() SankeyConfig {
+	return SankeyConfig{
+		Width:         600.0,
+		Height:        400.0,
+		NodeWidth:     10.0,
+		NodeAlignment: SankeyAlignJustify,
+		LinkColor:     SankeyLinkColorGradient,
+		ShowValues:    true,
+	}
+}
+
+func (c SankeyConfig) withOverrides(section map[string]any) SankeyConfig {
+	if v, ok := configFloat(section, "width"); ok && v > 0 {
+		c.Width = v
+	}
+	if v, ok := configFloat(section, "height"); ok && v > 0 {
+		c.Height = v
+	}
+	if v, ok := configString(section, "nodeAlignment"); ok {
+		switch align := SankeyNodeAlignment(lower(v)); align {
+		case SankeyAlignJustify, SankeyAlignLeft, SankeyAlignRight, SankeyAlignCenter:
+			c.NodeAlignment = align
+		}
+	}
+	if v, ok := configString(section, "linkColor"); ok && strings.TrimSpace(v) != "" {
+		c.LinkColor = strings.TrimSpace(v)
+	}
+	if v, ok := configBool(section, "showValues"); ok {
+		c.ShowValues = v
+	}
+	if v, ok := configString(section, "prefix"); ok {
+		c.Prefix = v
+	}
+	if v, ok := configString(section, "suffix"); ok {
+		c.Suffix = v
+	}
+	return c
+}
+
+type TreemapTile string
+
+const (
+	TreemapTileSlice    TreemapTile = "slice"
+	TreemapTileSquarify TreemapTile = "squarify"
+)
+
+type TreemapConfig struct {
+	Padding    float64
+	ShowValues bool
+	// ValueFormat is a d3-format specifier such as ",", ".1f" or "$,.2f".
+	ValueFormat string
+	Tile        TreemapTile
+}
+
+func DefaultTreemapConfig() TreemapConfig {
+	return TreemapConfig{
+		Padding:     10.0,
+		ShowValues:  true,
+		ValueFormat: ",",
+		Tile:        TreemapTileSlice,
+	}
+}
+
+func (c TreemapConfig) withOverrides(section map[string]any) TreemapConfig {
+	if v, ok := configFloat(section, "padding"); ok && v >= 0 {
+		c.Padding = v
+	}
+	if v, ok := configBool(section, "showValues"); ok {
+		c.ShowValues = v
+	}
+	if v, ok := configString(section, "valueFormat"); ok && strings.TrimSpace(v) != "" {
+		c.ValueFormat = strings.TrimSpace(v)
+	}
+	if v, ok := configString(section, "tile"); ok {
+		switch tile := TreemapTile(lower(v)); tile {
+		case TreemapTileSlice, TreemapTileSquarify:
+			c.Tile = tile
+		}
+	}
+	return c
+}
+
+type KanbanConfig struct {
+	// TicketBaseURL turns card tickets into links. A "#TICKET#" placeholder
+	// is replaced with the ticket; otherwise the ticket is appended.
+	TicketBaseURL string
+	WrapWidth     float64
+}
+
+func DefaultKanbanConfig() KanbanConfig {
+	return KanbanConfig{
+		WrapWidth: 175.0,
+	}
+}
+
+func (c KanbanConfig) withOverrides(section map[string]any) KanbanConfig {
+	if v, ok := configString(section, "ticketBaseUrl"); ok {
+		c.TicketBaseURL = strings.TrimSpace(v)
+	}
+	if v, ok := configFloat(section, "wrapWidth"); ok && v > 0 {
+		c.WrapWidth = v
+	}
+	return c
+}
+
+// ticketURL expands TicketBaseURL for a card ticket.
+func (c KanbanConfig) ticketURL(ticket string) string {
+	ticket = strings.TrimSpace(ticket)
+	if c.TicketBaseURL == "" || ticket == "" {
+		return ""
+	}
+	if strings.Contains(c.TicketBaseURL, "#TICKET#") {
+		return strings.ReplaceAll(c.TicketBaseURL, "#TICKET#", url.PathEscape(ticket))
+	}
+	return c.TicketBaseURL + url.PathEscape(ticket)
+}
+
+type RadarConfig struct {
+	Width           float64
+	Height          float64
+	MarginTop       float64
+	MarginRight     float64
+	MarginBottom    float64
+	MarginLeft      float64
+	AxisScaleFactor float64
+	AxisLabelFactor float64
+	CurveTension    float64
+	// SmoothPolygon draws curves as smoothed paths on polygon graticules too.
+	// It is enabled when the diagram config sets curveTension explicitly.
+	SmoothPolygon bool
+	// CurveColors overrides the curve palette by index (themeVariables
+	// cScale0..cScale11); empty entries keep the default color.
+	CurveColors  []string
+	CurveOpacity float64
+}
+
+func DefaultRadarConfig() RadarConfig {
+	return RadarConfig{
+		Width:           600.0,
+		Height:          600.0,
+		MarginTop:       50.0,
+		MarginRight:     50.0,
+		MarginBottom:    50.0,
+		MarginLeft:      50.0,
+		AxisScaleFactor: 1.0,
+		AxisLabelFactor: 1.05,
+		CurveTension:    0.17,
+		CurveOpacity:    0.5,
+	}
+}
+
+// withDefaults fills every zero field with its default. It runs before the
+// diagram's own config is applied, so zero margins, tension or opacity set
+// there are kept.
+func (c RadarConfig) withDefaults() RadarConfig {
+	defaults := DefaultRadarConfig()
+	c.Width = defaultFloat(c.Width, defaults.Width)
+	c.Height = defaultFloat(c.Height, defaults.Height)
+	c.MarginTop = defaultFloat(c.MarginTop, defaults.MarginTop)
+	c.MarginRight = defaultFloat(c.MarginRight, defaults.MarginRight)
+	c.MarginBottom = defaultFloat(c.MarginBottom, defaults.MarginBottom)
+	c.MarginLeft = defaultFloat(c.MarginLeft, defaults.MarginLeft)
+	c.AxisScaleFactor = defaultFloat(c.AxisScaleFactor, defaults.AxisScaleFactor)
+	c.AxisLabelFactor = defaultFloat(c.AxisLabelFactor, defaults.AxisLabelFactor)
+	c.CurveTension = defaultFloat(c.CurveTension, defaults.CurveTension)
+	c.CurveOpacity = defaultFloat(c.CurveOpacity, defaults.CurveOpacity)
+	return c
+}
+
+func (c RadarConfig) withOverrides(section map[string]any) RadarConfig {
+	positive := map[string]*float64{
+		"width":           &c.Width,
+		"height":          &c.Height,
+		"axisScaleFactor": &c.AxisScaleFactor,
+		"axisLabelFactor": &c.AxisLabelFactor,
+	}
+	for key, dst := range positive {
+		if v, ok := configFloat(section, key); ok && v > 0 {
+			*dst = v
+		}
+	}
+	margins := map[string]*float64{
+		"marginTop":    &c.MarginTop,
+		"marginRight":  &c.MarginRight,
+		"marginBottom": &c.MarginBottom,
+		"marginLeft":   &c.MarginLeft,
+	}
+	for key, dst := range margins {
+		if v, ok := configFloat(section, key); ok && v >= 0 {
+			*dst = v
+		}
+	}
+	if v, ok := configFloat(section, "curveTension"); ok && v >= 0 {
+		c.CurveTension = v
+		c.SmoothPolygon = true
+	}
+	return c
+}
+
+// withThemeVariables applies the cScale palette and radar.curveOpacity theme
+// variables.
+func (c RadarConfig) withThemeVariables(vars map[string]any) RadarConfig {
+	colors := make([]string, 12)
+	last := -1
+	for i := range colors {
+		if v, ok := configString(vars, "cScale"+intString(i)); ok && strings.TrimSpace(v) != "" {
+			colors[i] = strings.TrimSpace(v)
+			last = i
+		}
+	}
+	if last >= 0 {
+		c.CurveColors = colors[:last+1]
+	}
+	if v, ok := configFloat(configSection(vars, "radar"), "curveOpacity"); ok && v >= 0 {
+		c.CurveOpacity = min(v, 1)
+	}
+	return c
+}
+
 type LayoutConfig struct {
 	NodeSpacing          float64
 	RankSpacing          float64
@@ -388,6 +639,35 @@ type LayoutConfig struct {
 	AllowApproximate     bool
 	Pie                  PieConfig
 	GitGraph             GitGraphConfig
+	Sankey               SankeyConfig
+	Treemap              TreemapConfig
+	Kanban               KanbanConfig
+	Radar                RadarConfig
+	// Curve selects the edge interpolation of dagre-based diagrams
+	// (flowchart, state, requirement, ER) by mermaid name: basis, linear,
+	// cardinal, catmullRom, monotoneX, monotoneY, natural, step, stepBefore,
+	// stepAfter, bumpX or bumpY. Empty keeps each diagram's default; a
+	// per-edge curve takes precedence.
+	Curve string
+	// WrappingWidth is the width, in pixels, past which flowchart node and
+	// edge labels wrap onto further lines. Zero disables wrapping.
+	WrappingWidth float64
+	// SVGLabels renders every label as <text>/<tspan> instead of
+	// foreignObject HTML, like mermaid's htmlLabels: false, so the SVG
+	// displays in tools without an HTML engine.
+	SVGLabels bool
+	// DescribeStructure generates a text description of the diagram's
+	// structure for screen readers when the source has no accDescr.
+	DescribeStructure bool
+	// EmbedFont subsets the font text was measured with to the characters
+	// of the diagram and embeds it in SVG output, so labels fit their
+	// shapes on machines without that font.
+	EmbedFont bool
+	// AssetDirs lists the directories whose files flowchart img nodes may
+	// embed. Relative paths are looked up in each directory in turn and
+	// absolute paths must lie inside one. When empty, no local file is read:
+	// only data: URIs and remote URLs are used.
+	AssetDirs []string
 }
 
 func DefaultLayoutConfig() LayoutConfig {
@@ -397,9 +677,54 @@ func DefaultLayoutConfig() LayoutConfig {
 		LabelLineHeight: 1.15,
 		Pie:             DefaultPieConfig(),
 		GitGraph:        DefaultGitGraphConfig(),
+		Sankey:          DefaultSankeyConfig(),
+		Treemap:         DefaultTreemapConfig(),
+		Kanban:          DefaultKanbanConfig(),
+		Radar:           DefaultRadarConfig(),
+		WrappingWidth:   defaultWrappingWidth,
 	}
 }
 
+// withDiagramConfig applies the configuration declared inside the diagram
+// source (front matter or init directives) on top of the caller's config.
+func (c LayoutConfig) withDiagramConfig(config map[string]any) LayoutConfig {
+	if len(config) == 0 {
+		return c
+	}
+	if section := configSection(config, "sankey"); section != nil {
+		c.Sankey = c.Sankey.withOverrides(section)
+	}
+	if section := configSection(config, "treemap"); section != nil {
+		c.Treemap = c.Treemap.withOverrides(section)
+	}
+	if section := configSection(config, "kanban"); section != nil {
+		c.Kanban = c.Kanban.withOverrides(section)
+	}
+	if section := configSection(config, "radar"); section != nil {
+		c.Radar = c.Radar.withOverrides(section)
+	}
+	if section := configSection(config, "flowchart"); section != nil {
+		if v, ok := configString(section, "curve"); ok {
+			if curve, known := normalizeCurveName(v); known {
+				c.Curve = curve
+			}
+		}
+		if v, ok := configFloat(section, "wrappingWidth"); ok && v >= 0 {
+			c.WrappingWidth = v
+		}
+		if v, ok := configBool(section, "htmlLabels"); ok {
+			c.SVGLabels = !v
+		}
+	}
+	if vars := configSection(config, "themeVariables"); vars != nil {
+		c.Radar = c.Radar.withThemeVariables(vars)
+	}
+	if v, ok := configBool(config, "htmlLabels"); ok {
+		c.SVGLabels = !v
+	}
+	return c
+}
+
 type RenderConfig struct {
 	Width      float64
 	Height     float64
@@ -434,6 +759,64 @@ func DefaultConfig() Config {
 type RenderOptions struct {
 	Theme  Theme
 	Layout LayoutConfig
+	Raster RasterOptions
+	PDF    PDFOptions
+	Text   TextOptions
+	HTML   HTMLOptions
+}
+
+// RasterOptions controls PNG output. The zero value renders at the
+// diagram's intrinsic size on white.
+type RasterOptions struct {
+	// Scale multiplies the intrinsic pixel size; zero means 1.
+	Scale float64
+	// Background is the canvas color. "transparent" leaves the canvas
+	// clear and an empty value means white. PDF pages are filled with it
+	// too.
+	Background string
+	// DPI is recorded in the PNG pHYs chunk; zero means 96 times Scale so
+	// scaled images keep their physical size.
+	DPI float64
+}
+
+func (r RasterOptions) scale() float64 {
+	if r.Scale > 0 {
+		return r.Scale
+	}
+	return 1
+}
+
+func (r RasterOptions) dpi() float64 {
+	if r.DPI > 0 {
+		return r.DPI
+	}
+	return 96 * r.scale()
+}
+
+// PDFOptions controls PDF output. The zero value puts each diagram on a US
+// Letter page, shrunk to fit inside half-inch margins.
+type PDFOptions struct {
+	// Fit sizes each page to its diagram, like mmdc's --pdfFit.
+	Fit bool
+	// AllowRaster places diagram kinds the layout painter does not draw as
+	// an image of their SVG. Without it those kinds are a
+	// *NoVectorPDFError rather than a page without vectors or selectable
+	// text.
+	AllowRaster bool
+}
+
+// TextOptions controls RenderASCII output.
+type TextOptions struct {
+	// ASCII draws with plain ASCII characters instead of Unicode box
+	// drawing, for terminals and fonts without it. Labels are unchanged.
+	ASCII bool
+}
+
+// HTMLOptions controls RenderHTML output.
+type HTMLOptions struct {
+	// Title is the page title. When empty, a single diagram's own title is
+	// used.
+	Title string
 }
 
 func DefaultRenderOptions() RenderOptions {
@@ -496,3 +879,77 @@ func (o RenderOptions) WithViewportSize(width, height float64) RenderOptions {
 	}
 	return o
 }
+
+// WithSVGLabels selects pure SVG text labels instead of foreignObject HTML.
+func (o RenderOptions) WithSVGLabels(enabled bool) RenderOptions {
+	o.Layout.SVGLabels = enabled
+	return o
+}
+
+// WithEmbeddedFont embeds a subset of the measuring font in SVG output.
+func (o RenderOptions) WithEmbeddedFont(enabled bool) RenderOptions {
+	o.Layout.EmbedFont = enabled
+	return o
+}
+
+// WithAssetDirs allows flowchart img nodes to embed local files from the
+// given directories.
+func (o RenderOptions) WithAssetDirs(dirs ...string) RenderOptions {
+	o.Layout.AssetDirs = dirs
+	return o
+}
+
+// WithScale renders PNG output at scale times the intrinsic size.
+func (o RenderOptions) WithScale(scale float64) RenderOptions {
+	if scale > 0 {
+		o.Raster.Scale = scale
+	}
+	return o
+}
+
+// WithBackgroundColor sets the PNG canvas color; "transparent" keeps the
+// canvas clear.
+func (o RenderOptions) WithBackgroundColor(background string) RenderOptions {
+	o.Raster.Background = strings.TrimSpace(background)
+	return o
+}
+
+// WithDPI sets the resolution recorded in PNG output.
+func (o RenderOptions) WithDPI(dpi float64) RenderOptions {
+	if dpi > 0 {
+		o.Raster.DPI = dpi
+	}
+	return o
+}
+
+// WithPDFFit sizes PDF pages to their diagram instead of US Letter.
+func (o RenderOptions) WithPDFFit(fit bool) RenderOptions {
+	o.PDF.Fit = fit
+	return o
+}
+
+// WithPDFAllowRaster lets PDF output place diagram kinds the layout painter
+// does not draw as an image. See PDFOptions.AllowRaster.
+func (o RenderOptions) WithPDFAllowRaster(allow bool) RenderOptions {
+	o.PDF.AllowRaster = allow
+	return o
+}
+
+// WithASCII makes RenderASCII draw with plain ASCII characters.
+func (o RenderOptions) WithASCII(ascii bool) RenderOptions {
+	o.Text.ASCII = ascii
+	return o
+}
+
+// WithHTMLTitle sets the page title of HTML output.
+func (o RenderOptions) WithHTMLTitle(title string) RenderOptions {
+	o.HTML.Title = title
+	return o
+}
+
+// WithStructureDescription generates an accessible description of the
+// diagram structure for diagrams without accDescr.
+func (o RenderOptions) WithStructureDescription(enabled bool) RenderOptions {
+	o.Layout.DescribeStructure = enabled
+	return o
+}
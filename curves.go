@@ -0,0 +1,405 @@
+package mermaid
+
+import (
+	"math"
+	"strings"
+
+	"github.com/bvolpato/mermaid-go-renderer/dagre"
+)
+
+// curvePathBuilder accumulates SVG path commands in the d3-shape style. With
+// swap set it writes x and y exchanged, which is how d3 derives the Y
+// variants of the monotone curve from the X ones.
+type curvePathBuilder struct {
+	b    strings.Builder
+	swap bool
+}
+
+func (p *curvePathBuilder) pair(x, y float64) string {
+	if p.swap {
+		x, y = y, x
+	}
+	return formatFloat(x) + "," + formatFloat(y)
+}
+
+func (p *curvePathBuilder) moveTo(x, y float64) {
+	p.b.WriteString("M" + p.pair(x, y))
+}
+
+func (p *curvePathBuilder) lineTo(x, y float64) {
+	p.b.WriteString("L" + p.pair(x, y))
+}
+
+func (p *curvePathBuilder) bezierTo(x1, y1, x2, y2, x, y float64) {
+	p.b.WriteString("C" + p.pair(x1, y1) + "," + p.pair(x2, y2) + "," + p.pair(x, y))
+}
+
+// curveNames lists the interpolations accepted by flowchart.curve and by the
+// per-edge `curve` metadata, keyed by their lower-cased mermaid name.
+var curveNames = map[string]string{
+	"basis":      "basis",
+	"bumpx":      "bumpX",
+	"bumpy":      "bumpY",
+	"cardinal":   "cardinal",
+	"catmullrom": "catmullRom",
+	"linear":     "linear",
+	"monotonex":  "monotoneX",
+	"monotoney":  "monotoneY",
+	"natural":    "natural",
+	"step":       "step",
+	"stepafter":  "stepAfter",
+	"stepbefore": "stepBefore",
+}
+
+// normalizeCurveName returns the canonical mermaid curve name, or false when
+// the name is not a known interpolation.
+func normalizeCurveName(name string) (string, bool) {
+	canonical, ok := curveNames[lower(strings.TrimSpace(name))]
+	return canonical, ok
+}
+
+// resolveEdgeCurve picks the per-edge curve over the configured one.
+func resolveEdgeCurve(edgeCurve, configCurve string) string {
+	if curve, ok := normalizeCurveName(edgeCurve); ok {
+		return curve
+	}
+	if curve, ok := normalizeCurveName(configCurve); ok {
+		return curve
+	}
+	return ""
+}
+
+// edgeCurvePath draws dagre edge points with the named curve, falling back
+// to the smoothed spline of dagreEdgePath when no curve is selected.
+func edgeCurvePath(points []dagre.Point, curve string) string {
+	if curve == "" {
+		return dagreEdgePath(points)
+	}
+	return curvePath(points, curve)
+}
+
+// edgePoints copies dagre edge points into the layout.
+func edgePoints(points []dagre.Point) []Point {
+	out := make([]Point, len(points))
+	for i, p := range points {
+		out[i] = Point{X: p.X, Y: p.Y}
+	}
+	return out
+}
+
+// curvePath interpolates points with one of mermaid's named curves (see
+// curveNames). Unknown names fall back to straight segments.
+func curvePath(points []dagre.Point, curve string) string {
+	if len(points) == 0 {
+		return ""
+	}
+	var p curvePathBuilder
+	p.moveTo(points[0].X, points[0].Y)
+	if len(points) == 1 {
+		return p.b.String()
+	}
+	switch lower(strings.TrimSpace(curve)) {
+	case "basis":
+		curveBasis(&p, points)
+	case "bumpx":
+		curveBump(&p, points, true)
+	case "bumpy":
+		curveBump(&p, points, false)
+	case "cardinal":
+		curveCardinal(&p, points, 0)
+	case "catmullrom":
+		curveCatmullRom(&p, points, 0.5)
+	case "monotonex":
+		curveMonotoneX(&p, points)
+	case "monotoney":
+		swapped := make([]dagre.Point, len(points))
+		for i, pt := range points {
+			swapped[i] = dagre.Point{X: pt.Y, Y: pt.X}
+		}
+		p.swap = true
+		curveMonotoneX(&p, swapped)
+	case "natural":
+		curveNatural(&p, points)
+	case "step":
+		curveStep(&p, points, 0.5)
+	case "stepbefore":
+		curveStep(&p, points, 0)
+	case "stepafter":
+		curveStep(&p, points, 1)
+	default:
+		for _, pt := range points[1:] {
+			p.lineTo(pt.X, pt.Y)
+		}
+	}
+	return p.b.String()
+}
+
+// dagreEdgePath smooths dagre edge points with a uniform Catmull-Rom spline.
+// It is the interpolation used when no curve is configured.
+func dagreEdgePath(points []dagre.Point) string {
+	if len(points) == 0 {
+		return ""
+	}
+	if len(points) == 1 {
+		return "M " + formatFloat(points[0].X) + " " + formatFloat(points[0].Y)
+	}
+	if len(points) == 2 {
+		return "M " + formatFloat(points[0].X) + " " + formatFloat(points[0].Y) +
+			" L " + formatFloat(points[1].X) + " " + formatFloat(points[1].Y)
+	}
+	d := "M " + formatFloat(points[0].X) + " " + formatFloat(points[0].Y)
+	for i := 0; i < len(points)-1; i++ {
+		p0 := points[max(0, i-1)]
+		p1 := points[i]
+		p2 := points[i+1]
+		p3 := points[min(len(points)-1, i+2)]
+		cp1x := p1.X + (p2.X-p0.X)/6
+		cp1y := p1.Y + (p2.Y-p0.Y)/6
+		cp2x := p2.X - (p3.X-p1.X)/6
+		cp2y := p2.Y - (p3.Y-p1.Y)/6
+		d += " C " + formatFloat(cp1x) + " " + formatFloat(cp1y) +
+			" " + formatFloat(cp2x) + " " + formatFloat(cp2y) +
+			" " + formatFloat(p2.X) + " " + formatFloat(p2.Y)
+	}
+	return d
+}
+
+// curveBasis is d3.curveBasis: a cubic B-spline clamped to both endpoints.
+func curveBasis(p *curvePathBuilder, points []dagre.Point) {
+	if len(points) == 2 {
+		p.lineTo(points[1].X, points[1].Y)
+		return
+	}
+	first, second := points[0], points[1]
+	p.lineTo((5*first.X+second.X)/6, (5*first.Y+second.Y)/6)
+	bezier := func(p0, p1, next dagre.Point) {
+		p.bezierTo(
+			(2*p0.X+p1.X)/3, (2*p0.Y+p1.Y)/3,
+			(p0.X+2*p1.X)/3, (p0.Y+2*p1.Y)/3,
+			(p0.X+4*p1.X+next.X)/6, (p0.Y+4*p1.Y+next.Y)/6,
+		)
+	}
+	for i := 2; i < len(points); i++ {
+		bezier(points[i-2], points[i-1], points[i])
+	}
+	last := points[len(points)-1]
+	bezier(points[len(points)-2], last, last)
+	p.lineTo(last.X, last.Y)
+}
+
+// curveCardinal is d3.curveCardinal with the given tension.
+func curveCardinal(p *curvePathBuilder, points []dagre.Point, tension float64) {
+	if len(points) == 2 {
+		p.lineTo(points[1].X, points[1].Y)
+		return
+	}
+	k := (1 - tension) / 6
+	for i := 0; i < len(points)-1; i++ {
+		p0 := points[max(0, i-1)]
+		p1 := points[i]
+		p2 := points[i+1]
+		p3 := points[min(len(points)-1, i+2)]
+		if i == 0 {
+			p0 = p2
+		}
+		if i == len(points)-2 {
+			p3 = p1
+		}
+		p.bezierTo(
+			p1.X+k*(p2.X-p0.X), p1.Y+k*(p2.Y-p0.Y),
+			p2.X+k*(p1.X-p3.X), p2.Y+k*(p1.Y-p3.Y),
+			p2.X, p2.Y,
+		)
+	}
+}
+
+// curveStep is d3.curveStep (t=0.5), curveStepBefore (t=0) and
+// curveStepAfter (t=1).
+func curveStep(p *curvePathBuilder, points []dagre.Point, t float64) {
+	for i := 1; i < len(points); i++ {
+		prev, pt := points[i-1], points[i]
+		if t <= 0 {
+			p.lineTo(prev.X, pt.Y)
+			p.lineTo(pt.X, pt.Y)
+			continue
+		}
+		x := prev.X*(1-t) + pt.X*t
+		p.lineTo(x, prev.Y)
+		p.lineTo(x, pt.Y)
+	}
+	if t > 0 && t < 1 {
+		last := points[len(points)-1]
+		p.lineTo(last.X, last.Y)
+	}
+}
+
+// curveMonotoneX is d3.curveMonotoneX: a cubic spline that preserves
+// monotonicity in y, assuming x is monotonic.
+func curveMonotoneX(p *curvePathBuilder, points []dagre.Point) {
+	pts := make([]dagre.Point, 0, len(points))
+	for _, pt := range points {
+		if n := len(pts); n > 0 && pts[n-1] == pt {
+			continue
+		}
+		pts = append(pts, pt)
+	}
+	if len(pts) < 2 {
+		return
+	}
+	if len(pts) == 2 {
+		p.lineTo(pts[1].X, pts[1].Y)
+		return
+	}
+	segment := func(p0, p1 dagre.Point, t0, t1 float64) {
+		dx := (p1.X - p0.X) / 3
+		p.bezierTo(p0.X+dx, p0.Y+dx*t0, p1.X-dx, p1.Y-dx*t1, p1.X, p1.Y)
+	}
+	tangents := make([]float64, len(pts))
+	for i := 1; i < len(pts)-1; i++ {
+		tangents[i] = monotoneSlope3(pts[i-1], pts[i], pts[i+1])
+	}
+	tangents[0] = monotoneSlope2(pts[0], pts[1], tangents[1])
+	last := len(pts) - 1
+	tangents[last] = monotoneSlope2(pts[last-1], pts[last], tangents[last-1])
+	for i := 0; i < last; i++ {
+		segment(pts[i], pts[i+1], tangents[i], tangents[i+1])
+	}
+}
+
+func monotoneSlope3(p0, p1, p2 dagre.Point) float64 {
+	h0 := p1.X - p0.X
+	h1 := p2.X - p1.X
+	s0 := monotoneDivide(p1.Y-p0.Y, h0, h1)
+	s1 := monotoneDivide(p2.Y-p1.Y, h1, h0)
+	slope := (s0*h1 + s1*h0) / (h0 + h1)
+	v := (monotoneSign(s0) + monotoneSign(s1)) * min(math.Abs(s0), math.Abs(s1), 0.5*math.Abs(slope))
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0
+	}
+	return v
+}
+
+// monotoneDivide mirrors d3's `dy / (h || other < 0 && -0)`.
+func monotoneDivide(dy, h, other float64) float64 {
+	if h != 0 {
+		return dy / h
+	}
+	if other < 0 {
+		return dy / math.Copysign(0, -1)
+	}
+	return dy / 0.0
+}
+
+func monotoneSign(v float64) float64 {
+	if v < 0 {
+		return -1
+	}
+	return 1
+}
+
+func monotoneSlope2(p0, p1 dagre.Point, t float64) float64 {
+	h := p1.X - p0.X
+	if h == 0 {
+		return t
+	}
+	return (3*(p1.Y-p0.Y)/h - t) / 2
+}
+
+// curveBump is d3.curveBumpX (horizontal tangents) and d3.curveBumpY.
+func curveBump(p *curvePathBuilder, points []dagre.Point, horizontal bool) {
+	for i := 1; i < len(points); i++ {
+		prev, pt := points[i-1], points[i]
+		if horizontal {
+			mx := (prev.X + pt.X) / 2
+			p.bezierTo(mx, prev.Y, mx, pt.Y, pt.X, pt.Y)
+			continue
+		}
+		my := (prev.Y + pt.Y) / 2
+		p.bezierTo(prev.X, my, pt.X, my, pt.X, pt.Y)
+	}
+}
+
+// curveCatmullRom is d3.curveCatmullRom with the given alpha (0.5 gives the
+// centripetal spline mermaid uses).
+func curveCatmullRom(p *curvePathBuilder, points []dagre.Point, alpha float64) {
+	if len(points) == 2 {
+		p.lineTo(points[1].X, points[1].Y)
+		return
+	}
+	const epsilon = 1e-12
+	// Extend both ends by repeating the endpoints, as d3 does when the line
+	// starts and ends.
+	ext := make([]dagre.Point, 0, len(points)+2)
+	ext = append(ext, points[0])
+	ext = append(ext, points...)
+	ext = append(ext, points[len(points)-1])
+	dist := func(a, b dagre.Point) (float64, float64) {
+		d2a := math.Pow((a.X-b.X)*(a.X-b.X)+(a.Y-b.Y)*(a.Y-b.Y), alpha)
+		return math.Sqrt(d2a), d2a
+	}
+	for i := 1; i < len(ext)-2; i++ {
+		p0, p1, p2, p3 := ext[i-1], ext[i], ext[i+1], ext[i+2]
+		l01, l01Sq := dist(p0, p1)
+		l12, l12Sq := dist(p1, p2)
+		l23, l23Sq := dist(p2, p3)
+		c1, c2 := p1, p2
+		if l01 > epsilon {
+			a := 2*l01Sq + 3*l01*l12 + l12Sq
+			n := 3 * l01 * (l01 + l12)
+			c1.X = (p1.X*a - p0.X*l12Sq + p2.X*l01Sq) / n
+			c1.Y = (p1.Y*a - p0.Y*l12Sq + p2.Y*l01Sq) / n
+		}
+		if l23 > epsilon {
+			b := 2*l23Sq + 3*l23*l12 + l12Sq
+			m := 3 * l23 * (l23 + l12)
+			c2.X = (p2.X*b + p1.X*l23Sq - p3.X*l12Sq) / m
+			c2.Y = (p2.Y*b + p1.Y*l23Sq - p3.Y*l12Sq) / m
+		}
+		p.bezierTo(c1.X, c1.Y, c2.X, c2.Y, p2.X, p2.Y)
+	}
+}
+
+// curveNatural is d3.curveNatural: a natural cubic spline with zero second
+// derivatives at the ends.
+func curveNatural(p *curvePathBuilder, points []dagre.Point) {
+	if len(points) == 2 {
+		p.lineTo(points[1].X, points[1].Y)
+		return
+	}
+	xs := make([]float64, len(points))
+	ys := make([]float64, len(points))
+	for i, pt := range points {
+		xs[i], ys[i] = pt.X, pt.Y
+	}
+	ax, bx := naturalControlPoints(xs)
+	ay, by := naturalControlPoints(ys)
+	for i := 1; i < len(points); i++ {
+		p.bezierTo(ax[i-1], ay[i-1], bx[i-1], by[i-1], xs[i], ys[i])
+	}
+}
+
+func naturalControlPoints(x []float64) ([]float64, []float64) {
+	n := len(x) - 1
+	a := make([]float64, n)
+	b := make([]float64, n)
+	r := make([]float64, n)
+	a[0], b[0], r[0] = 0, 2, x[0]+2*x[1]
+	for i := 1; i < n-1; i++ {
+		a[i], b[i], r[i] = 1, 4, 4*x[i]+2*x[i+1]
+	}
+	a[n-1], b[n-1], r[n-1] = 2, 7, 8*x[n-1]+x[n]
+	for i := 1; i < n; i++ {
+		m := a[i] / b[i-1]
+		b[i] -= m
+		r[i] -= m * r[i-1]
+	}
+	a[n-1] = r[n-1] / b[n-1]
+	for i := n - 2; i >= 0; i-- {
+		a[i] = (r[i] - a[i+1]) / b[i]
+	}
+	b[n-1] = (x[n] + a[n-1]) / 2
+	for i := 0; i < n-1; i++ {
+		b[i] = 2*x[i+1] - a[i+1]
+	}
+	return a, b
+}
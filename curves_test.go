@@ -0,0 +1,82 @@
+package mermaid
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bvolpato/mermaid-go-renderer/dagre"
+)
+
+func TestCurvePath(t *testing.T) {
+	points := []dagre.Point{{X: 0, Y: 0}, {X: 12, Y: 12}, {X: 24, Y: 0}}
+	cases := []struct {
+		curve string
+		want  string
+	}{
+		{"linear", "M0,0L12,12L24,0"},
+		{"basis", "M0,0L2,2C4,4,8,8,12,8C16,8,20,4,22,2L24,0"},
+		{"step", "M0,0L6,0L6,12L18,12L18,0L24,0"},
+		{"stepBefore", "M0,0L0,12L12,12L12,0L24,0"},
+		{"stepAfter", "M0,0L12,0L12,12L24,12L24,0"},
+		{"cardinal", "M0,0C0,0,8,12,12,12C16,12,24,0,24,0"},
+		{"monotoneX", "M0,0C4,6,8,12,12,12C16,12,20,6,24,0"},
+		{"monotoneY", "M0,0C6,4,12,8,12,12C12,8,18,4,24,0"},
+		{"catmullRom", "M0,0C0,0,8,12,12,12C16,12,24,0,24,0"},
+		{"natural", "M0,0C4,6,8,12,12,12C16,12,20,6,24,0"},
+		{"bumpX", "M0,0C6,0,6,12,12,12C18,12,18,0,24,0"},
+		{"bumpY", "M0,0C0,6,12,6,12,12C12,6,24,6,24,0"},
+		{"unknown", "M0,0L12,12L24,0"},
+	}
+	for _, tc := range cases {
+		if got := curvePath(points, tc.curve); got != tc.want {
+			t.Errorf("curvePath(%q) = %q, want %q", tc.curve, got, tc.want)
+		}
+	}
+}
+
+func TestFlowchartCurveConfigAndEdgeOverride(t *testing.T) {
+	parsed, err := ParseMermaid(`%%{init: {"flowchart": {"curve": "stepBefore"}}}%%
+flowchart TD
+  A --> B
+  B e1@--> C
+  e1@{ curve: linear }`)
+	if err != nil {
+		t.Fatalf("ParseMermaid() error = %v", err)
+	}
+	layout := ComputeLayout(&parsed.Graph, DefaultRenderOptions().Theme, DefaultLayoutConfig())
+	if len(layout.Edges) != 2 {
+		t.Fatalf("expected 2 edges, got %d", len(layout.Edges))
+	}
+	if got := layout.Edges[0].Curve; got != "stepBefore" {
+		t.Fatalf("config curve = %q, want stepBefore", got)
+	}
+	if got := layout.Edges[1].Curve; got != "linear" {
+		t.Fatalf("edge curve = %q, want linear override", got)
+	}
+
+	config := DefaultLayoutConfig()
+	config.Curve = "natural"
+	plain, _ := ParseMermaid("stateDiagram-v2\n  A --> B\n  B --> C")
+	layout = ComputeLayout(&plain.Graph, DefaultRenderOptions().Theme, config)
+	if len(layout.Edges) == 0 || layout.Edges[0].Curve != "natural" {
+		t.Fatalf("expected LayoutConfig.Curve on state edges, got %+v", layout.Edges)
+	}
+}
+
+func TestStateUnknownCurveKeepsTheDefault(t *testing.T) {
+	parsed, err := ParseMermaid("stateDiagram-v2\n  A --> B\n  B --> C\n  A --> C")
+	if err != nil {
+		t.Fatalf("ParseMermaid() error = %v", err)
+	}
+	config := DefaultLayoutConfig()
+	config.Curve = "squiggly"
+	layout := ComputeLayout(&parsed.Graph, DefaultRenderOptions().Theme, config)
+	if len(layout.Paths) != len(layout.Edges) || len(layout.Paths) == 0 {
+		t.Fatalf("expected one path per edge, got %d paths and %d edges", len(layout.Paths), len(layout.Edges))
+	}
+	for i, path := range layout.Paths {
+		if path.D != layout.Edges[i].D || !strings.Contains(path.D, " C ") {
+			t.Fatalf("path %d = %q, want the default spline %q", i, path.D, layout.Edges[i].D)
+		}
+	}
+}
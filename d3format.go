@@ -0,0 +1,376 @@
+package mermaid
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// d3FormatSpec is a parsed d3-format specifier:
+// [[fill]align][sign][symbol][0][width][,][.precision][~][type].
+type d3FormatSpec struct {
+	fill      string
+	align     byte
+	sign      byte
+	symbol    byte
+	zero      bool
+	width     int
+	comma     bool
+	precision int
+	trim      bool
+	kind      byte
+}
+
+var d3SIPrefixes = []string{"y", "z", "a", "f", "p", "n", "µ", "m", "", "k", "M", "G", "T", "P", "E", "Z", "Y"}
+
+func parseD3FormatSpec(specifier string) (d3FormatSpec, bool) {
+	spec := d3FormatSpec{fill: " ", align: '>', sign: '-', precision: -1}
+	s := specifier
+
+	if r, size := utf8.DecodeRuneInString(s); size > 0 && len(s) > size && isD3Align(s[size]) {
+		spec.fill = string(r)
+		spec.align = s[size]
+		s = s[size+1:]
+	} else if len(s) > 0 && isD3Align(s[0]) {
+		spec.align = s[0]
+		s = s[1:]
+	}
+	if len(s) > 0 && strings.IndexByte("+-( ", s[0]) >= 0 {
+		spec.sign = s[0]
+		s = s[1:]
+	}
+	if len(s) > 0 && (s[0] == '$' || s[0] == '#') {
+		spec.symbol = s[0]
+		s = s[1:]
+	}
+	if len(s) > 0 && s[0] == '0' {
+		spec.zero = true
+		s = s[1:]
+	}
+	digits := 0
+	for digits < len(s) && s[digits] >= '0' && s[digits] <= '9' {
+		digits++
+	}
+	if digits > 0 {
+		spec.width, _ = strconv.Atoi(s[:digits])
+		s = s[digits:]
+	}
+	if len(s) > 0 && s[0] == ',' {
+		spec.comma = true
+		s = s[1:]
+	}
+	if len(s) > 0 && s[0] == '.' {
+		digits = 1
+		for digits < len(s) && s[digits] >= '0' && s[digits] <= '9' {
+			digits++
+		}
+		if digits == 1 {
+			return d3FormatSpec{}, false
+		}
+		spec.precision, _ = strconv.Atoi(s[1:digits])
+		s = s[digits:]
+	}
+	if len(s) > 0 && s[0] == '~' {
+		spec.trim = true
+		s = s[1:]
+	}
+	if len(s) > 1 {
+		return d3FormatSpec{}, false
+	}
+	if len(s) == 1 {
+		spec.kind = s[0]
+	}
+
+	switch spec.kind {
+	case 'n':
+		spec.comma = true
+		spec.kind = 'g'
+	case 'e', 'f', 'g', 'p', 'r', 's', '%', 'b', 'c', 'd', 'o', 'x', 'X':
+	default:
+		if spec.kind != 0 {
+			return d3FormatSpec{}, false
+		}
+		if spec.precision < 0 {
+			spec.precision = 12
+		}
+		spec.trim = true
+		spec.kind = 'g'
+	}
+	if spec.zero || (spec.fill == "0" && spec.align == '=') {
+		spec.zero = true
+		spec.fill = "0"
+		spec.align = '='
+	}
+	switch {
+	case spec.precision < 0:
+		spec.precision = 6
+	case strings.IndexByte("gprs", spec.kind) >= 0:
+		spec.precision = max(1, min(21, spec.precision))
+	default:
+		spec.precision = max(0, min(20, spec.precision))
+	}
+	return spec, true
+}
+
+func isD3Align(ch byte) bool {
+	return ch == '<' || ch == '>' || ch == '=' || ch == '^'
+}
+
+// formatD3 formats value like d3-format's format(specifier)(value) using the
+// en-US locale. Invalid specifiers fall back to the default "," format.
+func formatD3(specifier string, value float64) string {
+	spec, ok := parseD3FormatSpec(specifier)
+	if !ok {
+		spec, _ = parseD3FormatSpec(",")
+	}
+	return spec.format(value)
+}
+
+func (spec d3FormatSpec) format(value float64) string {
+	prefix := ""
+	suffix := ""
+	switch spec.symbol {
+	case '$':
+		prefix = "$"
+	case '#':
+		switch spec.kind {
+		case 'b':
+			prefix = "0b"
+		case 'o':
+			prefix = "0o"
+		case 'x', 'X':
+			prefix = "0x"
+		}
+	}
+	if spec.kind == '%' || spec.kind == 'p' {
+		suffix = "%"
+	}
+
+	var body string
+	valuePrefix := prefix
+	valueSuffix := suffix
+	if spec.kind == 'c' {
+		valueSuffix = formatD3Number(value) + valueSuffix
+	} else {
+		negative := value < 0 || math.Signbit(value)
+		siExponent := 0
+		if math.IsNaN(value) {
+			body = "NaN"
+		} else {
+			body, siExponent = spec.formatType(math.Abs(value))
+		}
+		if spec.trim {
+			body = trimD3Insignificant(body)
+		}
+		if negative {
+			if parsed, err := strconv.ParseFloat(body, 64); err == nil && parsed == 0 && spec.sign != '+' {
+				negative = false
+			}
+		}
+		switch {
+		case negative && spec.sign == '(':
+			valuePrefix = "(" + prefix
+		case negative:
+			valuePrefix = "−" + prefix
+		case spec.sign == '-' || spec.sign == '(':
+		default:
+			valuePrefix = string(spec.sign) + prefix
+		}
+		if spec.kind == 's' {
+			valueSuffix = d3SIPrefixes[8+siExponent/3] + valueSuffix
+		}
+		if negative && spec.sign == '(' {
+			valueSuffix += ")"
+		}
+		if strings.IndexByte("defgprs%", spec.kind) >= 0 {
+			for i := 0; i < len(body); i++ {
+				if body[i] < '0' || body[i] > '9' {
+					valueSuffix = body[i:] + valueSuffix
+					body = body[:i]
+					break
+				}
+			}
+		}
+	}
+
+	if spec.comma && !spec.zero {
+		body = groupD3Thousands(body, math.MaxInt)
+	}
+	length := utf8.RuneCountInString(valuePrefix) + len(body) + utf8.RuneCountInString(valueSuffix)
+	padding := ""
+	if length < spec.width {
+		padding = strings.Repeat(spec.fill, spec.width-length)
+	}
+	if spec.comma && spec.zero {
+		limit := math.MaxInt
+		if padding != "" {
+			limit = spec.width - utf8.RuneCountInString(valueSuffix)
+		}
+		body = groupD3Thousands(padding+body, limit)
+		padding = ""
+	}
+
+	switch spec.align {
+	case '<':
+		return valuePrefix + body + valueSuffix + padding
+	case '=':
+		return valuePrefix + padding + body + valueSuffix
+	case '^':
+		half := utf8.RuneCountInString(padding) / 2
+		runes := []rune(padding)
+		return string(runes[:half]) + valuePrefix + body + valueSuffix + string(runes[half:])
+	default:
+		return padding + valuePrefix + body + valueSuffix
+	}
+}
+
+// formatType renders a non-negative value for the spec type. The second
+// result is the SI exponent picked by the "s" type.
+func (spec d3FormatSpec) formatType(x float64) (string, int) {
+	p := spec.precision
+	switch spec.kind {
+	case '%':
+		return strconv.FormatFloat(x*100, 'f', p, 64), 0
+	case 'b':
+		return strconv.FormatInt(int64(math.Round(x)), 2), 0
+	case 'd':
+		return strconv.FormatFloat(math.Round(x), 'f', 0, 64), 0
+	case 'e':
+		return jsExponential(x, p), 0
+	case 'f':
+		return strconv.FormatFloat(x, 'f', p, 64), 0
+	case 'g':
+		return jsPrecision(x, p), 0
+	case 'o':
+		return strconv.FormatInt(int64(math.Round(x)), 8), 0
+	case 'p':
+		return formatD3Rounded(x*100, p), 0
+	case 'r':
+		return formatD3Rounded(x, p), 0
+	case 's':
+		return formatD3PrefixAuto(x, p)
+	case 'x':
+		return strconv.FormatInt(int64(math.Round(x)), 16), 0
+	case 'X':
+		return strings.ToUpper(strconv.FormatInt(int64(math.Round(x)), 16)), 0
+	default:
+		return formatD3Number(x), 0
+	}
+}
+
+func formatD3Number(x float64) string {
+	return strconv.FormatFloat(x, 'f', -1, 64)
+}
+
+// jsExponential mirrors Number.prototype.toExponential.
+func jsExponential(x float64, digits int) string {
+	out := strconv.FormatFloat(x, 'e', digits, 64)
+	mantissa, exponent, _ := strings.Cut(out, "e")
+	sign := exponent[:1]
+	exponent = strings.TrimLeft(exponent[1:], "0")
+	if exponent == "" {
+		exponent = "0"
+	}
+	return mantissa + "e" + sign + exponent
+}
+
+// jsPrecision mirrors Number.prototype.toPrecision.
+func jsPrecision(x float64, precision int) string {
+	if x == 0 {
+		return strconv.FormatFloat(0, 'f', precision-1, 64)
+	}
+	_, exponent := d3DecimalParts(x, precision)
+	if exponent < -6 || exponent >= precision {
+		return jsExponential(x, precision-1)
+	}
+	return strconv.FormatFloat(x, 'f', precision-1-exponent, 64)
+}
+
+// d3DecimalParts returns the significant digits of x rounded to p digits and
+// the decimal exponent of the first digit.
+func d3DecimalParts(x float64, p int) (string, int) {
+	out := strconv.FormatFloat(x, 'e', max(0, p-1), 64)
+	mantissa, exponent, _ := strings.Cut(out, "e")
+	exp, _ := strconv.Atoi(exponent)
+	return strings.Replace(mantissa, ".", "", 1), exp
+}
+
+func formatD3Rounded(x float64, p int) string {
+	coefficient, exponent := d3DecimalParts(x, p)
+	switch {
+	case exponent < 0:
+		return "0." + strings.Repeat("0", -exponent-1) + coefficient
+	case len(coefficient) > exponent+1:
+		return coefficient[:exponent+1] + "." + coefficient[exponent+1:]
+	default:
+		return coefficient + strings.Repeat("0", exponent-len(coefficient)+1)
+	}
+}
+
+func formatD3PrefixAuto(x float64, p int) (string, int) {
+	coefficient, exponent := d3DecimalParts(x, p)
+	siExponent := max(-8, min(8, int(math.Floor(float64(exponent)/3)))) * 3
+	i := exponent - siExponent + 1
+	n := len(coefficient)
+	switch {
+	case i == n:
+		return coefficient, siExponent
+	case i > n:
+		return coefficient + strings.Repeat("0", i-n), siExponent
+	case i > 0:
+		return coefficient[:i] + "." + coefficient[i:], siExponent
+	default:
+		digits, _ := d3DecimalParts(x, max(0, p+i-1))
+		return "0." + strings.Repeat("0", 1-i) + digits, siExponent
+	}
+}
+
+// trimD3Insignificant drops trailing zeros after the decimal point, and the
+// point itself when nothing is left, e.g. "1.2000" -> "1.2" and "1.0e+3" -> "1e+3".
+func trimD3Insignificant(s string) string {
+	start, end := -1, -1
+loop:
+	for i := 1; i < len(s); i++ {
+		switch ch := s[i]; {
+		case ch == '.':
+			start, end = i, i
+		case ch == '0':
+			if start == 0 {
+				start = i
+			}
+			end = i
+		case ch < '1' || ch > '9':
+			break loop
+		default:
+			if start > 0 {
+				start = 0
+			}
+		}
+	}
+	if start > 0 {
+		return s[:start] + s[end+1:]
+	}
+	return s
+}
+
+func groupD3Thousands(value string, width int) string {
+	parts := make([]string, 0, len(value)/3+1)
+	total := 0
+	for i := len(value); i > 0; {
+		size := 3
+		if total+size+1 > width {
+			size = max(1, width-total)
+		}
+		start := max(0, i-size)
+		parts = append(parts, value[start:i])
+		i = start
+		if total += size + 1; total > width {
+			break
+		}
+	}
+	for l, r := 0, len(parts)-1; l < r; l, r = l+1, r-1 {
+		parts[l], parts[r] = parts[r], parts[l]
+	}
+	return strings.Join(parts, ",")
+}
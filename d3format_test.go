@@ -0,0 +1,37 @@
+package mermaid
+
+import "testing"
+
+func TestFormatD3(t *testing.T) {
+	cases := []struct {
+		spec  string
+		value float64
+		want  string
+	}{
+		{",", 1234567.891, "1,234,567.891"},
+		{",", 42, "42"},
+		{".2f", 3.14159, "3.14"},
+		{",.2f", 1234.5, "1,234.50"},
+		{"$,.2f", 1234.5, "$1,234.50"},
+		{".0%", 0.123, "12%"},
+		{"+.1f", 2, "+2.0"},
+		{"(.1f", -2, "(2.0)"},
+		{".1f", -2, "−2.0"},
+		{".3s", 1234567, "1.23M"},
+		{"~s", 1500, "1.5k"},
+		{".2e", 12345, "1.23e+4"},
+		{".3r", 1234.5, "1230"},
+		{"08.2f", -3.5, "−0003.50"},
+		{"010,d", 1234567, "01,234,567"},
+		{"*^9d", 42, "***42****"},
+		{"#x", 255, "0xff"},
+		{"d", 2.6, "3"},
+		{".3g", 0.0001234, "0.000123"},
+		{"invalid!", 1234, "1,234"},
+	}
+	for _, tc := range cases {
+		if got := formatD3(tc.spec, tc.value); got != tc.want {
+			t.Errorf("formatD3(%q, %v) = %q, want %q", tc.spec, tc.value, got, tc.want)
+		}
+	}
+}
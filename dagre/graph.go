@@ -58,6 +58,11 @@ type NodeLabel struct {
 
 	SelfEdges []SelfEdge
 
+	// Intersect, when set, clips edge endpoints against the node's real
+	// outline instead of its bounding box. It receives the laid-out node
+	// and the point the edge arrives from.
+	Intersect func(node *NodeLabel, point Point) Point
+
 	Extra map[string]interface{}
 }
 
@@ -601,3 +606,51 @@ func IntersectRect(rect *NodeLabel, point Point) Point {
 	}
 	return Point{X: x + sx, Y: y + sy}
 }
+
+// IntersectEllipse computes where a line from point to the center of node
+// crosses an ellipse with radii rx and ry centred on the node.
+func IntersectEllipse(node *NodeLabel, rx, ry float64, point Point) Point {
+	dx := point.X - node.X
+	dy := point.Y - node.Y
+	if (dx == 0 && dy == 0) || rx <= 0 || ry <= 0 {
+		return Point{X: node.X, Y: node.Y}
+	}
+	t := 1 / math.Sqrt(dx*dx/(rx*rx)+dy*dy/(ry*ry))
+	return Point{X: node.X + dx*t, Y: node.Y + dy*t}
+}
+
+// IntersectPolygon computes where a ray from the center of node towards
+// point leaves polygon, whose vertices are relative to the node center.
+// For concave or self-crossing outlines the outermost crossing wins.
+// Nodes whose polygon the ray misses fall back to IntersectRect.
+func IntersectPolygon(node *NodeLabel, polygon []Point, point Point) Point {
+	dx := point.X - node.X
+	dy := point.Y - node.Y
+	best := -1.0
+	for i := range polygon {
+		a := polygon[i]
+		b := polygon[(i+1)%len(polygon)]
+		ex := b.X - a.X
+		ey := b.Y - a.Y
+		denom := dx*ey - dy*ex
+		if math.Abs(denom) < 1e-9 {
+			continue
+		}
+		t := (a.X*ey - a.Y*ex) / denom
+		s := (a.X*dy - a.Y*dx) / denom
+		if t > 0 && s >= 0 && s <= 1 && t > best {
+			best = t
+		}
+	}
+	if best < 0 {
+		return IntersectRect(node, point)
+	}
+	return Point{X: node.X + dx*best, Y: node.Y + dy*best}
+}
+
+func intersectNode(node *NodeLabel, point Point) Point {
+	if node.Intersect != nil {
+		return node.Intersect(node, point)
+	}
+	return IntersectRect(node, point)
+}
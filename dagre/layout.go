@@ -353,8 +353,8 @@ func assignNodeIntersects(g *Graph) {
 			p2 = el.Points[len(el.Points)-1]
 		}
 
-		v1 := IntersectRect(nodeV, p1)
-		v2 := IntersectRect(nodeW, p2)
+		v1 := intersectNode(nodeV, p1)
+		v2 := intersectNode(nodeW, p2)
 
 		// Prepend v1, append v2
 		newPts := make([]Point, 0, len(el.Points)+2)
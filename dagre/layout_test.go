@@ -1,6 +1,7 @@
 package dagre
 
 import (
+	"math"
 	"testing"
 )
 
@@ -162,3 +163,33 @@ func TestLRDirection(t *testing.T) {
 		t.Errorf("expected A.X (%.1f) < B.X (%.1f) in LR mode", a.X, b.X)
 	}
 }
+
+func TestCustomNodeIntersect(t *testing.T) {
+	g := NewGraph()
+	g.label.RankSep = 50
+	g.label.NodeSep = 50
+	g.label.RankDir = "TB"
+
+	diamond := []Point{{X: 0, Y: -40}, {X: 40, Y: 0}, {X: 0, Y: 40}, {X: -40, Y: 0}}
+	g.SetNode("A", &NodeLabel{Width: 80, Height: 80, Intersect: func(node *NodeLabel, point Point) Point {
+		return IntersectPolygon(node, diamond, point)
+	}})
+	g.SetNode("B", &NodeLabel{Width: 80, Height: 80, Intersect: func(node *NodeLabel, point Point) Point {
+		return IntersectEllipse(node, 40, 40, point)
+	}})
+	g.SetEdgeVW("A", "B", &EdgeLabel{MinLen: 1, Weight: 1})
+
+	Layout(g)
+
+	a := g.Node("A")
+	b := g.Node("B")
+	points := g.EdgeByKey(Edge{V: "A", W: "B"}).Points
+	start := points[0]
+	end := points[len(points)-1]
+	if d := math.Abs(start.X-a.X) + math.Abs(start.Y-a.Y); math.Abs(d-40) > 1e-6 {
+		t.Errorf("expected edge to start on the diamond outline, got %+v for node at %.1f,%.1f", start, a.X, a.Y)
+	}
+	if d := math.Hypot(end.X-b.X, end.Y-b.Y); math.Abs(d-40) > 1e-6 {
+		t.Errorf("expected edge to end on the circle, got %+v for node at %.1f,%.1f", end, b.X, b.Y)
+	}
+}
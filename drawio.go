@@ -0,0 +1,282 @@
+package mermaid
+
+import (
+	"html"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// MarshalDrawIO converts a flowchart, state, class, ER or architecture
+// layout into a draw.io (diagrams.net) file: an uncompressed mxGraphModel
+// with every shape, cluster and edge where the layout put it. Shapes are
+// nested in the clusters around them, edges keep their routed waypoints,
+// class and entity boxes list their members or attributes.
+func MarshalDrawIO(layout Layout, theme Theme) ([]byte, error) {
+	diagram, err := newExportDiagram(layout, theme, "draw.io")
+	if err != nil {
+		return nil, err
+	}
+	origin := map[string]Point{}
+	for _, shape := range slices.Concat(diagram.containers, diagram.nodes) {
+		origin[shape.id] = Point{X: shape.x, Y: shape.y}
+	}
+	cellID := func(kind, id string) string { return kind + "-" + id }
+
+	name := layout.Title
+	if name == "" {
+		name = "Page-1"
+	}
+
+	var b strings.Builder
+	b.WriteString(`<mxfile host="mmdg" type="device">` + "\n")
+	b.WriteString(`<diagram id="mmdg" name="` + drawioAttr(name) + `">` + "\n")
+	b.WriteString(`<mxGraphModel grid="1" gridSize="10" guides="1" tooltips="1" connect="1" arrows="1" fold="1" page="0" pageScale="1"`)
+	b.WriteString(` pageWidth="` + exportNumber(math.Ceil(layout.Width)) + `" pageHeight="` + exportNumber(math.Ceil(layout.Height)) + `" math="0" shadow="0">` + "\n")
+	b.WriteString(`<root>` + "\n")
+	b.WriteString(`<mxCell id="0"/>` + "\n")
+	b.WriteString(`<mxCell id="1" parent="0"/>` + "\n")
+
+	writeShape := func(shape exportShape, container bool) {
+		parent, x, y := "1", shape.x, shape.y
+		if shape.parent != "" {
+			parent = cellID("node", shape.parent)
+			x -= origin[shape.parent].X
+			y -= origin[shape.parent].Y
+		}
+		value, labelStyle := drawioLabel(shape)
+		style := drawioShapeStyle(shape, container) + labelStyle + drawioPaint(shape.fill, shape.stroke, shape.strokeWidth, theme)
+		if shape.dashed {
+			style += "dashed=1;"
+		}
+		b.WriteString(`<mxCell id="` + drawioAttr(cellID("node", shape.id)) + `" value="` + drawioAttr(value) + `" style="` + drawioAttr(style) + `" vertex="1" parent="` + drawioAttr(parent) + `">`)
+		b.WriteString(`<mxGeometry x="` + exportNumber(x) + `" y="` + exportNumber(y) + `" width="` + exportNumber(shape.w) + `" height="` + exportNumber(shape.h) + `" as="geometry"/>`)
+		b.WriteString("</mxCell>\n")
+	}
+	for _, shape := range diagram.containers {
+		writeShape(shape, true)
+	}
+	for _, shape := range diagram.nodes {
+		writeShape(shape, false)
+	}
+
+	for _, edge := range diagram.edges {
+		style := "rounded=0;edgeStyle=none;"
+		if edge.curved {
+			style += "curved=1;"
+		}
+		style += drawioArrowStyle("start", edge.startHead) + drawioArrowStyle("end", edge.endHead)
+		strokeWidth := 1.0
+		switch edge.style {
+		case EdgeDotted:
+			style += "dashed=1;"
+		case EdgeThick:
+			strokeWidth = 3
+		}
+		style += drawioPaint("none", theme.LineColor, strokeWidth, theme)
+		if background, _, ok := exportColor(theme.EdgeLabelBackground); ok {
+			style += "labelBackgroundColor=" + background + ";"
+		}
+		b.WriteString(`<mxCell id="` + drawioAttr(edge.id) + `" value="` + drawioAttr(edge.label) + `" style="` + drawioAttr(style) + `" edge="1" parent="1"`)
+		if edge.from != "" {
+			b.WriteString(` source="` + drawioAttr(cellID("node", edge.from)) + `"`)
+		}
+		if edge.to != "" {
+			b.WriteString(` target="` + drawioAttr(cellID("node", edge.to)) + `"`)
+		}
+		b.WriteString(`><mxGeometry relative="1" as="geometry">`)
+		first, last := edge.points[0], edge.points[len(edge.points)-1]
+		b.WriteString(`<mxPoint x="` + exportNumber(first.X) + `" y="` + exportNumber(first.Y) + `" as="sourcePoint"/>`)
+		b.WriteString(`<mxPoint x="` + exportNumber(last.X) + `" y="` + exportNumber(last.Y) + `" as="targetPoint"/>`)
+		if len(edge.points) > 2 {
+			b.WriteString(`<Array as="points">`)
+			for _, p := range edge.points[1 : len(edge.points)-1] {
+				b.WriteString(`<mxPoint x="` + exportNumber(p.X) + `" y="` + exportNumber(p.Y) + `"/>`)
+			}
+			b.WriteString(`</Array>`)
+		}
+		b.WriteString("</mxGeometry></mxCell>\n")
+	}
+	b.WriteString("</root>\n</mxGraphModel>\n</diagram>\n</mxfile>\n")
+	return []byte(b.String()), nil
+}
+
+// RenderDrawIO parses and lays out a Mermaid diagram and returns it as a
+// draw.io file. See MarshalDrawIO.
+func RenderDrawIO(input string, options RenderOptions) ([]byte, error) {
+	layout, err := computeExportLayout(input, options)
+	if err != nil {
+		return nil, err
+	}
+	return MarshalDrawIO(layout, options.Theme)
+}
+
+// WriteDrawIOFromSource writes a Mermaid diagram as a draw.io file, or to
+// stdout when outputPath is empty. See MarshalDrawIO.
+func WriteDrawIOFromSource(mermaidCode string, outputPath string, options RenderOptions) error {
+	data, err := RenderDrawIO(mermaidCode, options)
+	if err != nil {
+		return err
+	}
+	return writeOutputBytes(data, outputPath)
+}
+
+// drawioLabel returns the value of a shape's cell and the style its label
+// needs. Class and entity boxes become the HTML compartments draw.io's own
+// UML shapes use.
+func drawioLabel(shape exportShape) (string, string) {
+	if len(shape.sections) == 0 {
+		if shape.labelBelow {
+			return shape.label, "verticalLabelPosition=bottom;verticalAlign=top;"
+		}
+		return shape.label, ""
+	}
+	var b strings.Builder
+	b.WriteString(`<p style="margin:4px 0;text-align:center;"><b>` + html.EscapeString(shape.label) + `</b></p>`)
+	for _, section := range shape.sections {
+		b.WriteString(`<hr size="1"/><p style="margin:0 4px;">`)
+		for i, row := range section {
+			if i > 0 {
+				b.WriteString("<br/>")
+			}
+			b.WriteString(html.EscapeString(row))
+		}
+		b.WriteString(`</p>`)
+	}
+	return b.String(), "html=1;verticalAlign=top;align=left;overflow=fill;"
+}
+
+// drawioShapeStyle maps a node shape to the closest draw.io shape.
+// Containers keep their label at the top and carry the shapes inside them.
+func drawioShapeStyle(shape exportShape, container bool) string {
+	if container {
+		style := "container=1;collapsible=0;verticalAlign=top;"
+		if shape.shape == ShapeRoundRect {
+			style += "rounded=1;arcSize=4;"
+		} else {
+			style += "align=left;spacingLeft=8;"
+		}
+		return style
+	}
+	switch shape.shape {
+	case ShapeRoundRect:
+		return "rounded=1;"
+	case ShapeStadium:
+		return "rounded=1;arcSize=50;"
+	case ShapeSubroutine:
+		return "shape=process;"
+	case ShapeCylinder, ShapeLinedCylinder:
+		return "shape=cylinder3;boundedLbl=1;backgroundOutline=1;size=10;"
+	case ShapeHorizontalCylinder:
+		return "shape=cylinder3;boundedLbl=1;backgroundOutline=1;size=10;direction=south;"
+	case ShapeCircle, ShapeSmallCircle, ShapeFilledCircle:
+		return "ellipse;"
+	case ShapeDoubleCircle, ShapeFramedCircle:
+		return "ellipse;shape=doubleEllipse;"
+	case ShapeCrossedCircle:
+		return "shape=sumEllipse;perimeter=ellipsePerimeter;"
+	case ShapeDiamond:
+		return "rhombus;"
+	case ShapeHexagon:
+		return "shape=hexagon;perimeter=hexagonPerimeter2;size=0.15;"
+	case ShapeParallelogram:
+		return "shape=parallelogram;perimeter=parallelogramPerimeter;"
+	case ShapeLeanLeft:
+		return "shape=parallelogram;perimeter=parallelogramPerimeter;flipH=1;"
+	case ShapeTrapezoid:
+		return "shape=trapezoid;perimeter=trapezoidPerimeter;"
+	case ShapeTrapezoidTop:
+		return "shape=trapezoid;perimeter=trapezoidPerimeter;flipV=1;"
+	case ShapeDocument, ShapeLinedDocument, ShapeStackedDocument, ShapeTaggedDocument:
+		return "shape=document;boundedLbl=1;"
+	case ShapeNotchedRect:
+		return "shape=card;"
+	case ShapeTriangle:
+		return "triangle;direction=north;"
+	case ShapeFlippedTriangle:
+		return "triangle;direction=south;"
+	case ShapeDelay:
+		return "shape=delay;"
+	case ShapeCloud:
+		return "ellipse;shape=cloud;"
+	case ShapeHourglass:
+		return "shape=collate;"
+	case ShapePerson:
+		return "shape=umlActor;verticalLabelPosition=bottom;verticalAlign=top;"
+	case ShapeText:
+		return "text;"
+	}
+	return "rounded=0;"
+}
+
+// drawioPaint writes fill, stroke and font colors. draw.io colors are hex,
+// so alpha goes into the opacity keys.
+func drawioPaint(fill, stroke string, strokeWidth float64, theme Theme) string {
+	var b strings.Builder
+	if c, alpha, ok := exportColor(fill); ok {
+		b.WriteString("fillColor=" + c + ";")
+		if alpha < 1 {
+			b.WriteString("fillOpacity=" + strconv.Itoa(int(math.Round(alpha*100))) + ";")
+		}
+	} else {
+		b.WriteString("fillColor=none;")
+	}
+	if c, alpha, ok := exportColor(stroke); ok {
+		b.WriteString("strokeColor=" + c + ";")
+		if alpha < 1 {
+			b.WriteString("strokeOpacity=" + strconv.Itoa(int(math.Round(alpha*100))) + ";")
+		}
+		if strokeWidth > 0 {
+			b.WriteString("strokeWidth=" + exportNumber(strokeWidth) + ";")
+		}
+	} else {
+		b.WriteString("strokeColor=none;")
+	}
+	if c, _, ok := exportColor(theme.PrimaryTextColor); ok {
+		b.WriteString("fontColor=" + c + ";")
+	}
+	if theme.FontSize > 0 {
+		b.WriteString("fontSize=" + exportNumber(theme.FontSize) + ";")
+	}
+	b.WriteString("fontFamily=" + exportFontFamily(theme.FontFamily) + ";")
+	return b.String()
+}
+
+// drawioArrowStyle sets the startArrow or endArrow of an edge.
+func drawioArrowStyle(end string, head string) string {
+	arrow, fill := "none", "0"
+	switch head {
+	case "arrow":
+		arrow, fill = "block", "1"
+	case "extension":
+		arrow = "block"
+	case "composition":
+		arrow, fill = "diamondThin", "1"
+	case "aggregation":
+		arrow = "diamondThin"
+	case "dependency":
+		arrow = "open"
+	case "lollipop":
+		arrow = "oval"
+	case "circle":
+		arrow, fill = "oval", "1"
+	case "cross":
+		arrow = "cross"
+	case "onlyOne":
+		arrow = "ERmandOne"
+	case "zeroOrOne":
+		arrow = "ERzeroToOne"
+	case "oneOrMore":
+		arrow = "ERoneToMany"
+	case "zeroOrMore":
+		arrow = "ERzeroToMany"
+	}
+	return end + "Arrow=" + arrow + ";" + end + "Fill=" + fill + ";"
+}
+
+// drawioAttr escapes an XML attribute value, keeping line breaks, which
+// parsers would otherwise turn into spaces.
+func drawioAttr(s string) string {
+	return strings.ReplaceAll(html.EscapeString(s), "\n", "&#xa;")
+}
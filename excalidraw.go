@@ -0,0 +1,344 @@
+package mermaid
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"math"
+	"slices"
+	"strings"
+)
+
+// excalidrawFontFamily is Excalidraw's Helvetica, its plain sans-serif.
+const excalidrawFontFamily = 2
+
+type excalidrawFile struct {
+	Type     string         `json:"type"`
+	Version  int            `json:"version"`
+	Source   string         `json:"source"`
+	Elements []any          `json:"elements"`
+	AppState map[string]any `json:"appState"`
+	Files    map[string]any `json:"files"`
+}
+
+// excalidrawBase holds the properties every Excalidraw element has.
+type excalidrawBase struct {
+	ID              string               `json:"id"`
+	Type            string               `json:"type"`
+	X               float64              `json:"x"`
+	Y               float64              `json:"y"`
+	Width           float64              `json:"width"`
+	Height          float64              `json:"height"`
+	Angle           float64              `json:"angle"`
+	StrokeColor     string               `json:"strokeColor"`
+	BackgroundColor string               `json:"backgroundColor"`
+	FillStyle       string               `json:"fillStyle"`
+	StrokeWidth     float64              `json:"strokeWidth"`
+	StrokeStyle     string               `json:"strokeStyle"`
+	Roughness       int                  `json:"roughness"`
+	Opacity         int                  `json:"opacity"`
+	GroupIDs        []string             `json:"groupIds"`
+	FrameID         *string              `json:"frameId"`
+	Roundness       *excalidrawRoundness `json:"roundness"`
+	Seed            uint32               `json:"seed"`
+	Version         int                  `json:"version"`
+	VersionNonce    uint32               `json:"versionNonce"`
+	IsDeleted       bool                 `json:"isDeleted"`
+	BoundElements   []excalidrawBound    `json:"boundElements"`
+	Updated         int64                `json:"updated"`
+	Link            *string              `json:"link"`
+	Locked          bool                 `json:"locked"`
+}
+
+type excalidrawRoundness struct {
+	Type int `json:"type"`
+}
+
+type excalidrawBound struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+}
+
+type excalidrawText struct {
+	excalidrawBase
+	Text          string  `json:"text"`
+	OriginalText  string  `json:"originalText"`
+	FontSize      float64 `json:"fontSize"`
+	FontFamily    int     `json:"fontFamily"`
+	TextAlign     string  `json:"textAlign"`
+	VerticalAlign string  `json:"verticalAlign"`
+	ContainerID   *string `json:"containerId"`
+	LineHeight    float64 `json:"lineHeight"`
+	AutoResize    bool    `json:"autoResize"`
+}
+
+type excalidrawArrow struct {
+	excalidrawBase
+	Points             [][2]float64       `json:"points"`
+	LastCommittedPoint *[2]float64        `json:"lastCommittedPoint"`
+	StartBinding       *excalidrawBinding `json:"startBinding"`
+	EndBinding         *excalidrawBinding `json:"endBinding"`
+	StartArrowhead     *string            `json:"startArrowhead"`
+	EndArrowhead       *string            `json:"endArrowhead"`
+	Elbowed            bool               `json:"elbowed"`
+}
+
+type excalidrawBinding struct {
+	ElementID string  `json:"elementId"`
+	Focus     float64 `json:"focus"`
+	Gap       float64 `json:"gap"`
+}
+
+// MarshalExcalidraw converts a flowchart, state, class, ER or architecture
+// layout into an Excalidraw scene where the layout put everything. Labels
+// are bound to their shapes and arrows to the shapes they connect, so both
+// follow when a shape is moved; arrows keep their routed waypoints.
+// Excalidraw has only rectangles, diamonds and ellipses, so other node
+// shapes become rectangles.
+func MarshalExcalidraw(layout Layout, theme Theme) ([]byte, error) {
+	diagram, err := newExportDiagram(layout, theme, "Excalidraw")
+	if err != nil {
+		return nil, err
+	}
+	fontSize := theme.FontSize
+	if fontSize <= 0 {
+		fontSize = 16
+	}
+	textColor := excalidrawColor(theme.PrimaryTextColor, "#1e1e1e")
+
+	var elements []any
+	shapes := map[string]*excalidrawBase{}
+	text := func(id, value string, cx, top float64, container *excalidrawBase, align string) excalidrawText {
+		lines := strings.Split(value, "\n")
+		width := 0.0
+		for _, line := range lines {
+			width = max(width, measureTextWidthWithFontSize(line, fontSize, false, "Helvetica"))
+		}
+		height := float64(len(lines)) * fontSize * 1.25
+		x := cx - width/2
+		if align == "left" {
+			x = cx
+		}
+		t := excalidrawText{
+			excalidrawBase: newExcalidrawBase(id, "text", x, top, width, height),
+			Text:           value,
+			OriginalText:   value,
+			FontSize:       fontSize,
+			FontFamily:     excalidrawFontFamily,
+			TextAlign:      align,
+			VerticalAlign:  "top",
+			LineHeight:     1.25,
+			AutoResize:     true,
+		}
+		t.StrokeColor = textColor
+		if container != nil {
+			t.ContainerID = &container.ID
+			container.BoundElements = append(container.BoundElements, excalidrawBound{ID: id, Type: "text"})
+		}
+		return t
+	}
+
+	for i, shape := range slices.Concat(diagram.containers, diagram.nodes) {
+		container := i < len(diagram.containers)
+		kind := "rectangle"
+		switch shape.shape {
+		case ShapeDiamond:
+			kind = "diamond"
+		case ShapeCircle, ShapeDoubleCircle, ShapeSmallCircle, ShapeFilledCircle, ShapeFramedCircle, ShapeCrossedCircle:
+			kind = "ellipse"
+		}
+		base := newExcalidrawBase("node-"+shape.id, kind, shape.x, shape.y, shape.w, shape.h)
+		base.StrokeColor = excalidrawColor(shape.stroke, "transparent")
+		base.BackgroundColor = excalidrawColor(shape.fill, "transparent")
+		base.StrokeWidth = max(1, shape.strokeWidth)
+		if shape.dashed {
+			base.StrokeStyle = "dashed"
+		}
+		switch shape.shape {
+		case ShapeRoundRect, ShapeStadium:
+			base.Roundness = &excalidrawRoundness{Type: 3}
+		}
+		shapes[shape.id] = &base
+		elements = append(elements, shapes[shape.id])
+
+		label := shape.label
+		for _, section := range shape.sections {
+			label += "\n\n" + strings.Join(section, "\n")
+		}
+		if strings.TrimSpace(label) == "" {
+			continue
+		}
+		switch {
+		case container && shape.shape == ShapeRoundRect:
+			elements = append(elements, text("text-"+base.ID, label, shape.x+shape.w/2, shape.y+4, nil, "center"))
+		case container:
+			elements = append(elements, text("text-"+base.ID, label, shape.x+8, shape.y+4, nil, "left"))
+		case shape.labelBelow:
+			elements = append(elements, text("text-"+base.ID, label, shape.x+shape.w/2, shape.y+shape.h+4, nil, "center"))
+		default:
+			t := text("text-"+base.ID, label, shape.x+shape.w/2, 0, shapes[shape.id], "center")
+			t.Y = shape.y + (shape.h-t.Height)/2
+			if len(shape.sections) > 0 {
+				t.Y = shape.y + 4
+			}
+			t.VerticalAlign = "middle"
+			elements = append(elements, t)
+		}
+	}
+
+	lineColor := excalidrawColor(theme.LineColor, "#1e1e1e")
+	for _, edge := range diagram.edges {
+		first := edge.points[0]
+		minX, minY, maxX, maxY := first.X, first.Y, first.X, first.Y
+		points := make([][2]float64, len(edge.points))
+		for i, p := range edge.points {
+			points[i] = [2]float64{exportRound(p.X - first.X), exportRound(p.Y - first.Y)}
+			minX, minY = min(minX, p.X), min(minY, p.Y)
+			maxX, maxY = max(maxX, p.X), max(maxY, p.Y)
+		}
+		arrow := excalidrawArrow{
+			excalidrawBase: newExcalidrawBase(edge.id, "arrow", first.X, first.Y, maxX-minX, maxY-minY),
+			Points:         points,
+			StartArrowhead: excalidrawArrowhead(edge.startHead),
+			EndArrowhead:   excalidrawArrowhead(edge.endHead),
+		}
+		arrow.StrokeColor = lineColor
+		switch edge.style {
+		case EdgeDotted:
+			arrow.StrokeStyle = "dashed"
+		case EdgeThick:
+			arrow.StrokeWidth = 2
+		}
+		if edge.curved {
+			arrow.Roundness = &excalidrawRoundness{Type: 2}
+		}
+		if shape := shapes[edge.from]; shape != nil {
+			arrow.StartBinding = &excalidrawBinding{ElementID: shape.ID, Gap: 1}
+			shape.BoundElements = append(shape.BoundElements, excalidrawBound{ID: edge.id, Type: "arrow"})
+		}
+		if shape := shapes[edge.to]; shape != nil {
+			arrow.EndBinding = &excalidrawBinding{ElementID: shape.ID, Gap: 1}
+			shape.BoundElements = append(shape.BoundElements, excalidrawBound{ID: edge.id, Type: "arrow"})
+		}
+		elements = append(elements, &arrow)
+		if edge.label != "" {
+			n := len(edge.points)
+			mid := edge.points[n/2]
+			if n%2 == 0 {
+				a := edge.points[n/2-1]
+				mid = Point{X: (a.X + mid.X) / 2, Y: (a.Y + mid.Y) / 2}
+			}
+			t := text("text-"+edge.id, edge.label, mid.X, 0, &arrow.excalidrawBase, "center")
+			t.Y = mid.Y - t.Height/2
+			t.VerticalAlign = "middle"
+			elements = append(elements, t)
+		}
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	err = encoder.Encode(excalidrawFile{
+		Type:     "excalidraw",
+		Version:  2,
+		Source:   "https://github.com/bvolpato/mermaid-go-renderer",
+		Elements: elements,
+		AppState: map[string]any{"viewBackgroundColor": excalidrawColor(theme.Background, "#ffffff"), "gridSize": nil},
+		Files:    map[string]any{},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("marshal Excalidraw scene: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// RenderExcalidraw parses and lays out a Mermaid diagram and returns it as
+// an Excalidraw scene. See MarshalExcalidraw.
+func RenderExcalidraw(input string, options RenderOptions) ([]byte, error) {
+	layout, err := computeExportLayout(input, options)
+	if err != nil {
+		return nil, err
+	}
+	return MarshalExcalidraw(layout, options.Theme)
+}
+
+// WriteExcalidrawFromSource writes a Mermaid diagram as an Excalidraw
+// scene, or to stdout when outputPath is empty. See MarshalExcalidraw.
+func WriteExcalidrawFromSource(mermaidCode string, outputPath string, options RenderOptions) error {
+	data, err := RenderExcalidraw(mermaidCode, options)
+	if err != nil {
+		return err
+	}
+	return writeOutputBytes(data, outputPath)
+}
+
+// newExcalidrawBase returns an element with Excalidraw's defaults and the
+// clean "architect" stroke. Seeds are derived from the id, so the same
+// layout always gives the same file.
+func newExcalidrawBase(id, kind string, x, y, w, h float64) excalidrawBase {
+	seed := crc32.ChecksumIEEE([]byte(id))
+	return excalidrawBase{
+		ID:              id,
+		Type:            kind,
+		X:               exportRound(x),
+		Y:               exportRound(y),
+		Width:           exportRound(w),
+		Height:          exportRound(h),
+		StrokeColor:     "#1e1e1e",
+		BackgroundColor: "transparent",
+		FillStyle:       "solid",
+		StrokeWidth:     1,
+		StrokeStyle:     "solid",
+		Opacity:         100,
+		GroupIDs:        []string{},
+		Seed:            seed,
+		Version:         1,
+		VersionNonce:    seed ^ 0x5bd1e995,
+		Updated:         1,
+	}
+}
+
+// excalidrawColor converts a paint value to an opaque hex color, blending
+// translucent colors over white, since Excalidraw's opacity applies to a
+// whole element.
+func excalidrawColor(raw string, fallback string) string {
+	c, alpha, ok := parsePaintColor(raw)
+	if !ok || alpha == 0 {
+		return fallback
+	}
+	blend := func(v uint8) uint8 { return uint8(math.Round(float64(v)*alpha + 255*(1-alpha))) }
+	return fmt.Sprintf("#%02x%02x%02x", blend(c.R), blend(c.G), blend(c.B))
+}
+
+// excalidrawArrowhead names the Excalidraw arrowhead for an export
+// arrowhead, or nil for none.
+func excalidrawArrowhead(head string) *string {
+	name := ""
+	switch head {
+	case "arrow", "dependency":
+		name = "arrow"
+	case "extension":
+		name = "triangle_outline"
+	case "composition":
+		name = "diamond"
+	case "aggregation":
+		name = "diamond_outline"
+	case "lollipop":
+		name = "circle_outline"
+	case "circle":
+		name = "circle"
+	case "cross":
+		name = "bar"
+	case "onlyOne", "zeroOrOne":
+		name = "crowfoot_one"
+	case "oneOrMore":
+		name = "crowfoot_one_or_many"
+	case "zeroOrMore":
+		name = "crowfoot_many"
+	default:
+		return nil
+	}
+	return &name
+}
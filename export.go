@@ -0,0 +1,431 @@
+package mermaid
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// exportDiagram is a layout reduced to what editors such as draw.io and
+// Excalidraw model: boxes with a label and edges between them. Containers
+// come before the shapes inside them, outermost first.
+type exportDiagram struct {
+	containers []exportShape
+	nodes      []exportShape
+	edges      []exportEdge
+}
+
+type exportShape struct {
+	id     string
+	parent string
+	shape  NodeShape
+	x, y   float64
+	w, h   float64
+	label  string
+	// sections holds the rows of class members and methods or entity
+	// attributes, drawn under the label in compartments.
+	sections    [][]string
+	fill        string
+	stroke      string
+	strokeWidth float64
+	dashed      bool
+	// labelBelow places the label under the shape, as for architecture
+	// services, whose box is their icon.
+	labelBelow bool
+}
+
+type exportEdge struct {
+	id       string
+	from, to string
+	label    string
+	points   []Point
+	style    EdgeStyle
+	curved   bool
+	// startHead and endHead name the arrowheads: arrow, extension,
+	// composition, aggregation, dependency, lollipop, circle, cross, or
+	// the ER cardinalities onlyOne, zeroOrOne, oneOrMore and zeroOrMore.
+	startHead string
+	endHead   string
+}
+
+// newExportDiagram collects the shapes and edges of a flowchart, state,
+// class, ER or architecture layout. format names the output in errors.
+func newExportDiagram(layout Layout, theme Theme, format string) (exportDiagram, error) {
+	var diagram exportDiagram
+	switch layout.Kind {
+	case DiagramFlowchart, DiagramState, DiagramClass, DiagramER:
+		diagram = exportGraphShapes(layout, theme)
+	case DiagramArchitecture:
+		diagram = exportArchitectureShapes(layout)
+	default:
+		return exportDiagram{}, fmt.Errorf("%s export supports flowchart, state, class, ER and architecture diagrams, not %s", format, layout.Kind)
+	}
+	if len(diagram.nodes) == 0 && len(diagram.containers) == 0 {
+		return exportDiagram{}, errors.New("diagram has no shapes to export")
+	}
+
+	sort.SliceStable(diagram.containers, func(i, j int) bool {
+		a, b := diagram.containers[i], diagram.containers[j]
+		return a.w*a.h > b.w*b.h
+	})
+	for i := range diagram.containers {
+		diagram.containers[i].parent = exportParent(diagram.containers[:i], diagram.containers[i])
+	}
+	for i := range diagram.nodes {
+		diagram.nodes[i].parent = exportParent(diagram.containers, diagram.nodes[i])
+	}
+
+	ids := map[string]bool{}
+	for _, shape := range slices.Concat(diagram.containers, diagram.nodes) {
+		ids[shape.id] = true
+	}
+	curved := layout.Kind != DiagramArchitecture
+	for i, edge := range layout.Edges {
+		if edge.Style == EdgeInvisible {
+			continue
+		}
+		points := edge.Points
+		if len(points) < 2 {
+			points = []Point{{X: edge.X1, Y: edge.Y1}, {X: edge.X2, Y: edge.Y2}}
+		}
+		out := exportEdge{
+			id:     fmt.Sprintf("edge-%d", i),
+			label:  edge.Label,
+			points: points,
+			style:  edge.Style,
+			curved: curved && len(points) > 2 && edge.Curve != "linear" && !strings.HasPrefix(edge.Curve, "step"),
+		}
+		if ids[edge.From] {
+			out.from = edge.From
+		}
+		if ids[edge.To] {
+			out.to = edge.To
+		}
+		if edge.MarkerStart != "" || edge.MarkerEnd != "" {
+			out.startHead = exportArrowHead(edge.MarkerStart)
+			out.endHead = exportArrowHead(edge.MarkerEnd)
+		} else {
+			if edge.ArrowStart {
+				out.startHead = "arrow"
+			}
+			if edge.ArrowEnd {
+				out.endHead = "arrow"
+			}
+		}
+		diagram.edges = append(diagram.edges, out)
+	}
+	return diagram, nil
+}
+
+// computeExportLayout parses and lays out a diagram for the editor
+// exporters.
+func computeExportLayout(input string, options RenderOptions) (Layout, error) {
+	if strings.TrimSpace(input) == "" {
+		return Layout{}, errors.New("input diagram is empty")
+	}
+	parsed, err := ParseMermaid(input)
+	if err != nil {
+		return Layout{}, err
+	}
+	if err := ensureHighFidelityOrAllowApproximate(parsed.Graph.Kind, options); err != nil {
+		return Layout{}, err
+	}
+	return ComputeLayout(&parsed.Graph, options.Theme, options.Layout), nil
+}
+
+// exportGraphShapes collects the nodes and clusters of the dagre based
+// diagrams. Colors are taken from the shapes drawn for the nodes, so class
+// definitions and styles carry over.
+func exportGraphShapes(layout Layout, theme Theme) exportDiagram {
+	var diagram exportDiagram
+	hidden := map[exportBox]string{}
+	for _, node := range layout.Nodes {
+		box := exportBox{node.X, node.Y, node.W, node.H}
+		if node.Shape == ShapeHidden {
+			// Composite states are laid out as hidden nodes the size of
+			// their cluster.
+			hidden[box.rounded()] = node.ID
+			continue
+		}
+		label := node.Label
+		if len(node.LabelLines) > 0 {
+			label = labelLinesText(node.LabelLines)
+		}
+		fill, stroke, strokeWidth := exportNodePaint(layout, box, theme)
+		if node.Fill != "" {
+			fill = node.Fill
+		}
+		if node.Stroke != "" {
+			stroke = node.Stroke
+		}
+		if node.StrokeWidth > 0 {
+			strokeWidth = node.StrokeWidth
+		}
+		diagram.nodes = append(diagram.nodes, exportShape{
+			id:          node.ID,
+			shape:       node.Shape,
+			x:           node.X,
+			y:           node.Y,
+			w:           node.W,
+			h:           node.H,
+			label:       label,
+			sections:    exportSections(layout, node),
+			fill:        fill,
+			stroke:      stroke,
+			strokeWidth: strokeWidth,
+		})
+	}
+
+	var labels []string
+	labelByID := map[string]string{}
+	for _, text := range layout.Texts {
+		if text.Class != "cluster-label" {
+			continue
+		}
+		if text.ID != "" {
+			labelByID[text.ID] = text.Value
+		} else {
+			labels = append(labels, text.Value)
+		}
+	}
+	for _, rect := range layout.Rects {
+		if class, _, _ := strings.Cut(rect.Class, " "); class != "cluster" {
+			continue
+		}
+		// Flowchart subgraphs carry their id, so edges to a subgraph find
+		// it; state clusters match the hidden node laid out in their place
+		// and get their label by position.
+		id, label := rect.ID, labelByID[rect.ID]
+		if id == "" {
+			box := exportBox{rect.X, rect.Y, rect.W, rect.H}
+			var ok bool
+			if id, ok = hidden[box.rounded()]; !ok {
+				id = fmt.Sprintf("cluster-%d", len(diagram.containers)+1)
+			}
+			if len(labels) > 0 {
+				label, labels = labels[0], labels[1:]
+			}
+		}
+		diagram.containers = append(diagram.containers, exportShape{
+			id:          id,
+			shape:       ShapeRoundRect,
+			x:           rect.X,
+			y:           rect.Y,
+			w:           rect.W,
+			h:           rect.H,
+			label:       label,
+			fill:        rect.Fill,
+			stroke:      rect.Stroke,
+			strokeWidth: rect.StrokeWidth,
+			dashed:      rect.Dashed || rect.StrokeDasharray != "",
+		})
+	}
+	return diagram
+}
+
+// exportArchitectureShapes collects architecture groups as containers and
+// services as nodes labelled under their icon.
+func exportArchitectureShapes(layout Layout) exportDiagram {
+	var diagram exportDiagram
+	for _, group := range layout.ArchitectureGroups {
+		diagram.containers = append(diagram.containers, exportShape{
+			id:          group.ID,
+			shape:       ShapeRectangle,
+			x:           group.X,
+			y:           group.Y,
+			w:           group.W,
+			h:           group.H,
+			label:       group.Label,
+			fill:        "none",
+			stroke:      "#B7BDEB",
+			strokeWidth: 2,
+			dashed:      true,
+		})
+	}
+	for _, service := range layout.ArchitectureServices {
+		diagram.nodes = append(diagram.nodes, exportShape{
+			id:         service.ID,
+			shape:      ShapeRectangle,
+			x:          service.X,
+			y:          service.Y,
+			w:          service.W,
+			h:          service.H,
+			label:      service.Label,
+			fill:       "#087ebf",
+			stroke:     "none",
+			labelBelow: true,
+		})
+	}
+	return diagram
+}
+
+type exportBox struct {
+	x, y, w, h float64
+}
+
+// rounded snaps a box to whole units so boxes computed twice compare
+// equal.
+func (b exportBox) rounded() exportBox {
+	return exportBox{math.Round(b.x), math.Round(b.y), math.Round(b.w), math.Round(b.h)}
+}
+
+func (b exportBox) contains(x, y, w, h float64) bool {
+	const slack = 0.5
+	return x >= b.x-slack && y >= b.y-slack && x+w <= b.x+b.w+slack && y+h <= b.y+b.h+slack
+}
+
+// exportParent returns the innermost of containers, ordered outermost
+// first, that encloses shape.
+func exportParent(containers []exportShape, shape exportShape) string {
+	parent := ""
+	for _, c := range containers {
+		if c.id != shape.id && (exportBox{c.x, c.y, c.w, c.h}).contains(shape.x, shape.y, shape.w, shape.h) {
+			parent = c.id
+		}
+	}
+	return parent
+}
+
+// exportNodePaint finds the rectangle, circle or polygon drawn for a node
+// and returns its colors, or the theme's node colors when none matches.
+func exportNodePaint(layout Layout, box exportBox, theme Theme) (string, string, float64) {
+	near := func(a, b float64) bool { return math.Abs(a-b) < 0.5 }
+	for _, rect := range layout.Rects {
+		if near(rect.X, box.x) && near(rect.Y, box.y) && near(rect.W, box.w) && near(rect.H, box.h) {
+			return rect.Fill, rect.Stroke, rect.StrokeWidth
+		}
+	}
+	cx, cy := box.x+box.w/2, box.y+box.h/2
+	for _, circle := range layout.Circles {
+		if near(circle.CX, cx) && near(circle.CY, cy) && circle.R >= min(box.w, box.h)/2-1 {
+			return circle.Fill, circle.Stroke, circle.StrokeWidth
+		}
+	}
+	for _, polygon := range layout.Polygons {
+		if len(polygon.Points) == 0 {
+			continue
+		}
+		minX, minY := math.Inf(1), math.Inf(1)
+		maxX, maxY := math.Inf(-1), math.Inf(-1)
+		for _, p := range polygon.Points {
+			minX, minY = min(minX, p.X), min(minY, p.Y)
+			maxX, maxY = max(maxX, p.X), max(maxY, p.Y)
+		}
+		if near((minX+maxX)/2, cx) && near((minY+maxY)/2, cy) && maxX-minX >= box.w-1 {
+			return polygon.Fill, polygon.Stroke, polygon.StrokeWidth
+		}
+	}
+	return theme.PrimaryColor, theme.PrimaryBorderColor, 1
+}
+
+// exportSections gathers the class members and methods or the entity
+// attributes drawn inside a node, one row per line of text.
+func exportSections(layout Layout, node NodeLayout) [][]string {
+	box := exportBox{node.X, node.Y, node.W, node.H}
+	type cell struct {
+		section string
+		x, y    float64
+		value   string
+	}
+	var cells []cell
+	for _, text := range layout.Texts {
+		section := ""
+		switch {
+		case text.Class == "class-member-"+node.ID, text.Class == "class-method-"+node.ID:
+			section = text.Class
+		case strings.HasPrefix(text.Class, "label attribute-"):
+			section = "attributes"
+		default:
+			continue
+		}
+		if text.Value != "" && box.contains(text.X, text.Y, 0, 0) {
+			cells = append(cells, cell{section, text.X, text.Y, text.Value})
+		}
+	}
+	if len(cells) == 0 {
+		return nil
+	}
+	sort.SliceStable(cells, func(i, j int) bool {
+		if cells[i].y != cells[j].y {
+			return cells[i].y < cells[j].y
+		}
+		return cells[i].x < cells[j].x
+	})
+	var sections [][]string
+	var names []string
+	var row []string
+	for i, c := range cells {
+		row = append(row, c.value)
+		if i+1 < len(cells) && cells[i+1].y == c.y && cells[i+1].section == c.section {
+			continue
+		}
+		if len(names) == 0 || names[len(names)-1] != c.section {
+			names = append(names, c.section)
+			sections = append(sections, nil)
+		}
+		sections[len(sections)-1] = append(sections[len(sections)-1], strings.Join(row, " "))
+		row = nil
+	}
+	return sections
+}
+
+// exportArrowHead names the arrowhead an SVG marker id draws, such as
+// "my-svg_er-zeroOrMoreEnd" or "my-svg_class-extensionStart".
+func exportArrowHead(marker string) string {
+	name := strings.ToLower(marker)
+	for _, head := range []string{
+		"extension", "composition", "aggregation", "dependency", "lollipop",
+		"onlyone", "zeroorone", "oneormore", "zeroormore", "circle", "cross",
+	} {
+		if strings.Contains(name, head) {
+			switch head {
+			case "onlyone":
+				return "onlyOne"
+			case "zeroorone":
+				return "zeroOrOne"
+			case "oneormore":
+				return "oneOrMore"
+			case "zeroormore":
+				return "zeroOrMore"
+			}
+			return head
+		}
+	}
+	if name != "" {
+		return "arrow"
+	}
+	return ""
+}
+
+// exportColor converts a paint value to a hex color and its opacity. It
+// reports false for none and transparent.
+func exportColor(raw string) (string, float64, bool) {
+	c, alpha, ok := parsePaintColor(raw)
+	if !ok || alpha == 0 {
+		return "", 0, false
+	}
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B), alpha, true
+}
+
+// exportFontFamily returns the first family of a CSS font-family list.
+func exportFontFamily(fontFamily string) string {
+	first, _, _ := strings.Cut(fontFamily, ",")
+	first = strings.Trim(strings.TrimSpace(first), `"'`)
+	if first == "" {
+		return "Helvetica"
+	}
+	return first
+}
+
+// exportRound rounds a coordinate to hundredths of a unit.
+func exportRound(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// exportNumber formats a coordinate to hundredths of a unit.
+func exportNumber(v float64) string {
+	return formatFloat(exportRound(v))
+}
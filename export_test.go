@@ -0,0 +1,250 @@
+package mermaid
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type drawioTestCell struct {
+	ID       string `xml:"id,attr"`
+	Value    string `xml:"value,attr"`
+	Style    string `xml:"style,attr"`
+	Vertex   string `xml:"vertex,attr"`
+	Edge     string `xml:"edge,attr"`
+	Parent   string `xml:"parent,attr"`
+	Source   string `xml:"source,attr"`
+	Target   string `xml:"target,attr"`
+	Geometry struct {
+		X      float64 `xml:"x,attr"`
+		Y      float64 `xml:"y,attr"`
+		Width  float64 `xml:"width,attr"`
+		Height float64 `xml:"height,attr"`
+		Points []struct {
+			X float64 `xml:"x,attr"`
+			Y float64 `xml:"y,attr"`
+		} `xml:"Array>mxPoint"`
+	} `xml:"mxGeometry"`
+}
+
+func decodeDrawIO(t *testing.T, data []byte) map[string]drawioTestCell {
+	t.Helper()
+	var file struct {
+		Cells []drawioTestCell `xml:"diagram>mxGraphModel>root>mxCell"`
+	}
+	if err := xml.Unmarshal(data, &file); err != nil {
+		t.Fatalf("decode draw.io XML: %v", err)
+	}
+	cells := map[string]drawioTestCell{}
+	for _, cell := range file.Cells {
+		cells[cell.ID] = cell
+	}
+	return cells
+}
+
+func readSample(t *testing.T, name string) string {
+	t.Helper()
+	content, err := os.ReadFile(filepath.Join("samples", name))
+	if err != nil {
+		t.Fatalf("read sample: %v", err)
+	}
+	return string(content)
+}
+
+func TestDrawIOFlowchart(t *testing.T) {
+	data, err := RenderDrawIO("flowchart LR\n  A[Start] --> B{Check}\n  B -->|yes <ok>| C((Done))\n  B -.-> A", DefaultRenderOptions())
+	if err != nil {
+		t.Fatalf("RenderDrawIO() error = %v", err)
+	}
+	cells := decodeDrawIO(t, data)
+	for id, shape := range map[string]string{"node-A": "rounded=0;", "node-B": "rhombus;", "node-C": "ellipse;"} {
+		cell, ok := cells[id]
+		if !ok || cell.Vertex != "1" || cell.Parent != "1" {
+			t.Fatalf("expected a top-level vertex %s, got %+v", id, cell)
+		}
+		if !strings.HasPrefix(cell.Style, shape) || cell.Geometry.Width <= 0 {
+			t.Fatalf("unexpected %s cell: style %q, width %v", id, cell.Style, cell.Geometry.Width)
+		}
+	}
+	if cells["node-A"].Value != "Start" {
+		t.Fatalf("unexpected label %q", cells["node-A"].Value)
+	}
+
+	var labelled, dotted drawioTestCell
+	for _, cell := range cells {
+		if cell.Edge != "1" {
+			continue
+		}
+		switch {
+		case cell.Source == "node-B" && cell.Target == "node-C":
+			labelled = cell
+		case cell.Source == "node-B" && cell.Target == "node-A":
+			dotted = cell
+		}
+	}
+	if labelled.Value != "yes <ok>" || !strings.Contains(labelled.Style, "endArrow=block;") {
+		t.Fatalf("unexpected labelled edge: %+v", labelled)
+	}
+	if len(labelled.Geometry.Points) == 0 {
+		t.Fatal("expected the routed waypoints to be kept")
+	}
+	if !strings.Contains(dotted.Style, "dashed=1;") {
+		t.Fatalf("expected the dotted edge to be dashed, got %q", dotted.Style)
+	}
+}
+
+func TestDrawIONestsCompositeStates(t *testing.T) {
+	source := readSample(t, "state_complex.mmd")
+	layout, err := computeExportLayout(source, DefaultRenderOptions())
+	if err != nil {
+		t.Fatalf("layout: %v", err)
+	}
+	data, err := MarshalDrawIO(layout, DefaultRenderOptions().Theme)
+	if err != nil {
+		t.Fatalf("MarshalDrawIO() error = %v", err)
+	}
+	cells := decodeDrawIO(t, data)
+	nested := 0
+	for _, cell := range cells {
+		if cell.Vertex != "1" || cell.Parent == "1" {
+			continue
+		}
+		parent, ok := cells[cell.Parent]
+		if !ok || !strings.Contains(parent.Style, "container=1;") {
+			t.Fatalf("%s has parent %q, which is not a container", cell.ID, cell.Parent)
+		}
+		if cell.Geometry.X < 0 || cell.Geometry.Y < 0 || cell.Geometry.X+cell.Geometry.Width > parent.Geometry.Width+1 {
+			t.Fatalf("expected %s to be placed relative to %s", cell.ID, cell.Parent)
+		}
+		nested++
+	}
+	if nested == 0 {
+		t.Fatal("expected states inside composite states to be nested")
+	}
+}
+
+func TestDrawIOConnectsSubgraphEdges(t *testing.T) {
+	data, err := RenderDrawIO("flowchart TB\n  subgraph inner [Inner]\n    a\n  end\n  subgraph other [Other]\n    b\n  end\n  inner --> other", DefaultRenderOptions())
+	if err != nil {
+		t.Fatalf("RenderDrawIO() error = %v", err)
+	}
+	cells := decodeDrawIO(t, data)
+	for id, label := range map[string]string{"node-inner": "Inner", "node-other": "Other"} {
+		cell, ok := cells[id]
+		if !ok || !strings.Contains(cell.Style, "container=1;") || cell.Value != label {
+			t.Fatalf("expected container %s labelled %q, got %+v", id, label, cell)
+		}
+	}
+	found := false
+	for _, cell := range cells {
+		if cell.Edge == "1" && cell.Source == "node-inner" && cell.Target == "node-other" {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatal("expected the edge between subgraphs to connect their containers")
+	}
+}
+
+func TestDrawIOClassCompartments(t *testing.T) {
+	data, err := RenderDrawIO("classDiagram\n  class Animal {\n    +String name\n    +eat() void\n  }\n  Animal <|-- Dog", DefaultRenderOptions())
+	if err != nil {
+		t.Fatalf("RenderDrawIO() error = %v", err)
+	}
+	cells := decodeDrawIO(t, data)
+	animal := cells["node-Animal"]
+	if !strings.Contains(animal.Value, "<hr") || !strings.Contains(animal.Value, "name") || !strings.Contains(animal.Value, "eat()") {
+		t.Fatalf("expected members in compartments, got %q", animal.Value)
+	}
+	found := false
+	for _, cell := range cells {
+		if cell.Edge == "1" && (strings.Contains(cell.Style, "Arrow=block;") && strings.Contains(cell.Style, "Fill=0;")) {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatal("expected inheritance to use a hollow block arrow")
+	}
+}
+
+func TestExportSupportedKinds(t *testing.T) {
+	options := DefaultRenderOptions()
+	for _, name := range []string{"flowchart.mmd", "state_complex.mmd", "class_complex.mmd", "er_complex.mmd", "architecture_complex.mmd"} {
+		source := readSample(t, name)
+		if _, err := RenderDrawIO(source, options); err != nil {
+			t.Fatalf("%s: RenderDrawIO() error = %v", name, err)
+		}
+		if _, err := RenderExcalidraw(source, options); err != nil {
+			t.Fatalf("%s: RenderExcalidraw() error = %v", name, err)
+		}
+	}
+	if _, err := RenderDrawIO("pie\n  \"a\": 1", options); err == nil || !strings.Contains(err.Error(), "not pie") {
+		t.Fatalf("expected an unsupported kind error, got %v", err)
+	}
+	if _, err := RenderExcalidraw("sequenceDiagram\n  A->>B: hi", options); err == nil || !strings.Contains(err.Error(), "Excalidraw") {
+		t.Fatalf("expected an unsupported kind error, got %v", err)
+	}
+}
+
+func TestExcalidrawFlowchart(t *testing.T) {
+	data, err := RenderExcalidraw("flowchart LR\n  A[Start] -->|go| B{Check}", DefaultRenderOptions())
+	if err != nil {
+		t.Fatalf("RenderExcalidraw() error = %v", err)
+	}
+	var file struct {
+		Type     string `json:"type"`
+		Version  int    `json:"version"`
+		Elements []struct {
+			ID            string `json:"id"`
+			Type          string `json:"type"`
+			Text          string `json:"text"`
+			ContainerID   string `json:"containerId"`
+			BoundElements []struct {
+				ID string `json:"id"`
+			} `json:"boundElements"`
+			Points       [][2]float64 `json:"points"`
+			StartBinding *struct {
+				ElementID string `json:"elementId"`
+			} `json:"startBinding"`
+			EndBinding *struct {
+				ElementID string `json:"elementId"`
+			} `json:"endBinding"`
+			EndArrowhead *string `json:"endArrowhead"`
+		} `json:"elements"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		t.Fatalf("decode Excalidraw JSON: %v", err)
+	}
+	if file.Type != "excalidraw" || file.Version != 2 {
+		t.Fatalf("unexpected header: %q version %d", file.Type, file.Version)
+	}
+	types := map[string]string{}
+	texts := map[string]string{}
+	for _, element := range file.Elements {
+		types[element.ID] = element.Type
+		if element.Type == "text" {
+			texts[element.ContainerID] = element.Text
+		}
+		if element.Type != "arrow" {
+			continue
+		}
+		if element.StartBinding == nil || element.StartBinding.ElementID != "node-A" || element.EndBinding == nil || element.EndBinding.ElementID != "node-B" {
+			t.Fatalf("expected the arrow to be bound to both nodes, got %+v", element)
+		}
+		if len(element.Points) < 2 || element.Points[0] != [2]float64{0, 0} {
+			t.Fatalf("expected points relative to the arrow, got %v", element.Points)
+		}
+		if element.EndArrowhead == nil || *element.EndArrowhead != "arrow" {
+			t.Fatal("expected an arrowhead at the end")
+		}
+	}
+	if types["node-A"] != "rectangle" || types["node-B"] != "diamond" {
+		t.Fatalf("unexpected shapes: %v", types)
+	}
+	if texts["node-A"] != "Start" || texts["node-B"] != "Check" || texts["edge-0"] != "go" {
+		t.Fatalf("expected labels bound to their shapes, got %v", texts)
+	}
+}
@@ -0,0 +1,307 @@
+package mermaid
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"golang.org/x/image/font/sfnt"
+)
+
+// fontRegistry holds every font text can be measured and drawn with:
+// fonts added by RegisterFont, the files found in the font directories and
+// the bundled fallback fonts. Fonts that are not files get a pseudo path
+// whose data lives in the registry. serial changes with every update, so
+// font chains cached under an older serial are not used again.
+var fontRegistry = struct {
+	sync.RWMutex
+	dirs            []string
+	customDirs      bool
+	indexed         bool
+	system          []indexedFontFile
+	registered      []indexedFontFile
+	data            map[string][]byte
+	fallbacks       []string
+	customFallbacks bool
+	serial          int
+}{data: map[string][]byte{}}
+
+var (
+	fontChainCache sync.Map // map[string][]string, keyed by serial and family
+	glyphCoverage  sync.Map // map[fontGlyphKey]sfnt.GlyphIndex
+)
+
+type fontGlyphKey struct {
+	path string
+	r    rune
+}
+
+// bundledFonts are the fallback fonts compiled into the binary, used when
+// no registered or installed font matches a family. Building with the
+// mmdg_nofonts tag leaves them out.
+var bundledFonts []bundledFont
+
+type bundledFont struct {
+	name string
+	data []byte
+}
+
+// RegisterFont makes a TrueType or OpenType font available under a family
+// name, ahead of installed fonts. Register style variants with the style
+// appended to the family, e.g. "Inter" and "Inter Bold", so bold and italic
+// labels find them. Registering a name again replaces the font.
+func RegisterFont(name string, data []byte) error {
+	key := normalizeFontToken(name)
+	if key == "" {
+		return fmt.Errorf("font name %q has no letters or digits", name)
+	}
+	if _, err := sfnt.Parse(data); err != nil {
+		if _, errCollection := sfnt.ParseCollection(data); errCollection != nil {
+			return fmt.Errorf("font %q: %w", name, err)
+		}
+	}
+	fontRegistry.Lock()
+	defer fontRegistry.Unlock()
+	// Each registration gets a fresh path so cached faces of a replaced
+	// font are never reused.
+	fontRegistry.serial++
+	path := fmt.Sprintf("registered:%d:%s", fontRegistry.serial, key)
+	fontRegistry.data[path] = append([]byte(nil), data...)
+	registered := []indexedFontFile{{Name: key, Path: path}}
+	for _, file := range fontRegistry.registered {
+		if file.Name == key {
+			delete(fontRegistry.data, file.Path)
+		} else {
+			registered = append(registered, file)
+		}
+	}
+	fontRegistry.registered = registered
+	return nil
+}
+
+// SetFontDirs replaces the directories searched for installed fonts, which
+// default to DefaultFontDirs. Installed fonts are preferred over the
+// bundled ones, so by default layouts depend on the machine. Calling
+// SetFontDirs with no directories is the switch to registered and bundled
+// fonts only, so text measures the same on every machine.
+func SetFontDirs(dirs ...string) {
+	fontRegistry.Lock()
+	defer fontRegistry.Unlock()
+	fontRegistry.dirs = append([]string(nil), dirs...)
+	fontRegistry.customDirs = true
+	fontRegistry.indexed = false
+	fontRegistry.system = nil
+	fontRegistry.serial++
+}
+
+// SetFallbackFonts replaces the families searched, in order, for characters
+// the theme's font lacks, such as CJK ideographs, Hangul, Arabic or emoji.
+// They default to DefaultFallbackFonts; the bundled font is always tried
+// last.
+func SetFallbackFonts(families ...string) {
+	fontRegistry.Lock()
+	defer fontRegistry.Unlock()
+	fontRegistry.fallbacks = append([]string(nil), families...)
+	fontRegistry.customFallbacks = true
+	fontRegistry.serial++
+}
+
+// DefaultFallbackFonts returns the fallback families used unless
+// SetFallbackFonts chose others: common CJK, Arabic, Hebrew, Indic, Thai
+// and emoji fonts of macOS, Windows and Linux distributions. Fonts are
+// matched by file name, so these are the names of their files.
+func DefaultFallbackFonts() []string {
+	return []string{
+		"Noto Sans CJK", "Source Han Sans", "PingFang", "Hiragino Sans",
+		"Hiragino Kaku Gothic", "msyh", "YuGothR", "malgun", "AppleSDGothicNeo",
+		"NanumGothic", "wqy-zenhei", "DroidSansFallback", "Noto Sans Arabic",
+		"Noto Naskh Arabic", "Noto Sans Hebrew", "Noto Sans Devanagari",
+		"Noto Sans Thai", "Arial Unicode", "DejaVu Sans", "Noto Sans",
+		"Noto Color Emoji", "Apple Color Emoji", "seguiemj", "Noto Emoji", "Symbola",
+	}
+}
+
+// fontChain returns the fonts the characters of fontFamily are looked up
+// in, in order: the font resolveFontPath picks, the family's other fonts,
+// the fallback families and the bundled font. Installed fonts come before
+// the bundled ones, so the chain differs between machines unless
+// SetFontDirs() has limited it to registered and bundled fonts.
+func fontChain(fontFamily string) []string {
+	fontRegistry.RLock()
+	serial := fontRegistry.serial
+	fallbacks := fontRegistry.fallbacks
+	if !fontRegistry.customFallbacks {
+		fallbacks = DefaultFallbackFonts()
+	}
+	fontRegistry.RUnlock()
+	key := fmt.Sprintf("%d|%s", serial, fontFamily)
+	if cached, ok := fontChainCache.Load(key); ok {
+		return cached.([]string)
+	}
+
+	var chain []string
+	add := func(path string) {
+		if path != "" && !slices.Contains(chain, path) {
+			chain = append(chain, path)
+		}
+	}
+	add(resolveFontPath(fontFamily))
+	for _, candidate := range parseFontFamilyCandidates(fontFamily) {
+		add(matchFontPath([]string{candidate}))
+	}
+	for _, family := range fallbacks {
+		add(matchFontPath(parseFontFamilyCandidates(family)))
+	}
+	if len(bundledFonts) > 0 {
+		add("bundled:" + bundledFonts[0].name)
+	}
+	fontChainCache.Store(key, chain)
+	return chain
+}
+
+// fontGlyph returns the glyph for r in the font at path, or 0 when the font
+// has none. Lookups are cached, since every character of every label is
+// looked up in each font of its chain until one has it.
+func fontGlyph(path string, r rune) sfnt.GlyphIndex {
+	key := fontGlyphKey{path: path, r: r}
+	if cached, ok := glyphCoverage.Load(key); ok {
+		return cached.(sfnt.GlyphIndex)
+	}
+	var glyph sfnt.GlyphIndex
+	if face := loadFontFace(path); face != nil {
+		var buf sfnt.Buffer
+		glyph, _ = face.GlyphIndex(&buf, r)
+	}
+	glyphCoverage.Store(key, glyph)
+	return glyph
+}
+
+// fontForRune returns the position in chain of the first font with a glyph
+// for r, the glyph and the character it is for: an Arabic presentation
+// form no font has is replaced by its letter. The position is -1 when no
+// font has the character.
+func fontForRune(chain []string, r rune) (int, sfnt.GlyphIndex, rune) {
+	for _, candidate := range []rune{r, arabicBase(r)} {
+		for i, path := range chain {
+			if glyph := fontGlyph(path, candidate); glyph != 0 {
+				return i, glyph, candidate
+			}
+		}
+	}
+	return -1, 0, r
+}
+
+// DefaultFontDirs returns the macOS and Linux font directories searched
+// unless SetFontDirs chose others.
+func DefaultFontDirs() []string {
+	dirs := []string{
+		"/System/Library/Fonts/Supplemental",
+		"/System/Library/Fonts",
+		"/Library/Fonts",
+		"/usr/share/fonts",
+		"/usr/local/share/fonts",
+	}
+	if home, err := os.UserHomeDir(); err == nil && strings.TrimSpace(home) != "" {
+		dirs = append(dirs,
+			filepath.Join(home, "Library", "Fonts"),
+			filepath.Join(home, ".fonts"),
+			filepath.Join(home, ".local", "share", "fonts"),
+		)
+	}
+	return dirs
+}
+
+// fontFiles returns the registered fonts followed by the installed ones,
+// indexing the font directories on first use, and the bundled fonts.
+func fontFiles() (files []indexedFontFile, bundled []indexedFontFile) {
+	fontRegistry.RLock()
+	if !fontRegistry.indexed {
+		fontRegistry.RUnlock()
+		fontRegistry.Lock()
+		if !fontRegistry.indexed {
+			dirs := fontRegistry.dirs
+			if !fontRegistry.customDirs {
+				dirs = DefaultFontDirs()
+			}
+			fontRegistry.system = indexFontDirs(dirs)
+			fontRegistry.indexed = true
+		}
+		fontRegistry.Unlock()
+		fontRegistry.RLock()
+	}
+	defer fontRegistry.RUnlock()
+	files = make([]indexedFontFile, 0, len(fontRegistry.registered)+len(fontRegistry.system))
+	files = append(files, fontRegistry.registered...)
+	files = append(files, fontRegistry.system...)
+	bundled = make([]indexedFontFile, 0, len(bundledFonts))
+	for _, f := range bundledFonts {
+		bundled = append(bundled, indexedFontFile{Name: f.name, Path: "bundled:" + f.name})
+	}
+	return files, bundled
+}
+
+// readFontData reads a font file, or the data of a registered or bundled
+// font.
+func readFontData(path string) ([]byte, error) {
+	if name, ok := strings.CutPrefix(path, "bundled:"); ok {
+		for _, f := range bundledFonts {
+			if f.name == name {
+				return f.data, nil
+			}
+		}
+		return nil, fmt.Errorf("no bundled font %q", name)
+	}
+	if strings.HasPrefix(path, "registered:") {
+		fontRegistry.RLock()
+		data, ok := fontRegistry.data[path]
+		fontRegistry.RUnlock()
+		if !ok {
+			return nil, fmt.Errorf("no registered font %q", path)
+		}
+		return data, nil
+	}
+	return os.ReadFile(path)
+}
+
+// indexFontDirs lists the font files under dirs by normalized file name,
+// shortest names first.
+func indexFontDirs(dirs []string) []indexedFontFile {
+	index := make([]indexedFontFile, 0, 256)
+	for _, dir := range dirs {
+		filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+			if d.IsDir() {
+				return nil
+			}
+
+			name := d.Name()
+			ext := strings.ToLower(filepath.Ext(name))
+			if ext != ".ttf" && ext != ".otf" && ext != ".ttc" {
+				return nil
+			}
+			base := strings.TrimSuffix(name, filepath.Ext(name))
+			norm := normalizeFontToken(base)
+			if norm == "" {
+				return nil
+			}
+			index = append(index, indexedFontFile{
+				Name: norm,
+				Path: path,
+			})
+			return nil
+		})
+	}
+	sort.Slice(index, func(i, j int) bool {
+		if index[i].Name == index[j].Name {
+			return index[i].Path < index[j].Path
+		}
+		return len(index[i].Name) < len(index[j].Name)
+	})
+	return index
+}
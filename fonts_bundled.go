@@ -0,0 +1,25 @@
+//go:build !mmdg_nofonts
+
+package mermaid
+
+import (
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/gobolditalic"
+	"golang.org/x/image/font/gofont/goitalic"
+	"golang.org/x/image/font/gofont/gomono"
+	"golang.org/x/image/font/gofont/gomonobold"
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+// The Go fonts are the bundled fallback: a humanist sans-serif close in
+// width to the browser default and a matching monospace for code spans.
+func init() {
+	bundledFonts = []bundledFont{
+		{name: "goregular", data: goregular.TTF},
+		{name: "gobold", data: gobold.TTF},
+		{name: "goitalic", data: goitalic.TTF},
+		{name: "gobolditalic", data: gobolditalic.TTF},
+		{name: "gomono", data: gomono.TTF},
+		{name: "gomonobold", data: gomonobold.TTF},
+	}
+}
@@ -0,0 +1,138 @@
+package mermaid
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+)
+
+func TestRegisterFontTakesPrecedence(t *testing.T) {
+	isolateFonts(t)
+	if err := RegisterFont("Test Sans", goregular.TTF); err != nil {
+		t.Fatalf("RegisterFont() error = %v", err)
+	}
+	if err := RegisterFont("Test Sans Bold", gobold.TTF); err != nil {
+		t.Fatalf("RegisterFont(bold) error = %v", err)
+	}
+	regular := resolveFontPath(`"Test Sans", arial, sans-serif`)
+	if !strings.HasPrefix(regular, "registered:") {
+		t.Fatalf("resolveFontPath() = %q, want the registered font", regular)
+	}
+	if bold := resolveStyledFontPath("Test Sans", true, false); bold == regular || !strings.HasPrefix(bold, "registered:") {
+		t.Fatalf("bold path = %q, want the registered bold font", bold)
+	}
+	if width, ok := measureNativeTextWidth("Hello", 16, "Test Sans"); !ok || width <= 0 {
+		t.Fatalf("measureNativeTextWidth() = %v, %v", width, ok)
+	}
+
+	if err := RegisterFont("Test Sans", gobold.TTF); err != nil {
+		t.Fatalf("RegisterFont(again) error = %v", err)
+	}
+	if again := resolveFontPath("Test Sans"); again == regular {
+		t.Fatal("expected registering a name again to replace the font")
+	}
+	if err := RegisterFont("Broken", []byte("not a font")); err == nil {
+		t.Fatal("expected an error for invalid font data")
+	}
+}
+
+func TestBundledFontsReplaceMissingSystemFonts(t *testing.T) {
+	isolateFonts(t)
+	if len(bundledFonts) == 0 {
+		t.Skip("built without bundled fonts")
+	}
+	SetFontDirs()
+	if got := resolveFontPath(defaultMetricFontFamily); got != "bundled:goregular" {
+		t.Fatalf("resolveFontPath(default) = %q", got)
+	}
+	if got := resolveFontPath("monospace"); got != "bundled:gomono" {
+		t.Fatalf("resolveFontPath(monospace) = %q", got)
+	}
+	if got := resolveStyledFontPath(defaultMetricFontFamily, true, true); got != "bundled:gobolditalic" {
+		t.Fatalf("resolveStyledFontPath(bold italic) = %q", got)
+	}
+	if _, ok := measureNativeTextWidth("Hello", 16, ""); !ok {
+		t.Fatal("expected text to be measured with the bundled font")
+	}
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "Fancy-Regular.ttf"), goregular.TTF, 0o644); err != nil {
+		t.Fatalf("write font: %v", err)
+	}
+	SetFontDirs(dir)
+	if got := resolveFontPath("Fancy"); got != filepath.Join(dir, "Fancy-Regular.ttf") {
+		t.Fatalf("resolveFontPath(Fancy) = %q", got)
+	}
+}
+
+func TestFontFallbackPerRune(t *testing.T) {
+	isolateFonts(t)
+	if len(bundledFonts) == 0 {
+		t.Skip("built without bundled fonts")
+	}
+	SetFontDirs()
+	SetFallbackFonts()
+	full, err := sfnt.Parse(goregular.TTF)
+	if err != nil {
+		t.Fatalf("parse font: %v", err)
+	}
+	tiny, err := subsetWebFont(goregular.TTF, full, []rune("AB"))
+	if err != nil {
+		t.Fatalf("subsetWebFont() error = %v", err)
+	}
+	if err := RegisterFont("Tiny Test", tiny); err != nil {
+		t.Fatalf("RegisterFont() error = %v", err)
+	}
+	chain := fontChain("Tiny Test")
+	if len(chain) != 2 || chain[1] != "bundled:goregular" {
+		t.Fatalf("fontChain() = %q, want the registered font then the bundled one", chain)
+	}
+	if index, _, _ := fontForRune(chain, 'C'); index != 1 {
+		t.Fatalf("'C' found in font %d, want the fallback", index)
+	}
+
+	var buf sfnt.Buffer
+	glyph, _ := full.GlyphIndex(&buf, 'C')
+	advance, _ := full.GlyphAdvance(&buf, glyph, fixed.I(16), font.HintingNone)
+	if got, _ := measureNativeTextWidth("C", 16, "Tiny Test"); math.Abs(got-float64(advance)/64) > 0.01 {
+		t.Fatalf("width of 'C' = %.2f, want the fallback advance %.2f", got, float64(advance)/64)
+	}
+	if got, _ := measureNativeTextWidth("日本", 16, "Tiny Test"); got != 32 {
+		t.Fatalf("width of uncovered CJK = %.2f, want a full em each", got)
+	}
+	face := resolveRasterFontFace("Tiny Test", 16)
+	if got, _ := face.GlyphAdvance('C'); got != advance {
+		t.Fatalf("raster advance of 'C' = %v, want %v", got, advance)
+	}
+}
+
+// isolateFonts restores the font directories, registered, fallback and
+// bundled fonts when the test ends.
+func isolateFonts(t *testing.T) {
+	t.Helper()
+	fontRegistry.Lock()
+	dirs, customDirs := fontRegistry.dirs, fontRegistry.customDirs
+	indexed, system := fontRegistry.indexed, fontRegistry.system
+	registered := fontRegistry.registered
+	fallbacks, customFallbacks := fontRegistry.fallbacks, fontRegistry.customFallbacks
+	fontRegistry.Unlock()
+	bundled := bundledFonts
+	t.Cleanup(func() {
+		fontRegistry.Lock()
+		fontRegistry.dirs, fontRegistry.customDirs = dirs, customDirs
+		fontRegistry.indexed, fontRegistry.system = indexed, system
+		fontRegistry.registered = registered
+		fontRegistry.fallbacks, fontRegistry.customFallbacks = fallbacks, customFallbacks
+		fontRegistry.serial++
+		fontRegistry.Unlock()
+		bundledFonts = bundled
+	})
+}
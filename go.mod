@@ -6,9 +6,7 @@ require (
 	github.com/srwiley/oksvg v0.0.0-20221011165216-be6e8873101c
 	github.com/srwiley/rasterx v0.0.0-20220730225603-2ab79fcdd4ef
 	golang.org/x/image v0.38.0
+	golang.org/x/text v0.35.0
 )
 
-require (
-	golang.org/x/net v0.38.0 // indirect
-	golang.org/x/text v0.35.0 // indirect
-)
+require golang.org/x/net v0.38.0 // indirect
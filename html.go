@@ -0,0 +1,311 @@
+package mermaid
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+)
+
+var (
+	// svgMarkupPattern matches style sheets and tags, leaving out the text
+	// content of an SVG such as its labels and <desc>.
+	svgMarkupPattern = regexp.MustCompile(`<style[^>]*>[^<]*</style>|<[^>]*>`)
+	// svgIDRefPattern matches the my-svg id, and ids built on it like
+	// clip-my-svg-0, in id attributes and # references.
+	svgIDRefPattern = regexp.MustCompile(`(id="|#)([\w-]*?)my-svg`)
+)
+
+// RenderHTML renders a Mermaid diagram into a standalone HTML page. See
+// RenderHTMLDocument.
+func RenderHTML(input string, options RenderOptions) (string, error) {
+	if strings.TrimSpace(input) == "" {
+		return "", errors.New("input diagram is empty")
+	}
+	return RenderHTMLDocument([]string{input}, options)
+}
+
+// RenderHTMLDocument renders each diagram as a section of one standalone
+// HTML page, e.g. the blocks ExtractMermaidBlocks finds in a markdown file.
+// The page needs no network access: styles and the pan/zoom script are
+// inline. Sections are anchored as #diagram-1, #diagram-2 and so on, and a
+// toggle switches between light and dark colors.
+func RenderHTMLDocument(inputs []string, options RenderOptions) (string, error) {
+	if len(inputs) == 0 {
+		return "", errors.New("no diagrams to render")
+	}
+	sections := make([]htmlSection, 0, len(inputs))
+	for i, input := range inputs {
+		s, err := renderHTMLSection(input, i+1, options)
+		if err != nil {
+			if len(inputs) > 1 {
+				return "", fmt.Errorf("diagram %d: %w", i+1, err)
+			}
+			return "", err
+		}
+		sections = append(sections, s)
+	}
+
+	title := strings.TrimSpace(options.HTML.Title)
+	if title == "" && len(sections) == 1 {
+		title = sections[0].title
+	}
+	if title == "" {
+		title = "Mermaid diagrams"
+	}
+
+	var b strings.Builder
+	b.WriteString("<!DOCTYPE html>\n<html lang=\"en\">\n<head>\n<meta charset=\"utf-8\">\n")
+	b.WriteString(`<meta name="viewport" content="width=device-width, initial-scale=1">` + "\n")
+	b.WriteString("<title>" + html.EscapeString(title) + "</title>\n")
+	b.WriteString("<style>" + htmlPageStyle + "</style>\n</head>\n<body>\n")
+	b.WriteString("<header>\n<h1>" + html.EscapeString(title) + "</h1>\n")
+	b.WriteString(`<button type="button" id="theme-toggle" title="Toggle dark mode">Dark mode</button>` + "\n</header>\n")
+	if len(sections) > 1 {
+		b.WriteString("<nav>\n<ol>\n")
+		for i, s := range sections {
+			fmt.Fprintf(&b, "<li><a href=\"#diagram-%d\">%s</a></li>\n", i+1, html.EscapeString(s.title))
+		}
+		b.WriteString("</ol>\n</nav>\n")
+	}
+	b.WriteString("<main>\n")
+	for i, s := range sections {
+		id := fmt.Sprintf("diagram-%d", i+1)
+		fmt.Fprintf(&b, "<section class=\"diagram\" id=\"%s\">\n", id)
+		fmt.Fprintf(&b, "<h2><a href=\"#%s\">%s</a></h2>\n", id, html.EscapeString(s.title))
+		b.WriteString(`<div class="tools"><button type="button" data-zoom="in" title="Zoom in">+</button>` +
+			`<button type="button" data-zoom="out" title="Zoom out">&#8722;</button>` +
+			`<button type="button" data-zoom="fit" title="Fit to view">Fit</button></div>` + "\n")
+		fmt.Fprintf(&b, "<div class=\"viewport\" data-width=\"%d\" data-height=\"%d\" style=\"--h: %dpx\">", s.w, s.h, s.h)
+		fmt.Fprintf(&b, "<div class=\"stage\" style=\"width: %dpx; height: %dpx\">\n", s.w, s.h)
+		b.WriteString(s.svg)
+		b.WriteString("\n</div></div>\n</section>\n")
+	}
+	b.WriteString("</main>\n<script>" + htmlPageScript + "</script>\n</body>\n</html>\n")
+	return b.String(), nil
+}
+
+// htmlSection is one diagram of an HTML page with its intrinsic size.
+type htmlSection struct {
+	title string
+	svg   string
+	w, h  int
+}
+
+// renderHTMLSection renders diagram n of an HTML page. The SVG ids are
+// numbered so the markers and scoped styles of several diagrams do not
+// collide in one document.
+func renderHTMLSection(input string, n int, options RenderOptions) (section htmlSection, err error) {
+	if strings.TrimSpace(input) == "" {
+		return section, errors.New("input diagram is empty")
+	}
+	parsed, err := ParseMermaid(input)
+	if err != nil {
+		return section, err
+	}
+	if err := ensureHighFidelityOrAllowApproximate(parsed.Graph.Kind, options); err != nil {
+		return section, err
+	}
+	layout := ComputeLayout(&parsed.Graph, options.Theme, options.Layout)
+	svg := RenderSVG(layout, options.Theme, options.Layout)
+	if start := strings.Index(svg, "<svg"); start > 0 {
+		svg = svg[start:]
+	}
+	section.svg = renumberSVGIDs(svg, n)
+	section.w, section.h = detectSVGSize(svg)
+	section.title = strings.TrimSpace(parsed.Graph.Title)
+	if section.title == "" {
+		section.title = strings.TrimSpace(parsed.Graph.AccTitle)
+	}
+	if section.title == "" {
+		section.title = fmt.Sprintf("Diagram %d", n)
+	}
+	return section, nil
+}
+
+// renumberSVGIDs appends n to the my-svg id of svg and to every reference
+// to it.
+func renumberSVGIDs(svg string, n int) string {
+	replacement := fmt.Sprintf("${1}${2}my-svg-%d", n)
+	return svgMarkupPattern.ReplaceAllStringFunc(svg, func(markup string) string {
+		return svgIDRefPattern.ReplaceAllString(markup, replacement)
+	})
+}
+
+// WriteHTMLFromSource renders a Mermaid diagram to an HTML file, or to
+// stdout when outputPath is empty. See RenderHTML.
+func WriteHTMLFromSource(mermaidCode string, outputPath string, options RenderOptions) error {
+	page, err := RenderHTML(mermaidCode, options)
+	if err != nil {
+		return err
+	}
+	return writeOutputBytes([]byte(page), outputPath)
+}
+
+// WriteHTMLDocumentFromSource renders several diagrams into one HTML file.
+// See RenderHTMLDocument.
+func WriteHTMLDocumentFromSource(diagrams []string, outputPath string, options RenderOptions) error {
+	page, err := RenderHTMLDocument(diagrams, options)
+	if err != nil {
+		return err
+	}
+	return writeOutputBytes([]byte(page), outputPath)
+}
+
+// htmlPageStyle colors the page through CSS variables. Dark mode follows
+// the system preference until the toggle picks one, and inverts the
+// diagrams' light theme colors.
+const htmlPageStyle = `
+:root {
+  color-scheme: light;
+  --bg: #f6f8fa;
+  --fg: #1f2328;
+  --muted: #59636e;
+  --card: #ffffff;
+  --border: #d1d9e0;
+  --accent: #0969da;
+  --diagram-filter: none;
+}
+:root[data-theme="dark"] {
+  color-scheme: dark;
+  --bg: #0d1117;
+  --fg: #e6edf3;
+  --muted: #9198a1;
+  --card: #151b23;
+  --border: #3d444d;
+  --accent: #4493f8;
+  --diagram-filter: invert(0.9) hue-rotate(180deg);
+}
+@media (prefers-color-scheme: dark) {
+  :root:not([data-theme="light"]) {
+    color-scheme: dark;
+    --bg: #0d1117;
+    --fg: #e6edf3;
+    --muted: #9198a1;
+    --card: #151b23;
+    --border: #3d444d;
+    --accent: #4493f8;
+    --diagram-filter: invert(0.9) hue-rotate(180deg);
+  }
+}
+* { box-sizing: border-box; }
+body {
+  margin: 0;
+  padding: 0 24px 48px;
+  background: var(--bg);
+  color: var(--fg);
+  font: 15px/1.5 -apple-system, BlinkMacSystemFont, "Segoe UI", Helvetica, Arial, sans-serif;
+}
+header { display: flex; align-items: center; justify-content: space-between; gap: 16px; padding: 16px 0; }
+h1 { margin: 0; font-size: 22px; }
+h2 { margin: 0; font-size: 17px; }
+a { color: var(--accent); text-decoration: none; }
+a:hover { text-decoration: underline; }
+nav ol { margin: 0 0 16px; padding-left: 24px; color: var(--muted); }
+button {
+  font: inherit;
+  color: var(--fg);
+  background: var(--card);
+  border: 1px solid var(--border);
+  border-radius: 6px;
+  padding: 2px 10px;
+  cursor: pointer;
+}
+.diagram {
+  position: relative;
+  margin: 0 0 24px;
+  padding: 12px 16px 16px;
+  background: var(--card);
+  border: 1px solid var(--border);
+  border-radius: 8px;
+}
+.tools { position: absolute; top: 10px; right: 16px; display: flex; gap: 4px; }
+.viewport {
+  position: relative;
+  margin-top: 12px;
+  height: max(120px, min(var(--h), 80vh));
+  overflow: hidden;
+  cursor: grab;
+  touch-action: none;
+}
+.viewport.dragging { cursor: grabbing; }
+.stage { position: absolute; left: 0; top: 0; transform-origin: 0 0; filter: var(--diagram-filter); }
+.stage > svg { display: block; width: 100%; height: 100%; max-width: none !important; }
+`
+
+// htmlPageScript implements wheel and button zoom, drag to pan, double
+// click to fit, and the dark mode toggle.
+const htmlPageScript = `
+(function () {
+  document.querySelectorAll(".diagram").forEach(function (section) {
+    var view = section.querySelector(".viewport");
+    var stage = section.querySelector(".stage");
+    var w = +view.dataset.width, h = +view.dataset.height;
+    var s = 1, x = 0, y = 0, drag = null;
+    function apply() {
+      stage.style.transform = "translate(" + x + "px," + y + "px) scale(" + s + ")";
+    }
+    function fit() {
+      var r = view.getBoundingClientRect();
+      s = Math.min(r.width / w, r.height / h, 1);
+      x = (r.width - w * s) / 2;
+      y = (r.height - h * s) / 2;
+      apply();
+    }
+    function zoom(f, cx, cy) {
+      var n = Math.min(Math.max(s * f, 0.05), 40);
+      x = cx - (cx - x) * n / s;
+      y = cy - (cy - y) * n / s;
+      s = n;
+      apply();
+    }
+    function zoomCenter(f) {
+      var r = view.getBoundingClientRect();
+      zoom(f, r.width / 2, r.height / 2);
+    }
+    view.addEventListener("wheel", function (e) {
+      e.preventDefault();
+      var r = view.getBoundingClientRect();
+      zoom(Math.exp(-e.deltaY * 0.002), e.clientX - r.left, e.clientY - r.top);
+    }, { passive: false });
+    view.addEventListener("pointerdown", function (e) {
+      drag = { x: e.clientX - x, y: e.clientY - y };
+      view.setPointerCapture(e.pointerId);
+      view.classList.add("dragging");
+    });
+    view.addEventListener("pointermove", function (e) {
+      if (drag) {
+        x = e.clientX - drag.x;
+        y = e.clientY - drag.y;
+        apply();
+      }
+    });
+    view.addEventListener("pointerup", function () {
+      drag = null;
+      view.classList.remove("dragging");
+    });
+    view.addEventListener("dblclick", fit);
+    section.querySelector('[data-zoom="in"]').addEventListener("click", function () { zoomCenter(1.25); });
+    section.querySelector('[data-zoom="out"]').addEventListener("click", function () { zoomCenter(0.8); });
+    section.querySelector('[data-zoom="fit"]').addEventListener("click", fit);
+    window.addEventListener("resize", fit);
+    fit();
+  });
+
+  var root = document.documentElement;
+  try {
+    var saved = localStorage.getItem("mmdg-theme");
+    if (saved) root.dataset.theme = saved;
+  } catch (e) {}
+  document.getElementById("theme-toggle").addEventListener("click", function () {
+    var dark = root.dataset.theme
+      ? root.dataset.theme !== "dark"
+      : !window.matchMedia("(prefers-color-scheme: dark)").matches;
+    root.dataset.theme = dark ? "dark" : "light";
+    try {
+      localStorage.setItem("mmdg-theme", root.dataset.theme);
+    } catch (e) {}
+  });
+})();
+`
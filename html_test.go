@@ -0,0 +1,68 @@
+package mermaid
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderHTMLDocumentAnchorsEachDiagram(t *testing.T) {
+	markdown := "# Doc\n\n```mermaid\n---\ntitle: Flow\n---\nflowchart LR\n  A --> B\n```\n\n```mermaid\nsequenceDiagram\n  Alice->>Bob: Hi\n```\n"
+	page, err := RenderHTMLDocument(ExtractMermaidBlocks(markdown), DefaultRenderOptions().WithHTMLTitle("Design <doc>"))
+	if err != nil {
+		t.Fatalf("RenderHTMLDocument() error = %v", err)
+	}
+	for _, want := range []string{
+		"<!DOCTYPE html>",
+		"<title>Design &lt;doc&gt;</title>",
+		`<section class="diagram" id="diagram-1">`,
+		`<section class="diagram" id="diagram-2">`,
+		`<a href="#diagram-1">Flow</a>`,
+		`<a href="#diagram-2">Diagram 2</a>`,
+		`id="my-svg-1"`,
+		`id="my-svg-2"`,
+		`url(#my-svg-1_flowchart-v2-pointEnd)`,
+		`id="theme-toggle"`,
+		`:root[data-theme="dark"]`,
+	} {
+		if !strings.Contains(page, want) {
+			t.Fatalf("page is missing %q", want)
+		}
+	}
+	if strings.Contains(page, `id="my-svg"`) || strings.Contains(page, "<?xml") {
+		t.Fatal("expected the SVG ids to be numbered and the XML declarations dropped")
+	}
+	for _, external := range []string{`src="http`, `href="http`, "@import"} {
+		if strings.Contains(page, external) {
+			t.Fatalf("page loads an external resource: %q", external)
+		}
+	}
+
+	if _, err := RenderHTMLDocument([]string{"flowchart LR\n  A --> B", " "}, DefaultRenderOptions()); err == nil || !strings.Contains(err.Error(), "diagram 2") {
+		t.Fatalf("expected an error naming diagram 2, got %v", err)
+	}
+}
+
+func TestRenderHTMLDocumentKeepsLabelText(t *testing.T) {
+	page, err := RenderHTMLDocument([]string{"flowchart LR\n  accDescr: Styled by #my-svg rules\n  A[my-svg label] --> B"}, DefaultRenderOptions())
+	if err != nil {
+		t.Fatalf("RenderHTMLDocument() error = %v", err)
+	}
+	for _, want := range []string{"my-svg label", "Styled by #my-svg rules", `id="my-svg-1"`, "#my-svg-1 "} {
+		if !strings.Contains(page, want) {
+			t.Fatalf("page is missing %q", want)
+		}
+	}
+}
+
+func TestRenderHTMLUsesDiagramTitle(t *testing.T) {
+	page, err := RenderHTML("flowchart TD\n  accTitle: Login flow\n  A --> B", DefaultRenderOptions())
+	if err != nil {
+		t.Fatalf("RenderHTML() error = %v", err)
+	}
+	if !strings.Contains(page, "<title>Login flow</title>") {
+		t.Fatal("expected the page to take the diagram's title")
+	}
+	if strings.Contains(page, "<nav>") {
+		t.Fatal("expected no table of contents for a single diagram")
+	}
+}
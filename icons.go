@@ -0,0 +1,380 @@
+package mermaid
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/xml"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"math"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/srwiley/oksvg"
+)
+
+const (
+	defaultIconSize  = 48.0
+	defaultImageSize = 100.0
+	iconFormPadding  = 8.0
+	assetLabelGap    = 4.0
+)
+
+// unknownIconSVG is drawn for icon names that are not in the registry.
+const unknownIconSVG = `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 24 24" fill="none" stroke="currentColor" stroke-width="2" stroke-linecap="round"><rect x="2" y="2" width="20" height="20" rx="3"/><path d="M9 9a3 3 0 1 1 4 2.8c-.6.3-1 .8-1 1.5V14"/><circle cx="12" cy="17.5" r="0.6" fill="currentColor"/></svg>`
+
+var iconRegistry = struct {
+	sync.RWMutex
+	icons map[string]string
+}{icons: map[string]string{}}
+
+// RegisterIcon adds an SVG document to the icon registry used by flowchart
+// icon nodes. name is the full "pack:icon" reference used in diagrams, for
+// example "fa:user". Paths drawn with currentColor, or without a fill, take
+// the node's icon colour.
+func RegisterIcon(name, svg string) {
+	key := lower(strings.TrimSpace(name))
+	if key == "" {
+		return
+	}
+	iconRegistry.Lock()
+	iconRegistry.icons[key] = svg
+	iconRegistry.Unlock()
+}
+
+func lookupIcon(name string) (string, bool) {
+	iconRegistry.RLock()
+	defer iconRegistry.RUnlock()
+	svg, ok := iconRegistry.icons[lower(strings.TrimSpace(name))]
+	return svg, ok
+}
+
+// iconDataURI resolves an icon name to a base64 SVG data URI painted in color.
+func iconDataURI(name, color string) string {
+	svg, ok := lookupIcon(name)
+	if !ok {
+		svg = unknownIconSVG
+	}
+	svg = strings.ReplaceAll(svg, "currentColor", color)
+	if start := strings.Index(svg, "<svg"); start >= 0 {
+		end := strings.Index(svg[start:], ">")
+		if end > 0 && !strings.Contains(svg[start:start+end], " fill=") {
+			svg = svg[:start+4] + ` fill="` + color + `"` + svg[start+4:]
+		}
+	}
+	return "data:image/svg+xml;base64," + base64.StdEncoding.EncodeToString([]byte(svg))
+}
+
+// resolveImageAsset turns an img reference into the href written to the SVG
+// together with the natural image size, when it can be determined. Local
+// files inside one of assetDirs are embedded as data URIs; remote URLs, and
+// local paths that are not allowed, are kept as-is.
+func resolveImageAsset(src string, assetDirs []string) (href string, width, height float64) {
+	src = strings.TrimSpace(src)
+	if mime, data, ok := decodeDataURI(src); ok {
+		width, height = imageAssetSize(mime, data)
+		return src, width, height
+	}
+	path := src
+	if strings.HasPrefix(lower(src), "file://") {
+		parsed, err := url.Parse(src)
+		if err != nil {
+			return src, 0, 0
+		}
+		path = parsed.Path
+	} else if strings.Contains(src, "://") {
+		return src, 0, 0
+	}
+	data, ok := readAssetFile(path, assetDirs)
+	if !ok {
+		return src, 0, 0
+	}
+	mime := imageAssetMIME(path, data)
+	if mime == "" {
+		return src, 0, 0
+	}
+	width, height = imageAssetSize(mime, data)
+	return "data:" + mime + ";base64," + base64.StdEncoding.EncodeToString(data), width, height
+}
+
+// readAssetFile reads path from the first of dirs that contains it. Reads
+// go through os.Root, so neither ".." nor symlinks lead outside the
+// directory.
+func readAssetFile(path string, dirs []string) ([]byte, bool) {
+	for _, dir := range dirs {
+		rel := path
+		if filepath.IsAbs(path) {
+			abs, err := filepath.Abs(dir)
+			if err != nil {
+				continue
+			}
+			if rel, err = filepath.Rel(abs, path); err != nil {
+				continue
+			}
+		}
+		if !filepath.IsLocal(rel) {
+			continue
+		}
+		root, err := os.OpenRoot(dir)
+		if err != nil {
+			continue
+		}
+		data, err := root.ReadFile(rel)
+		root.Close()
+		if err == nil {
+			return data, true
+		}
+	}
+	return nil, false
+}
+
+// imageAssetMIME returns the type of an image file, or "" when it is not
+// an image browsers display: SVG files must have an <svg> root element.
+func imageAssetMIME(path string, data []byte) string {
+	if lower(filepath.Ext(path)) == ".svg" {
+		if isSVGDocument(data) {
+			return "image/svg+xml"
+		}
+		return ""
+	}
+	switch mime := http.DetectContentType(data); mime {
+	case "image/png", "image/jpeg", "image/gif", "image/webp", "image/bmp":
+		return mime
+	}
+	return ""
+}
+
+func isSVGDocument(data []byte) bool {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return false
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			return start.Name.Local == "svg"
+		}
+	}
+}
+
+// decodeDataURI splits a base64 or percent-encoded data URI.
+func decodeDataURI(uri string) (mime string, data []byte, ok bool) {
+	if !strings.HasPrefix(lower(uri), "data:") {
+		return "", nil, false
+	}
+	comma := strings.IndexByte(uri, ',')
+	if comma < 0 {
+		return "", nil, false
+	}
+	meta := uri[len("data:"):comma]
+	payload := uri[comma+1:]
+	params := strings.Split(meta, ";")
+	mime = lower(strings.TrimSpace(params[0]))
+	if params[len(params)-1] == "base64" {
+		decoded, err := base64.StdEncoding.DecodeString(payload)
+		if err != nil {
+			return "", nil, false
+		}
+		return mime, decoded, true
+	}
+	decoded, err := url.PathUnescape(payload)
+	if err != nil {
+		return "", nil, false
+	}
+	return mime, []byte(decoded), true
+}
+
+func imageAssetSize(mime string, data []byte) (float64, float64) {
+	if mime == "image/svg+xml" {
+		icon, err := oksvg.ReadIconStream(bytes.NewReader(data), oksvg.IgnoreErrorMode)
+		if err != nil {
+			return 0, 0
+		}
+		return icon.ViewBox.W, icon.ViewBox.H
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return 0, 0
+	}
+	return float64(config.Width), float64(config.Height)
+}
+
+// resolveNodeAssets embeds local images from assetDirs and settles the icon
+// or image size of a flowchart node before it is measured.
+func resolveNodeAssets(node Node, assetDirs []string) Node {
+	switch {
+	case node.Icon != "":
+		size := max(node.AssetWidth, node.AssetHeight)
+		if size <= 0 {
+			size = defaultIconSize
+		}
+		node.AssetWidth = size
+		node.AssetHeight = size
+	case node.Img != "":
+		href, naturalW, naturalH := resolveImageAsset(node.Img, assetDirs)
+		node.Img = href
+		aspect := 1.0
+		if naturalW > 0 && naturalH > 0 {
+			aspect = naturalW / naturalH
+		}
+		width := node.AssetWidth
+		if width <= 0 {
+			switch {
+			case node.AssetHeight > 0:
+				width = node.AssetHeight * aspect
+			case naturalW > 0:
+				width = naturalW
+			default:
+				width = defaultImageSize
+			}
+		}
+		height := node.AssetHeight
+		if node.Constraint || height <= 0 {
+			height = width / aspect
+		}
+		node.AssetWidth = width
+		node.AssetHeight = height
+	}
+	return node
+}
+
+func isAssetShape(shape NodeShape) bool {
+	switch shape {
+	case ShapeIcon, ShapeIconSquare, ShapeIconCircle, ShapeIconRounded, ShapeImage:
+		return true
+	}
+	return false
+}
+
+// assetFrameSize is the size of the icon background or image box.
+func assetFrameSize(shape NodeShape, assetW, assetH float64) (float64, float64) {
+	switch shape {
+	case ShapeIconSquare, ShapeIconRounded:
+		return assetW + 2*iconFormPadding, assetH + 2*iconFormPadding
+	case ShapeIconCircle:
+		d := max(assetW+2*iconFormPadding, assetW*math.Sqrt2)
+		return d, d
+	}
+	return assetW, assetH
+}
+
+func assetLabelHeight(label string) float64 {
+	if strings.TrimSpace(label) == "" {
+		return 0
+	}
+	return float64(len(splitLinesPreserve(label))) * 24.0
+}
+
+// assetNodeSize measures icon and image nodes: the frame plus the label
+// stacked above or below it.
+func assetNodeSize(node Node, config LayoutConfig) (float64, float64) {
+	frameW, frameH := assetFrameSize(node.Shape, node.AssetWidth, node.AssetHeight)
+	w := frameW
+	h := frameH
+	if strings.TrimSpace(node.Label) != "" {
+		lines := flowchartLabelLines(node.Label, config)
+		w = max(w, labelLinesWidth(lines, 16, "", config.FastTextMetrics)+8)
+		h += float64(len(lines))*24.0 + assetLabelGap
+	}
+	return w, h
+}
+
+// assetFrameRect places the frame inside the node box, leaving room for the
+// label on the side selected by pos.
+func assetFrameRect(node NodeLayout) (x, y, w, h float64) {
+	w, h = assetFrameSize(node.Shape, node.AssetWidth, node.AssetHeight)
+	x = node.X + (node.W-w)/2
+	y = node.Y
+	if node.LabelPos == "t" {
+		y = node.Y + node.H - h
+	}
+	return x, y, w, h
+}
+
+// assetLabelCenterY returns the vertical centre of an icon or image label.
+func assetLabelCenterY(node NodeLayout) (float64, bool) {
+	if !isAssetShape(node.Shape) {
+		return 0, false
+	}
+	labelH := assetLabelHeight(node.Label)
+	if node.LabelPos == "t" {
+		return node.Y + labelH/2, true
+	}
+	return node.Y + node.H - labelH/2, true
+}
+
+func addAssetNodePrimitive(layout *Layout, theme Theme, node NodeLayout, stroke string, strokeWidth float64) {
+	x, y, w, h := assetFrameRect(node)
+	if node.Shape == ShapeImage {
+		if strings.TrimSpace(node.Stroke) != "" {
+			layout.Rects = append(layout.Rects, LayoutRect{
+				X: x, Y: y, W: w, H: h,
+				Fill:        "none",
+				Stroke:      stroke,
+				StrokeWidth: strokeWidth,
+			})
+		}
+		layout.Images = append(layout.Images, LayoutImage{
+			ID:       node.ID,
+			Class:    "image-node",
+			X:        x,
+			Y:        y,
+			W:        w,
+			H:        h,
+			Href:     node.Img,
+			Preserve: "none",
+		})
+		return
+	}
+
+	iconColor := stroke
+	if node.Shape != ShapeIcon {
+		background := theme.PrimaryBorderColor
+		if strings.TrimSpace(node.Fill) != "" {
+			background = node.Fill
+		}
+		iconColor = theme.PrimaryColor
+		if strings.TrimSpace(node.Stroke) != "" {
+			iconColor = node.Stroke
+		}
+		switch node.Shape {
+		case ShapeIconCircle:
+			layout.Circles = append(layout.Circles, LayoutCircle{
+				CX:          x + w/2,
+				CY:          y + h/2,
+				R:           w / 2,
+				Fill:        background,
+				Stroke:      background,
+				StrokeWidth: strokeWidth,
+			})
+		default:
+			radius := 0.0
+			if node.Shape == ShapeIconRounded {
+				radius = 8
+			}
+			layout.Rects = append(layout.Rects, LayoutRect{
+				X: x, Y: y, W: w, H: h,
+				RX:          radius,
+				RY:          radius,
+				Fill:        background,
+				Stroke:      background,
+				StrokeWidth: strokeWidth,
+			})
+		}
+	}
+	layout.Images = append(layout.Images, LayoutImage{
+		ID:    node.ID,
+		Class: "icon-node",
+		X:     x + (w-node.AssetWidth)/2,
+		Y:     y + (h-node.AssetHeight)/2,
+		W:     node.AssetWidth,
+		H:     node.AssetHeight,
+		Href:  iconDataURI(node.Icon, iconColor),
+	})
+}
@@ -7,9 +7,25 @@ import (
 	"strconv"
 	"strings"
 	"unicode"
+
+	"github.com/bvolpato/mermaid-go-renderer/dagre"
 )
 
 func ComputeLayout(graph *Graph, theme Theme, config LayoutConfig) Layout {
+	config.Radar = config.Radar.withDefaults()
+	config = config.withDiagramConfig(graph.Config)
+	layout := computeDiagramLayout(graph, theme, config)
+	layout.SVGLabels = config.SVGLabels
+	layout.AccTitle = graph.AccTitle
+	layout.AccDescr = graph.AccDescr
+	if layout.AccDescr == "" && config.DescribeStructure {
+		layout.AccDescr = describeGraphStructure(graph)
+	}
+	addDiagramTitle(&layout, graph, theme, config)
+	return layout
+}
+
+func computeDiagramLayout(graph *Graph, theme Theme, config LayoutConfig) Layout {
 	switch graph.Kind {
 	case DiagramFlowchart, DiagramState, DiagramRequirement:
 		return layoutGraphLikeDagre(graph, theme, config)
@@ -841,7 +857,7 @@ func layoutGraphLike(graph *Graph, theme Theme, config LayoutConfig) Layout {
 		maxW := 320.0
 		paddingW := 28.0
 		if graph.Kind == DiagramFlowchart {
-			w, h := mermaidFlowchartNodeSize(node, config)
+			w, h := mermaidFlowchartNodeSize(node, graph.Direction, config)
 			nodeSizes[id] = Point{X: w, Y: h}
 			if w > maxNodeWidth {
 				maxNodeWidth = w
@@ -1086,6 +1102,7 @@ func layoutGraphLike(graph *Graph, theme Theme, config LayoutConfig) Layout {
 			clusterW := (subMaxX - minX) + clusterPadX*2
 			clusterH := (subMaxY - minY) + clusterPadTop + clusterPadBottom
 			layout.Rects = append(layout.Rects, LayoutRect{
+				ID:            subgraph.ID,
 				Class:         "cluster",
 				X:             clusterX,
 				Y:             clusterY,
@@ -1099,6 +1116,7 @@ func layoutGraphLike(graph *Graph, theme Theme, config LayoutConfig) Layout {
 				StrokeOpacity: 1,
 			})
 			layout.Texts = append(layout.Texts, LayoutText{
+				ID:               subgraph.ID,
 				Class:            "cluster-label",
 				X:                clusterX + clusterW/2,
 				Y:                clusterY + 13,
@@ -1154,6 +1172,8 @@ func layoutGraphLike(graph *Graph, theme Theme, config LayoutConfig) Layout {
 			ArrowEnd:    edge.ArrowEnd || edge.Directed,
 			MarkerStart: edge.MarkerStart,
 			MarkerEnd:   edge.MarkerEnd,
+			ID:          edge.ID,
+			Curve:       edge.Curve,
 		})
 	}
 	if hasBackwardFlowchartEdge {
@@ -1255,6 +1275,9 @@ func addGraphPrimitives(layout *Layout, theme Theme) {
 				patternClass = "edge-pattern-dotted"
 			}
 			pathID := "L_" + sanitizeID(edge.From, edge.From) + "_" + sanitizeID(edge.To, edge.To) + "_" + intString(edgeIdx)
+			if edge.ID != "" {
+				pathID = edge.ID
+			}
 			layout.Paths = append(layout.Paths, LayoutPath{
 				ID:          pathID,
 				Class:       thicknessClass + " " + patternClass + " flowchart-link",
@@ -1324,6 +1347,7 @@ func addGraphPrimitives(layout *Layout, theme Theme) {
 				Anchor: "middle",
 				Size:   labelSize,
 				Color:  labelColor,
+				Lines:  edge.LabelLines,
 			})
 		}
 	}
@@ -1416,11 +1440,17 @@ func addGraphPrimitives(layout *Layout, theme Theme) {
 				continue
 			}
 		}
+		if shapeHidesLabel(node.Shape) {
+			continue
+		}
 		textX := node.X + node.W/2
 		if node.Shape == ShapePerson {
 			textX += 8
 		}
 		textY := node.Y + node.H/2 + theme.FontSize*0.35
+		if centerY, ok := assetLabelCenterY(node); ok {
+			textY = centerY + theme.FontSize*0.35
+		}
 		labelLines := splitLinesPreserve(node.Label)
 		if len(labelLines) > 1 {
 			lineStep := max(14, theme.FontSize*1.2)
@@ -1438,11 +1468,15 @@ func addGraphPrimitives(layout *Layout, theme Theme) {
 			Anchor: "middle",
 			Size:   theme.FontSize,
 			Color:  theme.PrimaryTextColor,
+			Lines:  node.LabelLines,
 		})
 	}
 }
 
 func flowchartEdgePath(edge EdgeLayout) string {
+	if edge.Curve != "" {
+		return curvePath([]dagre.Point{{X: edge.X1, Y: edge.Y1}, {X: edge.X2, Y: edge.Y2}}, edge.Curve)
+	}
 	dx := edge.X2 - edge.X1
 	dy := edge.Y2 - edge.Y1
 	if math.Abs(dx) >= math.Abs(dy) && dx < 0 {
@@ -1471,9 +1505,13 @@ func flowchartEdgePath(edge EdgeLayout) string {
 		" " + formatFloat(edge.X2) + "," + formatFloat(edge.Y2)
 }
 
-func mermaidFlowchartNodeSize(node Node, config LayoutConfig) (float64, float64) {
-	labelWidth := measureTextWidth(node.Label, config.FastTextMetrics)
-	lineCount := max(1, len(splitLinesPreserve(node.Label)))
+func mermaidFlowchartNodeSize(node Node, direction Direction, config LayoutConfig) (float64, float64) {
+	if isAssetShape(node.Shape) {
+		return assetNodeSize(node, config)
+	}
+	labelLines := flowchartLabelLines(node.Label, config)
+	labelWidth := labelLinesWidth(labelLines, 16, "", config.FastTextMetrics)
+	lineCount := max(1, len(labelLines))
 	textHeight := float64(lineCount) * 24.0
 
 	minW := 70.0
@@ -1494,11 +1532,53 @@ func mermaidFlowchartNodeSize(node Node, config LayoutConfig) (float64, float64)
 		d := max(w, h, 90.0)
 		w = d
 		h = d
-	case ShapeCylinder:
+	case ShapeCylinder, ShapeLinedCylinder:
 		minW = 36.0
 		minH = 56.0
 		w = max(36.0, labelWidth+16.0)
 		h = max(56.0, textHeight+32.0)
+		if node.Shape == ShapeLinedCylinder {
+			h += 8
+		}
+	case ShapeSmallCircle, ShapeFilledCircle, ShapeFramedCircle:
+		return 14, 14
+	case ShapeCrossedCircle:
+		return 60, 60
+	case ShapeFork:
+		if direction == DirectionLeftRight || direction == DirectionRightLeft {
+			return 10, 70
+		}
+		return 70, 10
+	case ShapeHourglass:
+		return 30, 30
+	case ShapeBolt:
+		return 35, 70
+	case ShapeTriangle, ShapeFlippedTriangle:
+		w = max(w, 2*(labelWidth+20))
+		h = max(h, w*0.6)
+	case ShapeDocument, ShapeLinedDocument, ShapeTaggedDocument, ShapeFlag, ShapeDividedRect:
+		h += 12
+	case ShapeStackedDocument:
+		w += 2 * shapeStackOffset
+		h += 12 + 2*shapeStackOffset
+	case ShapeStackedRect, ShapeWindowPane:
+		w += 2 * shapeStackOffset
+		h += 2 * shapeStackOffset
+	case ShapeLinedRect:
+		w += 8
+	case ShapeSlopedRect:
+		h += 10
+	case ShapeHorizontalCylinder, ShapeDelay, ShapeCurvedTrapezoid, ShapeBowTieRect:
+		w += 20
+	case ShapeCloud, ShapeBang:
+		// Room for the label inside the lobes or spikes of the outline.
+		w = max(w, (labelWidth+20)*1.5)
+		h = max(h, (textHeight+10)*1.5)
+	case ShapeText:
+		minW = 20.0
+		minH = 20.0
+		w = labelWidth + 16.0
+		h = textHeight + 8.0
 	}
 
 	return clamp(w, minW, 360.0), clamp(h, minH, 240.0)
@@ -1521,6 +1601,8 @@ func addNodePrimitive(layout *Layout, theme Theme, kind DiagramKind, node NodeLa
 		strokeWidth = node.StrokeWidth
 	}
 	switch node.Shape {
+	case ShapeIcon, ShapeIconSquare, ShapeIconCircle, ShapeIconRounded, ShapeImage:
+		addAssetNodePrimitive(layout, theme, node, stroke, strokeWidth)
 	case ShapeRoundRect, ShapeStadium:
 		layout.Rects = append(layout.Rects, LayoutRect{
 			X:           node.X,
@@ -1616,80 +1698,10 @@ func addNodePrimitive(layout *Layout, theme Theme, kind DiagramKind, node NodeLa
 				StrokeWidth: 1.5,
 			},
 		)
-	case ShapeDiamond:
-		layout.Polygons = append(layout.Polygons, LayoutPolygon{
-			Points: []Point{
-				{X: node.X + node.W/2, Y: node.Y},
-				{X: node.X + node.W, Y: node.Y + node.H/2},
-				{X: node.X + node.W/2, Y: node.Y + node.H},
-				{X: node.X, Y: node.Y + node.H/2},
-			},
-			Fill:        fill,
-			Stroke:      stroke,
-			StrokeWidth: strokeWidth,
-		})
-	case ShapeHexagon:
-		layout.Polygons = append(layout.Polygons, LayoutPolygon{
-			Points: []Point{
-				{X: node.X + node.W*0.2, Y: node.Y},
-				{X: node.X + node.W*0.8, Y: node.Y},
-				{X: node.X + node.W, Y: node.Y + node.H/2},
-				{X: node.X + node.W*0.8, Y: node.Y + node.H},
-				{X: node.X + node.W*0.2, Y: node.Y + node.H},
-				{X: node.X, Y: node.Y + node.H/2},
-			},
-			Fill:        fill,
-			Stroke:      stroke,
-			StrokeWidth: strokeWidth,
-		})
-	case ShapeParallelogram:
+	case ShapeDiamond, ShapeHexagon, ShapeParallelogram, ShapeTrapezoid, ShapeAsymmetric:
+		geometry, _ := nodeShapeGeometry(node.Shape, node.X, node.Y, node.W, node.H)
 		layout.Polygons = append(layout.Polygons, LayoutPolygon{
-			Points: []Point{
-				{X: node.X + 14, Y: node.Y},
-				{X: node.X + node.W, Y: node.Y},
-				{X: node.X + node.W - 14, Y: node.Y + node.H},
-				{X: node.X, Y: node.Y + node.H},
-			},
-			Fill:        fill,
-			Stroke:      stroke,
-			StrokeWidth: strokeWidth,
-		})
-	case ShapeTrapezoid:
-		layout.Polygons = append(layout.Polygons, LayoutPolygon{
-			Points: []Point{
-				{X: node.X + 16, Y: node.Y},
-				{X: node.X + node.W - 16, Y: node.Y},
-				{X: node.X + node.W, Y: node.Y + node.H},
-				{X: node.X, Y: node.Y + node.H},
-			},
-			Fill:        fill,
-			Stroke:      stroke,
-			StrokeWidth: strokeWidth,
-		})
-	case ShapeAsymmetric:
-		layout.Polygons = append(layout.Polygons, LayoutPolygon{
-			Points: []Point{
-				{X: node.X, Y: node.Y},
-				{X: node.X + node.W, Y: node.Y},
-				{X: node.X + node.W*0.85, Y: node.Y + node.H},
-				{X: node.X, Y: node.Y + node.H},
-			},
-			Fill:        fill,
-			Stroke:      stroke,
-			StrokeWidth: strokeWidth,
-		})
-	case ShapeCylinder:
-		rx := node.W / 2
-		ry := node.H * 0.11125
-		side := node.H - 2*ry
-		path := "M " + formatFloat(node.X) + "," + formatFloat(node.Y+ry) +
-			" a " + formatFloat(rx) + "," + formatFloat(ry) + " 0,0,0 " + formatFloat(node.W) + ",0" +
-			" a " + formatFloat(rx) + "," + formatFloat(ry) + " 0,0,0 -" + formatFloat(node.W) + ",0" +
-			" l 0," + formatFloat(side) +
-			" a " + formatFloat(rx) + "," + formatFloat(ry) + " 0,0,0 " + formatFloat(node.W) + ",0" +
-			" l 0,-" + formatFloat(side)
-		layout.Paths = append(layout.Paths, LayoutPath{
-			D:           path,
+			Points:      geometry.hull,
 			Fill:        fill,
 			Stroke:      stroke,
 			StrokeWidth: strokeWidth,
@@ -1697,6 +1709,10 @@ func addNodePrimitive(layout *Layout, theme Theme, kind DiagramKind, node NodeLa
 	case ShapeHidden:
 		return
 	default:
+		if geometry, ok := nodeShapeGeometry(node.Shape, node.X, node.Y, node.W, node.H); ok {
+			addShapeGeometry(layout, geometry, fill, stroke, strokeWidth)
+			return
+		}
 		rectClass := ""
 		rx := 6.0
 		ry := 6.0
@@ -2301,18 +2317,24 @@ func layoutArchitecture(graph *Graph, theme Theme, config LayoutConfig) Layout {
 		if !okFrom || !okTo {
 			continue
 		}
-		pathD := ""
-		if math.Abs(x2-x1) >= math.Abs(y2-y1) {
-			midX := (x1 + x2) / 2
-			pathD = "M " + formatFloat(x1) + "," + formatFloat(y1) +
-				" L " + formatFloat(midX) + "," + formatFloat(y1) +
-				" L" + formatFloat(x2) + "," + formatFloat(y2)
-		} else {
-			midY := (y1 + y2) / 2
-			pathD = "M " + formatFloat(x1) + "," + formatFloat(y1) +
-				" L " + formatFloat(x1) + "," + formatFloat(midY) +
-				" L" + formatFloat(x2) + "," + formatFloat(y2)
+		bend := Point{X: (x1 + x2) / 2, Y: y1}
+		if math.Abs(x2-x1) < math.Abs(y2-y1) {
+			bend = Point{X: x1, Y: (y1 + y2) / 2}
 		}
+		pathD := "M " + formatFloat(x1) + "," + formatFloat(y1) +
+			" L " + formatFloat(bend.X) + "," + formatFloat(bend.Y) +
+			" L" + formatFloat(x2) + "," + formatFloat(y2)
+		layout.Edges = append(layout.Edges, EdgeLayout{
+			From:   edge.From.ID,
+			To:     edge.To.ID,
+			D:      pathD,
+			X1:     x1,
+			Y1:     y1,
+			X2:     x2,
+			Y2:     y2,
+			Style:  EdgeSolid,
+			Points: []Point{{X: x1, Y: y1}, bend, {X: x2, Y: y2}},
+		})
 		layout.Paths = append(layout.Paths, LayoutPath{
 			ID:          "L_" + edge.From.ID + "_" + edge.To.ID + "_" + intString(i),
 			Class:       "edge",
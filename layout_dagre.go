@@ -37,8 +37,6 @@ func layoutGraphLikeDagre(astGraph *Graph, theme Theme, config LayoutConfig) Lay
 		return layout
 	}
 
-	dg := dagre.NewGraph()
-
 	dir := "TB"
 	if astGraph.Direction == DirectionBottomTop {
 		dir = "BT"
@@ -87,14 +85,14 @@ func layoutGraphLikeDagre(astGraph *Graph, theme Theme, config LayoutConfig) Lay
 		marginy = 8
 	}
 
-	dg.SetGraph(dagre.GraphLabel{
+	settings := dagre.GraphLabel{
 		RankDir: dir,
 		NodeSep: nodesep,
 		EdgeSep: 10,
 		RankSep: ranksep,
 		MarginX: marginx,
 		MarginY: marginy,
-	})
+	}
 
 	// Track composite state IDs
 	compositeStateIDs := map[string]struct{}{}
@@ -107,63 +105,78 @@ func layoutGraphLikeDagre(astGraph *Graph, theme Theme, config LayoutConfig) Lay
 	}
 
 	// Add nodes
+	nodes := make(map[string]Node, len(astGraph.NodeOrder))
 	for _, v := range astGraph.NodeOrder {
 		node := astGraph.Nodes[v]
+		if astGraph.Kind == DiagramFlowchart {
+			node = resolveNodeAssets(node, config.AssetDirs)
+		}
+		nodes[v] = node
+	}
+	nodeLabel := func(v string) *dagre.NodeLabel {
+		node := nodes[v]
 
 		// Composite state nodes: small placeholder, dagre computes real size
 		if _, isComposite := compositeStateIDs[v]; isComposite {
-			dg.SetNode(v, &dagre.NodeLabel{Width: 1, Height: 1})
-			continue
+			return &dagre.NodeLabel{Width: 1, Height: 1}
 		}
 
 		// State start/end circles
 		if astGraph.Kind == DiagramState &&
 			(node.Shape == ShapeCircle || node.Shape == ShapeDoubleCircle) &&
 			strings.TrimSpace(node.Label) == "" {
-			dg.SetNode(v, &dagre.NodeLabel{Width: 14, Height: 14})
-			continue
+			return &dagre.NodeLabel{Width: 14, Height: 14}
 		}
 
 		w, h := dagreNodeSize(astGraph, node, theme, config)
-		dg.SetNode(v, &dagre.NodeLabel{Width: w, Height: h})
+		label := &dagre.NodeLabel{Width: w, Height: h}
+		if astGraph.Kind == DiagramFlowchart {
+			label.Intersect = shapeIntersector(node.Shape)
+		}
+		return label
 	}
 
 	// Add edges
-	for i, e := range astGraph.Edges {
-		if e.From == "" || e.To == "" {
-			continue
-		}
-		minLen := 1
-		if strings.Contains(e.Label, "\n") {
-			minLen = 2
-		}
-		edgeName := fmt.Sprintf("%d", i)
-
+	edgeLabel := func(e Edge) *dagre.EdgeLabel {
+		minLen := max(1, e.MinLen)
 		labelW := 0.0
 		labelH := 0.0
 		if e.Label != "" {
-			labelW = measureTextWidth(e.Label, config.FastTextMetrics) + 8
-			labelH = 20.0
+			if astGraph.Kind == DiagramFlowchart {
+				lines := flowchartLabelLines(e.Label, config)
+				labelW = labelLinesWidth(lines, 16, "", config.FastTextMetrics) + 8
+				labelH = 20.0 * float64(len(lines))
+				if len(lines) > 1 {
+					minLen = max(minLen, 2)
+				}
+			} else {
+				labelW = measureTextWidth(e.Label, config.FastTextMetrics) + 8
+				labelH = 20.0
+				if strings.Contains(e.Label, "\n") {
+					minLen = max(minLen, 2)
+				}
+			}
 		}
-
-		dg.SetEdge(dagre.Edge{V: e.From, W: e.To, Name: edgeName}, &dagre.EdgeLabel{
+		return &dagre.EdgeLabel{
 			MinLen:   minLen,
 			Weight:   1,
 			Width:    labelW,
 			Height:   labelH,
 			LabelPos: "c",
-		})
-	}
-
-	// Register subgraphs (flowchart subgraphs, composite states)
-	for _, sg := range astGraph.FlowSubgraphs {
-		dg.SetNode(sg.ID, &dagre.NodeLabel{Width: 0, Height: 0})
-		for _, child := range sg.NodeIDs {
-			dg.SetParent(child, sg.ID)
 		}
 	}
 
-	dagre.Layout(dg)
+	clusters := newFlowClusters(astGraph)
+	placed := dagreScopeBuilder{
+		graph:     astGraph,
+		clusters:  clusters,
+		settings:  settings,
+		nodeLabel: nodeLabel,
+		edgeLabel: edgeLabel,
+		labelWidth: func(label string) float64 {
+			return measureTextWidth(label, config.FastTextMetrics)
+		},
+	}.layout("")
 
 	// Build back the visual layout
 	minX := math.MaxFloat64
@@ -172,7 +185,7 @@ func layoutGraphLikeDagre(astGraph *Graph, theme Theme, config LayoutConfig) Lay
 	maxY := -math.MaxFloat64
 
 	for _, v := range astGraph.NodeOrder {
-		dn := dg.Node(v)
+		dn := placed.nodes[v]
 		if dn == nil {
 			continue
 		}
@@ -184,7 +197,7 @@ func layoutGraphLikeDagre(astGraph *Graph, theme Theme, config LayoutConfig) Lay
 		maxX = max(maxX, dn.X+dn.Width/2)
 		maxY = max(maxY, dn.Y+dn.Height/2)
 
-		astNode := astGraph.Nodes[v]
+		astNode := nodes[v]
 		shape := astNode.Shape
 		label := astNode.Label
 
@@ -192,6 +205,11 @@ func layoutGraphLikeDagre(astGraph *Graph, theme Theme, config LayoutConfig) Lay
 			shape = ShapeHidden
 			label = ""
 		}
+		var labelLines [][]TextRun
+		if astGraph.Kind == DiagramFlowchart {
+			labelLines = flowchartLabelLines(label, config)
+			label = labelLinesText(labelLines)
+		}
 
 		layout.Nodes = append(layout.Nodes, NodeLayout{
 			ID:          v,
@@ -204,6 +222,12 @@ func layoutGraphLikeDagre(astGraph *Graph, theme Theme, config LayoutConfig) Lay
 			Fill:        astNode.Fill,
 			Stroke:      astNode.Stroke,
 			StrokeWidth: astNode.StrokeWidth,
+			Icon:        astNode.Icon,
+			Img:         astNode.Img,
+			LabelPos:    astNode.LabelPos,
+			AssetWidth:  astNode.AssetWidth,
+			AssetHeight: astNode.AssetHeight,
+			LabelLines:  labelLines,
 		})
 	}
 
@@ -213,18 +237,18 @@ func layoutGraphLikeDagre(astGraph *Graph, theme Theme, config LayoutConfig) Lay
 		nodeIndex[node.ID] = node
 	}
 
-	// Subgraphs as clusters
-	for _, sg := range astGraph.FlowSubgraphs {
-		dn := dg.Node(sg.ID)
+	// Subgraphs as clusters. Boxes are computed innermost first so a
+	// flowchart cluster also encloses its nested clusters.
+	clusterBoxes := map[string]NodeLayout{}
+	for i := len(astGraph.FlowSubgraphs) - 1; i >= 0; i-- {
+		sg := astGraph.FlowSubgraphs[i]
+		dn := placed.nodes[sg.ID]
 		if dn == nil || dn.Width == 0 || dn.Height == 0 {
 			continue
 		}
-		tlX := dn.X - dn.Width/2
-		tlY := dn.Y - dn.Height/2
-		clusterW := dn.Width
-		clusterH := dn.Height
+		box := NodeLayout{ID: sg.ID, X: dn.X - dn.Width/2, Y: dn.Y - dn.Height/2, W: dn.Width, H: dn.Height}
 
-		if astGraph.Kind == DiagramFlowchart && len(sg.NodeIDs) > 0 {
+		if astGraph.Kind == DiagramFlowchart && len(sg.NodeIDs) > 0 && !clusters.isolated[sg.ID] {
 			minChildX := math.Inf(1)
 			minChildY := math.Inf(1)
 			maxChildX := math.Inf(-1)
@@ -239,39 +263,69 @@ func layoutGraphLikeDagre(astGraph *Graph, theme Theme, config LayoutConfig) Lay
 				maxChildX = max(maxChildX, child.X+child.W)
 				maxChildY = max(maxChildY, child.Y+child.H)
 			}
+			clusterPadX := 30.0
+			clusterPadTop := 15.0
+			clusterPadBottom := 15.0
+			for _, inner := range astGraph.FlowSubgraphs {
+				child, ok := clusterBoxes[inner.ID]
+				if !ok || inner.Parent != sg.ID {
+					continue
+				}
+				// Nested boxes already carry their own padding, but the
+				// outer label needs a row of its own above them.
+				minChildX = min(minChildX, child.X+clusterPadX/2)
+				minChildY = min(minChildY, child.Y-(isolatedClusterPadTop-clusterPadTop))
+				maxChildX = max(maxChildX, child.X+child.W-clusterPadX/2)
+				maxChildY = max(maxChildY, child.Y+child.H-clusterPadBottom/2)
+			}
 			if !math.IsInf(minChildX, 1) && !math.IsInf(minChildY, 1) {
-				clusterPadX := 30.0
-				clusterPadTop := 15.0
-				clusterPadBottom := 15.0
-				tlX = minChildX - clusterPadX
-				tlY = minChildY - clusterPadTop
-				clusterW = (maxChildX - minChildX) + clusterPadX*2
-				clusterH = (maxChildY - minChildY) + clusterPadTop + clusterPadBottom
+				box.X = minChildX - clusterPadX
+				box.Y = minChildY - clusterPadTop
+				box.W = (maxChildX - minChildX) + clusterPadX*2
+				box.H = (maxChildY - minChildY) + clusterPadTop + clusterPadBottom
 				minLabelW := measureTextWidth(sg.Label, config.FastTextMetrics) + 28.0
-				if clusterW < minLabelW {
-					extra := minLabelW - clusterW
-					tlX -= extra / 2
-					clusterW = minLabelW
+				if box.W < minLabelW {
+					extra := minLabelW - box.W
+					box.X -= extra / 2
+					box.W = minLabelW
 				}
 			}
 		}
+		clusterBoxes[sg.ID] = box
+	}
+
+	for _, sg := range astGraph.FlowSubgraphs {
+		box, ok := clusterBoxes[sg.ID]
+		if !ok {
+			continue
+		}
+		tlX, tlY, clusterW, clusterH := box.X, box.Y, box.W, box.H
 
 		minX = min(minX, tlX)
 		minY = min(minY, tlY)
 		maxX = max(maxX, tlX+clusterW)
 		maxY = max(maxY, tlY+clusterH)
 
+		class := "cluster"
+		if len(sg.Classes) > 0 {
+			class += " " + strings.Join(sg.Classes, " ")
+		}
+		strokeWidth := 1.0
+		if sg.StrokeWidth > 0 {
+			strokeWidth = sg.StrokeWidth
+		}
 		layout.Rects = append(layout.Rects, LayoutRect{
-			Class:         "cluster",
+			ID:            sg.ID,
+			Class:         class,
 			X:             tlX,
 			Y:             tlY,
 			W:             clusterW,
 			H:             clusterH,
 			RX:            6,
 			RY:            6,
-			Fill:          "rgba(255, 255, 222, 0.5)",
-			Stroke:        "rgba(170, 170, 51, 0.2)",
-			StrokeWidth:   1,
+			Fill:          defaultColor(sg.Fill, "rgba(255, 255, 222, 0.5)"),
+			Stroke:        defaultColor(sg.Stroke, "rgba(170, 170, 51, 0.2)"),
+			StrokeWidth:   strokeWidth,
 			StrokeOpacity: 1,
 		})
 		labelY := tlY + 13
@@ -279,6 +333,7 @@ func layoutGraphLikeDagre(astGraph *Graph, theme Theme, config LayoutConfig) Lay
 			labelY = tlY + 18
 		}
 		layout.Texts = append(layout.Texts, LayoutText{
+			ID:               sg.ID,
 			Class:            "cluster-label",
 			X:                tlX + clusterW/2,
 			Y:                labelY,
@@ -288,6 +343,9 @@ func layoutGraphLikeDagre(astGraph *Graph, theme Theme, config LayoutConfig) Lay
 			Color:            theme.PrimaryTextColor,
 			DominantBaseline: "middle",
 		})
+		if _, isNode := nodeIndex[sg.ID]; !isNode {
+			nodeIndex[sg.ID] = box
+		}
 	}
 
 	// Edges via dagre path points
@@ -296,13 +354,23 @@ func layoutGraphLikeDagre(astGraph *Graph, theme Theme, config LayoutConfig) Lay
 			continue
 		}
 		edgeName := fmt.Sprintf("%d", i)
-		dl := dg.EdgeByKey(dagre.Edge{V: e.From, W: e.To, Name: edgeName})
+		dl := placed.edges[edgeName]
 		if dl == nil {
 			continue
 		}
 
+		// Edges to a cluster were routed to a node inside it; end them at
+		// the cluster border instead.
+		points := dl.Points
+		if box, ok := clusterBoxes[e.From]; ok && clusters.endpoint(e.From) != e.From {
+			points = cutPathAtBox(points, box, false)
+		}
+		if box, ok := clusterBoxes[e.To]; ok && clusters.endpoint(e.To) != e.To {
+			points = cutPathAtBox(points, box, true)
+		}
+
 		var dBuilder strings.Builder
-		for pi, p := range dl.Points {
+		for pi, p := range points {
 			if pi == 0 {
 				dBuilder.WriteString("M " + strconv.FormatFloat(p.X, 'f', 2, 64) + " " + strconv.FormatFloat(p.Y, 'f', 2, 64))
 			} else {
@@ -335,16 +403,31 @@ func layoutGraphLikeDagre(astGraph *Graph, theme Theme, config LayoutConfig) Lay
 			}
 
 			pathID := "L_" + sanitizeID(e.From, e.From) + "_" + sanitizeID(e.To, e.To) + "_" + strconv.Itoa(i)
+			if e.ID != "" {
+				pathID = e.ID
+			}
+			pathClass := thicknessClass + " " + patternClass + " flowchart-link"
+			if e.Animation != "" {
+				pathClass += " edge-animation-" + e.Animation
+			}
+			if len(e.Classes) > 0 {
+				pathClass += " " + strings.Join(e.Classes, " ")
+			}
+			d := dBuilder.String()
+			if curve := resolveEdgeCurve(e.Curve, config.Curve); curve != "" {
+				d = curvePath(points, curve)
+			}
 			layout.Paths = append(layout.Paths, LayoutPath{
 				ID:          pathID,
-				D:           dBuilder.String(),
-				Class:       thicknessClass + " " + patternClass + " flowchart-link",
+				D:           d,
+				Class:       pathClass,
 				Fill:        "none",
 				Stroke:      theme.LineColor,
 				StrokeWidth: strokeWidth,
 				DashArray:   dashArray,
 				MarkerStart: markerStart,
 				MarkerEnd:   markerEnd,
+				Style:       classDefStyle(astGraph.ClassDefs, e.Classes),
 			})
 		} else {
 			edgeClass := "edgePath"
@@ -359,7 +442,7 @@ func layoutGraphLikeDagre(astGraph *Graph, theme Theme, config LayoutConfig) Lay
 
 			path := LayoutPath{
 				ID:          e.From + "-" + e.To + "-" + strconv.Itoa(i),
-				D:           dBuilder.String(),
+				D:           edgeCurvePath(points, resolveEdgeCurve(e.Curve, config.Curve)),
 				Class:       edgeClass,
 				MarkerStart: e.MarkerStart,
 				MarkerEnd:   e.MarkerEnd,
@@ -388,11 +471,17 @@ func layoutGraphLikeDagre(astGraph *Graph, theme Theme, config LayoutConfig) Lay
 		to, okTo := nodeIndex[e.To]
 		if okFrom && okTo {
 			x1, y1, x2, y2 := edgeEndpoints(from, to, astGraph.Direction)
+			edgeLabel := e.Label
+			var edgeLabelLines [][]TextRun
+			if astGraph.Kind == DiagramFlowchart && e.Label != "" {
+				edgeLabelLines = flowchartLabelLines(e.Label, config)
+				edgeLabel = labelLinesText(edgeLabelLines)
+			}
 			layout.Edges = append(layout.Edges, EdgeLayout{
 				From:        e.From,
 				To:          e.To,
-				Label:       e.Label,
-				D:           dagreEdgePath(dl.Points),
+				Label:       edgeLabel,
+				D:           edgeCurvePath(points, resolveEdgeCurve(e.Curve, config.Curve)),
 				X1:          x1,
 				Y1:          y1,
 				X2:          x2,
@@ -402,6 +491,10 @@ func layoutGraphLikeDagre(astGraph *Graph, theme Theme, config LayoutConfig) Lay
 				ArrowEnd:    e.ArrowEnd || e.Directed,
 				MarkerStart: e.MarkerStart,
 				MarkerEnd:   e.MarkerEnd,
+				ID:          e.ID,
+				Curve:       resolveEdgeCurve(e.Curve, config.Curve),
+				Points:      edgePoints(points),
+				LabelLines:  edgeLabelLines,
 			})
 		}
 
@@ -468,7 +561,7 @@ func layoutGraphLikeDagre(astGraph *Graph, theme Theme, config LayoutConfig) Lay
 // dagreNodeSize computes width/height for a node based on diagram type.
 func dagreNodeSize(g *Graph, node Node, theme Theme, config LayoutConfig) (float64, float64) {
 	if g.Kind == DiagramFlowchart {
-		return mermaidFlowchartNodeSize(node, config)
+		return mermaidFlowchartNodeSize(node, g.Direction, config)
 	}
 
 	minW := 50.0
@@ -90,8 +90,13 @@ func layoutERDiagramFidelity(graph *Graph, theme Theme, config LayoutConfig) Lay
 		}
 		from, okFrom := nodeIndex[edge.From]
 		to, okTo := nodeIndex[edge.To]
+		dl := dg.EdgeByKey(dagre.Edge{V: edge.From, W: edge.To, Name: strconv.Itoa(i)})
 		if okFrom && okTo {
 			x1, y1, x2, y2 := edgeEndpoints(from, to, graph.Direction)
+			var points []Point
+			if dl != nil {
+				points = edgePoints(dl.Points)
+			}
 			layout.Edges = append(layout.Edges, EdgeLayout{
 				From:        edge.From,
 				To:          edge.To,
@@ -103,17 +108,17 @@ func layoutERDiagramFidelity(graph *Graph, theme Theme, config LayoutConfig) Lay
 				Style:       edge.Style,
 				MarkerStart: edge.MarkerStart,
 				MarkerEnd:   edge.MarkerEnd,
+				Points:      points,
 			})
 		}
 
-		dl := dg.EdgeByKey(dagre.Edge{V: edge.From, W: edge.To, Name: strconv.Itoa(i)})
 		if dl == nil || len(dl.Points) == 0 {
 			continue
 		}
 		layout.Paths = append(layout.Paths, LayoutPath{
 			ID:          "L_" + sanitizeID(edge.From, edge.From) + "_" + sanitizeID(edge.To, edge.To) + "_" + intString(i),
 			Class:       erRelationshipClass(edge.Style),
-			D:           dagreEdgePath(dl.Points),
+			D:           edgeCurvePath(dl.Points, resolveEdgeCurve(edge.Curve, config.Curve)),
 			Fill:        "none",
 			Stroke:      theme.LineColor,
 			StrokeWidth: 1.2,
@@ -337,34 +342,6 @@ func appendERNodePrimitives(layout *Layout, graph *Graph, metrics map[string]erN
 	}
 }
 
-func dagreEdgePath(points []dagre.Point) string {
-	if len(points) == 0 {
-		return ""
-	}
-	if len(points) == 1 {
-		return "M " + formatFloat(points[0].X) + " " + formatFloat(points[0].Y)
-	}
-	if len(points) == 2 {
-		return "M " + formatFloat(points[0].X) + " " + formatFloat(points[0].Y) +
-			" L " + formatFloat(points[1].X) + " " + formatFloat(points[1].Y)
-	}
-	d := "M " + formatFloat(points[0].X) + " " + formatFloat(points[0].Y)
-	for i := 0; i < len(points)-1; i++ {
-		p0 := points[max(0, i-1)]
-		p1 := points[i]
-		p2 := points[i+1]
-		p3 := points[min(len(points)-1, i+2)]
-		cp1x := p1.X + (p2.X-p0.X)/6
-		cp1y := p1.Y + (p2.Y-p0.Y)/6
-		cp2x := p2.X - (p3.X-p1.X)/6
-		cp2y := p2.Y - (p3.Y-p1.Y)/6
-		d += " C " + formatFloat(cp1x) + " " + formatFloat(cp1y) +
-			" " + formatFloat(cp2x) + " " + formatFloat(cp2y) +
-			" " + formatFloat(p2.X) + " " + formatFloat(p2.Y)
-	}
-	return d
-}
-
 func erRankDir(direction Direction) string {
 	switch direction {
 	case DirectionBottomTop:
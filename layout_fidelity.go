@@ -2589,6 +2589,9 @@ func layoutKanbanFidelity(graph *Graph, theme Theme, config LayoutConfig) Layout
 		cardGapY    = 5.0
 	)
 
+	kanbanCfg := config.Kanban
+	wrapWidth := defaultFloat(kanbanCfg.WrapWidth, DefaultKanbanConfig().WrapWidth)
+
 	type cardLayout struct {
 		id        string
 		colX      float64
@@ -2596,6 +2599,7 @@ func layoutKanbanFidelity(graph *Graph, theme Theme, config LayoutConfig) Layout
 		h         float64
 		title     []string
 		ticket    string
+		ticketURL string
 		assigned  string
 		priority  string
 		priorityC string
@@ -2626,13 +2630,25 @@ func layoutKanbanFidelity(graph *Graph, theme Theme, config LayoutConfig) Layout
 		cardY := columnY + cardYTopPad
 		fill := columnFillPalette[idx%len(columnFillPalette)]
 		stroke := columnStrokePalette[idx%len(columnStrokePalette)]
+		strokeWidth := 2.0
 		titleColor := "#000000"
-		if idx%len(columnFillPalette) == 0 {
-			titleColor = "#000000"
+		for key, value := range graph.classStyleMap(strings.Fields(column.Class)...) {
+			switch key {
+			case "fill":
+				fill = value
+			case "stroke":
+				stroke = value
+			case "stroke-width":
+				if v, ok := parseFloat(strings.TrimSuffix(value, "px")); ok {
+					strokeWidth = v
+				}
+			case "color":
+				titleColor = value
+			}
 		}
 
 		for _, card := range column.Cards {
-			titleLines := wrapKanbanText(card.Title, 175.0, 16.0, config.FastTextMetrics)
+			titleLines := wrapKanbanText(card.Title, wrapWidth, 16.0, config.FastTextMetrics)
 			if len(titleLines) == 0 {
 				titleLines = []string{card.Title}
 			}
@@ -2648,6 +2664,7 @@ func layoutKanbanFidelity(graph *Graph, theme Theme, config LayoutConfig) Layout
 				h:         height,
 				title:     titleLines,
 				ticket:    card.Ticket,
+				ticketURL: kanbanCfg.ticketURL(card.Ticket),
 				assigned:  card.Assigned,
 				priority:  card.Priority,
 				priorityC: kanbanPriorityColor(card.Priority),
@@ -2658,9 +2675,17 @@ func layoutKanbanFidelity(graph *Graph, theme Theme, config LayoutConfig) Layout
 		columnHeight := (cardY + cardGapY) - columnY
 		maxColumnHeight = max(maxColumnHeight, columnHeight)
 
+		columnID := column.ID
+		if columnID == "" {
+			columnID = sanitizeID(column.Title, "")
+		}
+		columnClass := "kanban-column"
+		if column.Class != "" {
+			columnClass += " " + column.Class
+		}
 		layout.Rects = append(layout.Rects, LayoutRect{
-			ID:          sanitizeID(column.Title, ""),
-			Class:       "kanban-column",
+			ID:          columnID,
+			Class:       columnClass,
 			X:           x,
 			Y:           columnY,
 			W:           columnWidth,
@@ -2669,7 +2694,7 @@ func layoutKanbanFidelity(graph *Graph, theme Theme, config LayoutConfig) Layout
 			RY:          5,
 			Fill:        fill,
 			Stroke:      stroke,
-			StrokeWidth: 2,
+			StrokeWidth: strokeWidth,
 		})
 		layout.Texts = append(layout.Texts, LayoutText{
 			Class:            "kanban-column-title",
@@ -2724,6 +2749,7 @@ func layoutKanbanFidelity(graph *Graph, theme Theme, config LayoutConfig) Layout
 			Size:             12,
 			Color:            "#000000",
 			DominantBaseline: "middle",
+			Href:             card.ticketURL,
 		})
 		layout.Texts = append(layout.Texts, LayoutText{
 			Class:            "kanban-card-meta",
@@ -2797,9 +2823,16 @@ func layoutTreemapFidelity(graph *Graph, theme Theme, config LayoutConfig) Layou
 		return layoutGraphLike(graph, theme, config)
 	}
 
+	cfg := config.Treemap
+	if cfg == (TreemapConfig{}) {
+		cfg = DefaultTreemapConfig()
+	}
+
 	type treeNode struct {
 		Label    string
 		Value    float64
+		Styles   map[string]string
+		Class    string
 		Children []*treeNode
 	}
 
@@ -2809,6 +2842,10 @@ func layoutTreemapFidelity(graph *Graph, theme Theme, config LayoutConfig) Layou
 		node := &treeNode{
 			Label: item.Label,
 			Value: item.Value,
+			Class: item.Class,
+		}
+		if item.Class != "" {
+			node.Styles = graph.classStyleMap(strings.Fields(item.Class)...)
 		}
 		depth := max(0, item.Depth)
 		if depth == 0 {
@@ -2882,16 +2919,35 @@ func layoutTreemapFidelity(graph *Graph, theme Theme, config LayoutConfig) Layou
 		return "black"
 	}
 
-	type frame struct {
-		x float64
-		y float64
-		w float64
-		h float64
+	// applyStyles overrides the palette colors of a rect and its text with the
+	// classDef declarations attached to the node.
+	applyStyles := func(node *treeNode, rect *LayoutRect, textColor *string) {
+		if node.Class != "" {
+			rect.Class += " " + node.Class
+		}
+		for key, value := range node.Styles {
+			switch key {
+			case "fill":
+				rect.Fill = value
+			case "fill-opacity":
+				if v, ok := parseFloat(value); ok {
+					rect.FillOpacity = v
+				}
+			case "stroke":
+				rect.Stroke = value
+			case "stroke-width":
+				if v, ok := parseFloat(strings.TrimSuffix(value, "px")); ok {
+					rect.StrokeWidth = v
+				}
+			case "color":
+				*textColor = value
+			}
+		}
 	}
 
-	addLeaf := func(node *treeNode, f frame, classIdx int) {
+	addLeaf := func(node *treeNode, f treemapFrame, classIdx int) {
 		fill, _ := sectionColor(max(1, classIdx))
-		layout.Rects = append(layout.Rects, LayoutRect{
+		rect := LayoutRect{
 			Class:         "treemapLeaf",
 			X:             f.x,
 			Y:             f.y,
@@ -2902,36 +2958,38 @@ func layoutTreemapFidelity(graph *Graph, theme Theme, config LayoutConfig) Layou
 			Stroke:        fill,
 			StrokeWidth:   3,
 			StrokeOpacity: 1,
-		})
+		}
+		leafTextColor := sectionTextColor(fill)
+		applyStyles(node, &rect, &leafTextColor)
+		layout.Rects = append(layout.Rects, rect)
 		labelSize := math.Round(clamp(min(f.w, f.h)*0.25, 20, 38))
 		valueSize := math.Round(clamp(labelSize*0.6, 14, 23))
-		leafTextColor := sectionTextColor(fill)
-		layout.Texts = append(layout.Texts,
-			LayoutText{
-				Class:            "treemapLabel",
-				X:                f.x + f.w/2.0,
-				Y:                f.y + f.h/2.0,
-				Value:            node.Label,
-				Anchor:           "middle",
-				Size:             labelSize,
-				Color:            leafTextColor,
-				DominantBaseline: "middle",
-			},
-			LayoutText{
+		layout.Texts = append(layout.Texts, LayoutText{
+			Class:            "treemapLabel",
+			X:                f.x + f.w/2.0,
+			Y:                f.y + f.h/2.0,
+			Value:            node.Label,
+			Anchor:           "middle",
+			Size:             labelSize,
+			Color:            leafTextColor,
+			DominantBaseline: "middle",
+		})
+		if cfg.ShowValues {
+			layout.Texts = append(layout.Texts, LayoutText{
 				Class:            "treemapValue",
 				X:                f.x + f.w/2.0,
 				Y:                f.y + f.h/2.0 + valueSize*0.9,
-				Value:            strconv.FormatFloat(node.Value, 'f', 0, 64),
+				Value:            formatTreemapValue(cfg.ValueFormat, node.Value),
 				Anchor:           "middle",
 				Size:             valueSize,
 				Color:            leafTextColor,
 				DominantBaseline: "hanging",
-			},
-		)
+			})
+		}
 	}
 
-	var layoutNode func(node *treeNode, depth int, classIdx int, f frame)
-	layoutNode = func(node *treeNode, depth int, classIdx int, f frame) {
+	var layoutNode func(node *treeNode, depth int, classIdx int, f treemapFrame)
+	layoutNode = func(node *treeNode, depth int, classIdx int, f treemapFrame) {
 		if node == nil || f.w <= 1 || f.h <= 1 {
 			return
 		}
@@ -2946,7 +3004,7 @@ func layoutTreemapFidelity(graph *Graph, theme Theme, config LayoutConfig) Layou
 			fill = "transparent"
 			stroke = "transparent"
 		}
-		layout.Rects = append(layout.Rects, LayoutRect{
+		rect := LayoutRect{
 			Class:         rectClass,
 			X:             f.x,
 			Y:             f.y,
@@ -2957,92 +3015,62 @@ func layoutTreemapFidelity(graph *Graph, theme Theme, config LayoutConfig) Layou
 			Stroke:        stroke,
 			StrokeWidth:   2,
 			StrokeOpacity: 0.4,
-		})
+		}
+		textColor := sectionTextColor(fill)
+		applyStyles(node, &rect, &textColor)
+		layout.Rects = append(layout.Rects, rect)
 		if depth > 0 {
-			textColor := sectionTextColor(fill)
-			layout.Texts = append(layout.Texts,
-				LayoutText{
-					Class:            "treemapSectionLabel",
-					X:                f.x + 6,
-					Y:                f.y + 12.5,
-					Value:            node.Label,
-					Anchor:           "start",
-					Size:             12,
-					Weight:           "700",
-					Color:            textColor,
-					DominantBaseline: "middle",
-				},
-				LayoutText{
+			layout.Texts = append(layout.Texts, LayoutText{
+				Class:            "treemapSectionLabel",
+				X:                f.x + 6,
+				Y:                f.y + 12.5,
+				Value:            node.Label,
+				Anchor:           "start",
+				Size:             12,
+				Weight:           "700",
+				Color:            textColor,
+				DominantBaseline: "middle",
+			})
+			if cfg.ShowValues {
+				layout.Texts = append(layout.Texts, LayoutText{
 					Class:            "treemapSectionValue",
 					X:                f.x + f.w - 10,
 					Y:                f.y + 12.5,
-					Value:            strconv.FormatFloat(node.Value, 'f', 0, 64),
+					Value:            formatTreemapValue(cfg.ValueFormat, node.Value),
 					Anchor:           "end",
 					Size:             10,
 					Color:            textColor,
 					DominantBaseline: "middle",
-				},
-			)
+				})
+			}
 		}
 
 		children := append([]*treeNode(nil), node.Children...)
 		sort.Slice(children, func(i, j int) bool {
 			return children[i].Value > children[j].Value
 		})
-
-		content := frame{
-			x: f.x + 10,
-			y: f.y + 35,
-			w: max(1, f.w-20),
-			h: max(1, f.h-45),
-		}
 		if len(children) == 0 {
 			return
 		}
-		total := 0.0
-		for _, child := range children {
-			total += max(child.Value, 0.0001)
-		}
-		if total <= 0 {
-			total = float64(len(children))
-		}
-
-		gap := 10.0
-		horizontal := content.w > content.h*1.2
-		if horizontal {
-			available := content.w - float64(len(children)-1)*gap
-			used := 0.0
-			x := content.x
-			for idx, child := range children {
-				ratio := max(child.Value, 0.0001) / total
-				childW := available * ratio
-				if idx == len(children)-1 {
-					childW = max(1, content.x+content.w-x)
-				}
-				childClass := classIdx
-				if depth <= 1 {
-					if classIdx == 0 {
-						childClass = idx + 1
-					} else {
-						childClass = classIdx + idx + 1
-					}
-				}
-				layoutNode(child, depth+1, childClass, frame{x: x, y: content.y, w: childW, h: content.h})
-				x += childW + gap
-				used += childW
-			}
-			_ = used
-			return
-		}
 
-		available := content.h - float64(len(children)-1)*gap
-		y := content.y
+		padding := cfg.Padding
+		content := treemapFrame{
+			x: f.x + padding,
+			y: f.y + 25 + padding,
+			w: max(1, f.w-2*padding),
+			h: max(1, f.h-25-2*padding),
+		}
+		values := make([]float64, len(children))
+		for idx, child := range children {
+			values[idx] = max(child.Value, 0.0001)
+		}
+		var tiles []treemapFrame
+		if cfg.Tile == TreemapTileSquarify {
+			tiles = squarifyTreemap(values, content, padding)
+		} else {
+			tiles = sliceTreemap(values, content, padding)
+		}
 		for idx, child := range children {
-			ratio := max(child.Value, 0.0001) / total
-			childH := available * ratio
-			if idx == len(children)-1 {
-				childH = max(1, content.y+content.h-y)
-			}
 			childClass := classIdx
 			if depth <= 1 {
 				if classIdx == 0 {
@@ -3051,12 +3079,11 @@ func layoutTreemapFidelity(graph *Graph, theme Theme, config LayoutConfig) Layou
 					childClass = classIdx + idx + 1
 				}
 			}
-			layoutNode(child, depth+1, childClass, frame{x: content.x, y: y, w: content.w, h: childH})
-			y += childH + gap
+			layoutNode(child, depth+1, childClass, tiles[idx])
 		}
 	}
 
-	layoutNode(root, 0, 0, frame{x: 0, y: 0, w: 1000, h: 400})
+	layoutNode(root, 0, 0, treemapFrame{x: 0, y: 0, w: 1000, h: 400})
 	layout.Width = 996
 	layout.Height = 371
 	layout.ViewBoxX = 2
@@ -3066,6 +3093,156 @@ func layoutTreemapFidelity(graph *Graph, theme Theme, config LayoutConfig) Layou
 	return layout
 }
 
+// treemapFrame is the rectangle assigned to a treemap node.
+type treemapFrame struct {
+	x float64
+	y float64
+	w float64
+	h float64
+}
+
+// sliceTreemap lays children out in a single row or column, whichever suits
+// the frame's aspect ratio, with gap pixels between siblings.
+func sliceTreemap(values []float64, content treemapFrame, gap float64) []treemapFrame {
+	total := 0.0
+	for _, value := range values {
+		total += value
+	}
+	if total <= 0 {
+		total = float64(len(values))
+	}
+	out := make([]treemapFrame, len(values))
+	if content.w > content.h*1.2 {
+		available := content.w - float64(len(values)-1)*gap
+		x := content.x
+		for idx, value := range values {
+			childW := available * value / total
+			if idx == len(values)-1 {
+				childW = max(1, content.x+content.w-x)
+			}
+			out[idx] = treemapFrame{x: x, y: content.y, w: childW, h: content.h}
+			x += childW + gap
+		}
+		return out
+	}
+	available := content.h - float64(len(values)-1)*gap
+	y := content.y
+	for idx, value := range values {
+		childH := available * value / total
+		if idx == len(values)-1 {
+			childH = max(1, content.y+content.h-y)
+		}
+		out[idx] = treemapFrame{x: content.x, y: y, w: content.w, h: childH}
+		y += childH + gap
+	}
+	return out
+}
+
+// squarifyTreemap implements d3's treemapSquarify tiling (golden ratio
+// target aspect) for values sorted in descending order. Siblings are
+// separated by gap pixels like d3's paddingInner.
+func squarifyTreemap(values []float64, content treemapFrame, gap float64) []treemapFrame {
+	const ratio = 1.618033988749895
+	out := make([]treemapFrame, len(values))
+	x0 := content.x - gap/2
+	y0 := content.y - gap/2
+	x1 := content.x + content.w + gap/2
+	y1 := content.y + content.h + gap/2
+	remaining := 0.0
+	for _, value := range values {
+		remaining += value
+	}
+
+	n := len(values)
+	for i0, i1 := 0, 0; i0 < n; i0 = i1 {
+		dx := x1 - x0
+		dy := y1 - y0
+		sum := values[i1]
+		i1++
+		minValue, maxValue := sum, sum
+		alpha := max(dy/dx, dx/dy) / (remaining * ratio)
+		beta := sum * sum * alpha
+		minRatio := max(maxValue/beta, beta/minValue)
+		for ; i1 < n; i1++ {
+			value := values[i1]
+			sum += value
+			minValue = min(minValue, value)
+			maxValue = max(maxValue, value)
+			beta = sum * sum * alpha
+			newRatio := max(maxValue/beta, beta/minValue)
+			if newRatio > minRatio {
+				sum -= value
+				break
+			}
+			minRatio = newRatio
+		}
+
+		if dx < dy {
+			// Row spans the full width; children are placed left to right.
+			rowY1 := y1
+			if dy > 0 {
+				rowY1 = y0 + dy*sum/remaining
+			}
+			x := x0
+			for idx := i0; idx < i1; idx++ {
+				w := dx * values[idx] / sum
+				out[idx] = treemapFrame{x: x, y: y0, w: w, h: rowY1 - y0}
+				x += w
+			}
+			y0 = rowY1
+		} else {
+			// Column spans the full height; children are stacked top to bottom.
+			colX1 := x1
+			if dx > 0 {
+				colX1 = x0 + dx*sum/remaining
+			}
+			y := y0
+			for idx := i0; idx < i1; idx++ {
+				h := dy * values[idx] / sum
+				out[idx] = treemapFrame{x: x0, y: y, w: colX1 - x0, h: h}
+				y += h
+			}
+			x0 = colX1
+		}
+		remaining -= sum
+	}
+
+	for idx, tile := range out {
+		out[idx] = treemapFrame{
+			x: tile.x + gap/2,
+			y: tile.y + gap/2,
+			w: max(1, tile.w-gap),
+			h: max(1, tile.h-gap),
+		}
+	}
+	return out
+}
+
+// formatTreemapValue applies a treemap valueFormat. Like mermaid, a leading
+// "$" is treated as a literal currency prefix in front of the d3 format.
+func formatTreemapValue(format string, value float64) string {
+	switch {
+	case format == "$0,0":
+		return "$" + formatD3(",", value)
+	case strings.HasPrefix(format, "$") && strings.Contains(format, ","):
+		precision := ""
+		if idx := strings.Index(format, "."); idx >= 0 {
+			end := idx + 1
+			for end < len(format) && format[end] >= '0' && format[end] <= '9' {
+				end++
+			}
+			if end > idx+1 {
+				precision = format[idx:end]
+			}
+		}
+		return "$" + formatD3(","+precision+"f", value)
+	case strings.HasPrefix(format, "$"):
+		return "$" + formatD3(format[1:], value)
+	default:
+		return formatD3(format, value)
+	}
+}
+
 func isFinite(value float64) bool {
 	return !math.IsNaN(value) && !math.IsInf(value, 0)
 }
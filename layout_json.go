@@ -0,0 +1,154 @@
+package mermaid
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// LayoutJSONVersion is the version of the layout JSON format written by
+// MarshalLayout. Fields may be added without changing it; it is increased
+// when a field is removed, renamed or changes meaning.
+const LayoutJSONVersion = 1
+
+// layoutJSON is the serialised form of a Layout: its fields, flattened,
+// next to the format version.
+type layoutJSON struct {
+	Version int `json:"version"`
+	Layout
+}
+
+// MarshalLayout serialises a layout as indented JSON, the format documented
+// by LayoutJSONSchema. Coordinates are in SVG user units, with y growing
+// downwards; fields with zero values are left out.
+func MarshalLayout(layout Layout) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(layoutJSON{Version: LayoutJSONVersion, Layout: layout}); err != nil {
+		return nil, fmt.Errorf("marshal layout: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalLayout reads a layout written by MarshalLayout. Unknown fields
+// are ignored, so layouts from newer releases of the same version load;
+// layouts of a newer version are rejected.
+func UnmarshalLayout(data []byte) (Layout, error) {
+	var decoded layoutJSON
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return Layout{}, fmt.Errorf("unmarshal layout: %w", err)
+	}
+	switch {
+	case decoded.Version == 0:
+		return Layout{}, errors.New("unmarshal layout: missing version")
+	case decoded.Version > LayoutJSONVersion:
+		return Layout{}, fmt.Errorf("unmarshal layout: version %d is newer than the supported version %d", decoded.Version, LayoutJSONVersion)
+	}
+	return decoded.Layout, nil
+}
+
+// RenderLayoutJSON parses and lays out a Mermaid diagram and returns the
+// layout as JSON. See MarshalLayout.
+func RenderLayoutJSON(input string, options RenderOptions) ([]byte, error) {
+	if strings.TrimSpace(input) == "" {
+		return nil, errors.New("input diagram is empty")
+	}
+	parsed, err := ParseMermaid(input)
+	if err != nil {
+		return nil, err
+	}
+	if err := ensureHighFidelityOrAllowApproximate(parsed.Graph.Kind, options); err != nil {
+		return nil, err
+	}
+	return MarshalLayout(ComputeLayout(&parsed.Graph, options.Theme, options.Layout))
+}
+
+// WriteLayoutJSONFromSource writes the layout of a Mermaid diagram as JSON
+// to a file, or to stdout when outputPath is empty. See RenderLayoutJSON.
+func WriteLayoutJSONFromSource(mermaidCode string, outputPath string, options RenderOptions) error {
+	data, err := RenderLayoutJSON(mermaidCode, options)
+	if err != nil {
+		return err
+	}
+	return writeOutputBytes(data, outputPath)
+}
+
+// LayoutJSONSchema returns the JSON Schema of the layout format written by
+// MarshalLayout. It is generated from the Layout type, so it always matches
+// the fields this release writes.
+func LayoutJSONSchema() []byte {
+	defs := map[string]any{}
+	properties, required := jsonSchemaProperties(reflect.TypeFor[Layout](), defs)
+	properties["version"] = map[string]any{
+		"const":       LayoutJSONVersion,
+		"description": "Version of the layout format.",
+	}
+	schema := map[string]any{
+		"$schema":     "https://json-schema.org/draft/2020-12/schema",
+		"$id":         fmt.Sprintf("https://github.com/bvolpato/mermaid-go-renderer/schema/layout-v%d.schema.json", LayoutJSONVersion),
+		"title":       "mmdg diagram layout",
+		"description": "Positioned shapes, edges and text of a Mermaid diagram, in SVG user units with y growing downwards. Fields with zero values are left out.",
+		"type":        "object",
+		"properties":  properties,
+		"required":    append([]string{"version"}, required...),
+		"$defs":       defs,
+	}
+	data, _ := json.MarshalIndent(schema, "", "  ")
+	return append(data, '\n')
+}
+
+// jsonSchemaProperties describes the JSON fields of a struct type, adding
+// the structs it refers to to defs. Fields without omitempty are required.
+func jsonSchemaProperties(t reflect.Type, defs map[string]any) (map[string]any, []string) {
+	properties := map[string]any{}
+	var required []string
+	for i := range t.NumField() {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		name, options, _ := strings.Cut(tag, ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		properties[name] = jsonSchemaType(field.Type, defs)
+		if options != "omitempty" {
+			required = append(required, name)
+		}
+	}
+	return properties, required
+}
+
+// jsonSchemaType describes how values of t are written. Structs become
+// references into defs.
+func jsonSchemaType(t reflect.Type, defs map[string]any) map[string]any {
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice:
+		return map[string]any{"type": "array", "items": jsonSchemaType(t.Elem(), defs)}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": jsonSchemaType(t.Elem(), defs)}
+	case reflect.Struct:
+		if _, ok := defs[t.Name()]; !ok {
+			defs[t.Name()] = nil
+			properties, required := jsonSchemaProperties(t, defs)
+			def := map[string]any{"type": "object", "properties": properties}
+			if len(required) > 0 {
+				def["required"] = required
+			}
+			defs[t.Name()] = def
+		}
+		return map[string]any{"$ref": "#/$defs/" + t.Name()}
+	}
+	panic(fmt.Sprintf("no JSON schema for %s", t))
+}
@@ -0,0 +1,122 @@
+package mermaid
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLayoutJSONRoundTripRendersTheSameSVG(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join("samples", "*.mmd"))
+	if err != nil || len(paths) == 0 {
+		t.Fatalf("list samples: %v", err)
+	}
+	options := DefaultRenderOptions().WithAllowApproximate(true)
+	for _, path := range paths {
+		t.Run(strings.TrimSuffix(filepath.Base(path), ".mmd"), func(t *testing.T) {
+			content, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("read sample: %v", err)
+			}
+			parsed, err := ParseMermaid(string(content))
+			if err != nil {
+				t.Fatalf("parse sample: %v", err)
+			}
+			layout := ComputeLayout(&parsed.Graph, options.Theme, options.Layout)
+			data, err := MarshalLayout(layout)
+			if err != nil {
+				t.Fatalf("MarshalLayout() error = %v", err)
+			}
+			decoded, err := UnmarshalLayout(data)
+			if err != nil {
+				t.Fatalf("UnmarshalLayout() error = %v", err)
+			}
+			want := RenderSVG(layout, options.Theme, options.Layout)
+			if got := RenderSVG(decoded, options.Theme, options.Layout); got != want {
+				t.Fatal("expected the decoded layout to render the same SVG")
+			}
+		})
+	}
+}
+
+func TestLayoutJSONFormat(t *testing.T) {
+	data, err := RenderLayoutJSON("flowchart LR\n  A[Start] -->|go <b>now</b>| B(End)", DefaultRenderOptions())
+	if err != nil {
+		t.Fatalf("RenderLayoutJSON() error = %v", err)
+	}
+	var doc struct {
+		Version int     `json:"version"`
+		Kind    string  `json:"kind"`
+		Width   float64 `json:"width"`
+		Nodes   []struct {
+			ID string  `json:"id"`
+			W  float64 `json:"w"`
+		} `json:"nodes"`
+		Edges []struct {
+			From   string `json:"from"`
+			To     string `json:"to"`
+			Label  string `json:"label"`
+			Points []struct {
+				X float64 `json:"x"`
+				Y float64 `json:"y"`
+			} `json:"points"`
+		} `json:"edges"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("decode layout JSON: %v", err)
+	}
+	if doc.Version != LayoutJSONVersion || doc.Kind != "flowchart" || doc.Width <= 0 {
+		t.Fatalf("unexpected header: version %d, kind %q, width %v", doc.Version, doc.Kind, doc.Width)
+	}
+	if len(doc.Nodes) != 2 || doc.Nodes[0].ID != "A" || doc.Nodes[0].W <= 0 {
+		t.Fatalf("unexpected nodes: %+v", doc.Nodes)
+	}
+	if len(doc.Edges) != 1 || doc.Edges[0].From != "A" || doc.Edges[0].To != "B" {
+		t.Fatalf("unexpected edges: %+v", doc.Edges)
+	}
+	// Edge waypoints are part of version 1, for renderers that route edges
+	// themselves.
+	if points := doc.Edges[0].Points; len(points) < 2 || points[len(points)-1].X <= points[0].X {
+		t.Fatalf("expected left-to-right edge points, got %+v", points)
+	}
+	if !bytes.Contains(data, []byte("<b>now</b>")) {
+		t.Fatal("expected markup in labels to be written without HTML escaping")
+	}
+
+	if _, err := UnmarshalLayout([]byte(`{"kind":"flowchart"}`)); err == nil || !strings.Contains(err.Error(), "missing version") {
+		t.Fatalf("expected a missing version error, got %v", err)
+	}
+	if _, err := UnmarshalLayout([]byte(`{"version":99,"kind":"flowchart"}`)); err == nil || !strings.Contains(err.Error(), "newer") {
+		t.Fatalf("expected a newer version error, got %v", err)
+	}
+	if layout, err := UnmarshalLayout([]byte(`{"version":1,"kind":"pie","future_field":true}`)); err != nil || layout.Kind != DiagramPie {
+		t.Fatalf("expected unknown fields to be ignored, got %v, %v", layout.Kind, err)
+	}
+}
+
+func TestLayoutJSONSchemaFileIsCurrent(t *testing.T) {
+	path := filepath.Join("schema", "layout-v1.schema.json")
+	schema := LayoutJSONSchema()
+	if os.Getenv("MMDG_UPDATE_LAYOUT_SCHEMA") == "1" {
+		if err := os.WriteFile(path, schema, 0o644); err != nil {
+			t.Fatalf("write schema: %v", err)
+		}
+	}
+	committed, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read schema: %v", err)
+	}
+	if !bytes.Equal(committed, schema) {
+		t.Fatalf("%s is out of date; regenerate it with MMDG_UPDATE_LAYOUT_SCHEMA=1 go test -run TestLayoutJSONSchemaFileIsCurrent", path)
+	}
+	var parsed map[string]any
+	if err := json.Unmarshal(schema, &parsed); err != nil {
+		t.Fatalf("schema is not valid JSON: %v", err)
+	}
+	if _, ok := parsed["$defs"].(map[string]any)["NodeLayout"]; !ok {
+		t.Fatal("expected the schema to define NodeLayout")
+	}
+}
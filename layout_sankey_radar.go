@@ -46,12 +46,16 @@ func layoutSankeyFidelity(graph *Graph, theme Theme, config LayoutConfig) Layout
 		return layoutGraphLike(graph, theme, config)
 	}
 
-	const (
-		width       = 600.0
-		height      = 400.0
-		nodeWidth   = 10.0
-		nodePadding = 25.0
-	)
+	cfg := config.Sankey
+	defaults := DefaultSankeyConfig()
+	width := defaultFloat(cfg.Width, defaults.Width)
+	height := defaultFloat(cfg.Height, defaults.Height)
+	nodeWidth := defaultFloat(cfg.NodeWidth, defaults.NodeWidth)
+	// Mermaid reserves room for the value line under each label.
+	nodePadding := 10.0
+	if cfg.ShowValues {
+		nodePadding += 15.0
+	}
 
 	nodes := make([]*sankeyNodeState, 0, 16)
 	nodeByID := map[string]*sankeyNodeState{}
@@ -86,7 +90,7 @@ func layoutSankeyFidelity(graph *Graph, theme Theme, config LayoutConfig) Layout
 		return layoutGraphLike(graph, theme, config)
 	}
 
-	maxRank := assignSankeyRanks(nodes)
+	maxRank := assignSankeyRanks(nodes, cfg.NodeAlignment)
 	columns := make([][]*sankeyNodeState, maxRank+1)
 	for _, node := range nodes {
 		if node.Rank < 0 {
@@ -142,8 +146,13 @@ func layoutSankeyFidelity(graph *Graph, theme Theme, config LayoutConfig) Layout
 	for i, node := range nodes {
 		color := sankeyTableau10[i%len(sankeyTableau10)]
 		colorByID[node.ID] = color
+		label := node.ID
+		if cfg.ShowValues {
+			label += "\n" + cfg.Prefix + formatSankeyValue(math.Round(node.Value*100)/100) + cfg.Suffix
+		}
 		layout.SankeyNodes = append(layout.SankeyNodes, SankeyNodeLayout{
 			ID:    node.ID,
+			Label: label,
 			Value: node.Value,
 			X0:    node.X0,
 			Y0:    node.Y0,
@@ -159,6 +168,16 @@ func layoutSankeyFidelity(graph *Graph, theme Theme, config LayoutConfig) Layout
 			"C" + formatFloat(midX) + "," + formatFloat(link.Y0) +
 			"," + formatFloat(midX) + "," + formatFloat(link.Y1) +
 			"," + formatFloat(link.Target.X0) + "," + formatFloat(link.Y1)
+		stroke := ""
+		switch lower(cfg.LinkColor) {
+		case "", SankeyLinkColorGradient:
+		case SankeyLinkColorSource:
+			stroke = colorByID[link.Source.ID]
+		case SankeyLinkColorTarget:
+			stroke = colorByID[link.Target.ID]
+		default:
+			stroke = cfg.LinkColor
+		}
 		layout.SankeyLinks = append(layout.SankeyLinks, SankeyLinkLayout{
 			SourceID:    link.Source.ID,
 			TargetID:    link.Target.ID,
@@ -169,6 +188,7 @@ func layoutSankeyFidelity(graph *Graph, theme Theme, config LayoutConfig) Layout
 			X1:          link.Target.X0,
 			Y1:          link.Y1,
 			Path:        path,
+			Stroke:      stroke,
 			SourceColor: colorByID[link.Source.ID],
 			TargetColor: colorByID[link.Target.ID],
 		})
@@ -177,10 +197,14 @@ func layoutSankeyFidelity(graph *Graph, theme Theme, config LayoutConfig) Layout
 	return layout
 }
 
-func assignSankeyRanks(nodes []*sankeyNodeState) int {
+// assignSankeyRanks places every node in a column following d3-sankey's
+// alignment functions and returns the index of the last column.
+func assignSankeyRanks(nodes []*sankeyNodeState, align SankeyNodeAlignment) int {
 	indegree := map[*sankeyNodeState]int{}
+	outdegree := map[*sankeyNodeState]int{}
 	for _, node := range nodes {
 		indegree[node] = len(node.In)
+		outdegree[node] = len(node.Out)
 		sumIn := 0.0
 		for _, in := range node.In {
 			sumIn += in.Value
@@ -195,6 +219,7 @@ func assignSankeyRanks(nodes []*sankeyNodeState) int {
 		}
 	}
 
+	// Depth: longest distance from a source node.
 	queue := make([]*sankeyNodeState, 0, len(nodes))
 	for _, node := range nodes {
 		if indegree[node] == 0 {
@@ -204,7 +229,6 @@ func assignSankeyRanks(nodes []*sankeyNodeState) int {
 	if len(queue) == 0 {
 		queue = append(queue, nodes...)
 	}
-
 	for len(queue) > 0 {
 		node := queue[0]
 		queue = queue[1:]
@@ -219,21 +243,64 @@ func assignSankeyRanks(nodes []*sankeyNodeState) int {
 		}
 	}
 
+	// Height: longest distance to a sink node.
+	heights := map[*sankeyNodeState]int{}
+	queue = queue[:0]
+	for _, node := range nodes {
+		if outdegree[node] == 0 {
+			queue = append(queue, node)
+		}
+	}
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+		for _, in := range node.In {
+			if heights[in.Source] < heights[node]+1 {
+				heights[in.Source] = heights[node] + 1
+			}
+			outdegree[in.Source]--
+			if outdegree[in.Source] == 0 {
+				queue = append(queue, in.Source)
+			}
+		}
+	}
+
 	maxRank := 0
 	for _, node := range nodes {
 		if node.Rank > maxRank {
 			maxRank = node.Rank
 		}
 	}
+
+	depths := make(map[*sankeyNodeState]int, len(nodes))
 	for _, node := range nodes {
-		if len(node.Out) == 0 {
-			node.Rank = maxRank
-		}
+		depths[node] = node.Rank
 	}
 	for _, node := range nodes {
-		if node.Rank > maxRank {
-			maxRank = node.Rank
+		switch align {
+		case SankeyAlignLeft:
+			node.Rank = depths[node]
+		case SankeyAlignRight:
+			node.Rank = maxRank - heights[node]
+		case SankeyAlignCenter:
+			switch {
+			case len(node.In) > 0:
+				node.Rank = depths[node]
+			case len(node.Out) > 0:
+				minTarget := maxRank
+				for _, out := range node.Out {
+					minTarget = min(minTarget, depths[out.Target])
+				}
+				node.Rank = minTarget - 1
+			default:
+				node.Rank = 0
+			}
+		default:
+			if len(node.Out) == 0 {
+				node.Rank = maxRank
+			}
 		}
+		node.Rank = max(0, min(node.Rank, maxRank))
 	}
 	return maxRank
 }
@@ -394,17 +461,16 @@ func layoutRadarFidelity(graph *Graph, theme Theme, config LayoutConfig) Layout
 		return layoutGraphLike(graph, theme, config)
 	}
 
-	const (
-		chartWidth      = 600.0
-		chartHeight     = 600.0
-		marginTop       = 50.0
-		marginRight     = 50.0
-		marginBottom    = 50.0
-		marginLeft      = 50.0
-		axisScaleFactor = 1.0
-		axisLabelFactor = 1.05
-		curveTension    = 0.17
-	)
+	cfg := config.Radar
+	chartWidth := cfg.Width
+	chartHeight := cfg.Height
+	marginTop := cfg.MarginTop
+	marginRight := cfg.MarginRight
+	marginBottom := cfg.MarginBottom
+	marginLeft := cfg.MarginLeft
+	axisScaleFactor := cfg.AxisScaleFactor
+	axisLabelFactor := cfg.AxisLabelFactor
+	curveTension := cfg.CurveTension
 
 	totalWidth := chartWidth + marginLeft + marginRight
 	totalHeight := chartHeight + marginTop + marginBottom
@@ -467,6 +533,9 @@ func layoutRadarFidelity(graph *Graph, theme Theme, config LayoutConfig) Layout
 		RadarLegendX:          ((chartWidth*0.5 + marginRight) * 3.0) / 4.0,
 		RadarLegendY:          (-(chartHeight*0.5 + marginTop) * 3.0) / 4.0,
 		RadarLegendLineHeight: 20.0,
+		RadarTitleY:           -(chartHeight*0.5 + marginTop),
+		RadarCurveColors:      cfg.CurveColors,
+		RadarCurveOpacity:     cfg.CurveOpacity,
 	}
 	if layout.RadarGraticule == "" {
 		layout.RadarGraticule = "circle"
@@ -504,7 +573,11 @@ func layoutRadarFidelity(graph *Graph, theme Theme, config LayoutConfig) Layout
 			Label: curve.Label,
 			Class: "radarCurve-" + intString(i),
 		}
-		if layout.RadarGraticule == "polygon" {
+		if curve.Class != "" {
+			curveLayout.Class += " " + curve.Class
+			curveLayout.Style = classDefStyle(graph.ClassDefs, strings.Fields(curve.Class))
+		}
+		if layout.RadarGraticule == "polygon" && !cfg.SmoothPolygon {
 			curveLayout.Polygon = true
 			curveLayout.Path = radarPointsString(points)
 		} else {
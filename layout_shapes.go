@@ -0,0 +1,410 @@
+package mermaid
+
+import (
+	"math"
+	"strings"
+
+	"github.com/bvolpato/mermaid-go-renderer/dagre"
+)
+
+// shapeGeometry describes how a node shape is drawn inside its box. Parts
+// are painted in order; hull is the outline edges are clipped against.
+type shapeGeometry struct {
+	parts []shapePart
+	hull  []Point
+}
+
+// shapePart is one stroke of a shape. Closed parts are filled with the node
+// fill, open parts are only stroked and solid parts are filled with the
+// stroke colour.
+type shapePart struct {
+	points []Point
+	closed bool
+	solid  bool
+}
+
+const (
+	shapeCurveSteps  = 24
+	shapeCircleSteps = 64
+	shapeStackOffset = 5.0
+)
+
+// nodeShapeGeometry returns the outline of shape inside the box (x, y, w, h).
+// Curved sides are sampled so the same points serve SVG paths, the raster
+// painter and edge clipping. Plain rectangles and rounded boxes report false.
+func nodeShapeGeometry(shape NodeShape, x, y, w, h float64) (shapeGeometry, bool) {
+	r := x + w
+	b := y + h
+	cx := x + w/2
+	cy := y + h/2
+	switch shape {
+	case ShapeDiamond:
+		return polygonGeometry(Point{X: cx, Y: y}, Point{X: r, Y: cy}, Point{X: cx, Y: b}, Point{X: x, Y: cy}), true
+	case ShapeHexagon:
+		return polygonGeometry(
+			Point{X: x + w*0.2, Y: y}, Point{X: x + w*0.8, Y: y}, Point{X: r, Y: cy},
+			Point{X: x + w*0.8, Y: b}, Point{X: x + w*0.2, Y: b}, Point{X: x, Y: cy},
+		), true
+	case ShapeParallelogram:
+		return polygonGeometry(Point{X: x + 14, Y: y}, Point{X: r, Y: y}, Point{X: r - 14, Y: b}, Point{X: x, Y: b}), true
+	case ShapeLeanLeft:
+		return polygonGeometry(Point{X: x, Y: y}, Point{X: r - 14, Y: y}, Point{X: r, Y: b}, Point{X: x + 14, Y: b}), true
+	case ShapeTrapezoid:
+		return polygonGeometry(Point{X: x + 16, Y: y}, Point{X: r - 16, Y: y}, Point{X: r, Y: b}, Point{X: x, Y: b}), true
+	case ShapeTrapezoidTop:
+		return polygonGeometry(Point{X: x, Y: y}, Point{X: r, Y: y}, Point{X: r - 16, Y: b}, Point{X: x + 16, Y: b}), true
+	case ShapeAsymmetric:
+		notch := min(h/2, w/4)
+		return polygonGeometry(Point{X: x, Y: y}, Point{X: r, Y: y}, Point{X: r, Y: b}, Point{X: x, Y: b}, Point{X: x + notch, Y: cy}), true
+	case ShapeNotchedRect:
+		notch := min(12, w/4, h/4)
+		return polygonGeometry(Point{X: x + notch, Y: y}, Point{X: r, Y: y}, Point{X: r, Y: b}, Point{X: x, Y: b}, Point{X: x, Y: y + notch}), true
+	case ShapeNotchedPentagon:
+		notch := min(12, w/4, h/4)
+		return polygonGeometry(
+			Point{X: x + notch, Y: y}, Point{X: r - notch, Y: y}, Point{X: r, Y: y + notch},
+			Point{X: r, Y: b}, Point{X: x, Y: b}, Point{X: x, Y: y + notch},
+		), true
+	case ShapeSlopedRect:
+		slope := min(12, h/3)
+		return polygonGeometry(Point{X: x, Y: y + slope}, Point{X: r, Y: y}, Point{X: r, Y: b}, Point{X: x, Y: b}), true
+	case ShapeTriangle:
+		return polygonGeometry(Point{X: cx, Y: y}, Point{X: r, Y: b}, Point{X: x, Y: b}), true
+	case ShapeFlippedTriangle:
+		return polygonGeometry(Point{X: x, Y: y}, Point{X: r, Y: y}, Point{X: cx, Y: b}), true
+	case ShapeHourglass:
+		return polygonGeometry(Point{X: x, Y: y}, Point{X: r, Y: y}, Point{X: x, Y: b}, Point{X: r, Y: b}), true
+	case ShapeBolt:
+		return polygonGeometry(
+			Point{X: x + w*0.55, Y: y}, Point{X: x, Y: y + h*0.6}, Point{X: x + w*0.45, Y: y + h*0.6},
+			Point{X: x + w*0.3, Y: b}, Point{X: r, Y: y + h*0.35}, Point{X: x + w*0.55, Y: y + h*0.35},
+			Point{X: x + w*0.85, Y: y},
+		), true
+	case ShapeFork:
+		outline := rectOutline(x, y, w, h)
+		return shapeGeometry{parts: []shapePart{{points: outline, closed: true, solid: true}}, hull: outline}, true
+	case ShapeSubroutine:
+		return detailedRect(x, y, w, h,
+			[]Point{{X: x + 8, Y: y}, {X: x + 8, Y: b}},
+			[]Point{{X: r - 8, Y: y}, {X: r - 8, Y: b}},
+		), true
+	case ShapeLinedRect:
+		return detailedRect(x, y, w, h, []Point{{X: x + 8, Y: y}, {X: x + 8, Y: b}}), true
+	case ShapeDividedRect:
+		divider := y + min(12, h/4)
+		return detailedRect(x, y, w, h, []Point{{X: x, Y: divider}, {X: r, Y: divider}}), true
+	case ShapeWindowPane:
+		return detailedRect(x, y, w, h,
+			[]Point{{X: x + 10, Y: y}, {X: x + 10, Y: b}},
+			[]Point{{X: x, Y: y + 10}, {X: r, Y: y + 10}},
+		), true
+	case ShapeTaggedRect:
+		tag := min(w, h) * 0.2
+		return detailedRect(x, y, w, h, []Point{{X: r - tag, Y: b}, {X: r, Y: b - tag}}), true
+	case ShapeStackedRect:
+		o := shapeStackOffset
+		return shapeGeometry{
+			parts: []shapePart{
+				{points: rectOutline(x+2*o, y, w-2*o, h-2*o), closed: true},
+				{points: rectOutline(x+o, y+o, w-2*o, h-2*o), closed: true},
+				{points: rectOutline(x, y+2*o, w-2*o, h-2*o), closed: true},
+			},
+			hull: stackedHull(x, y, w, h, o),
+		}, true
+	case ShapeDocument, ShapeLinedDocument, ShapeTaggedDocument:
+		amp := documentWaveAmplitude(h)
+		outline := documentOutline(x, y, w, h, amp)
+		geometry := shapeGeometry{parts: []shapePart{{points: outline, closed: true}}, hull: outline}
+		switch shape {
+		case ShapeLinedDocument:
+			geometry.parts = append(geometry.parts, shapePart{points: []Point{
+				{X: x + 8, Y: y}, {X: x + 8, Y: documentWaveY(x+8, x, w, b-amp, amp)},
+			}})
+		case ShapeTaggedDocument:
+			tag := min(w, h) * 0.2
+			geometry.parts = append(geometry.parts, shapePart{points: []Point{
+				{X: r - tag, Y: documentWaveY(r-tag, x, w, b-amp, amp)}, {X: r, Y: b - amp - tag},
+			}})
+		}
+		return geometry, true
+	case ShapeStackedDocument:
+		o := shapeStackOffset
+		amp := documentWaveAmplitude(h - 2*o)
+		return shapeGeometry{
+			parts: []shapePart{
+				{points: documentOutline(x+2*o, y, w-2*o, h-2*o, amp), closed: true},
+				{points: documentOutline(x+o, y+o, w-2*o, h-2*o, amp), closed: true},
+				{points: documentOutline(x, y+2*o, w-2*o, h-2*o, amp), closed: true},
+			},
+			hull: stackedHull(x, y, w, h, o),
+		}, true
+	case ShapeFlag:
+		amp := documentWaveAmplitude(h)
+		outline := make([]Point, 0, 2*shapeCurveSteps+2)
+		for i := 0; i <= shapeCurveSteps; i++ {
+			px := x + w*float64(i)/shapeCurveSteps
+			outline = append(outline, Point{X: px, Y: documentWaveY(px, x, w, y+amp, amp)})
+		}
+		for i := shapeCurveSteps; i >= 0; i-- {
+			px := x + w*float64(i)/shapeCurveSteps
+			outline = append(outline, Point{X: px, Y: documentWaveY(px, x, w, b-amp, amp)})
+		}
+		return polygonGeometry(outline...), true
+	case ShapeDelay:
+		rx := min(h/2, w/2)
+		outline := []Point{{X: x, Y: y}}
+		outline = append(outline, ellipseArc(r-rx, cy, rx, h/2, -90, 90, shapeCurveSteps)...)
+		outline = append(outline, Point{X: x, Y: b})
+		return polygonGeometry(outline...), true
+	case ShapeCurvedTrapezoid:
+		rx := min(h/2, w/4)
+		tip := min(h/2, w/5)
+		outline := []Point{{X: x, Y: cy}, {X: x + tip, Y: y}}
+		outline = append(outline, ellipseArc(r-rx, cy, rx, h/2, -90, 90, shapeCurveSteps)...)
+		outline = append(outline, Point{X: x + tip, Y: b})
+		return polygonGeometry(outline...), true
+	case ShapeBowTieRect:
+		rx := min(w/5, h/4)
+		outline := []Point{{X: x + rx, Y: y}}
+		outline = append(outline, ellipseArc(r, cy, rx, h/2, 270, 90, shapeCurveSteps)...)
+		outline = append(outline, ellipseArc(x+rx, cy, rx, h/2, 90, 270, shapeCurveSteps)...)
+		return polygonGeometry(outline...), true
+	case ShapeCloud:
+		// Eight lobes around an ellipse, meeting in cusps.
+		outline := make([]Point, 0, 2*shapeCircleSteps)
+		for i := 0; i < 2*shapeCircleSteps; i++ {
+			angle := 2 * math.Pi * float64(i) / (2 * shapeCircleSteps)
+			scale := 0.86 + 0.14*math.Abs(math.Sin(4*angle))
+			outline = append(outline, Point{X: cx + w/2*scale*math.Cos(angle), Y: cy + h/2*scale*math.Sin(angle)})
+		}
+		return polygonGeometry(outline...), true
+	case ShapeBang:
+		// Twelve spikes around an ellipse.
+		const spikes = 12
+		outline := make([]Point, 0, 2*spikes)
+		for i := 0; i < 2*spikes; i++ {
+			angle := math.Pi * float64(i) / spikes
+			scale := 1.0
+			if i%2 == 1 {
+				scale = 0.8
+			}
+			outline = append(outline, Point{X: cx + w/2*scale*math.Cos(angle), Y: cy + h/2*scale*math.Sin(angle)})
+		}
+		return polygonGeometry(outline...), true
+	case ShapeCylinder, ShapeLinedCylinder:
+		ry := h * 0.11125
+		outline := ellipseArc(cx, y+ry, w/2, ry, 180, 360, shapeCurveSteps)
+		outline = append(outline, ellipseArc(cx, b-ry, w/2, ry, 0, 180, shapeCurveSteps)...)
+		geometry := shapeGeometry{
+			parts: []shapePart{
+				{points: outline, closed: true},
+				{points: ellipseArc(cx, y+ry, w/2, ry, 180, 0, shapeCurveSteps)},
+			},
+			hull: outline,
+		}
+		if shape == ShapeLinedCylinder {
+			geometry.parts = append(geometry.parts, shapePart{points: ellipseArc(cx, y+3*ry, w/2, ry, 180, 0, shapeCurveSteps)})
+		}
+		return geometry, true
+	case ShapeHorizontalCylinder:
+		rx := min(w/4, h/5)
+		outline := ellipseArc(r-rx, cy, rx, h/2, -90, 90, shapeCurveSteps)
+		outline = append(outline, ellipseArc(x+rx, cy, rx, h/2, 90, 270, shapeCurveSteps)...)
+		return shapeGeometry{
+			parts: []shapePart{
+				{points: outline, closed: true},
+				{points: ellipseArc(r-rx, cy, rx, h/2, 90, 270, shapeCurveSteps)},
+			},
+			hull: outline,
+		}, true
+	case ShapeBraceLeft, ShapeBraceRight, ShapeBraces:
+		radius := min(8, h/4)
+		left := braceLeftPoints(x, y, h, radius)
+		geometry := shapeGeometry{hull: rectOutline(x, y, w, h)}
+		if shape != ShapeBraceRight {
+			geometry.parts = append(geometry.parts, shapePart{points: left})
+		}
+		if shape != ShapeBraceLeft {
+			right := make([]Point, len(left))
+			for i, p := range left {
+				right[i] = Point{X: 2*cx - p.X, Y: p.Y}
+			}
+			geometry.parts = append(geometry.parts, shapePart{points: right})
+		}
+		return geometry, true
+	case ShapeText:
+		return shapeGeometry{hull: rectOutline(x, y, w, h)}, true
+	case ShapeSmallCircle, ShapeFilledCircle:
+		outline := ellipseArc(cx, cy, min(w, h)/2, min(w, h)/2, 0, 360, shapeCircleSteps)
+		return shapeGeometry{parts: []shapePart{{points: outline, closed: true, solid: true}}, hull: outline}, true
+	case ShapeFramedCircle:
+		radius := min(w, h) / 2
+		outline := ellipseArc(cx, cy, radius, radius, 0, 360, shapeCircleSteps)
+		return shapeGeometry{
+			parts: []shapePart{
+				{points: outline, closed: true},
+				{points: ellipseArc(cx, cy, radius/2, radius/2, 0, 360, shapeCircleSteps), closed: true, solid: true},
+			},
+			hull: outline,
+		}, true
+	case ShapeCrossedCircle:
+		radius := min(w, h) / 2
+		d := radius * math.Sqrt2 / 2
+		outline := ellipseArc(cx, cy, radius, radius, 0, 360, shapeCircleSteps)
+		return shapeGeometry{
+			parts: []shapePart{
+				{points: outline, closed: true},
+				{points: []Point{{X: cx - d, Y: cy - d}, {X: cx + d, Y: cy + d}}},
+				{points: []Point{{X: cx + d, Y: cy - d}, {X: cx - d, Y: cy + d}}},
+			},
+			hull: outline,
+		}, true
+	default:
+		return shapeGeometry{}, false
+	}
+}
+
+// shapeHidesLabel reports whether mermaid draws the shape without its label.
+func shapeHidesLabel(shape NodeShape) bool {
+	switch shape {
+	case ShapeSmallCircle, ShapeFilledCircle, ShapeFramedCircle, ShapeCrossedCircle,
+		ShapeFork, ShapeHourglass, ShapeBolt:
+		return true
+	default:
+		return false
+	}
+}
+
+// shapeIntersector returns the dagre hook that clips edges against the
+// outline of shape, or nil when the bounding box is already exact.
+func shapeIntersector(shape NodeShape) func(*dagre.NodeLabel, dagre.Point) dagre.Point {
+	switch shape {
+	case ShapeCircle, ShapeDoubleCircle, ShapeSmallCircle, ShapeFilledCircle, ShapeFramedCircle, ShapeCrossedCircle:
+		return func(node *dagre.NodeLabel, point dagre.Point) dagre.Point {
+			radius := min(node.Width, node.Height) / 2
+			return dagre.IntersectEllipse(node, radius, radius, point)
+		}
+	}
+	if _, ok := nodeShapeGeometry(shape, 0, 0, 1, 1); !ok {
+		return nil
+	}
+	return func(node *dagre.NodeLabel, point dagre.Point) dagre.Point {
+		geometry, _ := nodeShapeGeometry(shape, -node.Width/2, -node.Height/2, node.Width, node.Height)
+		polygon := make([]dagre.Point, len(geometry.hull))
+		for i, p := range geometry.hull {
+			polygon[i] = dagre.Point{X: p.X, Y: p.Y}
+		}
+		return dagre.IntersectPolygon(node, polygon, point)
+	}
+}
+
+// addShapeGeometry appends the parts of geometry to layout as paths.
+func addShapeGeometry(layout *Layout, geometry shapeGeometry, fill, stroke string, strokeWidth float64) {
+	for _, part := range geometry.parts {
+		path := LayoutPath{
+			D:           shapePartPath(part),
+			Fill:        "none",
+			Stroke:      stroke,
+			StrokeWidth: strokeWidth,
+		}
+		switch {
+		case part.solid:
+			path.Fill = stroke
+		case part.closed:
+			path.Fill = fill
+		}
+		layout.Paths = append(layout.Paths, path)
+	}
+}
+
+func shapePartPath(part shapePart) string {
+	var b strings.Builder
+	for i, p := range part.points {
+		if i == 0 {
+			b.WriteString("M ")
+		} else {
+			b.WriteString(" L ")
+		}
+		b.WriteString(formatFloat(p.X) + "," + formatFloat(p.Y))
+	}
+	if part.closed {
+		b.WriteString(" Z")
+	}
+	return b.String()
+}
+
+func polygonGeometry(points ...Point) shapeGeometry {
+	return shapeGeometry{parts: []shapePart{{points: points, closed: true}}, hull: points}
+}
+
+func rectOutline(x, y, w, h float64) []Point {
+	return []Point{{X: x, Y: y}, {X: x + w, Y: y}, {X: x + w, Y: y + h}, {X: x, Y: y + h}}
+}
+
+func detailedRect(x, y, w, h float64, lines ...[]Point) shapeGeometry {
+	geometry := polygonGeometry(rectOutline(x, y, w, h)...)
+	for _, line := range lines {
+		geometry.parts = append(geometry.parts, shapePart{points: line})
+	}
+	return geometry
+}
+
+// stackedHull is the outline of three boxes stacked diagonally by offset,
+// back box at the top right.
+func stackedHull(x, y, w, h, offset float64) []Point {
+	r := x + w
+	b := y + h
+	return []Point{
+		{X: x + 2*offset, Y: y}, {X: r, Y: y}, {X: r, Y: b - 2*offset},
+		{X: r - offset, Y: b - 2*offset}, {X: r - offset, Y: b - offset},
+		{X: r - 2*offset, Y: b - offset}, {X: r - 2*offset, Y: b},
+		{X: x, Y: b}, {X: x, Y: y + 2*offset}, {X: x + offset, Y: y + 2*offset},
+		{X: x + offset, Y: y + offset}, {X: x + 2*offset, Y: y + offset},
+	}
+}
+
+func documentWaveAmplitude(h float64) float64 {
+	return min(7, h/10)
+}
+
+// documentWaveY is the y of a single sine period spanning the box width,
+// centred on mid.
+func documentWaveY(px, x, w, mid, amp float64) float64 {
+	if w <= 0 {
+		return mid
+	}
+	return mid - amp*math.Sin(2*math.Pi*(px-x)/w)
+}
+
+func documentOutline(x, y, w, h, amp float64) []Point {
+	outline := make([]Point, 0, shapeCurveSteps+3)
+	outline = append(outline, Point{X: x, Y: y}, Point{X: x + w, Y: y})
+	for i := shapeCurveSteps; i >= 0; i-- {
+		px := x + w*float64(i)/shapeCurveSteps
+		outline = append(outline, Point{X: px, Y: documentWaveY(px, x, w, y+h-amp, amp)})
+	}
+	return outline
+}
+
+// ellipseArc samples an elliptical arc from angle a0 to a1 (degrees, y axis
+// pointing down), endpoints included.
+func ellipseArc(cx, cy, rx, ry, a0, a1 float64, steps int) []Point {
+	points := make([]Point, 0, steps+1)
+	for i := 0; i <= steps; i++ {
+		angle := (a0 + (a1-a0)*float64(i)/float64(steps)) * math.Pi / 180
+		points = append(points, Point{X: cx + rx*math.Cos(angle), Y: cy + ry*math.Sin(angle)})
+	}
+	return points
+}
+
+// braceLeftPoints traces a "{" whose tip touches x and whose ends open
+// towards x+2*radius.
+func braceLeftPoints(x, y, h, radius float64) []Point {
+	cy := y + h/2
+	b := y + h
+	points := ellipseArc(x+2*radius, y+radius, radius, radius, 270, 180, 6)
+	points = append(points, ellipseArc(x, cy-radius, radius, radius, 0, 90, 6)...)
+	points = append(points, ellipseArc(x, cy+radius, radius, radius, 270, 360, 6)...)
+	points = append(points, ellipseArc(x+2*radius, b-radius, radius, radius, 180, 90, 6)...)
+	return points
+}
@@ -0,0 +1,365 @@
+package mermaid
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/bvolpato/mermaid-go-renderer/dagre"
+)
+
+// Padding between an isolated subgraph's border and its contents. The top
+// padding leaves room for the cluster label.
+const (
+	isolatedClusterPadX      = 30.0
+	isolatedClusterPadTop    = 34.0
+	isolatedClusterPadBottom = 15.0
+)
+
+// flowClusters indexes subgraphs for the dagre layout: which subgraph owns
+// each node, how subgraphs nest, and which ones are laid out on their own.
+//
+// Like mermaid, a subgraph with its own `direction` and no edges crossing
+// its border is isolated: it is laid out as a separate graph in that
+// direction and enters its parent as a single node. Other subgraphs are
+// dagre clusters and follow the diagram direction.
+type flowClusters struct {
+	byID     map[string]FlowSubgraph
+	owner    map[string]string
+	parent   map[string]string
+	isolated map[string]bool
+	anchors  map[string]string
+}
+
+func newFlowClusters(graph *Graph) flowClusters {
+	c := flowClusters{
+		byID:     map[string]FlowSubgraph{},
+		owner:    map[string]string{},
+		parent:   map[string]string{},
+		isolated: map[string]bool{},
+		anchors:  map[string]string{},
+	}
+	for _, sg := range graph.FlowSubgraphs {
+		c.byID[sg.ID] = sg
+		if sg.Parent != "" {
+			c.parent[sg.ID] = sg.Parent
+		}
+		// Nodes are listed by every enclosing subgraph; the innermost one,
+		// declared last, owns them.
+		for _, id := range sg.NodeIDs {
+			c.owner[id] = sg.ID
+		}
+	}
+	for _, sg := range graph.FlowSubgraphs {
+		if sg.Direction == "" {
+			continue
+		}
+		hasNodes := false
+		for _, v := range graph.NodeOrder {
+			if c.isDescendant(v, sg.ID) {
+				hasNodes = true
+				break
+			}
+		}
+		crosses := false
+		for _, e := range graph.Edges {
+			if c.isDescendant(e.From, sg.ID) != c.isDescendant(e.To, sg.ID) {
+				crosses = true
+				break
+			}
+		}
+		c.isolated[sg.ID] = hasNodes && !crosses
+	}
+	// Edges to a dagre cluster are routed to its first node and cut at the
+	// cluster border afterwards.
+	for _, sg := range graph.FlowSubgraphs {
+		if c.isolated[sg.ID] {
+			continue
+		}
+		for _, v := range graph.NodeOrder {
+			if c.isDescendant(v, sg.ID) {
+				c.anchors[sg.ID] = c.lift(v, c.scopeOf(sg.ID))
+				break
+			}
+		}
+	}
+	return c
+}
+
+func (c flowClusters) isCluster(id string) bool {
+	_, ok := c.byID[id]
+	return ok
+}
+
+// container returns the subgraph directly enclosing a node or subgraph.
+func (c flowClusters) container(v string) string {
+	if c.isCluster(v) {
+		return c.parent[v]
+	}
+	return c.owner[v]
+}
+
+func (c flowClusters) isDescendant(v, cluster string) bool {
+	for x := c.container(v); x != ""; x = c.parent[x] {
+		if x == cluster {
+			return true
+		}
+	}
+	return false
+}
+
+// scopeOf returns the nearest isolated subgraph enclosing v, or "" when v
+// belongs to the top-level layout.
+func (c flowClusters) scopeOf(v string) string {
+	x := c.container(v)
+	for x != "" && !c.isolated[x] {
+		x = c.parent[x]
+	}
+	return x
+}
+
+// lift returns v, or the isolated subgraph containing it, that is laid out
+// directly in scope.
+func (c flowClusters) lift(v, scope string) string {
+	for v != "" && c.scopeOf(v) != scope {
+		v = c.scopeOf(v)
+	}
+	return v
+}
+
+// endpoint returns the dagre node an edge endpoint attaches to.
+func (c flowClusters) endpoint(v string) string {
+	if anchor, ok := c.anchors[v]; ok {
+		return anchor
+	}
+	return v
+}
+
+// edgeEnds resolves an edge to the scope it is laid out in and the dagre
+// nodes it connects there.
+func (c flowClusters) edgeEnds(e Edge) (scope, from, to string) {
+	from, to = c.endpoint(e.From), c.endpoint(e.To)
+	scope = c.scopeOf(from)
+	if c.scopeOf(to) != scope {
+		scope = ""
+		from, to = c.lift(from, scope), c.lift(to, scope)
+	}
+	return scope, from, to
+}
+
+// dagrePlacement holds the laid-out dagre labels of nodes, subgraphs and
+// edges (keyed by edge index). W and H are the size of an isolated scope.
+type dagrePlacement struct {
+	nodes map[string]*dagre.NodeLabel
+	edges map[string]*dagre.EdgeLabel
+	W, H  float64
+}
+
+// dagreScopeBuilder lays out a diagram one scope at a time.
+type dagreScopeBuilder struct {
+	graph      *Graph
+	clusters   flowClusters
+	settings   dagre.GraphLabel
+	nodeLabel  func(v string) *dagre.NodeLabel
+	edgeLabel  func(e Edge) *dagre.EdgeLabel
+	labelWidth func(label string) float64
+}
+
+// layout lays out the members of one scope: the whole diagram when scope is
+// empty, otherwise an isolated subgraph. Isolated subgraphs inside the scope
+// are laid out first and placed here as single nodes.
+func (b dagreScopeBuilder) layout(scope string) dagrePlacement {
+	c := b.clusters
+	settings := b.settings
+	if scope != "" {
+		settings.RankDir = erRankDir(c.byID[scope].Direction)
+		settings.MarginX = 0
+		settings.MarginY = 0
+	}
+	dg := dagre.NewGraph()
+	dg.SetGraph(settings)
+
+	for _, v := range b.graph.NodeOrder {
+		if c.scopeOf(v) == scope {
+			dg.SetNode(v, b.nodeLabel(v))
+		}
+	}
+	inner := map[string]dagrePlacement{}
+	for _, sg := range b.graph.FlowSubgraphs {
+		if c.isolated[sg.ID] && c.scopeOf(sg.ID) == scope {
+			sub := b.layout(sg.ID)
+			inner[sg.ID] = sub
+			dg.SetNode(sg.ID, &dagre.NodeLabel{Width: sub.W, Height: sub.H})
+		}
+	}
+
+	edgeKeys := make([]dagre.Edge, 0, len(b.graph.Edges))
+	for i, e := range b.graph.Edges {
+		if e.From == "" || e.To == "" {
+			continue
+		}
+		edgeScope, from, to := c.edgeEnds(e)
+		if edgeScope != scope {
+			continue
+		}
+		key := dagre.Edge{V: from, W: to, Name: fmt.Sprintf("%d", i)}
+		edgeKeys = append(edgeKeys, key)
+		dg.SetEdge(key, b.edgeLabel(e))
+	}
+
+	// Register subgraphs (flowchart subgraphs, composite states)
+	for _, sg := range b.graph.FlowSubgraphs {
+		if c.isolated[sg.ID] || c.scopeOf(sg.ID) != scope {
+			continue
+		}
+		if b.isEmptySubgraph(sg) {
+			// dagre cannot size a compound node without children, so an
+			// empty subgraph is laid out as a node holding just its label.
+			dg.SetNode(sg.ID, &dagre.NodeLabel{
+				Width:  b.labelWidth(sg.Label) + 28,
+				Height: isolatedClusterPadTop + isolatedClusterPadBottom,
+			})
+			continue
+		}
+		dg.SetNode(sg.ID, &dagre.NodeLabel{Width: 0, Height: 0})
+		for _, child := range sg.NodeIDs {
+			if c.scopeOf(child) == scope {
+				dg.SetParent(child, sg.ID)
+			}
+		}
+	}
+	for _, sg := range b.graph.FlowSubgraphs {
+		if sg.Parent != "" && c.scopeOf(sg.ID) == scope && !c.isolated[sg.Parent] {
+			dg.SetParent(sg.ID, sg.Parent)
+		}
+	}
+
+	dagre.Layout(dg)
+
+	placed := dagrePlacement{nodes: map[string]*dagre.NodeLabel{}, edges: map[string]*dagre.EdgeLabel{}}
+	for _, v := range dg.Nodes() {
+		placed.nodes[v] = dg.Node(v)
+	}
+	for _, key := range edgeKeys {
+		if dl := dg.EdgeByKey(key); dl != nil {
+			placed.edges[key.Name] = dl
+		}
+	}
+	for id, sub := range inner {
+		dn := dg.Node(id)
+		sub.translate(dn.X-dn.Width/2, dn.Y-dn.Height/2)
+		for v, n := range sub.nodes {
+			placed.nodes[v] = n
+		}
+		for name, e := range sub.edges {
+			placed.edges[name] = e
+		}
+	}
+	if scope != "" {
+		b.fitScope(scope, &placed)
+	}
+	return placed
+}
+
+// isEmptySubgraph reports whether sg has neither nodes nor nested subgraphs.
+func (b dagreScopeBuilder) isEmptySubgraph(sg FlowSubgraph) bool {
+	if len(sg.NodeIDs) > 0 {
+		return false
+	}
+	for _, other := range b.graph.FlowSubgraphs {
+		if other.Parent == sg.ID {
+			return false
+		}
+	}
+	return true
+}
+
+// fitScope moves an isolated scope's contents inside its cluster padding
+// and records the resulting cluster size.
+func (b dagreScopeBuilder) fitScope(scope string, placed *dagrePlacement) {
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for _, n := range placed.nodes {
+		minX = min(minX, n.X-n.Width/2)
+		minY = min(minY, n.Y-n.Height/2)
+		maxX = max(maxX, n.X+n.Width/2)
+		maxY = max(maxY, n.Y+n.Height/2)
+	}
+	for _, e := range placed.edges {
+		for _, p := range e.Points {
+			minX, minY = min(minX, p.X), min(minY, p.Y)
+			maxX, maxY = max(maxX, p.X), max(maxY, p.Y)
+		}
+	}
+	if math.IsInf(minX, 1) {
+		minX, minY, maxX, maxY = 0, 0, 0, 0
+	}
+	placed.W = (maxX - minX) + isolatedClusterPadX*2
+	placed.H = (maxY - minY) + isolatedClusterPadTop + isolatedClusterPadBottom
+	offsetX := isolatedClusterPadX - minX
+	if labelW := b.labelWidth(b.clusters.byID[scope].Label) + 28; placed.W < labelW {
+		offsetX += (labelW - placed.W) / 2
+		placed.W = labelW
+	}
+	placed.translate(offsetX, isolatedClusterPadTop-minY)
+}
+
+func (p dagrePlacement) translate(dx, dy float64) {
+	for v, n := range p.nodes {
+		moved := *n
+		moved.X += dx
+		moved.Y += dy
+		p.nodes[v] = &moved
+	}
+	for name, e := range p.edges {
+		moved := *e
+		moved.X += dx
+		moved.Y += dy
+		moved.Points = make([]dagre.Point, len(e.Points))
+		for i, pt := range e.Points {
+			moved.Points[i] = dagre.Point{X: pt.X + dx, Y: pt.Y + dy}
+		}
+		p.edges[name] = &moved
+	}
+}
+
+// cutPathAtBox trims an edge path at the border of box: from its first
+// entry when atEnd is set, otherwise from its last exit. Paths that start
+// inside the box, or never enter it, are returned unchanged.
+func cutPathAtBox(points []dagre.Point, box NodeLayout, atEnd bool) []dagre.Point {
+	if !atEnd {
+		reversed := reversePoints(points)
+		return reversePoints(cutPathAtBox(reversed, box, true))
+	}
+	inside := func(p dagre.Point) bool {
+		return p.X >= box.X && p.X <= box.X+box.W && p.Y >= box.Y && p.Y <= box.Y+box.H
+	}
+	for i, p := range points {
+		if !inside(p) {
+			continue
+		}
+		if i == 0 {
+			return points
+		}
+		// Bisect the segment that crosses the border.
+		out, in := points[i-1], p
+		for range 32 {
+			mid := dagre.Point{X: (out.X + in.X) / 2, Y: (out.Y + in.Y) / 2}
+			if inside(mid) {
+				in = mid
+			} else {
+				out = mid
+			}
+		}
+		cut := append([]dagre.Point{}, points[:i]...)
+		return append(cut, in)
+	}
+	return points
+}
+
+func reversePoints(points []dagre.Point) []dagre.Point {
+	out := make([]dagre.Point, len(points))
+	for i, p := range points {
+		out[len(points)-1-i] = p
+	}
+	return out
+}
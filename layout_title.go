@@ -0,0 +1,92 @@
+package mermaid
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+var svgMaxWidthPattern = regexp.MustCompile(`max-width:\s*[0-9.]+px`)
+
+// Title metrics from mermaid's titleTopMargin and the 18px *TitleText CSS.
+const (
+	diagramTitleTopMargin = 25.0
+	diagramTitleFontSize  = 18.0
+	diagramTitlePadding   = 8.0
+)
+
+// diagramTitleClass returns the CSS class mermaid gives the title of a
+// diagram kind. Kinds that draw their own title (pie, gantt, journey and
+// friends) have none and are left to their own layout.
+func diagramTitleClass(kind DiagramKind) string {
+	switch kind {
+	case DiagramFlowchart:
+		return "flowchartTitleText"
+	case DiagramClass:
+		return "classTitleText"
+	case DiagramState:
+		return "statediagramTitleText"
+	case DiagramER:
+		return "erDiagramTitleText"
+	case DiagramRequirement:
+		return "requirementDiagramTitleText"
+	case DiagramGitGraph:
+		return "gitTitleText"
+	case DiagramSequence:
+		return "sequenceTitleText"
+	default:
+		return ""
+	}
+}
+
+// addDiagramTitle places the graph title above the laid-out diagram and
+// grows the viewBox upward, and sideways when the title is wider than the
+// diagram, to make room for it.
+func addDiagramTitle(layout *Layout, graph *Graph, theme Theme, config LayoutConfig) {
+	title := strings.TrimSpace(graph.Title)
+	class := diagramTitleClass(layout.Kind)
+	if title == "" || class == "" {
+		return
+	}
+	if layout.ViewBoxWidth <= 0 || layout.ViewBoxHeight <= 0 {
+		layout.ViewBoxX, layout.ViewBoxY = 0, 0
+		layout.ViewBoxWidth, layout.ViewBoxHeight = max(1, layout.Width), max(1, layout.Height)
+	}
+	scaleX := max(1, layout.Width) / layout.ViewBoxWidth
+	scaleY := max(1, layout.Height) / layout.ViewBoxHeight
+
+	layout.Title = title
+	layout.TitleClass = class
+	layout.TitleX = layout.ViewBoxX + layout.ViewBoxWidth/2
+	layout.TitleY = layout.ViewBoxY + diagramTitlePadding - diagramTitleTopMargin
+
+	top := layout.TitleY - diagramTitleFontSize - diagramTitlePadding
+	if top < layout.ViewBoxY {
+		layout.ViewBoxHeight += layout.ViewBoxY - top
+		layout.ViewBoxY = top
+	}
+	titleW := measureTextWidthWithFontSize(title, diagramTitleFontSize, config.FastTextMetrics, theme.FontFamily) + diagramTitlePadding*2
+	if titleW > layout.ViewBoxWidth {
+		layout.ViewBoxX = layout.TitleX - titleW/2
+		layout.ViewBoxWidth = titleW
+	}
+	layout.Width = layout.ViewBoxWidth * scaleX
+	layout.Height = layout.ViewBoxHeight * scaleY
+	layout.SVGStyle = svgMaxWidthPattern.ReplaceAllString(layout.SVGStyle, "max-width: "+formatFloat(layout.ViewBoxWidth)+"px")
+}
+
+// insertDiagramTitle writes the layout title as the last element of the
+// SVG so it paints above the diagram.
+func insertDiagramTitle(svg string, layout Layout, theme Theme) string {
+	if layout.Title == "" {
+		return svg
+	}
+	end := strings.LastIndex(svg, "</svg>")
+	if end < 0 {
+		return svg
+	}
+	text := `<text text-anchor="middle" x="` + formatFloat(layout.TitleX) + `" y="` + formatFloat(layout.TitleY) +
+		`" class="` + layout.TitleClass + `" font-size="` + formatFloat(diagramTitleFontSize) +
+		`" fill="` + html.EscapeString(theme.PrimaryTextColor) + `">` + html.EscapeString(layout.Title) + `</text>`
+	return svg[:end] + text + svg[end:]
+}
@@ -1,280 +1,337 @@
 package mermaid
 
 type Point struct {
-	X float64
-	Y float64
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
 }
 
 type NodeLayout struct {
-	ID          string
-	Label       string
-	Shape       NodeShape
-	X           float64
-	Y           float64
-	W           float64
-	H           float64
-	Fill        string
-	Stroke      string
-	StrokeWidth float64
+	ID          string    `json:"id,omitempty"`
+	Label       string    `json:"label,omitempty"`
+	Shape       NodeShape `json:"shape,omitempty"`
+	X           float64   `json:"x,omitempty"`
+	Y           float64   `json:"y,omitempty"`
+	W           float64   `json:"w,omitempty"`
+	H           float64   `json:"h,omitempty"`
+	Fill        string    `json:"fill,omitempty"`
+	Stroke      string    `json:"stroke,omitempty"`
+	StrokeWidth float64   `json:"stroke_width,omitempty"`
+
+	Icon        string  `json:"icon,omitempty"`
+	Img         string  `json:"img,omitempty"`
+	LabelPos    string  `json:"label_pos,omitempty"`
+	AssetWidth  float64 `json:"asset_width,omitempty"`
+	AssetHeight float64 `json:"asset_height,omitempty"`
+
+	// LabelLines holds the styled, wrapped label of flowchart nodes; Label
+	// is its plain text.
+	LabelLines [][]TextRun `json:"label_lines,omitempty"`
 }
 
 type EdgeLayout struct {
-	From        string
-	To          string
-	Label       string
-	D           string
-	X1          float64
-	Y1          float64
-	X2          float64
-	Y2          float64
-	Style       EdgeStyle
-	ArrowStart  bool
-	ArrowEnd    bool
-	MarkerStart string
-	MarkerEnd   string
+	From        string    `json:"from,omitempty"`
+	To          string    `json:"to,omitempty"`
+	Label       string    `json:"label,omitempty"`
+	D           string    `json:"d,omitempty"`
+	X1          float64   `json:"x1,omitempty"`
+	Y1          float64   `json:"y1,omitempty"`
+	X2          float64   `json:"x2,omitempty"`
+	Y2          float64   `json:"y2,omitempty"`
+	Style       EdgeStyle `json:"style,omitempty"`
+	ArrowStart  bool      `json:"arrow_start,omitempty"`
+	ArrowEnd    bool      `json:"arrow_end,omitempty"`
+	MarkerStart string    `json:"marker_start,omitempty"`
+	MarkerEnd   string    `json:"marker_end,omitempty"`
+	ID          string    `json:"id,omitempty"`
+	Curve       string    `json:"curve,omitempty"`
+	// Points are the waypoints the edge was routed through, from its start
+	// to its end, where the layout has them.
+	Points []Point `json:"points,omitempty"`
+	// LabelLines holds the styled, wrapped label of flowchart edges.
+	LabelLines [][]TextRun `json:"label_lines,omitempty"`
 }
 
 type LayoutRect struct {
-	ID              string
-	Class           string
-	X               float64
-	Y               float64
-	W               float64
-	H               float64
-	RX              float64
-	RY              float64
-	Fill            string
-	FillOpacity     float64
-	Stroke          string
-	StrokeOpacity   float64
-	StrokeWidth     float64
-	Opacity         float64
-	Transform       string
-	TransformOrigin string
-	StrokeDasharray string
-	Dashed          bool
+	ID              string  `json:"id,omitempty"`
+	Class           string  `json:"class,omitempty"`
+	X               float64 `json:"x,omitempty"`
+	Y               float64 `json:"y,omitempty"`
+	W               float64 `json:"w,omitempty"`
+	H               float64 `json:"h,omitempty"`
+	RX              float64 `json:"rx,omitempty"`
+	RY              float64 `json:"ry,omitempty"`
+	Fill            string  `json:"fill,omitempty"`
+	FillOpacity     float64 `json:"fill_opacity,omitempty"`
+	Stroke          string  `json:"stroke,omitempty"`
+	StrokeOpacity   float64 `json:"stroke_opacity,omitempty"`
+	StrokeWidth     float64 `json:"stroke_width,omitempty"`
+	Opacity         float64 `json:"opacity,omitempty"`
+	Transform       string  `json:"transform,omitempty"`
+	TransformOrigin string  `json:"transform_origin,omitempty"`
+	StrokeDasharray string  `json:"stroke_dasharray,omitempty"`
+	Dashed          bool    `json:"dashed,omitempty"`
 }
 
 type LayoutLine struct {
-	ID            string
-	Class         string
-	D             string
-	X1            float64
-	Y1            float64
-	X2            float64
-	Y2            float64
-	Stroke        string
-	StrokeOpacity float64
-	StrokeWidth   float64
-	Opacity       float64
-	LineCap       string
-	LineJoin      string
-	DashArray     string
-	Transform     string
-	Dashed        bool
-	ArrowStart    bool
-	ArrowEnd      bool
-	MarkerStart   string
-	MarkerEnd     string
+	ID            string  `json:"id,omitempty"`
+	Class         string  `json:"class,omitempty"`
+	D             string  `json:"d,omitempty"`
+	X1            float64 `json:"x1,omitempty"`
+	Y1            float64 `json:"y1,omitempty"`
+	X2            float64 `json:"x2,omitempty"`
+	Y2            float64 `json:"y2,omitempty"`
+	Stroke        string  `json:"stroke,omitempty"`
+	StrokeOpacity float64 `json:"stroke_opacity,omitempty"`
+	StrokeWidth   float64 `json:"stroke_width,omitempty"`
+	Opacity       float64 `json:"opacity,omitempty"`
+	LineCap       string  `json:"line_cap,omitempty"`
+	LineJoin      string  `json:"line_join,omitempty"`
+	DashArray     string  `json:"dash_array,omitempty"`
+	Transform     string  `json:"transform,omitempty"`
+	Dashed        bool    `json:"dashed,omitempty"`
+	ArrowStart    bool    `json:"arrow_start,omitempty"`
+	ArrowEnd      bool    `json:"arrow_end,omitempty"`
+	MarkerStart   string  `json:"marker_start,omitempty"`
+	MarkerEnd     string  `json:"marker_end,omitempty"`
 }
 
 type LayoutCircle struct {
-	ID            string
-	Class         string
-	Title         string
-	CX            float64
-	CY            float64
-	R             float64
-	Fill          string
-	FillOpacity   float64
-	Stroke        string
-	StrokeOpacity float64
-	StrokeWidth   float64
-	Opacity       float64
-	Transform     string
+	ID            string  `json:"id,omitempty"`
+	Class         string  `json:"class,omitempty"`
+	Title         string  `json:"title,omitempty"`
+	CX            float64 `json:"cx,omitempty"`
+	CY            float64 `json:"cy,omitempty"`
+	R             float64 `json:"r,omitempty"`
+	Fill          string  `json:"fill,omitempty"`
+	FillOpacity   float64 `json:"fill_opacity,omitempty"`
+	Stroke        string  `json:"stroke,omitempty"`
+	StrokeOpacity float64 `json:"stroke_opacity,omitempty"`
+	StrokeWidth   float64 `json:"stroke_width,omitempty"`
+	Opacity       float64 `json:"opacity,omitempty"`
+	Transform     string  `json:"transform,omitempty"`
 }
 
 type LayoutEllipse struct {
-	ID            string
-	Class         string
-	CX            float64
-	CY            float64
-	RX            float64
-	RY            float64
-	Fill          string
-	FillOpacity   float64
-	Stroke        string
-	StrokeOpacity float64
-	StrokeWidth   float64
-	Opacity       float64
-	Transform     string
+	ID            string  `json:"id,omitempty"`
+	Class         string  `json:"class,omitempty"`
+	CX            float64 `json:"cx,omitempty"`
+	CY            float64 `json:"cy,omitempty"`
+	RX            float64 `json:"rx,omitempty"`
+	RY            float64 `json:"ry,omitempty"`
+	Fill          string  `json:"fill,omitempty"`
+	FillOpacity   float64 `json:"fill_opacity,omitempty"`
+	Stroke        string  `json:"stroke,omitempty"`
+	StrokeOpacity float64 `json:"stroke_opacity,omitempty"`
+	StrokeWidth   float64 `json:"stroke_width,omitempty"`
+	Opacity       float64 `json:"opacity,omitempty"`
+	Transform     string  `json:"transform,omitempty"`
+}
+
+type LayoutImage struct {
+	ID       string  `json:"id,omitempty"`
+	Class    string  `json:"class,omitempty"`
+	X        float64 `json:"x,omitempty"`
+	Y        float64 `json:"y,omitempty"`
+	W        float64 `json:"w,omitempty"`
+	H        float64 `json:"h,omitempty"`
+	Href     string  `json:"href,omitempty"`
+	Preserve string  `json:"preserve,omitempty"`
 }
 
 type LayoutPolygon struct {
-	Class         string
-	Points        []Point
-	Fill          string
-	FillOpacity   float64
-	Stroke        string
-	StrokeOpacity float64
-	StrokeWidth   float64
-	Opacity       float64
-	Transform     string
+	Class         string  `json:"class,omitempty"`
+	Points        []Point `json:"points,omitempty"`
+	Fill          string  `json:"fill,omitempty"`
+	FillOpacity   float64 `json:"fill_opacity,omitempty"`
+	Stroke        string  `json:"stroke,omitempty"`
+	StrokeOpacity float64 `json:"stroke_opacity,omitempty"`
+	StrokeWidth   float64 `json:"stroke_width,omitempty"`
+	Opacity       float64 `json:"opacity,omitempty"`
+	Transform     string  `json:"transform,omitempty"`
 }
 
 type LayoutPath struct {
-	ID            string
-	Class         string
-	D             string
-	Fill          string
-	FillOpacity   float64
-	Stroke        string
-	StrokeOpacity float64
-	StrokeWidth   float64
-	Opacity       float64
-	Transform     string
-	DashArray     string
-	LineCap       string
-	LineJoin      string
-	MarkerStart   string
-	MarkerEnd     string
+	ID            string  `json:"id,omitempty"`
+	Class         string  `json:"class,omitempty"`
+	D             string  `json:"d,omitempty"`
+	Fill          string  `json:"fill,omitempty"`
+	FillOpacity   float64 `json:"fill_opacity,omitempty"`
+	Stroke        string  `json:"stroke,omitempty"`
+	StrokeOpacity float64 `json:"stroke_opacity,omitempty"`
+	StrokeWidth   float64 `json:"stroke_width,omitempty"`
+	Opacity       float64 `json:"opacity,omitempty"`
+	Transform     string  `json:"transform,omitempty"`
+	DashArray     string  `json:"dash_array,omitempty"`
+	LineCap       string  `json:"line_cap,omitempty"`
+	LineJoin      string  `json:"line_join,omitempty"`
+	MarkerStart   string  `json:"marker_start,omitempty"`
+	MarkerEnd     string  `json:"marker_end,omitempty"`
+	// Style holds extra inline CSS, such as classDef declarations on edges.
+	Style string `json:"style,omitempty"`
 }
 
 type LayoutText struct {
-	ID               string
-	Class            string
-	X                float64
-	Y                float64
-	BoxX             float64
-	BoxY             float64
-	BoxW             float64
-	BoxH             float64
-	Value            string
-	Anchor           string
-	Size             float64
-	Weight           string
-	Color            string
-	Opacity          float64
-	Transform        string
-	DominantBaseline string
-	FontFamily       string
+	ID               string  `json:"id,omitempty"`
+	Class            string  `json:"class,omitempty"`
+	X                float64 `json:"x,omitempty"`
+	Y                float64 `json:"y,omitempty"`
+	BoxX             float64 `json:"box_x,omitempty"`
+	BoxY             float64 `json:"box_y,omitempty"`
+	BoxW             float64 `json:"box_w,omitempty"`
+	BoxH             float64 `json:"box_h,omitempty"`
+	Value            string  `json:"value,omitempty"`
+	Anchor           string  `json:"anchor,omitempty"`
+	Size             float64 `json:"size,omitempty"`
+	Weight           string  `json:"weight,omitempty"`
+	Color            string  `json:"color,omitempty"`
+	Opacity          float64 `json:"opacity,omitempty"`
+	Transform        string  `json:"transform,omitempty"`
+	DominantBaseline string  `json:"dominant_baseline,omitempty"`
+	FontFamily       string  `json:"font_family,omitempty"`
+	Href             string  `json:"href,omitempty"`
+	// Lines holds styled, wrapped label lines. When set it takes precedence
+	// over Value, which keeps the plain text.
+	Lines [][]TextRun `json:"lines,omitempty"`
 }
 
 type ArchitectureGroupLayout struct {
-	ID    string
-	Label string
-	Icon  string
-	X     float64
-	Y     float64
-	W     float64
-	H     float64
+	ID    string  `json:"id,omitempty"`
+	Label string  `json:"label,omitempty"`
+	Icon  string  `json:"icon,omitempty"`
+	X     float64 `json:"x,omitempty"`
+	Y     float64 `json:"y,omitempty"`
+	W     float64 `json:"w,omitempty"`
+	H     float64 `json:"h,omitempty"`
 }
 
 type ArchitectureServiceLayout struct {
-	ID      string
-	Label   string
-	Icon    string
-	GroupID string
-	X       float64
-	Y       float64
-	W       float64
-	H       float64
+	ID      string  `json:"id,omitempty"`
+	Label   string  `json:"label,omitempty"`
+	Icon    string  `json:"icon,omitempty"`
+	GroupID string  `json:"group_id,omitempty"`
+	X       float64 `json:"x,omitempty"`
+	Y       float64 `json:"y,omitempty"`
+	W       float64 `json:"w,omitempty"`
+	H       float64 `json:"h,omitempty"`
 }
 
 type SankeyNodeLayout struct {
-	ID    string
-	Value float64
-	X0    float64
-	Y0    float64
-	X1    float64
-	Y1    float64
-	Color string
+	ID    string  `json:"id,omitempty"`
+	Label string  `json:"label,omitempty"`
+	Value float64 `json:"value,omitempty"`
+	X0    float64 `json:"x0,omitempty"`
+	Y0    float64 `json:"y0,omitempty"`
+	X1    float64 `json:"x1,omitempty"`
+	Y1    float64 `json:"y1,omitempty"`
+	Color string  `json:"color,omitempty"`
 }
 
 type SankeyLinkLayout struct {
-	SourceID    string
-	TargetID    string
-	Value       float64
-	Width       float64
-	X0          float64
-	Y0          float64
-	D           string
-	X1          float64
-	Y1          float64
-	Path        string
-	SourceColor string
-	TargetColor string
+	SourceID    string  `json:"source_id,omitempty"`
+	TargetID    string  `json:"target_id,omitempty"`
+	Value       float64 `json:"value,omitempty"`
+	Width       float64 `json:"width,omitempty"`
+	X0          float64 `json:"x0,omitempty"`
+	Y0          float64 `json:"y0,omitempty"`
+	D           string  `json:"d,omitempty"`
+	X1          float64 `json:"x1,omitempty"`
+	Y1          float64 `json:"y1,omitempty"`
+	Path        string  `json:"path,omitempty"`
+	Stroke      string  `json:"stroke,omitempty"`
+	SourceColor string  `json:"source_color,omitempty"`
+	TargetColor string  `json:"target_color,omitempty"`
 }
 
 type RadarAxisLayout struct {
-	Label string
-	LineX float64
-	LineY float64
-	TextX float64
-	TextY float64
+	Label string  `json:"label,omitempty"`
+	LineX float64 `json:"line_x,omitempty"`
+	LineY float64 `json:"line_y,omitempty"`
+	TextX float64 `json:"text_x,omitempty"`
+	TextY float64 `json:"text_y,omitempty"`
 }
 
 type RadarCurveLayout struct {
-	Label   string
-	Class   string
-	Path    string
-	Polygon bool
+	Label   string `json:"label,omitempty"`
+	Class   string `json:"class,omitempty"`
+	Path    string `json:"path,omitempty"`
+	Polygon bool   `json:"polygon,omitempty"`
+	// Style holds inline CSS from the curve's classDef.
+	Style string `json:"style,omitempty"`
 }
 
 type Layout struct {
-	Kind   DiagramKind
-	Width  float64
-	Height float64
-
-	ViewBoxX      float64
-	ViewBoxY      float64
-	ViewBoxWidth  float64
-	ViewBoxHeight float64
-	SVGWidth      string
-	SVGHeight     string
-	SVGStyle      string
-
-	SequenceParticipants      []string
-	SequenceMessages          []SequenceMessage
-	SequenceEvents            []SequenceEvent
-	SequenceParticipantLabels map[string]string
-
-	ZenUMLTitle        string
-	ZenUMLParticipants []string
-	ZenUMLMessages     []SequenceMessage
-	ZenUMLAltBlocks    []ZenUMLAltBlock
-
-	ArchitectureGroups   []ArchitectureGroupLayout
-	ArchitectureServices []ArchitectureServiceLayout
-
-	SankeyNodes []SankeyNodeLayout
-	SankeyLinks []SankeyLinkLayout
-
-	MindmapRootID string
-	MindmapNodes  []MindmapNode
-
-	RadarTitle            string
-	RadarAxes             []RadarAxisLayout
-	RadarCurves           []RadarCurveLayout
-	RadarLegend           []string
-	RadarShowLegend       bool
-	RadarTicks            int
-	RadarGraticule        string
-	RadarGraticuleRadii   []float64
-	RadarCenterX          float64
-	RadarCenterY          float64
-	RadarLegendX          float64
-	RadarLegendY          float64
-	RadarLegendLineHeight float64
-
-	Nodes []NodeLayout
-	Edges []EdgeLayout
-
-	Rects    []LayoutRect
-	Lines    []LayoutLine
-	Circles  []LayoutCircle
-	Ellipses []LayoutEllipse
-	Polygons []LayoutPolygon
-	Paths    []LayoutPath
-	Texts    []LayoutText
+	Kind   DiagramKind `json:"kind"`
+	Width  float64     `json:"width"`
+	Height float64     `json:"height"`
+
+	ViewBoxX      float64 `json:"view_box_x,omitempty"`
+	ViewBoxY      float64 `json:"view_box_y,omitempty"`
+	ViewBoxWidth  float64 `json:"view_box_width,omitempty"`
+	ViewBoxHeight float64 `json:"view_box_height,omitempty"`
+	SVGWidth      string  `json:"svg_width,omitempty"`
+	SVGHeight     string  `json:"svg_height,omitempty"`
+	SVGStyle      string  `json:"svg_style,omitempty"`
+
+	SequenceParticipants      []string          `json:"sequence_participants,omitempty"`
+	SequenceMessages          []SequenceMessage `json:"sequence_messages,omitempty"`
+	SequenceEvents            []SequenceEvent   `json:"sequence_events,omitempty"`
+	SequenceParticipantLabels map[string]string `json:"sequence_participant_labels,omitempty"`
+
+	ZenUMLTitle        string            `json:"zenuml_title,omitempty"`
+	ZenUMLParticipants []string          `json:"zenuml_participants,omitempty"`
+	ZenUMLMessages     []SequenceMessage `json:"zenuml_messages,omitempty"`
+	ZenUMLAltBlocks    []ZenUMLAltBlock  `json:"zenuml_alt_blocks,omitempty"`
+
+	ArchitectureGroups   []ArchitectureGroupLayout   `json:"architecture_groups,omitempty"`
+	ArchitectureServices []ArchitectureServiceLayout `json:"architecture_services,omitempty"`
+
+	SankeyNodes []SankeyNodeLayout `json:"sankey_nodes,omitempty"`
+	SankeyLinks []SankeyLinkLayout `json:"sankey_links,omitempty"`
+
+	MindmapRootID string        `json:"mindmap_root_id,omitempty"`
+	MindmapNodes  []MindmapNode `json:"mindmap_nodes,omitempty"`
+
+	RadarTitle            string             `json:"radar_title,omitempty"`
+	RadarAxes             []RadarAxisLayout  `json:"radar_axes,omitempty"`
+	RadarCurves           []RadarCurveLayout `json:"radar_curves,omitempty"`
+	RadarLegend           []string           `json:"radar_legend,omitempty"`
+	RadarShowLegend       bool               `json:"radar_show_legend,omitempty"`
+	RadarTicks            int                `json:"radar_ticks,omitempty"`
+	RadarGraticule        string             `json:"radar_graticule,omitempty"`
+	RadarGraticuleRadii   []float64          `json:"radar_graticule_radii,omitempty"`
+	RadarCenterX          float64            `json:"radar_center_x,omitempty"`
+	RadarCenterY          float64            `json:"radar_center_y,omitempty"`
+	RadarLegendX          float64            `json:"radar_legend_x,omitempty"`
+	RadarLegendY          float64            `json:"radar_legend_y,omitempty"`
+	RadarLegendLineHeight float64            `json:"radar_legend_line_height,omitempty"`
+	RadarTitleY           float64            `json:"radar_title_y,omitempty"`
+	RadarCurveColors      []string           `json:"radar_curve_colors,omitempty"`
+	RadarCurveOpacity     float64            `json:"radar_curve_opacity,omitempty"`
+
+	Nodes []NodeLayout `json:"nodes,omitempty"`
+	Edges []EdgeLayout `json:"edges,omitempty"`
+
+	Rects    []LayoutRect    `json:"rects,omitempty"`
+	Lines    []LayoutLine    `json:"lines,omitempty"`
+	Circles  []LayoutCircle  `json:"circles,omitempty"`
+	Ellipses []LayoutEllipse `json:"ellipses,omitempty"`
+	Images   []LayoutImage   `json:"images,omitempty"`
+	Polygons []LayoutPolygon `json:"polygons,omitempty"`
+	Paths    []LayoutPath    `json:"paths,omitempty"`
+	Texts    []LayoutText    `json:"texts,omitempty"`
+
+	// SVGLabels records that labels must be rendered as SVG text rather
+	// than foreignObject HTML.
+	SVGLabels bool `json:"svg_labels,omitempty"`
+
+	// AccTitle and AccDescr are written as the SVG <title> and <desc>.
+	AccTitle string `json:"acc_title,omitempty"`
+	AccDescr string `json:"acc_descr,omitempty"`
+
+	// Title is drawn centred above the diagram at TitleX, TitleY with the
+	// CSS class TitleClass.
+	Title      string  `json:"title,omitempty"`
+	TitleClass string  `json:"title_class,omitempty"`
+	TitleX     float64 `json:"title_x,omitempty"`
+	TitleY     float64 `json:"title_y,omitempty"`
 }
@@ -0,0 +1,362 @@
+package mermaid
+
+import (
+	"html"
+	"strings"
+)
+
+// svgMarker is an SVG <marker>: the shapes drawn at a line end and the
+// point of them placed on it. The SVG writer emits it in <defs> and the
+// layout painter draws the same shapes, so both outputs share one geometry.
+type svgMarker struct {
+	ID    string
+	Class string
+	// ViewBox is minX, minY, width and height; a zero width writes none and
+	// leaves the shapes unscaled.
+	ViewBox       [4]float64
+	RefX, RefY    float64
+	Width, Height float64
+	// Units is the markerUnits value; empty keeps the strokeWidth default.
+	Units  string
+	Orient string
+	// ReverseAtStart turns the marker around where it starts a line when
+	// painted. Class relation ends are drawn for lines that run into the
+	// class, and the layout starts those lines at it.
+	ReverseAtStart bool
+	// Fill and Stroke are the paint the diagram's CSS gives the marker,
+	// which shapes without their own inherit. Empty is the color of the line
+	// the marker ends.
+	Fill, Stroke string
+	Shapes       []markerShape
+}
+
+// markerShape is one element of a marker, in marker coordinates: a path, a
+// circle, or a polygon or line through Points.
+type markerShape struct {
+	Tag       string
+	D         string
+	CX, CY, R float64
+	Points    [][2]float64
+	Class     string
+	Fill      string
+	Stroke    string
+	Style     string
+}
+
+func markerPath(d, style string) markerShape {
+	return markerShape{Tag: "path", D: d, Class: "arrowMarkerPath", Style: style}
+}
+
+func markerCircle(cx, cy, r float64, style string) markerShape {
+	return markerShape{Tag: "circle", CX: cx, CY: cy, R: r, Class: "arrowMarkerPath", Style: style}
+}
+
+// pathData returns the shape as SVG path data for the painter.
+func (s markerShape) pathData() string {
+	switch s.Tag {
+	case "circle":
+		return "M" + formatFloat(s.CX-s.R) + "," + formatFloat(s.CY) +
+			" A" + formatFloat(s.R) + "," + formatFloat(s.R) + " 0 1 0 " + formatFloat(s.CX+s.R) + "," + formatFloat(s.CY) +
+			" A" + formatFloat(s.R) + "," + formatFloat(s.R) + " 0 1 0 " + formatFloat(s.CX-s.R) + "," + formatFloat(s.CY) + " Z"
+	case "polygon", "line":
+		var d strings.Builder
+		for i, point := range s.Points {
+			if i == 0 {
+				d.WriteString("M")
+			} else {
+				d.WriteString(" L")
+			}
+			d.WriteString(formatFloat(point[0]) + "," + formatFloat(point[1]))
+		}
+		if s.Tag == "polygon" {
+			d.WriteString(" Z")
+		}
+		return d.String()
+	}
+	return s.D
+}
+
+// writeSVGMarker writes marker as a <marker> element.
+func writeSVGMarker(b *strings.Builder, marker svgMarker) {
+	b.WriteString(`<marker id="` + html.EscapeString(marker.ID) + `"`)
+	if marker.Class != "" {
+		b.WriteString(` class="` + html.EscapeString(marker.Class) + `"`)
+	}
+	if marker.ViewBox[2] > 0 {
+		b.WriteString(` viewBox="` + formatFloat(marker.ViewBox[0]) + ` ` + formatFloat(marker.ViewBox[1]) + ` ` +
+			formatFloat(marker.ViewBox[2]) + ` ` + formatFloat(marker.ViewBox[3]) + `"`)
+	}
+	b.WriteString(` refX="` + formatFloat(marker.RefX) + `" refY="` + formatFloat(marker.RefY) + `"`)
+	if marker.Units != "" {
+		b.WriteString(` markerUnits="` + marker.Units + `"`)
+	}
+	b.WriteString(` markerWidth="` + formatFloat(marker.Width) + `" markerHeight="` + formatFloat(marker.Height) + `"`)
+	b.WriteString(` orient="` + defaultColor(marker.Orient, "auto") + `">`)
+	for _, shape := range marker.Shapes {
+		b.WriteString(`<` + shape.Tag)
+		switch shape.Tag {
+		case "circle":
+			b.WriteString(` cx="` + formatFloat(shape.CX) + `" cy="` + formatFloat(shape.CY) + `" r="` + formatFloat(shape.R) + `"`)
+		case "polygon":
+			points := make([]string, 0, len(shape.Points))
+			for _, point := range shape.Points {
+				points = append(points, formatFloat(point[0])+","+formatFloat(point[1]))
+			}
+			b.WriteString(` points="` + strings.Join(points, " ") + `"`)
+		case "line":
+			b.WriteString(` x1="` + formatFloat(shape.Points[0][0]) + `" y1="` + formatFloat(shape.Points[0][1]) +
+				`" x2="` + formatFloat(shape.Points[1][0]) + `" y2="` + formatFloat(shape.Points[1][1]) + `"`)
+		default:
+			b.WriteString(` d="` + html.EscapeString(shape.D) + `"`)
+		}
+		if shape.Class != "" {
+			b.WriteString(` class="` + shape.Class + `"`)
+		}
+		if shape.Fill != "" {
+			b.WriteString(` fill="` + html.EscapeString(shape.Fill) + `"`)
+		}
+		if shape.Stroke != "" {
+			b.WriteString(` stroke="` + html.EscapeString(shape.Stroke) + `"`)
+		}
+		if shape.Style != "" {
+			b.WriteString(` style="` + html.EscapeString(shape.Style) + `"`)
+		}
+		b.WriteString(`/>`)
+	}
+	b.WriteString(`</marker>`)
+}
+
+// edgeMarkers are the point, circle and cross ends Mermaid's flowchart and
+// block renderers share, sized for the diagram.
+func edgeMarkers(prefix, class string, pointSize, pointRefX float64) []svgMarker {
+	solid := "stroke-width: 1; stroke-dasharray: 1, 0;"
+	cross := "stroke-width: 2; stroke-dasharray: 1, 0;"
+	crossPath := "M 1,1 l 9,9 M 10,1 l -9,9"
+	return []svgMarker{
+		{ID: prefix + "-pointEnd", Class: "marker " + class, ViewBox: [4]float64{0, 0, 10, 10}, RefX: pointRefX, RefY: 5, Units: "userSpaceOnUse", Width: pointSize, Height: pointSize,
+			Shapes: []markerShape{markerPath("M 0 0 L 10 5 L 0 10 z", solid)}},
+		{ID: prefix + "-pointStart", Class: "marker " + class, ViewBox: [4]float64{0, 0, 10, 10}, RefX: 4.5, RefY: 5, Units: "userSpaceOnUse", Width: pointSize, Height: pointSize,
+			Shapes: []markerShape{markerPath("M 0 5 L 10 10 L 10 0 z", solid)}},
+		{ID: prefix + "-circleEnd", Class: "marker " + class, ViewBox: [4]float64{0, 0, 10, 10}, RefX: 11, RefY: 5, Units: "userSpaceOnUse", Width: 11, Height: 11,
+			Shapes: []markerShape{markerCircle(5, 5, 5, solid)}},
+		{ID: prefix + "-circleStart", Class: "marker " + class, ViewBox: [4]float64{0, 0, 10, 10}, RefX: -1, RefY: 5, Units: "userSpaceOnUse", Width: 11, Height: 11,
+			Shapes: []markerShape{markerCircle(5, 5, 5, solid)}},
+		{ID: prefix + "-crossEnd", Class: "marker cross " + class, ViewBox: [4]float64{0, 0, 11, 11}, RefX: 12, RefY: 5.2, Units: "userSpaceOnUse", Width: 11, Height: 11,
+			Shapes: []markerShape{markerPath(crossPath, cross)}},
+		{ID: prefix + "-crossStart", Class: "marker cross " + class, ViewBox: [4]float64{0, 0, 11, 11}, RefX: -1, RefY: 5.2, Units: "userSpaceOnUse", Width: 11, Height: 11,
+			Shapes: []markerShape{markerPath(crossPath, cross)}},
+	}
+}
+
+func flowchartMarkers() []svgMarker {
+	markers := edgeMarkers("my-svg_flowchart-v2", "flowchart-v2", 8, 5)
+	margin := "stroke-width: 0; stroke-dasharray: 1, 0;"
+	crossMargin := "M 1,1 L 14,14 M 1,14 L 14,1"
+	class := "marker flowchart-v2"
+	// The -margin variants end edges that stop at the node border.
+	return append(markers[:2:2],
+		svgMarker{ID: "my-svg_flowchart-v2-pointEnd-margin", Class: class, ViewBox: [4]float64{0, 0, 11.5, 14}, RefX: 11.5, RefY: 7, Units: "userSpaceOnUse", Width: 10.5, Height: 14,
+			Shapes: []markerShape{markerPath("M 0 0 L 11.5 7 L 0 14 z", margin)}},
+		svgMarker{ID: "my-svg_flowchart-v2-pointStart-margin", Class: class, ViewBox: [4]float64{0, 0, 11.5, 14}, RefX: 1, RefY: 7, Units: "userSpaceOnUse", Width: 11.5, Height: 14,
+			Shapes: []markerShape{{Tag: "polygon", Points: [][2]float64{{0, 7}, {11.5, 14}, {11.5, 0}}, Class: "arrowMarkerPath", Style: margin}}},
+		markers[2],
+		markers[3],
+		svgMarker{ID: "my-svg_flowchart-v2-circleEnd-margin", Class: class, ViewBox: [4]float64{0, 0, 10, 10}, RefX: 12.25, RefY: 5, Units: "userSpaceOnUse", Width: 14, Height: 14,
+			Shapes: []markerShape{markerCircle(5, 5, 5, margin)}},
+		svgMarker{ID: "my-svg_flowchart-v2-circleStart-margin", Class: class, ViewBox: [4]float64{0, 0, 10, 10}, RefX: -2, RefY: 5, Units: "userSpaceOnUse", Width: 14, Height: 14,
+			Shapes: []markerShape{markerCircle(5, 5, 5, margin)}},
+		markers[4],
+		markers[5],
+		svgMarker{ID: "my-svg_flowchart-v2-crossEnd-margin", Class: "marker cross flowchart-v2", ViewBox: [4]float64{0, 0, 15, 15}, RefX: 17.7, RefY: 7.5, Units: "userSpaceOnUse", Width: 12, Height: 12,
+			Shapes: []markerShape{markerPath(crossMargin, "stroke-width: 2.5;")}},
+		svgMarker{ID: "my-svg_flowchart-v2-crossStart-margin", Class: "marker cross flowchart-v2", ViewBox: [4]float64{0, 0, 15, 15}, RefX: -3.5, RefY: 7.5, Units: "userSpaceOnUse", Width: 12, Height: 12,
+			Shapes: []markerShape{markerPath(crossMargin, "stroke-width: 2.5; stroke-dasharray: 1, 0;")}},
+	)
+}
+
+func blockMarkers() []svgMarker {
+	return edgeMarkers("my-svg_block", "block", 12, 6)
+}
+
+// classMarkers are the relation ends of class diagrams. Aggregation and
+// extension are hollow; the -margin variants end relations that stop at
+// the class border.
+func classMarkers() []svgMarker {
+	diamond := "M 18,7 L9,13 L1,7 L9,1 Z"
+	dependencyStart := "M 5,7 L9,13 L1,7 L9,1 Z"
+	dependencyEnd := "M 18,7 L9,13 L14,7 L9,1 Z"
+	lollipop := markerShape{Tag: "circle", CX: 7, CY: 7, R: 6, Stroke: "black", Fill: "transparent"}
+	lollipopMargin := markerShape{Tag: "circle", CX: 7, CY: 7, R: 6, Fill: "transparent", Style: "stroke-width: 2;"}
+	path := func(d, style string) []markerShape {
+		return []markerShape{{Tag: "path", D: d, Style: style}}
+	}
+	marker := func(id, kind string, refX, w, h float64, shapes []markerShape) svgMarker {
+		fill := ""
+		switch kind {
+		case "aggregation", "extension":
+			fill = "none"
+		case "lollipop":
+			fill = "#ECECFF"
+		}
+		return svgMarker{ID: "my-svg_class-" + id, Class: "marker " + kind + " class", RefX: refX, RefY: 7, Width: w, Height: h, Fill: fill,
+			ReverseAtStart: strings.HasSuffix(id, "Start"), Shapes: shapes}
+	}
+	margin := func(m svgMarker) svgMarker {
+		m.ID += "-margin"
+		m.Units = "userSpaceOnUse"
+		return m
+	}
+	extensionMargin := func(m svgMarker, points [][2]float64) svgMarker {
+		m = margin(m)
+		m.ViewBox = [4]float64{0, 0, 20, 14}
+		m.Shapes = []markerShape{{Tag: "polygon", Points: points, Style: "stroke-width: 2; stroke-dasharray: 0;"}}
+		return m
+	}
+	return []svgMarker{
+		marker("aggregationStart", "aggregation", 18, 190, 240, path(diamond, "")),
+		marker("aggregationEnd", "aggregation", 1, 20, 28, path(diamond, "")),
+		marker("extensionStart", "extension", 18, 190, 240, path("M 1,7 L18,13 V 1 Z", "")),
+		marker("extensionEnd", "extension", 1, 20, 28, path("M 1,1 V 13 L18,7 Z", "")),
+		marker("compositionStart", "composition", 18, 190, 240, path(diamond, "")),
+		marker("compositionEnd", "composition", 1, 20, 28, path(diamond, "")),
+		marker("dependencyStart", "dependency", 6, 190, 240, path(dependencyStart, "")),
+		marker("dependencyEnd", "dependency", 13, 20, 28, path(dependencyEnd, "")),
+		marker("lollipopStart", "lollipop", 13, 190, 240, []markerShape{lollipop}),
+		marker("lollipopEnd", "lollipop", 1, 190, 240, []markerShape{lollipop}),
+		margin(marker("aggregationStart", "aggregation", 15, 190, 240, path(diamond, "stroke-width: 2;"))),
+		margin(marker("aggregationEnd", "aggregation", 1, 20, 28, path(diamond, "stroke-width: 2;"))),
+		extensionMargin(marker("extensionStart", "extension", 18, 20, 28, nil), [][2]float64{{10, 7}, {18, 13}, {18, 1}}),
+		extensionMargin(marker("extensionEnd", "extension", 9, 20, 28, nil), [][2]float64{{10, 1}, {10, 13}, {18, 7}}),
+		margin(marker("compositionStart", "composition", 15, 190, 240, path(diamond, "stroke-width: 0;"))),
+		margin(marker("compositionEnd", "composition", 3.5, 20, 28, path(diamond, "stroke-width: 0;"))),
+		margin(marker("dependencyStart", "dependency", 4, 190, 240, path(dependencyStart, "stroke-width: 0;"))),
+		margin(marker("dependencyEnd", "dependency", 16, 20, 28, path(dependencyEnd, "stroke-width: 0;"))),
+		margin(marker("lollipopStart", "lollipop", 13, 190, 240, []markerShape{lollipopMargin})),
+		margin(marker("lollipopEnd", "lollipop", 1, 190, 240, []markerShape{lollipopMargin})),
+	}
+}
+
+func requirementMarkers() []svgMarker {
+	return []svgMarker{
+		{ID: "my-svg_requirement-requirement_containsStart", RefX: 0, RefY: 10, Width: 20, Height: 20, Shapes: []markerShape{
+			{Tag: "circle", CX: 10, CY: 10, R: 9, Fill: "none"},
+			{Tag: "line", Points: [][2]float64{{1, 10}, {19, 10}}},
+			{Tag: "line", Points: [][2]float64{{10, 1}, {10, 19}}},
+		}},
+		{ID: "my-svg_requirement-requirement_arrowEnd", RefX: 20, RefY: 10, Width: 20, Height: 20, Fill: "none", Shapes: []markerShape{
+			{Tag: "path", D: "M0,0 L20,10 M20,10 L0,20"},
+		}},
+	}
+}
+
+// erMarkers are the crow's foot cardinality ends. The ER stylesheet leaves
+// them unfilled except for the white circles of the zero variants.
+func erMarkers() []svgMarker {
+	marker := func(id, kind string, refX, refY, w, h float64, shapes ...markerShape) svgMarker {
+		return svgMarker{ID: "my-svg_er-" + id, Class: "marker " + kind + " er", RefX: refX, RefY: refY, Width: w, Height: h, Fill: "none", Shapes: shapes}
+	}
+	path := func(d string) markerShape { return markerShape{Tag: "path", D: d} }
+	circle := func(cx, cy float64) markerShape {
+		return markerShape{Tag: "circle", CX: cx, CY: cy, R: 6, Fill: "white"}
+	}
+	return []svgMarker{
+		marker("onlyOneStart", "onlyOne", 0, 9, 18, 18, path("M9,0 L9,18 M15,0 L15,18")),
+		marker("onlyOneEnd", "onlyOne", 18, 9, 18, 18, path("M3,0 L3,18 M9,0 L9,18")),
+		marker("zeroOrOneStart", "zeroOrOne", 0, 9, 30, 18, circle(21, 9), path("M9,0 L9,18")),
+		marker("zeroOrOneEnd", "zeroOrOne", 30, 9, 30, 18, circle(9, 9), path("M21,0 L21,18")),
+		marker("oneOrMoreStart", "oneOrMore", 18, 18, 45, 36, path("M0,18 Q 18,0 36,18 Q 18,36 0,18 M42,9 L42,27")),
+		marker("oneOrMoreEnd", "oneOrMore", 27, 18, 45, 36, path("M3,9 L3,27 M9,18 Q27,0 45,18 Q27,36 9,18")),
+		marker("zeroOrMoreStart", "zeroOrMore", 18, 18, 57, 36, circle(48, 18), path("M0,18 Q18,0 36,18 Q18,36 0,18")),
+		marker("zeroOrMoreEnd", "zeroOrMore", 39, 18, 57, 36, circle(9, 18), path("M21,18 Q39,0 57,18 Q39,36 21,18")),
+	}
+}
+
+func stateMarkers() []svgMarker {
+	return []svgMarker{{ID: "my-svg_stateDiagram-barbEnd", RefX: 19, RefY: 7, Units: "userSpaceOnUse", Width: 20, Height: 14,
+		Shapes: []markerShape{{Tag: "path", D: "M 19,7 L9,13 L14,7 L9,1 Z"}}}}
+}
+
+func timelineMarkers() []svgMarker {
+	return []svgMarker{{ID: "arrowhead", RefX: 5, RefY: 2, Width: 6, Height: 4,
+		Shapes: []markerShape{{Tag: "path", D: "M 0,0 V 4 L6,2 Z"}}}}
+}
+
+// lineArrowMarkers are the plain arrow ends of the remaining kinds, filled
+// with the theme's line color.
+func lineArrowMarkers(theme Theme) []svgMarker {
+	return []svgMarker{
+		{ID: "arrow-end", RefX: 8, RefY: 3.5, Units: "strokeWidth", Width: 10, Height: 7,
+			Shapes: []markerShape{{Tag: "path", D: "M0,0 L10,3.5 L0,7 z", Fill: theme.LineColor}}},
+		{ID: "arrow-start", RefX: 2, RefY: 3.5, Units: "strokeWidth", Width: 10, Height: 7,
+			Shapes: []markerShape{{Tag: "path", D: "M10,0 L0,3.5 L10,7 z", Fill: theme.LineColor}}},
+	}
+}
+
+// sequenceMarkers are the message ends of sequence diagrams.
+func sequenceMarkers() []svgMarker {
+	stick := func(id string, refY float64, d string) svgMarker {
+		return svgMarker{ID: "my-svg-" + id, RefX: 7.5, RefY: refY, Units: "userSpaceOnUse", Width: 12, Height: 12, Orient: "auto-start-reverse",
+			Shapes: []markerShape{{Tag: "path", D: d, Stroke: "black", Fill: "none", Style: "stroke-width: 1.5;"}}}
+	}
+	head := func(id string, refY float64, d string) svgMarker {
+		return svgMarker{ID: "my-svg-" + id, RefX: 7.9, RefY: refY, Units: "userSpaceOnUse", Width: 12, Height: 12, Orient: "auto-start-reverse",
+			Shapes: []markerShape{{Tag: "path", D: d}}}
+	}
+	return []svgMarker{
+		head("arrowhead", 5, "M -1 0 L 10 5 L 0 10 z"),
+		{ID: "my-svg-crosshead", RefX: 4, RefY: 4.5, Width: 15, Height: 8,
+			Shapes: []markerShape{{Tag: "path", D: "M 1,2 L 6,7 M 6,2 L 1,7", Fill: "none", Stroke: "#000000", Style: "stroke-width: 1.33; stroke-dasharray: 0, 0;"}}},
+		{ID: "my-svg-filled-head", RefX: 15.5, RefY: 7, Width: 20, Height: 28,
+			Shapes: []markerShape{{Tag: "path", D: "M 18,7 L9,13 L14,7 L9,1 Z"}}},
+		{ID: "my-svg-sequencenumber", RefX: 15, RefY: 15, Width: 60, Height: 40,
+			Shapes: []markerShape{{Tag: "circle", CX: 15, CY: 15, R: 6}}},
+		head("solidTopArrowHead", 7.25, "M 0 0 L 10 8 L 0 8 z"),
+		head("solidBottomArrowHead", 0.75, "M 0 0 L 10 0 L 0 8 z"),
+		stick("stickTopArrowHead", 7, "M 0 0 L 7 7"),
+		stick("stickBottomArrowHead", 0, "M 0 7 L 7 0"),
+	}
+}
+
+// c4Markers are the relationship ends of C4 diagrams.
+func c4Markers() []svgMarker {
+	return []svgMarker{
+		{ID: "arrowhead", RefX: 9, RefY: 5, Units: "userSpaceOnUse", Width: 12, Height: 12,
+			Shapes: []markerShape{{Tag: "path", D: "M 0 0 L 10 5 L 0 10 z"}}},
+		{ID: "arrowend", RefX: 1, RefY: 5, Units: "userSpaceOnUse", Width: 12, Height: 12,
+			Shapes: []markerShape{{Tag: "path", D: "M 10 0 L 0 5 L 10 10 z"}}},
+		{ID: "crosshead", RefX: 16, RefY: 4, Width: 15, Height: 8, Shapes: []markerShape{
+			{Tag: "path", D: "M 9,2 V 6 L16,4 Z", Fill: "black", Stroke: "#000000", Style: "stroke-width: 1px; stroke-dasharray: 0, 0;"},
+			{Tag: "path", D: "M 0,1 L 6,7 M 6,1 L 0,7", Fill: "none", Stroke: "#000000", Style: "stroke-width: 1px; stroke-dasharray: 0, 0;"},
+		}},
+		{ID: "filled-head", RefX: 18, RefY: 7, Width: 20, Height: 28,
+			Shapes: []markerShape{{Tag: "path", D: "M 18,7 L9,13 L14,7 L9,1 Z"}}},
+	}
+}
+
+// diagramMarkers returns the markers the SVG of a diagram kind defines.
+func diagramMarkers(kind DiagramKind, theme Theme) []svgMarker {
+	switch kind {
+	case DiagramFlowchart:
+		return flowchartMarkers()
+	case DiagramClass:
+		return classMarkers()
+	case DiagramRequirement:
+		return requirementMarkers()
+	case DiagramER:
+		return erMarkers()
+	case DiagramState:
+		return stateMarkers()
+	case DiagramTimeline, DiagramJourney:
+		return timelineMarkers()
+	case DiagramSequence:
+		return sequenceMarkers()
+	case DiagramBlock:
+		return blockMarkers()
+	case DiagramC4:
+		return c4Markers()
+	}
+	if !useArrowMarkers(kind) || kind == DiagramXYChart || kind == DiagramPie || kind == DiagramSankey {
+		return nil
+	}
+	return lineArrowMarkers(theme)
+}
@@ -2,8 +2,10 @@ package mermaid
 
 import (
 	"bytes"
+	"encoding/binary"
 	"encoding/xml"
 	"fmt"
+	"hash/crc32"
 	"html"
 	"image"
 	"image/color"
@@ -13,13 +15,16 @@ import (
 	"math"
 	"os"
 	"regexp"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	"unicode"
 
 	"github.com/srwiley/oksvg"
 	"github.com/srwiley/rasterx"
+	xdraw "golang.org/x/image/draw"
 	"golang.org/x/image/font"
 	"golang.org/x/image/font/basicfont"
 	"golang.org/x/image/font/opentype"
@@ -35,19 +40,81 @@ func WriteOutputSVG(svg string, outputPath string) error {
 }
 
 // WritePNGFromSource renders a Mermaid diagram to PNG using the pure-Go renderer.
-// It parses the Mermaid code, generates SVG, and rasterizes it to PNG.
+// It parses the Mermaid code, computes the layout and paints it to PNG.
 func WritePNGFromSource(mermaidCode string, outputPath string) error {
 	if strings.TrimSpace(mermaidCode) == "" {
 		return fmt.Errorf("mermaid code is empty")
 	}
-	if _, parseErr := ParseMermaid(mermaidCode); parseErr != nil {
-		return parseErr
-	}
-	svg, err := RenderWithOptions(mermaidCode, DefaultRenderOptions())
+	return WritePNGFromSourceWithOptions(mermaidCode, outputPath, DefaultRenderOptions())
+}
+
+// WritePNGFromSourceWithOptions renders a Mermaid diagram to PNG with the
+// given options, including options.Raster. See RenderImage.
+func WritePNGFromSourceWithOptions(mermaidCode string, outputPath string, options RenderOptions) error {
+	img, err := RenderImage(mermaidCode, options)
 	if err != nil {
 		return err
 	}
-	return writeOutputPNG(svg, outputPath, 0, 0)
+	return writePNGImage(img, outputPath, options.Raster.dpi())
+}
+
+// RenderImage renders a Mermaid diagram to an image at its intrinsic size
+// times options.Raster.Scale, on options.Raster.Background. Diagram kinds
+// the layout painter supports are drawn straight from the layout; the
+// others are rasterized from their SVG.
+func RenderImage(input string, options RenderOptions) (*image.NRGBA, error) {
+	if strings.TrimSpace(input) == "" {
+		return nil, fmt.Errorf("input diagram is empty")
+	}
+	background, err := parseRasterBackground(options.Raster.Background)
+	if err != nil {
+		return nil, err
+	}
+	parsed, err := ParseMermaid(input)
+	if err != nil {
+		return nil, err
+	}
+	if err := ensureHighFidelityOrAllowApproximate(parsed.Graph.Kind, options); err != nil {
+		return nil, err
+	}
+	layout := ComputeLayout(&parsed.Graph, options.Theme, options.Layout)
+	scale := options.Raster.scale()
+	if canPaintLayout(layout.Kind) {
+		view := layoutViewBox(layout)
+		img := newRasterCanvas(scaledRasterSize(view.W, scale), scaledRasterSize(view.H, scale), background)
+		PaintLayout(img, layout, options.Theme)
+		return img, nil
+	}
+	svg := RenderSVG(layout, options.Theme, options.Layout)
+	width, height := detectSVGSize(svg)
+	return rasterizeSVGOnto(svg, scaledRasterSize(float64(width), scale), scaledRasterSize(float64(height), scale), background)
+}
+
+// parseRasterBackground resolves a PNG background. Empty means white and
+// "transparent" a clear canvas.
+func parseRasterBackground(value string) (color.Color, error) {
+	value = strings.TrimSpace(value)
+	switch strings.ToLower(value) {
+	case "":
+		return color.White, nil
+	case "transparent", "none":
+		return color.Transparent, nil
+	}
+	fill, alpha, ok := parsePaintColor(value)
+	if !ok {
+		return nil, fmt.Errorf("invalid background color %q", value)
+	}
+	return rasterx.ApplyOpacity(fill, alpha), nil
+}
+
+func scaledRasterSize(size, scale float64) int {
+	return max(1, int(size*scale+0.5))
+}
+
+func newRasterCanvas(width, height int, background color.Color) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(img, img.Bounds(), &image.Uniform{C: background}, image.Point{}, draw.Src)
+	return img
 }
 
 // WritePNGFromSourceWithFallback is an alias for WritePNGFromSource.
@@ -70,6 +137,21 @@ func WriteOutputPNGWithSize(svg string, outputPath string, width int, height int
 	return writeOutputPNG(svg, outputPath, width, height)
 }
 
+// WriteOutputPNGWithOptions rasterizes svg at its intrinsic size scaled by
+// options.Scale, on options.Background.
+func WriteOutputPNGWithOptions(svg string, outputPath string, options RasterOptions) error {
+	background, err := parseRasterBackground(options.Background)
+	if err != nil {
+		return err
+	}
+	width, height := detectSVGSize(svg)
+	img, err := rasterizeSVGOnto(svg, scaledRasterSize(float64(width), options.scale()), scaledRasterSize(float64(height), options.scale()), background)
+	if err != nil {
+		return err
+	}
+	return writePNGImage(img, outputPath, options.dpi())
+}
+
 func writeOutputPNG(svg string, outputPath string, width int, height int) error {
 	if width <= 0 || height <= 0 {
 		width, height = detectSVGSize(svg)
@@ -78,15 +160,56 @@ func writeOutputPNG(svg string, outputPath string, width int, height int) error
 	if err != nil {
 		return err
 	}
-	if outputPath == "" {
-		return png.Encode(os.Stdout, img)
+	return writePNGImage(img, outputPath, 0)
+}
+
+// writePNGImage encodes img to outputPath, or stdout when it is empty. A
+// positive dpi is recorded in a pHYs chunk.
+func writePNGImage(img image.Image, outputPath string, dpi float64) error {
+	out := io.Writer(os.Stdout)
+	if outputPath != "" {
+		file, err := os.Create(outputPath)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		out = file
 	}
-	file, err := os.Create(outputPath)
-	if err != nil {
+	return encodePNG(out, img, dpi)
+}
+
+// encodePNG writes img as PNG. image/png has no way to set the physical
+// pixel size, so a pHYs chunk is spliced in after IHDR when dpi > 0.
+func encodePNG(w io.Writer, img image.Image, dpi float64) error {
+	if dpi <= 0 {
+		return png.Encode(w, img)
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
 		return err
 	}
-	defer file.Close()
-	return png.Encode(file, img)
+	encoded := buf.Bytes()
+	// The 8-byte signature is followed by IHDR: length, type, 13 data bytes, CRC.
+	const ihdrEnd = 8 + 4 + 4 + 13 + 4
+	if len(encoded) < ihdrEnd {
+		return fmt.Errorf("encode png: short output")
+	}
+	pixelsPerMeter := uint32(math.Round(dpi / 0.0254))
+	chunk := make([]byte, 0, 21)
+	chunk = binary.BigEndian.AppendUint32(chunk, 9)
+	chunk = append(chunk, "pHYs"...)
+	chunk = binary.BigEndian.AppendUint32(chunk, pixelsPerMeter)
+	chunk = binary.BigEndian.AppendUint32(chunk, pixelsPerMeter)
+	chunk = append(chunk, 1) // unit: meter
+	chunk = binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(chunk[4:]))
+	if _, err := w.Write(encoded[:ihdrEnd]); err != nil {
+		return err
+	}
+	if _, err := w.Write(chunk); err != nil {
+		return err
+	}
+	_, err := w.Write(encoded[ihdrEnd:])
+	return err
 }
 
 type svgViewBox struct {
@@ -138,6 +261,12 @@ func (t svgRasterTransform) mapY(y float64, viewBox svgViewBox) float64 {
 }
 
 func rasterizeSVGToImage(svg string, width int, height int) (*image.NRGBA, error) {
+	return rasterizeSVGOnto(svg, width, height, color.White)
+}
+
+// rasterizeSVGOnto rasterizes svg onto a width x height canvas filled with
+// background.
+func rasterizeSVGOnto(svg string, width int, height int, background color.Color) (*image.NRGBA, error) {
 	prepared := prepareSVGForRasterizer(svg)
 	textOverlaySource := prepared
 	rasterSVG := prepared
@@ -153,13 +282,18 @@ func rasterizeSVGToImage(svg string, width int, height int) (*image.NRGBA, error
 		viewBox = svgViewBox{X: 0, Y: 0, W: float64(width), H: float64(height)}
 	}
 	transform := computeSVGRasterTransform(width, height, viewBox)
-	icon.SetTarget(transform.TargetX, transform.TargetY, transform.TargetW, transform.TargetH)
-
-	img := image.NewNRGBA(image.Rect(0, 0, width, height))
-	draw.Draw(img, img.Bounds(), &image.Uniform{C: color.White}, image.Point{}, draw.Src)
+	// SetTarget would shift by the viewBox origin before scaling, which
+	// misplaces shapes whenever the output is not at 1:1.
+	icon.Transform = rasterx.Identity.
+		Translate(transform.TargetX, transform.TargetY).
+		Scale(transform.TargetW/icon.ViewBox.W, transform.TargetH/icon.ViewBox.H).
+		Translate(-icon.ViewBox.X, -icon.ViewBox.Y)
+
+	img := newRasterCanvas(width, height, background)
 	scanner := rasterx.NewScannerGV(width, height, img, img.Bounds())
 	dasher := rasterx.NewDasher(width, height, scanner)
 	icon.Draw(dasher, 1.0)
+	overlaySVGImages(img, textOverlaySource, width, height, viewBox)
 	overlaySVGText(img, textOverlaySource, width, height, viewBox, hasViewBox)
 	return img, nil
 }
@@ -443,6 +577,7 @@ var presentationStyleAttrs = map[string]string{
 	"text-anchor":        "text-anchor",
 	"dominant-baseline":  "dominant-baseline",
 	"alignment-baseline": "alignment-baseline",
+	"text-align":         "text-align",
 }
 
 func materializePresentationAttrs(attrs []xml.Attr, ctx elementContext, styles stylesMap) []xml.Attr {
@@ -989,6 +1124,66 @@ var (
 	zenumlMessagePattern     = regexp.MustCompile(`(?is)<div[^>]*\bdata-source="([^"]+)"[^>]*\bdata-target="([^"]+)"[^>]*\bdata-signature="([^"]*)"[^>]*>`)
 )
 
+var svgImageElementPattern = regexp.MustCompile(`(?is)<image\b([^>]*)/?>`)
+
+// overlaySVGImages paints <image> elements, which oksvg ignores. Only data
+// URIs are drawn; remote references are left out of raster output.
+func overlaySVGImages(img *image.NRGBA, svg string, width int, height int, viewBox svgViewBox) {
+	if !strings.Contains(svg, "<image") {
+		return
+	}
+	transform := computeSVGRasterTransform(width, height, viewBox)
+	for _, loc := range svgImageElementPattern.FindAllStringSubmatchIndex(svg, -1) {
+		attrs := svg[loc[2]:loc[3]]
+		href := parseAttr(attrs, "href")
+		if _, _, ok := decodeDataURI(href); !ok {
+			continue
+		}
+		x, _ := parseAnyFloat(parseAttr(attrs, "x"))
+		y, _ := parseAnyFloat(parseAttr(attrs, "y"))
+		w, okW := parseAnyFloat(parseAttr(attrs, "width"))
+		h, okH := parseAnyFloat(parseAttr(attrs, "height"))
+		if !okW || !okH || w <= 0 || h <= 0 {
+			continue
+		}
+		local := accumulateGroupTransform(svg[:loc[0]])
+		if transformAttr := strings.TrimSpace(parseAttr(attrs, "transform")); transformAttr != "" {
+			local = local.multiply(parseSVGTransform(transformAttr))
+		}
+		x0, y0 := local.apply(x, y)
+		x1, y1 := local.apply(x+w, y+h)
+		px0, py0 := transform.mapX(min(x0, x1), viewBox), transform.mapY(min(y0, y1), viewBox)
+		px1, py1 := transform.mapX(max(x0, x1), viewBox), transform.mapY(max(y0, y1), viewBox)
+		drawDataURIImage(img, href, px0, py0, px1, py1)
+	}
+}
+
+// drawDataURIImage scales the image in a data URI into the pixel box
+// (px0, py0)-(px1, py1). Other references are ignored.
+func drawDataURIImage(img draw.Image, href string, px0, py0, px1, py1 float64) {
+	mime, data, ok := decodeDataURI(href)
+	if !ok {
+		return
+	}
+	if mime == "image/svg+xml" {
+		icon, err := oksvg.ReadIconStream(bytes.NewReader(data), oksvg.IgnoreErrorMode)
+		if err != nil {
+			return
+		}
+		icon.SetTarget(px0, py0, px1-px0, py1-py0)
+		bounds := img.Bounds()
+		scanner := rasterx.NewScannerGV(bounds.Max.X, bounds.Max.Y, img, bounds)
+		icon.Draw(rasterx.NewDasher(bounds.Max.X, bounds.Max.Y, scanner), 1.0)
+		return
+	}
+	decoded, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return
+	}
+	target := image.Rect(int(math.Round(px0)), int(math.Round(py0)), int(math.Round(px1)), int(math.Round(py1)))
+	xdraw.CatmullRom.Scale(img, target, decoded, decoded.Bounds(), draw.Over, nil)
+}
+
 func overlaySVGText(img *image.NRGBA, svg string, width int, height int, viewBox svgViewBox, hasViewBox bool) {
 	svg = stripSVGForeignObjectSwitches(svg)
 	if !hasViewBox || viewBox.W <= 0 || viewBox.H <= 0 {
@@ -1007,6 +1202,7 @@ func overlaySVGText(img *image.NRGBA, svg string, width int, height int, viewBox
 		if strings.TrimSpace(content) == "" {
 			continue
 		}
+		content = displayText(content)
 		tspanAttrs, hasTSpan := firstTSpanAttrs(inner)
 
 		rawX := firstNumericToken(parseAttr(attrs, "x"))
@@ -1130,8 +1326,22 @@ func overlaySVGText(img *image.NRGBA, svg string, width int, height int, viewBox
 			rotateAngle += parseSVGTransform(transformAttr).rotationDegrees()
 		}
 
+		lines, offsets := svgTextLines(inner, fontSize)
 		if rotateAngle != 0 {
 			overlayRotatedText(img, content, face, textColor, px, py, rotateAngle, anchorOffsetX, baselineOffsetY)
+		} else if isRichLabel(lines) {
+			rasterSize := max(8.0, fontSize*transform.Scale)
+			for i, line := range lines {
+				lineX := px
+				lineW := labelLineAdvance(line, fontFamily, rasterSize)
+				switch anchor {
+				case "middle":
+					lineX -= lineW / 2.0
+				case "end":
+					lineX -= lineW
+				}
+				drawLabelLine(drawer, line, fontFamily, rasterSize, lineX, py+baselineOffsetY+offsets[i]*transform.Scale)
+			}
 		} else {
 			drawer.Dot = fixed.P(int(math.Round(px+anchorOffsetX)), int(math.Round(py+baselineOffsetY)))
 			drawer.DrawString(content)
@@ -1141,6 +1351,65 @@ func overlaySVGText(img *image.NRGBA, svg string, width int, height int, viewBox
 	overlaySVGForeignObjectText(img, svg, width, height, viewBox, hasViewBox)
 }
 
+// svgTextLines reads the styled lines of a <text> element. Each top-level
+// tspan with a non-zero dy starts a new line; nested tspans carry bold,
+// italic and monospace runs. offsets holds each line's dy from the first.
+func svgTextLines(inner string, fontSize float64) (lines [][]TextRun, offsets []float64) {
+	decoder := xml.NewDecoder(strings.NewReader("<text>" + inner + "</text>"))
+	decoder.Strict = false
+	decoder.Entity = xml.HTMLEntity
+	lines = [][]TextRun{nil}
+	offsets = []float64{0}
+	styles := []TextRun{{}}
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			break
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			style := styles[len(styles)-1]
+			if strings.EqualFold(t.Name.Local, "tspan") {
+				if len(styles) == 2 {
+					if dy := parseSVGTextLength(xmlAttr(t.Attr, "dy"), fontSize); dy != 0 && len(lines[len(lines)-1]) > 0 {
+						lines = append(lines, nil)
+						offsets = append(offsets, offsets[len(offsets)-1]+dy)
+					}
+				}
+				if isBoldWeight(xmlAttr(t.Attr, "font-weight")) {
+					style.Bold = true
+				}
+				if fontStyle := lower(xmlAttr(t.Attr, "font-style")); fontStyle == "italic" || fontStyle == "oblique" {
+					style.Italic = true
+				}
+				if strings.Contains(lower(xmlAttr(t.Attr, "font-family")), "mono") {
+					style.Code = true
+				}
+			}
+			styles = append(styles, style)
+		case xml.EndElement:
+			if len(styles) > 1 {
+				styles = styles[:len(styles)-1]
+			}
+		case xml.CharData:
+			if strings.TrimSpace(string(t)) == "" && len(styles) <= 2 {
+				continue
+			}
+			run := styles[len(styles)-1]
+			run.Text = string(t)
+			last := len(lines) - 1
+			lines[last] = appendLabelRuns(lines[last], run)
+		}
+	}
+	return collapseLabelLines(lines), offsets
+}
+
+func isBoldWeight(weight string) bool {
+	weight = lower(weight)
+	numeric, err := strconv.Atoi(weight)
+	return weight == "bold" || weight == "bolder" || (err == nil && numeric >= 600)
+}
+
 func firstTSpanAttrs(inner string) (string, bool) {
 	match := svgTSpanOpenPattern.FindStringSubmatch(inner)
 	if len(match) < 2 {
@@ -1174,14 +1443,14 @@ func parseSVGTextLength(raw string, fontSize float64) float64 {
 }
 
 func svgTextBaselineOffset(face font.Face, dominantBaseline string) float64 {
-	mode := strings.ToLower(strings.TrimSpace(dominantBaseline))
-	if mode == "" {
-		return 0
-	}
 	metrics := face.Metrics()
-	ascent := float64(metrics.Ascent) / 64.0
-	descent := float64(metrics.Descent) / 64.0
-	switch mode {
+	return dominantBaselineOffset(float64(metrics.Ascent)/64.0, float64(metrics.Descent)/64.0, dominantBaseline)
+}
+
+// dominantBaselineOffset is how far below the anchor the alphabetic baseline
+// sits for a dominant-baseline value, given the font's ascent and descent.
+func dominantBaselineOffset(ascent, descent float64, dominantBaseline string) float64 {
+	switch strings.ToLower(strings.TrimSpace(dominantBaseline)) {
 	case "middle", "central":
 		return (ascent - descent) / 2.0
 	case "hanging", "text-before-edge", "before-edge":
@@ -1216,12 +1485,32 @@ func overlaySVGForeignObjectText(img *image.NRGBA, svg string, width int, height
 		metrics := face.Metrics()
 		ascent := float64(metrics.Ascent) / 64.0
 		descent := float64(metrics.Descent) / 64.0
+		if isRichLabel(label.Lines) {
+			rasterSize := max(8.0, label.FontSize*transform.Scale)
+			lineBoxHeight := max(label.H*transform.Scale/float64(len(label.Lines)), ascent+descent)
+			for i, line := range label.Lines {
+				lineX := px
+				if label.W > 0 {
+					lineW := labelLineAdvance(line, label.FontFamily, rasterSize)
+					switch strings.ToLower(strings.TrimSpace(label.TextAlign)) {
+					case "center", "middle":
+						lineX += (label.W*transform.Scale - lineW) / 2.0
+					case "right", "end":
+						lineX += label.W*transform.Scale - lineW
+					}
+				}
+				baseline := py + float64(i)*lineBoxHeight + (lineBoxHeight-(ascent+descent))/2.0 + ascent
+				drawLabelLine(drawer, line, label.FontFamily, rasterSize, lineX, baseline)
+			}
+			continue
+		}
 		lineBoxHeight := label.H * transform.Scale
 		if lineBoxHeight < ascent+descent {
 			lineBoxHeight = ascent + descent
 		}
 		py += (lineBoxHeight-(ascent+descent))/2.0 + ascent
-		textWidth := float64(drawer.MeasureString(label.Text)) / 64.0
+		text := displayText(label.Text)
+		textWidth := float64(drawer.MeasureString(text)) / 64.0
 		if mindmapCenteredText && label.W > 0 {
 			boxWidth := label.W * transform.Scale
 			px += (boxWidth - textWidth) / 2.0
@@ -1235,7 +1524,7 @@ func overlaySVGForeignObjectText(img *image.NRGBA, svg string, width int, height
 			}
 		}
 		drawer.Dot = fixed.P(int(math.Round(px)), int(math.Round(py)))
-		drawer.DrawString(label.Text)
+		drawer.DrawString(text)
 	}
 }
 
@@ -1249,6 +1538,7 @@ type foreignObjectLabel struct {
 	FontFamily string
 	Color      string
 	TextAlign  string
+	Lines      [][]TextRun
 }
 
 type foreignObjectCapture struct {
@@ -1264,6 +1554,54 @@ type foreignObjectCapture struct {
 	TextAlign  string
 	Depth      int
 	Text       strings.Builder
+	Lines      [][]TextRun
+	styles     []TextRun
+}
+
+// style returns the inline style of the innermost open element.
+func (c *foreignObjectCapture) style() TextRun {
+	if len(c.styles) == 0 {
+		return TextRun{}
+	}
+	return c.styles[len(c.styles)-1]
+}
+
+// open records an element inside the label: its CSS, the run style it
+// implies, and <br> line breaks.
+func (c *foreignObjectCapture) open(t xml.StartElement) {
+	updateCaptureStyle(c, t.Attr)
+	style := c.style()
+	switch strings.ToLower(t.Name.Local) {
+	case "strong", "b":
+		style.Bold = true
+	case "em", "i":
+		style.Italic = true
+	case "code":
+		style.Code = true
+	case "br":
+		c.Lines = append(c.Lines, nil)
+	}
+	css := xmlAttr(t.Attr, "style")
+	if isBoldWeight(styleValue(css, "font-weight")) {
+		style.Bold = true
+	}
+	if fontStyle := lower(styleValue(css, "font-style")); fontStyle == "italic" || fontStyle == "oblique" {
+		style.Italic = true
+	}
+	c.styles = append(c.styles, style)
+}
+
+func (c *foreignObjectCapture) close() {
+	if len(c.styles) > 0 {
+		c.styles = c.styles[:len(c.styles)-1]
+	}
+}
+
+func (c *foreignObjectCapture) addText(text string) {
+	run := c.style()
+	run.Text = text
+	last := len(c.Lines) - 1
+	c.Lines[last] = appendLabelRuns(c.Lines[last], run)
 }
 
 type svgTransformState struct {
@@ -1317,13 +1655,14 @@ func extractForeignObjectLabels(svg string, viewBox svgViewBox) []foreignObjectL
 					H:        h,
 					FontSize: 16,
 					Depth:    1,
+					Lines:    [][]TextRun{nil},
 				}
 				continue
 			}
 
 			if current != nil {
 				current.Depth++
-				updateCaptureStyle(current, t.Attr)
+				current.open(t)
 			}
 
 		case xml.EndElement:
@@ -1334,6 +1673,7 @@ func extractForeignObjectLabels(svg string, viewBox svgViewBox) []foreignObjectL
 				continue
 			}
 			current.Depth--
+			current.close()
 			if strings.EqualFold(t.Name.Local, "foreignObject") || current.Depth <= 0 {
 				text := strings.Join(strings.Fields(current.Text.String()), " ")
 				text = strings.TrimSpace(html.UnescapeString(text))
@@ -1348,6 +1688,7 @@ func extractForeignObjectLabels(svg string, viewBox svgViewBox) []foreignObjectL
 						FontFamily: current.FontFamily,
 						Color:      current.Color,
 						TextAlign:  current.TextAlign,
+						Lines:      collapseLabelLines(current.Lines),
 					})
 				}
 				current = nil
@@ -1357,12 +1698,101 @@ func extractForeignObjectLabels(svg string, viewBox svgViewBox) []foreignObjectL
 			if current != nil {
 				current.Text.WriteString(" ")
 				current.Text.Write([]byte(t))
+				current.addText(string(t))
 			}
 		}
 	}
 	return labels
 }
 
+// collapseLabelLines applies HTML whitespace collapsing to captured label
+// lines.
+func collapseLabelLines(lines [][]TextRun) [][]TextRun {
+	out := make([][]TextRun, 0, len(lines))
+	for _, line := range lines {
+		collapsed := make([]TextRun, 0, len(line))
+		prevSpace := true
+		for _, run := range line {
+			var b strings.Builder
+			for _, r := range run.Text {
+				if unicode.IsSpace(r) {
+					if !prevSpace {
+						b.WriteByte(' ')
+					}
+					prevSpace = true
+					continue
+				}
+				b.WriteRune(r)
+				prevSpace = false
+			}
+			if b.Len() > 0 {
+				run.Text = b.String()
+				collapsed = appendLabelRuns(collapsed, run)
+			}
+		}
+		if n := len(collapsed); n > 0 {
+			collapsed[n-1].Text = strings.TrimRight(collapsed[n-1].Text, " ")
+			if collapsed[n-1].Text == "" {
+				collapsed = collapsed[:n-1]
+			}
+		}
+		out = append(out, collapsed)
+	}
+	return out
+}
+
+// isRichLabel reports whether lines need per-line, per-run drawing rather
+// than a single string.
+func isRichLabel(lines [][]TextRun) bool {
+	if len(lines) > 1 {
+		return true
+	}
+	for _, line := range lines {
+		for _, run := range line {
+			if run.Bold || run.Italic || run.Code {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// drawLabelLine draws one line of styled runs starting at the dot, switching
+// faces between runs.
+func drawLabelLine(drawer *font.Drawer, line []TextRun, fontFamily string, fontSize float64, x, baseline float64) {
+	drawer.Dot = fixed.Point26_6{X: fixed.Int26_6(math.Round(x * 64)), Y: fixed.Int26_6(math.Round(baseline * 64))}
+	for _, run := range visualRuns(line) {
+		drawer.Face = resolveRasterRunFace(fontFamily, fontSize, run)
+		drawer.DrawString(displayText(run.Text))
+	}
+}
+
+// visualRuns returns the runs of a line in drawing order, which reverses
+// them when the line reads right to left.
+func visualRuns(line []TextRun) []TextRun {
+	if len(line) < 2 {
+		return line
+	}
+	var text strings.Builder
+	for _, run := range line {
+		text.WriteString(run.Text)
+	}
+	if !baseDirectionRTL(text.String()) {
+		return line
+	}
+	reversed := slices.Clone(line)
+	slices.Reverse(reversed)
+	return reversed
+}
+
+func labelLineAdvance(line []TextRun, fontFamily string, fontSize float64) float64 {
+	w := 0.0
+	for _, run := range line {
+		w += float64(font.MeasureString(resolveRasterRunFace(fontFamily, fontSize, run), displayText(run.Text))) / 64.0
+	}
+	return w
+}
+
 func updateCaptureStyle(capture *foreignObjectCapture, attrs []xml.Attr) {
 	if capture == nil {
 		return
@@ -1698,6 +2128,10 @@ func autoContrastTextColor(img *image.NRGBA, x int, y int) color.Color {
 		y = bounds.Max.Y - 1
 	}
 	offset := img.PixOffset(x, y)
+	if img.Pix[offset+3] < 128 {
+		// A transparent canvas is assumed to end up on a light page.
+		return color.NRGBA{R: 0, G: 0, B: 0, A: 255}
+	}
 	r := float64(img.Pix[offset])
 	g := float64(img.Pix[offset+1])
 	b := float64(img.Pix[offset+2])
@@ -1927,10 +2361,100 @@ func buildZenUMLRasterFallbackSVG(sourceSVG string, width int, height int) (stri
 }
 
 func resolveRasterFontFace(fontFamily string, fontSize float64) font.Face {
-	path := resolveFontPath(fontFamily)
-	if path == "" {
-		path = resolveFontPath(defaultMetricFontFamily)
+	chain := fontChain(fontFamily)
+	if len(chain) == 0 {
+		chain = fontChain(defaultMetricFontFamily)
+	}
+	return rasterFallbackFace(chain, fontSize)
+}
+
+// resolveRasterRunFace picks the face for a styled label run: the bold or
+// italic sibling of the family's font, or a monospace face for code.
+func resolveRasterRunFace(fontFamily string, fontSize float64, run TextRun) font.Face {
+	if run.Code {
+		fontFamily = "monospace"
+	}
+	if strings.TrimSpace(fontFamily) == "" || resolveFontPath(fontFamily) == "" {
+		fontFamily = defaultMetricFontFamily
+	}
+	return rasterFallbackFace(styledFontChain(fontFamily, run.Bold, run.Italic), fontSize)
+}
+
+// styledFontChain returns the fallback chain of fontFamily with its bold or
+// italic font in place of the regular one.
+func styledFontChain(fontFamily string, bold, italic bool) []string {
+	chain := fontChain(fontFamily)
+	if styled := resolveStyledFontPath(fontFamily, bold, italic); len(chain) > 0 && styled != chain[0] {
+		chain = append([]string{styled}, chain[1:]...)
 	}
+	return chain
+}
+
+// rasterFallbackFace returns a face drawing each character with the first
+// font of chain that has it, or the basic bitmap face when chain is empty.
+func rasterFallbackFace(chain []string, fontSize float64) font.Face {
+	if len(chain) <= 1 {
+		return rasterFontFaceForPath(strings.Join(chain, ""), fontSize)
+	}
+	key := strings.Join(chain, "|") + "|" + formatFloat(fontSize)
+	if cached, ok := svgFontFaceCache.Load(key); ok {
+		if face, okFace := cached.(font.Face); okFace {
+			return face
+		}
+	}
+	face := &fallbackFace{chain: chain, faces: make([]font.Face, len(chain))}
+	for i, path := range chain {
+		face.faces[i] = rasterFontFaceForPath(path, fontSize)
+	}
+	svgFontFaceCache.Store(key, face)
+	return face
+}
+
+// fallbackFace is a font.Face over a font chain; see fontForRune.
+type fallbackFace struct {
+	chain []string
+	faces []font.Face
+}
+
+func (f *fallbackFace) pick(r rune) (font.Face, rune) {
+	index, _, found := fontForRune(f.chain, r)
+	if index < 0 {
+		return f.faces[0], r
+	}
+	return f.faces[index], found
+}
+
+func (f *fallbackFace) Close() error { return nil }
+
+func (f *fallbackFace) Glyph(dot fixed.Point26_6, r rune) (image.Rectangle, image.Image, image.Point, fixed.Int26_6, bool) {
+	face, r := f.pick(r)
+	return face.Glyph(dot, r)
+}
+
+func (f *fallbackFace) GlyphBounds(r rune) (fixed.Rectangle26_6, fixed.Int26_6, bool) {
+	face, r := f.pick(r)
+	return face.GlyphBounds(r)
+}
+
+func (f *fallbackFace) GlyphAdvance(r rune) (fixed.Int26_6, bool) {
+	face, r := f.pick(r)
+	return face.GlyphAdvance(r)
+}
+
+func (f *fallbackFace) Kern(r0, r1 rune) fixed.Int26_6 {
+	face0, r0 := f.pick(r0)
+	face1, r1 := f.pick(r1)
+	if face0 != face1 {
+		return 0
+	}
+	return face0.Kern(r0, r1)
+}
+
+func (f *fallbackFace) Metrics() font.Metrics {
+	return f.faces[0].Metrics()
+}
+
+func rasterFontFaceForPath(path string, fontSize float64) font.Face {
 	key := path + "|" + formatFloat(fontSize)
 	if cached, ok := svgFontFaceCache.Load(key); ok {
 		if face, okFace := cached.(font.Face); okFace {
@@ -1963,7 +2487,7 @@ func clampInt(v int, lo int, hi int) int {
 	return v
 }
 
-func overlayRotatedText(img *image.NRGBA, content string, face font.Face, textColor color.Color, px, py, angleDeg, anchorOffsetX, baselineOffsetY float64) {
+func overlayRotatedText(img draw.Image, content string, face font.Face, textColor color.Color, px, py, angleDeg, anchorOffsetX, baselineOffsetY float64) {
 	// 1. Measure the text
 	advance := font.MeasureString(face, content)
 	metrics := face.Metrics()
@@ -1996,7 +2520,6 @@ func overlayRotatedText(img *image.NRGBA, content string, face font.Face, textCo
 	sin, cos := math.Sincos(rad)
 
 	bounds := img.Bounds()
-	maxX, maxY := bounds.Dx(), bounds.Dy()
 
 	// 2. Map and blend pixels
 	for y := 0; y < h; y++ {
@@ -2016,7 +2539,7 @@ func overlayRotatedText(img *image.NRGBA, content string, face font.Face, textCo
 				dx := int(math.Round(px + rx))
 				dy := int(math.Round(py + ry))
 
-				if dx >= 0 && dy >= 0 && dx < maxX && dy < maxY {
+				if image.Pt(dx, dy).In(bounds) {
 					cr, cg, cb, ca := c.RGBA()
 					bg := img.At(dx, dy)
 					br, bg_, bb, ba := bg.RGBA()
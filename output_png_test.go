@@ -9,6 +9,7 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 )
@@ -222,6 +223,28 @@ func TestRasterizeSVGToImageDrawsTSpanPositionedText(t *testing.T) {
 	}
 }
 
+func TestRasterTextOverlayReadsStyledLines(t *testing.T) {
+	lines, offsets := svgTextLines(`<tspan x="0" dy="0"><tspan font-weight="bold">a</tspan> b</tspan><tspan x="0" dy="1.2em"><tspan font-style="italic">c</tspan></tspan>`, 10)
+	wantLines := [][]TextRun{{{Text: "a", Bold: true}, {Text: " b"}}, {{Text: "c", Italic: true}}}
+	if !reflect.DeepEqual(lines, wantLines) || !reflect.DeepEqual(offsets, []float64{0, 12}) {
+		t.Fatalf("svgTextLines = %+v %v", lines, offsets)
+	}
+
+	svg := `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 100 60"><foreignObject width="80" height="48"><div xmlns="http://www.w3.org/1999/xhtml"><p><strong>x</strong> y<br/><code>z</code></p></div></foreignObject></svg>`
+	labels := extractForeignObjectLabels(svg, svgViewBox{W: 100, H: 60})
+	wantLines = [][]TextRun{{{Text: "x", Bold: true}, {Text: " y"}}, {{Text: "z", Code: true}}}
+	if len(labels) != 1 || !reflect.DeepEqual(labels[0].Lines, wantLines) {
+		t.Fatalf("unexpected foreignObject labels: %+v", labels)
+	}
+
+	img := image.NewNRGBA(image.Rect(0, 0, 100, 60))
+	draw.Draw(img, img.Bounds(), &image.Uniform{C: color.White}, image.Point{}, draw.Src)
+	overlaySVGForeignObjectText(img, svg, 100, 60, svgViewBox{W: 100, H: 60}, true)
+	if countDarkPixels(img, 0, 0, 100, 24) == 0 || countDarkPixels(img, 0, 24, 100, 48) == 0 {
+		t.Fatal("expected both label lines to be drawn")
+	}
+}
+
 func TestOverlaySVGTextPrefersTSpanFillOverParentTextFill(t *testing.T) {
 	svg := `<?xml version="1.0" encoding="UTF-8"?>
 <svg xmlns="http://www.w3.org/2000/svg" width="120" height="40" viewBox="0 0 120 40">
@@ -709,3 +732,65 @@ func countNonWhitePixels(img image.Image) int {
 	}
 	return count
 }
+
+func TestRenderImageScaleAndTransparentBackground(t *testing.T) {
+	for _, input := range []string{
+		"flowchart LR\n  A --> B",
+		"sequenceDiagram\n  Alice->>Bob: Hi",
+	} {
+		base, err := RenderImage(input, DefaultRenderOptions())
+		if err != nil {
+			t.Fatalf("RenderImage() error = %v", err)
+		}
+		scaled, err := RenderImage(input, DefaultRenderOptions().WithScale(2).WithBackgroundColor("transparent"))
+		if err != nil {
+			t.Fatalf("RenderImage() scaled error = %v", err)
+		}
+		want := base.Bounds().Size().Mul(2)
+		if got := scaled.Bounds().Size(); got.X < want.X-1 || got.X > want.X+1 || got.Y < want.Y-1 || got.Y > want.Y+1 {
+			t.Fatalf("scaled size = %v, want about %v", got, want)
+		}
+		if _, _, _, a := rgba8At(scaled, 0, 0); a != 0 {
+			t.Fatalf("expected a transparent corner, got alpha %d", a)
+		}
+		if _, _, _, a := rgba8At(base, 0, 0); a != 255 {
+			t.Fatalf("expected an opaque default background, got alpha %d", a)
+		}
+	}
+}
+
+func TestRenderImageBackgroundColor(t *testing.T) {
+	img, err := RenderImage("flowchart LR\n  A --> B", DefaultRenderOptions().WithBackgroundColor("#102030"))
+	if err != nil {
+		t.Fatalf("RenderImage() error = %v", err)
+	}
+	if r, g, b, _ := rgba8At(img, 0, 0); !nearRGB(r, g, b, 0x10, 0x20, 0x30, 1) {
+		t.Fatalf("background = rgb(%d,%d,%d), want #102030", r, g, b)
+	}
+	if _, err := RenderImage("flowchart LR\n  A --> B", DefaultRenderOptions().WithBackgroundColor("nope")); err == nil {
+		t.Fatal("expected an error for an invalid background color")
+	}
+}
+
+func TestWritePNGFromSourceWithOptionsWritesPHYs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "scaled.png")
+	if err := WritePNGFromSourceWithOptions("flowchart LR\n  A --> B", path, DefaultRenderOptions().WithScale(2)); err != nil {
+		t.Fatalf("WritePNGFromSourceWithOptions() error = %v", err)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read png file: %v", err)
+	}
+	if _, err := png.Decode(bytes.NewReader(content)); err != nil {
+		t.Fatalf("decode png with pHYs: %v", err)
+	}
+	idx := bytes.Index(content, []byte("pHYs"))
+	if idx < 0 || idx+13 > len(content) {
+		t.Fatal("expected a pHYs chunk")
+	}
+	// 192 DPI is 7559 pixels per meter on both axes, unit meter.
+	want := []byte{0, 0, 0x1d, 0x87, 0, 0, 0x1d, 0x87, 1}
+	if got := content[idx+4 : idx+13]; !bytes.Equal(got, want) {
+		t.Fatalf("pHYs data = %x, want %x", got, want)
+	}
+}
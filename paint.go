@@ -0,0 +1,702 @@
+package mermaid
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/srwiley/oksvg"
+	"github.com/srwiley/rasterx"
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+// layoutCanvas receives the primitives of a Layout in paint order. Paths,
+// text anchors and image boxes are in layout units; m places them in the
+// layout's coordinate space and the canvas maps that space to its output.
+type layoutCanvas interface {
+	fillStroke(path rasterx.Path, m rasterx.Matrix2D, style paintStyle)
+	text(label paintText)
+	image(href string, x, y, w, h float64, m rasterx.Matrix2D)
+}
+
+// paintStyle is the resolved fill and stroke of one primitive. Opacities of
+// zero mean "unset", matching how the SVG renderer omits them.
+type paintStyle struct {
+	Fill          string
+	Stroke        string
+	FillOpacity   float64
+	StrokeOpacity float64
+	Opacity       float64
+	StrokeWidth   float64
+	DashArray     string
+	LineCap       string
+	LineJoin      string
+	EvenOdd       bool
+}
+
+// paintText is one label: lines of styled runs anchored at X and the first
+// line's Y, each following line LineStep further down.
+type paintText struct {
+	Lines    [][]TextRun
+	X        float64
+	Y        float64
+	Anchor   string
+	Baseline string
+	LineStep float64
+	Family   string
+	Size     float64
+	Color    string
+	Opacity  float64
+	// Transform is the text's own transform; the zero value means none.
+	Transform svgAffineTransform
+}
+
+// canPaintLayout reports whether the painter draws every primitive of a
+// diagram kind. The other kinds are drawn by dedicated SVG writers or take
+// their colors from CSS classes, and still go through the SVG rasterizer.
+func canPaintLayout(kind DiagramKind) bool {
+	switch kind {
+	case DiagramFlowchart, DiagramER, DiagramRequirement, DiagramPie, DiagramTimeline, DiagramXYChart,
+		DiagramSequence, DiagramClass, DiagramState:
+		return true
+	default:
+		return false
+	}
+}
+
+// PaintLayout draws layout onto dst with theme, scaling the layout's viewBox
+// to fit dst's bounds. It does not clear dst, so callers choose the
+// background.
+func PaintLayout(dst draw.Image, layout Layout, theme Theme) {
+	bounds := dst.Bounds()
+	if bounds.Empty() {
+		return
+	}
+	view := layoutViewBox(layout)
+	fit := computeSVGRasterTransform(bounds.Dx(), bounds.Dy(), view)
+	scanner := rasterx.NewScannerGV(bounds.Max.X, bounds.Max.Y, dst, bounds)
+	canvas := &rasterCanvas{
+		dst:    dst,
+		scale:  fit.Scale,
+		dasher: rasterx.NewDasher(bounds.Max.X, bounds.Max.Y, scanner),
+		base: rasterx.Identity.
+			Translate(float64(bounds.Min.X)+fit.TargetX, float64(bounds.Min.Y)+fit.TargetY).
+			Scale(fit.Scale, fit.Scale).
+			Translate(-view.X, -view.Y),
+	}
+	paintLayout(layout, theme, canvas)
+}
+
+// RasterizeLayout paints layout onto a new white image. A zero width or
+// height uses the layout's own size.
+func RasterizeLayout(layout Layout, theme Theme, width, height int) *image.NRGBA {
+	if width <= 0 || height <= 0 {
+		view := layoutViewBox(layout)
+		width = max(1, int(view.W+0.5))
+		height = max(1, int(view.H+0.5))
+	}
+	img := newRasterCanvas(width, height, color.White)
+	PaintLayout(img, layout, theme)
+	return img
+}
+
+func layoutViewBox(layout Layout) svgViewBox {
+	if layout.ViewBoxWidth > 0 && layout.ViewBoxHeight > 0 {
+		return svgViewBox{X: layout.ViewBoxX, Y: layout.ViewBoxY, W: layout.ViewBoxWidth, H: layout.ViewBoxHeight}
+	}
+	return svgViewBox{W: max(1.0, layout.Width), H: max(1.0, layout.Height)}
+}
+
+// paintLayout walks the layout primitives in the order renderSVGDocument
+// writes them, so overlapping shapes stack the same way in both outputs.
+func paintLayout(layout Layout, theme Theme, canvas layoutCanvas) {
+	mermaidLike := useMermaidLikeDOM(layout.Kind)
+	if layout.Kind == DiagramER {
+		for _, text := range layout.Texts {
+			if strings.Contains(text.Class, "attribute") {
+				mermaidLike = false
+				break
+			}
+		}
+	}
+	var markers map[string]svgMarker
+	if useArrowMarkers(layout.Kind) {
+		markers = map[string]svgMarker{}
+		for _, marker := range diagramMarkers(layout.Kind, theme) {
+			markers[marker.ID] = marker
+		}
+	}
+	family := theme.FontFamily
+	if family == "" {
+		family = "sans-serif"
+	}
+
+	switch layout.Kind {
+	case DiagramSequence:
+		paintSequenceLayout(canvas, layout, theme, markers, family)
+		paintLayoutTitle(canvas, layout, theme, family)
+		return
+	case DiagramClass:
+		paintClassLayout(canvas, layout, theme, markers, family)
+		paintLayoutTitle(canvas, layout, theme, family)
+		return
+	case DiagramState:
+		paintStateLayout(canvas, layout, theme, markers, family)
+		paintLayoutTitle(canvas, layout, theme, family)
+		return
+	}
+
+	for _, rect := range layout.Rects {
+		dash := strings.TrimSpace(rect.StrokeDasharray)
+		if rect.Dashed && dash == "" {
+			dash = "5,4"
+		}
+		canvas.fillStroke(compilePaintPath(rectToPath(rect)), paintTransform(rect.Transform, rect.TransformOrigin), paintStyle{
+			Fill:          rect.Fill,
+			Stroke:        rect.Stroke,
+			FillOpacity:   rect.FillOpacity,
+			StrokeOpacity: rect.StrokeOpacity,
+			Opacity:       rect.Opacity,
+			StrokeWidth:   defaultFloat(rect.StrokeWidth, 1),
+			DashArray:     dash,
+		})
+	}
+
+	mouths := make([]LayoutPath, 0)
+	for _, path := range layout.Paths {
+		if layout.Kind == DiagramJourney && strings.TrimSpace(path.Class) == "mouth" {
+			mouths = append(mouths, path)
+			continue
+		}
+		paintLayoutPath(canvas, path, markers)
+	}
+
+	for _, poly := range layout.Polygons {
+		if len(poly.Points) == 0 {
+			continue
+		}
+		var p rasterx.Path
+		p.Start(paintPoint(poly.Points[0].X, poly.Points[0].Y))
+		for _, point := range poly.Points[1:] {
+			p.Line(paintPoint(point.X, point.Y))
+		}
+		p.Stop(true)
+		canvas.fillStroke(p, paintTransform(poly.Transform, ""), paintStyle{
+			Fill:          poly.Fill,
+			Stroke:        poly.Stroke,
+			FillOpacity:   poly.FillOpacity,
+			StrokeOpacity: poly.StrokeOpacity,
+			Opacity:       poly.Opacity,
+			StrokeWidth:   defaultFloat(poly.StrokeWidth, 1),
+		})
+	}
+
+	for _, line := range layout.Lines {
+		var p rasterx.Path
+		p.Start(paintPoint(line.X1, line.Y1))
+		p.Line(paintPoint(line.X2, line.Y2))
+		dash := strings.TrimSpace(line.DashArray)
+		if line.Dashed {
+			dash = "5,4"
+		}
+		stroke := defaultColor(line.Stroke, "#333333")
+		m := paintTransform(line.Transform, "")
+		canvas.fillStroke(p, m, paintStyle{
+			Stroke:        stroke,
+			StrokeOpacity: line.StrokeOpacity,
+			Opacity:       line.Opacity,
+			StrokeWidth:   defaultFloat(line.StrokeWidth, 1),
+			DashArray:     dash,
+			LineCap:       line.LineCap,
+			LineJoin:      line.LineJoin,
+		})
+		startMarker := strings.TrimSpace(line.MarkerStart)
+		if startMarker == "" && markers != nil && line.ArrowStart && layout.Kind != DiagramTimeline {
+			startMarker = "arrow-start"
+		}
+		endMarker := strings.TrimSpace(line.MarkerEnd)
+		if endMarker == "" && markers != nil && line.ArrowEnd {
+			endMarker = "arrow-end"
+			if layout.Kind == DiagramTimeline || layout.Kind == DiagramJourney {
+				endMarker = "arrowhead"
+			}
+		}
+		width := defaultFloat(line.StrokeWidth, 1)
+		angle := math.Atan2(line.Y2-line.Y1, line.X2-line.X1)
+		if startMarker != "" {
+			paintMarker(canvas, markers[startMarker], stroke, width, line.X1, line.Y1, angle, true, m)
+		}
+		if endMarker != "" {
+			paintMarker(canvas, markers[endMarker], stroke, width, line.X2, line.Y2, angle, false, m)
+		}
+	}
+
+	for _, circle := range layout.Circles {
+		paintLayoutCircle(canvas, circle)
+	}
+	for _, path := range mouths {
+		path.Stroke = defaultColor(path.Stroke, "#666")
+		paintLayoutPath(canvas, path, nil)
+	}
+
+	for _, ellipse := range layout.Ellipses {
+		var p rasterx.Path
+		rasterx.AddEllipse(ellipse.CX, ellipse.CY, ellipse.RX, ellipse.RY, 0, &p)
+		canvas.fillStroke(p, paintTransform(ellipse.Transform, ""), paintStyle{
+			Fill:          ellipse.Fill,
+			Stroke:        ellipse.Stroke,
+			FillOpacity:   ellipse.FillOpacity,
+			StrokeOpacity: ellipse.StrokeOpacity,
+			Opacity:       ellipse.Opacity,
+			StrokeWidth:   defaultFloat(ellipse.StrokeWidth, 1),
+		})
+	}
+	for _, img := range layout.Images {
+		canvas.image(img.Href, img.X, img.Y, img.W, img.H, rasterx.Identity)
+	}
+
+	if layout.Kind == DiagramFlowchart {
+		for _, edge := range layout.Edges {
+			paintFlowchartEdgeLabel(canvas, edge, theme, family)
+		}
+	}
+	for _, text := range layout.Texts {
+		if layout.Kind == DiagramFlowchart && strings.TrimSpace(text.Class) == "edgeLabel" {
+			continue
+		}
+		paintLayoutText(canvas, layout.Kind, text, theme, family, mermaidLike)
+	}
+	paintLayoutTitle(canvas, layout, theme, family)
+}
+
+func paintLayoutTitle(canvas layoutCanvas, layout Layout, theme Theme, family string) {
+	if layout.Title != "" {
+		canvas.text(paintText{
+			Lines:  [][]TextRun{{{Text: layout.Title}}},
+			X:      layout.TitleX,
+			Y:      layout.TitleY,
+			Anchor: "middle",
+			Family: family,
+			Size:   diagramTitleFontSize,
+			Color:  theme.PrimaryTextColor,
+		})
+	}
+}
+
+func paintLayoutPath(canvas layoutCanvas, path LayoutPath, markers map[string]svgMarker) {
+	style := paintStyle{
+		Fill:          path.Fill,
+		Stroke:        path.Stroke,
+		FillOpacity:   path.FillOpacity,
+		StrokeOpacity: path.StrokeOpacity,
+		Opacity:       path.Opacity,
+		StrokeWidth:   defaultFloat(path.StrokeWidth, 1),
+		DashArray:     path.DashArray,
+		LineCap:       path.LineCap,
+		LineJoin:      path.LineJoin,
+	}
+	// Inline styles from classDef and linkStyle override the attributes.
+	if value := styleValue(path.Style, "fill"); value != "" {
+		style.Fill = value
+	}
+	if value := styleValue(path.Style, "stroke"); value != "" {
+		style.Stroke = value
+	}
+	if value, ok := parseAnyFloat(styleValue(path.Style, "stroke-width")); ok {
+		style.StrokeWidth = value
+	}
+	if value := styleValue(path.Style, "stroke-dasharray"); value != "" {
+		style.DashArray = value
+	}
+	m := paintTransform(path.Transform, "")
+	canvas.fillStroke(compilePaintPath(path.D), m, style)
+
+	if marker := strings.TrimSpace(path.MarkerStart); marker != "" {
+		if x, y, angle, ok := pathEndpoint(path.D, true); ok {
+			paintMarker(canvas, markers[marker], style.Stroke, style.StrokeWidth, x, y, angle, true, m)
+		}
+	}
+	if marker := strings.TrimSpace(path.MarkerEnd); marker != "" {
+		if x, y, angle, ok := pathEndpoint(path.D, false); ok {
+			paintMarker(canvas, markers[marker], style.Stroke, style.StrokeWidth, x, y, angle, false, m)
+		}
+	}
+}
+
+func paintLayoutCircle(canvas layoutCanvas, circle LayoutCircle) {
+	var p rasterx.Path
+	rasterx.AddCircle(circle.CX, circle.CY, circle.R, &p)
+	canvas.fillStroke(p, paintTransform(circle.Transform, ""), paintStyle{
+		Fill:          circle.Fill,
+		Stroke:        circle.Stroke,
+		FillOpacity:   circle.FillOpacity,
+		StrokeOpacity: circle.StrokeOpacity,
+		Opacity:       circle.Opacity,
+		StrokeWidth:   defaultFloat(circle.StrokeWidth, 1),
+	})
+}
+
+// paintMarker draws marker at (x, y), oriented along angle radians the way
+// orient="auto" does, on a line of the given stroke color and width. The
+// viewBox is scaled to markerWidth, and markers in the default strokeWidth
+// units grow with the line.
+func paintMarker(canvas layoutCanvas, marker svgMarker, stroke string, lineWidth, x, y, angle float64, atStart bool, m rasterx.Matrix2D) {
+	if len(marker.Shapes) == 0 {
+		return
+	}
+	if atStart && (marker.Orient == "auto-start-reverse" || marker.ReverseAtStart) {
+		angle += math.Pi
+	}
+	scale := 1.0
+	if marker.ViewBox[2] > 0 {
+		scale = marker.Width / marker.ViewBox[2]
+	}
+	if marker.Units != "userSpaceOnUse" {
+		scale *= lineWidth
+	}
+	lineColor := stroke
+	if _, _, ok := parsePaintColor(lineColor); !ok {
+		lineColor = "#333333"
+	}
+	inherit := func(own, marker string) string {
+		if own != "" {
+			return own
+		}
+		return defaultColor(marker, lineColor)
+	}
+	place := m.Translate(x, y).Rotate(angle).Scale(scale, scale).Translate(-marker.RefX, -marker.RefY)
+	// Stroke widths are in layout units, so they scale here, not with place.
+	for _, shape := range marker.Shapes {
+		width := 1.0
+		if value, ok := parseAnyFloat(styleValue(shape.Style, "stroke-width")); ok {
+			width = value
+		}
+		canvas.fillStroke(compilePaintPath(shape.pathData()), place, paintStyle{
+			Fill:        inherit(shape.Fill, marker.Fill),
+			Stroke:      inherit(shape.Stroke, marker.Stroke),
+			StrokeWidth: width * scale,
+		})
+	}
+}
+
+func paintFlowchartEdgeLabel(canvas layoutCanvas, edge EdgeLayout, theme Theme, family string) {
+	label := strings.TrimSpace(edge.Label)
+	if edge.Style == EdgeInvisible || label == "" {
+		return
+	}
+	lines := edge.LabelLines
+	textW := max(1.0, measureTextWidth(label, false)+8)
+	if len(lines) > 0 {
+		textW = max(1.0, labelLinesWidth(lines, 16, "", false)+8)
+	} else {
+		lines = [][]TextRun{{{Text: label}}}
+	}
+	textH := 24.0 * float64(len(lines))
+	cx := (edge.X1 + edge.X2) / 2
+	cy := (edge.Y1+edge.Y2)/2 - 6
+	var box rasterx.Path
+	rasterx.AddRect(cx-textW/2, cy-textH/2, cx+textW/2, cy+textH/2, 0, &box)
+	canvas.fillStroke(box, rasterx.Identity, paintStyle{Fill: theme.EdgeLabelBackground})
+	canvas.text(paintText{
+		Lines:    lines,
+		X:        cx,
+		Y:        cy - textH/2 + 12,
+		Anchor:   "middle",
+		Baseline: "central",
+		LineStep: 24,
+		Family:   family,
+		Size:     16,
+		Color:    theme.TextColor,
+	})
+}
+
+// paintLayoutText places a layout text the way the SVG renderer does: HTML
+// labels are centred in a line box of 1.5em per line, plain SVG text sits on
+// its baseline.
+func paintLayoutText(canvas layoutCanvas, kind DiagramKind, text LayoutText, theme Theme, family string, mermaidLike bool) {
+	if strings.TrimSpace(text.Value) == "" && len(text.Lines) == 0 {
+		return
+	}
+	anchor := text.Anchor
+	if anchor == "" {
+		anchor = "start"
+	}
+	size := text.Size
+	if size <= 0 {
+		size = 13
+	}
+	if text.FontFamily != "" {
+		family = text.FontFamily
+	}
+	lines := text.Lines
+	if len(lines) == 0 {
+		bold := text.Weight == "bold" || text.Weight == "bolder"
+		if weight, err := strconv.Atoi(text.Weight); err == nil && weight >= 600 {
+			bold = true
+		}
+		for _, line := range splitLinesPreserve(text.Value) {
+			lines = append(lines, []TextRun{{Text: line, Bold: bold}})
+		}
+	}
+	label := paintText{
+		Lines:     lines,
+		X:         text.X,
+		Y:         text.Y,
+		Anchor:    anchor,
+		Family:    family,
+		Size:      size,
+		Color:     defaultColor(text.Color, theme.PrimaryTextColor),
+		Opacity:   text.Opacity,
+		Transform: parseSVGTransform(text.Transform),
+		LineStep:  max(14, size*1.2),
+	}
+	if !mermaidLike {
+		if len(text.Lines) > 0 || useTspanText(kind) || len(lines) > 1 {
+			label.Baseline = "middle"
+		} else {
+			label.Baseline = text.DominantBaseline
+		}
+		canvas.text(label)
+		return
+	}
+
+	lineBox := max(16.0, size*1.5)
+	textH := lineBox * float64(len(lines))
+	top := text.Y - textH*0.8
+	if kind == DiagramER || kind == DiagramFlowchart {
+		top = text.Y - textH/2
+		if len(text.Lines) > 1 {
+			top += float64(len(text.Lines)-1) * max(14, size*1.2) / 2
+		}
+	}
+	if kind == DiagramER && strings.TrimSpace(text.Class) == "edgeLabel" {
+		textW := max(1.0, measureTextWidthWithFontSize(text.Value, size, false, family))
+		x := text.X
+		switch anchor {
+		case "middle":
+			x -= textW / 2
+		case "end":
+			x -= textW
+		}
+		var box rasterx.Path
+		rasterx.AddRect(x, top, x+textW, top+textH, 0, &box)
+		canvas.fillStroke(box, rasterx.Identity, paintStyle{Fill: theme.EdgeLabelBackground})
+	}
+	label.Y = top + lineBox/2
+	label.LineStep = lineBox
+	label.Baseline = "central"
+	label.Color = defaultColor(text.Color, theme.TextColor)
+	canvas.text(label)
+}
+
+func compilePaintPath(d string) rasterx.Path {
+	if strings.TrimSpace(d) == "" {
+		return nil
+	}
+	var cursor oksvg.PathCursor
+	// A malformed tail still leaves the segments compiled before it.
+	_ = cursor.CompilePath(d)
+	return cursor.Path
+}
+
+func paintPoint(x, y float64) fixed.Point26_6 {
+	return fixed.Point26_6{X: fixed.Int26_6(math.Round(x * 64)), Y: fixed.Int26_6(math.Round(y * 64))}
+}
+
+// paintTransform converts an SVG transform attribute, applied about the
+// CSS transform-origin when one is given, into a rasterx matrix.
+func paintTransform(transform, origin string) rasterx.Matrix2D {
+	t := identitySVGTransform()
+	if strings.TrimSpace(transform) != "" {
+		t = parseSVGTransform(transform)
+	}
+	if fields := strings.Fields(origin); len(fields) == 2 {
+		ox, okX := parseAnyFloat(fields[0])
+		oy, okY := parseAnyFloat(fields[1])
+		if okX && okY {
+			t = svgAffineTransform{A: 1, D: 1, E: ox, F: oy}.
+				multiply(t).
+				multiply(svgAffineTransform{A: 1, D: 1, E: -ox, F: -oy})
+		}
+	}
+	return rasterx.Matrix2D{A: t.A, B: t.B, C: t.C, D: t.D, E: t.E, F: t.F}
+}
+
+// parsePaintColor resolves an SVG paint value to an opaque color and its
+// alpha. It reports false for none, transparent and paint servers.
+func parsePaintColor(raw string) (color.NRGBA, float64, bool) {
+	value := strings.ToLower(strings.TrimSpace(raw))
+	switch {
+	case value == "" || value == "none" || value == "transparent" || strings.HasPrefix(value, "url("):
+		return color.NRGBA{}, 0, false
+	case strings.HasPrefix(value, "rgba(") || strings.HasPrefix(value, "hsl"):
+		open := strings.IndexByte(value, '(')
+		parts := strings.FieldsFunc(strings.TrimSuffix(value[open+1:], ")"), func(r rune) bool {
+			return r == ',' || r == ' ' || r == '/'
+		})
+		if len(parts) < 3 {
+			return color.NRGBA{}, 0, false
+		}
+		nums := make([]float64, len(parts))
+		for i, part := range parts {
+			parsed, err := strconv.ParseFloat(strings.TrimSuffix(part, "%"), 64)
+			if err != nil {
+				return color.NRGBA{}, 0, false
+			}
+			nums[i] = parsed
+		}
+		alpha := 1.0
+		if len(nums) > 3 {
+			alpha = math.Max(0, math.Min(1, nums[3]))
+		}
+		if strings.HasPrefix(value, "hsl") {
+			r, g, b := hslToRGB(nums[0], nums[1], nums[2])
+			return color.NRGBA{R: paintChannel(r * 255), G: paintChannel(g * 255), B: paintChannel(b * 255), A: 255}, alpha, true
+		}
+		return color.NRGBA{R: paintChannel(nums[0]), G: paintChannel(nums[1]), B: paintChannel(nums[2]), A: 255}, alpha, true
+	}
+	parsed, err := oksvg.ParseSVGColor(value)
+	if err != nil || parsed == nil {
+		return color.NRGBA{}, 0, false
+	}
+	r, g, b, _ := parsed.RGBA()
+	return color.NRGBA{R: uint8(r >> 8), G: uint8(g >> 8), B: uint8(b >> 8), A: 255}, 1, true
+}
+
+func paintChannel(v float64) uint8 {
+	return uint8(math.Round(math.Max(0, math.Min(255, v))))
+}
+
+// paintOpacity treats zero as "unset", like the SVG writer does.
+func paintOpacity(v float64) float64 {
+	if v > 0 && v < 1 {
+		return v
+	}
+	return 1
+}
+
+func parseDashArray(raw string, scale float64) []float64 {
+	fields := strings.FieldsFunc(raw, func(r rune) bool { return r == ',' || r == ' ' })
+	dashes := make([]float64, 0, len(fields))
+	total := 0.0
+	for _, field := range fields {
+		v, ok := parseAnyFloat(field)
+		if !ok || v < 0 {
+			return nil
+		}
+		dashes = append(dashes, v*scale)
+		total += v
+	}
+	if total <= 0 {
+		return nil
+	}
+	if len(dashes)%2 == 1 {
+		dashes = append(dashes, dashes...)
+	}
+	return dashes
+}
+
+// rasterCanvas paints onto a draw.Image through rasterx, mapping layout
+// units to pixels with base.
+type rasterCanvas struct {
+	dst    draw.Image
+	base   rasterx.Matrix2D
+	scale  float64
+	dasher *rasterx.Dasher
+}
+
+func (c *rasterCanvas) fillStroke(path rasterx.Path, m rasterx.Matrix2D, style paintStyle) {
+	if len(path) == 0 {
+		return
+	}
+	full := c.base.Mult(m)
+	opacity := paintOpacity(style.Opacity)
+	if fill, alpha, ok := parsePaintColor(style.Fill); ok {
+		c.dasher.Clear()
+		filler := &c.dasher.Filler
+		filler.SetWinding(!style.EvenOdd)
+		path.AddTo(&rasterx.MatrixAdder{Adder: filler, M: full})
+		filler.SetColor(rasterx.ApplyOpacity(fill, alpha*paintOpacity(style.FillOpacity)*opacity))
+		filler.Draw()
+		filler.SetWinding(true)
+	}
+	stroke, alpha, ok := parsePaintColor(style.Stroke)
+	if !ok || style.StrokeWidth <= 0 {
+		return
+	}
+	capFn := rasterx.ButtCap
+	switch strings.TrimSpace(style.LineCap) {
+	case "round":
+		capFn = rasterx.RoundCap
+	case "square":
+		capFn = rasterx.SquareCap
+	}
+	join := rasterx.MiterClip
+	switch strings.TrimSpace(style.LineJoin) {
+	case "round":
+		join = rasterx.Round
+	case "bevel":
+		join = rasterx.Bevel
+	}
+	c.dasher.Clear()
+	c.dasher.SetStroke(
+		fixed.Int26_6(style.StrokeWidth*c.scale*64),
+		fixed.Int26_6(4*64),
+		capFn, capFn, rasterx.FlatGap, join,
+		parseDashArray(style.DashArray, c.scale), 0,
+	)
+	path.AddTo(&rasterx.MatrixAdder{Adder: c.dasher, M: full})
+	c.dasher.SetColor(rasterx.ApplyOpacity(stroke, alpha*paintOpacity(style.StrokeOpacity)*opacity))
+	c.dasher.Draw()
+}
+
+func (c *rasterCanvas) text(label paintText) {
+	fill, alpha, ok := parsePaintColor(label.Color)
+	if !ok || len(label.Lines) == 0 {
+		return
+	}
+	textColor := rasterx.ApplyOpacity(fill, alpha*paintOpacity(label.Opacity))
+	size := max(1.0, label.Size*c.scale)
+	face := resolveRasterFontFace(label.Family, size)
+	offset := svgTextBaselineOffset(face, label.Baseline)
+	transform := label.Transform
+	if transform == (svgAffineTransform{}) {
+		transform = identitySVGTransform()
+	}
+	angle := transform.rotationDegrees()
+	drawer := &font.Drawer{Dst: c.dst, Src: image.NewUniform(textColor), Face: face}
+	for i, line := range label.Lines {
+		x, y := transform.apply(label.X, label.Y+float64(i)*label.LineStep)
+		px, py := c.base.Transform(x, y)
+		shift := 0.0
+		switch label.Anchor {
+		case "middle":
+			shift = labelLineAdvance(line, label.Family, size) / 2
+		case "end":
+			shift = labelLineAdvance(line, label.Family, size)
+		}
+		if math.Abs(angle) > 0.01 {
+			var content strings.Builder
+			for _, run := range line {
+				content.WriteString(run.Text)
+			}
+			overlayRotatedText(c.dst, displayText(content.String()), face, textColor, px, py, angle, -shift, offset)
+			continue
+		}
+		drawLabelLine(drawer, line, label.Family, size, px-shift, py+offset)
+	}
+}
+
+func (c *rasterCanvas) image(href string, x, y, w, h float64, m rasterx.Matrix2D) {
+	if w <= 0 || h <= 0 {
+		return
+	}
+	full := c.base.Mult(m)
+	x0, y0 := full.Transform(x, y)
+	x1, y1 := full.Transform(x+w, y+h)
+	drawDataURIImage(c.dst, href, min(x0, x1), min(y0, y1), max(x0, x1), max(y0, y1))
+}
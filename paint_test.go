@@ -0,0 +1,191 @@
+package mermaid
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/srwiley/rasterx"
+)
+
+func TestRenderImagePaintsFlowchartLayout(t *testing.T) {
+	input := "flowchart LR\n  A[Start] -->|go| B[End]\n  classDef hot fill:#ff0000,stroke:#0000ff\n  class B hot"
+	img, err := RenderImage(input, DefaultRenderOptions())
+	if err != nil {
+		t.Fatalf("RenderImage() error = %v", err)
+	}
+	svg, err := Render(input)
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	wantW, wantH := detectSVGSize(svg)
+	if got := img.Bounds().Size(); got.X != wantW || got.Y != wantH {
+		t.Fatalf("image size = %v, want %dx%d", got, wantW, wantH)
+	}
+
+	var red, edge int
+	bounds := img.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			r, g, b, _ := rgba8At(img, x, y)
+			if nearRGB(r, g, b, 255, 0, 0, 8) {
+				red++
+			}
+			if nearRGB(r, g, b, 0x33, 0x33, 0x33, 24) {
+				edge++
+			}
+		}
+	}
+	if red < 100 {
+		t.Fatalf("expected classDef fill to be painted, got %d red pixels", red)
+	}
+	if edge == 0 {
+		t.Fatal("expected edge stroke pixels in painted flowchart")
+	}
+}
+
+func TestPaintLayoutStaysInsideDestinationBounds(t *testing.T) {
+	parsed, err := ParseMermaid("pie title Pets\n  \"Dogs\" : 3\n  \"Cats\" : 1")
+	if err != nil {
+		t.Fatalf("ParseMermaid() error = %v", err)
+	}
+	options := DefaultRenderOptions()
+	theme := options.Theme
+	layout := ComputeLayout(&parsed.Graph, theme, options.Layout)
+
+	canvas := image.NewNRGBA(image.Rect(0, 0, 400, 400))
+	draw.Draw(canvas, canvas.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	target := image.Rect(100, 100, 300, 300)
+	PaintLayout(canvas.SubImage(target).(draw.Image), layout, theme)
+
+	inside := 0
+	for y := 0; y < 400; y++ {
+		for x := 0; x < 400; x++ {
+			r, g, b, _ := rgba8At(canvas, x, y)
+			white := r == 255 && g == 255 && b == 255
+			if image.Pt(x, y).In(target) {
+				if !white {
+					inside++
+				}
+			} else if !white {
+				t.Fatalf("pixel (%d,%d) outside the target was painted rgb(%d,%d,%d)", x, y, r, g, b)
+			}
+		}
+	}
+	if inside == 0 {
+		t.Fatal("expected the pie to be painted inside the target")
+	}
+}
+
+func TestRenderImagePaintsSequenceClassAndStateLayouts(t *testing.T) {
+	inputs := []string{
+		"sequenceDiagram\n  Alice->>Bob: Hi\n  Note over Alice,Bob: later",
+		"classDiagram\n  class Animal {\n    +String name\n  }\n  Animal *-- Leg",
+		"stateDiagram-v2\n  [*] --> Still\n  Still --> Moving : push",
+	}
+	for _, input := range inputs {
+		parsed, err := ParseMermaid(input)
+		if err != nil {
+			t.Fatalf("ParseMermaid() error = %v", err)
+		}
+		if !canPaintLayout(parsed.Graph.Kind) {
+			t.Fatalf("%s layouts should be painted", parsed.Graph.Kind)
+		}
+		img, err := RenderImage(input, DefaultRenderOptions())
+		if err != nil {
+			t.Fatalf("RenderImage(%s) error = %v", parsed.Graph.Kind, err)
+		}
+		var box, line int
+		bounds := img.Bounds()
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				r, g, b, _ := rgba8At(img, x, y)
+				if nearRGB(r, g, b, 0xEC, 0xEC, 0xFF, 4) {
+					box++
+				}
+				if nearRGB(r, g, b, 0x33, 0x33, 0x33, 24) {
+					line++
+				}
+			}
+		}
+		if box < 100 || line == 0 {
+			t.Fatalf("%s: got %d box and %d line pixels", parsed.Graph.Kind, box, line)
+		}
+	}
+}
+
+type recordingCanvas struct {
+	fills []rasterx.Matrix2D
+}
+
+func (c *recordingCanvas) fillStroke(path rasterx.Path, m rasterx.Matrix2D, style paintStyle) {
+	c.fills = append(c.fills, m)
+}
+
+func (c *recordingCanvas) text(paintText) {}
+
+func (c *recordingCanvas) image(string, float64, float64, float64, float64, rasterx.Matrix2D) {}
+
+func TestPaintMarkerPlacesRefPointOnLineEnd(t *testing.T) {
+	barb := stateMarkers()[0]
+	var canvas recordingCanvas
+	paintMarker(&canvas, barb, "#333333", 1, 40, 30, math.Pi/2, false, rasterx.Identity)
+	if len(canvas.fills) != len(barb.Shapes) {
+		t.Fatalf("painted %d shapes, want %d", len(canvas.fills), len(barb.Shapes))
+	}
+	x, y := canvas.fills[0].Transform(barb.RefX, barb.RefY)
+	if math.Abs(x-40) > 1e-9 || math.Abs(y-30) > 1e-9 {
+		t.Fatalf("ref point painted at (%v, %v), want (40, 30)", x, y)
+	}
+	// The barb points down the line, so its tail sits above the end.
+	if _, tailY := canvas.fills[0].Transform(9, 7); tailY >= 30 {
+		t.Fatalf("barb tail at y=%v, want above the line end", tailY)
+	}
+}
+
+func TestWritePNGFromSourceFallsBackForUnpaintedKinds(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gantt.png")
+	if err := WritePNGFromSource("gantt\n  title Plan\n  section Build\n  Task :a1, 2024-01-01, 3d", path); err != nil {
+		t.Fatalf("WritePNGFromSource() error = %v", err)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read png file: %v", err)
+	}
+	img, err := png.Decode(bytes.NewReader(content))
+	if err != nil {
+		t.Fatalf("decode gantt png: %v", err)
+	}
+	if img.Bounds().Empty() {
+		t.Fatal("expected a non-empty PNG")
+	}
+}
+
+func TestParsePaintColor(t *testing.T) {
+	tests := []struct {
+		raw   string
+		want  color.NRGBA
+		alpha float64
+		ok    bool
+	}{
+		{raw: "#ff8800", want: color.NRGBA{R: 255, G: 136, B: 0, A: 255}, alpha: 1, ok: true},
+		{raw: "red", want: color.NRGBA{R: 255, A: 255}, alpha: 1, ok: true},
+		{raw: "rgba(232,232,232, 0.8)", want: color.NRGBA{R: 232, G: 232, B: 232, A: 255}, alpha: 0.8, ok: true},
+		{raw: "hsl(240, 100%, 50%)", want: color.NRGBA{B: 255, A: 255}, alpha: 1, ok: true},
+		{raw: "none"},
+		{raw: "transparent"},
+		{raw: "url(#gradient)"},
+	}
+	for _, tt := range tests {
+		got, alpha, ok := parsePaintColor(tt.raw)
+		if ok != tt.ok || got != tt.want || alpha != tt.alpha {
+			t.Errorf("parsePaintColor(%q) = %v, %v, %v; want %v, %v, %v", tt.raw, got, alpha, ok, tt.want, tt.alpha, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,333 @@
+package mermaid
+
+import (
+	"math"
+	"strings"
+
+	"github.com/srwiley/rasterx"
+)
+
+// The sequence, class and state SVG writers take most of their colors from
+// the diagram stylesheet rather than from the layout, so their painters
+// mirror the writers and use the colors the stylesheet resolves to.
+
+func paintSequenceLayout(canvas layoutCanvas, layout Layout, theme Theme, markers map[string]svgMarker, family string) {
+	participants, labels, plan := layoutSequencePlan(layout, theme)
+	actorStyle := paintStyle{Fill: "#ECECFF", Stroke: "#9370DB", StrokeWidth: 1}
+	actor := func(participant string, y float64) {
+		label := participant
+		if named, ok := labels[participant]; ok && strings.TrimSpace(named) != "" {
+			label = named
+		}
+		paintRect(canvas, LayoutRect{X: plan.ParticipantLeft[participant], Y: y, W: plan.ParticipantWidth[participant], H: sequenceActorHeight, RX: 3, RY: 3}, actorStyle)
+		canvas.text(paintText{
+			Lines:    plainLabelLines(label),
+			X:        plan.ParticipantCenter[participant],
+			Y:        y + sequenceActorHeight/2,
+			Anchor:   "middle",
+			Baseline: "central",
+			Family:   family,
+			Size:     sequenceMessageFontSize,
+			Color:    "black",
+		})
+	}
+	for i := len(participants) - 1; i >= 0; i-- {
+		actor(participants[i], plan.BottomY)
+	}
+	for i := len(participants) - 1; i >= 0; i-- {
+		center := plan.ParticipantCenter[participants[i]]
+		paintLayoutPath(canvas, LayoutPath{
+			D:           linePathData(center, sequenceActorHeight, center, plan.LifelineEndY),
+			Stroke:      "#999",
+			StrokeWidth: 1,
+			DashArray:   "2,2",
+		}, nil)
+		actor(participants[i], 0)
+	}
+
+	for _, activation := range plan.ActivationLayouts {
+		paintRect(canvas, LayoutRect{X: activation.X, Y: activation.Y, W: activation.W, H: activation.H},
+			paintStyle{Fill: "#f4f4f4", Stroke: "#666", StrokeWidth: 1})
+	}
+
+	loopLine := func(x1, y1, x2, y2 float64, dash string) {
+		paintLayoutPath(canvas, LayoutPath{D: linePathData(x1, y1, x2, y2), Stroke: "#9370DB", StrokeWidth: 2, DashArray: dash}, nil)
+	}
+	loopText := func(value string, x, y float64) {
+		canvas.text(paintText{
+			Lines:  plainLabelLines(value),
+			X:      x,
+			Y:      y,
+			Anchor: "middle",
+			Family: family,
+			Size:   sequenceMessageFontSize,
+			Color:  "black",
+		})
+	}
+	for _, loop := range plan.LoopLayouts {
+		loopLine(loop.StartX, loop.StartY, loop.StopX, loop.StartY, "2,2")
+		loopLine(loop.StopX, loop.StartY, loop.StopX, loop.StopY, "2,2")
+		loopLine(loop.StartX, loop.StopY, loop.StopX, loop.StopY, "2,2")
+		loopLine(loop.StartX, loop.StartY, loop.StartX, loop.StopY, "2,2")
+		for _, section := range loop.Sections {
+			loopLine(loop.StartX, section.Y, loop.StopX, section.Y, "3,3")
+		}
+		var box rasterx.Path
+		box.Start(paintPoint(loop.StartX, loop.StartY))
+		box.Line(paintPoint(loop.StartX+50, loop.StartY))
+		box.Line(paintPoint(loop.StartX+50, loop.StartY+13))
+		box.Line(paintPoint(loop.StartX+41.6, loop.StartY+20))
+		box.Line(paintPoint(loop.StartX, loop.StartY+20))
+		box.Stop(true)
+		canvas.fillStroke(box, rasterx.Identity, paintStyle{Fill: "#ECECFF", Stroke: "#9370DB", StrokeWidth: 1})
+		canvas.text(paintText{
+			Lines:    plainLabelLines(loop.Kind),
+			X:        loop.StartX + 25,
+			Y:        loop.StartY + 13,
+			Anchor:   "middle",
+			Baseline: "middle",
+			Family:   family,
+			Size:     sequenceMessageFontSize,
+			Color:    "black",
+		})
+		midX := (loop.StartX + loop.StopX) / 2
+		if strings.TrimSpace(loop.Title) != "" {
+			loopText("["+loop.Title+"]", midX, loop.StartY+18)
+		}
+		for _, section := range loop.Sections {
+			if strings.TrimSpace(section.Label) != "" {
+				loopText("["+section.Label+"]", midX, section.Y+18)
+			}
+		}
+	}
+
+	for _, msg := range plan.MessageLayouts {
+		// The labels are middle-aligned one em below TextY (dy="1em").
+		label := paintText{
+			Lines:    plainLabelLines(msg.Message.Label),
+			X:        math.Round((msg.StartX + msg.StopX) / 2),
+			Y:        msg.TextY + sequenceMessageFontSize,
+			Anchor:   "middle",
+			Baseline: "middle",
+			Family:   family,
+			Size:     sequenceMessageFontSize,
+			Color:    "#333",
+		}
+		if msg.Note {
+			paintRect(canvas, LayoutRect{X: msg.StartX, Y: msg.LineY, W: msg.StopX - msg.StartX, H: msg.Height},
+				paintStyle{Fill: "#fff5ad", Stroke: "#aaaa33", StrokeWidth: 1})
+			label.Color = "black"
+			canvas.text(label)
+			continue
+		}
+		canvas.text(label)
+		line := LayoutPath{
+			D:           linePathData(msg.StartX, msg.LineY, msg.StopX, msg.LineY),
+			Stroke:      "#333",
+			StrokeWidth: 1.5,
+			MarkerEnd:   "my-svg-arrowhead",
+		}
+		if msg.Dashed {
+			line.DashArray = "3,3"
+		}
+		if msg.Self {
+			line.D = "M " + formatFloat(msg.StartX) + "," + formatFloat(msg.LineY) +
+				" C " + formatFloat(msg.StartX+60) + "," + formatFloat(msg.LineY-10) +
+				" " + formatFloat(msg.StartX+60) + "," + formatFloat(msg.LineY+30) +
+				" " + formatFloat(msg.StartX) + "," + formatFloat(msg.LineY+20)
+		}
+		paintLayoutPath(canvas, line, markers)
+	}
+}
+
+func paintClassLayout(canvas layoutCanvas, layout Layout, theme Theme, markers map[string]svgMarker, family string) {
+	for _, line := range layout.Lines {
+		if !strings.Contains(line.Class, "relation") {
+			continue
+		}
+		relation := LayoutPath{
+			D:           linePathData(line.X1, line.Y1, line.X2, line.Y2),
+			Stroke:      "#333333",
+			StrokeWidth: 1,
+			MarkerStart: line.MarkerStart,
+			MarkerEnd:   line.MarkerEnd,
+		}
+		switch {
+		case strings.Contains(line.Class, "dotted-line"):
+			relation.DashArray = "1,2"
+		case strings.Contains(line.Class, "dashed-line"):
+			relation.DashArray = "3"
+		}
+		paintLayoutPath(canvas, relation, markers)
+	}
+
+	boxStyle := paintStyle{Fill: "#ECECFF", Stroke: "#9370DB", StrokeWidth: 1}
+	for _, rect := range layout.Rects {
+		if rect.W <= 0 || rect.H <= 0 {
+			continue
+		}
+		cx := rect.X + rect.W/2
+		cy := rect.Y + rect.H/2
+		paintRect(canvas, LayoutRect{X: rect.X, Y: rect.Y, W: rect.W, H: rect.H}, boxStyle)
+
+		// The title is the topmost text inside the box, as in the writer.
+		title := ""
+		bestY := math.MaxFloat64
+		for _, text := range layout.Texts {
+			if strings.TrimSpace(text.Class) == "class-edge-label" {
+				continue
+			}
+			if text.X < rect.X-1 || text.X > rect.X+rect.W+1 || text.Y < rect.Y-5 || text.Y > rect.Y+rect.H+5 {
+				continue
+			}
+			if text.Y < bestY {
+				bestY = text.Y
+				title = strings.TrimSpace(text.Value)
+			}
+		}
+		canvas.text(paintText{
+			Lines:    [][]TextRun{{{Text: title, Bold: true}}},
+			X:        cx,
+			Y:        cy - 18,
+			Anchor:   "middle",
+			Baseline: "central",
+			Family:   family,
+			Size:     16,
+			Color:    theme.PrimaryTextColor,
+		})
+		for _, text := range layout.Texts {
+			if text.Class != "class-member-"+rect.ID && text.Class != "class-method-"+rect.ID {
+				continue
+			}
+			// Member rows are 14 tall boxes starting 10 above the text.
+			canvas.text(paintText{
+				Lines:    plainLabelLines(text.Value),
+				X:        rect.X + 10,
+				Y:        text.Y - 3,
+				Anchor:   "start",
+				Baseline: "central",
+				Family:   family,
+				Size:     defaultFloat(text.Size, 16),
+				Color:    theme.PrimaryTextColor,
+			})
+		}
+		for _, y := range []float64{cy + 6, cy + 24} {
+			paintLayoutPath(canvas, LayoutPath{D: linePathData(rect.X, y, rect.X+rect.W, y), Stroke: "#9370DB", StrokeWidth: 1}, nil)
+		}
+	}
+}
+
+func paintStateLayout(canvas layoutCanvas, layout Layout, theme Theme, markers map[string]svgMarker, family string) {
+	nodeFill := defaultColor(theme.PrimaryColor, "#ECECFF")
+	nodeStroke := defaultColor(theme.PrimaryBorderColor, "#9370DB")
+	edgeStroke := defaultColor(theme.LineColor, "#333333")
+
+	hasCompositeCluster := false
+	for _, rect := range layout.Rects {
+		if strings.TrimSpace(rect.Class) != "cluster" {
+			continue
+		}
+		hasCompositeCluster = true
+		stroke := defaultColor(rect.Stroke, nodeStroke)
+		width := defaultFloat(rect.StrokeWidth, 1)
+		// The stylesheet squares the inner rect and fills it white.
+		paintRect(canvas, LayoutRect{X: rect.X, Y: rect.Y, W: rect.W, H: rect.H, RX: max(4, rect.RX), RY: max(4, rect.RY)},
+			paintStyle{Fill: defaultColor(rect.Fill, nodeFill), Stroke: stroke, StrokeWidth: width})
+		paintRect(canvas, LayoutRect{X: rect.X, Y: rect.Y, W: rect.W, H: rect.H},
+			paintStyle{Fill: "white", Stroke: stroke, StrokeWidth: width})
+	}
+	for _, text := range layout.Texts {
+		if strings.TrimSpace(text.Class) != "cluster-label" || strings.TrimSpace(text.Value) == "" {
+			continue
+		}
+		canvas.text(paintText{
+			Lines:    plainLabelLines(text.Value),
+			X:        text.X,
+			Y:        text.Y,
+			Anchor:   "middle",
+			Baseline: "central",
+			Family:   "'trebuchet ms', verdana, arial, sans-serif",
+			Size:     15,
+			Color:    "#333333",
+		})
+	}
+
+	for _, edge := range layout.Edges {
+		path := LayoutPath{D: strings.TrimSpace(edge.D), Stroke: edgeStroke, StrokeWidth: 1}
+		if path.D == "" {
+			path.D = linePathData(edge.X1, edge.Y1, edge.X2, edge.Y2)
+		}
+		if edge.ArrowEnd || edge.From != "" {
+			path.MarkerEnd = "my-svg_stateDiagram-barbEnd"
+		}
+		paintLayoutPath(canvas, path, markers)
+	}
+	for _, edge := range layout.Edges {
+		label := strings.TrimSpace(edge.Label)
+		if label == "" {
+			continue
+		}
+		textW := max(1.0, measureTextWidth(label, false)+8)
+		cx := (edge.X1 + edge.X2) / 2
+		cy := (edge.Y1+edge.Y2)/2 - 6
+		paintRect(canvas, LayoutRect{X: cx - textW/2, Y: cy - 12, W: textW, H: 24}, paintStyle{Fill: theme.EdgeLabelBackground})
+		canvas.text(paintText{
+			Lines:    plainLabelLines(label),
+			X:        cx,
+			Y:        cy,
+			Anchor:   "middle",
+			Baseline: "central",
+			Family:   family,
+			Size:     16,
+			Color:    theme.PrimaryTextColor,
+		})
+	}
+
+	for _, node := range layout.Nodes {
+		cx := node.X + node.W/2
+		cy := node.Y + node.H/2
+		switch node.Shape {
+		case ShapeCircle:
+			paintLayoutCircle(canvas, LayoutCircle{CX: cx, CY: cy, R: 7, Fill: edgeStroke, Stroke: edgeStroke})
+		case ShapeDoubleCircle:
+			paintLayoutCircle(canvas, LayoutCircle{CX: cx, CY: cy, R: 7, Fill: nodeStroke, Stroke: "white", StrokeWidth: 1.5})
+			paintLayoutCircle(canvas, LayoutCircle{CX: cx, CY: cy, R: 2.5, Fill: "white", Stroke: "white", StrokeWidth: 1.5})
+		case ShapeDiamond:
+			var p rasterx.Path
+			p.Start(paintPoint(cx, cy-node.H/2))
+			p.Line(paintPoint(cx+node.W/2, cy))
+			p.Line(paintPoint(cx, cy+node.H/2))
+			p.Line(paintPoint(cx-node.W/2, cy))
+			p.Stop(true)
+			canvas.fillStroke(p, rasterx.Identity, paintStyle{Fill: nodeFill, Stroke: nodeStroke, StrokeWidth: 1.8})
+		case ShapeHidden:
+		default:
+			paintRect(canvas, LayoutRect{X: node.X, Y: node.Y, W: node.W, H: node.H, RX: 5, RY: 5},
+				paintStyle{Fill: nodeFill, Stroke: nodeStroke, StrokeWidth: 1})
+		}
+	}
+	for _, node := range layout.Nodes {
+		label := strings.TrimSpace(node.Label)
+		if label == "" || hasCompositeCluster && node.Shape == ShapeDiamond {
+			continue
+		}
+		canvas.text(paintText{
+			Lines:    plainLabelLines(label),
+			X:        node.X + node.W/2,
+			Y:        node.Y + node.H/2,
+			Anchor:   "middle",
+			Baseline: "central",
+			Family:   family,
+			Size:     16,
+			Color:    theme.PrimaryTextColor,
+		})
+	}
+}
+
+func paintRect(canvas layoutCanvas, rect LayoutRect, style paintStyle) {
+	canvas.fillStroke(compilePaintPath(rectToPath(rect)), rasterx.Identity, style)
+}
+
+func linePathData(x1, y1, x2, y2 float64) string {
+	return "M" + formatFloat(x1) + "," + formatFloat(y1) + " L" + formatFloat(x2) + "," + formatFloat(y2)
+}
@@ -10,8 +10,19 @@ type ParseOutput struct {
 }
 
 func ParseMermaid(input string) (ParseOutput, error) {
-	kind := detectDiagramKind(input)
+	out, err := parseByKind(input, detectDiagramKind(input))
+	if err != nil {
+		return ParseOutput{}, err
+	}
+	out.Graph.Config = parseDiagramConfig(input)
+	out.Graph.AccTitle, out.Graph.AccDescr = parseAccessibility(input)
+	if title := parseFrontMatterTitle(input); title != "" {
+		out.Graph.setTitle(title)
+	}
+	return out, nil
+}
 
+func parseByKind(input string, kind DiagramKind) (ParseOutput, error) {
 	switch kind {
 	case DiagramFlowchart:
 		return parseFlowchart(input)
@@ -136,12 +147,35 @@ func preprocessInputKeepIndent(input string) ([]string, error) {
 }
 
 func preprocessRawLines(input string, keepIndent bool) ([]string, error) {
-	lines := make([]string, 0, 64)
+	numbered, err := preprocessNumberedLines(input, keepIndent)
+	if err != nil {
+		return nil, err
+	}
+	lines := make([]string, len(numbered))
+	for i, line := range numbered {
+		lines[i] = line.Text
+	}
+	return lines, nil
+}
+
+// sourceLine is a preprocessed line with its 1-based line number in the
+// original input, for errors that point back at the source.
+type sourceLine struct {
+	Text string
+	No   int
+}
+
+// preprocessNumberedLines drops front matter, directives, comments, blank
+// and accessibility lines like preprocessInput, keeping the source line
+// number of every line it returns.
+func preprocessNumberedLines(input string, keepIndent bool) ([]sourceLine, error) {
+	lines := make([]sourceLine, 0, 64)
 	inDirectiveBlock := false
 	inFrontMatter := false
+	inAccDescr := false
 	canStartFrontMatter := true
 
-	for _, raw := range strings.Split(input, "\n") {
+	for idx, raw := range strings.Split(input, "\n") {
 		trimmed := strings.TrimSpace(raw)
 
 		if inFrontMatter {
@@ -162,6 +196,16 @@ func preprocessRawLines(input string, keepIndent bool) ([]string, error) {
 			continue
 		}
 
+		// Accessibility statements are read by parseAccessibility.
+		if inAccDescr {
+			inAccDescr = !strings.Contains(trimmed, "}")
+			continue
+		}
+		if _, _, open, ok := parseAccessibilityLine(trimmed); ok {
+			inAccDescr = open
+			continue
+		}
+
 		if canStartFrontMatter && trimmed == "---" {
 			inFrontMatter = true
 			canStartFrontMatter = false
@@ -184,7 +228,7 @@ func preprocessRawLines(input string, keepIndent bool) ([]string, error) {
 			if strings.TrimSpace(withoutComment) == "" {
 				continue
 			}
-			lines = append(lines, withoutComment)
+			lines = append(lines, sourceLine{Text: withoutComment, No: idx + 1})
 			continue
 		}
 
@@ -192,7 +236,7 @@ func preprocessRawLines(input string, keepIndent bool) ([]string, error) {
 		if trimmed == "" {
 			continue
 		}
-		lines = append(lines, trimmed)
+		lines = append(lines, sourceLine{Text: trimmed, No: idx + 1})
 	}
 
 	if len(lines) == 0 {
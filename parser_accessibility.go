@@ -0,0 +1,62 @@
+package mermaid
+
+import "strings"
+
+// parseAccessibilityLine recognizes `accTitle: ...`, `accDescr: ...` and the
+// opening line of an `accDescr { ... }` block. For a block, value holds the
+// text after the brace and open reports whether the block continues on the
+// following lines.
+func parseAccessibilityLine(trimmed string) (key, value string, open, ok bool) {
+	low := lower(trimmed)
+	for _, candidate := range []string{"acctitle", "accdescr"} {
+		if !strings.HasPrefix(low, candidate) {
+			continue
+		}
+		rest := strings.TrimSpace(trimmed[len(candidate):])
+		switch {
+		case strings.HasPrefix(rest, ":"):
+			return candidate, strings.TrimSpace(rest[1:]), false, true
+		case candidate == "accdescr" && strings.HasPrefix(rest, "{"):
+			body := rest[1:]
+			if end := strings.Index(body, "}"); end >= 0 {
+				return candidate, strings.TrimSpace(body[:end]), false, true
+			}
+			return candidate, strings.TrimSpace(body), true, true
+		}
+	}
+	return "", "", false, false
+}
+
+// parseAccessibility extracts the accessible title and description of a
+// diagram. Multi-line `accDescr { ... }` blocks keep their line breaks.
+func parseAccessibility(input string) (title, descr string) {
+	var block []string
+	inBlock := false
+	for _, raw := range strings.Split(input, "\n") {
+		trimmed := strings.TrimSpace(raw)
+		if inBlock {
+			if end := strings.Index(trimmed, "}"); end >= 0 {
+				block = append(block, strings.TrimSpace(trimmed[:end]))
+				descr = strings.TrimSpace(strings.Join(block, "\n"))
+				inBlock = false
+				continue
+			}
+			block = append(block, trimmed)
+			continue
+		}
+		key, value, open, ok := parseAccessibilityLine(trimmed)
+		if !ok {
+			continue
+		}
+		switch {
+		case key == "acctitle":
+			title = value
+		case open:
+			inBlock = true
+			block = []string{value}
+		default:
+			descr = value
+		}
+	}
+	return title, descr
+}
@@ -0,0 +1,60 @@
+package mermaid
+
+import "testing"
+
+func TestParseAccessibilityAllKinds(t *testing.T) {
+	cases := []struct {
+		name  string
+		input string
+		nodes int
+	}{
+		{"flowchart", "flowchart LR\n  accTitle: Title\n  accDescr: One line\n  A --> B", 2},
+		{"sequence", "sequenceDiagram\n  accTitle: Title\n  accDescr: One line\n  Alice->>Bob: hi", 2},
+		{"pie", "pie\n  accTitle: Title\n  accDescr: One line\n  \"Dogs\" : 3", 0},
+		{"state", "stateDiagram-v2\n  accTitle: Title\n  accDescr: One line\n  [*] --> Idle", -1},
+		{"class", "classDiagram\n  accTitle: Title\n  accDescr: One line\n  Animal <|-- Dog", 2},
+	}
+	for _, tc := range cases {
+		parsed, err := ParseMermaid(tc.input)
+		if err != nil {
+			t.Fatalf("%s: ParseMermaid() error = %v", tc.name, err)
+		}
+		g := parsed.Graph
+		if g.AccTitle != "Title" || g.AccDescr != "One line" {
+			t.Fatalf("%s: accTitle/accDescr = %q/%q", tc.name, g.AccTitle, g.AccDescr)
+		}
+		for _, id := range []string{"accTitle", "accDescr"} {
+			if _, ok := g.Nodes[id]; ok {
+				t.Fatalf("%s: accessibility statement parsed as node %q", tc.name, id)
+			}
+		}
+		if tc.nodes >= 0 && len(g.NodeOrder) != tc.nodes {
+			t.Fatalf("%s: node count = %d, want %d", tc.name, len(g.NodeOrder), tc.nodes)
+		}
+	}
+}
+
+func TestParseAccessibilityDescriptionBlock(t *testing.T) {
+	input := `flowchart TB
+  accDescr {
+    First line
+    second line
+  }
+  A --> B
+  accDescr{ inline block }`
+	title, descr := parseAccessibility(input)
+	if title != "" || descr != "inline block" {
+		t.Fatalf("parseAccessibility = %q/%q, want the last description", title, descr)
+	}
+	_, descr = parseAccessibility(input[:len(input)-len("\n  accDescr{ inline block }")])
+	if descr != "First line\nsecond line" {
+		t.Fatalf("block description = %q", descr)
+	}
+	parsed, err := ParseMermaid(input)
+	if err != nil {
+		t.Fatalf("ParseMermaid() error = %v", err)
+	}
+	if len(parsed.Graph.NodeOrder) != 2 {
+		t.Fatalf("expected only A and B, got %v", parsed.Graph.NodeOrder)
+	}
+}
@@ -2,6 +2,7 @@ package mermaid
 
 import (
 	"regexp"
+	"strconv"
 	"strings"
 )
 
@@ -13,6 +14,8 @@ var (
 	simpleArrowRe = regexp.MustCompile(`^(.+?)\s*(` + arrowPattern + `)\s*(.+)$`)
 	nodeMetaShape = regexp.MustCompile(`(?i)\bshape\s*:\s*([a-z0-9_-]+)`)
 	nodeMetaLabel = regexp.MustCompile(`(?i)\blabel\s*:\s*("([^"\\]|\\.)*"|'([^'\\]|\\.)*'|[^,}]+)`)
+	edgeIDRe      = regexp.MustCompile(`(?:^|\s)([A-Za-z0-9_]+)@$`)
+	nodeMetaField = regexp.MustCompile(`(?i)\b([a-z]+)\s*:\s*("([^"\\]|\\.)*"|'([^'\\]|\\.)*'|[^,}]+)`)
 )
 
 func parseDirectionLine(line string) (Direction, bool) {
@@ -23,6 +26,31 @@ func parseDirectionLine(line string) (Direction, bool) {
 	return directionFromToken(parts[1]), true
 }
 
+// joinMarkdownStringLines rejoins markdown strings ("`...`") that span
+// several source lines, keeping their line breaks.
+func joinMarkdownStringLines(lines []string) []string {
+	out := make([]string, 0, len(lines))
+	open := false
+	for _, line := range lines {
+		if open {
+			out[len(out)-1] += "\n" + line
+		} else {
+			out = append(out, line)
+		}
+		for i := 0; i+1 < len(line); i++ {
+			switch {
+			case !open && line[i] == '"' && line[i+1] == '`':
+				open = true
+				i++
+			case open && line[i] == '`' && line[i+1] == '"':
+				open = false
+				i++
+			}
+		}
+	}
+	return out
+}
+
 func splitStatements(line string) []string {
 	parts := make([]string, 0, 4)
 	var current strings.Builder
@@ -272,6 +300,12 @@ func splitEdgeChain(line string) []string {
 			}
 		}
 	}
+	for i := 0; i < len(arrows); i++ {
+		if base, id := splitEdgeID(nodes[i]); id != "" {
+			nodes[i] = base
+			arrows[i] = id + "@" + arrows[i]
+		}
+	}
 	for _, n := range nodes {
 		if n == "" {
 			return nil
@@ -295,6 +329,24 @@ type edgeMeta struct {
 	raw         string
 	startMarker string
 	endMarker   string
+	id          string
+	// length is the mermaid link length, the minimum rank span of the edge.
+	length int
+}
+
+// edgeArrowLength is mermaid's link length for an arrow: one rank per dash
+// or equals sign beyond the shortest form, or the number of dots in a dotted
+// link. `-->` and `-.->` are 1, `--->` and `-..->` are 2.
+func edgeArrowLength(arrow string) int {
+	line := strings.TrimLeft(strings.TrimSpace(arrow), "<xo")
+	if line == "" {
+		return 1
+	}
+	line = line[:len(line)-1]
+	if dots := strings.Count(line, "."); dots > 0 {
+		return dots
+	}
+	return max(1, len(line)-1)
 }
 
 func parseEdgeMeta(arrow string) edgeMeta {
@@ -306,6 +358,7 @@ func parseEdgeMeta(arrow string) edgeMeta {
 			style:    EdgeInvisible,
 			raw:      raw,
 			directed: false,
+			length:   1,
 		}
 	}
 
@@ -358,6 +411,7 @@ func parseEdgeMeta(arrow string) edgeMeta {
 		raw:         raw,
 		startMarker: startMarker,
 		endMarker:   endMarker,
+		length:      edgeArrowLength(raw),
 	}
 	if meta.endMarker == "" && strings.Contains(trimmed, "..") && strings.HasSuffix(trimmed, ">") {
 		meta.endMarker = "dependency"
@@ -380,6 +434,28 @@ func parseEdgeMeta(arrow string) edgeMeta {
 }
 
 func parseEdgeLine(line string) (left, label, right string, meta edgeMeta, ok bool) {
+	left, label, right, meta, ok = parseEdgeParts(line)
+	if !ok {
+		return left, label, right, meta, ok
+	}
+	left, meta.id = splitEdgeID(left)
+	if left == "" {
+		return "", "", "", edgeMeta{}, false
+	}
+	return left, label, right, meta, true
+}
+
+// splitEdgeID removes a v11 edge id written before the arrow, as in
+// `A e1@--> B`, from the source side of an edge.
+func splitEdgeID(left string) (string, string) {
+	match := edgeIDRe.FindStringSubmatchIndex(left)
+	if match == nil {
+		return left, ""
+	}
+	return strings.TrimSpace(left[:match[0]]), left[match[2]:match[3]]
+}
+
+func parseEdgeParts(line string) (left, label, right string, meta edgeMeta, ok bool) {
 	masked := maskBracketContent(line)
 
 	if idx := pipeLabelRe.FindStringSubmatchIndex(masked); idx != nil && len(idx) >= 10 {
@@ -413,6 +489,7 @@ func parseEdgeLine(line string) (left, label, right string, meta edgeMeta, ok bo
 			!strings.ContainsAny(arrow1, "<>") &&
 			strings.Contains(arrow2, ">") {
 			meta = parseEdgeMeta(arrow1 + arrow2)
+			meta.length = edgeArrowLength(arrow2)
 			return leftRaw, inlineLabel, rightRaw, meta, true
 		}
 	}
@@ -427,6 +504,7 @@ func parseEdgeLine(line string) (left, label, right string, meta edgeMeta, ok bo
 		right = strings.TrimSpace(submatch(line, idx, 14))
 		if left != "" && right != "" && label != "" {
 			meta = parseEdgeMeta(start + dash1 + dash2 + end)
+			meta.length = edgeArrowLength(dash2 + end)
 			return left, label, right, meta, true
 		}
 	}
@@ -548,16 +626,30 @@ func addEdgeFromLine(graph *Graph, line string) bool {
 					arrowEnd = false
 				}
 			}
+			edgeID := ""
+			if len(sourceIDs) == 1 && len(targetIDs) == 1 {
+				edgeID = meta.id
+			}
+			minLen := 0
+			if graph.Kind == DiagramFlowchart {
+				minLen = meta.length
+			}
+			edgeLabel := label
+			if graph.Kind == DiagramFlowchart {
+				edgeLabel = stripQuotes(label)
+			}
 			graph.addEdge(Edge{
 				From:        from,
 				To:          to,
-				Label:       label,
+				Label:       edgeLabel,
 				Directed:    meta.directed,
 				ArrowStart:  arrowStart,
 				ArrowEnd:    arrowEnd,
 				Style:       meta.style,
 				MarkerStart: markerStart,
 				MarkerEnd:   markerEnd,
+				ID:          edgeID,
+				MinLen:      minLen,
 			})
 		}
 	}
@@ -577,7 +669,8 @@ func splitNodeList(raw string) []string {
 }
 
 func parseNodeOnly(line string) (id, label string, shape NodeShape, ok bool) {
-	if arrowTokenRe.MatchString(line) || strings.Contains(line, "--") {
+	masked := maskBracketContent(line)
+	if arrowTokenRe.MatchString(masked) || strings.Contains(masked, "--") {
 		return "", "", "", false
 	}
 	id, label, shape, _ = parseNodeToken(line)
@@ -653,9 +746,101 @@ func parseNodeMetadataToken(token string) (id, label string, shape NodeShape, ok
 		shape = shapeFromMermaidToken(matches[1])
 	}
 
+	asset := parseNodeAssetFields(body)
+	if asset.Icon != "" {
+		switch lower(asset.form) {
+		case "square":
+			shape = ShapeIconSquare
+		case "circle":
+			shape = ShapeIconCircle
+		case "rounded":
+			shape = ShapeIconRounded
+		default:
+			shape = ShapeIcon
+		}
+	} else if asset.Img != "" {
+		shape = ShapeImage
+	}
+
 	return id, label, shape, true
 }
 
+// nodeAsset holds the icon and image fields of a node `@{ ... }` body.
+type nodeAsset struct {
+	Icon        string
+	Img         string
+	LabelPos    string
+	AssetWidth  float64
+	AssetHeight float64
+	Constraint  bool
+	form        string
+}
+
+func parseNodeAssetFields(body string) nodeAsset {
+	var asset nodeAsset
+	for _, match := range nodeMetaField.FindAllStringSubmatch(body, -1) {
+		value := strings.TrimSpace(stripQuotes(strings.TrimSpace(match[2])))
+		switch lower(match[1]) {
+		case "icon":
+			asset.Icon = value
+		case "img":
+			asset.Img = value
+		case "form":
+			asset.form = value
+		case "pos":
+			if p := lower(value); p == "t" || p == "b" {
+				asset.LabelPos = p
+			}
+		case "w":
+			if v, err := strconv.ParseFloat(value, 64); err == nil && v > 0 {
+				asset.AssetWidth = v
+			}
+		case "h":
+			if v, err := strconv.ParseFloat(value, 64); err == nil && v > 0 {
+				asset.AssetHeight = v
+			}
+		case "constraint":
+			asset.Constraint = lower(value) == "on"
+		}
+	}
+	return asset
+}
+
+// applyNodeAssets copies icon and image metadata from the `@{ ... }` node
+// tokens of a flowchart statement onto the graph nodes.
+func applyNodeAssets(graph *Graph, statement string) {
+	tokens := []string{statement}
+	if left, _, right, _, ok := parseEdgeLine(statement); ok {
+		tokens = append(splitNodeList(left), splitNodeList(right)...)
+	}
+	for _, token := range tokens {
+		base, _ := splitInlineClasses(strings.TrimSpace(token))
+		idx := strings.Index(base, "@{")
+		if idx <= 0 || !strings.HasSuffix(base, "}") {
+			continue
+		}
+		id, _, _, ok := parseNodeMetadataToken(base)
+		if !ok {
+			continue
+		}
+		node, exists := graph.Nodes[id]
+		if !exists {
+			continue
+		}
+		asset := parseNodeAssetFields(strings.TrimSuffix(base[idx+2:], "}"))
+		if asset.Icon == "" && asset.Img == "" {
+			continue
+		}
+		node.Icon = asset.Icon
+		node.Img = asset.Img
+		node.LabelPos = asset.LabelPos
+		node.AssetWidth = asset.AssetWidth
+		node.AssetHeight = asset.AssetHeight
+		node.Constraint = asset.Constraint
+		graph.Nodes[id] = node
+	}
+}
+
 func shapeFromMermaidToken(raw string) NodeShape {
 	token := lower(strings.TrimSpace(raw))
 	switch token {
@@ -663,24 +848,94 @@ func shapeFromMermaidToken(raw string) NodeShape {
 		return ShapeRoundRect
 	case "stadium", "pill", "terminal":
 		return ShapeStadium
-	case "subproc", "subprocess", "subroutine", "fr-rect":
+	case "subproc", "subprocess", "subroutine", "fr-rect", "framed-rectangle":
 		return ShapeSubroutine
-	case "cyl", "cylinder", "db", "database", "h-cyl", "lin-cyl":
+	case "cyl", "cylinder", "db", "database":
 		return ShapeCylinder
-	case "circle", "circ", "sm-circ", "start", "f-circ", "junction":
+	case "h-cyl", "das", "horizontal-cylinder":
+		return ShapeHorizontalCylinder
+	case "lin-cyl", "disk", "lined-cylinder":
+		return ShapeLinedCylinder
+	case "circle", "circ":
 		return ShapeCircle
-	case "dbl-circ", "double-circle", "fr-circ", "stop", "cross-circ":
+	case "sm-circ", "small-circle", "start":
+		return ShapeSmallCircle
+	case "f-circ", "filled-circle", "junction":
+		return ShapeFilledCircle
+	case "dbl-circ", "double-circle":
 		return ShapeDoubleCircle
+	case "fr-circ", "framed-circle", "stop":
+		return ShapeFramedCircle
+	case "cross-circ", "crossed-circle", "summary":
+		return ShapeCrossedCircle
 	case "diamond", "decision", "question", "diam":
 		return ShapeDiamond
 	case "hex", "hexagon", "prepare":
 		return ShapeHexagon
-	case "parallelogram", "lean-r", "lean-l", "in-out", "out-in":
+	case "parallelogram", "lean-r", "lean-right", "in-out":
 		return ShapeParallelogram
-	case "trapezoid", "trap-t", "trap-b", "priority", "manual":
+	case "lean-l", "lean-left", "out-in":
+		return ShapeLeanLeft
+	case "trapezoid", "trap-b", "trapezoid-bottom", "priority":
 		return ShapeTrapezoid
+	case "trap-t", "trapezoid-top", "inv-trapezoid", "manual":
+		return ShapeTrapezoidTop
 	case "asymmetric", "odd":
 		return ShapeAsymmetric
+	case "doc", "document":
+		return ShapeDocument
+	case "lin-doc", "lined-document":
+		return ShapeLinedDocument
+	case "docs", "documents", "st-doc", "stacked-document":
+		return ShapeStackedDocument
+	case "tag-doc", "tagged-document":
+		return ShapeTaggedDocument
+	case "notch-rect", "card", "notched-rectangle":
+		return ShapeNotchedRect
+	case "hourglass", "collate":
+		return ShapeHourglass
+	case "bolt", "com-link", "lightning-bolt":
+		return ShapeBolt
+	case "brace", "brace-l", "comment":
+		return ShapeBraceLeft
+	case "brace-r":
+		return ShapeBraceRight
+	case "braces":
+		return ShapeBraces
+	case "delay", "half-rounded-rectangle":
+		return ShapeDelay
+	case "curv-trap", "curved-trapezoid", "display":
+		return ShapeCurvedTrapezoid
+	case "div-rect", "div-proc", "divided-process", "divided-rectangle":
+		return ShapeDividedRect
+	case "tri", "extract", "triangle":
+		return ShapeTriangle
+	case "flip-tri", "flipped-triangle", "manual-file":
+		return ShapeFlippedTriangle
+	case "fork", "join":
+		return ShapeFork
+	case "win-pane", "internal-storage", "window-pane":
+		return ShapeWindowPane
+	case "lin-rect", "lin-proc", "lined-process", "lined-rectangle", "shaded-process":
+		return ShapeLinedRect
+	case "notch-pent", "loop-limit", "notched-pentagon":
+		return ShapeNotchedPentagon
+	case "sl-rect", "manual-input", "sloped-rectangle":
+		return ShapeSlopedRect
+	case "st-rect", "processes", "procs", "stacked-rectangle":
+		return ShapeStackedRect
+	case "flag", "paper-tape":
+		return ShapeFlag
+	case "bow-rect", "bow-tie-rectangle", "stored-data":
+		return ShapeBowTieRect
+	case "tag-rect", "tag-proc", "tagged-process", "tagged-rectangle":
+		return ShapeTaggedRect
+	case "cloud":
+		return ShapeCloud
+	case "bang":
+		return ShapeBang
+	case "text":
+		return ShapeText
 	default:
 		return ShapeRectangle
 	}
@@ -730,6 +985,10 @@ func parseShapeFromBrackets(raw string) (string, NodeShape) {
 		return stripQuotes(trimmed[2 : len(trimmed)-2]), ShapeParallelogram
 	case strings.HasPrefix(trimmed, "[/") && strings.HasSuffix(trimmed, "\\]"):
 		return stripQuotes(trimmed[2 : len(trimmed)-2]), ShapeTrapezoid
+	case strings.HasPrefix(trimmed, "[\\") && strings.HasSuffix(trimmed, "\\]"):
+		return stripQuotes(trimmed[2 : len(trimmed)-2]), ShapeLeanLeft
+	case strings.HasPrefix(trimmed, "[\\") && strings.HasSuffix(trimmed, "/]"):
+		return stripQuotes(trimmed[2 : len(trimmed)-2]), ShapeTrapezoidTop
 	case strings.HasPrefix(trimmed, "[(") && strings.HasSuffix(trimmed, ")]"):
 		return stripQuotes(trimmed[2 : len(trimmed)-2]), ShapeStadium
 	case strings.HasPrefix(trimmed, "[") && strings.HasSuffix(trimmed, "]"):
@@ -769,3 +1028,103 @@ func parseShapeFromBraces(raw string) (string, NodeShape) {
 	}
 	return stripQuotes(trimmed), ShapeDiamond
 }
+
+// parseClassDefDirective records a `classDef name[,name...] styles` line on
+// the graph. It reports whether the line was a classDef statement.
+func parseClassDefDirective(graph *Graph, line string) bool {
+	trimmed := strings.TrimSpace(line)
+	if !strings.HasPrefix(lower(trimmed), "classdef ") {
+		return false
+	}
+	rest := strings.TrimSpace(trimmed[len("classdef "):])
+	namesRaw, stylesRaw, _ := strings.Cut(rest, " ")
+	styles := splitStyleDeclarations(stylesRaw)
+	for _, name := range strings.Split(namesRaw, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if graph.ClassDefs == nil {
+			graph.ClassDefs = map[string][]string{}
+		}
+		graph.ClassDefs[name] = append(graph.ClassDefs[name], styles...)
+	}
+	return true
+}
+
+// splitStyleDeclarations splits a mermaid style list such as
+// "fill:#f9f,stroke:rgb(1,2,3)" on commas outside parentheses. An escaped
+// `\,` stays inside its declaration, as in "stroke-dasharray: 9\,5".
+func splitStyleDeclarations(raw string) []string {
+	out := make([]string, 0, 4)
+	depth := 0
+	start := 0
+	flush := func(end int) {
+		decl := strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(raw[start:end]), ";"))
+		decl = strings.ReplaceAll(decl, `\,`, ",")
+		if decl != "" {
+			out = append(out, decl)
+		}
+	}
+	for i := 0; i < len(raw); i++ {
+		switch raw[i] {
+		case '\\':
+			i++
+		case '(':
+			depth++
+		case ')':
+			depth = max(0, depth-1)
+		case ',', ';':
+			if depth == 0 {
+				flush(i)
+				start = i + 1
+			}
+		}
+	}
+	flush(len(raw))
+	return out
+}
+
+// classStyleMap resolves the CSS declarations of the given classDef names
+// into a property map; later classes win.
+func (g *Graph) classStyleMap(classes ...string) map[string]string {
+	styles := map[string]string{}
+	for _, class := range classes {
+		for _, decl := range g.ClassDefs[class] {
+			key, value, ok := strings.Cut(decl, ":")
+			if !ok {
+				continue
+			}
+			styles[lower(strings.TrimSpace(key))] = strings.TrimSpace(value)
+		}
+	}
+	return styles
+}
+
+// classDefStyle flattens the classDef declarations of the given classes into
+// an inline style, for primitives where the class should win over defaults.
+func classDefStyle(classDefs map[string][]string, classes []string) string {
+	decls := make([]string, 0, 4)
+	for _, class := range classes {
+		decls = append(decls, classDefs[class]...)
+	}
+	return strings.Join(decls, ";")
+}
+
+// applyNodeStyles copies the fill, stroke and stroke-width declarations of a
+// resolved style map onto the node.
+func applyNodeStyles(node Node, styles map[string]string) Node {
+	for key, value := range styles {
+		switch key {
+		case "fill":
+			node.Fill = value
+		case "stroke":
+			node.Stroke = value
+		case "stroke-width":
+			if strokeWidth, ok := parseFloat(strings.TrimSuffix(strings.TrimSpace(value), "px")); ok {
+				node.StrokeWidth = strokeWidth
+			}
+		}
+	}
+	return node
+}
@@ -0,0 +1,483 @@
+package mermaid
+
+import (
+	"strconv"
+	"strings"
+)
+
+// parseDiagramConfig collects the diagram-level configuration declared in the
+// source, merging the front matter `config:` block with any `%%{init: ...}%%`
+// directives the same way mermaid does (directives win over front matter).
+func parseDiagramConfig(input string) map[string]any {
+	config := map[string]any{}
+	frontMatter := extractFrontMatter(input)
+	if frontMatter != "" {
+		if parsed := parseYAMLSubset(frontMatter); parsed != nil {
+			if section, ok := parsed["config"].(map[string]any); ok {
+				mergeConfigMaps(config, section)
+			}
+		}
+	}
+	for _, directive := range extractInitDirectives(input) {
+		mergeConfigMaps(config, directive)
+	}
+	if len(config) == 0 {
+		return nil
+	}
+	return config
+}
+
+// parseFrontMatterTitle returns the `title:` declared in the front matter.
+func parseFrontMatterTitle(input string) string {
+	frontMatter := extractFrontMatter(input)
+	if frontMatter == "" {
+		return ""
+	}
+	if parsed := parseYAMLSubset(frontMatter); parsed != nil {
+		if title, ok := parsed["title"].(string); ok {
+			return strings.TrimSpace(title)
+		}
+	}
+	return ""
+}
+
+func extractFrontMatter(input string) string {
+	lines := strings.Split(input, "\n")
+	start := -1
+	for i, raw := range lines {
+		trimmed := strings.TrimSpace(raw)
+		if trimmed == "" {
+			continue
+		}
+		if trimmed != "---" {
+			return ""
+		}
+		start = i + 1
+		break
+	}
+	if start < 0 {
+		return ""
+	}
+	for i := start; i < len(lines); i++ {
+		trimmed := strings.TrimSpace(lines[i])
+		if trimmed == "---" || trimmed == "..." {
+			return strings.Join(lines[start:i], "\n")
+		}
+	}
+	return ""
+}
+
+func extractInitDirectives(input string) []map[string]any {
+	out := make([]map[string]any, 0, 1)
+	rest := input
+	for {
+		start := strings.Index(rest, "%%{")
+		if start < 0 {
+			break
+		}
+		end := strings.Index(rest[start:], "}%%")
+		if end < 0 {
+			break
+		}
+		body := strings.TrimSpace(rest[start+3 : start+end])
+		rest = rest[start+end+3:]
+
+		colon := strings.Index(body, ":")
+		if colon <= 0 {
+			continue
+		}
+		switch lower(body[:colon]) {
+		case "init", "initialize", "config":
+		default:
+			continue
+		}
+		value, ok := parseRelaxedJSON(body[colon+1:])
+		if !ok {
+			continue
+		}
+		if section, ok := value.(map[string]any); ok {
+			out = append(out, section)
+		}
+	}
+	return out
+}
+
+func mergeConfigMaps(dst, src map[string]any) {
+	for key, value := range src {
+		if nested, ok := value.(map[string]any); ok {
+			if existing, ok := dst[key].(map[string]any); ok {
+				mergeConfigMaps(existing, nested)
+				continue
+			}
+			copied := map[string]any{}
+			mergeConfigMaps(copied, nested)
+			dst[key] = copied
+			continue
+		}
+		dst[key] = value
+	}
+}
+
+// parseYAMLSubset understands the block-mapping subset of YAML used by
+// mermaid front matter: nested `key: value` maps, `- item` lists and inline
+// `{...}` / `[...]` flow values.
+func parseYAMLSubset(raw string) map[string]any {
+	type yamlLine struct {
+		indent int
+		text   string
+	}
+	lines := make([]yamlLine, 0, 16)
+	for _, line := range strings.Split(raw, "\n") {
+		text := stripYAMLComment(line)
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		lines = append(lines, yamlLine{indent: countIndent(text), text: strings.TrimSpace(text)})
+	}
+
+	var parseBlock func(pos, indent int) (any, int)
+	parseBlock = func(pos, indent int) (any, int) {
+		if pos < len(lines) && isYAMLListItem(lines[pos].text) {
+			list := make([]any, 0, 4)
+			for pos < len(lines) && lines[pos].indent == indent && isYAMLListItem(lines[pos].text) {
+				item := strings.TrimSpace(strings.TrimPrefix(lines[pos].text, "-"))
+				pos++
+				if item == "" {
+					if pos < len(lines) && lines[pos].indent > indent {
+						var nested any
+						nested, pos = parseBlock(pos, lines[pos].indent)
+						list = append(list, nested)
+					}
+					continue
+				}
+				list = append(list, parseYAMLScalar(item))
+			}
+			return list, pos
+		}
+
+		section := map[string]any{}
+		for pos < len(lines) && lines[pos].indent == indent {
+			key, value, ok := splitYAMLKeyValue(lines[pos].text)
+			pos++
+			if !ok {
+				continue
+			}
+			if value != "" {
+				section[key] = parseYAMLScalar(value)
+				continue
+			}
+			if pos < len(lines) && lines[pos].indent > indent {
+				var nested any
+				nested, pos = parseBlock(pos, lines[pos].indent)
+				section[key] = nested
+				continue
+			}
+			section[key] = nil
+		}
+		return section, pos
+	}
+
+	if len(lines) == 0 {
+		return nil
+	}
+	parsed, _ := parseBlock(0, lines[0].indent)
+	section, _ := parsed.(map[string]any)
+	return section
+}
+
+func isYAMLListItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+func stripYAMLComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		ch := line[i]
+		if quote != 0 {
+			if ch == quote {
+				quote = 0
+			}
+			continue
+		}
+		switch ch {
+		case '"', '\'':
+			quote = ch
+		case '#':
+			if i == 0 || line[i-1] == ' ' || line[i-1] == '\t' {
+				return line[:i]
+			}
+		}
+	}
+	return line
+}
+
+func splitYAMLKeyValue(text string) (string, string, bool) {
+	var quote byte
+	for i := 0; i < len(text); i++ {
+		ch := text[i]
+		if quote != 0 {
+			if ch == quote {
+				quote = 0
+			}
+			continue
+		}
+		if ch == '"' || ch == '\'' {
+			quote = ch
+			continue
+		}
+		if ch == ':' && (i+1 == len(text) || text[i+1] == ' ' || text[i+1] == '\t') {
+			key := stripQuotes(strings.TrimSpace(text[:i]))
+			if key == "" {
+				return "", "", false
+			}
+			return key, strings.TrimSpace(text[i+1:]), true
+		}
+	}
+	return "", "", false
+}
+
+func parseYAMLScalar(raw string) any {
+	value := strings.TrimSpace(raw)
+	if strings.HasPrefix(value, "{") || strings.HasPrefix(value, "[") {
+		if parsed, ok := parseRelaxedJSON(value); ok {
+			return parsed
+		}
+	}
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		if value[0] == '"' {
+			if unquoted, err := strconv.Unquote(value); err == nil {
+				return unquoted
+			}
+		}
+		return strings.ReplaceAll(value[1:len(value)-1], "''", "'")
+	}
+	switch lower(value) {
+	case "true", "yes", "on":
+		return true
+	case "false", "no", "off":
+		return false
+	case "null", "~":
+		return nil
+	}
+	if number, ok := parseFloat(value); ok {
+		return number
+	}
+	return value
+}
+
+// parseRelaxedJSON parses the JSON dialect accepted in mermaid directives:
+// single-quoted strings, unquoted keys and trailing commas are allowed.
+func parseRelaxedJSON(raw string) (any, bool) {
+	p := relaxedJSONParser{src: raw}
+	value, ok := p.parseValue()
+	if !ok {
+		return nil, false
+	}
+	p.skipSpace()
+	return value, p.pos == len(p.src)
+}
+
+type relaxedJSONParser struct {
+	src string
+	pos int
+}
+
+func (p *relaxedJSONParser) skipSpace() {
+	for p.pos < len(p.src) {
+		switch p.src[p.pos] {
+		case ' ', '\t', '\n', '\r':
+			p.pos++
+		default:
+			return
+		}
+	}
+}
+
+func (p *relaxedJSONParser) parseValue() (any, bool) {
+	p.skipSpace()
+	if p.pos >= len(p.src) {
+		return nil, false
+	}
+	switch ch := p.src[p.pos]; {
+	case ch == '{':
+		return p.parseObject()
+	case ch == '[':
+		return p.parseArray()
+	case ch == '"' || ch == '\'':
+		return p.parseString()
+	default:
+		token := p.parseBareword()
+		if token == "" {
+			return nil, false
+		}
+		switch token {
+		case "true":
+			return true, true
+		case "false":
+			return false, true
+		case "null":
+			return nil, true
+		}
+		if number, ok := parseFloat(token); ok {
+			return number, true
+		}
+		return token, true
+	}
+}
+
+func (p *relaxedJSONParser) parseObject() (any, bool) {
+	p.pos++
+	out := map[string]any{}
+	for {
+		p.skipSpace()
+		if p.pos >= len(p.src) {
+			return nil, false
+		}
+		if p.src[p.pos] == '}' {
+			p.pos++
+			return out, true
+		}
+		var key string
+		if ch := p.src[p.pos]; ch == '"' || ch == '\'' {
+			parsed, ok := p.parseString()
+			if !ok {
+				return nil, false
+			}
+			key = parsed.(string)
+		} else {
+			key = p.parseBareword()
+		}
+		if key == "" {
+			return nil, false
+		}
+		p.skipSpace()
+		if p.pos >= len(p.src) || p.src[p.pos] != ':' {
+			return nil, false
+		}
+		p.pos++
+		value, ok := p.parseValue()
+		if !ok {
+			return nil, false
+		}
+		out[key] = value
+		p.skipSpace()
+		if p.pos < len(p.src) && p.src[p.pos] == ',' {
+			p.pos++
+		}
+	}
+}
+
+func (p *relaxedJSONParser) parseArray() (any, bool) {
+	p.pos++
+	out := make([]any, 0, 4)
+	for {
+		p.skipSpace()
+		if p.pos >= len(p.src) {
+			return nil, false
+		}
+		if p.src[p.pos] == ']' {
+			p.pos++
+			return out, true
+		}
+		value, ok := p.parseValue()
+		if !ok {
+			return nil, false
+		}
+		out = append(out, value)
+		p.skipSpace()
+		if p.pos < len(p.src) && p.src[p.pos] == ',' {
+			p.pos++
+		}
+	}
+}
+
+func (p *relaxedJSONParser) parseString() (any, bool) {
+	quote := p.src[p.pos]
+	p.pos++
+	var b strings.Builder
+	for p.pos < len(p.src) {
+		ch := p.src[p.pos]
+		p.pos++
+		if ch == quote {
+			return b.String(), true
+		}
+		if ch == '\\' && p.pos < len(p.src) {
+			next := p.src[p.pos]
+			p.pos++
+			switch next {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			default:
+				b.WriteByte(next)
+			}
+			continue
+		}
+		b.WriteByte(ch)
+	}
+	return nil, false
+}
+
+func (p *relaxedJSONParser) parseBareword() string {
+	start := p.pos
+	for p.pos < len(p.src) {
+		switch p.src[p.pos] {
+		case ',', ':', '}', ']', ' ', '\t', '\n', '\r':
+			return p.src[start:p.pos]
+		}
+		p.pos++
+	}
+	return p.src[start:p.pos]
+}
+
+func configSection(config map[string]any, path ...string) map[string]any {
+	current := config
+	for _, key := range path {
+		next, ok := current[key].(map[string]any)
+		if !ok {
+			return nil
+		}
+		current = next
+	}
+	return current
+}
+
+func configString(section map[string]any, key string) (string, bool) {
+	switch v := section[key].(type) {
+	case string:
+		return v, true
+	case float64:
+		return formatFloat(v), true
+	case bool:
+		return strconv.FormatBool(v), true
+	default:
+		return "", false
+	}
+}
+
+func configFloat(section map[string]any, key string) (float64, bool) {
+	switch v := section[key].(type) {
+	case float64:
+		return v, true
+	case string:
+		return parseFloat(v)
+	default:
+		return 0, false
+	}
+}
+
+func configBool(section map[string]any, key string) (bool, bool) {
+	switch v := section[key].(type) {
+	case bool:
+		return v, true
+	case string:
+		switch lower(v) {
+		case "true":
+			return true, true
+		case "false":
+			return false, true
+		}
+	}
+	return false, false
+}
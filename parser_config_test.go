@@ -0,0 +1,106 @@
+package mermaid
+
+import "testing"
+
+func TestParseDiagramConfigFrontMatter(t *testing.T) {
+	input := `---
+title: Flows
+config:
+  sankey:
+    showValues: false
+    linkColor: "#ff0000"
+    width: 800 # wider canvas
+---
+sankey-beta
+A,B,10
+`
+	out, err := ParseMermaid(input)
+	if err != nil {
+		t.Fatalf("ParseMermaid returned error: %v", err)
+	}
+	section := configSection(out.Graph.Config, "sankey")
+	if section == nil {
+		t.Fatalf("expected sankey config section, got %#v", out.Graph.Config)
+	}
+	if v, ok := configBool(section, "showValues"); !ok || v {
+		t.Fatalf("expected showValues=false, got %#v", section["showValues"])
+	}
+	if v, _ := configString(section, "linkColor"); v != "#ff0000" {
+		t.Fatalf("expected quoted hex color to survive, got %q", v)
+	}
+	if v, _ := configFloat(section, "width"); v != 800 {
+		t.Fatalf("expected width=800, got %v", v)
+	}
+}
+
+func TestParseDiagramConfigInitDirectiveOverridesFrontMatter(t *testing.T) {
+	input := `---
+config:
+  sankey:
+    linkColor: source
+    prefix: "$"
+---
+%%{init: {'sankey': {'linkColor': 'target', showValues: true,}}}%%
+sankey-beta
+A,B,10
+`
+	out, err := ParseMermaid(input)
+	if err != nil {
+		t.Fatalf("ParseMermaid returned error: %v", err)
+	}
+	section := configSection(out.Graph.Config, "sankey")
+	if v, _ := configString(section, "linkColor"); v != "target" {
+		t.Fatalf("expected directive to win, got %q", v)
+	}
+	if v, _ := configString(section, "prefix"); v != "$" {
+		t.Fatalf("expected front matter keys to be kept, got %q", v)
+	}
+}
+
+func TestParseDiagramConfigWithoutConfig(t *testing.T) {
+	out, err := ParseMermaid("flowchart LR\n  A --> B")
+	if err != nil {
+		t.Fatalf("ParseMermaid returned error: %v", err)
+	}
+	if out.Graph.Config != nil {
+		t.Fatalf("expected nil config, got %#v", out.Graph.Config)
+	}
+}
+
+func TestParseDiagramTitle(t *testing.T) {
+	cases := []struct {
+		input string
+		kind  DiagramKind
+	}{
+		{"---\ntitle: Orders\n---\nflowchart LR\n  A --> B", DiagramFlowchart},
+		{"flowchart LR\n  title Orders\n  A --> B", DiagramFlowchart},
+		{"---\ntitle: \"Orders\"\n---\nclassDiagram\n  class A", DiagramClass},
+		{"---\ntitle: Orders\n---\nstateDiagram-v2\n  [*] --> A", DiagramState},
+		{"---\ntitle: Orders\n---\nerDiagram\n  A ||--o{ B : has", DiagramER},
+		{"sequenceDiagram\n  title Orders\n  A->>B: hi", DiagramSequence},
+	}
+	for _, tc := range cases {
+		parsed, err := ParseMermaid(tc.input)
+		if err != nil {
+			t.Fatalf("ParseMermaid(%q) error = %v", tc.input, err)
+		}
+		if parsed.Graph.Kind != tc.kind || parsed.Graph.Title != "Orders" {
+			t.Errorf("ParseMermaid(%q) kind = %s, title = %q", tc.input, parsed.Graph.Kind, parsed.Graph.Title)
+		}
+	}
+
+	parsed, err := ParseMermaid("---\ntitle: Pets\n---\npie\n  \"Dogs\": 3")
+	if err != nil {
+		t.Fatalf("ParseMermaid() error = %v", err)
+	}
+	if parsed.Graph.PieTitle != "Pets" {
+		t.Fatalf("expected the front-matter title to fill PieTitle, got %q", parsed.Graph.PieTitle)
+	}
+	parsed, err = ParseMermaid("---\ntitle: Pets\n---\npie title Animals\n  \"Dogs\": 3")
+	if err != nil {
+		t.Fatalf("ParseMermaid() error = %v", err)
+	}
+	if parsed.Graph.PieTitle != "Animals" {
+		t.Fatalf("expected the pie title statement to win, got %q", parsed.Graph.PieTitle)
+	}
+}
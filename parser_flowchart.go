@@ -7,9 +7,11 @@ func parseFlowchart(input string) (ParseOutput, error) {
 	if err != nil {
 		return ParseOutput{}, err
 	}
+	lines = joinMarkdownStringLines(lines)
 
 	graph := newGraph(DiagramFlowchart)
 	graph.Source = input
+	classAssignments := make([]flowchartClassAssignment, 0, 4)
 	subgraphNodeSets := make([]map[string]struct{}, 0, 8)
 	activeSubgraphs := make([]int, 0, 4)
 
@@ -51,7 +53,11 @@ func parseFlowchart(input string) (ParseOutput, error) {
 			}
 
 			if dir, ok := parseDirectionLine(trimmed); ok {
-				graph.Direction = dir
+				if len(activeSubgraphs) > 0 {
+					graph.FlowSubgraphs[activeSubgraphs[len(activeSubgraphs)-1]].Direction = dir
+				} else {
+					graph.Direction = dir
+				}
 				continue
 			}
 
@@ -74,10 +80,15 @@ func parseFlowchart(input string) (ParseOutput, error) {
 				if subgraphLabel == "" {
 					subgraphLabel = subgraphID
 				}
+				parent := ""
+				if len(activeSubgraphs) > 0 {
+					parent = graph.FlowSubgraphs[activeSubgraphs[len(activeSubgraphs)-1]].ID
+				}
 				graph.FlowSubgraphs = append(graph.FlowSubgraphs, FlowSubgraph{
 					ID:      subgraphID,
 					Label:   subgraphLabel,
 					NodeIDs: []string{},
+					Parent:  parent,
 				})
 				subgraphNodeSets = append(subgraphNodeSets, map[string]struct{}{})
 				activeSubgraphs = append(activeSubgraphs, len(graph.FlowSubgraphs)-1)
@@ -87,13 +98,21 @@ func parseFlowchart(input string) (ParseOutput, error) {
 				parseFlowchartStyleDirective(&graph, trimmed)
 				continue
 			}
-			if strings.HasPrefix(low, "classdef ") ||
-				strings.HasPrefix(low, "class ") ||
-				strings.HasPrefix(low, "linkstyle ") ||
-				strings.HasPrefix(low, "click ") ||
-				strings.HasPrefix(low, "title ") ||
-				strings.HasPrefix(low, "accdescr") ||
-				strings.HasPrefix(low, "acctitle") {
+			if parseClassDefDirective(&graph, trimmed) {
+				continue
+			}
+			if strings.HasPrefix(low, "class ") {
+				if ids, classes, ok := parseFlowchartClassDirective(trimmed); ok {
+					classAssignments = append(classAssignments, flowchartClassAssignment{ids: ids, classes: classes})
+				}
+				continue
+			}
+			if strings.HasPrefix(low, "title ") {
+				graph.Title = stripQuotes(strings.TrimSpace(trimmed[len("title "):]))
+				continue
+			}
+			if strings.HasPrefix(low, "linkstyle ") ||
+				strings.HasPrefix(low, "click ") {
 				continue
 			}
 
@@ -101,6 +120,7 @@ func parseFlowchart(input string) (ParseOutput, error) {
 				addedAny := false
 				for _, stmt := range statements {
 					if addEdgeFromLine(&graph, stmt) {
+						applyNodeAssets(&graph, stmt)
 						addNodesToActiveSubgraphs(flowchartEdgeNodeIDs(stmt))
 						addedAny = true
 					}
@@ -111,20 +131,134 @@ func parseFlowchart(input string) (ParseOutput, error) {
 			}
 
 			if addEdgeFromLine(&graph, trimmed) {
+				applyNodeAssets(&graph, trimmed)
 				addNodesToActiveSubgraphs(flowchartEdgeNodeIDs(trimmed))
 				continue
 			}
 
+			if applyEdgeMetadata(&graph, trimmed) {
+				continue
+			}
+
 			if id, label, shape, ok := parseNodeOnly(trimmed); ok {
 				graph.ensureNode(id, label, shape)
+				applyNodeAssets(&graph, trimmed)
 				addNodesToActiveSubgraphs([]string{id})
 			}
 		}
 	}
 
+	promoteSubgraphNodes(&graph)
+	for _, assignment := range classAssignments {
+		applyFlowchartClasses(&graph, assignment.ids, assignment.classes)
+	}
+
 	return ParseOutput{Graph: graph}, nil
 }
 
+// flowchartClassAssignment is a `class` statement, applied after parsing so
+// classDefs declared further down still take effect.
+type flowchartClassAssignment struct {
+	ids     []string
+	classes []string
+}
+
+// parseFlowchartClassDirective splits `class a,b name1,name2` into ids and
+// class names.
+func parseFlowchartClassDirective(line string) (ids, classes []string, ok bool) {
+	fields := strings.Fields(strings.TrimSpace(line[len("class "):]))
+	if len(fields) < 2 {
+		return nil, nil, false
+	}
+	for _, id := range strings.Split(fields[0], ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	for _, class := range strings.Split(strings.Join(fields[1:], ""), ",") {
+		if class = strings.TrimSpace(class); class != "" {
+			classes = append(classes, class)
+		}
+	}
+	return ids, classes, len(ids) > 0 && len(classes) > 0
+}
+
+// applyFlowchartClasses attaches classes to edges by id and resolves them
+// into node styles. Declarations from `style` statements take precedence.
+func applyFlowchartClasses(graph *Graph, ids, classes []string) {
+	for _, id := range ids {
+		if edgeIdx := graph.edgeIndexByID(id); edgeIdx >= 0 {
+			graph.Edges[edgeIdx].Classes = append(graph.Edges[edgeIdx].Classes, classes...)
+			continue
+		}
+		if sgIdx := graph.flowSubgraphIndex(id); sgIdx >= 0 {
+			sg := &graph.FlowSubgraphs[sgIdx]
+			sg.Classes = append(sg.Classes, classes...)
+			styles := graph.classStyleMap(classes...)
+			if sg.Fill != "" {
+				delete(styles, "fill")
+			}
+			if sg.Stroke != "" {
+				delete(styles, "stroke")
+			}
+			if sg.StrokeWidth > 0 {
+				delete(styles, "stroke-width")
+			}
+			styled := applyNodeStyles(Node{Fill: sg.Fill, Stroke: sg.Stroke, StrokeWidth: sg.StrokeWidth}, styles)
+			sg.Fill, sg.Stroke, sg.StrokeWidth = styled.Fill, styled.Stroke, styled.StrokeWidth
+			continue
+		}
+		node, ok := graph.Nodes[id]
+		if !ok {
+			continue
+		}
+		styles := graph.classStyleMap(classes...)
+		if node.Fill != "" {
+			delete(styles, "fill")
+		}
+		if node.Stroke != "" {
+			delete(styles, "stroke")
+		}
+		if node.StrokeWidth > 0 {
+			delete(styles, "stroke-width")
+		}
+		graph.Nodes[id] = applyNodeStyles(node, styles)
+	}
+}
+
+// applyEdgeMetadata handles `e1@{ animate: true, curve: linear }` statements.
+// It reports false when the id does not name an edge, so the statement is
+// parsed as a node instead.
+func applyEdgeMetadata(graph *Graph, line string) bool {
+	idx := strings.Index(line, "@{")
+	if idx <= 0 || !strings.HasSuffix(line, "}") {
+		return false
+	}
+	edgeIdx := graph.edgeIndexByID(strings.TrimSpace(line[:idx]))
+	if edgeIdx < 0 {
+		return false
+	}
+	edge := &graph.Edges[edgeIdx]
+	for _, match := range nodeMetaField.FindAllStringSubmatch(line[idx+2:len(line)-1], -1) {
+		value := lower(strings.TrimSpace(stripQuotes(strings.TrimSpace(match[2]))))
+		switch lower(match[1]) {
+		case "animate":
+			if value != "true" {
+				edge.Animation = ""
+			} else if edge.Animation == "" {
+				edge.Animation = "fast"
+			}
+		case "animation":
+			if value == "fast" || value == "slow" {
+				edge.Animation = value
+			}
+		case "curve":
+			edge.Curve = strings.TrimSpace(stripQuotes(strings.TrimSpace(match[2])))
+		}
+	}
+	return true
+}
+
 func parseFlowchartStyleDirective(graph *Graph, line string) {
 	rest := strings.TrimSpace(line[len("style "):])
 	if rest == "" {
@@ -162,6 +296,37 @@ func parseFlowchartStyleDirective(graph *Graph, line string) {
 	graph.Nodes[nodeID] = node
 }
 
+// promoteSubgraphNodes drops the placeholder nodes created when a subgraph
+// id is used as an edge endpoint or in a `style` statement, so the id refers
+// to the cluster. Styles given to the placeholder move to the subgraph.
+func promoteSubgraphNodes(graph *Graph) {
+	for i := range graph.FlowSubgraphs {
+		sg := &graph.FlowSubgraphs[i]
+		node, ok := graph.Nodes[sg.ID]
+		if !ok {
+			continue
+		}
+		sg.Fill = node.Fill
+		sg.Stroke = node.Stroke
+		sg.StrokeWidth = node.StrokeWidth
+		delete(graph.Nodes, sg.ID)
+		graph.NodeOrder = removeString(graph.NodeOrder, sg.ID)
+		for j := range graph.FlowSubgraphs {
+			graph.FlowSubgraphs[j].NodeIDs = removeString(graph.FlowSubgraphs[j].NodeIDs, sg.ID)
+		}
+	}
+}
+
+func removeString(values []string, value string) []string {
+	out := values[:0]
+	for _, v := range values {
+		if v != value {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
 func flowchartEdgeNodeIDs(line string) []string {
 	left, _, right, _, ok := parseEdgeLine(line)
 	if !ok {
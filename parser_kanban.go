@@ -5,7 +5,10 @@ import (
 	"strings"
 )
 
-var kanbanCardRe = regexp.MustCompile(`^([A-Za-z0-9_-]+)\[(.+?)\](?:@\{(.+)\})?$`)
+var (
+	kanbanCardRe   = regexp.MustCompile(`^([A-Za-z0-9_-]+)\[(.+?)\](?:@\{(.+)\})?$`)
+	kanbanColumnRe = regexp.MustCompile(`^([A-Za-z0-9_-]+)\[(.+)\]$`)
+)
 
 func parseKanban(input string) (ParseOutput, error) {
 	lines, err := preprocessInputKeepIndent(input)
@@ -25,14 +28,17 @@ func parseKanban(input string) (ParseOutput, error) {
 		if strings.HasPrefix(lower(line), "kanban") {
 			continue
 		}
+		if parseClassDefDirective(&graph, line) {
+			continue
+		}
 
 		depth := kanbanIndentDepth(raw)
 		if depth <= 1 {
-			title := stripQuotes(line)
-			if title == "" {
+			column, ok := parseKanbanColumn(line)
+			if !ok {
 				continue
 			}
-			graph.KanbanBoard = append(graph.KanbanBoard, KanbanColumn{Title: title})
+			graph.KanbanBoard = append(graph.KanbanBoard, column)
 			currentColumn = len(graph.KanbanBoard) - 1
 			continue
 		}
@@ -53,6 +59,19 @@ func parseKanban(input string) (ParseOutput, error) {
 	return ParseOutput{Graph: graph}, nil
 }
 
+func parseKanbanColumn(line string) (KanbanColumn, bool) {
+	base, classes := splitInlineClasses(line)
+	column := KanbanColumn{
+		Title: stripQuotes(base),
+		Class: strings.Join(classes, " "),
+	}
+	if m := kanbanColumnRe.FindStringSubmatch(base); len(m) == 3 {
+		column.ID = sanitizeID(m[1], "")
+		column.Title = stripQuotes(strings.TrimSpace(m[2]))
+	}
+	return column, column.Title != ""
+}
+
 func parseKanbanCard(line string) (KanbanCard, bool) {
 	m := kanbanCardRe.FindStringSubmatch(strings.TrimSpace(line))
 	if len(m) < 3 {
@@ -76,15 +95,23 @@ func parseKanbanCard(line string) (KanbanCard, bool) {
 		if len(pair) != 2 {
 			continue
 		}
-		key := lower(strings.TrimSpace(pair[0]))
+		key := stripQuotes(strings.TrimSpace(pair[0]))
 		value := stripQuotes(strings.TrimSpace(pair[1]))
-		switch key {
+		switch lower(key) {
 		case "ticket":
 			card.Ticket = value
 		case "assigned":
 			card.Assigned = value
 		case "priority":
 			card.Priority = value
+		default:
+			if key == "" {
+				continue
+			}
+			if card.Metadata == nil {
+				card.Metadata = map[string]string{}
+			}
+			card.Metadata[key] = value
 		}
 	}
 	return card, true
@@ -1,6 +1,9 @@
 package mermaid
 
-import "testing"
+import (
+	"strings"
+	"testing"
+)
 
 func TestParseKanbanBoard(t *testing.T) {
 	input := `kanban
@@ -28,3 +31,76 @@ func TestParseKanbanBoard(t *testing.T) {
 		t.Fatalf("unexpected card metadata: %+v", card)
 	}
 }
+
+func TestParseKanbanMetadataAndColumnClass(t *testing.T) {
+	input := `kanban
+  todo[To Do]:::urgent
+    t1[Wire SSO]@{ ticket: SEC-7, estimate: 5, "reviewer": 'dave' }
+classDef urgent fill:#fdd,stroke:#c00
+`
+
+	out, err := ParseMermaid(input)
+	if err != nil {
+		t.Fatalf("ParseMermaid returned error: %v", err)
+	}
+	if len(out.Graph.KanbanBoard) != 1 {
+		t.Fatalf("expected 1 column, got %d", len(out.Graph.KanbanBoard))
+	}
+	column := out.Graph.KanbanBoard[0]
+	if column.ID != "todo" || column.Title != "To Do" || column.Class != "urgent" {
+		t.Fatalf("unexpected column: %+v", column)
+	}
+	card := column.Cards[0]
+	if card.Ticket != "SEC-7" || card.Metadata["estimate"] != "5" || card.Metadata["reviewer"] != "dave" {
+		t.Fatalf("unexpected card metadata: %+v", card)
+	}
+}
+
+func TestKanbanTicketLinksAndColumnStyles(t *testing.T) {
+	input := `---
+config:
+  kanban:
+    ticketBaseUrl: "https://tracker.example.com/browse/#TICKET#"
+---
+kanban
+  todo[To Do]:::urgent
+    t1[Wire SSO]@{ ticket: SEC-7 }
+classDef urgent fill:#fdd,stroke:#c00
+`
+
+	svg, err := Render(input)
+	if err != nil {
+		t.Fatalf("Render returned error: %v", err)
+	}
+	if !strings.Contains(svg, `href="https://tracker.example.com/browse/SEC-7"`) {
+		t.Fatalf("expected ticket link in SVG")
+	}
+	if !strings.Contains(svg, `class="cluster urgent section-1"`) || !strings.Contains(svg, `fill="#fdd"`) {
+		t.Fatalf("expected column class styling in SVG")
+	}
+}
+
+func TestKanbanWrapWidthConfig(t *testing.T) {
+	out, err := ParseMermaid(`kanban
+  Todo
+    t1[Design the authentication flow]
+`)
+	if err != nil {
+		t.Fatalf("ParseMermaid returned error: %v", err)
+	}
+	countLines := func(config LayoutConfig) int {
+		layout := ComputeLayout(&out.Graph, DefaultRenderOptions().Theme, config)
+		lines := 0
+		for _, text := range layout.Texts {
+			if text.Class == "kanban-card-text" {
+				lines++
+			}
+		}
+		return lines
+	}
+	narrow := DefaultLayoutConfig()
+	narrow.Kanban.WrapWidth = 60
+	if wide, tight := countLines(DefaultLayoutConfig()), countLines(narrow); tight <= wide {
+		t.Fatalf("expected narrower wrap width to add lines, got %d vs %d", tight, wide)
+	}
+}
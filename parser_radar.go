@@ -15,6 +15,7 @@ type radarCurveDraft struct {
 	Label   string
 	Entries []float64
 	ByAxis  map[string]float64
+	Class   string
 }
 
 func parseRadar(input string) (ParseOutput, error) {
@@ -36,6 +37,9 @@ func parseRadar(input string) (ParseOutput, error) {
 		if i == 0 && (strings.HasPrefix(low, "radar-beta") || strings.HasPrefix(low, "radar")) {
 			continue
 		}
+		if parseClassDefDirective(&graph, line) {
+			continue
+		}
 		switch {
 		case strings.HasPrefix(low, "title "):
 			graph.RadarTitle = stripQuotes(strings.TrimSpace(line[len("title"):]))
@@ -105,6 +109,7 @@ func parseRadar(input string) (ParseOutput, error) {
 			Name:    draft.Name,
 			Label:   draft.Label,
 			Entries: entries,
+			Class:   draft.Class,
 		})
 	}
 
@@ -131,7 +136,8 @@ func parseRadarAxisToken(token string) (RadarAxis, bool) {
 }
 
 func parseRadarCurveToken(token string) (radarCurveDraft, bool) {
-	m := radarCurveTokenRe.FindStringSubmatch(strings.TrimSpace(token))
+	token, classes := splitInlineClasses(token)
+	m := radarCurveTokenRe.FindStringSubmatch(token)
 	if len(m) != 4 {
 		return radarCurveDraft{}, false
 	}
@@ -171,13 +177,14 @@ func parseRadarCurveToken(token string) (radarCurveDraft, bool) {
 			entries = append(entries, value)
 		}
 	}
+	class := strings.Join(classes, " ")
 	if hasNamedEntry {
-		return radarCurveDraft{Name: name, Label: label, ByAxis: byAxis}, true
+		return radarCurveDraft{Name: name, Label: label, ByAxis: byAxis, Class: class}, true
 	}
 	if len(entries) == 0 {
 		return radarCurveDraft{}, false
 	}
-	return radarCurveDraft{Name: name, Label: label, Entries: entries}, true
+	return radarCurveDraft{Name: name, Label: label, Entries: entries, Class: class}, true
 }
 
 func splitRadarTopLevelCSV(raw string) []string {
@@ -1,6 +1,9 @@
 package mermaid
 
-import "testing"
+import (
+	"strings"
+	"testing"
+)
 
 func TestParseRadarAxesAndCurves(t *testing.T) {
 	input := `radar-beta
@@ -41,3 +44,116 @@ min 0
 		t.Fatalf("unexpected radar min: %+v", out.Graph.RadarMin)
 	}
 }
+
+func TestRadarConfigAndCurveStyling(t *testing.T) {
+	input := `---
+config:
+  radar:
+    width: 400
+    height: 400
+    curveTension: 0.3
+  themeVariables:
+    cScale0: "#ff0000"
+    radar:
+      curveOpacity: 0.2
+---
+radar-beta
+axis a, b, c
+curve x{1, 2, 3}
+curve y{3, 2, 1}:::bold
+graticule polygon
+classDef bold stroke-width:4
+`
+
+	out, err := ParseMermaid(input)
+	if err != nil {
+		t.Fatalf("ParseMermaid returned error: %v", err)
+	}
+	if got := out.Graph.RadarCurves[1].Class; got != "bold" {
+		t.Fatalf("expected curve class bold, got %q", got)
+	}
+	layout := ComputeLayout(&out.Graph, DefaultRenderOptions().Theme, DefaultLayoutConfig())
+	if layout.Width != 500 || layout.RadarTitleY != -250 {
+		t.Fatalf("expected 400px chart plus margins, got width %.1f title y %.1f", layout.Width, layout.RadarTitleY)
+	}
+	for _, curve := range layout.RadarCurves {
+		if curve.Polygon || !strings.HasPrefix(curve.Path, "M") || !strings.Contains(curve.Path, " C") {
+			t.Fatalf("expected smoothed closed path with explicit curveTension, got %+v", curve)
+		}
+	}
+	if layout.RadarCurves[1].Style != "stroke-width:4" {
+		t.Fatalf("expected classDef style on curve, got %q", layout.RadarCurves[1].Style)
+	}
+
+	svg := RenderSVG(layout, DefaultRenderOptions().Theme, DefaultLayoutConfig())
+	if !strings.Contains(svg, `.radarCurve-0{color:#ff0000;fill:#ff0000;fill-opacity:0.2;`) {
+		t.Fatalf("expected cScale0 and curveOpacity in radar CSS")
+	}
+	if !strings.Contains(svg, `.radarCurve-1{color:#ffff78;fill:#ffff78;fill-opacity:0.2;`) {
+		t.Fatalf("expected curves without a cScale override to keep the default palette")
+	}
+}
+
+func TestRadarThemeVariablesOverlayThePalette(t *testing.T) {
+	out, err := ParseMermaid(`---
+config:
+  themeVariables:
+    cScale2: "#00ff00"
+    radar:
+      curveOpacity: 0
+---
+radar-beta
+axis a, b, c
+curve x{1, 2, 3}
+curve y{3, 2, 1}
+curve z{2, 2, 2}
+`)
+	if err != nil {
+		t.Fatalf("ParseMermaid returned error: %v", err)
+	}
+	layout := ComputeLayout(&out.Graph, DefaultRenderOptions().Theme, DefaultLayoutConfig())
+	svg := RenderSVG(layout, DefaultRenderOptions().Theme, DefaultLayoutConfig())
+	for _, rule := range []string{
+		`.radarCurve-0{color:#8686ff;fill:#8686ff;fill-opacity:0;`,
+		`.radarCurve-1{color:#ffff78;`,
+		`.radarCurve-2{color:#00ff00;`,
+		`.radarCurve-3{color:#c086ff;`,
+	} {
+		if !strings.Contains(svg, rule) {
+			t.Fatalf("expected %s in radar CSS", rule)
+		}
+	}
+}
+
+func TestRadarPolygonGraticuleDefaultsToPolygons(t *testing.T) {
+	out, err := ParseMermaid(`radar-beta
+axis a, b, c
+curve x{1, 2, 3}
+graticule polygon
+`)
+	if err != nil {
+		t.Fatalf("ParseMermaid returned error: %v", err)
+	}
+	layout := ComputeLayout(&out.Graph, DefaultRenderOptions().Theme, DefaultLayoutConfig())
+	if len(layout.RadarCurves) != 1 || !layout.RadarCurves[0].Polygon {
+		t.Fatalf("expected polygon curve, got %+v", layout.RadarCurves)
+	}
+}
+
+func TestRadarPartialConfigKeepsDefaults(t *testing.T) {
+	out, err := ParseMermaid("radar-beta\naxis a, b, c\ncurve x{1, 2, 3}\n")
+	if err != nil {
+		t.Fatalf("ParseMermaid returned error: %v", err)
+	}
+	config := DefaultLayoutConfig()
+	config.Radar = RadarConfig{Width: 600, Height: 600}
+	got := ComputeLayout(&out.Graph, DefaultRenderOptions().Theme, config)
+	want := ComputeLayout(&out.Graph, DefaultRenderOptions().Theme, DefaultLayoutConfig())
+	if got.Width != want.Width || got.Height != want.Height || got.RadarCurveOpacity != want.RadarCurveOpacity {
+		t.Fatalf("expected unset fields to take their defaults, got %.1fx%.1f opacity %v, want %.1fx%.1f opacity %v",
+			got.Width, got.Height, got.RadarCurveOpacity, want.Width, want.Height, want.RadarCurveOpacity)
+	}
+	if got.RadarCurves[0].Path != want.RadarCurves[0].Path {
+		t.Fatalf("expected the default axis scale and tension, got %q want %q", got.RadarCurves[0].Path, want.RadarCurves[0].Path)
+	}
+}
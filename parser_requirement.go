@@ -1,9 +1,35 @@
 package mermaid
 
-import "strings"
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+var (
+	requirementKinds = map[string]string{
+		"requirement":            "requirement",
+		"functionalrequirement":  "functionalRequirement",
+		"interfacerequirement":   "interfaceRequirement",
+		"performancerequirement": "performanceRequirement",
+		"physicalrequirement":    "physicalRequirement",
+		"designconstraint":       "designConstraint",
+		"element":                "element",
+	}
+	requirementRisks         = []string{"Low", "Medium", "High"}
+	requirementVerifyMethods = []string{"Analysis", "Inspection", "Test", "Demonstration"}
+	requirementRelations     = []string{"contains", "copies", "derives", "satisfies", "verifies", "refines", "traces"}
+)
+
+type requirementRelation struct {
+	from string
+	to   string
+	kind string
+	line int
+}
 
 func parseRequirement(input string) (ParseOutput, error) {
-	lines, err := preprocessInput(input)
+	lines, err := preprocessNumberedLines(input, false)
 	if err != nil {
 		return ParseOutput{}, err
 	}
@@ -11,22 +37,23 @@ func parseRequirement(input string) (ParseOutput, error) {
 	graph.Source = input
 
 	type requirementBlock struct {
-		kind string
-		id   string
-		attr map[string]string
+		kind    string
+		id      string
+		name    string
+		attr    map[string]string
+		classes []string
+		line    int
 	}
 
 	var current *requirementBlock
-	flushBlock := func(block *requirementBlock) {
-		if block == nil || block.id == "" {
-			return
-		}
-		label := buildRequirementNodeLabel(block.kind, block.id, block.attr)
-		graph.ensureNode(block.id, label, ShapeRectangle)
-	}
+	relations := make([]requirementRelation, 0, 8)
+	nodeClasses := map[string][]string{}
+	nodeStyles := map[string][]string{}
+	styleOrder := make([]string, 0, 4)
 
 	for idx, raw := range lines {
-		line := strings.TrimSpace(raw)
+		line := strings.TrimSpace(raw.Text)
+		lineNo := raw.No
 		if idx == 0 && strings.HasPrefix(lower(line), "requirementdiagram") {
 			continue
 		}
@@ -36,79 +63,249 @@ func parseRequirement(input string) (ParseOutput, error) {
 
 		if current != nil {
 			if line == "}" {
-				flushBlock(current)
+				label := buildRequirementNodeLabel(current.kind, current.name, current.attr)
+				graph.ensureNode(current.id, label, ShapeRectangle)
+				nodeClasses[current.id] = append(nodeClasses[current.id], current.classes...)
 				current = nil
 				continue
 			}
-			if sep := strings.Index(line, ":"); sep > 0 {
-				key := lower(strings.TrimSpace(line[:sep]))
-				value := strings.TrimSpace(stripQuotes(line[sep+1:]))
-				if key != "" && value != "" {
-					current.attr[key] = value
+			key, value, err := parseRequirementAttribute(current.kind, line)
+			if err != nil {
+				return ParseOutput{}, fmt.Errorf("requirementDiagram line %d: %s %q: %w", lineNo, current.kind, current.name, err)
+			}
+			current.attr[key] = value
+			continue
+		}
+
+		if parseClassDefDirective(&graph, line) {
+			continue
+		}
+		low := lower(line)
+		switch {
+		case strings.HasPrefix(low, "direction "):
+			dir, ok := parseDirectionLine(line)
+			if !ok {
+				return ParseOutput{}, fmt.Errorf("requirementDiagram line %d: invalid direction %q", lineNo, strings.TrimSpace(line[len("direction "):]))
+			}
+			graph.Direction = dir
+			continue
+		case strings.HasPrefix(low, "class "):
+			fields := requirementFields(line[len("class "):])
+			if len(fields) != 2 {
+				return ParseOutput{}, fmt.Errorf("requirementDiagram line %d: expected \"class <ids> <classes>\"", lineNo)
+			}
+			for _, id := range strings.Split(fields[0], ",") {
+				id = sanitizeID(id, strings.TrimSpace(id))
+				nodeClasses[id] = append(nodeClasses[id], strings.Split(fields[1], ",")...)
+			}
+			continue
+		case strings.HasPrefix(low, "style "):
+			rest := strings.TrimSpace(line[len("style "):])
+			fields := requirementFields(rest)
+			if len(fields) < 2 {
+				return ParseOutput{}, fmt.Errorf("requirementDiagram line %d: expected \"style <ids> <styles>\"", lineNo)
+			}
+			decls := splitStyleDeclarations(strings.TrimSpace(strings.TrimPrefix(rest, fields[0])))
+			for _, id := range strings.Split(fields[0], ",") {
+				id = sanitizeID(id, strings.TrimSpace(id))
+				if _, seen := nodeStyles[id]; !seen {
+					styleOrder = append(styleOrder, id)
 				}
+				nodeStyles[id] = append(nodeStyles[id], decls...)
 			}
 			continue
 		}
 
 		if strings.HasSuffix(line, "{") {
 			head := strings.TrimSpace(strings.TrimSuffix(line, "{"))
-			parts := strings.Fields(head)
-			if len(parts) >= 2 {
-				id := sanitizeID(parts[1], parts[1])
-				if id != "" {
-					current = &requirementBlock{
-						kind: lower(parts[0]),
-						id:   id,
-						attr: map[string]string{},
-					}
-				}
+			parts := requirementFields(head)
+			if len(parts) != 2 {
+				return ParseOutput{}, fmt.Errorf("requirementDiagram line %d: expected \"<type> <name> {\", got %q", lineNo, line)
+			}
+			kind, ok := requirementKinds[lower(parts[0])]
+			if !ok {
+				return ParseOutput{}, fmt.Errorf("requirementDiagram line %d: unknown requirement type %q", lineNo, parts[0])
+			}
+			name, classes := splitInlineClasses(parts[1])
+			name = stripQuotes(name)
+			id := sanitizeID(name, name)
+			if _, exists := graph.Nodes[id]; exists {
+				return ParseOutput{}, fmt.Errorf("requirementDiagram line %d: %q is defined more than once", lineNo, name)
+			}
+			current = &requirementBlock{
+				kind:    kind,
+				id:      id,
+				name:    name,
+				attr:    map[string]string{},
+				classes: classes,
+				line:    lineNo,
 			}
 			continue
 		}
 
-		if addRequirementRelationLine(&graph, line) {
+		if relation, ok, err := parseRequirementRelation(line); ok {
+			if err != nil {
+				return ParseOutput{}, fmt.Errorf("requirementDiagram line %d: %w", lineNo, err)
+			}
+			relation.line = lineNo
+			relations = append(relations, relation)
 			continue
 		}
-		if addEdgeFromLine(&graph, line) {
+
+		if name, classes := splitInlineClasses(line); len(classes) > 0 && !strings.ContainsAny(name, " \t") {
+			id := sanitizeID(name, name)
+			nodeClasses[id] = append(nodeClasses[id], classes...)
 			continue
 		}
+
+		return ParseOutput{}, fmt.Errorf("requirementDiagram line %d: unrecognized statement %q", lineNo, line)
+	}
+	if current != nil {
+		return ParseOutput{}, fmt.Errorf("requirementDiagram line %d: %s %q is missing its closing \"}\"", current.line, current.kind, current.name)
+	}
+
+	for _, relation := range relations {
+		for _, end := range []string{relation.from, relation.to} {
+			if _, ok := graph.Nodes[end]; !ok {
+				return ParseOutput{}, fmt.Errorf("requirementDiagram line %d: relation %s references undefined requirement or element %q", relation.line, relation.kind, end)
+			}
+		}
+		graph.addEdge(Edge{
+			From:     relation.from,
+			To:       relation.to,
+			Label:    "<<" + relation.kind + ">>",
+			Directed: true,
+			ArrowEnd: true,
+			Style:    EdgeDotted,
+		})
+	}
+
+	for _, id := range graph.NodeOrder {
+		if classes := nodeClasses[id]; len(classes) > 0 {
+			node := graph.Nodes[id]
+			graph.Nodes[id] = applyNodeStyles(node, graph.classStyleMap(classes...))
+		}
+	}
+	for id := range nodeClasses {
+		if _, ok := graph.Nodes[id]; !ok {
+			return ParseOutput{}, fmt.Errorf("requirementDiagram: class assigned to undefined requirement or element %q", id)
+		}
+	}
+	for _, id := range styleOrder {
+		node, ok := graph.Nodes[id]
+		if !ok {
+			return ParseOutput{}, fmt.Errorf("requirementDiagram: style applied to undefined requirement or element %q", id)
+		}
+		styles := map[string]string{}
+		for _, decl := range nodeStyles[id] {
+			if key, value, ok := strings.Cut(decl, ":"); ok {
+				styles[lower(strings.TrimSpace(key))] = strings.TrimSpace(value)
+			}
+		}
+		graph.Nodes[id] = applyNodeStyles(node, styles)
 	}
 
-	flushBlock(current)
 	return ParseOutput{Graph: graph}, nil
 }
 
-func addRequirementRelationLine(graph *Graph, line string) bool {
-	fields := strings.Fields(line)
+// parseRequirementAttribute validates one `key: value` line inside a
+// requirement or element block.
+func parseRequirementAttribute(kind, line string) (string, string, error) {
+	sep := strings.Index(line, ":")
+	if sep <= 0 {
+		return "", "", fmt.Errorf("expected \"key: value\", got %q", line)
+	}
+	key := lower(strings.TrimSpace(line[:sep]))
+	value := strings.TrimSpace(stripQuotes(strings.TrimSpace(line[sep+1:])))
+	if value == "" {
+		return "", "", fmt.Errorf("%s has no value", key)
+	}
+	if kind == "element" {
+		switch key {
+		case "type", "docref":
+			return key, value, nil
+		}
+		return "", "", fmt.Errorf("unknown element attribute %q (want type or docref)", key)
+	}
+	switch key {
+	case "id", "text":
+		return key, value, nil
+	case "risk":
+		return key, value, checkRequirementEnum("risk", value, requirementRisks)
+	case "verifymethod":
+		return key, value, checkRequirementEnum("verifymethod", value, requirementVerifyMethods)
+	}
+	return "", "", fmt.Errorf("unknown requirement attribute %q (want id, text, risk or verifymethod)", key)
+}
+
+func checkRequirementEnum(key, value string, allowed []string) error {
+	for _, candidate := range allowed {
+		if strings.EqualFold(candidate, value) {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid %s %q (want %s)", key, value, strings.Join(allowed, ", "))
+}
+
+// parseRequirementRelation parses `src - type -> dst` and `dst <- type - src`.
+// ok reports whether the line has the shape of a relation at all.
+func parseRequirementRelation(line string) (relation requirementRelation, ok bool, err error) {
+	fields := requirementFields(line)
 	if len(fields) < 5 {
-		return false
-	}
-	if fields[1] != "-" || fields[len(fields)-2] != "->" {
-		return false
-	}
-	fromID := sanitizeID(fields[0], "")
-	toID := sanitizeID(fields[len(fields)-1], "")
-	if fromID == "" || toID == "" {
-		return false
-	}
-	rel := strings.TrimSpace(strings.Join(fields[2:len(fields)-2], " "))
-	if rel == "" {
-		return false
-	}
-	rel = "<<" + lower(rel) + ">>"
-	graph.ensureNode(fromID, fromID, ShapeRectangle)
-	graph.ensureNode(toID, toID, ShapeRectangle)
-	graph.addEdge(Edge{
-		From:        fromID,
-		To:          toID,
-		Label:       rel,
-		Directed:    true,
-		ArrowEnd:    true,
-		Style:       EdgeDotted,
-		MarkerEnd:   "",
-		MarkerStart: "",
-	})
-	return true
+		return requirementRelation{}, false, nil
+	}
+	first, last := fields[1], fields[len(fields)-2]
+	var fromRaw, toRaw string
+	switch {
+	case first == "-" && last == "->":
+		fromRaw, toRaw = fields[0], fields[len(fields)-1]
+	case first == "<-" && last == "-":
+		fromRaw, toRaw = fields[len(fields)-1], fields[0]
+	default:
+		return requirementRelation{}, false, nil
+	}
+	kind := lower(strings.Join(fields[2:len(fields)-2], " "))
+	valid := false
+	for _, candidate := range requirementRelations {
+		if kind == candidate {
+			valid = true
+			break
+		}
+	}
+	if !valid {
+		return requirementRelation{}, true, fmt.Errorf("unknown relation type %q (want %s)", kind, strings.Join(requirementRelations, ", "))
+	}
+	return requirementRelation{
+		from: sanitizeID(fromRaw, fromRaw),
+		to:   sanitizeID(toRaw, toRaw),
+		kind: kind,
+	}, true, nil
+}
+
+// requirementFields splits a statement at whitespace, keeping a quoted
+// name such as "test req" together as one field.
+func requirementFields(line string) []string {
+	var fields []string
+	var field strings.Builder
+	quoted := false
+	for _, r := range line {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			field.WriteRune(r)
+		case !quoted && unicode.IsSpace(r):
+			if field.Len() > 0 {
+				fields = append(fields, field.String())
+				field.Reset()
+			}
+		default:
+			field.WriteRune(r)
+		}
+	}
+	if field.Len() > 0 {
+		fields = append(fields, field.String())
+	}
+	return fields
 }
 
 func buildRequirementNodeLabel(kind, id string, attrs map[string]string) string {
@@ -124,6 +321,7 @@ func buildRequirementNodeLabel(kind, id string, attrs map[string]string) string
 	appendIf("id", "ID")
 	appendIf("text", "Text")
 	appendIf("type", "Type")
+	appendIf("docref", "Doc Ref")
 	appendIf("risk", "Risk")
 	appendIf("verifymethod", "Verification")
 	return strings.Join(lines, "\n")
@@ -0,0 +1,148 @@
+package mermaid
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseRequirementGrammar(t *testing.T) {
+	input := `requirementDiagram
+  direction LR
+  designConstraint brakes:::critical {
+    id: "SAFE-1"
+    text: "Stop within 40m"
+    risk: Medium
+    verifymethod: demonstration
+  }
+  element ecu {
+    type: "hardware"
+    docref: specs/ecu.pdf
+  }
+  brakes <- verifies - ecu
+  style ecu fill:#dfd
+  classDef critical stroke:#c00,stroke-width:3px
+`
+
+	out, err := ParseMermaid(input)
+	if err != nil {
+		t.Fatalf("ParseMermaid returned error: %v", err)
+	}
+	graph := out.Graph
+	if graph.Direction != DirectionLeftRight {
+		t.Fatalf("expected LR direction, got %q", graph.Direction)
+	}
+	brakes := graph.Nodes["brakes"]
+	if !strings.Contains(brakes.Label, "<<Design Constraint>>") || !strings.Contains(brakes.Label, "Verification: Demonstration") {
+		t.Fatalf("unexpected requirement label: %q", brakes.Label)
+	}
+	if brakes.Stroke != "#c00" || brakes.StrokeWidth != 3 {
+		t.Fatalf("expected classDef styling, got %+v", brakes)
+	}
+	ecu := graph.Nodes["ecu"]
+	if !strings.Contains(ecu.Label, "Doc Ref: specs/ecu.pdf") || ecu.Fill != "#dfd" {
+		t.Fatalf("unexpected element: %+v", ecu)
+	}
+	if len(graph.Edges) != 1 || graph.Edges[0].From != "ecu" || graph.Edges[0].To != "brakes" || graph.Edges[0].Label != "<<verifies>>" {
+		t.Fatalf("unexpected relations: %+v", graph.Edges)
+	}
+}
+
+func TestParseRequirementQuotedNames(t *testing.T) {
+	input := `requirementDiagram
+  requirement "test req":::critical {
+    id: 1
+    text: "the test text."
+  }
+  element "test entity" {
+    type: simulation
+  }
+  "test entity" - satisfies -> "test req"
+  class "test entity" critical
+  classDef critical stroke:#c00
+  style "test req" fill:#f00
+`
+
+	out, err := ParseMermaid(input)
+	if err != nil {
+		t.Fatalf("ParseMermaid returned error: %v", err)
+	}
+	graph := out.Graph
+	req, ok := graph.Nodes["test_req"]
+	if !ok || !strings.Contains(req.Label, "\ntest req\n") || req.Stroke != "#c00" {
+		t.Fatalf("unexpected requirement: %+v", req)
+	}
+	if req.Fill != "#f00" {
+		t.Fatalf("expected style on the quoted requirement, got fill %q", req.Fill)
+	}
+	if entity := graph.Nodes["test_entity"]; entity.Stroke != "#c00" {
+		t.Fatalf("expected class on the quoted element, got %+v", entity)
+	}
+	if len(graph.Edges) != 1 || graph.Edges[0].From != "test_entity" || graph.Edges[0].To != "test_req" {
+		t.Fatalf("unexpected relations: %+v", graph.Edges)
+	}
+}
+
+func TestParseRequirementRejectsInvalidInput(t *testing.T) {
+	cases := map[string]struct {
+		body string
+		want string
+	}{
+		"risk enum": {
+			body: "requirement r1 {\n  risk: Hgh\n}",
+			want: `invalid risk "Hgh"`,
+		},
+		"verify enum": {
+			body: "requirement r1 {\n  verifymethod: Testing\n}",
+			want: `invalid verifymethod "Testing"`,
+		},
+		"element attribute": {
+			body: "element e1 {\n  risk: High\n}",
+			want: `unknown element attribute "risk"`,
+		},
+		"relation type": {
+			body: "requirement r1 {\n  id: 1\n}\nrequirement r2 {\n  id: 2\n}\nr1 - satisfys -> r2",
+			want: `unknown relation type "satisfys"`,
+		},
+		"dangling target": {
+			body: "requirement r1 {\n  id: 1\n}\nr1 - traces -> r9",
+			want: `undefined requirement or element "r9"`,
+		},
+		"unclosed block": {
+			body: "requirement r1 {\n  id: 1",
+			want: `missing its closing`,
+		},
+		"unknown type": {
+			body: "requirment r1 {\n}",
+			want: `unknown requirement type "requirment"`,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := ParseMermaid("requirementDiagram\n" + tc.body + "\n")
+			if err == nil {
+				t.Fatalf("expected error containing %q", tc.want)
+			}
+			if !strings.Contains(err.Error(), tc.want) {
+				t.Fatalf("expected error containing %q, got %v", tc.want, err)
+			}
+		})
+	}
+}
+
+func TestParseRequirementErrorsUseSourceLineNumbers(t *testing.T) {
+	input := `---
+title: Requirements
+---
+requirementDiagram
+%% comment before the block
+
+  requirement r1 {
+    id: 1
+    risk: extreme
+  }
+`
+	_, err := ParseMermaid(input)
+	if err == nil || !strings.Contains(err.Error(), "line 9:") {
+		t.Fatalf("expected error at source line 9, got %v", err)
+	}
+}
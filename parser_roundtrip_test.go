@@ -90,6 +90,151 @@ func TestParseFlowchartShapes(t *testing.T) {
 	assertNodeShape(t, g, "F", ShapeSubroutine)
 }
 
+func TestParseFlowchartV11Shapes(t *testing.T) {
+	input := `flowchart TD
+  A@{ shape: lean-l, label: "Out" }
+  B@{ shape: h-cyl }
+  C@{ shape: cross-circ }
+  D@{ shape: docs }
+  E@{ shape: bolt }
+  F@{ shape: win-pane }
+  G@{ shape: flip-tri }
+  H@{ shape: unknown-shape }
+  I[\Lean left\]
+  J[\Manual/]
+  K@{ shape: cloud }
+  L@{ shape: bang }`
+
+	parsed, err := ParseMermaid(input)
+	if err != nil {
+		t.Fatalf("ParseMermaid() error = %v", err)
+	}
+	g := parsed.Graph
+
+	assertNodeShape(t, g, "A", ShapeLeanLeft)
+	assertNodeShape(t, g, "B", ShapeHorizontalCylinder)
+	assertNodeShape(t, g, "C", ShapeCrossedCircle)
+	assertNodeShape(t, g, "D", ShapeStackedDocument)
+	assertNodeShape(t, g, "E", ShapeBolt)
+	assertNodeShape(t, g, "F", ShapeWindowPane)
+	assertNodeShape(t, g, "G", ShapeFlippedTriangle)
+	assertNodeShape(t, g, "H", ShapeRectangle)
+	assertNodeShape(t, g, "I", ShapeLeanLeft)
+	assertNodeShape(t, g, "J", ShapeTrapezoidTop)
+	assertNodeShape(t, g, "K", ShapeCloud)
+	assertNodeShape(t, g, "L", ShapeBang)
+	if label := g.Nodes["I"].Label; label != "Lean left" {
+		t.Fatalf("node I label = %q, want %q", label, "Lean left")
+	}
+}
+
+func TestParseFlowchartIconAndImageNodes(t *testing.T) {
+	input := `flowchart TD
+  A@{ icon: "fa:user", form: "square", label: "User", pos: "t", h: 60 } --> B@{ img: "https://example.com/a-b.png", label: "Logo", w: 80, constraint: "on" }
+  C@{ icon: "fa:db" }`
+
+	parsed, err := ParseMermaid(input)
+	if err != nil {
+		t.Fatalf("ParseMermaid() error = %v", err)
+	}
+	g := parsed.Graph
+
+	assertNodeShape(t, g, "A", ShapeIconSquare)
+	assertNodeShape(t, g, "B", ShapeImage)
+	assertNodeShape(t, g, "C", ShapeIcon)
+	a := g.Nodes["A"]
+	if a.Icon != "fa:user" || a.LabelPos != "t" || a.AssetHeight != 60 || a.Label != "User" {
+		t.Fatalf("unexpected icon node: %+v", a)
+	}
+	b := g.Nodes["B"]
+	if b.Img != "https://example.com/a-b.png" || b.AssetWidth != 80 || !b.Constraint {
+		t.Fatalf("unexpected image node: %+v", b)
+	}
+	if len(g.Edges) != 1 || g.Edges[0].From != "A" || g.Edges[0].To != "B" {
+		t.Fatalf("expected edge A->B, got %+v", g.Edges)
+	}
+}
+
+func TestParseFlowchartEdgeIDsAndMetadata(t *testing.T) {
+	input := `flowchart LR
+  A e1@--> B e2@==> C
+  C e3@-.->|late| D
+  e1@{ animate: true }
+  e2@{ animation: slow, curve: stepAfter }
+  classDef hot stroke:#f00,stroke-dasharray: 9\,5
+  class e3,C hot`
+
+	parsed, err := ParseMermaid(input)
+	if err != nil {
+		t.Fatalf("ParseMermaid() error = %v", err)
+	}
+	g := parsed.Graph
+	if len(g.Edges) != 3 || len(g.NodeOrder) != 4 {
+		t.Fatalf("expected 3 edges over 4 nodes, got %d edges and nodes %v", len(g.Edges), g.NodeOrder)
+	}
+	if _, ok := g.Nodes["e1"]; ok {
+		t.Fatalf("edge metadata statement created a node")
+	}
+	e1, e2, e3 := g.Edges[0], g.Edges[1], g.Edges[2]
+	if e1.ID != "e1" || e1.From != "A" || e1.To != "B" || e1.Animation != "fast" {
+		t.Fatalf("unexpected e1: %+v", e1)
+	}
+	if e2.ID != "e2" || e2.From != "B" || e2.Animation != "slow" || e2.Curve != "stepAfter" || e2.Style != EdgeThick {
+		t.Fatalf("unexpected e2: %+v", e2)
+	}
+	if e3.ID != "e3" || e3.Label != "late" || len(e3.Classes) != 1 || e3.Classes[0] != "hot" {
+		t.Fatalf("unexpected e3: %+v", e3)
+	}
+	if stroke := g.Nodes["C"].Stroke; stroke != "#f00" {
+		t.Fatalf("node C stroke = %q, want class stroke", stroke)
+	}
+	if got := g.ClassDefs["hot"]; len(got) != 2 || got[1] != "stroke-dasharray: 9,5" {
+		t.Fatalf("unexpected classDef declarations: %q", got)
+	}
+}
+
+func TestParseFlowchartEdgeLength(t *testing.T) {
+	input := `flowchart TD
+  A --> B
+  A ---> C
+  A ====> D
+  A -...-> E
+  A -- long text ----> F
+  A ---- G`
+
+	parsed, err := ParseMermaid(input)
+	if err != nil {
+		t.Fatalf("ParseMermaid() error = %v", err)
+	}
+	want := map[string]int{"B": 1, "C": 2, "D": 3, "E": 3, "F": 3, "G": 2}
+	for _, edge := range parsed.Graph.Edges {
+		if edge.MinLen != want[edge.To] {
+			t.Errorf("edge %s->%s MinLen = %d, want %d", edge.From, edge.To, edge.MinLen, want[edge.To])
+		}
+	}
+}
+
+func TestParseFlowchartMarkdownStrings(t *testing.T) {
+	input := "flowchart LR\n" +
+		"  A[\"`**Multi**\n" +
+		"  line`\"] -->|\"`_edge_`\"| B\n" +
+		"  B --> C[\"`plain`\"]"
+
+	parsed, err := ParseMermaid(input)
+	if err != nil {
+		t.Fatalf("ParseMermaid() error = %v", err)
+	}
+	if got := parsed.Graph.Nodes["A"].Label; got != "`**Multi**\nline`" {
+		t.Fatalf("A label = %q", got)
+	}
+	if got := parsed.Graph.Nodes["C"].Label; got != "`plain`" {
+		t.Fatalf("C label = %q", got)
+	}
+	if len(parsed.Graph.Edges) != 2 || parsed.Graph.Edges[0].Label != "`_edge_`" {
+		t.Fatalf("unexpected edges: %+v", parsed.Graph.Edges)
+	}
+}
+
 func TestParseFlowchartSubgraphs(t *testing.T) {
 	input := `flowchart TB
   subgraph Backend
@@ -115,6 +260,56 @@ func TestParseFlowchartSubgraphs(t *testing.T) {
 	}
 }
 
+func TestParseFlowchartSubgraphDirectionEdgesAndStyles(t *testing.T) {
+	input := `flowchart TB
+  one --> two
+  subgraph one
+    a1 --> a2
+  end
+  subgraph two
+    direction LR
+    subgraph inner
+      b1 --> b2
+    end
+  end
+  style one fill:#f9f,stroke:#333
+  classDef hot stroke-width:3px
+  class two hot`
+
+	parsed, err := ParseMermaid(input)
+	if err != nil {
+		t.Fatalf("ParseMermaid() error = %v", err)
+	}
+	g := parsed.Graph
+	if g.Direction != DirectionTopDown {
+		t.Fatalf("graph direction = %q, want TD", g.Direction)
+	}
+	for _, id := range []string{"one", "two", "inner"} {
+		if _, ok := g.Nodes[id]; ok {
+			t.Fatalf("subgraph %q should not also be a node", id)
+		}
+	}
+	if len(g.Edges) != 3 || g.Edges[0].From != "one" || g.Edges[0].To != "two" {
+		t.Fatalf("expected an edge between the subgraphs, got %+v", g.Edges)
+	}
+	sgs := map[string]FlowSubgraph{}
+	for _, sg := range g.FlowSubgraphs {
+		sgs[sg.ID] = sg
+	}
+	if sgs["two"].Direction != DirectionLeftRight || sgs["one"].Direction != "" {
+		t.Fatalf("subgraph directions = %q/%q", sgs["one"].Direction, sgs["two"].Direction)
+	}
+	if sgs["inner"].Parent != "two" || sgs["two"].Parent != "" {
+		t.Fatalf("inner parent = %q, two parent = %q", sgs["inner"].Parent, sgs["two"].Parent)
+	}
+	if sgs["one"].Fill != "#f9f" || sgs["one"].Stroke != "#333" {
+		t.Fatalf("style one = %+v", sgs["one"])
+	}
+	if sgs["two"].StrokeWidth != 3 || len(sgs["two"].Classes) != 1 {
+		t.Fatalf("class two = %+v", sgs["two"])
+	}
+}
+
 func TestParseFlowchartEdgeStyles(t *testing.T) {
 	input := `flowchart TD
   A --> B
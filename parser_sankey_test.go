@@ -1,6 +1,9 @@
 package mermaid
 
-import "testing"
+import (
+	"strings"
+	"testing"
+)
 
 func TestParseSankeyCSV(t *testing.T) {
 	input := `sankey-beta
@@ -23,3 +26,62 @@ func TestParseSankeyCSV(t *testing.T) {
 		t.Fatalf("unexpected first sankey link: %+v", first)
 	}
 }
+
+func TestSankeyConfigAlignmentAndLinkColor(t *testing.T) {
+	input := `---
+config:
+  sankey:
+    nodeAlignment: left
+    linkColor: source
+    prefix: "$"
+    suffix: " USD"
+---
+sankey-beta
+A,B,10
+A,C,5
+B,D,10
+`
+	out, err := ParseMermaid(input)
+	if err != nil {
+		t.Fatalf("ParseMermaid returned error: %v", err)
+	}
+	layout := ComputeLayout(&out.Graph, DefaultRenderOptions().Theme, DefaultLayoutConfig())
+
+	nodeX := map[string]float64{}
+	colors := map[string]string{}
+	for _, node := range layout.SankeyNodes {
+		nodeX[node.ID] = node.X0
+		colors[node.ID] = node.Color
+		if node.ID == "A" && node.Label != "A\n$15 USD" {
+			t.Fatalf("unexpected value label for A: %q", node.Label)
+		}
+	}
+	if nodeX["C"] != nodeX["B"] {
+		t.Fatalf("left alignment should keep sink C next to B, got C=%v B=%v", nodeX["C"], nodeX["B"])
+	}
+	for _, link := range layout.SankeyLinks {
+		if link.Stroke != colors[link.SourceID] {
+			t.Fatalf("expected source-colored link %s->%s, got %q", link.SourceID, link.TargetID, link.Stroke)
+		}
+	}
+
+	justified := ComputeLayout(&Graph{Kind: DiagramSankey, SankeyLinks: out.Graph.SankeyLinks}, DefaultRenderOptions().Theme, DefaultLayoutConfig())
+	for _, node := range justified.SankeyNodes {
+		if node.ID == "C" && node.X0 != nodeX["D"] {
+			t.Fatalf("justify alignment should push sink C to the last column")
+		}
+	}
+}
+
+func TestSankeyRenderHidesValuesAndUsesGradientDefs(t *testing.T) {
+	svg, err := Render("%%{init: {\"sankey\": {\"showValues\": false}}}%%\nsankey-beta\nA,B,10\n")
+	if err != nil {
+		t.Fatalf("render error: %v", err)
+	}
+	if !strings.Contains(svg, `text-anchor="start">A</text>`) {
+		t.Fatalf("expected value-less label, got %s", svg)
+	}
+	if !strings.Contains(svg, `<linearGradient`) || !strings.Contains(svg, `stroke="url(#linearGradient-`) {
+		t.Fatalf("expected gradient link stroke")
+	}
+}
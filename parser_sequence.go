@@ -33,6 +33,10 @@ func parseSequence(input string) (ParseOutput, error) {
 		if line == "" {
 			continue
 		}
+		if low := lower(line); strings.HasPrefix(low, "title ") || strings.HasPrefix(low, "title:") {
+			graph.Title = stripQuotes(strings.TrimSpace(line[len("title "):]))
+			continue
+		}
 
 		if participantID, participantLabel, ok := parseSequenceParticipant(line); ok {
 			if _, exists := participantSet[participantID]; !exists {
@@ -19,8 +19,12 @@ func parseTreemap(input string) (ParseOutput, error) {
 		if strings.HasPrefix(lower(trimmed), "treemap") {
 			continue
 		}
+		if parseClassDefDirective(&graph, trimmed) {
+			continue
+		}
 
 		depth := treemapDepth(raw)
+		trimmed, classes := splitInlineClasses(trimmed)
 		labelPart := trimmed
 		value := 0.0
 		hasValue := false
@@ -43,6 +47,7 @@ func parseTreemap(input string) (ParseOutput, error) {
 			Label:    label,
 			Value:    value,
 			HasValue: hasValue,
+			Class:    strings.Join(classes, " "),
 		})
 	}
 
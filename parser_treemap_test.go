@@ -1,6 +1,9 @@
 package mermaid
 
-import "testing"
+import (
+	"strings"
+	"testing"
+)
 
 func TestParseTreemap(t *testing.T) {
 	input := `treemap-beta
@@ -26,3 +29,102 @@ func TestParseTreemap(t *testing.T) {
 		t.Fatalf("expected parsed leaf value 140, got %+v", out.Graph.TreemapItems[2])
 	}
 }
+
+func TestParseTreemapClassDefs(t *testing.T) {
+	input := `treemap-beta
+"Section":::group
+  "Leaf": 1500:::hot
+classDef hot fill:#f00,stroke:rgb(0,0,0),color:white
+`
+
+	out, err := ParseMermaid(input)
+	if err != nil {
+		t.Fatalf("ParseMermaid returned error: %v", err)
+	}
+	items := out.Graph.TreemapItems
+	if len(items) != 2 {
+		t.Fatalf("expected 2 treemap items, got %d", len(items))
+	}
+	if items[0].Label != "Section" || items[0].Class != "group" {
+		t.Fatalf("unexpected section item: %+v", items[0])
+	}
+	if items[1].Label != "Leaf" || items[1].Value != 1500 || items[1].Class != "hot" {
+		t.Fatalf("unexpected leaf item: %+v", items[1])
+	}
+	styles := out.Graph.classStyleMap("hot")
+	if styles["fill"] != "#f00" || styles["stroke"] != "rgb(0,0,0)" || styles["color"] != "white" {
+		t.Fatalf("unexpected classDef styles: %#v", styles)
+	}
+}
+
+func TestTreemapLayoutHonoursConfigAndStyles(t *testing.T) {
+	input := `---
+config:
+  treemap:
+    valueFormat: "$,.2f"
+    tile: squarify
+---
+treemap-beta
+"Root"
+  "A": 1500:::hot
+  "B": 700
+  "C": 300
+  "D": 200
+classDef hot fill:#ff0000,stroke-width:5px
+`
+
+	out, err := ParseMermaid(input)
+	if err != nil {
+		t.Fatalf("ParseMermaid returned error: %v", err)
+	}
+	layout := ComputeLayout(&out.Graph, DefaultRenderOptions().Theme, DefaultLayoutConfig())
+
+	var hot *LayoutRect
+	leaves := 0
+	for i := range layout.Rects {
+		rect := &layout.Rects[i]
+		if !strings.HasPrefix(rect.Class, "treemapLeaf") {
+			continue
+		}
+		leaves++
+		if rect.Class == "treemapLeaf hot" {
+			hot = rect
+		}
+	}
+	if leaves != 4 {
+		t.Fatalf("expected 4 leaves, got %d", leaves)
+	}
+	if hot == nil || hot.Fill != "#ff0000" || hot.StrokeWidth != 5 {
+		t.Fatalf("expected classDef styles on leaf, got %+v", hot)
+	}
+	if hot.W < 100 || hot.H < 100 {
+		t.Fatalf("expected squarified tile for the largest leaf, got %.1fx%.1f", hot.W, hot.H)
+	}
+
+	values := map[string]bool{}
+	for _, text := range layout.Texts {
+		if text.Class == "treemapValue" {
+			values[text.Value] = true
+		}
+	}
+	if !values["$1,500.00"] || !values["$700.00"] {
+		t.Fatalf("expected formatted values, got %v", values)
+	}
+}
+
+func TestTreemapHidesValues(t *testing.T) {
+	input := `%%{init: {"treemap": {"showValues": false}}}%%
+treemap-beta
+"Root"
+  "A": 10
+  "B": 20
+`
+
+	svg, err := Render(input)
+	if err != nil {
+		t.Fatalf("Render returned error: %v", err)
+	}
+	if strings.Contains(svg, `class="treemapValue"`) {
+		t.Fatalf("expected no value labels when showValues is false")
+	}
+}
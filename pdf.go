@@ -0,0 +1,627 @@
+package mermaid
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/srwiley/rasterx"
+	"golang.org/x/image/math/fixed"
+)
+
+const (
+	pdfLetterWidth  = 612.0
+	pdfLetterHeight = 792.0
+	pdfPageMargin   = 36.0
+	// pdfPointsPerPixel converts CSS pixels to PDF points (72 / 96).
+	pdfPointsPerPixel = 0.75
+	// pdfImageScale is the pixels per layout unit of images placed in a PDF,
+	// both data URI images and diagrams the painter does not draw.
+	pdfImageScale = 2.0
+)
+
+// NoVectorPDFError is returned for a diagram kind the layout painter does
+// not draw, which has no vector PDF output, unless PDFOptions.AllowRaster
+// places it as an image.
+type NoVectorPDFError struct {
+	Kind DiagramKind
+}
+
+func (e *NoVectorPDFError) Error() string {
+	return fmt.Sprintf("diagram kind %q has no vector PDF output; set PDF.AllowRaster to place it as an image", e.Kind)
+}
+
+// RenderPDF renders a Mermaid diagram to a one-page PDF. Diagram kinds the
+// layout painter supports are written as vector paths with selectable text
+// in embedded font subsets. The others are a *NoVectorPDFError unless
+// options.PDF.AllowRaster places them as an image of their SVG.
+func RenderPDF(input string, options RenderOptions) ([]byte, error) {
+	if strings.TrimSpace(input) == "" {
+		return nil, fmt.Errorf("input diagram is empty")
+	}
+	return RenderPDFPages([]string{input}, options)
+}
+
+// RenderPDFPages renders each diagram onto its own page of a single PDF, in
+// order, e.g. the blocks ExtractMermaidBlocks finds in a markdown file.
+func RenderPDFPages(inputs []string, options RenderOptions) ([]byte, error) {
+	if len(inputs) == 0 {
+		return nil, fmt.Errorf("no diagrams to render")
+	}
+	doc := newPDFDocument()
+	for i, input := range inputs {
+		if err := doc.addDiagramPage(input, options); err != nil {
+			if len(inputs) > 1 {
+				return nil, fmt.Errorf("diagram %d: %w", i+1, err)
+			}
+			return nil, err
+		}
+	}
+	return doc.bytes()
+}
+
+// WritePDFFromSource renders a Mermaid diagram to a PDF file, or to stdout
+// when outputPath is empty. See RenderPDF.
+func WritePDFFromSource(mermaidCode string, outputPath string, options RenderOptions) error {
+	data, err := RenderPDF(mermaidCode, options)
+	if err != nil {
+		return err
+	}
+	return writeOutputBytes(data, outputPath)
+}
+
+// WritePDFPagesFromSource renders several diagrams as the pages of one PDF
+// file. See RenderPDFPages.
+func WritePDFPagesFromSource(diagrams []string, outputPath string, options RenderOptions) error {
+	data, err := RenderPDFPages(diagrams, options)
+	if err != nil {
+		return err
+	}
+	return writeOutputBytes(data, outputPath)
+}
+
+func writeOutputBytes(data []byte, outputPath string) error {
+	if outputPath == "" {
+		_, err := os.Stdout.Write(data)
+		return err
+	}
+	return os.WriteFile(outputPath, data, 0o644)
+}
+
+// pdfDocument accumulates the objects of a PDF file. Objects 1 to 3 are the
+// catalog, the page tree and the resource dictionary every page shares;
+// fonts are written last, once all text is known and can be subset.
+type pdfDocument struct {
+	objects [][]byte
+	pages   []int
+	title   string
+
+	fonts    map[string]*pdfFont
+	fontList []*pdfFont
+	states   map[string]string
+	stateDef []string
+	images   []string
+}
+
+const (
+	pdfCatalogID   = 1
+	pdfPagesID     = 2
+	pdfResourcesID = 3
+)
+
+func newPDFDocument() *pdfDocument {
+	doc := &pdfDocument{fonts: map[string]*pdfFont{}, states: map[string]string{}}
+	doc.reserve()
+	doc.reserve()
+	doc.reserve()
+	return doc
+}
+
+func (d *pdfDocument) reserve() int {
+	d.objects = append(d.objects, nil)
+	return len(d.objects)
+}
+
+func (d *pdfDocument) set(id int, body []byte) {
+	d.objects[id-1] = body
+}
+
+// setStream stores data Flate-compressed; extra holds further dictionary
+// entries.
+func (d *pdfDocument) setStream(id int, extra string, data []byte) {
+	var compressed bytes.Buffer
+	w, _ := zlib.NewWriterLevel(&compressed, zlib.BestCompression)
+	w.Write(data)
+	w.Close()
+	var body bytes.Buffer
+	fmt.Fprintf(&body, "<< /Length %d /Filter /FlateDecode", compressed.Len())
+	if extra != "" {
+		body.WriteString(" " + extra)
+	}
+	body.WriteString(" >>\nstream\n")
+	body.Write(compressed.Bytes())
+	body.WriteString("\nendstream")
+	d.set(id, body.Bytes())
+}
+
+// addDiagramPage lays out input and adds it as a page: a US Letter page
+// with the diagram shrunk to fit inside the margins, or with options.PDF.Fit
+// a page the size of the diagram.
+func (d *pdfDocument) addDiagramPage(input string, options RenderOptions) error {
+	if strings.TrimSpace(input) == "" {
+		return fmt.Errorf("input diagram is empty")
+	}
+	background, err := parsePDFBackground(options.Raster.Background)
+	if err != nil {
+		return err
+	}
+	parsed, err := ParseMermaid(input)
+	if err != nil {
+		return err
+	}
+	if err := ensureHighFidelityOrAllowApproximate(parsed.Graph.Kind, options); err != nil {
+		return err
+	}
+	if !canPaintLayout(parsed.Graph.Kind) && !options.PDF.AllowRaster {
+		return &NoVectorPDFError{Kind: parsed.Graph.Kind}
+	}
+	layout := ComputeLayout(&parsed.Graph, options.Theme, options.Layout)
+	if d.title == "" {
+		d.title = strings.TrimSpace(layout.AccTitle)
+		if d.title == "" {
+			d.title = strings.TrimSpace(layout.Title)
+		}
+	}
+
+	view := layoutViewBox(layout)
+	svg := ""
+	if !canPaintLayout(layout.Kind) {
+		svg = RenderSVG(layout, options.Theme, options.Layout)
+		if box, ok := parseSVGViewBox(svg); ok && box.W > 0 && box.H > 0 {
+			view = box
+		} else {
+			w, h := detectSVGSize(svg)
+			view = svgViewBox{W: float64(w), H: float64(h)}
+		}
+	}
+
+	scale := pdfPointsPerPixel
+	pageW, pageH := view.W*scale, view.H*scale
+	left, top := 0.0, 0.0
+	if !options.PDF.Fit {
+		scale *= min(1, (pdfLetterWidth-2*pdfPageMargin)/pageW, (pdfLetterHeight-2*pdfPageMargin)/pageH)
+		pageW, pageH = pdfLetterWidth, pdfLetterHeight
+		left, top = pdfPageMargin, pdfPageMargin
+	}
+
+	canvas := &pdfCanvas{doc: d}
+	if background != "" {
+		fmt.Fprintf(&canvas.content, "%s rg\n0 0 %s %s re f\n", background, formatPDFNumber(pageW), formatPDFNumber(pageH))
+	}
+	// Flip the y axis so everything below draws in layout units, y down.
+	fmt.Fprintf(&canvas.content, "%s 0 0 %s %s %s cm\n",
+		formatPDFNumber(scale), formatPDFNumber(-scale),
+		formatPDFNumber(left-scale*view.X), formatPDFNumber(pageH-top+scale*view.Y))
+	if svg == "" {
+		paintLayout(layout, options.Theme, canvas)
+	} else {
+		img, err := rasterizeSVGOnto(svg, scaledRasterSize(view.W, pdfImageScale), scaledRasterSize(view.H, pdfImageScale), image.Transparent)
+		if err != nil {
+			return err
+		}
+		canvas.drawImage(img, view.X, view.Y, view.W, view.H, rasterx.Identity)
+	}
+
+	content := d.reserve()
+	d.setStream(content, "", canvas.content.Bytes())
+	page := d.reserve()
+	d.set(page, []byte(fmt.Sprintf("<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %s %s] /Resources %d 0 R /Contents %d 0 R >>",
+		pdfPagesID, formatPDFNumber(pageW), formatPDFNumber(pageH), pdfResourcesID, content)))
+	d.pages = append(d.pages, page)
+	return nil
+}
+
+// parsePDFBackground returns the fill operands for a page background, or ""
+// for none. Pages are white in every viewer, so only explicit colors paint.
+func parsePDFBackground(value string) (string, error) {
+	value = strings.TrimSpace(value)
+	switch strings.ToLower(value) {
+	case "", "transparent", "none":
+		return "", nil
+	}
+	fill, _, ok := parsePaintColor(value)
+	if !ok {
+		return "", fmt.Errorf("invalid background color %q", value)
+	}
+	return pdfColor(fill), nil
+}
+
+// font returns the font resource for a run of family, embedding the same
+// TrueType file the raster painter would draw it with.
+func (d *pdfDocument) font(family string, run TextRun) *pdfFont {
+	if run.Code {
+		family = "monospace"
+	}
+	if strings.TrimSpace(family) == "" || resolveFontPath(family) == "" {
+		family = defaultMetricFontFamily
+	}
+	path := resolveStyledFontPath(family, run.Bold, run.Italic)
+	if f, ok := d.fonts[path]; ok {
+		return f
+	}
+	if path != "" {
+		if f, ok := newPDFTrueTypeFont(path); ok {
+			d.registerFont(path, f)
+			return f
+		}
+	}
+	base, ascent, descent := pdfStandardFont(run)
+	f, ok := d.fonts[base]
+	if !ok {
+		f = &pdfFont{base: base, ascent: ascent, descent: descent}
+		d.registerFont(base, f)
+	}
+	if path != "" {
+		d.fonts[path] = f
+	}
+	return f
+}
+
+func (d *pdfDocument) registerFont(key string, f *pdfFont) {
+	f.name = "F" + strconv.Itoa(len(d.fontList)+1)
+	f.id = d.reserve()
+	d.fonts[key] = f
+	d.fontList = append(d.fontList, f)
+}
+
+// extGState returns the resource name of a graphics state with the given
+// fill and stroke alpha.
+func (d *pdfDocument) extGState(fill, stroke float64) string {
+	key := formatPDFNumber(fill) + " " + formatPDFNumber(stroke)
+	if name, ok := d.states[key]; ok {
+		return name
+	}
+	name := "GS" + strconv.Itoa(len(d.states)+1)
+	d.states[key] = name
+	d.stateDef = append(d.stateDef, fmt.Sprintf("/%s << /ca %s /CA %s >>", name, formatPDFNumber(fill), formatPDFNumber(stroke)))
+	return name
+}
+
+// addImage writes img as an RGB image XObject, with a soft mask when it has
+// transparent pixels, and returns its resource name.
+func (d *pdfDocument) addImage(img *image.NRGBA) string {
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	rgb := make([]byte, 0, w*h*3)
+	alpha := make([]byte, 0, w*h)
+	opaque := true
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		row := img.Pix[img.PixOffset(bounds.Min.X, y):]
+		for x := 0; x < w; x++ {
+			px := row[x*4 : x*4+4]
+			rgb = append(rgb, px[0], px[1], px[2])
+			alpha = append(alpha, px[3])
+			opaque = opaque && px[3] == 255
+		}
+	}
+	extra := fmt.Sprintf("/Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /DeviceRGB /BitsPerComponent 8", w, h)
+	if !opaque {
+		mask := d.reserve()
+		d.setStream(mask, fmt.Sprintf("/Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /DeviceGray /BitsPerComponent 8", w, h), alpha)
+		extra += fmt.Sprintf(" /SMask %d 0 R", mask)
+	}
+	id := d.reserve()
+	d.setStream(id, extra, rgb)
+	name := "Im" + strconv.Itoa(len(d.images)+1)
+	d.images = append(d.images, fmt.Sprintf("/%s %d 0 R", name, id))
+	return name
+}
+
+// bytes finishes the document: fonts, shared resources, page tree, catalog,
+// info dictionary and cross-reference table.
+func (d *pdfDocument) bytes() ([]byte, error) {
+	fontRefs := make([]string, 0, len(d.fontList))
+	for _, f := range d.fontList {
+		if err := f.writeObjects(d); err != nil {
+			return nil, fmt.Errorf("embed font %s: %w", f.path, err)
+		}
+		fontRefs = append(fontRefs, fmt.Sprintf("/%s %d 0 R", f.name, f.id))
+	}
+	var resources strings.Builder
+	resources.WriteString("<< /ProcSet [/PDF /Text /ImageB /ImageC]")
+	if len(fontRefs) > 0 {
+		resources.WriteString(" /Font << " + strings.Join(fontRefs, " ") + " >>")
+	}
+	if len(d.stateDef) > 0 {
+		resources.WriteString(" /ExtGState << " + strings.Join(d.stateDef, " ") + " >>")
+	}
+	if len(d.images) > 0 {
+		resources.WriteString(" /XObject << " + strings.Join(d.images, " ") + " >>")
+	}
+	resources.WriteString(" >>")
+	d.set(pdfResourcesID, []byte(resources.String()))
+
+	kids := make([]string, len(d.pages))
+	for i, page := range d.pages {
+		kids[i] = strconv.Itoa(page) + " 0 R"
+	}
+	d.set(pdfPagesID, []byte(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages))))
+	d.set(pdfCatalogID, []byte(fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", pdfPagesID)))
+	info := d.reserve()
+	infoBody := "<< /Producer (mermaid-go-renderer)"
+	if d.title != "" {
+		infoBody += " /Title " + pdfTextString(d.title)
+	}
+	d.set(info, []byte(infoBody+" >>"))
+
+	var out bytes.Buffer
+	out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	offsets := make([]int, len(d.objects))
+	for i, body := range d.objects {
+		offsets[i] = out.Len()
+		fmt.Fprintf(&out, "%d 0 obj\n", i+1)
+		out.Write(body)
+		out.WriteString("\nendobj\n")
+	}
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(d.objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root %d 0 R /Info %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(d.objects)+1, pdfCatalogID, info, xref)
+	return out.Bytes(), nil
+}
+
+// pdfCanvas writes the layout primitives of one page as PDF content stream
+// operators. The page's base matrix already maps layout units, so paths
+// are written in layout coordinates.
+type pdfCanvas struct {
+	doc     *pdfDocument
+	content bytes.Buffer
+}
+
+func (c *pdfCanvas) fillStroke(path rasterx.Path, m rasterx.Matrix2D, style paintStyle) {
+	if len(path) == 0 {
+		return
+	}
+	opacity := paintOpacity(style.Opacity)
+	fill, fillAlpha, hasFill := parsePaintColor(style.Fill)
+	stroke, strokeAlpha, hasStroke := parsePaintColor(style.Stroke)
+	hasStroke = hasStroke && style.StrokeWidth > 0
+	if !hasFill && !hasStroke {
+		return
+	}
+	b := &c.content
+	b.WriteString("q\n")
+	fillAlpha *= paintOpacity(style.FillOpacity) * opacity
+	strokeAlpha *= paintOpacity(style.StrokeOpacity) * opacity
+	if !hasFill {
+		fillAlpha = 1
+	}
+	if !hasStroke {
+		strokeAlpha = 1
+	}
+	c.setAlpha(fillAlpha, strokeAlpha)
+	if hasFill {
+		fmt.Fprintf(b, "%s rg\n", pdfColor(fill))
+	}
+	if hasStroke {
+		capStyle := 0
+		switch strings.TrimSpace(style.LineCap) {
+		case "round":
+			capStyle = 1
+		case "square":
+			capStyle = 2
+		}
+		join := 0
+		switch strings.TrimSpace(style.LineJoin) {
+		case "round":
+			join = 1
+		case "bevel":
+			join = 2
+		}
+		fmt.Fprintf(b, "%s RG\n%s w\n%d J\n%d j\n4 M\n", pdfColor(stroke), formatPDFNumber(style.StrokeWidth), capStyle, join)
+		if dashes := parseDashArray(style.DashArray, 1); len(dashes) > 0 {
+			parts := make([]string, len(dashes))
+			for i, dash := range dashes {
+				parts[i] = formatPDFNumber(dash)
+			}
+			fmt.Fprintf(b, "[%s] 0 d\n", strings.Join(parts, " "))
+		}
+	}
+	path.AddTo(&rasterx.MatrixAdder{Adder: &pdfPathWriter{b: b}, M: m})
+	op := "S"
+	switch {
+	case hasFill && hasStroke:
+		op = "B"
+	case hasFill:
+		op = "f"
+	}
+	if hasFill && style.EvenOdd {
+		op += "*"
+	}
+	b.WriteString(op + "\nQ\n")
+}
+
+func (c *pdfCanvas) setAlpha(fill, stroke float64) {
+	if fill >= 1 && stroke >= 1 {
+		return
+	}
+	fmt.Fprintf(&c.content, "/%s gs\n", c.doc.extGState(fill, stroke))
+}
+
+// pdfTextRun is one run of a text line, encoded for its font.
+type pdfTextRun struct {
+	font *pdfFont
+	data []byte
+}
+
+func (c *pdfCanvas) text(label paintText) {
+	fill, alpha, ok := parsePaintColor(label.Color)
+	if !ok || len(label.Lines) == 0 {
+		return
+	}
+	size := max(1.0, label.Size)
+	regular := c.doc.font(label.Family, TextRun{})
+	offset := dominantBaselineOffset(regular.ascent*size/1000, regular.descent*size/1000, label.Baseline)
+	transform := label.Transform
+	if transform == (svgAffineTransform{}) {
+		transform = identitySVGTransform()
+	}
+
+	b := &c.content
+	b.WriteString("q\n")
+	c.setAlpha(alpha*paintOpacity(label.Opacity), 1)
+	fmt.Fprintf(b, "%s rg\n", pdfColor(fill))
+	if transform != identitySVGTransform() {
+		fmt.Fprintf(b, "%s %s %s %s %s %s cm\n",
+			formatPDFNumber(transform.A), formatPDFNumber(transform.B), formatPDFNumber(transform.C),
+			formatPDFNumber(transform.D), formatPDFNumber(transform.E), formatPDFNumber(transform.F))
+	}
+	b.WriteString("BT\n")
+	var current *pdfFont
+	for i, line := range label.Lines {
+		runs := make([]pdfTextRun, 0, len(line))
+		width := 0.0
+		for _, run := range visualRuns(line) {
+			if run.Text == "" {
+				continue
+			}
+			f := c.doc.font(label.Family, run)
+			data, advance := f.encode(displayText(run.Text))
+			runs = append(runs, pdfTextRun{font: f, data: data})
+			width += advance * size / 1000
+		}
+		if len(runs) == 0 {
+			continue
+		}
+		x := label.X
+		switch label.Anchor {
+		case "middle":
+			x -= width / 2
+		case "end":
+			x -= width
+		}
+		// The text matrix flips glyphs back upright under the page's y-down
+		// base matrix.
+		fmt.Fprintf(b, "1 0 0 -1 %s %s Tm\n", formatPDFNumber(x), formatPDFNumber(label.Y+float64(i)*label.LineStep+offset))
+		for _, run := range runs {
+			if run.font != current {
+				fmt.Fprintf(b, "/%s %s Tf\n", run.font.name, formatPDFNumber(size))
+				current = run.font
+			}
+			fmt.Fprintf(b, "<%X> Tj\n", run.data)
+		}
+	}
+	b.WriteString("ET\nQ\n")
+}
+
+func (c *pdfCanvas) image(href string, x, y, w, h float64, m rasterx.Matrix2D) {
+	if w <= 0 || h <= 0 {
+		return
+	}
+	pw, ph := scaledRasterSize(w, pdfImageScale), scaledRasterSize(h, pdfImageScale)
+	img := image.NewNRGBA(image.Rect(0, 0, pw, ph))
+	drawDataURIImage(img, href, 0, 0, float64(pw), float64(ph))
+	for i := 3; i < len(img.Pix); i += 4 {
+		if img.Pix[i] != 0 {
+			c.drawImage(img, x, y, w, h, m)
+			return
+		}
+	}
+}
+
+// drawImage places img over the box (x, y, w, h) in layout units.
+func (c *pdfCanvas) drawImage(img *image.NRGBA, x, y, w, h float64, m rasterx.Matrix2D) {
+	name := c.doc.addImage(img)
+	fmt.Fprintf(&c.content, "q\n%s %s %s %s %s %s cm\n%s 0 0 %s %s %s cm\n/%s Do\nQ\n",
+		formatPDFNumber(m.A), formatPDFNumber(m.B), formatPDFNumber(m.C),
+		formatPDFNumber(m.D), formatPDFNumber(m.E), formatPDFNumber(m.F),
+		formatPDFNumber(w), formatPDFNumber(-h), formatPDFNumber(x), formatPDFNumber(y+h), name)
+}
+
+// pdfPathWriter turns rasterx path commands into PDF path operators.
+type pdfPathWriter struct {
+	b    *bytes.Buffer
+	last fixed.Point26_6
+}
+
+func (p *pdfPathWriter) Start(a fixed.Point26_6) {
+	fmt.Fprintf(p.b, "%s %s m\n", pdfFixed(a.X), pdfFixed(a.Y))
+	p.last = a
+}
+
+func (p *pdfPathWriter) Line(b fixed.Point26_6) {
+	fmt.Fprintf(p.b, "%s %s l\n", pdfFixed(b.X), pdfFixed(b.Y))
+	p.last = b
+}
+
+// QuadBezier raises the curve to the cubic PDF paths support.
+func (p *pdfPathWriter) QuadBezier(b, c fixed.Point26_6) {
+	c1x := float64(p.last.X) + 2.0/3.0*float64(b.X-p.last.X)
+	c1y := float64(p.last.Y) + 2.0/3.0*float64(b.Y-p.last.Y)
+	c2x := float64(c.X) + 2.0/3.0*float64(b.X-c.X)
+	c2y := float64(c.Y) + 2.0/3.0*float64(b.Y-c.Y)
+	fmt.Fprintf(p.b, "%s %s %s %s %s %s c\n",
+		formatPDFNumber(c1x/64), formatPDFNumber(c1y/64), formatPDFNumber(c2x/64), formatPDFNumber(c2y/64),
+		pdfFixed(c.X), pdfFixed(c.Y))
+	p.last = c
+}
+
+func (p *pdfPathWriter) CubeBezier(b, c, d fixed.Point26_6) {
+	fmt.Fprintf(p.b, "%s %s %s %s %s %s c\n", pdfFixed(b.X), pdfFixed(b.Y), pdfFixed(c.X), pdfFixed(c.Y), pdfFixed(d.X), pdfFixed(d.Y))
+	p.last = d
+}
+
+func (p *pdfPathWriter) Stop(closeLoop bool) {
+	if closeLoop {
+		p.b.WriteString("h\n")
+	}
+}
+
+func pdfFixed(v fixed.Int26_6) string {
+	return formatPDFNumber(float64(v) / 64)
+}
+
+func pdfColor(c color.NRGBA) string {
+	return formatPDFNumber(float64(c.R)/255) + " " + formatPDFNumber(float64(c.G)/255) + " " + formatPDFNumber(float64(c.B)/255)
+}
+
+// formatPDFNumber writes v with at most three decimals; PDF has no
+// exponent notation.
+func formatPDFNumber(v float64) string {
+	v = math.Round(v*1000) / 1000
+	if v == 0 {
+		return "0"
+	}
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// pdfTextString encodes s as a PDF text string: a literal for ASCII, else
+// UTF-16BE with a byte order mark.
+func pdfTextString(s string) string {
+	ascii := true
+	for _, r := range s {
+		if r < 0x20 || r > 0x7e {
+			ascii = false
+			break
+		}
+	}
+	if ascii {
+		return "(" + strings.NewReplacer(`\`, `\\`, "(", `\(`, ")", `\)`).Replace(s) + ")"
+	}
+	var b strings.Builder
+	b.WriteString("<FEFF")
+	for _, unit := range utf16.Encode([]rune(s)) {
+		fmt.Fprintf(&b, "%04X", unit)
+	}
+	b.WriteString(">")
+	return b.String()
+}
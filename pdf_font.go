@@ -0,0 +1,492 @@
+package mermaid
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"sort"
+	"strings"
+	"unicode/utf16"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+)
+
+// pdfFont is one font resource of a PDF document. Fonts resolved from an
+// installed TrueType file are embedded as a CID font holding only the glyphs
+// the document uses; anything else falls back to a standard 14 font, which
+// PDF viewers supply themselves.
+type pdfFont struct {
+	name string
+	id   int
+	// path is the TrueType file, empty for a standard font.
+	path string
+	face *sfnt.Font
+	// base is the standard font name when path is empty.
+	base    string
+	ascent  float64
+	descent float64
+	// used maps each glyph drawn to the text it stands for, so the ToUnicode
+	// map keeps the text selectable and searchable.
+	used map[sfnt.GlyphIndex]string
+	buf  sfnt.Buffer
+}
+
+// pdfStandardFont returns the standard 14 font used when no TrueType file
+// can be embedded for a run.
+func pdfStandardFont(run TextRun) (base string, ascent, descent float64) {
+	if run.Code {
+		base = "Courier"
+		ascent, descent = 629, 157
+		switch {
+		case run.Bold && run.Italic:
+			base += "-BoldOblique"
+		case run.Bold:
+			base += "-Bold"
+		case run.Italic:
+			base += "-Oblique"
+		}
+		return base, ascent, descent
+	}
+	base = "Helvetica"
+	ascent, descent = 718, 207
+	switch {
+	case run.Bold && run.Italic:
+		base += "-BoldOblique"
+	case run.Bold:
+		base += "-Bold"
+	case run.Italic:
+		base += "-Oblique"
+	}
+	return base, ascent, descent
+}
+
+// newPDFTrueTypeFont loads the font at path for embedding. It reports false
+// for files that are not glyf-based TrueType, such as CFF OpenType fonts.
+func newPDFTrueTypeFont(path string) (*pdfFont, bool) {
+	face := loadFontFace(path)
+	if face == nil {
+		return nil, false
+	}
+	data, err := readFontData(path)
+	if err != nil {
+		return nil, false
+	}
+	if _, err := readTrueTypeTables(data); err != nil {
+		return nil, false
+	}
+	f := &pdfFont{path: path, face: face, used: map[sfnt.GlyphIndex]string{}}
+	if metrics, err := face.Metrics(&f.buf, fixed.I(1000), font.HintingNone); err == nil {
+		f.ascent = float64(metrics.Ascent) / 64
+		f.descent = float64(metrics.Descent) / 64
+	}
+	return f, true
+}
+
+// encode returns the bytes of a PDF string drawing text and its advance in
+// thousandths of the font size. Standard fonts use WinAnsiEncoding, so
+// characters outside Latin-1 become '?'.
+func (f *pdfFont) encode(text string) ([]byte, float64) {
+	if f.face == nil {
+		out := make([]byte, 0, len(text))
+		for _, r := range text {
+			if r < 0x20 || (r >= 0x7f && r < 0xa0) || r > 0xff {
+				r = '?'
+			}
+			out = append(out, byte(r))
+		}
+		if strings.HasPrefix(f.base, "Courier") {
+			return out, 600 * float64(len(out))
+		}
+		return out, measureTextWidthWithFontSize(string(out), 1000, false, "helvetica, arial, sans-serif")
+	}
+	out := make([]byte, 0, len(text)*2)
+	advance := 0.0
+	for _, r := range text {
+		glyph, err := f.face.GlyphIndex(&f.buf, r)
+		if err != nil {
+			glyph = 0
+		}
+		if _, ok := f.used[glyph]; !ok {
+			f.used[glyph] = string(r)
+		}
+		out = append(out, byte(glyph>>8), byte(glyph))
+		advance += f.glyphWidth(glyph)
+	}
+	return out, advance
+}
+
+func (f *pdfFont) glyphWidth(glyph sfnt.GlyphIndex) float64 {
+	advance, err := f.face.GlyphAdvance(&f.buf, glyph, fixed.I(1000), font.HintingNone)
+	if err != nil {
+		return 0
+	}
+	return float64(advance) / 64
+}
+
+// writeObjects writes the font's dictionaries into doc under the object
+// number reserved for it.
+func (f *pdfFont) writeObjects(doc *pdfDocument) error {
+	if f.face == nil {
+		doc.set(f.id, []byte(fmt.Sprintf("<< /Type /Font /Subtype /Type1 /BaseFont /%s /Encoding /WinAnsiEncoding >>", f.base)))
+		return nil
+	}
+	data, err := readFontData(f.path)
+	if err != nil {
+		return err
+	}
+	glyphs := make([]sfnt.GlyphIndex, 0, len(f.used)+1)
+	keep := map[sfnt.GlyphIndex]bool{0: true}
+	for glyph := range f.used {
+		glyphs = append(glyphs, glyph)
+		keep[glyph] = true
+	}
+	sort.Slice(glyphs, func(i, j int) bool { return glyphs[i] < glyphs[j] })
+	subset, err := subsetTrueType(data, keep)
+	if err != nil {
+		return err
+	}
+
+	// The six-letter subset tag is derived from the glyph set, so the same
+	// text always produces the same file.
+	var tagSeed bytes.Buffer
+	for _, glyph := range glyphs {
+		tagSeed.WriteByte(byte(glyph >> 8))
+		tagSeed.WriteByte(byte(glyph))
+	}
+	hash := crc32.ChecksumIEEE(append(tagSeed.Bytes(), f.path...))
+	tag := make([]byte, 6)
+	for i := range tag {
+		tag[i] = byte('A' + hash%26)
+		hash /= 26
+	}
+	name := pdfFontPostScriptName(f.face, &f.buf)
+	baseFont := string(tag) + "+" + name
+
+	var widths strings.Builder
+	for _, glyph := range glyphs {
+		fmt.Fprintf(&widths, "%d [%s] ", glyph, formatPDFNumber(f.glyphWidth(glyph)))
+	}
+	bounds, _ := f.face.Bounds(&f.buf, fixed.I(1000), font.HintingNone)
+	flags := 32
+	italicAngle := 0.0
+	if post := f.face.PostTable(); post != nil {
+		italicAngle = post.ItalicAngle
+		if post.IsFixedPitch {
+			flags |= 1
+		}
+	}
+	if italicAngle != 0 {
+		flags |= 64
+	}
+
+	descendant := doc.reserve()
+	descriptor := doc.reserve()
+	toUnicode := doc.reserve()
+	file := doc.reserve()
+	doc.set(f.id, []byte(fmt.Sprintf(
+		"<< /Type /Font /Subtype /Type0 /BaseFont /%s /Encoding /Identity-H /DescendantFonts [%d 0 R] /ToUnicode %d 0 R >>",
+		baseFont, descendant, toUnicode)))
+	doc.set(descendant, []byte(fmt.Sprintf(
+		"<< /Type /Font /Subtype /CIDFontType2 /BaseFont /%s /CIDSystemInfo << /Registry (Adobe) /Ordering (Identity) /Supplement 0 >> /FontDescriptor %d 0 R /CIDToGIDMap /Identity /DW 0 /W [%s] >>",
+		baseFont, descriptor, strings.TrimSpace(widths.String()))))
+	doc.set(descriptor, []byte(fmt.Sprintf(
+		"<< /Type /FontDescriptor /FontName /%s /Flags %d /FontBBox [%s %s %s %s] /ItalicAngle %s /Ascent %s /Descent %s /CapHeight %s /StemV 80 /FontFile2 %d 0 R >>",
+		baseFont, flags,
+		formatPDFNumber(float64(bounds.Min.X)/64), formatPDFNumber(float64(-bounds.Max.Y)/64),
+		formatPDFNumber(float64(bounds.Max.X)/64), formatPDFNumber(float64(-bounds.Min.Y)/64),
+		formatPDFNumber(italicAngle), formatPDFNumber(f.ascent), formatPDFNumber(-f.descent),
+		formatPDFNumber(f.ascent), file)))
+	doc.setStream(toUnicode, "", pdfToUnicodeCMap(glyphs, f.used))
+	doc.setStream(file, fmt.Sprintf("/Length1 %d", len(subset)), subset)
+	return nil
+}
+
+// pdfFontPostScriptName returns the font's PostScript name reduced to the
+// characters a PDF name may hold unescaped.
+func pdfFontPostScriptName(face *sfnt.Font, buf *sfnt.Buffer) string {
+	raw, err := face.Name(buf, sfnt.NameIDPostScript)
+	if err != nil || raw == "" {
+		raw, _ = face.Name(buf, sfnt.NameIDFull)
+	}
+	var b strings.Builder
+	for _, r := range raw {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '-' {
+			b.WriteRune(r)
+		}
+	}
+	if b.Len() == 0 {
+		return "Embedded"
+	}
+	return b.String()
+}
+
+// pdfToUnicodeCMap maps each two-byte glyph code back to its text.
+func pdfToUnicodeCMap(glyphs []sfnt.GlyphIndex, text map[sfnt.GlyphIndex]string) []byte {
+	var b strings.Builder
+	b.WriteString("/CIDInit /ProcSet findresource begin\n12 dict begin\nbegincmap\n")
+	b.WriteString("/CIDSystemInfo << /Registry (Adobe) /Ordering (UCS) /Supplement 0 >> def\n")
+	b.WriteString("/CMapName /Adobe-Identity-UCS def\n/CMapType 2 def\n")
+	b.WriteString("1 begincodespacerange\n<0000> <FFFF>\nendcodespacerange\n")
+	mapped := make([]sfnt.GlyphIndex, 0, len(glyphs))
+	for _, glyph := range glyphs {
+		if glyph != 0 && text[glyph] != "" {
+			mapped = append(mapped, glyph)
+		}
+	}
+	// A bfchar block holds at most 100 entries.
+	for start := 0; start < len(mapped); start += 100 {
+		end := min(start+100, len(mapped))
+		fmt.Fprintf(&b, "%d beginbfchar\n", end-start)
+		for _, glyph := range mapped[start:end] {
+			fmt.Fprintf(&b, "<%04X> <", uint16(glyph))
+			for _, unit := range utf16.Encode([]rune(text[glyph])) {
+				fmt.Fprintf(&b, "%04X", unit)
+			}
+			b.WriteString(">\n")
+		}
+		b.WriteString("endbfchar\n")
+	}
+	b.WriteString("endcmap\nCMapName currentdict /CMap defineresource pop\nend\nend\n")
+	return []byte(b.String())
+}
+
+// pdfEmbeddedTables are the TrueType tables a PDF viewer needs to draw an
+// embedded CID font; cmap, name and the rest are left out.
+var pdfEmbeddedTables = []string{"cvt ", "fpgm", "glyf", "head", "hhea", "hmtx", "loca", "maxp", "prep"}
+
+// readTrueTypeTables returns the tables of a TrueType file, or of the first
+// font of a collection, by tag.
+func readTrueTypeTables(data []byte) (map[string][]byte, error) {
+	base := 0
+	if len(data) >= 16 && string(data[:4]) == "ttcf" {
+		base = int(binary.BigEndian.Uint32(data[12:16]))
+	}
+	if base+12 > len(data) {
+		return nil, errors.New("truncated font header")
+	}
+	count := int(binary.BigEndian.Uint16(data[base+4:]))
+	if base+12+count*16 > len(data) {
+		return nil, errors.New("truncated table directory")
+	}
+	tables := make(map[string][]byte, count)
+	for i := 0; i < count; i++ {
+		record := data[base+12+i*16:]
+		offset := int(binary.BigEndian.Uint32(record[8:]))
+		length := int(binary.BigEndian.Uint32(record[12:]))
+		if offset < 0 || length < 0 || offset+length > len(data) {
+			return nil, fmt.Errorf("table %q out of range", record[:4])
+		}
+		tables[string(record[:4])] = data[offset : offset+length]
+	}
+	for _, tag := range []string{"glyf", "loca", "head", "hhea", "hmtx", "maxp"} {
+		if _, ok := tables[tag]; !ok {
+			return nil, fmt.Errorf("not a TrueType outline font: missing %q table", tag)
+		}
+	}
+	if len(tables["head"]) < 54 || len(tables["maxp"]) < 6 {
+		return nil, errors.New("truncated head or maxp table")
+	}
+	return tables, nil
+}
+
+// subsetTrueType returns a copy of the font whose glyf table keeps only the
+// outlines of keep and the components they reference. Glyph indices are left
+// unchanged, so text encoded with the original indices draws the same.
+func subsetTrueType(data []byte, keep map[sfnt.GlyphIndex]bool) ([]byte, error) {
+	tables, err := readTrueTypeTables(data)
+	if err != nil {
+		return nil, err
+	}
+	glyphs := trueTypeGlyphs(tables)
+	kept := keepTrueTypeComponents(glyphs, keep)
+
+	var newGlyf bytes.Buffer
+	newLoca := make([]byte, 0, (len(glyphs)+1)*4)
+	for glyph, outline := range glyphs {
+		newLoca = binary.BigEndian.AppendUint32(newLoca, uint32(newGlyf.Len()))
+		if kept[glyph] {
+			writeTrueTypeGlyph(&newGlyf, outline)
+		}
+	}
+	newLoca = binary.BigEndian.AppendUint32(newLoca, uint32(newGlyf.Len()))
+
+	head := append([]byte(nil), tables["head"]...)
+	binary.BigEndian.PutUint32(head[8:], 0)
+	binary.BigEndian.PutUint16(head[50:], 1)
+
+	out := make(map[string][]byte, len(pdfEmbeddedTables))
+	for _, tag := range pdfEmbeddedTables {
+		if table, ok := tables[tag]; ok {
+			out[tag] = table
+		}
+	}
+	out["glyf"] = newGlyf.Bytes()
+	out["loca"] = newLoca
+	out["head"] = head
+	return finishTrueType(out), nil
+}
+
+// trueTypeGlyphs slices the glyf table into the outline of every glyph.
+// Glyphs with a broken loca entry come back empty.
+func trueTypeGlyphs(tables map[string][]byte) [][]byte {
+	glyf := tables["glyf"]
+	loca := tables["loca"]
+	longOffsets := binary.BigEndian.Uint16(tables["head"][50:]) != 0
+	numGlyphs := int(binary.BigEndian.Uint16(tables["maxp"][4:]))
+	glyphs := make([][]byte, numGlyphs)
+	for glyph := range glyphs {
+		var start, end int
+		if longOffsets {
+			if (glyph+2)*4 > len(loca) {
+				continue
+			}
+			start = int(binary.BigEndian.Uint32(loca[glyph*4:]))
+			end = int(binary.BigEndian.Uint32(loca[glyph*4+4:]))
+		} else {
+			if (glyph+2)*2 > len(loca) {
+				continue
+			}
+			start = int(binary.BigEndian.Uint16(loca[glyph*2:])) * 2
+			end = int(binary.BigEndian.Uint16(loca[glyph*2+2:])) * 2
+		}
+		if start < end && end <= len(glyf) {
+			glyphs[glyph] = glyf[start:end]
+		}
+	}
+	return glyphs
+}
+
+// keepTrueTypeComponents extends keep with the glyphs that kept composite
+// glyphs are drawn from.
+func keepTrueTypeComponents(glyphs [][]byte, keep map[sfnt.GlyphIndex]bool) []bool {
+	pending := make([]int, 0, len(keep))
+	kept := make([]bool, len(glyphs))
+	for glyph := range keep {
+		if int(glyph) < len(glyphs) && !kept[glyph] {
+			kept[glyph] = true
+			pending = append(pending, int(glyph))
+		}
+	}
+	for len(pending) > 0 {
+		glyph := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		forEachTrueTypeComponent(glyphs[glyph], func(pos int) {
+			component := int(binary.BigEndian.Uint16(glyphs[glyph][pos:]))
+			if component < len(glyphs) && !kept[component] {
+				kept[component] = true
+				pending = append(pending, component)
+			}
+		})
+	}
+	return kept
+}
+
+// forEachTrueTypeComponent calls fn with the offset of each component glyph
+// index of a composite glyph outline. Simple glyphs have no components.
+func forEachTrueTypeComponent(outline []byte, fn func(pos int)) {
+	if len(outline) < 10 || int16(binary.BigEndian.Uint16(outline)) >= 0 {
+		return
+	}
+	for pos := 10; pos+4 <= len(outline); {
+		flags := binary.BigEndian.Uint16(outline[pos:])
+		fn(pos + 2)
+		pos += 4
+		if flags&0x0001 != 0 {
+			pos += 4
+		} else {
+			pos += 2
+		}
+		switch {
+		case flags&0x0008 != 0:
+			pos += 2
+		case flags&0x0040 != 0:
+			pos += 4
+		case flags&0x0080 != 0:
+			pos += 8
+		}
+		if flags&0x0020 == 0 {
+			return
+		}
+	}
+}
+
+// writeTrueTypeGlyph appends a glyph outline to a glyf table, padded so
+// long loca offsets stay 4-byte aligned.
+func writeTrueTypeGlyph(glyf *bytes.Buffer, outline []byte) {
+	glyf.Write(outline)
+	for glyf.Len()%4 != 0 {
+		glyf.WriteByte(0)
+	}
+}
+
+// finishTrueType writes the tables as a font file and sets the head
+// checksum adjustment so the whole file sums to the magic value.
+func finishTrueType(tables map[string][]byte) []byte {
+	font := writeTrueType(tables)
+	binary.BigEndian.PutUint32(font[writtenTableOffset(font, "head")+8:], 0xB1B0AFBA-trueTypeChecksum(font))
+	return font
+}
+
+// writeTrueType assembles tables into a font file with a sorted table
+// directory and per-table checksums.
+func writeTrueType(tables map[string][]byte) []byte {
+	tags := make([]string, 0, len(tables))
+	for tag := range tables {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	count := len(tags)
+	entrySelector := 0
+	for 1<<(entrySelector+1) <= count {
+		entrySelector++
+	}
+	searchRange := (1 << entrySelector) * 16
+
+	out := make([]byte, 0, 12+count*16)
+	out = binary.BigEndian.AppendUint32(out, 0x00010000)
+	out = binary.BigEndian.AppendUint16(out, uint16(count))
+	out = binary.BigEndian.AppendUint16(out, uint16(searchRange))
+	out = binary.BigEndian.AppendUint16(out, uint16(entrySelector))
+	out = binary.BigEndian.AppendUint16(out, uint16(count*16-searchRange))
+	offset := 12 + count*16
+	for _, tag := range tags {
+		table := tables[tag]
+		out = append(out, tag...)
+		out = binary.BigEndian.AppendUint32(out, trueTypeChecksum(table))
+		out = binary.BigEndian.AppendUint32(out, uint32(offset))
+		out = binary.BigEndian.AppendUint32(out, uint32(len(table)))
+		offset += (len(table) + 3) &^ 3
+	}
+	for _, tag := range tags {
+		out = append(out, tables[tag]...)
+		for len(out)%4 != 0 {
+			out = append(out, 0)
+		}
+	}
+	return out
+}
+
+func writtenTableOffset(font []byte, tag string) int {
+	count := int(binary.BigEndian.Uint16(font[4:]))
+	for i := 0; i < count; i++ {
+		record := font[12+i*16:]
+		if string(record[:4]) == tag {
+			return int(binary.BigEndian.Uint32(record[8:]))
+		}
+	}
+	return 0
+}
+
+func trueTypeChecksum(data []byte) uint32 {
+	var sum uint32
+	for i := 0; i < len(data); i += 4 {
+		var word [4]byte
+		copy(word[:], data[i:])
+		sum += binary.BigEndian.Uint32(word[:])
+	}
+	return sum
+}
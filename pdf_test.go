@@ -0,0 +1,222 @@
+package mermaid
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+)
+
+func TestRenderPDFWritesVectorPage(t *testing.T) {
+	input := "flowchart LR\n  A[Start] -->|go| B[End]\n  classDef hot fill:#ff0000,stroke:#0000ff\n  class B hot"
+	data, err := RenderPDF(input, DefaultRenderOptions())
+	if err != nil {
+		t.Fatalf("RenderPDF() error = %v", err)
+	}
+	if !bytes.HasPrefix(data, []byte("%PDF-1.4")) || !bytes.HasSuffix(data, []byte("%%EOF\n")) {
+		t.Fatal("expected a complete PDF file")
+	}
+	if !bytes.Contains(data, []byte("/MediaBox [0 0 612 792]")) {
+		t.Fatal("expected a US Letter page by default")
+	}
+	content := strings.Join(pdfStreams(t, data), "\n")
+	for _, want := range []string{"1 0 0 rg", " m\n", " l\n", "BT\n", " Tj\n"} {
+		if !strings.Contains(content, want) {
+			t.Fatalf("page content is missing %q", want)
+		}
+	}
+	if bytes.Contains(data, []byte("/Subtype /Image")) {
+		t.Fatal("flowchart should be drawn as vectors, not as an image")
+	}
+
+	fit, err := RenderPDF(input, DefaultRenderOptions().WithPDFFit(true))
+	if err != nil {
+		t.Fatalf("RenderPDF(fit) error = %v", err)
+	}
+	parsed, err := ParseMermaid(input)
+	if err != nil {
+		t.Fatalf("ParseMermaid() error = %v", err)
+	}
+	view := layoutViewBox(ComputeLayout(&parsed.Graph, DefaultRenderOptions().Theme, DefaultRenderOptions().Layout))
+	want := "/MediaBox [0 0 " + formatPDFNumber(view.W*0.75) + " " + formatPDFNumber(view.H*0.75) + "]"
+	if !bytes.Contains(fit, []byte(want)) {
+		t.Fatalf("expected fitted page %s", want)
+	}
+}
+
+func TestRenderPDFDrawsSequenceDiagramAsVectors(t *testing.T) {
+	data, err := RenderPDF("sequenceDiagram\n  Alice->>Bob: Hi\n  Note right of Bob: thinks", DefaultRenderOptions())
+	if err != nil {
+		t.Fatalf("RenderPDF() error = %v", err)
+	}
+	content := strings.Join(pdfStreams(t, data), "\n")
+	for _, want := range []string{" m\n", " l\n", "BT\n", " Tj\n"} {
+		if !strings.Contains(content, want) {
+			t.Fatalf("page content is missing %q", want)
+		}
+	}
+	if bytes.Contains(data, []byte("/Subtype /Image")) {
+		t.Fatal("sequence diagram should be drawn as vectors, not as an image")
+	}
+}
+
+func TestRenderPDFPagesWritesOnePagePerDiagram(t *testing.T) {
+	markdown := "# Doc\n\n```mermaid\npie title Pets\n  \"Dogs\" : 3\n```\n\n```mermaid\ngantt\n  title Plan\n  section Build\n  Task :a1, 2024-01-01, 3d\n```\n"
+	_, err := RenderPDFPages(ExtractMermaidBlocks(markdown), DefaultRenderOptions())
+	var noVector *NoVectorPDFError
+	if !errors.As(err, &noVector) || noVector.Kind != DiagramGantt || !strings.Contains(err.Error(), "diagram 2") {
+		t.Fatalf("expected the gantt chart to need AllowRaster, got %v", err)
+	}
+	data, err := RenderPDFPages(ExtractMermaidBlocks(markdown), DefaultRenderOptions().WithPDFAllowRaster(true))
+	if err != nil {
+		t.Fatalf("RenderPDFPages() error = %v", err)
+	}
+	if !bytes.Contains(data, []byte("/Count 2")) {
+		t.Fatal("expected two pages")
+	}
+	if got := bytes.Count(data, []byte("/Type /Page ")); got != 2 {
+		t.Fatalf("page objects = %d, want 2", got)
+	}
+	if !bytes.Contains(data, []byte("/Subtype /Image")) {
+		t.Fatal("expected the gantt chart to be placed as an image")
+	}
+
+	if _, err := RenderPDFPages([]string{"pie\n  \"A\" : 1", "   "}, DefaultRenderOptions()); err == nil || !strings.Contains(err.Error(), "diagram 2") {
+		t.Fatalf("expected an error naming diagram 2, got %v", err)
+	}
+}
+
+func TestSubsetTrueTypeKeepsOnlyUsedGlyphs(t *testing.T) {
+	full, err := sfnt.Parse(goregular.TTF)
+	if err != nil {
+		t.Fatalf("parse font: %v", err)
+	}
+	var buf sfnt.Buffer
+	glyphA, _ := full.GlyphIndex(&buf, 'A')
+	glyphB, _ := full.GlyphIndex(&buf, 'B')
+	subset, err := subsetTrueType(goregular.TTF, map[sfnt.GlyphIndex]bool{0: true, glyphA: true})
+	if err != nil {
+		t.Fatalf("subsetTrueType() error = %v", err)
+	}
+	if len(subset) >= len(goregular.TTF)/2 {
+		t.Fatalf("subset is %d bytes, original %d", len(subset), len(goregular.TTF))
+	}
+	if trueTypeChecksum(subset) != 0xB1B0AFBA {
+		t.Fatal("expected the head checksum adjustment to balance the file")
+	}
+
+	tables, err := readTrueTypeTables(subset)
+	if err != nil {
+		t.Fatalf("read subset: %v", err)
+	}
+	if _, ok := tables["cmap"]; ok {
+		t.Fatal("expected the cmap table to be dropped")
+	}
+	// sfnt needs cmap and post to parse, so graft the originals back on.
+	original := mustTrueTypeTables(t, goregular.TTF)
+	tables["cmap"] = original["cmap"]
+	tables["post"] = original["post"]
+	parsed, err := sfnt.Parse(writeTrueType(tables))
+	if err != nil {
+		t.Fatalf("parse subset: %v", err)
+	}
+	if segments, err := parsed.LoadGlyph(&buf, glyphA, fixed.I(16), nil); err != nil || len(segments) == 0 {
+		t.Fatalf("expected glyph A outline, got %d segments, err %v", len(segments), err)
+	}
+	if segments, err := parsed.LoadGlyph(&buf, glyphB, fixed.I(16), nil); err != nil || len(segments) != 0 {
+		t.Fatalf("expected glyph B to be emptied, got %d segments, err %v", len(segments), err)
+	}
+	if advance, _ := parsed.GlyphAdvance(&buf, glyphB, fixed.I(16), font.HintingNone); advance == 0 {
+		t.Fatal("expected advances to be kept for every glyph")
+	}
+}
+
+func TestPDFFontEmbedsSubsetWithToUnicode(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "Go-Regular.ttf")
+	if err := os.WriteFile(path, goregular.TTF, 0o644); err != nil {
+		t.Fatalf("write font: %v", err)
+	}
+	f, ok := newPDFTrueTypeFont(path)
+	if !ok {
+		t.Fatal("expected Go Regular to be embeddable")
+	}
+	doc := newPDFDocument()
+	doc.registerFont(path, f)
+	encoded, advance := f.encode("Hi")
+	if len(encoded) != 4 || advance <= 0 {
+		t.Fatalf("encode() = %x, %v", encoded, advance)
+	}
+	data, err := doc.bytes()
+	if err != nil {
+		t.Fatalf("bytes() error = %v", err)
+	}
+	for _, want := range []string{"/Subtype /Type0", "/Encoding /Identity-H", "/FontFile2", "+GoRegular"} {
+		if !bytes.Contains(data, []byte(want)) {
+			t.Fatalf("font objects are missing %q", want)
+		}
+	}
+	streams := strings.Join(pdfStreams(t, data), "\n")
+	if !regexp.MustCompile(`<[0-9A-F]{4}> <0048>`).MatchString(streams) {
+		t.Fatal("expected the ToUnicode map to cover 'H'")
+	}
+}
+
+func TestRenderPDFFallsBackToStandardFonts(t *testing.T) {
+	isolateFonts(t)
+	bundledFonts = nil
+	SetFontDirs()
+	doc := newPDFDocument()
+	f := doc.font("no such family", TextRun{Bold: true})
+	if f.face != nil || f.base != "Helvetica-Bold" {
+		t.Fatalf("fallback font = %q, want Helvetica-Bold", f.base)
+	}
+	if encoded, _ := f.encode("é→"); string(encoded) != "\xe9?" {
+		t.Fatalf("encode() = %q, want WinAnsi bytes", encoded)
+	}
+}
+
+func mustTrueTypeTables(t *testing.T, data []byte) map[string][]byte {
+	t.Helper()
+	tables, err := readTrueTypeTables(data)
+	if err != nil {
+		t.Fatalf("readTrueTypeTables() error = %v", err)
+	}
+	return tables
+}
+
+// pdfStreams inflates every Flate-compressed stream of a PDF file.
+func pdfStreams(t *testing.T, data []byte) []string {
+	t.Helper()
+	var out []string
+	for {
+		start := bytes.Index(data, []byte("stream\n"))
+		if start < 0 {
+			return out
+		}
+		data = data[start+len("stream\n"):]
+		end := bytes.Index(data, []byte("\nendstream"))
+		if end < 0 {
+			t.Fatal("unterminated stream")
+		}
+		r, err := zlib.NewReader(bytes.NewReader(data[:end]))
+		if err != nil {
+			t.Fatalf("inflate stream: %v", err)
+		}
+		inflated, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatalf("inflate stream: %v", err)
+		}
+		out = append(out, string(inflated))
+		data = data[end+len("\nendstream"):]
+	}
+}
@@ -9,7 +9,19 @@ import (
 	"strings"
 )
 
-func RenderSVG(layout Layout, theme Theme, _ LayoutConfig) string {
+func RenderSVG(layout Layout, theme Theme, config LayoutConfig) string {
+	layout.SVGLabels = layout.SVGLabels || config.SVGLabels
+	svg := renderSVGDocument(layout, theme)
+	svg = insertDiagramTitle(svg, layout, theme)
+	if config.EmbedFont {
+		if path := resolveFontPath(theme.FontFamily); path != "" {
+			svg = embedSVGFont(svg, path, theme.FontFamily)
+		}
+	}
+	return svg
+}
+
+func renderSVGDocument(layout Layout, theme Theme) string {
 	width := max(1.0, layout.Width)
 	height := max(1.0, layout.Height)
 	viewBoxX := 0.0
@@ -114,8 +126,20 @@ func RenderSVG(layout Layout, theme Theme, _ LayoutConfig) string {
 			b.WriteString(` aria-roledescription="` + html.EscapeString(ariaRoleDesc) + `"`)
 		}
 	}
+	if layout.AccTitle != "" {
+		b.WriteString(` aria-labelledby="chart-title-my-svg"`)
+	}
+	if layout.AccDescr != "" {
+		b.WriteString(` aria-describedby="chart-desc-my-svg"`)
+	}
 	b.WriteString(">")
 	b.WriteString("\n")
+	if layout.AccTitle != "" {
+		b.WriteString(`<title id="chart-title-my-svg">` + html.EscapeString(layout.AccTitle) + `</title>`)
+	}
+	if layout.AccDescr != "" {
+		b.WriteString(`<desc id="chart-desc-my-svg">` + html.EscapeString(layout.AccDescr) + `</desc>`)
+	}
 	if mermaidRoot {
 		if layout.Kind == DiagramPacket {
 			b.WriteString(`<style>#my-svg{font-family:"trebuchet ms",verdana,arial,sans-serif;font-size:16px;fill:#333;}#my-svg p{margin:0;}#my-svg .packetByte{font-size:10px;}#my-svg .packetByte.start{fill:black;}#my-svg .packetByte.end{fill:black;}#my-svg .packetLabel{fill:black;font-size:12px;}#my-svg .packetTitle{fill:black;font-size:14px;}#my-svg .packetBlock{stroke:black;stroke-width:1;fill:#efefef;}#my-svg :root{--mermaid-font-family:"trebuchet ms",verdana,arial,sans-serif;}</style>`)
@@ -132,7 +156,7 @@ func RenderSVG(layout Layout, theme Theme, _ LayoutConfig) string {
 		} else if layout.Kind == DiagramSankey {
 			b.WriteString(`<style>#my-svg{font-family:"trebuchet ms",verdana,arial,sans-serif;font-size:16px;fill:#333;}@keyframes edge-animation-frame{from{stroke-dashoffset:0;}}@keyframes dash{to{stroke-dashoffset:0;}}#my-svg .edge-animation-slow{stroke-dasharray:9,5!important;stroke-dashoffset:900;animation:dash 50s linear infinite;stroke-linecap:round;}#my-svg .edge-animation-fast{stroke-dasharray:9,5!important;stroke-dashoffset:900;animation:dash 20s linear infinite;stroke-linecap:round;}#my-svg .error-icon{fill:#552222;}#my-svg .error-text{fill:#552222;stroke:#552222;}#my-svg .edge-thickness-normal{stroke-width:1px;}#my-svg .edge-thickness-thick{stroke-width:3.5px;}#my-svg .edge-pattern-solid{stroke-dasharray:0;}#my-svg .edge-thickness-invisible{stroke-width:0;fill:none;}#my-svg .edge-pattern-dashed{stroke-dasharray:3;}#my-svg .edge-pattern-dotted{stroke-dasharray:2;}#my-svg .marker{fill:#333333;stroke:#333333;}#my-svg .marker.cross{stroke:#333333;}#my-svg svg{font-family:"trebuchet ms",verdana,arial,sans-serif;font-size:16px;}#my-svg p{margin:0;}#my-svg .label{font-family:"trebuchet ms",verdana,arial,sans-serif;}#my-svg :root{--mermaid-font-family:"trebuchet ms",verdana,arial,sans-serif;}</style>`)
 		} else if layout.Kind == DiagramRadar {
-			b.WriteString(`<style>` + radarStyleCSS() + `</style>`)
+			b.WriteString(`<style>` + radarStyleCSS(layout.RadarCurveColors, layout.RadarCurveOpacity) + `</style>`)
 		} else if layout.Kind == DiagramArchitecture {
 			b.WriteString(`<style>#my-svg{font-family:"trebuchet ms",verdana,arial,sans-serif;font-size:16px;fill:#333;}@keyframes edge-animation-frame{from{stroke-dashoffset:0;}}@keyframes dash{to{stroke-dashoffset:0;}}#my-svg .edge-animation-slow{stroke-dasharray:9,5!important;stroke-dashoffset:900;animation:dash 50s linear infinite;stroke-linecap:round;}#my-svg .edge-animation-fast{stroke-dasharray:9,5!important;stroke-dashoffset:900;animation:dash 20s linear infinite;stroke-linecap:round;}#my-svg .error-icon{fill:#552222;}#my-svg .error-text{fill:#552222;stroke:#552222;}#my-svg .edge-thickness-normal{stroke-width:1px;}#my-svg .edge-thickness-thick{stroke-width:3.5px;}#my-svg .edge-pattern-solid{stroke-dasharray:0;}#my-svg .edge-thickness-invisible{stroke-width:0;fill:none;}#my-svg .edge-pattern-dashed{stroke-dasharray:3;}#my-svg .edge-pattern-dotted{stroke-dasharray:2;}#my-svg .marker{fill:#333333;stroke:#333333;}#my-svg .marker.cross{stroke:#333333;}#my-svg svg{font-family:"trebuchet ms",verdana,arial,sans-serif;font-size:16px;}#my-svg p{margin:0;}#my-svg .edge{stroke-width:3;stroke:#333333;fill:none;}#my-svg .arrow{fill:#333333;}#my-svg .node-bkg{fill:none;stroke:hsl(240, 60%, 86.2745098039%);stroke-width:2px;stroke-dasharray:8;}#my-svg .node-icon-text{display:flex;align-items:center;}#my-svg .node-icon-text>div{color:#fff;margin:1px;height:fit-content;text-align:center;overflow:hidden;display:-webkit-box;-webkit-box-orient:vertical;}#my-svg :root{--mermaid-font-family:"trebuchet ms",verdana,arial,sans-serif;}</style>`)
 		} else if layout.Kind == DiagramMindmap {
@@ -164,7 +188,10 @@ func RenderSVG(layout Layout, theme Theme, _ LayoutConfig) string {
 		}
 		b.WriteString("\n")
 	}
-	if layout.Kind == DiagramZenUML {
+	// ZenUML is drawn as one HTML document. Its layout also holds the
+	// participants, messages and frames as shapes, which SVG label mode
+	// draws instead.
+	if layout.Kind == DiagramZenUML && !layout.SVGLabels {
 		b.WriteString("<g/>\n")
 		b.WriteString(renderZenUMLForeignObject(layout))
 		b.WriteString("\n</svg>\n")
@@ -176,7 +203,7 @@ func RenderSVG(layout Layout, theme Theme, _ LayoutConfig) string {
 		return b.String()
 	}
 	if layout.Kind == DiagramBlock {
-		b.WriteString(renderBlockMermaid(layout))
+		b.WriteString(renderBlockMermaid(layout, theme))
 		b.WriteString("</svg>\n")
 		return b.String()
 	}
@@ -186,7 +213,7 @@ func RenderSVG(layout Layout, theme Theme, _ LayoutConfig) string {
 		return b.String()
 	}
 	if layout.Kind == DiagramMindmap {
-		b.WriteString(renderMindmapMermaid(layout))
+		b.WriteString(renderMindmapMermaid(layout, theme))
 		b.WriteString(dropShadowDefs())
 		b.WriteString("</svg>\n")
 		return b.String()
@@ -224,84 +251,7 @@ func RenderSVG(layout Layout, theme Theme, _ LayoutConfig) string {
 		}
 	}
 	if includeArrowMarkers {
-		if layout.Kind == DiagramFlowchart {
-			b.WriteString(`<marker id="my-svg_flowchart-v2-pointEnd" class="marker flowchart-v2" viewBox="0 0 10 10" refX="5" refY="5" markerUnits="userSpaceOnUse" markerWidth="8" markerHeight="8" orient="auto"><path d="M 0 0 L 10 5 L 0 10 z" class="arrowMarkerPath" style="stroke-width: 1; stroke-dasharray: 1, 0;"/></marker>`)
-			b.WriteString("\n")
-			b.WriteString(`<marker id="my-svg_flowchart-v2-pointStart" class="marker flowchart-v2" viewBox="0 0 10 10" refX="4.5" refY="5" markerUnits="userSpaceOnUse" markerWidth="8" markerHeight="8" orient="auto"><path d="M 0 5 L 10 10 L 10 0 z" class="arrowMarkerPath" style="stroke-width: 1; stroke-dasharray: 1, 0;"/></marker>`)
-			b.WriteString("\n")
-			b.WriteString(`<marker id="my-svg_flowchart-v2-pointEnd-margin" class="marker flowchart-v2" viewBox="0 0 11.5 14" refX="11.5" refY="7" markerUnits="userSpaceOnUse" markerWidth="10.5" markerHeight="14" orient="auto"><path d="M 0 0 L 11.5 7 L 0 14 z" class="arrowMarkerPath" style="stroke-width: 0; stroke-dasharray: 1, 0;"/></marker>`)
-			b.WriteString("\n")
-			b.WriteString(`<marker id="my-svg_flowchart-v2-pointStart-margin" class="marker flowchart-v2" viewBox="0 0 11.5 14" refX="1" refY="7" markerUnits="userSpaceOnUse" markerWidth="11.5" markerHeight="14" orient="auto"><polygon points="0,7 11.5,14 11.5,0" class="arrowMarkerPath" style="stroke-width: 0; stroke-dasharray: 1, 0;"/></marker>`)
-			b.WriteString("\n")
-			b.WriteString(`<marker id="my-svg_flowchart-v2-circleEnd" class="marker flowchart-v2" viewBox="0 0 10 10" refX="11" refY="5" markerUnits="userSpaceOnUse" markerWidth="11" markerHeight="11" orient="auto"><circle cx="5" cy="5" r="5" class="arrowMarkerPath" style="stroke-width: 1; stroke-dasharray: 1, 0;"/></marker>`)
-			b.WriteString("\n")
-			b.WriteString(`<marker id="my-svg_flowchart-v2-circleStart" class="marker flowchart-v2" viewBox="0 0 10 10" refX="-1" refY="5" markerUnits="userSpaceOnUse" markerWidth="11" markerHeight="11" orient="auto"><circle cx="5" cy="5" r="5" class="arrowMarkerPath" style="stroke-width: 1; stroke-dasharray: 1, 0;"/></marker>`)
-			b.WriteString("\n")
-			b.WriteString(`<marker id="my-svg_flowchart-v2-circleEnd-margin" class="marker flowchart-v2" viewBox="0 0 10 10" refY="5" refX="12.25" markerUnits="userSpaceOnUse" markerWidth="14" markerHeight="14" orient="auto"><circle cx="5" cy="5" r="5" class="arrowMarkerPath" style="stroke-width: 0; stroke-dasharray: 1, 0;"/></marker>`)
-			b.WriteString("\n")
-			b.WriteString(`<marker id="my-svg_flowchart-v2-circleStart-margin" class="marker flowchart-v2" viewBox="0 0 10 10" refX="-2" refY="5" markerUnits="userSpaceOnUse" markerWidth="14" markerHeight="14" orient="auto"><circle cx="5" cy="5" r="5" class="arrowMarkerPath" style="stroke-width: 0; stroke-dasharray: 1, 0;"/></marker>`)
-			b.WriteString("\n")
-			b.WriteString(`<marker id="my-svg_flowchart-v2-crossEnd" class="marker cross flowchart-v2" viewBox="0 0 11 11" refX="12" refY="5.2" markerUnits="userSpaceOnUse" markerWidth="11" markerHeight="11" orient="auto"><path d="M 1,1 l 9,9 M 10,1 l -9,9" class="arrowMarkerPath" style="stroke-width: 2; stroke-dasharray: 1, 0;"/></marker>`)
-			b.WriteString("\n")
-			b.WriteString(`<marker id="my-svg_flowchart-v2-crossStart" class="marker cross flowchart-v2" viewBox="0 0 11 11" refX="-1" refY="5.2" markerUnits="userSpaceOnUse" markerWidth="11" markerHeight="11" orient="auto"><path d="M 1,1 l 9,9 M 10,1 l -9,9" class="arrowMarkerPath" style="stroke-width: 2; stroke-dasharray: 1, 0;"/></marker>`)
-			b.WriteString("\n")
-			b.WriteString(`<marker id="my-svg_flowchart-v2-crossEnd-margin" class="marker cross flowchart-v2" viewBox="0 0 15 15" refX="17.7" refY="7.5" markerUnits="userSpaceOnUse" markerWidth="12" markerHeight="12" orient="auto"><path d="M 1,1 L 14,14 M 1,14 L 14,1" class="arrowMarkerPath" style="stroke-width: 2.5;"/></marker>`)
-			b.WriteString("\n")
-			b.WriteString(`<marker id="my-svg_flowchart-v2-crossStart-margin" class="marker cross flowchart-v2" viewBox="0 0 15 15" refX="-3.5" refY="7.5" markerUnits="userSpaceOnUse" markerWidth="12" markerHeight="12" orient="auto"><path d="M 1,1 L 14,14 M 1,14 L 14,1" class="arrowMarkerPath" style="stroke-width: 2.5; stroke-dasharray: 1, 0;"/></marker>`)
-			b.WriteString("\n")
-		} else if layout.Kind == DiagramClass {
-			writeClassMarkerDefsSeparate(&b)
-		} else if layout.Kind == DiagramRequirement {
-			b.WriteString(`<defs><marker id="my-svg_requirement-requirement_containsStart" refX="0" refY="10" markerWidth="20" markerHeight="20" orient="auto"><g><circle cx="10" cy="10" r="9" fill="none"/><line x1="1" x2="19" y1="10" y2="10"/><line y1="1" y2="19" x1="10" x2="10"/></g></marker></defs>`)
-			b.WriteString("\n")
-			b.WriteString(`<defs><marker id="my-svg_requirement-requirement_arrowEnd" refX="20" refY="10" markerWidth="20" markerHeight="20" orient="auto"><path d="M0,0 L20,10 M20,10 L0,20"/></marker></defs>`)
-			b.WriteString("\n")
-		} else {
-			if layout.Kind == DiagramC4 {
-				writeC4Defs(&b)
-			} else if layout.Kind == DiagramER {
-				writeERMarkerDefs(&b)
-			} else {
-				noMarkerKinds := layout.Kind == DiagramXYChart ||
-					layout.Kind == DiagramPie ||
-					layout.Kind == DiagramSankey ||
-					layout.Kind == DiagramGantt ||
-					layout.Kind == DiagramPacket ||
-					layout.Kind == DiagramTreemap ||
-					layout.Kind == DiagramQuadrant ||
-					layout.Kind == DiagramArchitecture ||
-					layout.Kind == DiagramRadar ||
-					layout.Kind == DiagramGitGraph ||
-					layout.Kind == DiagramKanban ||
-					layout.Kind == DiagramZenUML
-				if !noMarkerKinds {
-					b.WriteString("<defs>\n")
-					if layout.Kind == DiagramState {
-						b.WriteString(`<marker id="my-svg_stateDiagram-barbEnd" refX="19" refY="7" markerWidth="20" markerHeight="14" markerUnits="userSpaceOnUse" orient="auto">`)
-						b.WriteString(`<path d="M 19,7 L9,13 L14,7 L9,1 Z"/>`)
-						b.WriteString(`</marker>`)
-						b.WriteString("\n")
-					} else if layout.Kind == DiagramTimeline || layout.Kind == DiagramJourney {
-						b.WriteString(`<marker id="arrowhead" refX="5" refY="2" markerWidth="6" markerHeight="4" orient="auto">`)
-						b.WriteString(`<path d="M 0,0 V 4 L6,2 Z"/>`)
-						b.WriteString(`</marker>`)
-						b.WriteString("\n")
-					} else {
-						b.WriteString(`<marker id="arrow-end" markerWidth="10" markerHeight="7" refX="8" refY="3.5" orient="auto" markerUnits="strokeWidth">`)
-						b.WriteString(`<path d="M0,0 L10,3.5 L0,7 z" fill="`)
-						b.WriteString(theme.LineColor)
-						b.WriteString(`"/></marker>`)
-						b.WriteString("\n")
-						b.WriteString(`<marker id="arrow-start" markerWidth="10" markerHeight="7" refX="2" refY="3.5" orient="auto" markerUnits="strokeWidth">`)
-						b.WriteString(`<path d="M10,0 L0,3.5 L10,7 z" fill="`)
-						b.WriteString(theme.LineColor)
-						b.WriteString(`"/></marker>`)
-						b.WriteString("\n")
-					}
-					b.WriteString("</defs>\n")
-				}
-			}
-		}
+		writeArrowMarkerDefs(&b, layout.Kind, theme)
 	}
 
 	if mermaidRoot && layout.Kind != DiagramC4 && layout.Kind != DiagramQuadrant && layout.Kind != DiagramKanban {
@@ -332,6 +282,10 @@ func RenderSVG(layout Layout, theme Theme, _ LayoutConfig) string {
 					if label != "" {
 						textW = max(1.0, measureTextWidth(label, false)+8)
 						textH = 24.0
+						if len(edge.LabelLines) > 0 {
+							textW = max(1.0, labelLinesWidth(edge.LabelLines, 16, "", false)+8)
+							textH = 24.0 * float64(len(edge.LabelLines))
+						}
 						labelX := (edge.X1 + edge.X2) / 2
 						labelY := (edge.Y1+edge.Y2)/2 - 6
 						outerTransform = ` transform="translate(` + formatFloat(labelX) + `,` + formatFloat(labelY) + `)"`
@@ -339,8 +293,19 @@ func RenderSVG(layout Layout, theme Theme, _ LayoutConfig) string {
 						innerY = -textH / 2
 					}
 					b.WriteString(`<g class="edgeLabel"` + outerTransform + `><g class="label" data-id="` + edgeID + `" transform="translate(` + formatFloat(innerX) + `, ` + formatFloat(innerY) + `)">`)
+					if layout.SVGLabels {
+						lines := edge.LabelLines
+						if len(lines) == 0 {
+							lines = plainLabelLines(label)
+						}
+						writeSVGTextLabel(&b, svgTextLabel{W: textW, H: textH, Lines: lines}, theme.PrimaryTextColor)
+						b.WriteString(`</g></g>`)
+						continue
+					}
 					b.WriteString(`<foreignObject width="` + formatFloat(textW) + `" height="` + formatFloat(textH) + `"><div xmlns="http://www.w3.org/1999/xhtml" class="labelBkg" style="display: table-cell; white-space: nowrap; line-height: 1.5; max-width: 200px; text-align: center;"><span class="edgeLabel">`)
-					if label != "" {
+					if len(edge.LabelLines) > 0 {
+						b.WriteString(`<p>` + labelLinesHTML(edge.LabelLines) + `</p>`)
+					} else if label != "" {
 						b.WriteString(`<p>` + html.EscapeString(label) + `</p>`)
 					}
 					b.WriteString(`</span></div></foreignObject></g></g>`)
@@ -378,7 +343,7 @@ func RenderSVG(layout Layout, theme Theme, _ LayoutConfig) string {
 		return b.String()
 	}
 	if layout.Kind == DiagramClass {
-		b.WriteString(renderClassMermaid(layout))
+		b.WriteString(renderClassMermaid(layout, theme))
 		if mermaidRoot {
 			b.WriteString("</g>\n")
 		}
@@ -400,7 +365,7 @@ func RenderSVG(layout Layout, theme Theme, _ LayoutConfig) string {
 		return b.String()
 	}
 	if layout.Kind == DiagramKanban {
-		b.WriteString(renderKanbanMermaid(layout))
+		b.WriteString(renderKanbanMermaid(layout, theme))
 		b.WriteString("</svg>\n")
 		return b.String()
 	}
@@ -537,8 +502,9 @@ func RenderSVG(layout Layout, theme Theme, _ LayoutConfig) string {
 		if strings.TrimSpace(path.Transform) != "" {
 			b.WriteString(` transform="` + html.EscapeString(path.Transform) + `"`)
 		}
+		extraStyle := strings.TrimSpace(path.Style)
 		if styleOnlyPrimitives || mermaidLike {
-			b.WriteString(` style="` + html.EscapeString(mermaidStyle(
+			style := mermaidStyle(
 				fill,
 				stroke,
 				strokeWidth,
@@ -548,7 +514,13 @@ func RenderSVG(layout Layout, theme Theme, _ LayoutConfig) string {
 				path.FillOpacity,
 				path.StrokeOpacity,
 				path.Opacity,
-			)) + `"`)
+			)
+			if extraStyle != "" {
+				style += " " + extraStyle + ";"
+			}
+			b.WriteString(` style="` + html.EscapeString(style) + `"`)
+		} else if extraStyle != "" {
+			b.WriteString(` style="` + html.EscapeString(extraStyle) + `"`)
 		}
 		if !styleOnlyPrimitives && strings.TrimSpace(path.DashArray) != "" {
 			b.WriteString(` stroke-dasharray="` + html.EscapeString(path.DashArray) + `"`)
@@ -875,6 +847,28 @@ func RenderSVG(layout Layout, theme Theme, _ LayoutConfig) string {
 		}
 		b.WriteString("\n")
 	}
+	for _, img := range layout.Images {
+		if groupPrimitives {
+			b.WriteString(`<g class="node default" transform="translate(0,0)">`)
+		}
+		b.WriteString(`<image`)
+		if strings.TrimSpace(img.ID) != "" {
+			b.WriteString(` id="` + html.EscapeString(img.ID) + `"`)
+		}
+		if strings.TrimSpace(img.Class) != "" {
+			b.WriteString(` class="` + html.EscapeString(img.Class) + `"`)
+		}
+		b.WriteString(fmt.Sprintf(` x="%s" y="%s" width="%s" height="%s"`,
+			formatFloat(img.X), formatFloat(img.Y), formatFloat(img.W), formatFloat(img.H)))
+		if strings.TrimSpace(img.Preserve) != "" {
+			b.WriteString(` preserveAspectRatio="` + html.EscapeString(img.Preserve) + `"`)
+		}
+		b.WriteString(` href="` + html.EscapeString(img.Href) + `"/>`)
+		if groupPrimitives {
+			b.WriteString("</g>")
+		}
+		b.WriteString("\n")
+	}
 	if layout.Kind == DiagramC4 {
 		iconHref := "data:image/svg+xml,%3Csvg xmlns='http://www.w3.org/2000/svg' width='16' height='16' viewBox='0 0 16 16'%3E%3Ccircle cx='8' cy='4' r='3' fill='none' stroke='%230B4884' stroke-width='1.2'/%3E%3Cpath d='M3 15c0-2.8 2.2-5 5-5s5 2.2 5 5' fill='none' stroke='%230B4884' stroke-width='1.2'/%3E%3C/svg%3E"
 		for _, node := range layout.Nodes {
@@ -934,6 +928,10 @@ func RenderSVG(layout Layout, theme Theme, _ LayoutConfig) string {
 			}
 			textW := max(1.0, measureTextWidthWithFontSize(text.Value, size, false, family))
 			textH := max(16.0, size*1.5)
+			if len(text.Lines) > 0 {
+				textW = max(1.0, labelLinesWidth(text.Lines, size, family, false))
+				textH *= float64(len(text.Lines))
+			}
 			if layout.Kind == DiagramER && strings.TrimSpace(text.Value) == "" {
 				textW = 0
 				textH = 0
@@ -954,49 +952,85 @@ func RenderSVG(layout Layout, theme Theme, _ LayoutConfig) string {
 			labelWithTransform := layout.Kind == DiagramER || layout.Kind == DiagramClass || layout.Kind == DiagramFlowchart
 			if labelWithTransform {
 				y = text.Y - textH/2
+				if len(text.Lines) > 1 {
+					// Multi-line labels are positioned by their first line.
+					y += float64(len(text.Lines)-1) * max(14, size*1.2) / 2
+				}
 				groupTransform = `translate(` + formatFloat(x) + `,` + formatFloat(y) + `)`
 			}
 			b.WriteString(`<g class="` + html.EscapeString(outerClass) + `" transform="` + groupTransform + `">`)
 
 			if layout.Kind == DiagramClass && textClass == "class-edge-label" {
 				b.WriteString(`<g class="label" data-id="` + html.EscapeString(textID) + `" transform="translate(0, 0)">`)
-				b.WriteString(`<foreignObject width="` + formatFloat(textW) + `" height="` + formatFloat(textH) + `">`)
-				b.WriteString(`<div class="labelBkg" style="display: table-cell; white-space: nowrap; line-height: 1.5; max-width: 200px; text-align: center;" xmlns="http://www.w3.org/1999/xhtml"><span class="edgeLabel">`)
-				if strings.TrimSpace(text.Value) != "" {
-					b.WriteString(`<p>`)
-					b.WriteString(html.EscapeString(text.Value))
-					b.WriteString(`</p>`)
+				if layout.SVGLabels {
+					writeSVGTextLabel(&b, svgTextLabel{W: textW, H: textH, Lines: plainLabelLines(text.Value)}, theme.PrimaryTextColor)
+				} else {
+					b.WriteString(`<foreignObject width="` + formatFloat(textW) + `" height="` + formatFloat(textH) + `">`)
+					b.WriteString(`<div class="labelBkg" style="display: table-cell; white-space: nowrap; line-height: 1.5; max-width: 200px; text-align: center;" xmlns="http://www.w3.org/1999/xhtml"><span class="edgeLabel">`)
+					if strings.TrimSpace(text.Value) != "" {
+						b.WriteString(`<p>`)
+						b.WriteString(html.EscapeString(text.Value))
+						b.WriteString(`</p>`)
+					}
+					b.WriteString(`</span></div></foreignObject>`)
 				}
-				b.WriteString(`</span></div></foreignObject></g>`)
+				b.WriteString(`</g>`)
 			} else if layout.Kind == DiagramER && outerClass == "edgeLabel" {
 				b.WriteString(`<g class="label"`)
 				if textID != "" {
 					b.WriteString(` data-id="` + html.EscapeString(textID) + `"`)
 				}
 				b.WriteString(` transform="translate(0, 0)">`)
-				b.WriteString(`<foreignObject width="` + formatFloat(textW) + `" height="` + formatFloat(textH) + `">`)
-				b.WriteString(`<div class="labelBkg" style="display: table-cell; white-space: nowrap; line-height: 1.5; max-width: 200px; text-align: center;" xmlns="http://www.w3.org/1999/xhtml"><span class="edgeLabel">`)
-				if strings.TrimSpace(text.Value) != "" {
-					b.WriteString(`<p>`)
-					b.WriteString(html.EscapeString(text.Value))
-					b.WriteString(`</p>`)
+				if layout.SVGLabels {
+					writeSVGTextLabel(&b, svgTextLabel{W: textW, H: textH, Lines: plainLabelLines(text.Value)}, theme.PrimaryTextColor)
+				} else {
+					b.WriteString(`<foreignObject width="` + formatFloat(textW) + `" height="` + formatFloat(textH) + `">`)
+					b.WriteString(`<div class="labelBkg" style="display: table-cell; white-space: nowrap; line-height: 1.5; max-width: 200px; text-align: center;" xmlns="http://www.w3.org/1999/xhtml"><span class="edgeLabel">`)
+					if strings.TrimSpace(text.Value) != "" {
+						b.WriteString(`<p>`)
+						b.WriteString(html.EscapeString(text.Value))
+						b.WriteString(`</p>`)
+					}
+					b.WriteString(`</span></div></foreignObject>`)
 				}
-				b.WriteString(`</span></div></foreignObject></g>`)
+				b.WriteString(`</g>`)
 			} else if layout.Kind == DiagramClass {
 				styleAttr := ""
 				if weight != "" && weight != "400" {
 					styleAttr = ` style="font-weight: ` + html.EscapeString(weight) + `"`
 				}
 				b.WriteString(`<g class="label"` + styleAttr + ` transform="translate(0,-12)">`)
-				b.WriteString(`<foreignObject width="` + formatFloat(textW) + `" height="` + formatFloat(textH) + `">`)
-				b.WriteString(`<div style="display: table-cell; white-space: nowrap; line-height: 1.5; max-width: 200px; text-align: center;" xmlns="http://www.w3.org/1999/xhtml"><span class="nodeLabel markdown-node-label" style=""><p>`)
-				b.WriteString(html.EscapeString(text.Value))
-				b.WriteString(`</p></span></div></foreignObject></g>`)
+				if layout.SVGLabels {
+					writeSVGTextLabel(&b, svgTextLabel{W: textW, H: textH, Lines: plainLabelLines(text.Value)}, theme.PrimaryTextColor)
+				} else {
+					b.WriteString(`<foreignObject width="` + formatFloat(textW) + `" height="` + formatFloat(textH) + `">`)
+					b.WriteString(`<div style="display: table-cell; white-space: nowrap; line-height: 1.5; max-width: 200px; text-align: center;" xmlns="http://www.w3.org/1999/xhtml"><span class="nodeLabel markdown-node-label" style=""><p>`)
+					b.WriteString(html.EscapeString(text.Value))
+					b.WriteString(`</p></span></div></foreignObject>`)
+				}
+				b.WriteString(`</g>`)
 			} else {
 				if (layout.Kind == DiagramFlowchart && outerClass != "edgeLabel") ||
 					(layout.Kind == DiagramER && outerClass != "edgeLabel") {
 					b.WriteString(`<rect/>`)
 				}
+				htmlLabel := layout.Kind == DiagramFlowchart || layout.Kind == DiagramRequirement || layout.Kind == DiagramER
+				if layout.SVGLabels {
+					label := svgTextLabel{W: textW, H: textH, Lines: textLabelLines(text), Opacity: text.Opacity, Transform: text.Transform}
+					if !labelWithTransform {
+						label.X, label.Y = x, y
+					}
+					if htmlLabel {
+						label.Anchor = anchor
+					} else {
+						label.Size, label.Family, label.Weight = size, family, weight
+						label.Color = defaultColor(color, "#1b263b")
+					}
+					writeSVGTextLabel(&b, label, theme.PrimaryTextColor)
+					b.WriteString(`</g>`)
+					b.WriteString("\n")
+					continue
+				}
 				b.WriteString(`<foreignObject`)
 				if labelWithTransform {
 					b.WriteString(fmt.Sprintf(` width="%s" height="%s"`, formatFloat(textW), formatFloat(textH)))
@@ -1010,7 +1044,7 @@ func RenderSVG(layout Layout, theme Theme, _ LayoutConfig) string {
 				if strings.TrimSpace(text.Transform) != "" {
 					b.WriteString(` transform="` + html.EscapeString(text.Transform) + `"`)
 				}
-				if layout.Kind == DiagramFlowchart || layout.Kind == DiagramRequirement || layout.Kind == DiagramER {
+				if htmlLabel {
 					align := "center"
 					if anchor == "start" {
 						align = "start"
@@ -1018,7 +1052,11 @@ func RenderSVG(layout Layout, theme Theme, _ LayoutConfig) string {
 						align = "right"
 					}
 					b.WriteString(`><div xmlns="http://www.w3.org/1999/xhtml" style="display: table-cell; white-space: nowrap; line-height: 1.5; max-width: 200px; text-align: ` + align + `;"><span class="nodeLabel markdown-node-label"><p>`)
-					b.WriteString(html.EscapeString(text.Value))
+					if len(text.Lines) > 0 {
+						b.WriteString(labelLinesHTML(text.Lines))
+					} else {
+						b.WriteString(html.EscapeString(text.Value))
+					}
 					b.WriteString(`</p></span></div></foreignObject>`)
 				} else {
 					b.WriteString(`><div xmlns="http://www.w3.org/1999/xhtml" style="display: inline-block; white-space: nowrap;">`)
@@ -1068,14 +1106,19 @@ func RenderSVG(layout Layout, theme Theme, _ LayoutConfig) string {
 				}
 				b.WriteString(`<g class="` + html.EscapeString(groupClass) + `" style="text-align:left !important" transform="translate(` + formatFloat(x) + `, ` + formatFloat(y) + `)">`)
 				b.WriteString(`<rect/>`)
-				b.WriteString(`<foreignObject width="` + formatFloat(textW) + `" height="` + formatFloat(textH) + `">`)
-				divStyle := `text-align: center; display: table-cell; white-space: nowrap; line-height: 1.5; max-width: 175px;`
-				if strings.Contains(textClass, "kanban-card-text") && textH >= 48 {
-					divStyle = `text-align: center; display: table; white-space: break-spaces; line-height: 1.5; max-width: 175px; width: 175px;`
+				if layout.SVGLabels {
+					writeSVGTextLabel(&b, svgTextLabel{W: textW, H: textH, Lines: plainLabelLines(text.Value)}, theme.PrimaryTextColor)
+				} else {
+					b.WriteString(`<foreignObject width="` + formatFloat(textW) + `" height="` + formatFloat(textH) + `">`)
+					divStyle := `text-align: center; display: table-cell; white-space: nowrap; line-height: 1.5; max-width: 175px;`
+					if strings.Contains(textClass, "kanban-card-text") && textH >= 48 {
+						divStyle = `text-align: center; display: table; white-space: break-spaces; line-height: 1.5; max-width: 175px; width: 175px;`
+					}
+					b.WriteString(`<div style="` + divStyle + `" xmlns="http://www.w3.org/1999/xhtml"><span style="text-align:left !important" class="nodeLabel"><p>`)
+					b.WriteString(html.EscapeString(text.Value))
+					b.WriteString(`</p></span></div></foreignObject>`)
 				}
-				b.WriteString(`<div style="` + divStyle + `" xmlns="http://www.w3.org/1999/xhtml"><span style="text-align:left !important" class="nodeLabel"><p>`)
-				b.WriteString(html.EscapeString(text.Value))
-				b.WriteString(`</p></span></div></foreignObject></g>`)
+				b.WriteString(`</g>`)
 				b.WriteString("\n")
 				if wrapTextGroup {
 					b.WriteString("</g>\n")
@@ -1112,15 +1155,22 @@ func RenderSVG(layout Layout, theme Theme, _ LayoutConfig) string {
 					boxClass = "task"
 				}
 				textSize := formatFloat(max(12.0, text.Size))
-				b.WriteString(`<g><switch>`)
-				b.WriteString(`<foreignObject x="` + formatFloat(text.BoxX) + `" y="` + formatFloat(text.BoxY) + `" width="` + formatFloat(text.BoxW) + `" height="` + formatFloat(text.BoxH) + `">`)
-				b.WriteString(`<div class="` + html.EscapeString(boxClass) + `" xmlns="http://www.w3.org/1999/xhtml" style="display: table; height: 100%; width: 100%;"><div class="label" style="display: table-cell; text-align: center; vertical-align: middle;">`)
-				b.WriteString(html.EscapeString(text.Value))
-				b.WriteString(`</div></div></foreignObject>`)
+				b.WriteString(`<g>`)
+				if !layout.SVGLabels {
+					b.WriteString(`<switch>`)
+					b.WriteString(`<foreignObject x="` + formatFloat(text.BoxX) + `" y="` + formatFloat(text.BoxY) + `" width="` + formatFloat(text.BoxW) + `" height="` + formatFloat(text.BoxH) + `">`)
+					b.WriteString(`<div class="` + html.EscapeString(boxClass) + `" xmlns="http://www.w3.org/1999/xhtml" style="display: table; height: 100%; width: 100%;"><div class="label" style="display: table-cell; text-align: center; vertical-align: middle;">`)
+					b.WriteString(html.EscapeString(text.Value))
+					b.WriteString(`</div></div></foreignObject>`)
+				}
 				b.WriteString(`<text x="` + formatFloat(text.X) + `" y="` + formatFloat(text.Y) + `" dominant-baseline="central" alignment-baseline="central" style="text-anchor: middle; font-size: ` + textSize + `px; font-family: &quot;Open Sans&quot;, sans-serif;">`)
 				b.WriteString(`<tspan x="` + formatFloat(text.X) + `" dy="0">`)
 				b.WriteString(html.EscapeString(text.Value))
-				b.WriteString(`</tspan></text></switch></g>`)
+				b.WriteString(`</tspan></text>`)
+				if !layout.SVGLabels {
+					b.WriteString(`</switch>`)
+				}
+				b.WriteString(`</g>`)
 				b.WriteString("\n")
 				if wrapTextGroup {
 					b.WriteString("</g>\n")
@@ -1177,7 +1227,15 @@ func RenderSVG(layout Layout, theme Theme, _ LayoutConfig) string {
 			if len(lines) == 0 {
 				lines = []string{""}
 			}
-			if useTspanText(layout.Kind) || len(lines) > 1 {
+			if len(text.Lines) > 0 {
+				b.WriteString(` alignment-baseline="middle" dominant-baseline="middle" dy="0">`)
+				tspanX := formatFloat(text.X)
+				if useTransformPos {
+					tspanX = "0"
+				}
+				b.WriteString(labelLinesTSpans(text.Lines, tspanX, max(14, size*1.2)))
+				b.WriteString("</text>\n")
+			} else if useTspanText(layout.Kind) || len(lines) > 1 {
 				b.WriteString(` alignment-baseline="middle" dominant-baseline="middle" dy="0">`)
 				tspanX := formatFloat(text.X)
 				if useTransformPos {
@@ -1222,7 +1280,7 @@ func RenderSVG(layout Layout, theme Theme, _ LayoutConfig) string {
 	return b.String()
 }
 
-func renderClassMermaid(layout Layout) string {
+func renderClassMermaid(layout Layout, theme Theme) string {
 	var b strings.Builder
 	b.Grow(8192)
 
@@ -1267,7 +1325,10 @@ func renderClassMermaid(layout Layout) string {
 			b.WriteString(` data-id="` + html.EscapeString(id) + `"`)
 		}
 		b.WriteString(` transform="translate(0, 0)">`)
-		b.WriteString(`<foreignObject width="0" height="0"><div xmlns="http://www.w3.org/1999/xhtml" class="labelBkg" style="display: table-cell; white-space: nowrap; line-height: 1.5; max-width: 200px; text-align: center;"><span class="edgeLabel"></span></div></foreignObject></g></g>`)
+		if !layout.SVGLabels {
+			b.WriteString(`<foreignObject width="0" height="0"><div xmlns="http://www.w3.org/1999/xhtml" class="labelBkg" style="display: table-cell; white-space: nowrap; line-height: 1.5; max-width: 200px; text-align: center;"><span class="edgeLabel"></span></div></foreignObject>`)
+		}
+		b.WriteString(`</g></g>`)
 	}
 	b.WriteString(`</g>`)
 	b.WriteString("\n")
@@ -1320,18 +1381,28 @@ func renderClassMermaid(layout Layout) string {
 		maxLabelW := max(1.0, rect.W-20)
 		b.WriteString(`<g class="label-group text" transform="translate(` + formatFloat(labelX) + `, -18)">`)
 		b.WriteString(`<g class="label" style="font-weight: bolder" transform="translate(0,-12)">`)
-		b.WriteString(`<foreignObject width="` + formatFloat(titleW) + `" height="24"><div xmlns="http://www.w3.org/1999/xhtml" style="display: table-cell; white-space: nowrap; line-height: 1.5; max-width: ` + formatFloat(maxLabelW) + `px; text-align: center;"><span class="nodeLabel markdown-node-label" style=""><p>`)
-		b.WriteString(html.EscapeString(title))
-		b.WriteString(`</p></span></div></foreignObject></g></g>`)
+		if layout.SVGLabels {
+			writeSVGTextLabel(&b, svgTextLabel{W: titleW, H: 24, Lines: plainLabelLines(title)}, theme.PrimaryTextColor)
+		} else {
+			b.WriteString(`<foreignObject width="` + formatFloat(titleW) + `" height="24"><div xmlns="http://www.w3.org/1999/xhtml" style="display: table-cell; white-space: nowrap; line-height: 1.5; max-width: ` + formatFloat(maxLabelW) + `px; text-align: center;"><span class="nodeLabel markdown-node-label" style=""><p>`)
+			b.WriteString(html.EscapeString(title))
+			b.WriteString(`</p></span></div></foreignObject>`)
+		}
+		b.WriteString(`</g></g>`)
 
 		b.WriteString(`<g class="members-group text" transform="translate(` + formatFloat(-w2+10) + `, 30)">`)
 		for _, text := range layout.Texts {
 			if text.Class == "class-member-"+rect.ID {
 				relY := (text.Y - cy) - 10
 				b.WriteString(`<g class="label" transform="translate(0, ` + formatFloat(relY-30) + `)">`)
-				b.WriteString(`<foreignObject width="` + formatFloat(rect.W-20) + `" height="14"><div xmlns="http://www.w3.org/1999/xhtml" style="display: table-cell; white-space: nowrap; line-height: 1.5; max-width: ` + formatFloat(rect.W-20) + `px; text-align: left;"><span class="nodeLabel markdown-node-label" style="font-size: ` + formatFloat(text.Size) + `px;"><p>`)
-				b.WriteString(html.EscapeString(text.Value))
-				b.WriteString(`</p></span></div></foreignObject></g>`)
+				if layout.SVGLabels {
+					writeSVGTextLabel(&b, svgTextLabel{W: rect.W - 20, H: 14, Lines: plainLabelLines(text.Value), Anchor: "start", Size: text.Size}, theme.PrimaryTextColor)
+				} else {
+					b.WriteString(`<foreignObject width="` + formatFloat(rect.W-20) + `" height="14"><div xmlns="http://www.w3.org/1999/xhtml" style="display: table-cell; white-space: nowrap; line-height: 1.5; max-width: ` + formatFloat(rect.W-20) + `px; text-align: left;"><span class="nodeLabel markdown-node-label" style="font-size: ` + formatFloat(text.Size) + `px;"><p>`)
+					b.WriteString(html.EscapeString(text.Value))
+					b.WriteString(`</p></span></div></foreignObject>`)
+				}
+				b.WriteString(`</g>`)
 			}
 		}
 		b.WriteString(`</g>`)
@@ -1341,9 +1412,14 @@ func renderClassMermaid(layout Layout) string {
 			if text.Class == "class-method-"+rect.ID {
 				relY := (text.Y - cy) - 10
 				b.WriteString(`<g class="label" transform="translate(0, ` + formatFloat(relY-60) + `)">`)
-				b.WriteString(`<foreignObject width="` + formatFloat(rect.W-20) + `" height="14"><div xmlns="http://www.w3.org/1999/xhtml" style="display: table-cell; white-space: nowrap; line-height: 1.5; max-width: ` + formatFloat(rect.W-20) + `px; text-align: left;"><span class="nodeLabel markdown-node-label" style="font-size: ` + formatFloat(text.Size) + `px;"><p>`)
-				b.WriteString(html.EscapeString(text.Value))
-				b.WriteString(`</p></span></div></foreignObject></g>`)
+				if layout.SVGLabels {
+					writeSVGTextLabel(&b, svgTextLabel{W: rect.W - 20, H: 14, Lines: plainLabelLines(text.Value), Anchor: "start", Size: text.Size}, theme.PrimaryTextColor)
+				} else {
+					b.WriteString(`<foreignObject width="` + formatFloat(rect.W-20) + `" height="14"><div xmlns="http://www.w3.org/1999/xhtml" style="display: table-cell; white-space: nowrap; line-height: 1.5; max-width: ` + formatFloat(rect.W-20) + `px; text-align: left;"><span class="nodeLabel markdown-node-label" style="font-size: ` + formatFloat(text.Size) + `px;"><p>`)
+					b.WriteString(html.EscapeString(text.Value))
+					b.WriteString(`</p></span></div></foreignObject>`)
+				}
+				b.WriteString(`</g>`)
 			}
 		}
 		b.WriteString(`</g>`)
@@ -1702,19 +1778,21 @@ func renderQuadrantMermaid(layout Layout) string {
 	return b.String()
 }
 
-func renderKanbanMermaid(layout Layout) string {
+func renderKanbanMermaid(layout Layout, theme Theme) string {
 	var b strings.Builder
 	b.Grow(8192)
 
 	type columnRender struct {
-		Rect  LayoutRect
-		Title string
+		Rect       LayoutRect
+		Title      string
+		TitleColor string
 	}
 	type cardRender struct {
 		Rect         LayoutRect
 		ID           string
 		Title        []string
 		Ticket       string
+		TicketHref   string
 		Assigned     string
 		PriorityLine *LayoutLine
 	}
@@ -1729,7 +1807,7 @@ func renderKanbanMermaid(layout Layout) string {
 
 	columns := make([]columnRender, 0, 8)
 	for _, rect := range layout.Rects {
-		if strings.TrimSpace(rect.Class) != "kanban-column" {
+		if classes := strings.Fields(rect.Class); len(classes) == 0 || classes[0] != "kanban-column" {
 			continue
 		}
 		col := columnRender{Rect: rect}
@@ -1739,6 +1817,7 @@ func renderKanbanMermaid(layout Layout) string {
 			}
 			if math.Abs(text.X-(rect.X+rect.W/2)) <= rect.W {
 				col.Title = strings.TrimSpace(text.Value)
+				col.TitleColor = strings.TrimSpace(text.Color)
 				break
 			}
 		}
@@ -1774,6 +1853,7 @@ func renderKanbanMermaid(layout Layout) string {
 					card.Assigned = strings.TrimSpace(text.Value)
 				} else {
 					card.Ticket = strings.TrimSpace(text.Value)
+					card.TicketHref = strings.TrimSpace(text.Href)
 				}
 			}
 		}
@@ -1805,7 +1885,20 @@ func renderKanbanMermaid(layout Layout) string {
 
 	b.WriteString(`<g class="sections">`)
 	for idx, col := range columns {
-		sectionClass := "cluster undefined section-" + intString(idx+1)
+		cssClasses := "undefined"
+		if classes := strings.Fields(col.Rect.Class); len(classes) > 1 {
+			cssClasses = strings.Join(classes[1:], " ")
+		}
+		sectionClass := "cluster " + cssClasses + " section-" + intString(idx+1)
+		rectStyle := ""
+		if cssClasses != "undefined" {
+			// Inline the class colors so they win over the section palette CSS.
+			rectStyle = "fill:" + defaultColor(col.Rect.Fill, "#ECECFF") + " !important;stroke:" + defaultColor(col.Rect.Stroke, "#9370DB") + " !important"
+		}
+		titleStyle := ""
+		if col.TitleColor != "" && col.TitleColor != "#000000" {
+			titleStyle = ` style="color:` + html.EscapeString(col.TitleColor) + ` !important"`
+		}
 		colID := strings.TrimSpace(col.Rect.ID)
 		if colID == "" {
 			colID = sanitizeID(col.Title, "column-"+intString(idx+1))
@@ -1817,9 +1910,17 @@ func renderKanbanMermaid(layout Layout) string {
 		titleW := measureLabelWidth(title)
 		titleX := col.Rect.X + col.Rect.W/2 - titleW/2
 		b.WriteString(`<g class="` + html.EscapeString(sectionClass) + `" id="` + html.EscapeString(colID) + `" data-look="classic">`)
-		b.WriteString(`<rect style="" rx="` + formatFloat(col.Rect.RX) + `" ry="` + formatFloat(col.Rect.RY) + `" x="` + formatFloat(col.Rect.X) + `" y="` + formatFloat(col.Rect.Y) + `" width="` + formatFloat(col.Rect.W) + `" height="` + formatFloat(col.Rect.H) + `" fill="` + html.EscapeString(defaultColor(col.Rect.Fill, "#ECECFF")) + `" stroke="` + html.EscapeString(defaultColor(col.Rect.Stroke, "#9370DB")) + `" stroke-width="` + formatFloat(max(1, col.Rect.StrokeWidth)) + `"/>`)
+		b.WriteString(`<rect style="` + html.EscapeString(rectStyle) + `" rx="` + formatFloat(col.Rect.RX) + `" ry="` + formatFloat(col.Rect.RY) + `" x="` + formatFloat(col.Rect.X) + `" y="` + formatFloat(col.Rect.Y) + `" width="` + formatFloat(col.Rect.W) + `" height="` + formatFloat(col.Rect.H) + `" fill="` + html.EscapeString(defaultColor(col.Rect.Fill, "#ECECFF")) + `" stroke="` + html.EscapeString(defaultColor(col.Rect.Stroke, "#9370DB")) + `" stroke-width="` + formatFloat(max(1, col.Rect.StrokeWidth)) + `"/>`)
 		b.WriteString(`<g class="cluster-label" transform="translate(` + formatFloat(titleX) + `, ` + formatFloat(col.Rect.Y) + `)">`)
-		b.WriteString(`<foreignObject width="` + formatFloat(titleW) + `" height="24"><div xmlns="http://www.w3.org/1999/xhtml" style="display: table-cell; white-space: nowrap; line-height: 1.5; max-width: 200px; text-align: center;"><span class="nodeLabel"><p>` + html.EscapeString(title) + `</p></span></div></foreignObject>`)
+		if layout.SVGLabels {
+			titleColor := ""
+			if titleStyle != "" {
+				titleColor = col.TitleColor
+			}
+			writeSVGTextLabel(&b, svgTextLabel{W: titleW, H: 24, Lines: plainLabelLines(title), Color: titleColor}, theme.PrimaryTextColor)
+		} else {
+			b.WriteString(`<foreignObject width="` + formatFloat(titleW) + `" height="24"><div xmlns="http://www.w3.org/1999/xhtml" style="display: table-cell; white-space: nowrap; line-height: 1.5; max-width: 200px; text-align: center;"><span class="nodeLabel"` + titleStyle + `><p>` + html.EscapeString(title) + `</p></span></div></foreignObject>`)
+		}
 		b.WriteString(`</g></g>`)
 	}
 	b.WriteString(`</g>`)
@@ -1842,7 +1943,19 @@ func renderKanbanMermaid(layout Layout) string {
 			align = "left"
 		}
 		b.WriteString(`<g class="label" style="text-align:` + html.EscapeString(align) + ` !important" transform="translate(` + formatFloat(x) + `, ` + formatFloat(y) + `)">`)
-		b.WriteString(`<rect/><foreignObject width="` + formatFloat(labelW) + `" height="` + formatFloat(labelH) + `">`)
+		b.WriteString(`<rect/>`)
+		if layout.SVGLabels {
+			var lines [][]TextRun
+			for _, value := range values {
+				if strings.TrimSpace(value) != "" {
+					lines = append(lines, plainLabelLines(value)...)
+				}
+			}
+			writeSVGTextLabel(&b, svgTextLabel{W: labelW, H: 24 * float64(len(lines)), Lines: lines, Anchor: htmlTextAnchor(align)}, theme.PrimaryTextColor)
+			b.WriteString(`</g>`)
+			return labelW
+		}
+		b.WriteString(`<foreignObject width="` + formatFloat(labelW) + `" height="` + formatFloat(labelH) + `">`)
 		b.WriteString(`<div style="text-align: ` + html.EscapeString(align) + `; display: table-cell; white-space: nowrap; line-height: 1.5; max-width: 175px;" xmlns="http://www.w3.org/1999/xhtml"><span style="text-align:` + html.EscapeString(align) + ` !important" class="nodeLabel">`)
 		for _, value := range values {
 			if strings.TrimSpace(value) != "" {
@@ -1868,7 +1981,13 @@ func renderKanbanMermaid(layout Layout) string {
 		}
 		writeItemLabel(x0+10, y0+10, card.Title, "left")
 		metaY := card.Rect.H/2 - 10
-		writeItemLabel(x0+10, metaY, []string{card.Ticket}, "left")
+		if card.TicketHref != "" {
+			b.WriteString(`<a class="kanban-ticket-link" href="` + html.EscapeString(card.TicketHref) + `" target="_blank">`)
+			writeItemLabel(x0+10, metaY, []string{card.Ticket}, "left")
+			b.WriteString(`</a>`)
+		} else {
+			writeItemLabel(x0+10, metaY, []string{card.Ticket}, "left")
+		}
 		assignedWidth := measureLabelWidth(card.Assigned)
 		writeItemLabel(card.Rect.W/2-10-assignedWidth, metaY, []string{card.Assigned}, "right")
 		b.WriteString(`</g>`)
@@ -1914,10 +2033,21 @@ func renderStateMermaid(layout Layout, theme Theme) string {
 		x := text.X - textW/2
 		y := text.Y - textH/2
 		b.WriteString(`<g class="cluster-label" transform="translate(0,0)">`)
-		b.WriteString(`<foreignObject x="` + formatFloat(x) + `" y="` + formatFloat(y) + `" width="` + formatFloat(textW) + `" height="` + formatFloat(textH) + `">`)
-		b.WriteString(`<div xmlns="http://www.w3.org/1999/xhtml" style="display: inline-block; white-space: nowrap;"><span class="nodeLabel" style="font-size: 15px; font-family: 'trebuchet ms', verdana, arial, sans-serif; font-weight: 400; color: #333333;">`)
-		b.WriteString(html.EscapeString(text.Value))
-		b.WriteString(`</span></div></foreignObject></g>`)
+		if layout.SVGLabels {
+			writeSVGTextLabel(&b, svgTextLabel{
+				X: x, Y: y, W: textW, H: textH,
+				Lines:  plainLabelLines(text.Value),
+				Size:   15,
+				Family: "'trebuchet ms', verdana, arial, sans-serif",
+				Color:  "#333333",
+			}, theme.PrimaryTextColor)
+		} else {
+			b.WriteString(`<foreignObject x="` + formatFloat(x) + `" y="` + formatFloat(y) + `" width="` + formatFloat(textW) + `" height="` + formatFloat(textH) + `">`)
+			b.WriteString(`<div xmlns="http://www.w3.org/1999/xhtml" style="display: inline-block; white-space: nowrap;"><span class="nodeLabel" style="font-size: 15px; font-family: 'trebuchet ms', verdana, arial, sans-serif; font-weight: 400; color: #333333;">`)
+			b.WriteString(html.EscapeString(text.Value))
+			b.WriteString(`</span></div></foreignObject>`)
+		}
+		b.WriteString(`</g>`)
 		b.WriteString("\n")
 	}
 	b.WriteString(`</g>`)
@@ -1973,14 +2103,19 @@ func renderStateMermaid(layout Layout, theme Theme) string {
 		b.WriteString(outerTransform)
 		b.WriteString(`>`)
 		b.WriteString(`<g class="label" data-id="` + edgeID + `" transform="translate(` + formatFloat(innerX) + `, ` + formatFloat(innerY) + `)">`)
-		b.WriteString(`<foreignObject width="` + formatFloat(textW) + `" height="` + formatFloat(textH) + `">`)
-		b.WriteString(`<div class="labelBkg" style="display: table-cell; white-space: nowrap; line-height: 1.5; max-width: 200px; text-align: center;" xmlns="http://www.w3.org/1999/xhtml"><span class="edgeLabel">`)
-		if label != "" {
-			b.WriteString(`<p>`)
-			b.WriteString(html.EscapeString(label))
-			b.WriteString(`</p>`)
+		if layout.SVGLabels {
+			writeSVGTextLabel(&b, svgTextLabel{W: textW, H: textH, Lines: plainLabelLines(label)}, theme.PrimaryTextColor)
+		} else {
+			b.WriteString(`<foreignObject width="` + formatFloat(textW) + `" height="` + formatFloat(textH) + `">`)
+			b.WriteString(`<div class="labelBkg" style="display: table-cell; white-space: nowrap; line-height: 1.5; max-width: 200px; text-align: center;" xmlns="http://www.w3.org/1999/xhtml"><span class="edgeLabel">`)
+			if label != "" {
+				b.WriteString(`<p>`)
+				b.WriteString(html.EscapeString(label))
+				b.WriteString(`</p>`)
+			}
+			b.WriteString(`</span></div></foreignObject>`)
 		}
-		b.WriteString(`</span></div></foreignObject></g></g>`)
+		b.WriteString(`</g></g>`)
 		b.WriteString("\n")
 	}
 	b.WriteString(`</g>`)
@@ -2061,10 +2196,15 @@ func renderStateMermaid(layout Layout, theme Theme) string {
 		y := node.Y + node.H/2 - textH/2
 		b.WriteString(`<g class="label" style="" transform="translate(` + formatFloat(x) + `, ` + formatFloat(y) + `)">`)
 		b.WriteString(`<rect/>`)
-		b.WriteString(`<foreignObject width="` + formatFloat(textW) + `" height="` + formatFloat(textH) + `">`)
-		b.WriteString(`<div style="display: table-cell; white-space: nowrap; line-height: 1.5; max-width: 200px; text-align: center;" xmlns="http://www.w3.org/1999/xhtml"><span class="nodeLabel"><p>`)
-		b.WriteString(html.EscapeString(label))
-		b.WriteString(`</p></span></div></foreignObject></g>`)
+		if layout.SVGLabels {
+			writeSVGTextLabel(&b, svgTextLabel{W: textW, H: textH, Lines: plainLabelLines(label)}, theme.PrimaryTextColor)
+		} else {
+			b.WriteString(`<foreignObject width="` + formatFloat(textW) + `" height="` + formatFloat(textH) + `">`)
+			b.WriteString(`<div style="display: table-cell; white-space: nowrap; line-height: 1.5; max-width: 200px; text-align: center;" xmlns="http://www.w3.org/1999/xhtml"><span class="nodeLabel"><p>`)
+			b.WriteString(html.EscapeString(label))
+			b.WriteString(`</p></span></div></foreignObject>`)
+		}
+		b.WriteString(`</g>`)
 		b.WriteString("\n")
 	}
 	b.WriteString(`</g>`)
@@ -2244,29 +2384,7 @@ func renderGanttMermaid(layout Layout) string {
 }
 
 func renderSequenceMermaid(layout Layout, theme Theme) string {
-	participants := append([]string(nil), layout.SequenceParticipants...)
-	if len(participants) == 0 {
-		seen := map[string]bool{}
-		for _, msg := range layout.SequenceMessages {
-			if strings.TrimSpace(msg.From) != "" && !seen[msg.From] {
-				seen[msg.From] = true
-				participants = append(participants, msg.From)
-			}
-			if strings.TrimSpace(msg.To) != "" && !seen[msg.To] {
-				seen[msg.To] = true
-				participants = append(participants, msg.To)
-			}
-		}
-	}
-	labels := layout.SequenceParticipantLabels
-	if labels == nil {
-		labels = map[string]string{}
-	}
-	events := layout.SequenceEvents
-	if len(events) == 0 {
-		events = defaultSequenceEvents(layout.SequenceMessages)
-	}
-	plan := buildSequencePlan(participants, labels, layout.SequenceMessages, events, theme)
+	participants, labels, plan := layoutSequencePlan(layout, theme)
 
 	var b strings.Builder
 	b.Grow(16384)
@@ -2375,7 +2493,7 @@ func renderSequenceMermaid(layout Layout, theme Theme) string {
 	return b.String()
 }
 
-func renderBlockMermaid(layout Layout) string {
+func renderBlockMermaid(layout Layout, theme Theme) string {
 	var b strings.Builder
 	b.Grow(8192)
 	b.WriteString(`<g/>`)
@@ -2414,10 +2532,15 @@ func renderBlockMermaid(layout Layout) string {
 		labelH := 18.5
 		b.WriteString(`<g class="label" style="" transform="translate(` + formatFloat(-labelW/2) + `, -9.25)">`)
 		b.WriteString(`<rect/>`)
-		b.WriteString(`<foreignObject width="` + formatFloat(labelW) + `" height="` + formatFloat(labelH) + `">`)
-		b.WriteString(`<div xmlns="http://www.w3.org/1999/xhtml" style="display: inline-block; white-space: nowrap;"><span class="nodeLabel">`)
-		b.WriteString(html.EscapeString(node.Label))
-		b.WriteString(`</span></div></foreignObject></g>`)
+		if layout.SVGLabels {
+			writeSVGTextLabel(&b, svgTextLabel{W: labelW, H: labelH, Lines: plainLabelLines(node.Label)}, theme.PrimaryTextColor)
+		} else {
+			b.WriteString(`<foreignObject width="` + formatFloat(labelW) + `" height="` + formatFloat(labelH) + `">`)
+			b.WriteString(`<div xmlns="http://www.w3.org/1999/xhtml" style="display: inline-block; white-space: nowrap;"><span class="nodeLabel">`)
+			b.WriteString(html.EscapeString(node.Label))
+			b.WriteString(`</span></div></foreignObject>`)
+		}
+		b.WriteString(`</g>`)
 		b.WriteString(`</g>`)
 	}
 
@@ -2439,7 +2562,7 @@ func renderBlockMermaid(layout Layout) string {
 	return b.String()
 }
 
-func renderMindmapMermaid(layout Layout) string {
+func renderMindmapMermaid(layout Layout, theme Theme) string {
 	var b strings.Builder
 	b.Grow(16384)
 
@@ -2529,7 +2652,11 @@ func renderMindmapMermaid(layout Layout) string {
 			continue
 		}
 		edgeID := "edge_" + intString(parentIdx) + "_" + intString(childIdx)
-		b.WriteString(`<g class="edgeLabel"><g class="label" data-id="` + edgeID + `" transform="translate(0, 0)"><foreignObject width="0" height="0"><div xmlns="http://www.w3.org/1999/xhtml" class="labelBkg" style="display: table-cell; white-space: nowrap; line-height: 1.5; max-width: 200px; text-align: center;"><span class="edgeLabel"></span></div></foreignObject></g></g>`)
+		b.WriteString(`<g class="edgeLabel"><g class="label" data-id="` + edgeID + `" transform="translate(0, 0)">`)
+		if !layout.SVGLabels {
+			b.WriteString(`<foreignObject width="0" height="0"><div xmlns="http://www.w3.org/1999/xhtml" class="labelBkg" style="display: table-cell; white-space: nowrap; line-height: 1.5; max-width: 200px; text-align: center;"><span class="edgeLabel"></span></div></foreignObject>`)
+		}
+		b.WriteString(`</g></g>`)
 	}
 	b.WriteString(`</g>`)
 	b.WriteString(`<g class="nodes">`)
@@ -2546,10 +2673,9 @@ func renderMindmapMermaid(layout Layout) string {
 			b.WriteString(`<g class="node mindmap-node section-root section--1" id="node_` + intString(i) + `" transform="translate(` + formatFloat(cx) + `, ` + formatFloat(cy) + `)">`)
 			b.WriteString(`<circle class="basic label-container" style="" r="` + formatFloat(r) + `" cx="0" cy="0"/>`)
 			b.WriteString(`<g class="label" style="" transform="translate(-` + formatFloat(labelW/2) + `, -12)">`)
-			b.WriteString(`<rect/><foreignObject width="` + formatFloat(labelW) + `" height="24">`)
-			b.WriteString(`<div xmlns="http://www.w3.org/1999/xhtml" style="display: table-cell; white-space: nowrap; line-height: 1.5; max-width: 200px; text-align: center;"><span class="nodeLabel"><p>`)
-			b.WriteString(html.EscapeString(node.Label))
-			b.WriteString(`</p></span></div></foreignObject></g></g>`)
+			b.WriteString(`<rect/>`)
+			writeMindmapLabel(&b, layout.SVGLabels, node.Label, labelW, theme)
+			b.WriteString(`</g></g>`)
 			continue
 		}
 
@@ -2570,16 +2696,28 @@ func renderMindmapMermaid(layout Layout) string {
 		b.WriteString(`<path id="node-` + intString(i) + `" class="node-bkg node-0" style="" d="` + html.EscapeString(pathD) + `"/>`)
 		b.WriteString(`<line class="node-line-" x1="-` + formatFloat(halfW) + `" y1="17" x2="` + formatFloat(halfW) + `" y2="17"/>`)
 		b.WriteString(`<g class="label" style="" transform="translate(-` + formatFloat(labelW/2) + `, -12)">`)
-		b.WriteString(`<rect/><foreignObject width="` + formatFloat(labelW) + `" height="24">`)
-		b.WriteString(`<div xmlns="http://www.w3.org/1999/xhtml" style="display: table-cell; white-space: nowrap; line-height: 1.5; max-width: 200px; text-align: center;"><span class="nodeLabel"><p>`)
-		b.WriteString(html.EscapeString(node.Label))
-		b.WriteString(`</p></span></div></foreignObject></g></g>`)
+		b.WriteString(`<rect/>`)
+		writeMindmapLabel(&b, layout.SVGLabels, node.Label, labelW, theme)
+		b.WriteString(`</g></g>`)
 	}
 	b.WriteString(`</g>`)
 	b.WriteString(`</g>`)
 	return b.String()
 }
 
+// writeMindmapLabel writes the label of a mindmap node, as HTML or, in SVG
+// label mode, as text.
+func writeMindmapLabel(b *strings.Builder, svgLabels bool, label string, labelW float64, theme Theme) {
+	if svgLabels {
+		writeSVGTextLabel(b, svgTextLabel{W: labelW, H: 24, Lines: plainLabelLines(label)}, theme.PrimaryTextColor)
+		return
+	}
+	b.WriteString(`<foreignObject width="` + formatFloat(labelW) + `" height="24">`)
+	b.WriteString(`<div xmlns="http://www.w3.org/1999/xhtml" style="display: table-cell; white-space: nowrap; line-height: 1.5; max-width: 200px; text-align: center;"><span class="nodeLabel"><p>`)
+	b.WriteString(html.EscapeString(label))
+	b.WriteString(`</p></span></div></foreignObject>`)
+}
+
 func renderTreemapMermaid(layout Layout) string {
 	var b strings.Builder
 	b.Grow(16384)
@@ -2800,11 +2938,17 @@ func renderTreemapMermaid(layout Layout) string {
 			valueTextColor = value.Color
 		}
 
-		b.WriteString(`<g class="treemapNode treemapLeafGroup leaf` + intString(idx) + `x" transform="translate(` + formatFloat(rect.X) + `,` + formatFloat(rect.Y) + `)">`)
+		groupClass := "treemapNode treemapLeafGroup leaf" + intString(idx) + "x"
+		if extra := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(rect.Class), "treemapLeaf")); extra != "" {
+			groupClass += " " + extra
+		}
+		b.WriteString(`<g class="` + html.EscapeString(groupClass) + `" transform="translate(` + formatFloat(rect.X) + `,` + formatFloat(rect.Y) + `)">`)
 		b.WriteString(`<rect width="` + formatFloat(rect.W) + `" height="` + formatFloat(rect.H) + `" class="treemapLeaf" fill="` + html.EscapeString(fill) + `" style="" fill-opacity="` + formatFloat(fillOpacity) + `" stroke="` + html.EscapeString(stroke) + `" stroke-width="` + formatFloat(strokeWidth) + `"/>`)
 		b.WriteString(`<clipPath id="clip-my-svg-` + intString(idx) + `"><rect width="` + formatFloat(max(1, rect.W-4)) + `" height="` + formatFloat(max(1, rect.H-4)) + `"/></clipPath>`)
 		b.WriteString(`<text class="treemapLabel" x="` + formatFloat(labelX) + `" y="` + formatFloat(labelY) + `" style="text-anchor: middle; dominant-baseline: middle; font-size: ` + formatFloat(labelSize) + `px;fill:` + labelTextColor + `;" clip-path="url(#clip-my-svg-` + intString(idx) + `)">` + html.EscapeString(labelText) + `</text>`)
-		b.WriteString(`<text class="treemapValue" x="` + formatFloat(valueX) + `" y="` + formatFloat(valueY) + `" style="text-anchor: middle; dominant-baseline: hanging; font-size: ` + formatFloat(valueSize) + `px; fill: ` + valueTextColor + `;" clip-path="url(#clip-my-svg-` + intString(idx) + `)">` + html.EscapeString(valueText) + `</text>`)
+		if value != nil {
+			b.WriteString(`<text class="treemapValue" x="` + formatFloat(valueX) + `" y="` + formatFloat(valueY) + `" style="text-anchor: middle; dominant-baseline: hanging; font-size: ` + formatFloat(valueSize) + `px; fill: ` + valueTextColor + `;" clip-path="url(#clip-my-svg-` + intString(idx) + `)">` + html.EscapeString(valueText) + `</text>`)
+		}
 		b.WriteString(`</g>`)
 	}
 	b.WriteString(`</g>`)
@@ -2903,13 +3047,43 @@ func architectureIconBody(icon string) string {
 	}
 }
 
+// writeArrowMarkerDefs writes the <marker> definitions that the edges of a
+// diagram kind reference.
+func writeArrowMarkerDefs(b *strings.Builder, kind DiagramKind, theme Theme) {
+	switch kind {
+	case DiagramFlowchart:
+		for _, marker := range flowchartMarkers() {
+			writeSVGMarker(b, marker)
+			b.WriteString("\n")
+		}
+	case DiagramClass, DiagramRequirement:
+		for _, marker := range diagramMarkers(kind, theme) {
+			b.WriteString("<defs>")
+			writeSVGMarker(b, marker)
+			b.WriteString("</defs>\n")
+		}
+	case DiagramC4:
+		writeC4Defs(b)
+	case DiagramER:
+		writeERMarkerDefs(b)
+	default:
+		markers := diagramMarkers(kind, theme)
+		if len(markers) == 0 {
+			return
+		}
+		b.WriteString("<defs>\n")
+		for _, marker := range markers {
+			writeSVGMarker(b, marker)
+			b.WriteString("\n")
+		}
+		b.WriteString("</defs>\n")
+	}
+}
+
 func writeBlockMarkers(b *strings.Builder) {
-	b.WriteString(`<marker id="my-svg_block-pointEnd" class="marker block" viewBox="0 0 10 10" refX="6" refY="5" markerUnits="userSpaceOnUse" markerWidth="12" markerHeight="12" orient="auto"><path d="M 0 0 L 10 5 L 0 10 z" class="arrowMarkerPath" style="stroke-width: 1; stroke-dasharray: 1, 0;"/></marker>`)
-	b.WriteString(`<marker id="my-svg_block-pointStart" class="marker block" viewBox="0 0 10 10" refX="4.5" refY="5" markerUnits="userSpaceOnUse" markerWidth="12" markerHeight="12" orient="auto"><path d="M 0 5 L 10 10 L 10 0 z" class="arrowMarkerPath" style="stroke-width: 1; stroke-dasharray: 1, 0;"/></marker>`)
-	b.WriteString(`<marker id="my-svg_block-circleEnd" class="marker block" viewBox="0 0 10 10" refX="11" refY="5" markerUnits="userSpaceOnUse" markerWidth="11" markerHeight="11" orient="auto"><circle cx="5" cy="5" r="5" class="arrowMarkerPath" style="stroke-width: 1; stroke-dasharray: 1, 0;"/></marker>`)
-	b.WriteString(`<marker id="my-svg_block-circleStart" class="marker block" viewBox="0 0 10 10" refX="-1" refY="5" markerUnits="userSpaceOnUse" markerWidth="11" markerHeight="11" orient="auto"><circle cx="5" cy="5" r="5" class="arrowMarkerPath" style="stroke-width: 1; stroke-dasharray: 1, 0;"/></marker>`)
-	b.WriteString(`<marker id="my-svg_block-crossEnd" class="marker cross block" viewBox="0 0 11 11" refX="12" refY="5.2" markerUnits="userSpaceOnUse" markerWidth="11" markerHeight="11" orient="auto"><path d="M 1,1 l 9,9 M 10,1 l -9,9" class="arrowMarkerPath" style="stroke-width: 2; stroke-dasharray: 1, 0;"/></marker>`)
-	b.WriteString(`<marker id="my-svg_block-crossStart" class="marker cross block" viewBox="0 0 11 11" refX="-1" refY="5.2" markerUnits="userSpaceOnUse" markerWidth="11" markerHeight="11" orient="auto"><path d="M 1,1 l 9,9 M 10,1 l -9,9" class="arrowMarkerPath" style="stroke-width: 2; stroke-dasharray: 1, 0;"/></marker>`)
+	for _, marker := range blockMarkers() {
+		writeSVGMarker(b, marker)
+	}
 }
 
 func writeSequenceDefs(b *strings.Builder) {
@@ -2919,22 +3093,11 @@ func writeSequenceDefs(b *strings.Builder) {
 	b.WriteString("\n")
 	b.WriteString(`<defs><symbol id="clock" width="24" height="24"><path transform="scale(.5)" d="M12 2c5.514 0 10 4.486 10 10s-4.486 10-10 10-10-4.486-10-10 4.486-10 10-10zm0-2c-6.627 0-12 5.373-12 12s5.373 12 12 12 12-5.373 12-12-5.373-12-12-12zm5.848 12.459c.202.038.202.333.001.372-1.907.361-6.045 1.111-6.547 1.111-.719 0-1.301-.582-1.301-1.301 0-.512.77-5.447 1.125-7.445.034-.192.312-.181.343.014l.985 6.238 5.394 1.011z"/></symbol></defs>`)
 	b.WriteString("\n")
-	b.WriteString(`<defs><marker id="my-svg-arrowhead" refX="7.9" refY="5" markerUnits="userSpaceOnUse" markerWidth="12" markerHeight="12" orient="auto-start-reverse"><path d="M -1 0 L 10 5 L 0 10 z"/></marker></defs>`)
-	b.WriteString("\n")
-	b.WriteString(`<defs><marker id="my-svg-crosshead" markerWidth="15" markerHeight="8" orient="auto" refX="4" refY="4.5"><path fill="none" stroke="#000000" stroke-width="1pt" d="M 1,2 L 6,7 M 6,2 L 1,7" style="stroke-dasharray: 0, 0;"/></marker></defs>`)
-	b.WriteString("\n")
-	b.WriteString(`<defs><marker id="my-svg-filled-head" refX="15.5" refY="7" markerWidth="20" markerHeight="28" orient="auto"><path d="M 18,7 L9,13 L14,7 L9,1 Z"/></marker></defs>`)
-	b.WriteString("\n")
-	b.WriteString(`<defs><marker id="my-svg-sequencenumber" refX="15" refY="15" markerWidth="60" markerHeight="40" orient="auto"><circle cx="15" cy="15" r="6"/></marker></defs>`)
-	b.WriteString("\n")
-	b.WriteString(`<defs><marker id="my-svg-solidTopArrowHead" refX="7.9" refY="7.25" markerUnits="userSpaceOnUse" markerWidth="12" markerHeight="12" orient="auto-start-reverse"><path d="M 0 0 L 10 8 L 0 8 z"/></marker></defs>`)
-	b.WriteString("\n")
-	b.WriteString(`<defs><marker id="my-svg-solidBottomArrowHead" refX="7.9" refY="0.75" markerUnits="userSpaceOnUse" markerWidth="12" markerHeight="12" orient="auto-start-reverse"><path d="M 0 0 L 10 0 L 0 8 z"/></marker></defs>`)
-	b.WriteString("\n")
-	b.WriteString(`<defs><marker id="my-svg-stickTopArrowHead" refX="7.5" refY="7" markerUnits="userSpaceOnUse" markerWidth="12" markerHeight="12" orient="auto-start-reverse"><path d="M 0 0 L 7 7" stroke="black" stroke-width="1.5" fill="none"/></marker></defs>`)
-	b.WriteString("\n")
-	b.WriteString(`<defs><marker id="my-svg-stickBottomArrowHead" refX="7.5" refY="0" markerUnits="userSpaceOnUse" markerWidth="12" markerHeight="12" orient="auto-start-reverse"><path d="M 0 7 L 7 0" stroke="black" stroke-width="1.5" fill="none"/></marker></defs>`)
-	b.WriteString("\n")
+	for _, marker := range sequenceMarkers() {
+		b.WriteString("<defs>")
+		writeSVGMarker(b, marker)
+		b.WriteString("</defs>\n")
+	}
 }
 
 func renderZenUMLForeignObject(layout Layout) string {
@@ -3071,96 +3234,10 @@ func renderZenUMLForeignObject(layout Layout) string {
 }
 
 func writeERMarkerDefs(b *strings.Builder) {
-	b.WriteString(`<defs><marker id="my-svg_er-onlyOneStart" class="marker onlyOne er" refX="0" refY="9" markerWidth="18" markerHeight="18" orient="auto">`)
-	b.WriteString(`<path d="M9,0 L9,18 M15,0 L15,18"/></marker></defs>`)
-	b.WriteString(`<defs><marker id="my-svg_er-onlyOneEnd" class="marker onlyOne er" refX="18" refY="9" markerWidth="18" markerHeight="18" orient="auto">`)
-	b.WriteString(`<path d="M3,0 L3,18 M9,0 L9,18"/></marker></defs>`)
-	b.WriteString(`<defs><marker id="my-svg_er-zeroOrOneStart" class="marker zeroOrOne er" refX="0" refY="9" markerWidth="30" markerHeight="18" orient="auto">`)
-	b.WriteString(`<circle fill="white" cx="21" cy="9" r="6"/><path d="M9,0 L9,18"/></marker></defs>`)
-	b.WriteString(`<defs><marker id="my-svg_er-zeroOrOneEnd" class="marker zeroOrOne er" refX="30" refY="9" markerWidth="30" markerHeight="18" orient="auto">`)
-	b.WriteString(`<circle fill="white" cx="9" cy="9" r="6"/><path d="M21,0 L21,18"/></marker></defs>`)
-	b.WriteString(`<defs><marker id="my-svg_er-oneOrMoreStart" class="marker oneOrMore er" refX="18" refY="18" markerWidth="45" markerHeight="36" orient="auto">`)
-	b.WriteString(`<path d="M0,18 Q 18,0 36,18 Q 18,36 0,18 M42,9 L42,27"/></marker></defs>`)
-	b.WriteString(`<defs><marker id="my-svg_er-oneOrMoreEnd" class="marker oneOrMore er" refX="27" refY="18" markerWidth="45" markerHeight="36" orient="auto">`)
-	b.WriteString(`<path d="M3,9 L3,27 M9,18 Q27,0 45,18 Q27,36 9,18"/></marker></defs>`)
-	b.WriteString(`<defs><marker id="my-svg_er-zeroOrMoreStart" class="marker zeroOrMore er" refX="18" refY="18" markerWidth="57" markerHeight="36" orient="auto">`)
-	b.WriteString(`<circle fill="white" cx="48" cy="18" r="6"/><path d="M0,18 Q18,0 36,18 Q18,36 0,18"/></marker></defs>`)
-	b.WriteString(`<defs><marker id="my-svg_er-zeroOrMoreEnd" class="marker zeroOrMore er" refX="39" refY="18" markerWidth="57" markerHeight="36" orient="auto">`)
-	b.WriteString(`<circle fill="white" cx="9" cy="18" r="6"/><path d="M21,18 Q39,0 57,18 Q39,36 21,18"/></marker></defs>`)
-}
-
-func writeClassMarkerDefs(b *strings.Builder) {
-	b.WriteString(`<marker id="my-svg_class-aggregationStart" class="marker aggregation class" refX="18" refY="7" markerWidth="190" markerHeight="240" orient="auto"><path d="M 18,7 L9,13 L1,7 L9,1 Z"/></marker>`)
-	b.WriteString("\n")
-	b.WriteString(`<marker id="my-svg_class-aggregationEnd" class="marker aggregation class" refX="1" refY="7" markerWidth="20" markerHeight="28" orient="auto"><path d="M 18,7 L9,13 L1,7 L9,1 Z"/></marker>`)
-	b.WriteString("\n")
-	b.WriteString(`<marker id="my-svg_class-extensionStart" class="marker extension class" refX="18" refY="7" markerWidth="190" markerHeight="240" orient="auto"><path d="M 1,7 L18,13 V 1 Z"/></marker>`)
-	b.WriteString("\n")
-	b.WriteString(`<marker id="my-svg_class-extensionEnd" class="marker extension class" refX="1" refY="7" markerWidth="20" markerHeight="28" orient="auto"><path d="M 1,1 V 13 L18,7 Z"/></marker>`)
-	b.WriteString("\n")
-	b.WriteString(`<marker id="my-svg_class-compositionStart" class="marker composition class" refX="18" refY="7" markerWidth="190" markerHeight="240" orient="auto"><path d="M 18,7 L9,13 L1,7 L9,1 Z"/></marker>`)
-	b.WriteString("\n")
-	b.WriteString(`<marker id="my-svg_class-compositionEnd" class="marker composition class" refX="1" refY="7" markerWidth="20" markerHeight="28" orient="auto"><path d="M 18,7 L9,13 L1,7 L9,1 Z"/></marker>`)
-	b.WriteString("\n")
-	b.WriteString(`<marker id="my-svg_class-dependencyStart" class="marker dependency class" refX="6" refY="7" markerWidth="190" markerHeight="240" orient="auto"><path d="M 5,7 L9,13 L1,7 L9,1 Z"/></marker>`)
-	b.WriteString("\n")
-	b.WriteString(`<marker id="my-svg_class-dependencyEnd" class="marker dependency class" refX="13" refY="7" markerWidth="20" markerHeight="28" orient="auto"><path d="M 18,7 L9,13 L14,7 L9,1 Z"/></marker>`)
-	b.WriteString("\n")
-	b.WriteString(`<marker id="my-svg_class-lollipopStart" class="marker lollipop class" refX="13" refY="7" markerWidth="190" markerHeight="240" orient="auto"><circle stroke="black" fill="transparent" cx="7" cy="7" r="6"/></marker>`)
-	b.WriteString("\n")
-	b.WriteString(`<marker id="my-svg_class-lollipopEnd" class="marker lollipop class" refX="1" refY="7" markerWidth="190" markerHeight="240" orient="auto"><circle stroke="black" fill="transparent" cx="7" cy="7" r="6"/></marker>`)
-	b.WriteString("\n")
-	// -margin variants (match mmdc output)
-	b.WriteString(`<marker id="my-svg_class-aggregationStart-margin" class="marker aggregation class" refX="15" refY="7" markerWidth="190" markerHeight="240" orient="auto" markerUnits="userSpaceOnUse"><path d="M 18,7 L9,13 L1,7 L9,1 Z" style="stroke-width: 2;"/></marker>`)
-	b.WriteString("\n")
-	b.WriteString(`<marker id="my-svg_class-aggregationEnd-margin" class="marker aggregation class" refX="1" refY="7" markerWidth="20" markerHeight="28" orient="auto" markerUnits="userSpaceOnUse"><path d="M 18,7 L9,13 L1,7 L9,1 Z" style="stroke-width: 2;"/></marker>`)
-	b.WriteString("\n")
-	b.WriteString(`<marker id="my-svg_class-extensionStart-margin" class="marker extension class" refX="18" refY="7" markerWidth="20" markerHeight="28" orient="auto" markerUnits="userSpaceOnUse" viewBox="0 0 20 14"><polygon points="10,7 18,13 18,1" style="stroke-width: 2; stroke-dasharray: 0;"/></marker>`)
-	b.WriteString("\n")
-	b.WriteString(`<marker id="my-svg_class-extensionEnd-margin" class="marker extension class" refX="9" refY="7" markerWidth="20" markerHeight="28" orient="auto" markerUnits="userSpaceOnUse" viewBox="0 0 20 14"><polygon points="10,1 10,13 18,7" style="stroke-width: 2; stroke-dasharray: 0;"/></marker>`)
-	b.WriteString("\n")
-	b.WriteString(`<marker id="my-svg_class-compositionStart-margin" class="marker composition class" refX="15" refY="7" markerWidth="190" markerHeight="240" orient="auto" markerUnits="userSpaceOnUse"><path viewBox="0 0 15 15" d="M 18,7 L9,13 L1,7 L9,1 Z" style="stroke-width: 0;"/></marker>`)
-	b.WriteString("\n")
-	b.WriteString(`<marker id="my-svg_class-compositionEnd-margin" class="marker composition class" refX="3.5" refY="7" markerWidth="20" markerHeight="28" orient="auto" markerUnits="userSpaceOnUse"><path d="M 18,7 L9,13 L1,7 L9,1 Z" style="stroke-width: 0;"/></marker>`)
-	b.WriteString("\n")
-	b.WriteString(`<marker id="my-svg_class-dependencyStart-margin" class="marker dependency class" refX="4" refY="7" markerWidth="190" markerHeight="240" orient="auto" markerUnits="userSpaceOnUse"><path d="M 5,7 L9,13 L1,7 L9,1 Z" style="stroke-width: 0;"/></marker>`)
-	b.WriteString("\n")
-	b.WriteString(`<marker id="my-svg_class-dependencyEnd-margin" class="marker dependency class" refX="16" refY="7" markerWidth="20" markerHeight="28" orient="auto" markerUnits="userSpaceOnUse"><path d="M 18,7 L9,13 L14,7 L9,1 Z" style="stroke-width: 0;"/></marker>`)
-	b.WriteString("\n")
-	b.WriteString(`<marker id="my-svg_class-lollipopStart-margin" class="marker lollipop class" refX="13" refY="7" markerWidth="190" markerHeight="240" orient="auto" markerUnits="userSpaceOnUse"><circle fill="transparent" cx="7" cy="7" r="6" stroke-width="2"/></marker>`)
-	b.WriteString("\n")
-	b.WriteString(`<marker id="my-svg_class-lollipopEnd-margin" class="marker lollipop class" refX="1" refY="7" markerWidth="190" markerHeight="240" orient="auto" markerUnits="userSpaceOnUse"><circle fill="transparent" cx="7" cy="7" r="6" stroke-width="2"/></marker>`)
-	b.WriteString("\n")
-}
-
-func writeClassMarkerDefsSeparate(b *strings.Builder) {
-	markers := []string{
-		`<marker id="my-svg_class-aggregationStart" class="marker aggregation class" refX="18" refY="7" markerWidth="190" markerHeight="240" orient="auto"><path d="M 18,7 L9,13 L1,7 L9,1 Z"/></marker>`,
-		`<marker id="my-svg_class-aggregationEnd" class="marker aggregation class" refX="1" refY="7" markerWidth="20" markerHeight="28" orient="auto"><path d="M 18,7 L9,13 L1,7 L9,1 Z"/></marker>`,
-		`<marker id="my-svg_class-extensionStart" class="marker extension class" refX="18" refY="7" markerWidth="190" markerHeight="240" orient="auto"><path d="M 1,7 L18,13 V 1 Z"/></marker>`,
-		`<marker id="my-svg_class-extensionEnd" class="marker extension class" refX="1" refY="7" markerWidth="20" markerHeight="28" orient="auto"><path d="M 1,1 V 13 L18,7 Z"/></marker>`,
-		`<marker id="my-svg_class-compositionStart" class="marker composition class" refX="18" refY="7" markerWidth="190" markerHeight="240" orient="auto"><path d="M 18,7 L9,13 L1,7 L9,1 Z"/></marker>`,
-		`<marker id="my-svg_class-compositionEnd" class="marker composition class" refX="1" refY="7" markerWidth="20" markerHeight="28" orient="auto"><path d="M 18,7 L9,13 L1,7 L9,1 Z"/></marker>`,
-		`<marker id="my-svg_class-dependencyStart" class="marker dependency class" refX="6" refY="7" markerWidth="190" markerHeight="240" orient="auto"><path d="M 5,7 L9,13 L1,7 L9,1 Z"/></marker>`,
-		`<marker id="my-svg_class-dependencyEnd" class="marker dependency class" refX="13" refY="7" markerWidth="20" markerHeight="28" orient="auto"><path d="M 18,7 L9,13 L14,7 L9,1 Z"/></marker>`,
-		`<marker id="my-svg_class-lollipopStart" class="marker lollipop class" refX="13" refY="7" markerWidth="190" markerHeight="240" orient="auto"><circle stroke="black" fill="transparent" cx="7" cy="7" r="6"/></marker>`,
-		`<marker id="my-svg_class-lollipopEnd" class="marker lollipop class" refX="1" refY="7" markerWidth="190" markerHeight="240" orient="auto"><circle stroke="black" fill="transparent" cx="7" cy="7" r="6"/></marker>`,
-		// -margin variants (match mmdc output)
-		`<marker id="my-svg_class-aggregationStart-margin" class="marker aggregation class" refX="15" refY="7" markerWidth="190" markerHeight="240" orient="auto" markerUnits="userSpaceOnUse"><path d="M 18,7 L9,13 L1,7 L9,1 Z" style="stroke-width: 2;"/></marker>`,
-		`<marker id="my-svg_class-aggregationEnd-margin" class="marker aggregation class" refX="1" refY="7" markerWidth="20" markerHeight="28" orient="auto" markerUnits="userSpaceOnUse"><path d="M 18,7 L9,13 L1,7 L9,1 Z" style="stroke-width: 2;"/></marker>`,
-		`<marker id="my-svg_class-extensionStart-margin" class="marker extension class" refX="18" refY="7" markerWidth="20" markerHeight="28" orient="auto" markerUnits="userSpaceOnUse" viewBox="0 0 20 14"><polygon points="10,7 18,13 18,1" style="stroke-width: 2; stroke-dasharray: 0;"/></marker>`,
-		`<marker id="my-svg_class-extensionEnd-margin" class="marker extension class" refX="9" refY="7" markerWidth="20" markerHeight="28" orient="auto" markerUnits="userSpaceOnUse" viewBox="0 0 20 14"><polygon points="10,1 10,13 18,7" style="stroke-width: 2; stroke-dasharray: 0;"/></marker>`,
-		`<marker id="my-svg_class-compositionStart-margin" class="marker composition class" refX="15" refY="7" markerWidth="190" markerHeight="240" orient="auto" markerUnits="userSpaceOnUse"><path viewBox="0 0 15 15" d="M 18,7 L9,13 L1,7 L9,1 Z" style="stroke-width: 0;"/></marker>`,
-		`<marker id="my-svg_class-compositionEnd-margin" class="marker composition class" refX="3.5" refY="7" markerWidth="20" markerHeight="28" orient="auto" markerUnits="userSpaceOnUse"><path d="M 18,7 L9,13 L1,7 L9,1 Z" style="stroke-width: 0;"/></marker>`,
-		`<marker id="my-svg_class-dependencyStart-margin" class="marker dependency class" refX="4" refY="7" markerWidth="190" markerHeight="240" orient="auto" markerUnits="userSpaceOnUse"><path d="M 5,7 L9,13 L1,7 L9,1 Z" style="stroke-width: 0;"/></marker>`,
-		`<marker id="my-svg_class-dependencyEnd-margin" class="marker dependency class" refX="16" refY="7" markerWidth="20" markerHeight="28" orient="auto" markerUnits="userSpaceOnUse"><path d="M 18,7 L9,13 L14,7 L9,1 Z" style="stroke-width: 0;"/></marker>`,
-		`<marker id="my-svg_class-lollipopStart-margin" class="marker lollipop class" refX="13" refY="7" markerWidth="190" markerHeight="240" orient="auto" markerUnits="userSpaceOnUse"><circle fill="transparent" cx="7" cy="7" r="6" stroke-width="2"/></marker>`,
-		`<marker id="my-svg_class-lollipopEnd-margin" class="marker lollipop class" refX="1" refY="7" markerWidth="190" markerHeight="240" orient="auto" markerUnits="userSpaceOnUse"><circle fill="transparent" cx="7" cy="7" r="6" stroke-width="2"/></marker>`,
-	}
-	for _, marker := range markers {
+	for _, marker := range erMarkers() {
 		b.WriteString("<defs>")
-		b.WriteString(marker)
-		b.WriteString("</defs>\n")
+		writeSVGMarker(b, marker)
+		b.WriteString("</defs>")
 	}
 }
 
@@ -3171,14 +3248,11 @@ func writeC4Defs(b *strings.Builder) {
 	b.WriteString("\n")
 	b.WriteString(`<defs><symbol id="clock" width="24" height="24"><path transform="scale(.5)" d="M12 2c5.514 0 10 4.486 10 10s-4.486 10-10 10-10-4.486-10-10 4.486-10 10-10zm0-2c-6.627 0-12 5.373-12 12s5.373 12 12 12 12-5.373 12-12-5.373-12-12-12zm5.848 12.459c.202.038.202.333.001.372-1.907.361-6.045 1.111-6.547 1.111-.719 0-1.301-.582-1.301-1.301 0-.512.77-5.447 1.125-7.445.034-.192.312-.181.343.014l.985 6.238 5.394 1.011z"/></symbol></defs>`)
 	b.WriteString("\n")
-	b.WriteString(`<defs><marker id="arrowhead" refX="9" refY="5" markerUnits="userSpaceOnUse" markerWidth="12" markerHeight="12" orient="auto"><path d="M 0 0 L 10 5 L 0 10 z"/></marker></defs>`)
-	b.WriteString("\n")
-	b.WriteString(`<defs><marker id="arrowend" refX="1" refY="5" markerUnits="userSpaceOnUse" markerWidth="12" markerHeight="12" orient="auto"><path d="M 10 0 L 0 5 L 10 10 z"/></marker></defs>`)
-	b.WriteString("\n")
-	b.WriteString(`<defs><marker id="crosshead" markerWidth="15" markerHeight="8" orient="auto" refX="16" refY="4"><path fill="black" stroke="#000000" stroke-width="1px" d="M 9,2 V 6 L16,4 Z" style="stroke-dasharray: 0, 0;"/><path fill="none" stroke="#000000" stroke-width="1px" d="M 0,1 L 6,7 M 6,1 L 0,7" style="stroke-dasharray: 0, 0;"/></marker></defs>`)
-	b.WriteString("\n")
-	b.WriteString(`<defs><marker id="filled-head" refX="18" refY="7" markerWidth="20" markerHeight="28" orient="auto"><path d="M 18,7 L9,13 L14,7 L9,1 Z"/></marker></defs>`)
-	b.WriteString("\n")
+	for _, marker := range c4Markers() {
+		b.WriteString("<defs>")
+		writeSVGMarker(b, marker)
+		b.WriteString("</defs>\n")
+	}
 }
 
 func defaultColor(value, fallback string) string {
@@ -3495,17 +3569,20 @@ func renderSankeyMermaid(layout Layout) string {
 		}
 		y := (node.Y0 + node.Y1) * 0.5
 		b.WriteString(`<text x="` + formatFloat(x) + `" y="` + formatFloat(y) + `" dy="0em" text-anchor="` + anchor + `">`)
-		b.WriteString(html.EscapeString(node.ID))
-		b.WriteString("\n")
-		b.WriteString(html.EscapeString(formatSankeyValue(node.Value)))
+		b.WriteString(html.EscapeString(node.Label))
 		b.WriteString(`</text>`)
 	}
 	b.WriteString(`</g>`)
 
 	b.WriteString(`<g class="links" fill="none" stroke-opacity="0.5">`)
 	for i, link := range layout.SankeyLinks {
-		gradientID := "linearGradient-" + intString(len(layout.SankeyNodes)+i+1)
 		b.WriteString(`<g class="link" style="mix-blend-mode: multiply;">`)
+		if link.Stroke != "" {
+			b.WriteString(`<path d="` + link.Path + `" stroke="` + html.EscapeString(link.Stroke) + `" stroke-width="` + formatFloat(max(1, link.Width)) + `"/>`)
+			b.WriteString(`</g>`)
+			continue
+		}
+		gradientID := "linearGradient-" + intString(len(layout.SankeyNodes)+i+1)
 		b.WriteString(`<linearGradient id="` + gradientID + `" gradientUnits="userSpaceOnUse" x1="` + formatFloat(link.X0) + `" x2="` + formatFloat(link.X1) + `">`)
 		b.WriteString(`<stop offset="0%" stop-color="` + html.EscapeString(link.SourceColor) + `"/>`)
 		b.WriteString(`<stop offset="100%" stop-color="` + html.EscapeString(link.TargetColor) + `"/>`)
@@ -3551,29 +3628,41 @@ func renderRadarMermaid(layout Layout) string {
 	}
 
 	for _, curve := range layout.RadarCurves {
+		style := ""
+		if curve.Style != "" {
+			style = ` style="` + html.EscapeString(curve.Style) + `"`
+		}
 		if curve.Polygon {
-			b.WriteString(`<polygon points="` + curve.Path + `" class="` + curve.Class + `"/>`)
+			b.WriteString(`<polygon points="` + curve.Path + `" class="` + html.EscapeString(curve.Class) + `"` + style + `/>`)
 		} else {
-			b.WriteString(`<path d="` + curve.Path + `" class="` + curve.Class + `"/>`)
+			b.WriteString(`<path d="` + curve.Path + `" class="` + html.EscapeString(curve.Class) + `"` + style + `/>`)
 		}
 	}
 
 	if layout.RadarShowLegend {
 		for i, label := range layout.RadarLegend {
 			y := layout.RadarLegendY + float64(i)*layout.RadarLegendLineHeight
+			style := ""
+			if i < len(layout.RadarCurves) && layout.RadarCurves[i].Style != "" {
+				style = ` style="` + html.EscapeString(layout.RadarCurves[i].Style) + `"`
+			}
 			b.WriteString(`<g transform="translate(` + formatFloat(layout.RadarLegendX) + `, ` + formatFloat(y) + `)">`)
-			b.WriteString(`<rect width="12" height="12" class="radarLegendBox-` + intString(i) + `"/>`)
+			b.WriteString(`<rect width="12" height="12" class="radarLegendBox-` + intString(i) + `"` + style + `/>`)
 			b.WriteString(`<text x="16" y="0" class="radarLegendText">` + html.EscapeString(label) + `</text>`)
 			b.WriteString(`</g>`)
 		}
 	}
 
-	b.WriteString(`<text class="radarTitle" x="0" y="-350">` + html.EscapeString(layout.RadarTitle) + `</text>`)
+	titleY := layout.RadarTitleY
+	if titleY == 0 {
+		titleY = -350
+	}
+	b.WriteString(`<text class="radarTitle" x="0" y="` + formatFloat(titleY) + `">` + html.EscapeString(layout.RadarTitle) + `</text>`)
 	b.WriteString(`</g>`)
 	return b.String()
 }
 
-func radarStyleCSS() string {
+func radarStyleCSS(curveColors []string, curveOpacity float64) string {
 	var b strings.Builder
 	b.WriteString(`#my-svg{font-family:"trebuchet ms",verdana,arial,sans-serif;font-size:16px;}@keyframes edge-animation-frame{from{stroke-dashoffset:0;}}@keyframes dash{to{stroke-dashoffset:0;}}#my-svg .edge-animation-slow{stroke-dasharray:9,5!important;stroke-dashoffset:900;animation:dash 50s linear infinite;stroke-linecap:round;}#my-svg .edge-animation-fast{stroke-dasharray:9,5!important;stroke-dashoffset:900;animation:dash 20s linear infinite;stroke-linecap:round;}#my-svg .error-icon{fill:#552222;}#my-svg .error-text{fill:#552222;stroke:#552222;}#my-svg .edge-thickness-normal{stroke-width:1px;}#my-svg .edge-thickness-thick{stroke-width:3.5px;}#my-svg .edge-pattern-solid{stroke-dasharray:0;}#my-svg .edge-thickness-invisible{stroke-width:0;fill:none;}#my-svg .edge-pattern-dashed{stroke-dasharray:3;}#my-svg .edge-pattern-dotted{stroke-dasharray:2;}#my-svg .marker{fill:#333333;stroke:#333333;}#my-svg .marker.cross{stroke:#333333;}#my-svg svg{font-family:"trebuchet ms",verdana,arial,sans-serif;font-size:16px;}#my-svg p{margin:0;}#my-svg .radarTitle{font-size:16px;color:#333;dominant-baseline:hanging;text-anchor:middle;}#my-svg .radarAxisLine{stroke:#333333;stroke-width:2;}#my-svg .radarAxisLabel{dominant-baseline:middle;text-anchor:middle;font-size:12px;color:#333333;}#my-svg .radarGraticule{fill:#DEDEDE;fill-opacity:0.3;stroke:#DEDEDE;stroke-width:1;}#my-svg .radarLegendText{text-anchor:start;font-size:12px;dominant-baseline:hanging;}`)
 	palette := []string{
@@ -3590,9 +3679,15 @@ func radarStyleCSS() string {
 		"#86ffff", // hsl(180, 100%, 76.2745098039%)
 		"#86c0ff", // hsl(210, 100%, 76.2745098039%)
 	}
+	for i, color := range curveColors {
+		if i < len(palette) && color != "" {
+			palette[i] = color
+		}
+	}
+	opacity := formatFloat(curveOpacity)
 	for i, color := range palette {
-		b.WriteString(`#my-svg .radarCurve-` + intString(i) + `{color:` + color + `;fill:` + color + `;fill-opacity:0.5;stroke:` + color + `;stroke-width:2;}`)
-		b.WriteString(`#my-svg .radarLegendBox-` + intString(i) + `{fill:` + color + `;fill-opacity:0.5;stroke:` + color + `;}`)
+		b.WriteString(`#my-svg .radarCurve-` + intString(i) + `{color:` + color + `;fill:` + color + `;fill-opacity:` + opacity + `;stroke:` + color + `;stroke-width:2;}`)
+		b.WriteString(`#my-svg .radarLegendBox-` + intString(i) + `{fill:` + color + `;fill-opacity:` + opacity + `;stroke:` + color + `;}`)
 	}
 	b.WriteString(`#my-svg :root{--mermaid-font-family:"trebuchet ms",verdana,arial,sans-serif;}`)
 	return b.String()
@@ -1,6 +1,15 @@
 package mermaid
 
 import (
+	"bytes"
+	"encoding/base64"
+	"image"
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
 	"testing"
 )
@@ -77,6 +86,509 @@ func TestRenderFlowchartDefaultRectangleDoesNotRoundCorners(t *testing.T) {
 	}
 }
 
+func TestFlowchartImageNodeEmbedsLocalFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "logo.png")
+	pixels := image.NewNRGBA(image.Rect(0, 0, 40, 20))
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, pixels); err != nil {
+		t.Fatalf("png.Encode() error = %v", err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	input := "flowchart LR\n  A@{ img: \"" + path + "\", label: \"Logo\", w: 80, constraint: \"on\" } --> B@{ img: \"https://example.com/x.png\" }"
+	svg, err := RenderWithOptions(input, DefaultRenderOptions().WithAssetDirs(dir))
+	if err != nil {
+		t.Fatalf("RenderWithOptions() error = %v", err)
+	}
+	if !strings.Contains(svg, `width="80" height="40"`) {
+		t.Fatalf("expected constrained 80x40 image, got: %s", svg)
+	}
+	if !strings.Contains(svg, `href="data:image/png;base64,`) {
+		t.Fatalf("expected local image embedded as data URI")
+	}
+	if !strings.Contains(svg, `href="https://example.com/x.png"`) {
+		t.Fatalf("expected remote image href kept as-is")
+	}
+}
+
+func TestFlowchartImageNodeReadsOnlyAllowedFiles(t *testing.T) {
+	allowed := t.TempDir()
+	private := t.TempDir()
+	secret := filepath.Join(private, "secret.xml")
+	if err := os.WriteFile(secret, []byte("<config><password>hunter2</password></config>"), 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	notSVG := filepath.Join(allowed, "page.svg")
+	if err := os.WriteFile(notSVG, []byte("<html><body>hunter2</body></html>"), 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	if err := os.Symlink(secret, filepath.Join(allowed, "link.png")); err != nil {
+		t.Fatalf("Symlink() error = %v", err)
+	}
+
+	for name, options := range map[string]RenderOptions{
+		"default":    DefaultRenderOptions(),
+		"asset dirs": DefaultRenderOptions().WithAssetDirs(allowed),
+	} {
+		for _, src := range []string{secret, "file://" + secret, "../" + filepath.Base(private) + "/secret.xml", notSVG, "link.png"} {
+			svg, err := RenderWithOptions("flowchart LR\n  A@{ img: \""+src+"\" }", options)
+			if err != nil {
+				t.Fatalf("%s, %s: RenderWithOptions() error = %v", name, src, err)
+			}
+			if strings.Contains(svg, "data:") || strings.Contains(svg, base64.StdEncoding.EncodeToString([]byte("<config>"))[:8]) {
+				t.Fatalf("%s: expected %s not to be embedded", name, src)
+			}
+		}
+	}
+
+	logo := `<?xml version="1.0"?><svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 30 10"/>`
+	if err := os.WriteFile(filepath.Join(allowed, "logo.svg"), []byte(logo), 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	svg, err := RenderWithOptions("flowchart LR\n  A@{ img: \"logo.svg\" }", DefaultRenderOptions().WithAssetDirs(allowed))
+	if err != nil {
+		t.Fatalf("RenderWithOptions() error = %v", err)
+	}
+	if !strings.Contains(svg, `href="data:image/svg+xml;base64,`) {
+		t.Fatal("expected an SVG inside the asset directory to be embedded")
+	}
+}
+
+func TestFlowchartIconNodeUsesRegistry(t *testing.T) {
+	RegisterIcon("test:dot", `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 10 10"><circle cx="5" cy="5" r="4" fill="currentColor"/></svg>`)
+	parsed, err := ParseMermaid("flowchart TD\n  A@{ icon: \"test:dot\", form: \"circle\", label: \"Dot\" }")
+	if err != nil {
+		t.Fatalf("ParseMermaid() error = %v", err)
+	}
+	layout := ComputeLayout(&parsed.Graph, DefaultRenderOptions().Theme, DefaultLayoutConfig())
+	if len(layout.Images) != 1 {
+		t.Fatalf("expected one icon image, got %d", len(layout.Images))
+	}
+	icon := layout.Images[0]
+	if icon.W != defaultIconSize || icon.H != defaultIconSize {
+		t.Fatalf("icon size = %vx%v, want %v", icon.W, icon.H, defaultIconSize)
+	}
+	_, data, ok := decodeDataURI(icon.Href)
+	if !ok || !strings.Contains(string(data), `r="4"`) || strings.Contains(string(data), "currentColor") {
+		t.Fatalf("expected registered icon with resolved colour, got %q", data)
+	}
+	node := layout.Nodes[0]
+	if node.H <= defaultIconSize+2*iconFormPadding {
+		t.Fatalf("expected node height to include the label, got %v", node.H)
+	}
+}
+
+func TestFlowchartEdgeAnimationCurveAndClass(t *testing.T) {
+	svg, err := Render(`flowchart TD
+  A e1@--> B
+  B e2@--> C
+  e1@{ animate: true, curve: step }
+  classDef hot stroke:#f00
+  class e2 hot`)
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	if !strings.Contains(svg, `id="e1" class="edge-thickness-normal edge-pattern-solid flowchart-link edge-animation-fast"`) {
+		t.Fatalf("expected animated edge e1 in SVG")
+	}
+	if !strings.Contains(svg, `class="edge-thickness-normal edge-pattern-solid flowchart-link hot"`) ||
+		!strings.Contains(svg, "stroke:#f00;") {
+		t.Fatalf("expected classDef styles on edge e2")
+	}
+	end := strings.Index(svg, `" id="e1"`)
+	start := strings.LastIndex(svg[:end], `d="`)
+	if d := svg[start+3 : end]; !strings.HasPrefix(d, "M") || strings.Count(d, "L") != 5 || strings.Contains(d, " ") {
+		t.Fatalf("expected step interpolation for e1, got %q", d)
+	}
+}
+
+func TestFlowchartLongerArrowsSpanMoreRanks(t *testing.T) {
+	parsed, err := ParseMermaid("flowchart TD\n  A --> B\n  A ----> C")
+	if err != nil {
+		t.Fatalf("ParseMermaid() error = %v", err)
+	}
+	layout := ComputeLayout(&parsed.Graph, DefaultRenderOptions().Theme, DefaultLayoutConfig())
+	ys := map[string]float64{}
+	for _, node := range layout.Nodes {
+		ys[node.ID] = node.Y
+	}
+	if ys["B"] <= ys["A"] || ys["C"] < ys["B"]+DefaultLayoutConfig().RankSpacing*2 {
+		t.Fatalf("expected C ranks below B (A=%v B=%v C=%v)", ys["A"], ys["B"], ys["C"])
+	}
+}
+
+func TestRenderHTMLLabelsFalseUsesSVGText(t *testing.T) {
+	input := `---
+config:
+  htmlLabels: false
+---
+flowchart LR
+  A["` + "`**Bold** label`" + `"] -->|note| B[Line one<br>Line two]`
+	svg, err := Render(input)
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	if strings.Contains(svg, "<foreignObject") {
+		t.Fatal("expected htmlLabels: false to avoid foreignObject")
+	}
+	for _, want := range []string{
+		`<tspan font-weight="bold">Bold</tspan> label</tspan>`,
+		">note</tspan>",
+		">Line one</tspan>",
+		">Line two</tspan>",
+	} {
+		if !strings.Contains(svg, want) {
+			t.Fatalf("expected %q in SVG", want)
+		}
+	}
+}
+
+func TestZenUMLSVGLabelsDrawTextFromTheLayout(t *testing.T) {
+	svg, err := RenderWithOptions("zenuml\n  Alice->Bob: hello", DefaultRenderOptions().WithSVGLabels(true))
+	if err != nil {
+		t.Fatalf("RenderWithOptions() error = %v", err)
+	}
+	if strings.Contains(svg, "<foreignObject") {
+		t.Fatal("expected no foreignObject in SVG label mode")
+	}
+	for _, want := range []string{">Alice</", ">Bob</", ">hello</"} {
+		if !strings.Contains(svg, want) {
+			t.Fatalf("expected %q as SVG text", want)
+		}
+	}
+}
+func TestFlowchartSubgraphDirectionAndClusterEdges(t *testing.T) {
+	input := `flowchart TB
+  c1 --> a2
+  subgraph one
+    a1 --> a2
+  end
+  subgraph two
+    direction LR
+    b1 --> b2
+  end
+  one --> two
+  style two fill:#f9f`
+	parsed, err := ParseMermaid(input)
+	if err != nil {
+		t.Fatalf("ParseMermaid() error = %v", err)
+	}
+	layout := ComputeLayout(&parsed.Graph, DefaultRenderOptions().Theme, DefaultLayoutConfig())
+	nodes := map[string]NodeLayout{}
+	for _, node := range layout.Nodes {
+		nodes[node.ID] = node
+	}
+	if _, ok := nodes["one"]; ok {
+		t.Fatal("subgraph one should be drawn as a cluster, not a node")
+	}
+	// "two" has its own direction and no edges crossing its border.
+	if b1, b2 := nodes["b1"], nodes["b2"]; b2.X <= b1.X+b1.W || b1.Y != b2.Y {
+		t.Fatalf("expected two to lay out left to right, got b1=%+v b2=%+v", b1, b2)
+	}
+	// "one" is connected to c1 from outside, so it keeps the graph direction.
+	if a1, a2 := nodes["a1"], nodes["a2"]; a2.Y <= a1.Y+a1.H {
+		t.Fatalf("expected one to lay out top to bottom, got a1=%+v a2=%+v", a1, a2)
+	}
+
+	var clusterOne, clusterTwo LayoutRect
+	for _, rect := range layout.Rects {
+		if rect.Class != "cluster" {
+			continue
+		}
+		switch {
+		case rect.Fill == "#f9f":
+			clusterTwo = rect
+		default:
+			clusterOne = rect
+		}
+	}
+	if clusterTwo.W == 0 || clusterOne.W == 0 {
+		t.Fatalf("expected both clusters, got %+v", layout.Rects)
+	}
+	var edge EdgeLayout
+	for _, e := range layout.Edges {
+		if e.From == "one" && e.To == "two" {
+			edge = e
+		}
+	}
+	coords := regexp.MustCompile(`-?\d+(\.\d+)?`).FindAllString(edge.D, -1)
+	if len(coords) < 4 {
+		t.Fatalf("expected a path for one --> two, got %q", edge.D)
+	}
+	startY, _ := strconv.ParseFloat(coords[1], 64)
+	endY, _ := strconv.ParseFloat(coords[len(coords)-1], 64)
+	if math.Abs(startY-(clusterOne.Y+clusterOne.H)) > 1 {
+		t.Fatalf("edge should leave one at its bottom border %v, starts at y=%v", clusterOne.Y+clusterOne.H, startY)
+	}
+	if math.Abs(endY-clusterTwo.Y) > 1 {
+		t.Fatalf("edge should reach two at its top border %v, ends at y=%v", clusterTwo.Y, endY)
+	}
+}
+
+func TestRenderAccessibleTitleAndDescription(t *testing.T) {
+	svg, err := Render("flowchart LR\n  accTitle: Order <flow>\n  accDescr: Cart to checkout\n  A[Cart] --> B[Checkout]")
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	for _, want := range []string{
+		`aria-labelledby="chart-title-my-svg" aria-describedby="chart-desc-my-svg">`,
+		`<title id="chart-title-my-svg">Order &lt;flow&gt;</title>`,
+		`<desc id="chart-desc-my-svg">Cart to checkout</desc>`,
+	} {
+		if !strings.Contains(svg, want) {
+			t.Fatalf("expected %q in SVG", want)
+		}
+	}
+
+	plain := "flowchart LR\n  A[Cart] -->|pay| B[Checkout]"
+	svg, err = Render(plain)
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	if strings.Contains(svg, "<desc") || strings.Contains(svg, "aria-describedby") {
+		t.Fatal("expected no description without accDescr")
+	}
+	svg, err = RenderWithOptions(plain, DefaultRenderOptions().WithStructureDescription(true))
+	if err != nil {
+		t.Fatalf("RenderWithOptions() error = %v", err)
+	}
+	want := `<desc id="chart-desc-my-svg">flowchart diagram with 2 nodes and 1 connection. Nodes: Cart, Checkout. Connections: Cart to Checkout labelled pay.</desc>`
+	if !strings.Contains(svg, want) {
+		t.Fatalf("expected generated description %q", want)
+	}
+}
+
+func TestRenderDiagramTitleGrowsViewBox(t *testing.T) {
+	source := "flowchart LR\n  A --> B"
+	parsed, err := ParseMermaid(source)
+	if err != nil {
+		t.Fatalf("ParseMermaid() error = %v", err)
+	}
+	plain := ComputeLayout(&parsed.Graph, DefaultRenderOptions().Theme, DefaultLayoutConfig())
+	parsed, err = ParseMermaid("---\ntitle: A title much wider than the diagram below it\n---\n" + source)
+	if err != nil {
+		t.Fatalf("ParseMermaid() error = %v", err)
+	}
+	titled := ComputeLayout(&parsed.Graph, DefaultRenderOptions().Theme, DefaultLayoutConfig())
+	if titled.ViewBoxY >= plain.ViewBoxY || titled.ViewBoxHeight <= plain.ViewBoxHeight {
+		t.Fatalf("expected the viewBox to grow upward, got y=%v h=%v from y=%v h=%v",
+			titled.ViewBoxY, titled.ViewBoxHeight, plain.ViewBoxY, plain.ViewBoxHeight)
+	}
+	if titled.ViewBoxWidth <= plain.ViewBoxWidth {
+		t.Fatalf("expected the viewBox to widen for the title, got %v from %v", titled.ViewBoxWidth, plain.ViewBoxWidth)
+	}
+	if titled.TitleY <= titled.ViewBoxY || titled.TitleY >= plain.ViewBoxY+diagramTitlePadding {
+		t.Fatalf("expected the title baseline between the new top and the diagram, got %v", titled.TitleY)
+	}
+
+	for _, tc := range []struct{ source, class string }{
+		{"---\ntitle: Orders\n---\nflowchart LR\n  A --> B", "flowchartTitleText"},
+		{"---\ntitle: Orders\n---\nclassDiagram\n  class A", "classTitleText"},
+		{"---\ntitle: Orders\n---\nstateDiagram-v2\n  [*] --> A", "statediagramTitleText"},
+		{"---\ntitle: Orders\n---\nerDiagram\n  A ||--o{ B : has", "erDiagramTitleText"},
+	} {
+		svg, err := Render(tc.source)
+		if err != nil {
+			t.Fatalf("Render(%q) error = %v", tc.source, err)
+		}
+		if !strings.Contains(svg, `class="`+tc.class+`"`) || !strings.Contains(svg, ">Orders</text></svg>") {
+			t.Errorf("expected a %s title in %q", tc.class, tc.source)
+		}
+	}
+}
+
+func TestFlowchartMarkdownLabelsAndWrapping(t *testing.T) {
+	input := `flowchart LR
+  A["` + "`**Bold** and _italic_`" + `"] -->|"` + "`*note*`" + `"| B[This plain label is long enough that it has to wrap]
+  B --> C[short]`
+	svg, err := Render(input)
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	for _, want := range []string{
+		"<p><strong>Bold</strong> and <em>italic</em></p>",
+		"<p><em>note</em></p>",
+	} {
+		if !strings.Contains(svg, want) {
+			t.Fatalf("expected %q in SVG", want)
+		}
+	}
+	if !regexp.MustCompile(`<p>This plain label[^<]*(<br/>[^<]+)+</p>`).MatchString(svg) {
+		t.Fatal("expected the long label to wrap with <br/>")
+	}
+
+	parsed, err := ParseMermaid(input)
+	if err != nil {
+		t.Fatalf("ParseMermaid() error = %v", err)
+	}
+	layout := ComputeLayout(&parsed.Graph, DefaultRenderOptions().Theme, DefaultLayoutConfig())
+	nodes := map[string]NodeLayout{}
+	for _, node := range layout.Nodes {
+		nodes[node.ID] = node
+	}
+	if got := nodes["A"].Label; got != "Bold and italic" {
+		t.Fatalf("A plain label = %q", got)
+	}
+	if len(nodes["B"].LabelLines) != 2 || nodes["B"].H <= nodes["C"].H {
+		t.Fatalf("expected B to wrap onto two lines and grow taller than C, got %d lines, %v vs %v",
+			len(nodes["B"].LabelLines), nodes["B"].H, nodes["C"].H)
+	}
+
+	config := DefaultLayoutConfig()
+	config.WrappingWidth = 0
+	layout = ComputeLayout(&parsed.Graph, DefaultRenderOptions().Theme, config)
+	for _, node := range layout.Nodes {
+		if node.ID == "B" && len(node.LabelLines) != 1 {
+			t.Fatalf("expected wrapping to be disabled, got %d lines", len(node.LabelLines))
+		}
+	}
+}
+
+func TestFlowchartEdgesClipToShapeOutline(t *testing.T) {
+	parsed, err := ParseMermaid(`flowchart TD
+  A{Decide} --> B@{ shape: tri, label: "Extract" }
+  B --> C@{ shape: f-circ }`)
+	if err != nil {
+		t.Fatalf("ParseMermaid() error = %v", err)
+	}
+	layout := ComputeLayout(&parsed.Graph, MermaidDefaultTheme(), DefaultLayoutConfig())
+
+	nodes := map[string]NodeLayout{}
+	for _, node := range layout.Nodes {
+		nodes[node.ID] = node
+	}
+	if c := nodes["C"]; c.W != 14 || c.H != 14 {
+		t.Fatalf("junction size = %vx%v, want 14x14", c.W, c.H)
+	}
+	for _, text := range layout.Texts {
+		if text.Value == "C" {
+			t.Fatalf("expected junction label to be hidden, got %#v", text)
+		}
+	}
+
+	// A straight vertical edge leaves the diamond at its bottom vertex and
+	// enters the triangle at its apex, both well inside the bounding boxes.
+	a, b := nodes["A"], nodes["B"]
+	for _, path := range layout.Paths {
+		if !strings.HasPrefix(path.ID, "L_A_B_") {
+			continue
+		}
+		fields := strings.Fields(path.D)
+		startY, _ := strconv.ParseFloat(fields[2], 64)
+		endY, _ := strconv.ParseFloat(fields[len(fields)-1], 64)
+		if math.Abs(startY-(a.Y+a.H)) > 0.5 {
+			t.Fatalf("edge starts at y=%v, want diamond bottom %v", startY, a.Y+a.H)
+		}
+		if math.Abs(endY-b.Y) > 0.5 {
+			t.Fatalf("edge ends at y=%v, want triangle apex %v", endY, b.Y)
+		}
+		return
+	}
+	t.Fatalf("edge A->B not found in %#v", layout.Paths)
+}
+
+func TestFlowchartCloudAndBangClipEdges(t *testing.T) {
+	parsed, err := ParseMermaid(`flowchart LR
+  A --> B@{ shape: cloud, label: "Internet" } --> C@{ shape: bang, label: "Boom" }`)
+	if err != nil {
+		t.Fatalf("ParseMermaid() error = %v", err)
+	}
+	layout := ComputeLayout(&parsed.Graph, MermaidDefaultTheme(), DefaultLayoutConfig())
+
+	nodes := map[string]NodeLayout{}
+	for _, node := range layout.Nodes {
+		nodes[node.ID] = node
+	}
+	endX := func(prefix string) float64 {
+		for _, path := range layout.Paths {
+			if strings.HasPrefix(path.ID, prefix) {
+				fields := strings.Fields(path.D)
+				x, _ := strconv.ParseFloat(fields[len(fields)-2], 64)
+				return x
+			}
+		}
+		t.Fatalf("edge %s not found in %#v", prefix, layout.Paths)
+		return 0
+	}
+
+	// The cloud dips into a cusp on its left side, while the bang has a
+	// spike there that reaches its bounding box.
+	b, c := nodes["B"], nodes["C"]
+	if x := endX("L_A_B_"); x < b.X+0.05*b.W || x > b.X+b.W/2 {
+		t.Fatalf("edge to the cloud ends at x=%v, want inside the box from x=%v", x, b.X)
+	}
+	if x := endX("L_B_C_"); math.Abs(x-c.X) > 1 {
+		t.Fatalf("edge to the bang ends at x=%v, want its spike at x=%v", x, c.X)
+	}
+}
+
+func TestFlowchartNestedSubgraphLabelsDoNotOverlap(t *testing.T) {
+	input := `flowchart TB
+  subgraph one
+    subgraph inner
+      A --> B
+    end
+  end
+  C --> A`
+	parsed, err := ParseMermaid(input)
+	if err != nil {
+		t.Fatalf("ParseMermaid() error = %v", err)
+	}
+	layout := ComputeLayout(&parsed.Graph, DefaultRenderOptions().Theme, DefaultLayoutConfig())
+	labels := map[string]LayoutText{}
+	for _, text := range layout.Texts {
+		if text.Class == "cluster-label" {
+			labels[text.Value] = text
+		}
+	}
+	outer, inner := labels["one"], labels["inner"]
+	if outer.Value == "" || inner.Value == "" {
+		t.Fatalf("expected both cluster labels, got %+v", layout.Texts)
+	}
+	if inner.Y-outer.Y < inner.Size*1.5 {
+		t.Fatalf("expected the outer label a full line above the inner one, got one at y=%v and inner at y=%v", outer.Y, inner.Y)
+	}
+	var innerBox LayoutRect
+	for _, rect := range layout.Rects {
+		if rect.Class == "cluster" && rect.Y > innerBox.Y {
+			innerBox = rect
+		}
+	}
+	if outer.Y+outer.Size/2 > innerBox.Y {
+		t.Fatalf("expected the outer label above the inner box at y=%v, got y=%v", innerBox.Y, outer.Y)
+	}
+}
+
+func TestFlowchartEdgeFromEmptySubgraph(t *testing.T) {
+	svg, err := Render("flowchart TB; subgraph sg1; end; subgraph sg2; a; end; sg1 --> sg2")
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	if strings.Contains(svg, "NaN") {
+		t.Fatal("expected the edge from an empty subgraph to have finite coordinates")
+	}
+	parsed, err := ParseMermaid("flowchart TB; subgraph sg1; end; subgraph sg2; a; end; sg1 --> sg2")
+	if err != nil {
+		t.Fatalf("ParseMermaid() error = %v", err)
+	}
+	layout := ComputeLayout(&parsed.Graph, DefaultRenderOptions().Theme, DefaultLayoutConfig())
+	clusters := 0
+	for _, rect := range layout.Rects {
+		if rect.Class == "cluster" {
+			clusters++
+			if rect.W <= 0 || rect.H <= 0 {
+				t.Fatalf("expected a sized cluster box, got %+v", rect)
+			}
+		}
+	}
+	if clusters != 2 {
+		t.Fatalf("clusters = %d, want 2", clusters)
+	}
+}
+
 func TestFlowchartSubgraphClustersUseChildBounds(t *testing.T) {
 	input := `flowchart TD
   subgraph API
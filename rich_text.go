@@ -0,0 +1,350 @@
+package mermaid
+
+import (
+	"html"
+	"strings"
+	"unicode"
+)
+
+// defaultWrappingWidth matches mermaid's flowchart.wrappingWidth.
+const defaultWrappingWidth = 200.0
+
+// TextRun is a span of label text drawn in one inline style.
+type TextRun struct {
+	Text   string `json:"text,omitempty"`
+	Bold   bool   `json:"bold,omitempty"`
+	Italic bool   `json:"italic,omitempty"`
+	Code   bool   `json:"code,omitempty"`
+}
+
+func (r TextRun) sameStyle(other TextRun) bool {
+	return r.Bold == other.Bold && r.Italic == other.Italic && r.Code == other.Code
+}
+
+// markdownLabel reports whether label is a mermaid markdown string, written
+// between backticks, and returns its body.
+func markdownLabel(label string) (string, bool) {
+	trimmed := strings.TrimSpace(label)
+	if len(trimmed) >= 2 && trimmed[0] == '`' && trimmed[len(trimmed)-1] == '`' {
+		return trimmed[1 : len(trimmed)-1], true
+	}
+	return label, false
+}
+
+// parseLabelText splits a label into lines of styled runs. Lines break on
+// newlines and <br>. Markdown strings additionally understand **bold**,
+// __bold__, *italic*, _italic_ and `code`; other labels are plain text.
+func parseLabelText(label string) [][]TextRun {
+	body, isMarkdown := markdownLabel(label)
+	lines := splitLinesPreserve(body)
+	if !isMarkdown {
+		out := make([][]TextRun, 0, len(lines))
+		for _, line := range lines {
+			out = append(out, []TextRun{{Text: line}})
+		}
+		return out
+	}
+	// Markdown strings are usually indented along with the diagram source.
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(line)
+	}
+	for len(lines) > 1 && lines[0] == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 1 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return parseInlineMarkdown(strings.Join(lines, "\n"))
+}
+
+// parseInlineMarkdown tokenizes the inline subset of markdown used in
+// labels. Emphasis may span line breaks; a delimiter without a matching
+// closer is kept as literal text.
+func parseInlineMarkdown(text string) [][]TextRun {
+	src := []rune(text)
+	out := [][]TextRun{nil}
+	var style TextRun
+	var buf strings.Builder
+	flush := func() {
+		if buf.Len() == 0 {
+			return
+		}
+		run := style
+		run.Text = buf.String()
+		buf.Reset()
+		line := &out[len(out)-1]
+		if n := len(*line); n > 0 && (*line)[n-1].sameStyle(run) {
+			(*line)[n-1].Text += run.Text
+			return
+		}
+		*line = append(*line, run)
+	}
+	closes := func(from int, delim string) bool {
+		return strings.Contains(string(src[from:]), delim)
+	}
+	at := func(i int) rune {
+		if i < 0 || i >= len(src) {
+			return ' '
+		}
+		return src[i]
+	}
+
+	for i := 0; i < len(src); i++ {
+		r := src[i]
+		switch {
+		case r == '\n':
+			flush()
+			out = append(out, nil)
+		case style.Code && r != '`':
+			buf.WriteRune(r)
+		case r == '`':
+			if style.Code || closes(i+1, "`") {
+				flush()
+				style.Code = !style.Code
+				continue
+			}
+			buf.WriteRune(r)
+		case r == '\\' && i+1 < len(src) && strings.ContainsRune("\\`*_", src[i+1]):
+			buf.WriteRune(src[i+1])
+			i++
+		case (r == '*' || r == '_') && at(i+1) == r:
+			delim := string([]rune{r, r})
+			if r == '_' && !style.Bold && isWordRune(at(i-1)) {
+				buf.WriteString(delim)
+				i++
+				continue
+			}
+			if style.Bold || (closes(i+2, delim) && !unicode.IsSpace(at(i+2))) {
+				flush()
+				style.Bold = !style.Bold
+			} else {
+				buf.WriteString(delim)
+			}
+			i++
+		case r == '*' || r == '_':
+			opening := !style.Italic
+			if r == '_' && ((opening && isWordRune(at(i-1))) || (!opening && isWordRune(at(i+1)))) {
+				buf.WriteRune(r)
+				continue
+			}
+			if !opening || (closes(i+1, string(r)) && !unicode.IsSpace(at(i+1))) {
+				flush()
+				style.Italic = !style.Italic
+				continue
+			}
+			buf.WriteRune(r)
+		default:
+			buf.WriteRune(r)
+		}
+	}
+	flush()
+	for i, line := range out {
+		if line == nil {
+			out[i] = []TextRun{{Text: ""}}
+		}
+	}
+	return out
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// labelRunWidth measures one run with the face it is drawn in: the bold or
+// italic font of the family, or a monospace face for code.
+func labelRunWidth(run TextRun, fontSize float64, fontFamily string, fast bool) float64 {
+	family := fontFamily
+	if run.Code {
+		family = "monospace"
+	}
+	if !fast && (run.Bold || run.Italic) {
+		if w, ok := measureChainTextWidth(run.Text, fontSize, styledFontChain(family, run.Bold, run.Italic)); ok {
+			return w
+		}
+	}
+	return measureTextWidthWithFontSize(run.Text, fontSize, fast, family)
+}
+
+func labelLineWidth(line []TextRun, fontSize float64, fontFamily string, fast bool) float64 {
+	w := 0.0
+	for _, run := range line {
+		w += labelRunWidth(run, fontSize, fontFamily, fast)
+	}
+	return w
+}
+
+func labelLinesWidth(lines [][]TextRun, fontSize float64, fontFamily string, fast bool) float64 {
+	w := 0.0
+	for _, line := range lines {
+		w = max(w, labelLineWidth(line, fontSize, fontFamily, fast))
+	}
+	return w
+}
+
+// wrapLabelLines breaks lines wider than maxWidth at spaces. A single word
+// wider than maxWidth keeps a line of its own. A maxWidth of zero or less
+// disables wrapping.
+func wrapLabelLines(lines [][]TextRun, maxWidth, fontSize float64, fontFamily string, fast bool) [][]TextRun {
+	if maxWidth <= 0 {
+		return lines
+	}
+	out := make([][]TextRun, 0, len(lines))
+	for _, line := range lines {
+		if labelLineWidth(line, fontSize, fontFamily, fast) <= maxWidth {
+			out = append(out, line)
+			continue
+		}
+		var current []TextRun
+		currentW := 0.0
+		var pendingSpace []TextRun
+		for _, word := range splitLabelWords(line) {
+			if isSpaceRuns(word) {
+				pendingSpace = append(pendingSpace, word...)
+				continue
+			}
+			wordW := labelLineWidth(word, fontSize, fontFamily, fast)
+			spaceW := labelLineWidth(pendingSpace, fontSize, fontFamily, fast)
+			if len(current) > 0 && currentW+spaceW+wordW > maxWidth {
+				out = append(out, current)
+				current = nil
+				currentW = 0
+				pendingSpace = nil
+				spaceW = 0
+			}
+			if len(current) > 0 {
+				current = appendLabelRuns(current, pendingSpace...)
+				currentW += spaceW
+			}
+			pendingSpace = nil
+			current = appendLabelRuns(current, word...)
+			currentW += wordW
+		}
+		if len(current) == 0 {
+			current = []TextRun{{Text: ""}}
+		}
+		out = append(out, current)
+	}
+	return out
+}
+
+// splitLabelWords cuts a line into alternating word and space groups. A word
+// may span several runs, as in "**bold**ed".
+func splitLabelWords(line []TextRun) [][]TextRun {
+	var groups [][]TextRun
+	var current []TextRun
+	currentSpace := false
+	for _, run := range line {
+		for _, r := range run.Text {
+			space := unicode.IsSpace(r)
+			if len(current) > 0 && space != currentSpace {
+				groups = append(groups, current)
+				current = nil
+			}
+			currentSpace = space
+			piece := run
+			piece.Text = string(r)
+			current = appendLabelRuns(current, piece)
+		}
+	}
+	if len(current) > 0 {
+		groups = append(groups, current)
+	}
+	return groups
+}
+
+func isSpaceRuns(runs []TextRun) bool {
+	for _, run := range runs {
+		if strings.TrimSpace(run.Text) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+func appendLabelRuns(line []TextRun, runs ...TextRun) []TextRun {
+	for _, run := range runs {
+		if n := len(line); n > 0 && line[n-1].sameStyle(run) {
+			line[n-1].Text += run.Text
+			continue
+		}
+		line = append(line, run)
+	}
+	return line
+}
+
+// labelLinesText flattens styled lines back to plain text.
+func labelLinesText(lines [][]TextRun) string {
+	parts := make([]string, len(lines))
+	for i, line := range lines {
+		var b strings.Builder
+		for _, run := range line {
+			b.WriteString(run.Text)
+		}
+		parts[i] = b.String()
+	}
+	return strings.Join(parts, "\n")
+}
+
+// labelLinesHTML renders styled lines as the inline HTML written inside
+// foreignObject labels.
+func labelLinesHTML(lines [][]TextRun) string {
+	var b strings.Builder
+	for i, line := range lines {
+		if i > 0 {
+			b.WriteString("<br/>")
+		}
+		for _, run := range line {
+			text := html.EscapeString(run.Text)
+			if run.Code {
+				text = "<code>" + text + "</code>"
+			}
+			if run.Italic {
+				text = "<em>" + text + "</em>"
+			}
+			if run.Bold {
+				text = "<strong>" + text + "</strong>"
+			}
+			b.WriteString(text)
+		}
+	}
+	return b.String()
+}
+
+// labelLinesTSpans renders styled lines as one outer tspan per line, with
+// nested tspans carrying the run styles.
+func labelLinesTSpans(lines [][]TextRun, x string, lineStep float64) string {
+	var b strings.Builder
+	for idx, line := range lines {
+		dy := "0"
+		if idx > 0 {
+			dy = formatFloat(lineStep)
+		}
+		b.WriteString(`<tspan x="` + x + `" dy="` + dy + `">`)
+		for _, run := range line {
+			text := html.EscapeString(run.Text)
+			if !run.Bold && !run.Italic && !run.Code {
+				b.WriteString(text)
+				continue
+			}
+			b.WriteString(`<tspan`)
+			if run.Bold {
+				b.WriteString(` font-weight="bold"`)
+			}
+			if run.Italic {
+				b.WriteString(` font-style="italic"`)
+			}
+			if run.Code {
+				b.WriteString(` font-family="monospace"`)
+			}
+			b.WriteString(`>` + text + `</tspan>`)
+		}
+		b.WriteString(`</tspan>`)
+	}
+	return b.String()
+}
+
+// flowchartLabelLines parses and wraps a flowchart node or edge label at the
+// 16px size its box is measured with.
+func flowchartLabelLines(label string, config LayoutConfig) [][]TextRun {
+	return wrapLabelLines(parseLabelText(label), config.WrappingWidth, 16, "", config.FastTextMetrics)
+}
@@ -0,0 +1,95 @@
+package mermaid
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseLabelText(t *testing.T) {
+	cases := []struct {
+		label string
+		want  [][]TextRun
+	}{
+		{"plain **text**", [][]TextRun{{{Text: "plain **text**"}}}},
+		{"one<br>two", [][]TextRun{{{Text: "one"}}, {{Text: "two"}}}},
+		{"`**bold** and _italic_`", [][]TextRun{{
+			{Text: "bold", Bold: true},
+			{Text: " and "},
+			{Text: "italic", Italic: true},
+		}}},
+		{"`***both*** `code *x*``", [][]TextRun{{
+			{Text: "both", Bold: true, Italic: true},
+			{Text: " "},
+			{Text: "code *x*", Code: true},
+		}}},
+		{"`snake_case_name 2 * 3`", [][]TextRun{{{Text: "snake_case_name 2 * 3"}}}},
+		{"`**spans\n  lines**`", [][]TextRun{{{Text: "spans", Bold: true}}, {{Text: "lines", Bold: true}}}},
+		{"`\\*literal\\*`", [][]TextRun{{{Text: "*literal*"}}}},
+	}
+	for _, tc := range cases {
+		if got := parseLabelText(tc.label); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("parseLabelText(%q) = %+v, want %+v", tc.label, got, tc.want)
+		}
+	}
+}
+
+func TestWrapLabelLines(t *testing.T) {
+	lines := parseLabelText("`aaaa **bbbb cccc** dddd`")
+	// Fast metrics measure 8px per rune at 16px.
+	wrapped := wrapLabelLines(lines, 80, 16, "", true)
+	want := [][]TextRun{
+		{{Text: "aaaa "}, {Text: "bbbb", Bold: true}},
+		{{Text: "cccc", Bold: true}, {Text: " dddd"}},
+	}
+	if !reflect.DeepEqual(wrapped, want) {
+		t.Fatalf("wrapLabelLines = %+v, want %+v", wrapped, want)
+	}
+	if got := labelLinesText(wrapped); got != "aaaa bbbb\ncccc dddd" {
+		t.Fatalf("labelLinesText = %q", got)
+	}
+
+	long := parseLabelText("supercalifragilistic word")
+	wrapped = wrapLabelLines(long, 40, 16, "", true)
+	if got := labelLinesText(wrapped); got != "supercalifragilistic\nword" {
+		t.Fatalf("expected an overlong word to keep its own line, got %q", got)
+	}
+	if got := wrapLabelLines(long, 0, 16, "", true); !reflect.DeepEqual(got, long) {
+		t.Fatalf("expected a zero width to disable wrapping, got %+v", got)
+	}
+}
+
+func TestLabelLinesMarkup(t *testing.T) {
+	lines := parseLabelText("`**a** <_b_>\n`c``")
+	if got := labelLinesHTML(lines); got != "<strong>a</strong> &lt;<em>b</em>&gt;<br/><code>c</code>" {
+		t.Fatalf("labelLinesHTML = %q", got)
+	}
+	tspans := labelLinesTSpans(lines, "0", 19.2)
+	for _, want := range []string{
+		`<tspan x="0" dy="0"><tspan font-weight="bold">a</tspan> &lt;<tspan font-style="italic">b</tspan>&gt;</tspan>`,
+		`<tspan x="0" dy="19.2"><tspan font-family="monospace">c</tspan></tspan>`,
+	} {
+		if !strings.Contains(tspans, want) {
+			t.Fatalf("expected %q in %q", want, tspans)
+		}
+	}
+}
+
+func TestLabelRunWidthMeasuresStyledFaces(t *testing.T) {
+	isolateFonts(t)
+	if len(bundledFonts) == 0 {
+		t.Skip("built without bundled fonts")
+	}
+	SetFontDirs()
+	for _, run := range []TextRun{{Text: "Weighted", Bold: true}, {Text: "Weighted", Italic: true}} {
+		path := resolveStyledFontPath("", run.Bold, run.Italic)
+		want, ok := measureChainTextWidth(run.Text, 16, []string{path})
+		if !ok {
+			t.Fatalf("could not measure with %s", path)
+		}
+		if got := labelRunWidth(run, 16, "", false); math.Abs(got-want) > 0.01 {
+			t.Fatalf("labelRunWidth(%+v) = %v, want %v measured with %s", run, got, want, path)
+		}
+	}
+}
@@ -1,6 +1,8 @@
 package mermaid
 
 import (
+	"encoding/xml"
+	"io"
 	"os"
 	"path/filepath"
 	"sort"
@@ -60,3 +62,35 @@ func TestSamplesRender(t *testing.T) {
 		})
 	}
 }
+
+func TestSamplesRenderWithSVGLabels(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join("samples", "*.mmd"))
+	if err != nil {
+		t.Fatalf("list samples: %v", err)
+	}
+	for _, path := range paths {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("read sample %s: %v", path, err)
+		}
+		svg, err := RenderWithOptions(
+			string(content),
+			DefaultRenderOptions().WithAllowApproximate(true).WithSVGLabels(true),
+		)
+		if err != nil {
+			t.Fatalf("render sample %s: %v", path, err)
+		}
+		if strings.Contains(svg, "<foreignObject") {
+			t.Errorf("sample %s kept a foreignObject label in SVG label mode", path)
+		}
+		decoder := xml.NewDecoder(strings.NewReader(svg))
+		for {
+			if _, err := decoder.Token(); err != nil {
+				if err != io.EOF {
+					t.Errorf("sample %s is not well-formed XML: %v", path, err)
+				}
+				break
+			}
+		}
+	}
+}
@@ -572,3 +572,32 @@ func sequenceOpenArrow(arrow string) bool {
 	base := sequenceArrowBase(arrow)
 	return strings.Contains(base, "->") && !strings.Contains(base, ">>")
 }
+
+// layoutSequencePlan returns the participants, their display labels and the
+// render plan of a sequence layout, falling back to the message order for
+// layouts without an explicit participant list.
+func layoutSequencePlan(layout Layout, theme Theme) ([]string, map[string]string, sequenceRenderPlan) {
+	participants := append([]string(nil), layout.SequenceParticipants...)
+	if len(participants) == 0 {
+		seen := map[string]bool{}
+		for _, msg := range layout.SequenceMessages {
+			if strings.TrimSpace(msg.From) != "" && !seen[msg.From] {
+				seen[msg.From] = true
+				participants = append(participants, msg.From)
+			}
+			if strings.TrimSpace(msg.To) != "" && !seen[msg.To] {
+				seen[msg.To] = true
+				participants = append(participants, msg.To)
+			}
+		}
+	}
+	labels := layout.SequenceParticipantLabels
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	events := layout.SequenceEvents
+	if len(events) == 0 {
+		events = defaultSequenceEvents(layout.SequenceMessages)
+	}
+	return participants, labels, buildSequencePlan(participants, labels, layout.SequenceMessages, events, theme)
+}
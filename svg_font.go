@@ -0,0 +1,335 @@
+package mermaid
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"html"
+	"sort"
+	"strings"
+	"unicode"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+)
+
+// embedSVGFont subsets the font at fontPath to the characters the SVG
+// displays and inlines it as an @font-face rule that every element of the
+// diagram uses. The subset carries the advances and kerning text was
+// measured with, browserCharScale included, so labels render exactly as
+// wide as the layout made room for. The SVG is returned unchanged when
+// the font cannot be read.
+func embedSVGFont(svg string, fontPath string, fontFamily string) string {
+	face := loadFontFace(fontPath)
+	if face == nil {
+		return svg
+	}
+	data, err := readFontData(fontPath)
+	if err != nil {
+		return svg
+	}
+	subset, err := subsetWebFont(data, face, svgTextRunes(svg))
+	if err != nil {
+		return svg
+	}
+
+	family := fmt.Sprintf("mmdg-%08x", crc32.ChecksumIEEE(subset))
+	fallback := strings.TrimSpace(fontFamily)
+	if fallback == "" {
+		fallback = defaultMetricFontFamily
+	}
+	css := `@font-face{font-family:"` + family + `";src:url(data:font/ttf;base64,` +
+		base64.StdEncoding.EncodeToString(subset) + `) format("truetype");}` +
+		`#my-svg,#my-svg *{font-family:"` + family + `",` + fallback + ` !important;}`
+
+	if i := strings.Index(svg, "</style>"); i >= 0 {
+		return svg[:i] + css + svg[i:]
+	}
+	start := strings.Index(svg, "<svg")
+	if start < 0 {
+		return svg
+	}
+	end := strings.Index(svg[start:], ">")
+	if end < 0 {
+		return svg
+	}
+	end += start + 1
+	return svg[:end] + "<style>" + css + "</style>" + svg[end:]
+}
+
+// svgTextRunes returns the distinct characters of an SVG's text content,
+// skipping markup, style sheets and scripts.
+func svgTextRunes(svg string) []rune {
+	seen := map[rune]bool{' ': true}
+	for len(svg) > 0 {
+		open := strings.IndexByte(svg, '<')
+		if open < 0 {
+			open = len(svg)
+		}
+		for _, r := range html.UnescapeString(svg[:open]) {
+			if !unicode.IsControl(r) {
+				seen[r] = true
+			}
+		}
+		svg = svg[open:]
+		for _, raw := range []string{"<style", "<script"} {
+			if strings.HasPrefix(svg, raw) {
+				if end := strings.Index(svg, "</"+raw[1:]); end >= 0 {
+					svg = svg[end:]
+				}
+			}
+		}
+		closeTag := strings.IndexByte(svg, '>')
+		if closeTag < 0 {
+			break
+		}
+		svg = svg[closeTag+1:]
+	}
+	runes := make([]rune, 0, len(seen))
+	for r := range seen {
+		runes = append(runes, r)
+	}
+	sort.Slice(runes, func(i, j int) bool { return runes[i] < runes[j] })
+	return runes
+}
+
+// maxWebFontKernPairs bounds the kern table to what one format 0 subtable
+// can hold.
+const maxWebFontKernPairs = (0xFFFF - 14) / 6
+
+// subsetWebFont builds a TrueType font holding only the glyphs of runes,
+// renumbered from 1, with a cmap for them. Advances are scaled by
+// browserCharScale and kerning is flattened into a kern table, matching
+// measureNativeTextWidth; GSUB and GPOS are left out so browsers apply no
+// shaping the layout did not account for.
+func subsetWebFont(data []byte, face *sfnt.Font, runes []rune) ([]byte, error) {
+	tables, err := readTrueTypeTables(data)
+	if err != nil {
+		return nil, err
+	}
+	for _, tag := range []string{"name", "OS/2"} {
+		if _, ok := tables[tag]; !ok {
+			return nil, fmt.Errorf("font has no %q table", tag)
+		}
+	}
+	if len(tables["hhea"]) < 36 {
+		return nil, fmt.Errorf("truncated hhea table")
+	}
+	glyphs := trueTypeGlyphs(tables)
+	unitsPerEm := fixed.Int26_6(binary.BigEndian.Uint16(tables["head"][18:])) << 6
+
+	var buf sfnt.Buffer
+	keep := map[sfnt.GlyphIndex]bool{0: true}
+	runeGlyph := make(map[rune]sfnt.GlyphIndex, len(runes))
+	scale := map[sfnt.GlyphIndex]float64{}
+	for _, r := range runes {
+		glyph, err := face.GlyphIndex(&buf, r)
+		if err != nil || glyph == 0 || int(glyph) >= len(glyphs) {
+			continue
+		}
+		keep[glyph] = true
+		runeGlyph[r] = glyph
+		if _, ok := scale[glyph]; !ok {
+			scale[glyph] = browserCharScale(r)
+		}
+	}
+	kept := keepTrueTypeComponents(glyphs, keep)
+
+	order := make([]sfnt.GlyphIndex, 0, len(keep))
+	remap := make(map[sfnt.GlyphIndex]uint16, len(keep))
+	for glyph, ok := range kept {
+		if ok {
+			remap[sfnt.GlyphIndex(glyph)] = uint16(len(order))
+			order = append(order, sfnt.GlyphIndex(glyph))
+		}
+	}
+
+	hmtx := tables["hmtx"]
+	metricCount := int(binary.BigEndian.Uint16(tables["hhea"][34:]))
+	metrics := func(glyph int) (uint16, uint16) {
+		if metricCount == 0 || len(hmtx) < metricCount*4 {
+			return 0, 0
+		}
+		if glyph < metricCount {
+			return binary.BigEndian.Uint16(hmtx[glyph*4:]), binary.BigEndian.Uint16(hmtx[glyph*4+2:])
+		}
+		advance := binary.BigEndian.Uint16(hmtx[(metricCount-1)*4:])
+		pos := metricCount*4 + (glyph-metricCount)*2
+		if pos+2 > len(hmtx) {
+			return advance, 0
+		}
+		return advance, binary.BigEndian.Uint16(hmtx[pos:])
+	}
+
+	var newGlyf bytes.Buffer
+	newLoca := make([]byte, 0, (len(order)+1)*4)
+	newHmtx := make([]byte, 0, len(order)*4)
+	for _, glyph := range order {
+		newLoca = binary.BigEndian.AppendUint32(newLoca, uint32(newGlyf.Len()))
+		outline := append([]byte(nil), glyphs[glyph]...)
+		forEachTrueTypeComponent(outline, func(pos int) {
+			binary.BigEndian.PutUint16(outline[pos:], remap[sfnt.GlyphIndex(binary.BigEndian.Uint16(outline[pos:]))])
+		})
+		writeTrueTypeGlyph(&newGlyf, outline)
+
+		advance, lsb := metrics(int(glyph))
+		if s, ok := scale[glyph]; ok {
+			advance = uint16(float64(advance)*s + 0.5)
+		}
+		newHmtx = binary.BigEndian.AppendUint16(newHmtx, advance)
+		newHmtx = binary.BigEndian.AppendUint16(newHmtx, lsb)
+	}
+	newLoca = binary.BigEndian.AppendUint32(newLoca, uint32(newGlyf.Len()))
+
+	type kernPair struct {
+		left, right uint16
+		value       int16
+	}
+	var pairs []kernPair
+	for _, left := range order {
+		if _, ok := scale[left]; !ok {
+			continue
+		}
+		for _, right := range order {
+			if _, ok := scale[right]; !ok || len(pairs) == maxWebFontKernPairs {
+				continue
+			}
+			kern, err := face.Kern(&buf, left, right, unitsPerEm, font.HintingNone)
+			if err == nil && kern != 0 {
+				pairs = append(pairs, kernPair{remap[left], remap[right], int16(kern.Round())})
+			}
+		}
+	}
+
+	head := append([]byte(nil), tables["head"]...)
+	binary.BigEndian.PutUint32(head[8:], 0)
+	binary.BigEndian.PutUint16(head[50:], 1)
+	hhea := append([]byte(nil), tables["hhea"]...)
+	binary.BigEndian.PutUint16(hhea[34:], uint16(len(order)))
+	maxp := append([]byte(nil), tables["maxp"]...)
+	binary.BigEndian.PutUint16(maxp[4:], uint16(len(order)))
+	post := make([]byte, 32)
+	copy(post, tables["post"])
+	binary.BigEndian.PutUint32(post, 0x00030000)
+
+	out := map[string][]byte{
+		"cmap": webFontCmap(runeGlyph, remap),
+		"glyf": newGlyf.Bytes(),
+		"head": head,
+		"hhea": hhea,
+		"hmtx": newHmtx,
+		"loca": newLoca,
+		"maxp": maxp,
+		"name": tables["name"],
+		"OS/2": tables["OS/2"],
+		"post": post,
+	}
+	for _, tag := range []string{"cvt ", "fpgm", "prep"} {
+		if table, ok := tables[tag]; ok {
+			out[tag] = table
+		}
+	}
+	if len(pairs) > 0 {
+		kern := binary.BigEndian.AppendUint16(nil, 0)
+		kern = binary.BigEndian.AppendUint16(kern, 1)
+		kern = binary.BigEndian.AppendUint16(kern, 0)
+		kern = binary.BigEndian.AppendUint16(kern, uint16(14+len(pairs)*6))
+		kern = binary.BigEndian.AppendUint16(kern, 0x0001)
+		kern = binary.BigEndian.AppendUint16(kern, uint16(len(pairs)))
+		kern = appendBinarySearchFields(kern, len(pairs), 6)
+		for _, p := range pairs {
+			kern = binary.BigEndian.AppendUint16(kern, p.left)
+			kern = binary.BigEndian.AppendUint16(kern, p.right)
+			kern = binary.BigEndian.AppendUint16(kern, uint16(p.value))
+		}
+		out["kern"] = kern
+	}
+	return finishTrueType(out), nil
+}
+
+// webFontCmap maps runes to renumbered glyphs: a format 4 subtable for the
+// Basic Multilingual Plane and, when needed, a format 12 one for the rest.
+func webFontCmap(runeGlyph map[rune]sfnt.GlyphIndex, remap map[sfnt.GlyphIndex]uint16) []byte {
+	runes := make([]rune, 0, len(runeGlyph))
+	astral := false
+	for r := range runeGlyph {
+		runes = append(runes, r)
+		astral = astral || r > 0xFFFF
+	}
+	sort.Slice(runes, func(i, j int) bool { return runes[i] < runes[j] })
+
+	// Format 4 with one segment per character, closed by the 0xFFFF one.
+	var bmp []rune
+	for _, r := range runes {
+		if r < 0xFFFF {
+			bmp = append(bmp, r)
+		}
+	}
+	segments := len(bmp) + 1
+	format4 := binary.BigEndian.AppendUint16(nil, 4)
+	format4 = binary.BigEndian.AppendUint16(format4, uint16(16+segments*8))
+	format4 = binary.BigEndian.AppendUint16(format4, 0)
+	format4 = binary.BigEndian.AppendUint16(format4, uint16(segments*2))
+	format4 = appendBinarySearchFields(format4, segments, 2)
+	for _, r := range bmp {
+		format4 = binary.BigEndian.AppendUint16(format4, uint16(r))
+	}
+	format4 = binary.BigEndian.AppendUint16(format4, 0xFFFF)
+	format4 = binary.BigEndian.AppendUint16(format4, 0)
+	for _, r := range bmp {
+		format4 = binary.BigEndian.AppendUint16(format4, uint16(r))
+	}
+	format4 = binary.BigEndian.AppendUint16(format4, 0xFFFF)
+	for _, r := range bmp {
+		format4 = binary.BigEndian.AppendUint16(format4, remap[runeGlyph[r]]-uint16(r))
+	}
+	format4 = binary.BigEndian.AppendUint16(format4, 1)
+	format4 = append(format4, make([]byte, segments*2)...)
+
+	subtables := [][]byte{format4}
+	encodings := []uint16{1}
+	if astral {
+		format12 := binary.BigEndian.AppendUint16(nil, 12)
+		format12 = binary.BigEndian.AppendUint16(format12, 0)
+		format12 = binary.BigEndian.AppendUint32(format12, uint32(16+len(runes)*12))
+		format12 = binary.BigEndian.AppendUint32(format12, 0)
+		format12 = binary.BigEndian.AppendUint32(format12, uint32(len(runes)))
+		for _, r := range runes {
+			format12 = binary.BigEndian.AppendUint32(format12, uint32(r))
+			format12 = binary.BigEndian.AppendUint32(format12, uint32(r))
+			format12 = binary.BigEndian.AppendUint32(format12, uint32(remap[runeGlyph[r]]))
+		}
+		subtables = append(subtables, format12)
+		encodings = append(encodings, 10)
+	}
+
+	cmap := binary.BigEndian.AppendUint16(nil, 0)
+	cmap = binary.BigEndian.AppendUint16(cmap, uint16(len(subtables)))
+	offset := 4 + 8*len(subtables)
+	for i, subtable := range subtables {
+		cmap = binary.BigEndian.AppendUint16(cmap, 3)
+		cmap = binary.BigEndian.AppendUint16(cmap, encodings[i])
+		cmap = binary.BigEndian.AppendUint32(cmap, uint32(offset))
+		offset += len(subtable)
+	}
+	for _, subtable := range subtables {
+		cmap = append(cmap, subtable...)
+	}
+	return cmap
+}
+
+// appendBinarySearchFields appends the searchRange, entrySelector and
+// rangeShift fields that describe a sorted TrueType array.
+func appendBinarySearchFields(b []byte, count int, size int) []byte {
+	entrySelector := 0
+	for 1<<(entrySelector+1) <= count {
+		entrySelector++
+	}
+	searchRange := (1 << entrySelector) * size
+	b = binary.BigEndian.AppendUint16(b, uint16(searchRange))
+	b = binary.BigEndian.AppendUint16(b, uint16(entrySelector))
+	return binary.BigEndian.AppendUint16(b, uint16(count*size-searchRange))
+}
@@ -0,0 +1,83 @@
+package mermaid
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+)
+
+func TestEmbedSVGFontSubsetsMeasuringFont(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "Go-Regular.ttf")
+	if err := os.WriteFile(path, goregular.TTF, 0o644); err != nil {
+		t.Fatalf("write font: %v", err)
+	}
+	svg := `<svg id="my-svg"><style>#my-svg{font-family:"trebuchet ms";}</style><text>AVt &amp; ü</text></svg>`
+	out := embedSVGFont(svg, path, "")
+	if !strings.Contains(out, `format("truetype");}#my-svg,#my-svg *{font-family:"mmdg-`) {
+		t.Fatalf("expected an @font-face rule applied to the diagram, got %s", out)
+	}
+	if !strings.HasSuffix(out, `!important;}</style><text>AVt &amp; ü</text></svg>`) {
+		t.Fatal("expected the rule to be appended to the existing style sheet")
+	}
+	match := regexp.MustCompile(`base64,([A-Za-z0-9+/=]+)\)`).FindStringSubmatch(out)
+	if match == nil {
+		t.Fatal("expected a base64 data URI")
+	}
+	data, err := base64.StdEncoding.DecodeString(match[1])
+	if err != nil {
+		t.Fatalf("decode font: %v", err)
+	}
+	subset, err := sfnt.Parse(data)
+	if err != nil {
+		t.Fatalf("parse subset: %v", err)
+	}
+	full, err := sfnt.Parse(goregular.TTF)
+	if err != nil {
+		t.Fatalf("parse font: %v", err)
+	}
+	if subset.NumGlyphs() > 12 {
+		t.Fatalf("subset keeps %d glyphs", subset.NumGlyphs())
+	}
+
+	var buf sfnt.Buffer
+	ppem := fixed.I(16)
+	advance := func(f *sfnt.Font, r rune) float64 {
+		glyph, err := f.GlyphIndex(&buf, r)
+		if err != nil || glyph == 0 {
+			t.Fatalf("no glyph for %q", r)
+		}
+		a, _ := f.GlyphAdvance(&buf, glyph, ppem, font.HintingNone)
+		return float64(a) / 64
+	}
+	for _, r := range "AVt&ü " {
+		want := advance(full, r) * browserCharScale(r)
+		if got := advance(subset, r); got < want-0.05 || got > want+0.05 {
+			t.Fatalf("advance of %q = %.2f, want the measured %.2f", r, got, want)
+		}
+	}
+	if glyph, _ := subset.GlyphIndex(&buf, 'Z'); glyph != 0 {
+		t.Fatal("expected characters outside the diagram to be dropped")
+	}
+
+	if out := embedSVGFont(`<svg id="my-svg"><text>x</text></svg>`, path, ""); !strings.HasPrefix(out, `<svg id="my-svg"><style>@font-face{`) {
+		t.Fatalf("expected a style element to be added, got %.60s", out)
+	}
+	if out := embedSVGFont(svg, filepath.Join(t.TempDir(), "missing.ttf"), ""); out != svg {
+		t.Fatal("expected the SVG to be unchanged without a font")
+	}
+}
+
+func TestSVGTextRunesSkipsMarkup(t *testing.T) {
+	got := string(svgTextRunes(`<svg><style>.a{color:red}</style><g class="q"><text>b&lt;a</text></g>` + "\n</svg>"))
+	if got != " <ab" {
+		t.Fatalf("svgTextRunes() = %q", got)
+	}
+}
@@ -0,0 +1,98 @@
+package mermaid
+
+import (
+	"html"
+	"strings"
+)
+
+// svgTextLabel is a label written as <text> in SVG label mode. The box is
+// the one the foreignObject form of the label would take; lines are stacked
+// inside it and aligned by Anchor.
+type svgTextLabel struct {
+	X, Y, W, H float64
+	Lines      [][]TextRun
+	// Anchor is start, middle or end; labels without one are centred, as
+	// their box is sized to the text.
+	Anchor    string
+	Size      float64
+	Family    string
+	Weight    string
+	Color     string
+	Opacity   float64
+	Transform string
+}
+
+// plainLabelLines is the single line of an unstyled label.
+func plainLabelLines(value string) [][]TextRun {
+	return [][]TextRun{{{Text: value}}}
+}
+
+// textLabelLines returns the layout's styled lines of a text, or its value
+// as a single line.
+func textLabelLines(text LayoutText) [][]TextRun {
+	if len(text.Lines) > 0 {
+		return text.Lines
+	}
+	return plainLabelLines(text.Value)
+}
+
+// htmlTextAnchor maps a CSS text-align value to an SVG text-anchor.
+func htmlTextAnchor(align string) string {
+	switch lower(align) {
+	case "start", "left":
+		return "start"
+	case "end", "right":
+		return "end"
+	}
+	return "middle"
+}
+
+// writeSVGTextLabel writes label as a <text> element with one tspan per
+// line. Empty labels write nothing.
+func writeSVGTextLabel(b *strings.Builder, label svgTextLabel, textColor string) {
+	if strings.TrimSpace(labelLinesText(label.Lines)) == "" {
+		return
+	}
+	lineHeight := label.H / float64(len(label.Lines))
+	if lineHeight <= 0 {
+		lineHeight = max(label.Size, 16) * 1.5
+	}
+	anchor := label.Anchor
+	textX := label.X + label.W/2
+	switch anchor {
+	case "start":
+		textX = label.X
+	case "end":
+		textX = label.X + label.W
+	default:
+		anchor = "middle"
+	}
+	fill := label.Color
+	if fill == "" {
+		fill = textColor
+	}
+
+	b.WriteString(`<text x="` + formatFloat(textX) + `" y="` + formatFloat(label.Y+lineHeight/2) + `"`)
+	b.WriteString(` text-anchor="` + anchor + `" dominant-baseline="central"`)
+	if label.Size > 0 {
+		b.WriteString(` font-size="` + formatFloat(label.Size) + `"`)
+	}
+	if label.Family != "" {
+		b.WriteString(` font-family="` + html.EscapeString(label.Family) + `"`)
+	}
+	if label.Weight != "" && label.Weight != "400" && label.Weight != "normal" {
+		b.WriteString(` font-weight="` + html.EscapeString(label.Weight) + `"`)
+	}
+	if fill != "" {
+		b.WriteString(` fill="` + html.EscapeString(fill) + `"`)
+	}
+	if strings.TrimSpace(label.Transform) != "" {
+		b.WriteString(` transform="` + html.EscapeString(label.Transform) + `"`)
+	}
+	if label.Opacity > 0 && label.Opacity < 1 {
+		b.WriteString(` opacity="` + formatFloat(label.Opacity) + `"`)
+	}
+	b.WriteString(`>`)
+	b.WriteString(labelLinesTSpans(label.Lines, formatFloat(textX), lineHeight))
+	b.WriteString(`</text>`)
+}
@@ -2,9 +2,6 @@ package mermaid
 
 import (
 	"math"
-	"os"
-	"path/filepath"
-	"sort"
 	"strings"
 	"sync"
 
@@ -20,23 +17,26 @@ type indexedFontFile struct {
 	Path string
 }
 
-var (
-	fontIndexOnce sync.Once
-	fontIndex     []indexedFontFile
-
-	fontFaceCache sync.Map // map[string]*sfnt.Font
-)
+var fontFaceCache sync.Map // map[string]*sfnt.Font
 
+// measureNativeTextWidth measures text with the font of fontFamily, taking
+// each character from the first font of its fallback chain that has it.
+// Kerning applies between characters of the same font, and the browser
+// calibration only to the primary font.
 func measureNativeTextWidth(text string, fontSize float64, fontFamily string) (float64, bool) {
 	if text == "" || fontSize <= 0 {
 		return 0, true
 	}
-	path := resolveFontPath(fontFamily)
-	if path == "" {
-		return 0, false
+	return measureChainTextWidth(text, fontSize, fontChain(fontFamily))
+}
+
+// measureChainTextWidth measures text with the fonts of chain, the first
+// being the primary font.
+func measureChainTextWidth(text string, fontSize float64, chain []string) (float64, bool) {
+	if text == "" || fontSize <= 0 {
+		return 0, true
 	}
-	face := loadFontFace(path)
-	if face == nil {
+	if len(chain) == 0 || loadFontFace(chain[0]) == nil {
 		return 0, false
 	}
 
@@ -44,9 +44,10 @@ func measureNativeTextWidth(text string, fontSize float64, fontFamily string) (f
 	ppem := fixed.Int26_6(math.Round(fontSize * 64.0))
 	fallbackAdvance := fontSize * 0.56
 	width := 0.0
+	prevFont := -1
 	var prevGlyph sfnt.GlyphIndex
 
-	for _, r := range text {
+	for _, r := range shapeArabic(text) {
 		if r == '\n' || r == '\r' {
 			prevGlyph = 0
 			continue
@@ -56,14 +57,15 @@ func measureNativeTextWidth(text string, fontSize float64, fontFamily string) (f
 			prevGlyph = 0
 			continue
 		}
-		glyphIdx, err := face.GlyphIndex(&buf, r)
-		if err != nil || glyphIdx == 0 {
-			width += fallbackAdvance
+		index, glyphIdx, _ := fontForRune(chain, r)
+		if index < 0 {
+			width += missingGlyphAdvance(r, fontSize)
 			prevGlyph = 0
 			continue
 		}
+		face := loadFontFace(chain[index])
 
-		if prevGlyph != 0 {
+		if prevGlyph != 0 && prevFont == index {
 			kern, err := face.Kern(&buf, prevGlyph, glyphIdx, ppem, font.HintingNone)
 			if err == nil {
 				width += float64(kern) / 64.0
@@ -77,13 +79,27 @@ func measureNativeTextWidth(text string, fontSize float64, fontFamily string) (f
 			continue
 		}
 		charWidth := float64(advance) / 64.0
-		charWidth *= browserCharScale(r)
+		if index == 0 {
+			charWidth *= browserCharScale(r)
+		}
 		width += charWidth
-		prevGlyph = glyphIdx
+		prevFont, prevGlyph = index, glyphIdx
 	}
 	return width, true
 }
 
+// missingGlyphAdvance estimates the width of a character no font has:
+// nothing for combining marks, a full em for East Asian wide characters.
+func missingGlyphAdvance(r rune, fontSize float64) float64 {
+	switch asciiRuneWidth(r) {
+	case 0:
+		return 0
+	case 2:
+		return fontSize
+	}
+	return fontSize * 0.56
+}
+
 // browserCharScale returns a per-character scale factor that calibrates native
 // sfnt glyph advance (DejaVu Sans) to match the browser reference renderer
 // (Chrome/Skia Trebuchet MS). Derived from empirical measurement of mmdc vs
@@ -152,7 +168,6 @@ func browserCharScale(r rune) float64 {
 }
 
 func resolveFontPath(fontFamily string) string {
-	fontIndexOnce.Do(buildFontIndex)
 	if strings.TrimSpace(fontFamily) == "" {
 		fontFamily = defaultMetricFontFamily
 	}
@@ -160,10 +175,41 @@ func resolveFontPath(fontFamily string) string {
 	if len(candidates) == 0 {
 		candidates = parseFontFamilyCandidates(defaultMetricFontFamily)
 	}
+	if path := matchFontPath(candidates); path != "" {
+		return path
+	}
+
+	// Finally the bundled fonts, monospace for monospace families.
+	fallback := "goregular"
+	for _, candidate := range candidates {
+		if strings.Contains(candidate, "mono") || strings.Contains(candidate, "courier") {
+			fallback = "gomono"
+			break
+		}
+	}
+	_, bundled := fontFiles()
+	for _, file := range bundled {
+		if file.Name == fallback {
+			return file.Path
+		}
+	}
+	return ""
+}
+
+// matchFontPath returns the registered, installed or bundled font named
+// like the first candidate that has one, or a registered or installed font
+// whose name contains it.
+func matchFontPath(candidates []string) string {
+	files, bundled := fontFiles()
 
 	// Exact match first.
 	for _, candidate := range candidates {
-		for _, file := range fontIndex {
+		for _, file := range files {
+			if file.Name == candidate {
+				return file.Path
+			}
+		}
+		for _, file := range bundled {
 			if file.Name == candidate {
 				return file.Path
 			}
@@ -173,7 +219,7 @@ func resolveFontPath(fontFamily string) string {
 	for _, candidate := range candidates {
 		bestPath := ""
 		bestLen := int(^uint(0) >> 1)
-		for _, file := range fontIndex {
+		for _, file := range files {
 			if strings.Contains(file.Name, candidate) || strings.Contains(candidate, file.Name) {
 				if l := len(file.Name); l < bestLen {
 					bestLen = l
@@ -185,68 +231,43 @@ func resolveFontPath(fontFamily string) string {
 			return bestPath
 		}
 	}
-
 	return ""
 }
 
-func buildFontIndex() {
-	dirs := []string{
-		"/System/Library/Fonts/Supplemental",
-		"/System/Library/Fonts",
-		"/Library/Fonts",
-		"/usr/share/fonts",
-		"/usr/local/share/fonts",
-		"~/.fonts",
-		"~/.local/share/fonts",
+// resolveStyledFontPath returns the bold and/or italic sibling of the font
+// resolveFontPath picks for fontFamily, or the regular font when the family
+// has no such style installed.
+func resolveStyledFontPath(fontFamily string, bold, italic bool) string {
+	path := resolveFontPath(fontFamily)
+	if path == "" || (!bold && !italic) {
+		return path
 	}
-	if home, err := os.UserHomeDir(); err == nil && strings.TrimSpace(home) != "" {
-		dirs = append(dirs, filepath.Join(home, "Library", "Fonts"))
-		dirs = append(dirs, filepath.Join(home, ".fonts"))
-		dirs = append(dirs, filepath.Join(home, ".local", "share", "fonts"))
+	files, bundled := fontFiles()
+	files = append(files, bundled...)
+	base := ""
+	for _, file := range files {
+		if file.Path == path {
+			base = strings.TrimSuffix(file.Name, "regular")
+			break
+		}
 	}
-
-	index := make([]indexedFontFile, 0, 256)
-	for _, dir := range dirs {
-		if strings.HasPrefix(dir, "~/") {
-			home, err := os.UserHomeDir()
-			if err != nil {
-				continue
+	var suffixes []string
+	switch {
+	case bold && italic:
+		suffixes = []string{"bolditalic", "boldoblique", "bi", "z"}
+	case bold:
+		suffixes = []string{"bold", "bd", "b"}
+	default:
+		suffixes = []string{"italic", "oblique", "i"}
+	}
+	for _, suffix := range suffixes {
+		for _, file := range files {
+			if file.Name == base+suffix {
+				return file.Path
 			}
-			dir = filepath.Join(home, dir[2:])
 		}
-
-		filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
-			if err != nil {
-				return nil
-			}
-			if d.IsDir() {
-				return nil
-			}
-
-			name := d.Name()
-			ext := strings.ToLower(filepath.Ext(name))
-			if ext != ".ttf" && ext != ".otf" && ext != ".ttc" {
-				return nil
-			}
-			base := strings.TrimSuffix(name, filepath.Ext(name))
-			norm := normalizeFontToken(base)
-			if norm == "" {
-				return nil
-			}
-			index = append(index, indexedFontFile{
-				Name: norm,
-				Path: path,
-			})
-			return nil
-		})
 	}
-	sort.Slice(index, func(i, j int) bool {
-		if index[i].Name == index[j].Name {
-			return index[i].Path < index[j].Path
-		}
-		return len(index[i].Name) < len(index[j].Name)
-	})
-	fontIndex = index
+	return path
 }
 
 func parseFontFamilyCandidates(fontFamily string) []string {
@@ -294,12 +315,12 @@ func loadFontFace(path string) *sfnt.Font {
 			return face
 		}
 	}
-	data, err := os.ReadFile(path)
+	data, err := readFontData(path)
 	if err != nil {
 		return nil
 	}
 
-	if strings.EqualFold(filepath.Ext(path), ".ttc") {
+	if len(data) >= 4 && string(data[:4]) == "ttcf" {
 		collection, err := sfnt.ParseCollection(data)
 		if err == nil {
 			for i := 0; i < collection.NumFonts(); i++ {
@@ -0,0 +1,334 @@
+package mermaid
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/bidi"
+)
+
+// displayText prepares a line for drawing glyph by glyph: Arabic letters
+// take their joined forms and right-to-left runs are put in visual order.
+// Browsers do this themselves, so it is only needed for raster and PDF.
+func displayText(line string) string {
+	return visualOrder(shapeArabic(line))
+}
+
+// hasRightToLeft reports whether s contains Hebrew, Arabic or other
+// right-to-left letters.
+func hasRightToLeft(s string) bool {
+	for _, r := range s {
+		if r >= 0x0590 {
+			if p, _ := bidi.LookupRune(r); p.Class() == bidi.R || p.Class() == bidi.AL {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// baseDirectionRTL reports whether the first strong character of s is
+// right-to-left, which makes it the paragraph direction.
+func baseDirectionRTL(s string) bool {
+	for _, r := range s {
+		switch p, _ := bidi.LookupRune(r); p.Class() {
+		case bidi.L:
+			return false
+		case bidi.R, bidi.AL:
+			return true
+		}
+	}
+	return false
+}
+
+// visualOrder reorders one line from logical to visual order with the
+// implicit rules of the Unicode bidirectional algorithm: numbers keep their
+// order inside right-to-left text, neutrals take the direction of the text
+// around them, and brackets in right-to-left runs are mirrored. Explicit
+// embedding and isolate controls are not supported.
+func visualOrder(line string) string {
+	if !hasRightToLeft(line) {
+		return line
+	}
+	runes := []rune(line)
+	classes := make([]bidi.Class, len(runes))
+	for i, r := range runes {
+		p, _ := bidi.LookupRune(r)
+		classes[i] = p.Class()
+	}
+	base := 0
+	if baseDirectionRTL(line) {
+		base = 1
+	}
+	sos := bidi.L
+	if base == 1 {
+		sos = bidi.R
+	}
+
+	// W1-W3: marks follow their base, numbers after Arabic letters are
+	// Arabic numbers, and Arabic letters are right-to-left.
+	strong := sos
+	for i, c := range classes {
+		if c == bidi.NSM {
+			if i == 0 {
+				c = sos
+			} else {
+				c = classes[i-1]
+			}
+		}
+		switch c {
+		case bidi.L, bidi.R:
+			strong = c
+		case bidi.AL:
+			strong = bidi.AL
+			c = bidi.R
+		case bidi.EN:
+			if strong == bidi.AL {
+				c = bidi.AN
+			}
+		}
+		classes[i] = c
+	}
+	// W4-W6: separators between numbers and terminators next to them join
+	// the number; the rest are neutral.
+	for i := 1; i+1 < len(classes); i++ {
+		prev, next := classes[i-1], classes[i+1]
+		switch {
+		case classes[i] == bidi.ES && prev == bidi.EN && next == bidi.EN,
+			classes[i] == bidi.CS && prev == bidi.EN && next == bidi.EN:
+			classes[i] = bidi.EN
+		case classes[i] == bidi.CS && prev == bidi.AN && next == bidi.AN:
+			classes[i] = bidi.AN
+		}
+	}
+	for i := 0; i < len(classes); {
+		if classes[i] != bidi.ET {
+			i++
+			continue
+		}
+		j := i
+		for j < len(classes) && classes[j] == bidi.ET {
+			j++
+		}
+		if (i > 0 && classes[i-1] == bidi.EN) || (j < len(classes) && classes[j] == bidi.EN) {
+			for k := i; k < j; k++ {
+				classes[k] = bidi.EN
+			}
+		}
+		i = j
+	}
+	for i, c := range classes {
+		switch c {
+		case bidi.ES, bidi.ET, bidi.CS:
+			classes[i] = bidi.ON
+		}
+	}
+	// W7: European numbers after left-to-right text are left-to-right.
+	strong = sos
+	for i, c := range classes {
+		switch c {
+		case bidi.L, bidi.R:
+			strong = c
+		case bidi.EN:
+			if strong == bidi.L {
+				classes[i] = bidi.L
+			}
+		}
+	}
+	// N1-N2: neutrals between text of one direction take that direction,
+	// numbers counting as right-to-left; others take the base direction.
+	direction := func(c bidi.Class) bidi.Class {
+		switch c {
+		case bidi.L:
+			return bidi.L
+		case bidi.R, bidi.EN, bidi.AN:
+			return bidi.R
+		}
+		return bidi.ON
+	}
+	for i := 0; i < len(classes); {
+		if direction(classes[i]) != bidi.ON {
+			i++
+			continue
+		}
+		j := i
+		for j < len(classes) && direction(classes[j]) == bidi.ON {
+			j++
+		}
+		before, after := sos, sos
+		if i > 0 {
+			before = direction(classes[i-1])
+		}
+		if j < len(classes) {
+			after = direction(classes[j])
+		}
+		resolved := sos
+		if before == after {
+			resolved = before
+		}
+		for k := i; k < j; k++ {
+			classes[k] = resolved
+		}
+		i = j
+	}
+
+	// I1-I2 and L1: embedding levels, with trailing whitespace at the
+	// base level.
+	levels := make([]int, len(runes))
+	highest := base
+	for i, c := range classes {
+		level := base
+		switch {
+		case base == 0 && c == bidi.R:
+			level = 1
+		case base == 0 && (c == bidi.AN || c == bidi.EN):
+			level = 2
+		case base == 1 && c != bidi.R:
+			level = 2
+		}
+		levels[i] = level
+		highest = max(highest, level)
+	}
+	for i := len(runes) - 1; i >= 0 && unicode.IsSpace(runes[i]); i-- {
+		levels[i] = base
+	}
+
+	// L2: reverse every run at or above each level, highest first.
+	for level := highest; level >= 1; level-- {
+		for i := 0; i < len(runes); i++ {
+			if levels[i] < level {
+				continue
+			}
+			j := i
+			for j < len(runes) && levels[j] >= level {
+				j++
+			}
+			for a, b := i, j-1; a < b; a, b = a+1, b-1 {
+				runes[a], runes[b] = runes[b], runes[a]
+				levels[a], levels[b] = levels[b], levels[a]
+			}
+			i = j
+		}
+	}
+	// L4: mirror brackets drawn right to left.
+	for i, r := range runes {
+		if levels[i]%2 == 1 {
+			if m, ok := mirroredRunes[r]; ok {
+				runes[i] = m
+			}
+		}
+	}
+	return string(runes)
+}
+
+var mirroredRunes = map[rune]rune{
+	'(': ')', ')': '(', '[': ']', ']': '[', '{': '}', '}': '{',
+	'<': '>', '>': '<', '«': '»', '»': '«', '‹': '›', '›': '‹',
+}
+
+// arabicForms maps an Arabic letter to its isolated presentation form and
+// the number of forms: isolated and final for letters that only join the
+// letter before them, then initial and medial for letters joining both.
+var arabicForms = map[rune]struct {
+	isolated rune
+	forms    rune
+}{
+	0x0621: {0xFE80, 1}, 0x0622: {0xFE81, 2}, 0x0623: {0xFE83, 2},
+	0x0624: {0xFE85, 2}, 0x0625: {0xFE87, 2}, 0x0626: {0xFE89, 4},
+	0x0627: {0xFE8D, 2}, 0x0628: {0xFE8F, 4}, 0x0629: {0xFE93, 2},
+	0x062A: {0xFE95, 4}, 0x062B: {0xFE99, 4}, 0x062C: {0xFE9D, 4},
+	0x062D: {0xFEA1, 4}, 0x062E: {0xFEA5, 4}, 0x062F: {0xFEA9, 2},
+	0x0630: {0xFEAB, 2}, 0x0631: {0xFEAD, 2}, 0x0632: {0xFEAF, 2},
+	0x0633: {0xFEB1, 4}, 0x0634: {0xFEB5, 4}, 0x0635: {0xFEB9, 4},
+	0x0636: {0xFEBD, 4}, 0x0637: {0xFEC1, 4}, 0x0638: {0xFEC5, 4},
+	0x0639: {0xFEC9, 4}, 0x063A: {0xFECD, 4}, 0x0641: {0xFED1, 4},
+	0x0642: {0xFED5, 4}, 0x0643: {0xFED9, 4}, 0x0644: {0xFEDD, 4},
+	0x0645: {0xFEE1, 4}, 0x0646: {0xFEE5, 4}, 0x0647: {0xFEE9, 4},
+	0x0648: {0xFEED, 2}, 0x0649: {0xFEEF, 2}, 0x064A: {0xFEF1, 4},
+	0x067E: {0xFB56, 4}, 0x0686: {0xFB7A, 4}, 0x0698: {0xFB8A, 2},
+	0x06A9: {0xFB8E, 4}, 0x06AF: {0xFB92, 4}, 0x06CC: {0xFBFC, 4},
+}
+
+// lamAlef maps the alef that follows a lam to the isolated form of their
+// ligature; the final form follows it.
+var lamAlef = map[rune]rune{0x0622: 0xFEF5, 0x0623: 0xFEF7, 0x0625: 0xFEF9, 0x0627: 0xFEFB}
+
+// shapeArabic replaces Arabic letters with the presentation form matching
+// how they join their neighbours, and lam-alef pairs with their ligature.
+// Text without Arabic letters is returned unchanged.
+func shapeArabic(s string) string {
+	if !strings.ContainsFunc(s, func(r rune) bool { return r >= 0x0600 && r <= 0x06FF }) {
+		return s
+	}
+	runes := []rune(s)
+	transparent := func(r rune) bool { return unicode.Is(unicode.Mn, r) }
+	// joins reports whether the letter at i connects to the letter after
+	// it, or with after false, to the one before it. Tatweel joins both.
+	joins := func(i int, after bool) bool {
+		if runes[i] == 0x0640 {
+			return true
+		}
+		f, ok := arabicForms[runes[i]]
+		if after {
+			return ok && f.forms == 4
+		}
+		return ok && f.forms >= 2
+	}
+	neighbour := func(i, step int) int {
+		for i += step; i >= 0 && i < len(runes) && transparent(runes[i]); i += step {
+		}
+		if i < 0 || i >= len(runes) {
+			return -1
+		}
+		return i
+	}
+
+	out := make([]rune, 0, len(runes))
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		f, ok := arabicForms[r]
+		if !ok {
+			out = append(out, r)
+			continue
+		}
+		prev := neighbour(i, -1)
+		joinPrev := f.forms >= 2 && prev >= 0 && joins(prev, true)
+		if r == 0x0644 && i+1 < len(runes) {
+			if ligature, ok := lamAlef[runes[i+1]]; ok {
+				if joinPrev {
+					ligature++
+				}
+				out = append(out, ligature)
+				i++
+				continue
+			}
+		}
+		next := neighbour(i, 1)
+		joinNext := f.forms == 4 && next >= 0 && joins(next, false)
+		form := f.isolated
+		switch {
+		case joinPrev && joinNext:
+			form += 3
+		case joinNext:
+			form += 2
+		case joinPrev:
+			form++
+		}
+		out = append(out, form)
+	}
+	return string(out)
+}
+
+// arabicBase returns the letter a presentation form was made from, for
+// fonts that cover Arabic but not its presentation forms.
+func arabicBase(r rune) rune {
+	if r < 0xFB50 || r > 0xFEFC {
+		return r
+	}
+	for base, f := range arabicForms {
+		if r >= f.isolated && r < f.isolated+f.forms {
+			return base
+		}
+	}
+	return r
+}
@@ -0,0 +1,37 @@
+package mermaid
+
+import "testing"
+
+func TestVisualOrder(t *testing.T) {
+	tests := []struct {
+		name, in, want string
+	}{
+		{"left to right", "plain text (1)", "plain text (1)"},
+		{"hebrew", "שלום (עולם)", "(םלוע) םולש"},
+		{"number in hebrew", "abc שלום 123 (x)", "abc 123 םולש (x)"},
+		{"latin in hebrew", "שלום abc!", "!abc םולש"},
+		{"trailing space", "abc שלום ", "abc םולש "},
+	}
+	for _, tt := range tests {
+		if got := visualOrder(tt.in); got != tt.want {
+			t.Errorf("%s: visualOrder(%q) = %q, want %q", tt.name, tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestShapeArabic(t *testing.T) {
+	// Seen initial, lam-alef final, meem isolated.
+	if got := shapeArabic("سلام"); got != "ﺳﻼﻡ" {
+		t.Fatalf("shapeArabic(salam) = %U", []rune(got))
+	}
+	// Beh medial between beh and beh, hamza never joins.
+	if got := shapeArabic("ببب ء"); got != "ﺑﺒﺐ ﺀ" {
+		t.Fatalf("shapeArabic() = %U", []rune(got))
+	}
+	if got := displayText("سلام"); got != "ﻡﻼﺳ" {
+		t.Fatalf("displayText() = %U, want visual order", []rune(got))
+	}
+	if got := arabicBase(0xFE92); got != 0x0628 {
+		t.Fatalf("arabicBase(medial beh) = %U", got)
+	}
+}
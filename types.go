@@ -74,6 +74,48 @@ const (
 	ShapeAsymmetric    NodeShape = "asymmetric"
 	ShapePerson        NodeShape = "person"
 	ShapeHidden        NodeShape = "hidden"
+
+	// Mermaid v11 flowchart shapes, named after one of their mermaid aliases.
+	ShapeLeanLeft           NodeShape = "lean-left"
+	ShapeTrapezoidTop       NodeShape = "trapezoid-top"
+	ShapeSmallCircle        NodeShape = "small-circle"
+	ShapeFilledCircle       NodeShape = "filled-circle"
+	ShapeFramedCircle       NodeShape = "framed-circle"
+	ShapeCrossedCircle      NodeShape = "crossed-circle"
+	ShapeHorizontalCylinder NodeShape = "horizontal-cylinder"
+	ShapeLinedCylinder      NodeShape = "lined-cylinder"
+	ShapeDocument           NodeShape = "document"
+	ShapeLinedDocument      NodeShape = "lined-document"
+	ShapeStackedDocument    NodeShape = "stacked-document"
+	ShapeTaggedDocument     NodeShape = "tagged-document"
+	ShapeNotchedRect        NodeShape = "notched-rectangle"
+	ShapeHourglass          NodeShape = "hourglass"
+	ShapeBolt               NodeShape = "lightning-bolt"
+	ShapeBraceLeft          NodeShape = "brace-l"
+	ShapeBraceRight         NodeShape = "brace-r"
+	ShapeBraces             NodeShape = "braces"
+	ShapeDelay              NodeShape = "delay"
+	ShapeCurvedTrapezoid    NodeShape = "curved-trapezoid"
+	ShapeDividedRect        NodeShape = "divided-rectangle"
+	ShapeTriangle           NodeShape = "triangle"
+	ShapeFlippedTriangle    NodeShape = "flipped-triangle"
+	ShapeFork               NodeShape = "fork"
+	ShapeWindowPane         NodeShape = "window-pane"
+	ShapeLinedRect          NodeShape = "lined-rectangle"
+	ShapeNotchedPentagon    NodeShape = "notched-pentagon"
+	ShapeSlopedRect         NodeShape = "sloped-rectangle"
+	ShapeStackedRect        NodeShape = "stacked-rectangle"
+	ShapeFlag               NodeShape = "flag"
+	ShapeBowTieRect         NodeShape = "bow-tie-rectangle"
+	ShapeTaggedRect         NodeShape = "tagged-rectangle"
+	ShapeCloud              NodeShape = "cloud"
+	ShapeBang               NodeShape = "bang"
+	ShapeText               NodeShape = "text"
+	ShapeIcon               NodeShape = "icon"
+	ShapeIconSquare         NodeShape = "icon-square"
+	ShapeIconCircle         NodeShape = "icon-circle"
+	ShapeIconRounded        NodeShape = "icon-rounded"
+	ShapeImage              NodeShape = "image"
 )
 
 type EdgeStyle string
@@ -92,6 +134,17 @@ type Node struct {
 	Fill        string
 	Stroke      string
 	StrokeWidth float64
+
+	// Icon and Img come from `@{ icon: ..., img: ... }` node metadata.
+	// LabelPos is "t" or "b" and places the label above or below the
+	// asset; AssetWidth/AssetHeight are the requested w/h and Constraint
+	// keeps the image aspect ratio.
+	Icon        string
+	Img         string
+	LabelPos    string
+	AssetWidth  float64
+	AssetHeight float64
+	Constraint  bool
 }
 
 type Edge struct {
@@ -104,17 +157,28 @@ type Edge struct {
 	MarkerStart string
 	MarkerEnd   string
 	Style       EdgeStyle
+
+	// ID is the v11 edge id from `A e1@--> B`. Animation is "fast" or
+	// "slow" when the edge is animated, Curve a per-edge interpolation
+	// name and Classes the classDef names applied with `class e1 name`.
+	ID        string
+	Animation string
+	Curve     string
+	Classes   []string
+	// MinLen is the number of ranks the edge spans at least, from extra
+	// dashes or equals signs in the arrow. Zero means the default of one.
+	MinLen int
 }
 
 type SequenceMessage struct {
-	From          string
-	To            string
-	Label         string
-	Arrow         string
-	Index         string
-	NotePlacement SequenceNotePlacement
-	IsReturn      bool
-	IsNote        bool
+	From          string                `json:"from,omitempty"`
+	To            string                `json:"to,omitempty"`
+	Label         string                `json:"label,omitempty"`
+	Arrow         string                `json:"arrow,omitempty"`
+	Index         string                `json:"index,omitempty"`
+	NotePlacement SequenceNotePlacement `json:"note_placement,omitempty"`
+	IsReturn      bool                  `json:"is_return,omitempty"`
+	IsNote        bool                  `json:"is_note,omitempty"`
 }
 
 type SequenceEventKind string
@@ -140,10 +204,10 @@ const (
 )
 
 type SequenceEvent struct {
-	Kind         SequenceEventKind
-	MessageIndex int
-	Label        string
-	Actor        string
+	Kind         SequenceEventKind `json:"kind,omitempty"`
+	MessageIndex int               `json:"message_index,omitempty"`
+	Label        string            `json:"label,omitempty"`
+	Actor        string            `json:"actor,omitempty"`
 }
 
 type PieSlice struct {
@@ -191,6 +255,7 @@ type RadarCurve struct {
 	Name    string
 	Label   string
 	Entries []float64
+	Class   string
 }
 
 type PacketField struct {
@@ -204,6 +269,7 @@ type TreemapItem struct {
 	Label    string
 	Value    float64
 	HasValue bool
+	Class    string
 }
 
 type KanbanCard struct {
@@ -212,25 +278,41 @@ type KanbanCard struct {
 	Ticket   string
 	Assigned string
 	Priority string
+	// Metadata keeps every `@{ ... }` key that has no dedicated field.
+	Metadata map[string]string
 }
 
 type KanbanColumn struct {
+	ID    string
 	Title string
+	Class string
 	Cards []KanbanCard
 }
 
 type MindmapNode struct {
-	ID     string
-	Label  string
-	Level  int
-	Parent string
-	Shape  NodeShape
+	ID     string    `json:"id,omitempty"`
+	Label  string    `json:"label,omitempty"`
+	Level  int       `json:"level,omitempty"`
+	Parent string    `json:"parent,omitempty"`
+	Shape  NodeShape `json:"shape,omitempty"`
 }
 
 type FlowSubgraph struct {
 	ID      string
 	Label   string
 	NodeIDs []string
+
+	// Parent is the id of the enclosing subgraph, empty at the top level.
+	// Direction is set by a `direction` statement inside the subgraph.
+	Parent    string
+	Direction Direction
+
+	// Fill, Stroke and StrokeWidth come from `style` and `class`
+	// statements naming the subgraph; Classes are the applied class names.
+	Fill        string
+	Stroke      string
+	StrokeWidth float64
+	Classes     []string
 }
 
 type GitCommit struct {
@@ -306,16 +388,25 @@ type ArchitectureLink struct {
 }
 
 type ZenUMLAltBlock struct {
-	Condition string
-	Start     int
-	ElseStart int
-	End       int
+	Condition string `json:"condition,omitempty"`
+	Start     int    `json:"start,omitempty"`
+	ElseStart int    `json:"else_start,omitempty"`
+	End       int    `json:"end,omitempty"`
 }
 
 type Graph struct {
 	Kind      DiagramKind
 	Direction Direction
 	Source    string
+	Config    map[string]any
+	// ClassDefs maps classDef names to their CSS declarations.
+	ClassDefs map[string][]string
+	// AccTitle and AccDescr come from the accTitle and accDescr
+	// statements and describe the diagram to assistive technology.
+	AccTitle string
+	AccDescr string
+	// Title is the diagram title from front matter or a `title` statement.
+	Title string
 
 	Nodes     map[string]Node
 	NodeOrder []string
@@ -405,6 +496,7 @@ func newGraph(kind DiagramKind) Graph {
 		Kind:                      kind,
 		Direction:                 DirectionTopDown,
 		Nodes:                     map[string]Node{},
+		ClassDefs:                 map[string][]string{},
 		RadarShowLegend:           true,
 		RadarTicks:                5,
 		RadarGraticule:            "circle",
@@ -449,6 +541,53 @@ func (g *Graph) ensureNode(id, label string, shape NodeShape) {
 	g.Nodes[id] = node
 }
 
+// edgeIndexByID returns the index of the edge declared with id, or -1.
+func (g *Graph) edgeIndexByID(id string) int {
+	if id == "" {
+		return -1
+	}
+	for i := range g.Edges {
+		if g.Edges[i].ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// setTitle records the diagram title. Diagrams with a title of their own
+// take it when their source did not already set one.
+func (g *Graph) setTitle(title string) {
+	g.Title = title
+	slots := map[DiagramKind]*string{
+		DiagramPie:      &g.PieTitle,
+		DiagramGantt:    &g.GanttTitle,
+		DiagramTimeline: &g.TimelineTitle,
+		DiagramJourney:  &g.JourneyTitle,
+		DiagramC4:       &g.C4Title,
+		DiagramZenUML:   &g.ZenUMLTitle,
+		DiagramRadar:    &g.RadarTitle,
+		DiagramPacket:   &g.PacketTitle,
+		DiagramXYChart:  &g.XYTitle,
+		DiagramQuadrant: &g.QuadrantTitle,
+	}
+	if slot, ok := slots[g.Kind]; ok && *slot == "" {
+		*slot = title
+	}
+}
+
+// flowSubgraphIndex returns the index of the subgraph with id, or -1.
+func (g *Graph) flowSubgraphIndex(id string) int {
+	if id == "" {
+		return -1
+	}
+	for i := range g.FlowSubgraphs {
+		if g.FlowSubgraphs[i].ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
 func (g *Graph) addEdge(e Edge) {
 	if e.From == "" || e.To == "" {
 		return